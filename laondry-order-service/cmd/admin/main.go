@@ -0,0 +1,313 @@
+// Command admin provides back-office maintenance tasks for the order service.
+//
+//	go run ./cmd/admin webhooks list   [-order ID] [-status S] [-error TEXT] [-from DATE] [-to DATE] [-all] [-limit N]
+//	go run ./cmd/admin webhooks replay [-id UUID,...] [-order ID] [-from DATE] [-to DATE] [-limit N] [-dry-run] [-force]
+//	go run ./cmd/admin settlements import -file REPORT.csv [-outlet UUID]
+//	go run ./cmd/admin settlements report [-date DATE] [-outlet UUID] [-status S] [-limit N]
+//	go run ./cmd/admin api-keys issue -name NAME -permissions P,... [-outlet UUID] [-expires DATE]
+//	go run ./cmd/admin api-keys list
+//	go run ./cmd/admin api-keys revoke -id UUID
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/database"
+	"laondry-order-service/internal/domain/apikey"
+	apikeyservice "laondry-order-service/internal/domain/apikey/service"
+	"laondry-order-service/internal/domain/notification"
+	"laondry-order-service/internal/domain/payment"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/domain/payment/service"
+	"laondry-order-service/internal/domain/realtime"
+	"laondry-order-service/internal/domain/webhook"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/logger"
+	"laondry-order-service/pkg/validator"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	// Keep service logs readable and off stdout, which carries the output
+	slog.SetDefault(logger.New(os.Stderr, cfg.Logging.Level, "text"))
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		fatalf("failed to connect to database: %v", err)
+	}
+	v := validator.NewValidator()
+	notificationDomain := notification.NewNotificationDomain(cfg, v, db)
+	webhookDomain := webhook.NewWebhookDomain(cfg, v, db)
+	// Payments settled by replays reach live streams over Redis
+	realtimeDomain := realtime.NewRealtimeDomain(cfg, db)
+	bus := events.NewBus()
+	notificationDomain.RegisterEventHandlers(bus)
+	webhookDomain.RegisterEventHandlers(bus)
+	realtimeDomain.RegisterEventHandlers(bus)
+	paymentDomain := payment.NewPaymentDomain(cfg, v, db, bus)
+	apikeyDomain := apikey.NewAPIKeyDomain(cfg, v, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	group, cmd, args := os.Args[1], os.Args[2], os.Args[3:]
+	switch group + " " + cmd {
+	case "webhooks list":
+		err = webhooksList(ctx, paymentDomain.Service, args)
+	case "webhooks replay":
+		err = webhooksReplay(ctx, paymentDomain.Service, args)
+	case "settlements import":
+		err = settlementsImport(ctx, paymentDomain.Service, args)
+	case "settlements report":
+		err = settlementsReport(ctx, paymentDomain.Service, args)
+	case "api-keys issue":
+		err = apiKeysIssue(ctx, apikeyDomain.Service, v, args)
+	case "api-keys list":
+		err = apiKeysList(ctx, apikeyDomain.Service)
+	case "api-keys revoke":
+		err = apiKeysRevoke(ctx, apikeyDomain.Service, args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  admin webhooks list   [-order ID] [-status S] [-error TEXT] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-all] [-limit N]
+  admin webhooks replay [-id UUID,...] [-order ID] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-limit N] [-dry-run] [-force]
+  admin settlements import -file REPORT.csv [-outlet UUID]
+  admin settlements report [-date YYYY-MM-DD] [-outlet UUID] [-status S] [-limit N]
+  admin api-keys issue -name NAME -permissions orders:read,... [-outlet UUID] [-expires YYYY-MM-DD]
+  admin api-keys list
+  admin api-keys revoke -id UUID`)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "admin: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// webhookFilterFlags registers the shared webhook log filter flags on fs.
+func webhookFilterFlags(fs *flag.FlagSet) func() repository.WebhookLogFilters {
+	order := fs.String("order", "", "payment order ID")
+	status := fs.String("status", "", "Midtrans transaction_status")
+	errText := fs.String("error", "", "substring of processing error")
+	from := fs.String("from", "", "start date (YYYY-MM-DD)")
+	to := fs.String("to", "", "end date (YYYY-MM-DD)")
+	all := fs.Bool("all", false, "include successfully processed logs")
+	limit := fs.Int("limit", 50, "maximum number of logs")
+
+	return func() repository.WebhookLogFilters {
+		f := repository.WebhookLogFilters{OnlyFailed: !*all, Page: 1, Limit: *limit}
+		if *order != "" {
+			f.PaymentOrderID = order
+		}
+		if *status != "" {
+			f.TransactionStatus = status
+		}
+		if *errText != "" {
+			f.ErrorContains = errText
+		}
+		if *from != "" {
+			f.StartDate = from
+		}
+		if *to != "" {
+			f.EndDate = to
+		}
+		return f
+	}
+}
+
+func webhooksList(ctx context.Context, svc service.PaymentService, args []string) error {
+	fs := flag.NewFlagSet("webhooks list", flag.ExitOnError)
+	filters := webhookFilterFlags(fs)
+	_ = fs.Parse(args)
+
+	logs, total, err := svc.ListWebhookLogs(ctx, filters())
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]interface{}{"total": total, "webhook_logs": logs})
+}
+
+func webhooksReplay(ctx context.Context, svc service.PaymentService, args []string) error {
+	fs := flag.NewFlagSet("webhooks replay", flag.ExitOnError)
+	ids := fs.String("id", "", "comma-separated webhook log IDs")
+	dryRun := fs.Bool("dry-run", false, "verify and map payloads without updating transactions")
+	force := fs.Bool("force", false, "replay logs that were already processed")
+	filters := webhookFilterFlags(fs)
+	_ = fs.Parse(args)
+
+	req := service.ReplayWebhookRequest{DryRun: *dryRun, Force: *force}
+	if *ids != "" {
+		for _, raw := range strings.Split(*ids, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("invalid log id %q: %w", raw, err)
+			}
+			req.LogIDs = append(req.LogIDs, id)
+		}
+	} else {
+		f := filters()
+		req.Filters = &f
+	}
+
+	res, err := svc.ReplayWebhookLogs(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func settlementsImport(ctx context.Context, svc service.PaymentService, args []string) error {
+	fs := flag.NewFlagSet("settlements import", flag.ExitOnError)
+	path := fs.String("file", "", "Midtrans settlement report CSV")
+	outlet := fs.String("outlet", "", "outlet whose own merchant account the report is from")
+	_ = fs.Parse(args)
+	if *path == "" {
+		return fmt.Errorf("-file is required")
+	}
+	var outletID *uuid.UUID
+	if *outlet != "" {
+		id, err := uuid.Parse(*outlet)
+		if err != nil {
+			return fmt.Errorf("invalid outlet id %q: %w", *outlet, err)
+		}
+		outletID = &id
+	}
+
+	f, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	res, err := svc.ImportSettlementReport(ctx, service.SettlementImportRequest{
+		OutletID: outletID,
+		FileName: filepath.Base(*path),
+		Report:   f,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func settlementsReport(ctx context.Context, svc service.PaymentService, args []string) error {
+	fs := flag.NewFlagSet("settlements report", flag.ExitOnError)
+	date := fs.String("date", "", "settlement day (YYYY-MM-DD)")
+	outlet := fs.String("outlet", "", "outlet ID")
+	status := fs.String("status", "", "MATCHED, MISSING, EXTRA, AMOUNT_MISMATCH or STATUS_MISMATCH")
+	limit := fs.Int("limit", 100, "maximum number of entries")
+	_ = fs.Parse(args)
+
+	filters := repository.SettlementFilters{Page: 1, Limit: *limit}
+	if *date != "" {
+		filters.Date = date
+	}
+	if *outlet != "" {
+		id, err := uuid.Parse(*outlet)
+		if err != nil {
+			return fmt.Errorf("invalid outlet id %q: %w", *outlet, err)
+		}
+		filters.OutletID = &id
+	}
+	if *status != "" {
+		filters.Status = status
+	}
+
+	res, err := svc.GetSettlementReconciliation(ctx, filters)
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func apiKeysIssue(ctx context.Context, svc apikeyservice.APIKeyService, v *validator.Validator, args []string) error {
+	fs := flag.NewFlagSet("api-keys issue", flag.ExitOnError)
+	name := fs.String("name", "", "who the key is for, e.g. core-api")
+	permissions := fs.String("permissions", "", "comma-separated: orders:read, orders:create, orders:update, orders:status")
+	outlet := fs.String("outlet", "", "limit the key to this outlet's orders")
+	expires := fs.String("expires", "", "expiry date (YYYY-MM-DD), never when empty")
+	_ = fs.Parse(args)
+
+	req := apikeyservice.IssueRequest{Name: *name}
+	for _, p := range strings.Split(*permissions, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			req.Permissions = append(req.Permissions, p)
+		}
+	}
+	if *outlet != "" {
+		id, err := uuid.Parse(*outlet)
+		if err != nil {
+			return fmt.Errorf("invalid outlet id %q: %w", *outlet, err)
+		}
+		req.OutletID = &id
+	}
+	if *expires != "" {
+		t, err := time.ParseInLocation("2006-01-02", *expires, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid expiry date %q: %w", *expires, err)
+		}
+		req.ExpiresAt = &t
+	}
+	if errs := v.Validate(req); len(errs) > 0 {
+		return fmt.Errorf("invalid key: %+v", errs)
+	}
+
+	key, err := svc.Issue(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Store the key now, it cannot be shown again.")
+	return printJSON(key)
+}
+
+func apiKeysList(ctx context.Context, svc apikeyservice.APIKeyService) error {
+	keys, err := svc.List(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(keys)
+}
+
+func apiKeysRevoke(ctx context.Context, svc apikeyservice.APIKeyService, args []string) error {
+	fs := flag.NewFlagSet("api-keys revoke", flag.ExitOnError)
+	rawID := fs.String("id", "", "API key ID")
+	_ = fs.Parse(args)
+
+	id, err := uuid.Parse(*rawID)
+	if err != nil {
+		return fmt.Errorf("invalid key id %q: %w", *rawID, err)
+	}
+	key, err := svc.Revoke(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printJSON(key)
+}
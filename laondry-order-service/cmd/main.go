@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -16,42 +16,108 @@ import (
 	"time"
 
 	"github.com/newrelic/go-agent/v3/newrelic"
+	redis "github.com/redis/go-redis/v9"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"gorm.io/gorm"
 
 	"laondry-order-service/internal/config"
 	"laondry-order-service/internal/database"
+	"laondry-order-service/internal/domain/apikey"
+	"laondry-order-service/internal/domain/notification"
 	"laondry-order-service/internal/domain/order"
+	"laondry-order-service/internal/domain/payment"
+	"laondry-order-service/internal/domain/realtime"
+	"laondry-order-service/internal/domain/webhook"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/idempotency"
+	"laondry-order-service/internal/logger"
+	"laondry-order-service/internal/metrics"
 	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/outbox"
 	"laondry-order-service/internal/routes"
+	"laondry-order-service/internal/scheduler"
 	"laondry-order-service/pkg/validator"
 )
 
 func main() {
 	cfg := config.LoadConfig()
 
-	log.Printf("Starting %s in %s mode", cfg.App.Name, cfg.App.Environment)
+	// JSON records on stdout, see package logger; the standard log package
+	// writes through it as well
+	slog.SetDefault(logger.New(os.Stdout, cfg.Logging.Level, cfg.Logging.Format))
+
+	slog.Info("Starting service", "app", cfg.App.Name, "env", cfg.App.Environment)
 
 	// Set core API URL for auth middleware
 	mw.SetCoreAPIURL(cfg.External.CoreAPIURL)
 
 	db, err := database.NewPostgresConnection(&cfg.Database)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", "error", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to get database instance: %v", err)
+		fatal("Failed to get database instance", "error", err)
 	}
 	defer sqlDB.Close()
 
+	mw.SetAuthenticator(newAuthenticator(cfg, db))
+
 	validatorInstance := validator.NewValidator()
 
-	orderDomain := order.NewOrderDomain(db, validatorInstance, cfg)
+	// Internal callers authenticate with X-Api-Key instead of a user token
+	apikeyDomain := apikey.NewAPIKeyDomain(cfg, validatorInstance, db)
+	mw.SetAPIKeyAuthenticator(apikeyDomain.Service)
+
+	notificationDomain := notification.NewNotificationDomain(cfg, validatorInstance, db)
+	webhookDomain := webhook.NewWebhookDomain(cfg, validatorInstance, db)
+	realtimeDomain := realtime.NewRealtimeDomain(cfg, db)
+
+	// Order and payment events reach their side effects through the bus
+	bus := events.NewBus()
+	notificationDomain.RegisterEventHandlers(bus)
+	webhookDomain.RegisterEventHandlers(bus)
+	realtimeDomain.RegisterEventHandlers(bus)
+	bus.Subscribe("metrics", metrics.CountEvents)
 
-	router := routes.NewRouter(cfg, db, validatorInstance, orderDomain)
+	orderDomain := order.NewOrderDomain(db, validatorInstance, cfg, bus)
+	paymentDomain := payment.NewPaymentDomain(cfg, validatorInstance, db, bus)
+
+	// Clients retrying order creation or payment tokens get the first response
+	idem := idempotency.New(idempotency.NewStore(db), paymentDomain.Locker, time.Duration(cfg.Idempotency.TTLHours)*time.Hour)
+
+	router := routes.NewRouter(orderDomain, paymentDomain, notificationDomain, webhookDomain, realtimeDomain, apikeyDomain, newRateLimiter(cfg), idem)
 	handler := router.Setup()
 
+	// Background jobs run in the serving process only: not in the prefork
+	// master, and in prefork only in the first worker.
+	jobs := scheduler.New(paymentDomain.Locker)
+	for _, job := range paymentDomain.Jobs(cfg) {
+		jobs.Add(job)
+	}
+	for _, job := range notificationDomain.Jobs(cfg) {
+		jobs.Add(job)
+	}
+	for _, job := range webhookDomain.Jobs(cfg) {
+		jobs.Add(job)
+	}
+	dispatcher := outbox.NewDispatcher(outbox.NewStore(db), cfg.Outbox.MaxAttempts)
+	paymentDomain.RegisterOutboxHandlers(dispatcher)
+	if cfg.Outbox.PollIntervalSeconds > 0 {
+		jobs.Add(dispatcher.Job(time.Duration(cfg.Outbox.PollIntervalSeconds) * time.Second))
+	}
+	jobs.Add(idem.Job(time.Hour))
+	isPreforkMaster := cfg.App.ClusterEnabled && cfg.App.ClusterPrefork && !cfg.App.IsWorker
+	if !isPreforkMaster && cfg.App.WorkerIndex <= 0 {
+		jobsCtx, stopJobs := context.WithCancel(context.Background())
+		jobs.Start(jobsCtx)
+		defer func() {
+			stopJobs()
+			jobs.Wait()
+		}()
+	}
+
 	// New Relic setup (optional via env)
 	var nrApp *newrelic.Application
 	if cfg.Observability.NewRelicEnabled {
@@ -68,9 +134,9 @@ func main() {
 				newrelic.ConfigDistributedTracerEnabled(true),
 			)
 			if err != nil {
-				log.Printf("New Relic init failed: %v", err)
+				slog.Warn("New Relic init failed", "error", err)
 			} else {
-				log.Printf("New Relic enabled for app %s", appName)
+				slog.Info("New Relic enabled", "app", appName)
 			}
 		}
 	}
@@ -81,7 +147,7 @@ func main() {
 	if cfg.Logging.AccessLogPath != "" {
 		// ensure dir exists
 		if err := os.MkdirAll(filepath.Dir(cfg.Logging.AccessLogPath), 0o755); err != nil {
-			log.Printf("failed to create log dir: %v", err)
+			slog.Warn("Failed to create log dir", "error", err)
 		}
 		accessWriter := &lumberjack.Logger{
 			Filename:   cfg.Logging.AccessLogPath,
@@ -91,16 +157,26 @@ func main() {
 			Compress:   cfg.Logging.AccessLogCompress,
 		}
 		handler = mw.AccessLog(accessWriter)(handler)
-		log.Printf("Access log -> %s", cfg.Logging.AccessLogPath)
+		slog.Info("Writing access log", "path", cfg.Logging.AccessLogPath)
 	}
 
 	// Prefork mode: spawn N worker processes that listen on the same port using SO_REUSEPORT
-	if cfg.App.ClusterEnabled && cfg.App.ClusterPrefork && !cfg.App.IsWorker {
+	if isPreforkMaster {
 		workers := cfg.App.ClusterWorkers
 		if workers <= 0 {
 			workers = runtime.NumCPU()
 		}
-		log.Printf("Prefork mode enabled: starting %d workers on :%s", workers, cfg.App.Port)
+		slog.Info("Prefork mode enabled", "workers", workers, "port", cfg.App.Port)
+
+		// Workers serve their metrics on sockets in metricsDir; the master
+		// serves all of them on METRICS_PORT
+		var metricsDir string
+		if cfg.Observability.MetricsPort != "" {
+			if metricsDir, err = os.MkdirTemp("", "laondry-metrics-"); err != nil {
+				slog.Warn("[Metrics] Cannot create socket dir, metrics disabled", "error", err)
+			}
+		}
+		var metricsSockets []string
 
 		// Spawn workers
 		procs := make([]*exec.Cmd, 0, workers)
@@ -113,34 +189,48 @@ func main() {
 				"APP_WORKER_INDEX="+strconv.Itoa(i),
 				"APP_CLUSTER_ENABLED=true",
 			)
+			if metricsDir != "" {
+				socket := metrics.WorkerSocket(metricsDir, i)
+				cmd.Env = append(cmd.Env, "METRICS_SOCKET="+socket)
+				metricsSockets = append(metricsSockets, socket)
+			}
 			if err := cmd.Start(); err != nil {
-				log.Fatalf("failed to start worker %d: %v", i, err)
+				fatal("[master] Failed to start worker", "worker", i, "error", err)
 			}
-			log.Printf("[master] started worker %d pid %d", i, cmd.Process.Pid)
+			slog.Info("[master] Started worker", "worker", i, "pid", cmd.Process.Pid)
 			procs = append(procs, cmd)
 		}
+		if metricsDir != "" {
+			metricsServer := metrics.Serve("0.0.0.0:"+cfg.Observability.MetricsPort, metrics.NewWorkers(metricsSockets).Handler())
+			defer func() {
+				_ = metricsServer.Close()
+				_ = os.RemoveAll(metricsDir)
+			}()
+		}
 
 		// Wait for termination signal
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
-		log.Println("Master shutting down, signaling workers...")
+		slog.Info("[master] Shutting down, signaling workers")
 		for i, p := range procs {
 			if p.Process == nil {
 				continue
 			}
 			_ = p.Process.Signal(syscall.SIGTERM)
-			log.Printf("[master] signaled worker %d pid %d", i, p.Process.Pid)
+			slog.Info("[master] Signaled worker", "worker", i, "pid", p.Process.Pid)
 		}
 		// Optionally wait workers to exit
 		for i, p := range procs {
 			_ = p.Wait()
-			log.Printf("[master] worker %d exited", i)
+			slog.Info("[master] Worker exited", "worker", i)
 		}
-		log.Println("Master exited gracefully")
+		slog.Info("[master] Exited gracefully")
 		return
 	}
 
+	defer serveMetrics(cfg)()
+
 	// If this is a worker process (prefork), run a single server bound with REUSEPORT
 	if cfg.App.ClusterEnabled && cfg.App.IsWorker {
 		runWorkerReusePort(handler, cfg)
@@ -153,7 +243,7 @@ func main() {
 		if workers <= 0 {
 			workers = runtime.NumCPU()
 		}
-		log.Printf("Cluster mode enabled: %d workers on :%s", workers, cfg.App.Port)
+		slog.Info("Cluster mode enabled", "workers", workers, "port", cfg.App.Port)
 
 		var servers []*http.Server
 		var listeners []net.Listener
@@ -164,10 +254,10 @@ func main() {
 		for i := 0; i < workers; i++ {
 			ln, lerr := listenWithOptionalReusePort(cfg.App.ClusterReusePort, "0.0.0.0:"+cfg.App.Port)
 			if lerr != nil {
-				log.Printf("[worker %d] reuseport listen error: %v", i, lerr)
+				slog.Error("[worker] Reuseport listen error", "worker", i, "error", lerr)
 				// Fallback to single server if first worker fails to bind
 				if i == 0 {
-					log.Printf("Falling back to single-worker mode on :%s", cfg.App.Port)
+					slog.Warn("Falling back to single-worker mode", "port", cfg.App.Port)
 					startSingleServer(handler, cfg)
 					return
 				}
@@ -192,9 +282,9 @@ func main() {
 			i := i
 			go func() {
 				defer wg.Done()
-				log.Printf("[worker %d pid %d] listening on 0.0.0.0:%s", i, os.Getpid(), cfg.App.Port)
+				slog.Info("[worker] Listening", "worker", i, "pid", os.Getpid(), "addr", "0.0.0.0:"+cfg.App.Port)
 				if err := servers[i].Serve(listeners[i]); err != nil && err != http.ErrServerClosed {
-					log.Printf("[worker %d] server error: %v", i, err)
+					slog.Error("[worker] Server error", "worker", i, "error", err)
 				}
 			}()
 		}
@@ -203,16 +293,16 @@ func main() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
-		log.Println("Shutting down cluster...")
+		slog.Info("Shutting down cluster")
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 		for i, srv := range servers {
 			if err := srv.Shutdown(ctx); err != nil {
-				log.Printf("[worker %d] forced shutdown: %v", i, err)
+				slog.Warn("[worker] Forced shutdown", "worker", i, "error", err)
 			}
 		}
 		wg.Wait()
-		log.Println("Cluster exited gracefully")
+		slog.Info("Cluster exited gracefully")
 		return
 	}
 
@@ -220,6 +310,147 @@ func main() {
 	startSingleServer(handler, cfg)
 }
 
+// serveMetrics serves the metrics of this process on METRICS_PORT, or on the
+// socket the prefork master scrapes. Call the returned func to stop.
+func serveMetrics(cfg *config.Config) func() {
+	var srv *http.Server
+	switch {
+	case cfg.Observability.MetricsSocket != "":
+		var err error
+		if srv, err = metrics.ServeSocket(cfg.Observability.MetricsSocket); err != nil {
+			slog.Warn("[Metrics] Cannot serve metrics to the prefork master", "socket", cfg.Observability.MetricsSocket, "error", err)
+			return func() {}
+		}
+	case cfg.Observability.MetricsPort != "":
+		srv = metrics.Serve("0.0.0.0:"+cfg.Observability.MetricsPort, metrics.Handler())
+	default:
+		return func() {}
+	}
+	return func() { _ = srv.Close() }
+}
+
+// newAuthenticator builds the bearer token authenticator for AUTH_MODE. Local
+// modes without any secret or key verify tokens with core-api.
+func newAuthenticator(appCfg *config.Config, db *gorm.DB) mw.TokenAuthenticator {
+	cfg := &appCfg.Auth
+	if cfg.Mode == mw.AuthModeCoreAPI {
+		slog.Info("[Auth] Verifying tokens with core-api")
+		return newCoreAPIAuthenticator(appCfg)
+	}
+	if cfg.Mode != mw.AuthModeLocal && cfg.Mode != mw.AuthModeLocalWithFallback {
+		fatal("Unknown AUTH_MODE", "mode", cfg.Mode)
+	}
+
+	jwtCfg := mw.JWTConfig{
+		JWKSURL:     cfg.JWKSURL,
+		JWKSRefresh: time.Duration(cfg.JWKSRefreshSeconds) * time.Second,
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		Leeway:      time.Duration(cfg.LeewaySeconds) * time.Second,
+	}
+	for _, secret := range cfg.HS256Secrets {
+		jwtCfg.HS256Secrets = append(jwtCfg.HS256Secrets, []byte(secret))
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			fatal("Failed to read AUTH_JWT_PUBLIC_KEY_FILE", "error", err)
+		}
+		if jwtCfg.RS256Keys, err = mw.ParseRSAPublicKeys(data); err != nil {
+			fatal("Failed to parse AUTH_JWT_PUBLIC_KEY_FILE", "error", err)
+		}
+	}
+	if cfg.CheckTokenVersion {
+		jwtCfg.TokenVersions = mw.NewUserTokenVersions(db)
+	}
+
+	verifier, err := mw.NewJWTVerifier(jwtCfg)
+	if err != nil {
+		slog.Warn("[Auth] Cannot verify tokens locally, verifying them with core-api", "error", err)
+		return newCoreAPIAuthenticator(appCfg)
+	}
+	if cfg.Mode == mw.AuthModeLocalWithFallback {
+		slog.Info("[Auth] Verifying tokens locally, falling back to core-api")
+		return mw.FallbackAuthenticator{Local: verifier, Remote: newCoreAPIAuthenticator(appCfg)}
+	}
+	slog.Info("[Auth] Verifying tokens locally")
+	return verifier
+}
+
+// newCoreAPIAuthenticator caches core-api validations in Redis if REDIS_ADDR
+// is set, in memory otherwise
+func newCoreAPIAuthenticator(cfg *config.Config) mw.TokenAuthenticator {
+	if !cfg.Auth.CacheEnabled {
+		return mw.CoreAPIAuthenticator{}
+	}
+	var cache mw.TokenCache
+	if cfg.Redis.Addr != "" {
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			slog.Warn("[Auth] Redis connection failed, token cache not shared", "error", err)
+		} else {
+			slog.Info("[Auth] Using Redis token cache", "addr", cfg.Redis.Addr)
+			cache = mw.NewRedisTokenCache(rdb)
+		}
+	}
+	if cache == nil {
+		// A logout reaches one prefork worker only; the others would keep
+		// accepting the token from their own cache until the TTL
+		if cfg.App.ClusterEnabled && cfg.App.ClusterPrefork {
+			slog.Warn("[Auth] Token cache disabled: prefork workers cannot share an in-memory cache, set REDIS_ADDR")
+			return mw.CoreAPIAuthenticator{}
+		}
+		slog.Info("[Auth] Using in-memory token cache")
+		cache = mw.NewMemoryTokenCache()
+	}
+	return mw.NewCachingAuthenticator(mw.CoreAPIAuthenticator{}, cache,
+		time.Duration(cfg.Auth.CacheTTLSeconds)*time.Second,
+		time.Duration(cfg.Auth.CacheNegativeTTLSeconds)*time.Second)
+}
+
+// newRateLimiter keeps token buckets in Redis if REDIS_ADDR is set, in
+// memory otherwise
+func newRateLimiter(cfg *config.Config) *mw.RateLimiter {
+	if !cfg.RateLimit.Enabled {
+		slog.Info("[RateLimit] Rate limiting disabled")
+		return nil
+	}
+	var store mw.RateLimitStore
+	if cfg.Redis.Addr != "" {
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			slog.Warn("[RateLimit] Redis connection failed, using in-memory rate limits", "error", err)
+			store = mw.NewMemoryRateLimitStore()
+		} else {
+			slog.Info("[RateLimit] Using Redis rate limits", "addr", cfg.Redis.Addr)
+			store = mw.NewRedisRateLimitStore(rdb)
+		}
+	} else {
+		slog.Info("[RateLimit] Using in-memory rate limits (no Redis configured)")
+		store = mw.NewMemoryRateLimitStore()
+	}
+	rule := func(r config.RateLimitRule) mw.RateLimitRule {
+		return mw.RateLimitRule{PerMinute: r.PerMinute, Burst: r.Burst}
+	}
+	return mw.NewRateLimiter(store, map[string]mw.RateLimitRule{
+		mw.RateLimitQuote:        rule(cfg.RateLimit.Quote),
+		mw.RateLimitOrders:       rule(cfg.RateLimit.Orders),
+		mw.RateLimitNotification: rule(cfg.RateLimit.Notification),
+	}, cfg.RateLimit.TrustProxy)
+}
+
 // startSingleServer starts a single http.Server with ListenAndServe and graceful shutdown.
 func startSingleServer(handler http.Handler, cfg *config.Config) {
 	server := &http.Server{
@@ -231,9 +462,9 @@ func startSingleServer(handler http.Handler, cfg *config.Config) {
 	}
 
 	go func() {
-		log.Printf("Server is running on 0.0.0.0:%s", cfg.App.Port)
+		slog.Info("Server is running", "addr", server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("Failed to start server", "error", err)
 		}
 	}()
 
@@ -241,16 +472,16 @@ func startSingleServer(handler http.Handler, cfg *config.Config) {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", "error", err)
 	}
 
-	log.Println("Server exited gracefully")
+	slog.Info("Server exited gracefully")
 }
 
 // listenWithOptionalReusePort creates a listener on addr. If reusePort is true,
@@ -280,7 +511,7 @@ func listenWithOptionalReusePort(reusePort bool, addr string) (net.Listener, err
 func runWorkerReusePort(handler http.Handler, cfg *config.Config) {
 	ln, err := listenWithOptionalReusePort(cfg.App.ClusterReusePort, "0.0.0.0:"+cfg.App.Port)
 	if err != nil {
-		log.Fatalf("worker failed to bind :%s: %v", cfg.App.Port, err)
+		fatal("[worker] Failed to bind", "port", cfg.App.Port, "error", err)
 	}
 	srv := &http.Server{
 		Handler:      handler,
@@ -289,18 +520,24 @@ func runWorkerReusePort(handler http.Handler, cfg *config.Config) {
 		IdleTimeout:  60 * time.Second,
 	}
 	go func() {
-		log.Printf("[worker pid %d] listening on 0.0.0.0:%s", os.Getpid(), cfg.App.Port)
+		slog.Info("[worker] Listening", "pid", os.Getpid(), "addr", "0.0.0.0:"+cfg.App.Port)
 		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("worker server error: %v", err)
+			fatal("[worker] Server error", "error", err)
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Printf("[worker pid %d] shutting down...", os.Getpid())
+	slog.Info("[worker] Shutting down", "pid", os.Getpid())
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctx)
-	log.Printf("[worker pid %d] exited", os.Getpid())
+	slog.Info("[worker] Exited", "pid", os.Getpid())
+}
+
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
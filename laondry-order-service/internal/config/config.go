@@ -13,18 +13,134 @@ type Config struct {
 	Logging       LoggingConfig
 	Redis         RedisConfig
 	External      ExternalConfig
+	Auth          AuthConfig
 	Midtrans      MidtransConfig
+	Reconcile     ReconcileConfig
+	Outbox        OutboxConfig
+	Wallet        WalletConfig
+	Notification  NotificationConfig
+	Webhook       WebhookConfig
+	RateLimit     RateLimitConfig
+	Idempotency   IdempotencyConfig
 }
 
 type ExternalConfig struct {
 	CoreAPIURL string
 }
 
+// AuthConfig controls how bearer tokens are verified. Tokens are issued by
+// core-api; see middleware.JWTVerifier.
+type AuthConfig struct {
+	// Mode is local, core_api or local_with_fallback. The fallback mode asks
+	// core-api only for tokens no local key can verify.
+	Mode string
+	// HS256Secrets are the shared secrets of core-api's JWT_SECRET; more than
+	// one allows rotating it
+	HS256Secrets []string
+	// PublicKeyFile is a PEM file with one or more RS256 public keys
+	PublicKeyFile      string
+	JWKSURL            string
+	JWKSRefreshSeconds int
+	Issuer             string
+	Audience           string
+	LeewaySeconds      int
+	// CheckTokenVersion rejects tokens older than the user's token_version,
+	// which core-api bumps on logout-all and password or PIN changes
+	CheckTokenVersion bool
+
+	// Cache core-api validations for CacheTTLSeconds, rejections for
+	// CacheNegativeTTLSeconds. Logout-all reaches cached tokens only after
+	// the TTL. In prefork mode the cache needs REDIS_ADDR and is off
+	// without it: an in-memory cache per worker would miss logouts.
+	CacheEnabled            bool
+	CacheTTLSeconds         int
+	CacheNegativeTTLSeconds int
+}
+
 type MidtransConfig struct {
 	ServerKey       string
 	ClientKey       string
 	IsProduction    bool
 	EnabledPayments []string
+	// Optional override of the Midtrans API host (e.g. a local fake for tests)
+	APIBaseURL string
+	// CredentialsKey is the base64 AES-256 key that encrypts per-outlet
+	// merchant server keys at rest
+	CredentialsKey string
+	// Surcharges lists per-method payment fees, e.g.
+	// "credit_card:2.9%+2000:customer,gopay:2%"; see package surcharge
+	Surcharges string
+}
+
+// ReconcileConfig controls the background job that re-checks PENDING
+// payment transactions against Midtrans.
+type ReconcileConfig struct {
+	Enabled           bool
+	IntervalSeconds   int
+	StaleAfterMinutes int
+	BatchSize         int
+}
+
+// WalletConfig controls customer wallet top-ups and refunds.
+type WalletConfig struct {
+	TopupMinAmount float64
+	TopupMaxAmount float64
+	// RefundCanceledOrders credits settled payments of canceled orders to the
+	// customer's wallet instead of refunding them through the gateway
+	RefundCanceledOrders bool
+}
+
+// OutboxConfig controls delivery of transactional outbox messages.
+type OutboxConfig struct {
+	PollIntervalSeconds int
+	MaxAttempts         int
+}
+
+// NotificationConfig controls customer notifications of order and payment
+// events. Channels without credentials are skipped.
+type NotificationConfig struct {
+	// Channels lists the enabled channels: whatsapp, sms, email, push, log.
+	// The log channel records every notification regardless of opt-in.
+	Channels            []string
+	DefaultLanguage     string
+	PollIntervalSeconds int
+	MaxAttempts         int
+	// LogFile is where the log channel appends messages; empty logs them
+	LogFile string
+
+	// WhatsApp Cloud API
+	WhatsAppAPIURL        string
+	WhatsAppPhoneNumberID string
+	WhatsAppAccessToken   string
+
+	// Twilio-compatible SMS API
+	SMSAPIURL     string
+	SMSAccountSID string
+	SMSAuthToken  string
+	SMSFrom       string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Expo-compatible push API
+	PushAPIURL      string
+	PushAccessToken string
+}
+
+// WebhookConfig controls outgoing webhooks to partner systems
+type WebhookConfig struct {
+	// SecretsKey is the base64 AES-256 key that encrypts subscription signing
+	// secrets. Partner webhooks are disabled when it is empty.
+	SecretsKey          string
+	PollIntervalSeconds int
+	MaxAttempts         int // per delivery, then it is marked FAILED
+	// DisableAfterFailures deactivates a subscription after this many failed
+	// attempts in a row; 0 never disables
+	DisableAfterFailures int
+	TimeoutSeconds       int
 }
 
 type AppConfig struct {
@@ -58,9 +174,21 @@ type ObservabilityConfig struct {
 	NewRelicEnabled bool
 	NewRelicLicense string
 	NewRelicAppName string
+
+	// MetricsPort serves Prometheus metrics on /metrics, apart from the API;
+	// empty disables them. In prefork mode the master serves the metrics of
+	// every worker.
+	MetricsPort string
+	// MetricsSocket is where a prefork worker serves its metrics to the
+	// master (set internally)
+	MetricsSocket string
 }
 
 type LoggingConfig struct {
+	// Level is debug, info, warn or error; Format is json or text
+	Level  string
+	Format string
+
 	AccessLogPath       string
 	AccessLogMaxSizeMB  int
 	AccessLogMaxBackups int
@@ -68,6 +196,32 @@ type LoggingConfig struct {
 	AccessLogCompress   bool
 }
 
+// RateLimitConfig sets the token buckets of rate-limited route groups. Every
+// caller (API key, user, or client IP when anonymous) has its own bucket per
+// group, shared by all instances when REDIS_ADDR is set.
+type RateLimitConfig struct {
+	Enabled bool
+	// TrustProxy takes the client IP from X-Real-IP or X-Forwarded-For. Only
+	// enable behind a proxy that sets them.
+	TrustProxy   bool
+	Quote        RateLimitRule // POST /quote
+	Orders       RateLimitRule // /orders
+	Notification RateLimitRule // Midtrans payment notifications, per IP
+}
+
+// RateLimitRule allows bursts of Burst requests refilled at PerMinute;
+// PerMinute 0 disables the limit
+type RateLimitRule struct {
+	PerMinute int
+	Burst     int
+}
+
+// IdempotencyConfig sets how long responses to requests with an
+// Idempotency-Key are replayed
+type IdempotencyConfig struct {
+	TTLHours int
+}
+
 type RedisConfig struct {
 	Addr     string
 	Password string
@@ -106,7 +260,11 @@ func LoadConfig() *Config {
 	viper.SetDefault("NEW_RELIC_ENABLED", true)
 	viper.SetDefault("NEW_RELIC_LICENSE", "")
 	viper.SetDefault("NEW_RELIC_APP_NAME", "")
+	viper.SetDefault("METRICS_PORT", "9464")
+	viper.SetDefault("METRICS_SOCKET", "")
 
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
 	viper.SetDefault("ACCESS_LOG_PATH", "logs/access.log")
 	viper.SetDefault("ACCESS_LOG_MAX_SIZE_MB", 10)
 	viper.SetDefault("ACCESS_LOG_MAX_BACKUPS", 7)
@@ -118,12 +276,77 @@ func LoadConfig() *Config {
 	viper.SetDefault("REDIS_DB", 0)
 
 	viper.SetDefault("CORE_API_URL", "http://192.168.1.20:8000/api/v1")
+	viper.SetDefault("AUTH_MODE", "local")
+	// Comma-separated list, newest first
+	viper.SetDefault("AUTH_JWT_SECRETS", "")
+	viper.SetDefault("AUTH_JWT_PUBLIC_KEY_FILE", "")
+	viper.SetDefault("AUTH_JWKS_URL", "")
+	viper.SetDefault("AUTH_JWKS_REFRESH_SECONDS", 3600)
+	viper.SetDefault("AUTH_JWT_ISSUER", "")
+	viper.SetDefault("AUTH_JWT_AUDIENCE", "")
+	viper.SetDefault("AUTH_JWT_LEEWAY_SECONDS", 30)
+	viper.SetDefault("AUTH_CHECK_TOKEN_VERSION", true)
+	viper.SetDefault("AUTH_CACHE_ENABLED", true)
+	viper.SetDefault("AUTH_CACHE_TTL_SECONDS", 60)
+	viper.SetDefault("AUTH_CACHE_NEGATIVE_TTL_SECONDS", 10)
 	// Midtrans defaults (sandbox)
 	viper.SetDefault("MIDTRANS_SERVER_KEY", "")
 	viper.SetDefault("MIDTRANS_CLIENT_KEY", "")
 	viper.SetDefault("MIDTRANS_IS_PRODUCTION", false)
 	// Comma-separated list, e.g.: "gopay,qris,bca_va,bni_va,bri_va,credit_card"
 	viper.SetDefault("MIDTRANS_ENABLED_PAYMENTS", "")
+	viper.SetDefault("MIDTRANS_API_BASE_URL", "")
+	viper.SetDefault("MIDTRANS_CREDENTIALS_KEY", "")
+	viper.SetDefault("PAYMENT_SURCHARGES", "")
+
+	viper.SetDefault("PAYMENT_RECONCILE_ENABLED", true)
+	viper.SetDefault("PAYMENT_RECONCILE_INTERVAL_SECONDS", 300)
+	viper.SetDefault("PAYMENT_RECONCILE_STALE_MINUTES", 15)
+	viper.SetDefault("PAYMENT_RECONCILE_BATCH_SIZE", 50)
+
+	viper.SetDefault("OUTBOX_POLL_INTERVAL_SECONDS", 10)
+	viper.SetDefault("OUTBOX_MAX_ATTEMPTS", 10)
+
+	viper.SetDefault("WALLET_TOPUP_MIN_AMOUNT", 10000)
+	viper.SetDefault("WALLET_TOPUP_MAX_AMOUNT", 5000000)
+	viper.SetDefault("WALLET_REFUND_CANCELED_ORDERS", true)
+
+	viper.SetDefault("NOTIFY_CHANNELS", "log")
+	viper.SetDefault("NOTIFY_DEFAULT_LANGUAGE", "id")
+	viper.SetDefault("NOTIFY_POLL_INTERVAL_SECONDS", 10)
+	viper.SetDefault("NOTIFY_MAX_ATTEMPTS", 5)
+	viper.SetDefault("NOTIFY_LOG_FILE", "")
+	viper.SetDefault("WHATSAPP_API_URL", "https://graph.facebook.com/v19.0")
+	viper.SetDefault("WHATSAPP_PHONE_NUMBER_ID", "")
+	viper.SetDefault("WHATSAPP_ACCESS_TOKEN", "")
+	viper.SetDefault("SMS_API_URL", "https://api.twilio.com/2010-04-01")
+	viper.SetDefault("SMS_ACCOUNT_SID", "")
+	viper.SetDefault("SMS_AUTH_TOKEN", "")
+	viper.SetDefault("SMS_FROM", "")
+	viper.SetDefault("SMTP_HOST", "")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_USERNAME", "")
+	viper.SetDefault("SMTP_PASSWORD", "")
+	viper.SetDefault("SMTP_FROM", "")
+	viper.SetDefault("PUSH_API_URL", "https://exp.host/--/api/v2/push/send")
+	viper.SetDefault("PUSH_ACCESS_TOKEN", "")
+
+	viper.SetDefault("WEBHOOK_SECRETS_KEY", "")
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL_SECONDS", 10)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_DISABLE_AFTER_FAILURES", 20)
+	viper.SetDefault("WEBHOOK_TIMEOUT_SECONDS", 10)
+
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_TRUST_PROXY", false)
+	viper.SetDefault("RATE_LIMIT_QUOTE_PER_MINUTE", 60)
+	viper.SetDefault("RATE_LIMIT_QUOTE_BURST", 20)
+	viper.SetDefault("RATE_LIMIT_ORDERS_PER_MINUTE", 120)
+	viper.SetDefault("RATE_LIMIT_ORDERS_BURST", 40)
+	viper.SetDefault("RATE_LIMIT_NOTIFICATION_PER_MINUTE", 600)
+	viper.SetDefault("RATE_LIMIT_NOTIFICATION_BURST", 100)
+
+	viper.SetDefault("IDEMPOTENCY_TTL_HOURS", 24)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Println("Info: .env not found or unreadable, relying on environment variables")
@@ -161,8 +384,12 @@ func LoadConfig() *Config {
 			NewRelicEnabled: viper.GetBool("NEW_RELIC_ENABLED"),
 			NewRelicLicense: viper.GetString("NEW_RELIC_LICENSE"),
 			NewRelicAppName: viper.GetString("NEW_RELIC_APP_NAME"),
+			MetricsPort:     viper.GetString("METRICS_PORT"),
+			MetricsSocket:   viper.GetString("METRICS_SOCKET"),
 		},
 		Logging: LoggingConfig{
+			Level:               viper.GetString("LOG_LEVEL"),
+			Format:              viper.GetString("LOG_FORMAT"),
 			AccessLogPath:       viper.GetString("ACCESS_LOG_PATH"),
 			AccessLogMaxSizeMB:  viper.GetInt("ACCESS_LOG_MAX_SIZE_MB"),
 			AccessLogMaxBackups: viper.GetInt("ACCESS_LOG_MAX_BACKUPS"),
@@ -177,11 +404,90 @@ func LoadConfig() *Config {
 		External: ExternalConfig{
 			CoreAPIURL: viper.GetString("CORE_API_URL"),
 		},
+		Auth: AuthConfig{
+			Mode:                    viper.GetString("AUTH_MODE"),
+			HS256Secrets:            parseCSV(viper.GetString("AUTH_JWT_SECRETS")),
+			PublicKeyFile:           viper.GetString("AUTH_JWT_PUBLIC_KEY_FILE"),
+			JWKSURL:                 viper.GetString("AUTH_JWKS_URL"),
+			JWKSRefreshSeconds:      viper.GetInt("AUTH_JWKS_REFRESH_SECONDS"),
+			Issuer:                  viper.GetString("AUTH_JWT_ISSUER"),
+			Audience:                viper.GetString("AUTH_JWT_AUDIENCE"),
+			LeewaySeconds:           viper.GetInt("AUTH_JWT_LEEWAY_SECONDS"),
+			CheckTokenVersion:       viper.GetBool("AUTH_CHECK_TOKEN_VERSION"),
+			CacheEnabled:            viper.GetBool("AUTH_CACHE_ENABLED"),
+			CacheTTLSeconds:         viper.GetInt("AUTH_CACHE_TTL_SECONDS"),
+			CacheNegativeTTLSeconds: viper.GetInt("AUTH_CACHE_NEGATIVE_TTL_SECONDS"),
+		},
 		Midtrans: MidtransConfig{
 			ServerKey:       viper.GetString("MIDTRANS_SERVER_KEY"),
 			ClientKey:       viper.GetString("MIDTRANS_CLIENT_KEY"),
 			IsProduction:    viper.GetBool("MIDTRANS_IS_PRODUCTION"),
 			EnabledPayments: parseCSV(viper.GetString("MIDTRANS_ENABLED_PAYMENTS")),
+			APIBaseURL:      viper.GetString("MIDTRANS_API_BASE_URL"),
+			CredentialsKey:  viper.GetString("MIDTRANS_CREDENTIALS_KEY"),
+			Surcharges:      viper.GetString("PAYMENT_SURCHARGES"),
+		},
+		Reconcile: ReconcileConfig{
+			Enabled:           viper.GetBool("PAYMENT_RECONCILE_ENABLED"),
+			IntervalSeconds:   viper.GetInt("PAYMENT_RECONCILE_INTERVAL_SECONDS"),
+			StaleAfterMinutes: viper.GetInt("PAYMENT_RECONCILE_STALE_MINUTES"),
+			BatchSize:         viper.GetInt("PAYMENT_RECONCILE_BATCH_SIZE"),
+		},
+		Outbox: OutboxConfig{
+			PollIntervalSeconds: viper.GetInt("OUTBOX_POLL_INTERVAL_SECONDS"),
+			MaxAttempts:         viper.GetInt("OUTBOX_MAX_ATTEMPTS"),
+		},
+		Wallet: WalletConfig{
+			TopupMinAmount:       viper.GetFloat64("WALLET_TOPUP_MIN_AMOUNT"),
+			TopupMaxAmount:       viper.GetFloat64("WALLET_TOPUP_MAX_AMOUNT"),
+			RefundCanceledOrders: viper.GetBool("WALLET_REFUND_CANCELED_ORDERS"),
+		},
+		Notification: NotificationConfig{
+			Channels:              parseCSV(viper.GetString("NOTIFY_CHANNELS")),
+			DefaultLanguage:       viper.GetString("NOTIFY_DEFAULT_LANGUAGE"),
+			PollIntervalSeconds:   viper.GetInt("NOTIFY_POLL_INTERVAL_SECONDS"),
+			MaxAttempts:           viper.GetInt("NOTIFY_MAX_ATTEMPTS"),
+			LogFile:               viper.GetString("NOTIFY_LOG_FILE"),
+			WhatsAppAPIURL:        viper.GetString("WHATSAPP_API_URL"),
+			WhatsAppPhoneNumberID: viper.GetString("WHATSAPP_PHONE_NUMBER_ID"),
+			WhatsAppAccessToken:   viper.GetString("WHATSAPP_ACCESS_TOKEN"),
+			SMSAPIURL:             viper.GetString("SMS_API_URL"),
+			SMSAccountSID:         viper.GetString("SMS_ACCOUNT_SID"),
+			SMSAuthToken:          viper.GetString("SMS_AUTH_TOKEN"),
+			SMSFrom:               viper.GetString("SMS_FROM"),
+			SMTPHost:              viper.GetString("SMTP_HOST"),
+			SMTPPort:              viper.GetInt("SMTP_PORT"),
+			SMTPUsername:          viper.GetString("SMTP_USERNAME"),
+			SMTPPassword:          viper.GetString("SMTP_PASSWORD"),
+			SMTPFrom:              viper.GetString("SMTP_FROM"),
+			PushAPIURL:            viper.GetString("PUSH_API_URL"),
+			PushAccessToken:       viper.GetString("PUSH_ACCESS_TOKEN"),
+		},
+		Webhook: WebhookConfig{
+			SecretsKey:           viper.GetString("WEBHOOK_SECRETS_KEY"),
+			PollIntervalSeconds:  viper.GetInt("WEBHOOK_POLL_INTERVAL_SECONDS"),
+			MaxAttempts:          viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			DisableAfterFailures: viper.GetInt("WEBHOOK_DISABLE_AFTER_FAILURES"),
+			TimeoutSeconds:       viper.GetInt("WEBHOOK_TIMEOUT_SECONDS"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:    viper.GetBool("RATE_LIMIT_ENABLED"),
+			TrustProxy: viper.GetBool("RATE_LIMIT_TRUST_PROXY"),
+			Quote: RateLimitRule{
+				PerMinute: viper.GetInt("RATE_LIMIT_QUOTE_PER_MINUTE"),
+				Burst:     viper.GetInt("RATE_LIMIT_QUOTE_BURST"),
+			},
+			Orders: RateLimitRule{
+				PerMinute: viper.GetInt("RATE_LIMIT_ORDERS_PER_MINUTE"),
+				Burst:     viper.GetInt("RATE_LIMIT_ORDERS_BURST"),
+			},
+			Notification: RateLimitRule{
+				PerMinute: viper.GetInt("RATE_LIMIT_NOTIFICATION_PER_MINUTE"),
+				Burst:     viper.GetInt("RATE_LIMIT_NOTIFICATION_BURST"),
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours: viper.GetInt("IDEMPOTENCY_TTL_HOURS"),
 		},
 	}
 }
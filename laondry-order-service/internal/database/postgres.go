@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/metrics"
 )
 
 func NewPostgresConnection(cfg *config.DatabaseConfig) (*gorm.DB, error) {
@@ -50,6 +52,10 @@ func NewPostgresConnection(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database instance: %w", err)
@@ -63,6 +69,6 @@ func NewPostgresConnection(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Database connection established successfully")
+	slog.Info("[Database] Connection established")
 	return db, nil
 }
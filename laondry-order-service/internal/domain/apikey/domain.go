@@ -0,0 +1,28 @@
+package apikey
+
+import (
+	"laondry-order-service/internal/config"
+	ahandler "laondry-order-service/internal/domain/apikey/handler/rest"
+	arepo "laondry-order-service/internal/domain/apikey/repository"
+	aservice "laondry-order-service/internal/domain/apikey/service"
+	"laondry-order-service/pkg/validator"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyDomain struct {
+	Repository arepo.APIKeyRepository
+	Service    aservice.APIKeyService
+	Handler    *ahandler.APIKeyHandler
+}
+
+func NewAPIKeyDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB) *APIKeyDomain {
+	repo := arepo.NewAPIKeyRepository(db)
+	svc := aservice.NewAPIKeyService(repo)
+
+	return &APIKeyDomain{
+		Repository: repo,
+		Service:    svc,
+		Handler:    ahandler.NewAPIKeyHandler(svc, v),
+	}
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/apikey/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+	"laondry-order-service/pkg/validator"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+	validator     *validator.Validator
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService, validator *validator.Validator) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		validator:     validator,
+	}
+}
+
+// GET /api/v1/admin/api-keys
+// Lists service API keys, revoked ones included. Admin only.
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyService.List(r.Context())
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "API keys retrieved successfully", keys)
+}
+
+// POST /api/v1/admin/api-keys
+// Issues an API key with the given permissions, optionally limited to one
+// outlet and an expiry date. The key is only returned here.
+func (h *APIKeyHandler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req service.IssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request payload", err.Error())
+		return
+	}
+	if validationErrors := h.validator.Validate(req); len(validationErrors) > 0 {
+		response.UnprocessableEntity(w, "Validation failed", validationErrors)
+		return
+	}
+	if userID, ok := currentUserID(r); ok {
+		req.CreatedBy = &userID
+	}
+
+	key, err := h.apiKeyService.Issue(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	mw.SetAccessField(r, "issued_api_key_id", key.ID.String())
+
+	response.Created(w, "API key issued successfully", key)
+}
+
+// GET /api/v1/admin/api-keys/{id}
+func (h *APIKeyHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyService.Get(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "API key retrieved successfully", key)
+}
+
+// DELETE /api/v1/admin/api-keys/{id}
+// Revokes an API key; it is kept for the audit trail
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyService.Revoke(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "API key revoked successfully", key)
+}
+
+func apiKeyID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid API key ID", err.Error())
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// currentUserID returns the authenticated user's ID
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	Save(ctx context.Context, key *entity.APIKey) error
+	// FindByID and FindByPrefix return gorm.ErrRecordNotFound for unknown keys
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+	// List returns every key, revoked ones included, newest first
+	List(ctx context.Context) ([]entity.APIKey, error)
+	// TouchLastUsed records a use of the key unless one was recorded after
+	// since, so busy keys do not write on every request
+	TouchLastUsed(ctx context.Context, id uuid.UUID, ip string, now, since time.Time) error
+}
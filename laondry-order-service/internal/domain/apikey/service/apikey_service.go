@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	mw "laondry-order-service/internal/middleware"
+)
+
+type APIKeyService interface {
+	// AuthenticateAPIKey resolves an X-Api-Key header for middleware.Auth.
+	// Unknown, revoked and expired keys fail with middleware.ErrInvalidAPIKey.
+	mw.APIKeyAuthenticator
+
+	// Issue creates a key; the key itself is only returned here
+	Issue(ctx context.Context, req IssueRequest) (*APIKeyResponse, error)
+	List(ctx context.Context) ([]APIKeyResponse, error)
+	Get(ctx context.Context, id uuid.UUID) (*APIKeyResponse, error)
+	// Revoke disables a key for good; revoking it again is a no-op
+	Revoke(ctx context.Context, id uuid.UUID) (*APIKeyResponse, error)
+}
+
+type IssueRequest struct {
+	Name        string     `json:"name" validate:"required,max=100"`
+	Permissions []string   `json:"permissions" validate:"required,min=1,dive,oneof=orders:read orders:create orders:update orders:status"`
+	OutletID    *uuid.UUID `json:"outlet_id"`  // only orders of this outlet; all outlets when empty
+	ExpiresAt   *time.Time `json:"expires_at"` // never expires when empty
+	CreatedBy   *uuid.UUID `json:"-"`
+}
+
+type APIKeyResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Permissions []string   `json:"permissions"`
+	OutletID    *uuid.UUID `json:"outlet_id"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  *string    `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   *uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// Key is only returned when it is issued
+	Key string `json:"key,omitempty"`
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/apikey/repository"
+	"laondry-order-service/internal/entity"
+	mw "laondry-order-service/internal/middleware"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+const (
+	// Keys look like lok_<12 hex>_<64 hex>; the part before the last
+	// underscore is the prefix stored in clear
+	keyPrefix      = "lok_"
+	prefixBytes    = 6
+	secretBytes    = 32
+	lastUsedPeriod = time.Minute // last_used_at is updated at most this often
+)
+
+type apiKeyService struct {
+	repo repository.APIKeyRepository
+	now  func() time.Time
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo: repo, now: time.Now}
+}
+
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key, remoteIP string) (*mw.UserClaims, error) {
+	prefix, ok := splitPrefix(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed key", mw.ErrInvalidAPIKey)
+	}
+	k, err := s.repo.FindByPrefix(ctx, prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown key %s", mw.ErrInvalidAPIKey, prefix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key %s: %w", prefix, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(k.KeyHash)) != 1 {
+		return nil, fmt.Errorf("%w: wrong secret for %s", mw.ErrInvalidAPIKey, prefix)
+	}
+	now := s.now()
+	if k.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: %s was revoked", mw.ErrInvalidAPIKey, prefix)
+	}
+	if !k.Usable(now) {
+		return nil, fmt.Errorf("%w: %s expired", mw.ErrInvalidAPIKey, prefix)
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, k.ID, remoteIP, now, now.Add(-lastUsedPeriod)); err != nil {
+		slog.WarnContext(ctx, "[APIKey] Failed to record use", "api_key_prefix", prefix, "error", err)
+	}
+
+	claims := &mw.UserClaims{
+		Role:         mw.RoleService,
+		APIKeyID:     k.ID.String(),
+		APIKeyPrefix: k.Prefix,
+		Permissions:  k.PermissionList(),
+	}
+	if k.OutletID != nil {
+		claims.Outlets = []string{k.OutletID.String()}
+	}
+	return claims, nil
+}
+
+func (s *apiKeyService) Issue(ctx context.Context, req IssueRequest) (*APIKeyResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, appErrors.BadRequest("expires_at must be in the future", nil)
+	}
+	key, prefix, err := newKey()
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to generate API key", err)
+	}
+
+	k := &entity.APIKey{
+		Name:        strings.TrimSpace(req.Name),
+		Prefix:      prefix,
+		KeyHash:     hashKey(key),
+		Permissions: joinPermissions(req.Permissions),
+		OutletID:    req.OutletID,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   req.CreatedBy,
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		return nil, appErrors.InternalServerError("Failed to create API key", err)
+	}
+
+	slog.InfoContext(ctx, "[APIKey] Key issued", "api_key_prefix", k.Prefix, "api_key_id", k.ID, "name", k.Name, "permissions", k.Permissions)
+	resp := apiKeyResponse(k)
+	resp.Key = key
+	return resp, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]APIKeyResponse, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list API keys", err)
+	}
+	list := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		list = append(list, *apiKeyResponse(&keys[i]))
+	}
+	return list, nil
+}
+
+func (s *apiKeyService) Get(ctx context.Context, id uuid.UUID) (*APIKeyResponse, error) {
+	k, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyResponse(k), nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id uuid.UUID) (*APIKeyResponse, error) {
+	k, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt == nil {
+		now := s.now()
+		k.RevokedAt = &now
+		if err := s.repo.Save(ctx, k); err != nil {
+			return nil, appErrors.InternalServerError("Failed to revoke API key", err)
+		}
+		slog.InfoContext(ctx, "[APIKey] Key revoked", "api_key_prefix", k.Prefix, "api_key_id", k.ID)
+	}
+	return apiKeyResponse(k), nil
+}
+
+func (s *apiKeyService) find(ctx context.Context, id uuid.UUID) (*entity.APIKey, error) {
+	k, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appErrors.NotFound("API key not found", err)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load API key", err)
+	}
+	return k, nil
+}
+
+// newKey returns a random key and its prefix
+func newKey() (key, prefix string, err error) {
+	buf := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	prefix = keyPrefix + hex.EncodeToString(buf[:prefixBytes])
+	return prefix + "_" + hex.EncodeToString(buf[prefixBytes:]), prefix, nil
+}
+
+// splitPrefix returns the prefix of a key in the format made by newKey
+func splitPrefix(key string) (string, bool) {
+	i := strings.LastIndexByte(key, '_')
+	if !strings.HasPrefix(key, keyPrefix) || i <= len(keyPrefix) || i == len(key)-1 {
+		return "", false
+	}
+	return key[:i], true
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// joinPermissions stores permissions without duplicates, in the order of
+// middleware.APIKeyPermissions
+func joinPermissions(permissions []string) string {
+	var list []string
+	for _, p := range mw.APIKeyPermissions {
+		if slices.Contains(permissions, p) {
+			list = append(list, p)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+func apiKeyResponse(k *entity.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Permissions: k.PermissionList(),
+		OutletID:    k.OutletID,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		LastUsedIP:  k.LastUsedIP,
+		RevokedAt:   k.RevokedAt,
+		CreatedBy:   k.CreatedBy,
+		CreatedAt:   k.CreatedAt,
+	}
+}
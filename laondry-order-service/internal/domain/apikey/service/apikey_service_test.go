@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/domain/apikey/repository"
+	"laondry-order-service/internal/entity"
+	mw "laondry-order-service/internal/middleware"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func setupAPIKeyService(t *testing.T) (*apiKeyService, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&entity.APIKey{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	return NewAPIKeyService(repository.NewAPIKeyRepository(db)).(*apiKeyService), db
+}
+
+func issue(t *testing.T, svc *apiKeyService, req IssueRequest) *APIKeyResponse {
+	t.Helper()
+	key, err := svc.Issue(context.Background(), req)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	return key
+}
+
+func TestAPIKeyService_IssueAndAuthenticate(t *testing.T) {
+	svc, db := setupAPIKeyService(t)
+	ctx := context.Background()
+	outletID := uuid.New()
+
+	key := issue(t, svc, IssueRequest{
+		Name:        "core-api",
+		Permissions: []string{mw.PermOrdersStatus, mw.PermOrdersRead, mw.PermOrdersRead},
+		OutletID:    &outletID,
+	})
+	if !strings.HasPrefix(key.Key, key.Prefix+"_") {
+		t.Fatalf("key %q does not start with prefix %q", key.Key, key.Prefix)
+	}
+	if got := strings.Join(key.Permissions, ","); got != "orders:read,orders:status" {
+		t.Errorf("permissions = %s", got)
+	}
+
+	var stored entity.APIKey
+	db.First(&stored, "id = ?", key.ID)
+	if stored.KeyHash == "" || strings.Contains(stored.KeyHash, key.Key) {
+		t.Errorf("key must be stored hashed, got %q", stored.KeyHash)
+	}
+
+	user, err := svc.AuthenticateAPIKey(ctx, key.Key, "10.0.0.7")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if !user.IsAPIKey() || user.APIKeyID != key.ID.String() || user.Role != mw.RoleService {
+		t.Errorf("unexpected claims: %+v", user)
+	}
+	if !user.HasPermission(mw.PermOrdersRead) || user.HasPermission(mw.PermOrdersCreate) {
+		t.Errorf("unexpected permissions: %v", user.Permissions)
+	}
+	if !mw.CanAccessOutlet(user, outletID.String()) || mw.CanAccessOutlet(user, uuid.NewString()) {
+		t.Errorf("key must be limited to outlet %s, got %v", outletID, user.Outlets)
+	}
+
+	got, _ := svc.Get(ctx, key.ID)
+	if got.LastUsedAt == nil || got.LastUsedIP == nil || *got.LastUsedIP != "10.0.0.7" {
+		t.Errorf("last use not recorded: %+v", got)
+	}
+	if got.Key != "" {
+		t.Error("the key must only be returned when issued")
+	}
+}
+
+func TestAPIKeyService_RejectsBadKeys(t *testing.T) {
+	svc, _ := setupAPIKeyService(t)
+	ctx := context.Background()
+	key := issue(t, svc, IssueRequest{Name: "jobs", Permissions: []string{mw.PermOrdersRead}})
+
+	for name, bad := range map[string]string{
+		"empty":          "",
+		"malformed":      "not-a-key",
+		"unknown prefix": "lok_000000000000_" + strings.Repeat("0", 64),
+		"wrong secret":   key.Prefix + "_" + strings.Repeat("0", 64),
+	} {
+		if _, err := svc.AuthenticateAPIKey(ctx, bad, "10.0.0.7"); !errors.Is(err, mw.ErrInvalidAPIKey) {
+			t.Errorf("%s: err = %v, want ErrInvalidAPIKey", name, err)
+		}
+	}
+}
+
+func TestAPIKeyService_ExpiryAndRevocation(t *testing.T) {
+	svc, _ := setupAPIKeyService(t)
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	expires := now.Add(time.Hour)
+	key := issue(t, svc, IssueRequest{Name: "jobs", Permissions: []string{mw.PermOrdersRead}, ExpiresAt: &expires})
+	if _, err := svc.AuthenticateAPIKey(ctx, key.Key, ""); err != nil {
+		t.Fatalf("authenticate before expiry: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := svc.AuthenticateAPIKey(ctx, key.Key, ""); !errors.Is(err, mw.ErrInvalidAPIKey) {
+		t.Errorf("expired key: err = %v", err)
+	}
+
+	past := now.Add(-time.Minute)
+	var appErr *appErrors.AppError
+	if _, err := svc.Issue(ctx, IssueRequest{Name: "jobs", Permissions: []string{mw.PermOrdersRead}, ExpiresAt: &past}); !errors.As(err, &appErr) {
+		t.Errorf("issuing an expired key: err = %v", err)
+	}
+
+	other := issue(t, svc, IssueRequest{Name: "core-api", Permissions: []string{mw.PermOrdersRead}})
+	revoked, err := svc.Revoke(ctx, other.ID)
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("revoke: %+v, %v", revoked, err)
+	}
+	again, err := svc.Revoke(ctx, other.ID)
+	if err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+		t.Errorf("revoking twice must keep the first revocation: %+v, %v", again, err)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, other.Key, ""); !errors.Is(err, mw.ErrInvalidAPIKey) {
+		t.Errorf("revoked key: err = %v", err)
+	}
+	if _, err := svc.Revoke(ctx, uuid.New()); !errors.As(err, &appErr) || appErr.StatusCode != 404 {
+		t.Errorf("unknown key: err = %v", err)
+	}
+}
+
+func TestAPIKeyService_ThrottlesLastUsed(t *testing.T) {
+	svc, _ := setupAPIKeyService(t)
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	key := issue(t, svc, IssueRequest{Name: "jobs", Permissions: []string{mw.PermOrdersRead}})
+
+	lastUsedIP := func() string {
+		got, _ := svc.Get(ctx, key.ID)
+		return *got.LastUsedIP
+	}
+	svc.AuthenticateAPIKey(ctx, key.Key, "10.0.0.1")
+	now = now.Add(10 * time.Second)
+	svc.AuthenticateAPIKey(ctx, key.Key, "10.0.0.2")
+	if ip := lastUsedIP(); ip != "10.0.0.1" {
+		t.Errorf("last use within a minute must not be written, got %s", ip)
+	}
+	now = now.Add(time.Minute)
+	svc.AuthenticateAPIKey(ctx, key.Key, "10.0.0.3")
+	if ip := lastUsedIP(); ip != "10.0.0.3" {
+		t.Errorf("last_used_ip = %s, want 10.0.0.3", ip)
+	}
+}
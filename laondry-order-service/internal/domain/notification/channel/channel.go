@@ -0,0 +1,79 @@
+// Package channel sends customer notifications over WhatsApp, SMS, email,
+// push, or a local log for development and tests.
+package channel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Channel names, as used in NOTIFY_CHANNELS and the delivery log
+const (
+	WhatsApp = "whatsapp"
+	SMS      = "sms"
+	Email    = "email"
+	Push     = "push"
+	Log      = "log"
+)
+
+// Message is one notification to one recipient: a phone number, email
+// address, push token or customer ID depending on the channel
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Data    map[string]string // extra fields for push payloads, e.g. order_id
+}
+
+type Channel interface {
+	Name() string
+	// Send delivers the message once; an error means it may be retried
+	Send(ctx context.Context, msg Message) error
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// do sends req and fails on non-2xx responses, including the start of the
+// response body in the error
+func do(req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(strings.TrimSpace(string(body)), 300))
+	}
+	return body, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// NormalizePhone converts Indonesian numbers to international digits without
+// the plus sign: 0812-3456-789 and +62 812 3456 789 both become 628123456789
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	switch {
+	case strings.HasPrefix(d, "0"):
+		return "62" + d[1:]
+	case strings.HasPrefix(d, "8"):
+		return "62" + d
+	}
+	return d
+}
@@ -0,0 +1,111 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	for in, want := range map[string]string{
+		"0812-3456-789":    "628123456789",
+		"+62 812 3456 789": "628123456789",
+		"8123456789":       "628123456789",
+		"6281234":          "6281234",
+	} {
+		assert.Equal(t, want, NormalizePhone(in), in)
+	}
+}
+
+func TestWhatsApp_Send(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v19.0/12345/messages", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer srv.Close()
+
+	err := NewWhatsApp(srv.URL+"/v19.0/", "12345", "token").Send(context.Background(), Message{To: "0812345678", Body: "Cucian siap"})
+	require.NoError(t, err)
+	assert.Equal(t, "62812345678", got["to"])
+	assert.Equal(t, map[string]interface{}{"body": "Cucian siap"}, got["text"])
+}
+
+func TestSMS_SendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC1", user)
+		assert.Equal(t, "secret", pass)
+		assert.Equal(t, "+62812345678", r.FormValue("To"))
+		assert.Equal(t, "LAONDRY", r.FormValue("From"))
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"invalid number"}`))
+	}))
+	defer srv.Close()
+
+	err := NewSMS(srv.URL, "AC1", "secret", "LAONDRY").Send(context.Background(), Message{To: "0812345678", Body: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 400")
+	assert.Contains(t, err.Error(), "invalid number")
+}
+
+func TestPush_RejectedToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(t, string(body), `"order_id":"o-1"`)
+		_, _ = w.Write([]byte(`{"data":{"status":"error","message":"DeviceNotRegistered"}}`))
+	}))
+	defer srv.Close()
+
+	err := NewPush(srv.URL, "").Send(context.Background(), Message{To: "ExponentPushToken[x]", Subject: "Ready", Data: map[string]string{"order_id": "o-1"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DeviceNotRegistered")
+}
+
+func TestEmail_Send(t *testing.T) {
+	c := NewEmail("smtp.example.com", 587, "user", "pass", "Laondry <no-reply@example.com>").(*email)
+	var from string
+	var msg []byte
+	c.send = func(addr string, a smtp.Auth, f string, to []string, m []byte) error {
+		assert.Equal(t, "smtp.example.com:587", addr)
+		assert.NotNil(t, a)
+		assert.Equal(t, []string{"siti@example.com"}, to)
+		from, msg = f, m
+		return nil
+	}
+
+	require.NoError(t, c.Send(context.Background(), Message{To: "siti@example.com", Subject: "Cucian siap diambil", Body: "Halo\nSiti"}))
+	assert.Equal(t, "no-reply@example.com", from)
+	assert.Contains(t, string(msg), "Subject: Cucian siap diambil\r\n")
+	assert.True(t, strings.HasSuffix(string(msg), "\r\n\r\nHalo\r\nSiti\r\n"))
+}
+
+func TestLog_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	c, err := NewLog(path)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Send(context.Background(), Message{To: "cust-1", Subject: "a", Body: "b"}))
+	require.NoError(t, c.Send(context.Background(), Message{To: "cust-2", Subject: "c", Body: "d"}))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	require.Len(t, lines, 2)
+	var first struct{ To, Subject, Body string }
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "cust-1", first.To)
+	assert.Equal(t, "b", first.Body)
+}
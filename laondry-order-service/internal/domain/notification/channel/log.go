@@ -0,0 +1,50 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type logChannel struct {
+	mu  sync.Mutex
+	out io.Writer // nil writes to the service log
+}
+
+// NewLog records notifications instead of sending them: as JSON lines
+// appended to path, or in the service log when path is empty. It stands in
+// for real channels in development and tests.
+func NewLog(path string) (Channel, error) {
+	if path == "" {
+		return &logChannel{}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &logChannel{out: f}, nil
+}
+
+func (c *logChannel) Name() string { return Log }
+
+func (c *logChannel) Send(ctx context.Context, msg Message) error {
+	if c.out == nil {
+		slog.InfoContext(ctx, "[Notification] Notification logged", "recipient", msg.To, "subject", msg.Subject, "body", msg.Body)
+		return nil
+	}
+	line, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		Message
+	}{time.Now().UTC(), msg})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.out.Write(append(line, '\n'))
+	return err
+}
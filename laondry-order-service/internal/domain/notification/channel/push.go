@@ -0,0 +1,58 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type push struct {
+	url         string
+	accessToken string
+}
+
+// NewPush sends push notifications through an Expo-compatible push API; the
+// recipient is the device's push token
+func NewPush(url, accessToken string) Channel {
+	return &push{url: url, accessToken: accessToken}
+}
+
+func (c *push) Name() string { return Push }
+
+func (c *push) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"to":    msg.To,
+		"title": msg.Subject,
+		"body":  msg.Body,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+	body, err := do(req)
+	if err != nil {
+		return err
+	}
+
+	// Rejected tokens come back as 200 with an error ticket
+	var res struct {
+		Data struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(body, &res) == nil && res.Data.Status == "error" {
+		return errors.New("push rejected: " + res.Data.Message)
+	}
+	return nil
+}
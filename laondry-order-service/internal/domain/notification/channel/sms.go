@@ -0,0 +1,44 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type sms struct {
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string
+}
+
+// NewSMS sends text messages through a Twilio-compatible Messages API
+func NewSMS(baseURL, accountSID, authToken, from string) Channel {
+	return &sms{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+	}
+}
+
+func (c *sms) Name() string { return SMS }
+
+func (c *sms) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"To":   {"+" + NormalizePhone(msg.To)},
+		"From": {c.from},
+		"Body": {msg.Body},
+	}
+	endpoint := c.baseURL + "/Accounts/" + url.PathEscape(c.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.accountSID, c.authToken)
+	_, err = do(req)
+	return err
+}
@@ -0,0 +1,48 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type whatsApp struct {
+	baseURL       string
+	phoneNumberID string
+	accessToken   string
+}
+
+// NewWhatsApp sends text messages through the WhatsApp Cloud API. Free-form
+// text only reaches customers who messaged the business in the last 24
+// hours; other sends are rejected by the API and retried until they fail.
+func NewWhatsApp(baseURL, phoneNumberID, accessToken string) Channel {
+	return &whatsApp{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		phoneNumberID: phoneNumberID,
+		accessToken:   accessToken,
+	}
+}
+
+func (c *whatsApp) Name() string { return WhatsApp }
+
+func (c *whatsApp) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                NormalizePhone(msg.To),
+		"type":              "text",
+		"text":              map[string]string{"body": msg.Body},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+c.phoneNumberID+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	_, err = do(req)
+	return err
+}
@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/notification/channel"
+	nhandler "laondry-order-service/internal/domain/notification/handler/rest"
+	nrepo "laondry-order-service/internal/domain/notification/repository"
+	nservice "laondry-order-service/internal/domain/notification/service"
+	orepo "laondry-order-service/internal/domain/order/repository"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/scheduler"
+	"laondry-order-service/pkg/validator"
+
+	"gorm.io/gorm"
+)
+
+type NotificationDomain struct {
+	Repository nrepo.NotificationRepository
+	Service    nservice.NotificationService
+	Handler    *nhandler.NotificationHandler
+}
+
+func NewNotificationDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB) *NotificationDomain {
+	repo := nrepo.NewNotificationRepository(db)
+	svc := nservice.NewNotificationService(repo, orepo.NewOrderRepository(db), channels(cfg.Notification),
+		cfg.Notification.DefaultLanguage, cfg.Notification.MaxAttempts)
+
+	return &NotificationDomain{
+		Repository: repo,
+		Service:    svc,
+		Handler:    nhandler.NewNotificationHandler(svc, v),
+	}
+}
+
+// RegisterEventHandlers notifies customers of status changes and payments
+func (d *NotificationDomain) RegisterEventHandlers(bus *events.Bus) {
+	bus.Subscribe("notification", d.Service)
+}
+
+// channels builds the channels listed in NOTIFY_CHANNELS, skipping those
+// without credentials
+func channels(cfg config.NotificationConfig) []channel.Channel {
+	var enabled []channel.Channel
+	for _, name := range cfg.Channels {
+		var ch channel.Channel
+		switch name {
+		case channel.WhatsApp:
+			if cfg.WhatsAppPhoneNumberID != "" && cfg.WhatsAppAccessToken != "" {
+				ch = channel.NewWhatsApp(cfg.WhatsAppAPIURL, cfg.WhatsAppPhoneNumberID, cfg.WhatsAppAccessToken)
+			}
+		case channel.SMS:
+			if cfg.SMSAccountSID != "" && cfg.SMSAuthToken != "" && cfg.SMSFrom != "" {
+				ch = channel.NewSMS(cfg.SMSAPIURL, cfg.SMSAccountSID, cfg.SMSAuthToken, cfg.SMSFrom)
+			}
+		case channel.Email:
+			if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
+				ch = channel.NewEmail(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+			}
+		case channel.Push:
+			if cfg.PushAPIURL != "" {
+				ch = channel.NewPush(cfg.PushAPIURL, cfg.PushAccessToken)
+			}
+		case channel.Log:
+			var err error
+			if ch, err = channel.NewLog(cfg.LogFile); err != nil {
+				slog.Warn("[Notification] Cannot open NOTIFY_LOG_FILE", "error", err)
+			}
+		default:
+			slog.Warn("[Notification] Unknown channel in NOTIFY_CHANNELS", "channel", name)
+			continue
+		}
+		if ch == nil {
+			slog.Warn("[Notification] Channel is not configured, skipping", "channel", name)
+			continue
+		}
+		slog.Info("[Notification] Channel enabled", "channel", name)
+		enabled = append(enabled, ch)
+	}
+	return enabled
+}
+
+// Jobs returns the notification background jobs enabled by configuration.
+func (d *NotificationDomain) Jobs(cfg *config.Config) []scheduler.Job {
+	if cfg.Notification.PollIntervalSeconds <= 0 {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "notification-delivery",
+		Interval: time.Duration(cfg.Notification.PollIntervalSeconds) * time.Second,
+		LockTTL:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := d.Service.DeliverDue(ctx)
+			return err
+		},
+	}}
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/notification/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+	"laondry-order-service/pkg/validator"
+)
+
+type NotificationHandler struct {
+	notificationService service.NotificationService
+	validator           *validator.Validator
+}
+
+func NewNotificationHandler(notificationService service.NotificationService, validator *validator.Validator) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		validator:           validator,
+	}
+}
+
+// GET /api/v1/notifications/preferences
+// Shows the channels the authenticated customer receives notifications on
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := currentUserID(r)
+	if !ok {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	pref, err := h.notificationService.GetPreferences(r.Context(), customerID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "Notification preferences retrieved successfully", pref)
+}
+
+// PUT /api/v1/notifications/preferences
+// Opts the authenticated customer in or out of WhatsApp, SMS, email and push
+// notifications and sets their language (id or en)
+func (h *NotificationHandler) SavePreferences(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := currentUserID(r)
+	if !ok {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	var req service.PreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request payload", err.Error())
+		return
+	}
+	if validationErrors := h.validator.Validate(req); len(validationErrors) > 0 {
+		response.UnprocessableEntity(w, "Validation failed", validationErrors)
+		return
+	}
+	req.CustomerID = customerID
+
+	pref, err := h.notificationService.SavePreferences(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "Notification preferences saved successfully", pref)
+}
+
+// GET /api/v1/orders/{id}/notifications
+// Lists the notifications sent (or still queued) for an order. Staff only.
+func (h *NotificationHandler) ListOrderNotifications(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid order ID", err.Error())
+		return
+	}
+	mw.SetAccessField(r, "order_id", orderID.String())
+
+	deliveries, err := h.notificationService.ListOrderDeliveries(r.Context(), orderID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "Order notifications retrieved successfully", deliveries)
+}
+
+// currentUserID returns the authenticated user's ID
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+)
+
+type NotificationRepository interface {
+	// FindPreference returns nil, nil when the customer has not set any
+	FindPreference(ctx context.Context, customerID uuid.UUID) (*entity.NotificationPreference, error)
+	SavePreference(ctx context.Context, pref *entity.NotificationPreference) error
+
+	// CreateDeliveries queues deliveries, skipping those whose event was
+	// already queued on the same channel. It returns how many were queued.
+	CreateDeliveries(ctx context.Context, deliveries []*entity.NotificationDelivery) (int64, error)
+	// ListDueDeliveries returns PENDING deliveries whose next attempt is due,
+	// oldest first
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.NotificationDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *entity.NotificationDelivery) error
+	ListDeliveriesByOrderID(ctx context.Context, orderID uuid.UUID) ([]entity.NotificationDelivery, error)
+}
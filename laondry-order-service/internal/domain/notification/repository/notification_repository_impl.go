@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laondry-order-service/internal/entity"
+)
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) FindPreference(ctx context.Context, customerID uuid.UUID) (*entity.NotificationPreference, error) {
+	var pref entity.NotificationPreference
+	err := r.db.WithContext(ctx).First(&pref, "customer_id = ?", customerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// SavePreference creates or replaces the customer's preferences
+func (r *notificationRepository) SavePreference(ctx context.Context, pref *entity.NotificationPreference) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language", "whatsapp", "sms", "email", "push", "push_token", "updated_at"}),
+	}).Create(pref).Error
+}
+
+func (r *notificationRepository) CreateDeliveries(ctx context.Context, deliveries []*entity.NotificationDelivery) (int64, error) {
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_key"}, {Name: "channel"}},
+			DoNothing: true,
+		}).
+		Create(deliveries)
+	return res.RowsAffected, res.Error
+}
+
+func (r *notificationRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.NotificationDelivery, error) {
+	var deliveries []entity.NotificationDelivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", entity.NotificationPending, now).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *notificationRepository) SaveDelivery(ctx context.Context, delivery *entity.NotificationDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+func (r *notificationRepository) ListDeliveriesByOrderID(ctx context.Context, orderID uuid.UUID) ([]entity.NotificationDelivery, error) {
+	var deliveries []entity.NotificationDelivery
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("created_at ASC, channel ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/entity"
+)
+
+func setupNotificationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&entity.NotificationPreference{}, &entity.NotificationDelivery{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	return db
+}
+
+func TestNotificationRepository_Preferences(t *testing.T) {
+	repo := NewNotificationRepository(setupNotificationDB(t))
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	pref, err := repo.FindPreference(ctx, customerID)
+	if err != nil || pref != nil {
+		t.Fatalf("expected no preference, got %+v (err %v)", pref, err)
+	}
+
+	if err := repo.SavePreference(ctx, &entity.NotificationPreference{CustomerID: customerID, Language: "id", WhatsApp: true, Email: true}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	// Opting out must store false
+	if err := repo.SavePreference(ctx, &entity.NotificationPreference{CustomerID: customerID, Language: "en", Push: true}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	pref, err = repo.FindPreference(ctx, customerID)
+	if err != nil || pref == nil {
+		t.Fatalf("expected preference, got err %v", err)
+	}
+	if pref.Language != "en" || pref.WhatsApp || pref.Email || !pref.Push {
+		t.Fatalf("unexpected preference: %+v", pref)
+	}
+}
+
+func TestNotificationRepository_Deliveries(t *testing.T) {
+	repo := NewNotificationRepository(setupNotificationDB(t))
+	ctx := context.Background()
+	orderID := uuid.New()
+	now := time.Now()
+
+	newDelivery := func(key, channel string) *entity.NotificationDelivery {
+		return &entity.NotificationDelivery{
+			EventKey: key, Event: "order.completed", OrderID: orderID, CustomerID: uuid.New(),
+			Channel: channel, Recipient: "628123", Language: "id", Subject: "s", Body: "b",
+		}
+	}
+
+	n, err := repo.CreateDeliveries(ctx, []*entity.NotificationDelivery{newDelivery("order-status:1", "whatsapp"), newDelivery("order-status:1", "email")})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 queued, got %d (err %v)", n, err)
+	}
+	// The same event again is not queued twice
+	n, err = repo.CreateDeliveries(ctx, []*entity.NotificationDelivery{newDelivery("order-status:1", "whatsapp")})
+	if err != nil || n != 0 {
+		t.Fatalf("expected duplicate to be skipped, got %d (err %v)", n, err)
+	}
+
+	due, err := repo.ListDueDeliveries(ctx, now.Add(time.Second), 10)
+	if err != nil || len(due) != 2 {
+		t.Fatalf("expected 2 due deliveries, got %d (err %v)", len(due), err)
+	}
+
+	due[0].Status = entity.NotificationSent
+	due[1].NextAttemptAt = now.Add(time.Minute)
+	for i := range due {
+		if err := repo.SaveDelivery(ctx, &due[i]); err != nil {
+			t.Fatalf("save delivery: %v", err)
+		}
+	}
+	if due, _ := repo.ListDueDeliveries(ctx, now.Add(time.Second), 10); len(due) != 0 {
+		t.Fatalf("expected nothing due, got %d", len(due))
+	}
+
+	all, err := repo.ListDeliveriesByOrderID(ctx, orderID)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected 2 deliveries for the order, got %d (err %v)", len(all), err)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+)
+
+type NotificationService interface {
+	// OrderStatusChanged and PaymentSucceeded queue notifications to the
+	// order's customer on the channels they opted into. HandleEvent calls
+	// them for events published on the bus; they never fail the caller.
+	events.Subscriber
+	OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged)
+	PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded)
+
+	// DeliverDue sends queued notifications, rescheduling failed ones with
+	// backoff
+	DeliverDue(ctx context.Context) (*DeliveryResult, error)
+
+	GetPreferences(ctx context.Context, customerID uuid.UUID) (*entity.NotificationPreference, error)
+	SavePreferences(ctx context.Context, req PreferenceRequest) (*entity.NotificationPreference, error)
+	ListOrderDeliveries(ctx context.Context, orderID uuid.UUID) ([]entity.NotificationDelivery, error)
+}
+
+// OrderReader loads orders with their customer and outlet; the order
+// repository satisfies it
+type OrderReader interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error)
+	SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error)
+}
+
+type PreferenceRequest struct {
+	CustomerID uuid.UUID `json:"-"`
+	Language   string    `json:"language" validate:"omitempty,oneof=id en"` // Default NOTIFY_DEFAULT_LANGUAGE
+	WhatsApp   bool      `json:"whatsapp"`
+	SMS        bool      `json:"sms"`
+	Email      bool      `json:"email"`
+	Push       bool      `json:"push"`
+	PushToken  *string   `json:"push_token" validate:"omitempty,max=255"`
+}
+
+type DeliveryResult struct {
+	Sent    int `json:"sent"`
+	Retried int `json:"retried"`
+	Failed  int `json:"failed"`
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/notification/channel"
+	"laondry-order-service/internal/domain/notification/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/invoice"
+	"laondry-order-service/internal/retry"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+const (
+	defaultMaxAttempts = 5
+	deliveryBatchSize  = 50
+)
+
+// backoff schedules retries of failed deliveries
+var backoff = retry.Backoff{Base: 30 * time.Second, Max: time.Hour}
+
+type notificationService struct {
+	repo        repository.NotificationRepository
+	orders      OrderReader
+	channels    map[string]channel.Channel
+	language    string
+	maxAttempts int
+	now         func() time.Time
+}
+
+// NewNotificationService sends notifications over the given channels.
+// defaultLanguage applies to customers who did not pick one.
+func NewNotificationService(repo repository.NotificationRepository, orders OrderReader, channels []channel.Channel, defaultLanguage string, maxAttempts int) NotificationService {
+	if defaultLanguage == "" {
+		defaultLanguage = "id"
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	byName := make(map[string]channel.Channel, len(channels))
+	for _, ch := range channels {
+		byName[ch.Name()] = ch
+	}
+	return &notificationService{
+		repo:        repo,
+		orders:      orders,
+		channels:    byName,
+		language:    defaultLanguage,
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+func (s *notificationService) HandleEvent(ctx context.Context, evt events.Event) {
+	switch e := evt.(type) {
+	case events.OrderStatusChanged:
+		s.OrderStatusChanged(ctx, e)
+	case events.PaymentSucceeded:
+		s.PaymentSucceeded(ctx, e)
+	}
+}
+
+func (s *notificationService) OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged) {
+	event := "order." + strings.ToLower(change.ToStatus)
+	if event == "order.cancelled" {
+		event = EventOrderCanceled
+	}
+	s.queue(ctx, change.OrderID, "order-status:"+change.StatusLogID.String(), event, func(d *templateData) {
+		if change.Note != nil {
+			d.Note = strings.TrimRight(strings.TrimSpace(*change.Note), ".")
+		}
+	})
+}
+
+func (s *notificationService) PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded) {
+	s.queue(ctx, payment.OrderID, "payment:"+payment.PaymentID.String(), EventPaymentSucceeded, func(d *templateData) {
+		d.Amount = invoice.FormatRupiah(payment.GrossAmount)
+	})
+}
+
+// queue renders the event for the order's customer and queues one delivery
+// per channel they can be reached on
+func (s *notificationService) queue(ctx context.Context, orderID uuid.UUID, eventKey, event string, fill func(*templateData)) {
+	if _, ok := templates[event]; !ok {
+		return
+	}
+	order, err := s.orders.FindByID(ctx, orderID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to load order", "order_id", orderID, "event", event, "error", err)
+		return
+	}
+	paid, err := s.orders.SumPaidAmount(ctx, orderID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to load payments of order", "order_id", orderID, "error", err)
+		return
+	}
+	order.SetPaidAmount(paid)
+
+	pref, err := s.repo.FindPreference(ctx, order.CustomerID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to load preferences", "customer_id", order.CustomerID, "error", err)
+		return
+	}
+	lang := s.language
+	if pref != nil && pref.Language != "" {
+		lang = pref.Language
+	}
+
+	data := newTemplateData(order, lang)
+	fill(&data)
+	subject, body, err := render(event, lang, data)
+	if err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to render notification", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+		return
+	}
+
+	var deliveries []*entity.NotificationDelivery
+	for _, name := range s.channelNames() {
+		to := recipient(name, order, pref)
+		if to == "" {
+			continue
+		}
+		deliveries = append(deliveries, &entity.NotificationDelivery{
+			EventKey:      eventKey,
+			Event:         event,
+			OrderID:       order.ID,
+			CustomerID:    order.CustomerID,
+			Channel:       name,
+			Recipient:     to,
+			Language:      lang,
+			Subject:       subject,
+			Body:          body,
+			NextAttemptAt: s.now(),
+		})
+	}
+	queued, err := s.repo.CreateDeliveries(ctx, deliveries)
+	if err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to queue notifications", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+		return
+	}
+	if queued > 0 {
+		slog.InfoContext(ctx, "[Notification] Notifications queued", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "count", queued)
+	}
+}
+
+func (s *notificationService) channelNames() []string {
+	names := make([]string, 0, len(s.channels))
+	for name := range s.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recipient is the customer's address on a channel, or empty when they did
+// not opt in or cannot be reached there. The log channel records everything.
+func recipient(name string, order *entity.Order, pref *entity.NotificationPreference) string {
+	if name == channel.Log {
+		return order.CustomerID.String()
+	}
+	if pref == nil || order.Customer == nil {
+		return ""
+	}
+	c := order.Customer
+	switch {
+	case name == channel.WhatsApp && pref.WhatsApp, name == channel.SMS && pref.SMS:
+		return derefString(c.PhoneNumber)
+	case name == channel.Email && pref.Email:
+		return derefString(c.Email)
+	case name == channel.Push && pref.Push:
+		return derefString(pref.PushToken)
+	}
+	return ""
+}
+
+func newTemplateData(order *entity.Order, lang string) templateData {
+	d := templateData{
+		CustomerName: "Pelanggan",
+		OrderNo:      order.OrderNo,
+		Total:        invoice.FormatRupiah(order.GrandTotal),
+	}
+	if lang == "en" {
+		d.CustomerName = "there"
+	}
+	if order.Customer != nil && order.Customer.FullName != "" {
+		d.CustomerName = order.Customer.FullName
+	}
+	if order.Outlet != nil {
+		d.OutletName = order.Outlet.Name
+	}
+	if order.PromisedAt != nil {
+		d.PromisedAt = invoice.FormatDate(*order.PromisedAt)
+	}
+	if order.PaidAmount > 0 {
+		d.Paid = invoice.FormatRupiah(order.PaidAmount)
+	}
+	if order.OutstandingAmount > 0 {
+		d.Outstanding = invoice.FormatRupiah(order.OutstandingAmount)
+	}
+	return d
+}
+
+func (s *notificationService) DeliverDue(ctx context.Context) (*DeliveryResult, error) {
+	due, err := s.repo.ListDueDeliveries(ctx, s.now(), deliveryBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("list due notifications: %w", err)
+	}
+
+	policy := retry.Policy{Backoff: backoff, MaxAttempts: s.maxAttempts}
+	counts := retry.Due(ctx, due, policy, s.now, s.attempt, s.settle)
+	return &DeliveryResult{Sent: counts.Done, Retried: counts.Retried, Failed: counts.GaveUp}, nil
+}
+
+func (s *notificationService) attempt(ctx context.Context, d *entity.NotificationDelivery) (int, error) {
+	err := s.send(ctx, d)
+	d.Attempts++
+	return d.Attempts, err
+}
+
+func (s *notificationService) settle(ctx context.Context, d *entity.NotificationDelivery, o retry.Outcome, err error, now, next time.Time) {
+	switch o {
+	case retry.Done:
+		d.Status = entity.NotificationSent
+		d.SentAt = &now
+		d.LastError = nil
+	case retry.GiveUp:
+		slog.WarnContext(ctx, "[Notification] Delivery failed permanently", "delivery_id", d.ID, "channel", d.Channel,
+			"event", d.Event, "order_id", d.OrderID, "recipient", d.Recipient, "attempts", d.Attempts, "error", err)
+		d.Status = entity.NotificationFailed
+		d.LastError = strPtr(err.Error())
+	case retry.Retry:
+		slog.InfoContext(ctx, "[Notification] Delivery attempt failed", "delivery_id", d.ID, "channel", d.Channel,
+			"event", d.Event, "order_id", d.OrderID, "recipient", d.Recipient, "attempts", d.Attempts, "error", err)
+		d.NextAttemptAt = next
+		d.LastError = strPtr(err.Error())
+	}
+	if err := s.repo.SaveDelivery(ctx, d); err != nil {
+		slog.WarnContext(ctx, "[Notification] Failed to save delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+func (s *notificationService) send(ctx context.Context, d *entity.NotificationDelivery) error {
+	ch, ok := s.channels[d.Channel]
+	if !ok {
+		return fmt.Errorf("channel %s is not enabled", d.Channel)
+	}
+	return ch.Send(ctx, channel.Message{
+		To:      d.Recipient,
+		Subject: d.Subject,
+		Body:    d.Body,
+		Data:    map[string]string{"event": d.Event, "order_id": d.OrderID.String()},
+	})
+}
+
+// GetPreferences returns the customer's preferences; customers who never set
+// them get everything off
+func (s *notificationService) GetPreferences(ctx context.Context, customerID uuid.UUID) (*entity.NotificationPreference, error) {
+	pref, err := s.repo.FindPreference(ctx, customerID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load notification preferences", err)
+	}
+	if pref == nil {
+		pref = &entity.NotificationPreference{CustomerID: customerID, Language: s.language}
+	}
+	return pref, nil
+}
+
+func (s *notificationService) SavePreferences(ctx context.Context, req PreferenceRequest) (*entity.NotificationPreference, error) {
+	pref := &entity.NotificationPreference{
+		CustomerID: req.CustomerID,
+		Language:   req.Language,
+		WhatsApp:   req.WhatsApp,
+		SMS:        req.SMS,
+		Email:      req.Email,
+		Push:       req.Push,
+	}
+	if req.PushToken != nil && strings.TrimSpace(*req.PushToken) != "" {
+		pref.PushToken = strPtr(strings.TrimSpace(*req.PushToken))
+	}
+	if pref.Language == "" {
+		pref.Language = s.language
+	}
+	if pref.Push && pref.PushToken == nil {
+		return nil, appErrors.UnprocessableEntity("push_token is required to receive push notifications", nil)
+	}
+	if err := s.repo.SavePreference(ctx, pref); err != nil {
+		return nil, appErrors.InternalServerError("Failed to save notification preferences", err)
+	}
+
+	slog.InfoContext(ctx, "[Notification] Preferences updated", "customer_id", req.CustomerID)
+	return s.GetPreferences(ctx, req.CustomerID)
+}
+
+func (s *notificationService) ListOrderDeliveries(ctx context.Context, orderID uuid.UUID) ([]entity.NotificationDelivery, error) {
+	deliveries, err := s.repo.ListDeliveriesByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list notifications", err)
+	}
+	return deliveries, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
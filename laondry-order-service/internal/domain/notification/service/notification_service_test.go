@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/domain/notification/channel"
+	"laondry-order-service/internal/domain/notification/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+)
+
+type fakeOrderReader struct {
+	order *entity.Order
+	paid  float64
+}
+
+func (f *fakeOrderReader) FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+	o := *f.order
+	return &o, nil
+}
+
+func (f *fakeOrderReader) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	return f.paid, nil
+}
+
+type recordingChannel struct {
+	name string
+	sent []channel.Message
+	err  error
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Send(ctx context.Context, msg channel.Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func setupNotificationService(t *testing.T, orders OrderReader, channels ...channel.Channel) (*notificationService, repository.NotificationRepository) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&entity.NotificationPreference{}, &entity.NotificationDelivery{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+
+	repo := repository.NewNotificationRepository(db)
+	return NewNotificationService(repo, orders, channels, "id", 3).(*notificationService), repo
+}
+
+func testOrder() *entity.Order {
+	phone := "081234567890"
+	email := "budi@example.com"
+	customerID := uuid.New()
+	return &entity.Order{
+		ID:         uuid.New(),
+		OrderNo:    "ORD-001",
+		CustomerID: customerID,
+		Customer:   &entity.User{ID: customerID, FullName: "Budi", PhoneNumber: &phone, Email: &email},
+		Outlet:     &entity.Outlet{Name: "Laondry Kemang"},
+		GrandTotal: 50000,
+	}
+}
+
+func TestNotificationService_OrderStatusChanged_RespectsOptIn(t *testing.T) {
+	order := testOrder()
+	wa := &recordingChannel{name: channel.WhatsApp}
+	mail := &recordingChannel{name: channel.Email}
+	logCh := &recordingChannel{name: channel.Log}
+	svc, repo := setupNotificationService(t, &fakeOrderReader{order: order, paid: 20000}, wa, mail, logCh)
+	ctx := context.Background()
+
+	if err := repo.SavePreference(ctx, &entity.NotificationPreference{CustomerID: order.CustomerID, Language: "en", WhatsApp: true}); err != nil {
+		t.Fatalf("save preference: %v", err)
+	}
+
+	change := events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "COMPLETED"}
+	svc.OrderStatusChanged(ctx, change)
+	// Redelivered events are not queued twice
+	svc.OrderStatusChanged(ctx, change)
+
+	result, err := svc.DeliverDue(ctx)
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if result.Sent != 2 || result.Retried != 0 || result.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(mail.sent) != 0 {
+		t.Fatalf("expected no email without opt-in, got %+v", mail.sent)
+	}
+	if len(wa.sent) != 1 || wa.sent[0].To != "081234567890" {
+		t.Fatalf("expected one WhatsApp message to the customer, got %+v", wa.sent)
+	}
+	msg := wa.sent[0]
+	if msg.Subject != "Your laundry ORD-001 is ready" {
+		t.Fatalf("unexpected subject: %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Laondry Kemang") || !strings.Contains(msg.Body, "Amount due: Rp 30.000") {
+		t.Fatalf("unexpected body: %q", msg.Body)
+	}
+	if len(logCh.sent) != 1 || logCh.sent[0].To != order.CustomerID.String() {
+		t.Fatalf("expected the log channel to record the notification, got %+v", logCh.sent)
+	}
+
+	deliveries, err := svc.ListOrderDeliveries(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(deliveries))
+	}
+	for _, d := range deliveries {
+		if d.Status != entity.NotificationSent || d.SentAt == nil || d.Attempts != 1 {
+			t.Fatalf("unexpected delivery: %+v", d)
+		}
+	}
+}
+
+func TestNotificationService_OrderStatusChanged_DefaultLanguageAndUnnotifiedStatus(t *testing.T) {
+	order := testOrder()
+	logCh := &recordingChannel{name: channel.Log}
+	svc, _ := setupNotificationService(t, &fakeOrderReader{order: order}, logCh)
+	ctx := context.Background()
+
+	note := "Pelanggan membatalkan."
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "NEW"})
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "CANCELLED", Note: &note})
+
+	if _, err := svc.DeliverDue(ctx); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if len(logCh.sent) != 1 {
+		t.Fatalf("expected only the cancellation to be sent, got %+v", logCh.sent)
+	}
+	msg := logCh.sent[0]
+	if msg.Subject != "Pesanan ORD-001 dibatalkan" || !strings.Contains(msg.Body, "Alasan: Pelanggan membatalkan.") {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.Data["event"] != EventOrderCanceled || msg.Data["order_id"] != order.ID.String() {
+		t.Fatalf("unexpected data: %+v", msg.Data)
+	}
+}
+
+func TestNotificationService_PaymentSucceeded(t *testing.T) {
+	order := testOrder()
+	logCh := &recordingChannel{name: channel.Log}
+	svc, _ := setupNotificationService(t, &fakeOrderReader{order: order, paid: 50000}, logCh)
+	ctx := context.Background()
+
+	svc.PaymentSucceeded(ctx, events.PaymentSucceeded{PaymentID: uuid.New(), OrderID: order.ID, GrossAmount: 50000})
+	if _, err := svc.DeliverDue(ctx); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if len(logCh.sent) != 1 {
+		t.Fatalf("expected one message, got %d", len(logCh.sent))
+	}
+	body := logCh.sent[0].Body
+	if !strings.Contains(body, "pembayaran Rp 50.000") || !strings.Contains(body, "sudah lunas") {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
+
+func TestNotificationService_DeliverDue_RetriesThenFails(t *testing.T) {
+	order := testOrder()
+	logCh := &recordingChannel{name: channel.Log, err: errors.New("gateway down")}
+	svc, _ := setupNotificationService(t, &fakeOrderReader{order: order}, logCh)
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "IN_PROGRESS"})
+
+	result, err := svc.DeliverDue(ctx)
+	if err != nil || result.Retried != 1 {
+		t.Fatalf("expected a retry, got %+v (err %v)", result, err)
+	}
+	// Not due again until the backoff passes
+	if result, _ := svc.DeliverDue(ctx); result.Retried+result.Sent+result.Failed != 0 {
+		t.Fatalf("expected nothing due during backoff, got %+v", result)
+	}
+
+	now = now.Add(time.Minute)
+	if result, _ := svc.DeliverDue(ctx); result.Retried != 1 {
+		t.Fatalf("expected second retry, got %+v", result)
+	}
+	now = now.Add(2 * time.Minute)
+	if result, _ := svc.DeliverDue(ctx); result.Failed != 1 {
+		t.Fatalf("expected delivery to fail after max attempts, got %+v", result)
+	}
+
+	deliveries, _ := svc.ListOrderDeliveries(ctx, order.ID)
+	if len(deliveries) != 1 || deliveries[0].Status != entity.NotificationFailed || deliveries[0].Attempts != 3 {
+		t.Fatalf("unexpected delivery: %+v", deliveries)
+	}
+	if deliveries[0].LastError == nil || *deliveries[0].LastError != "gateway down" {
+		t.Fatalf("expected last error to be recorded, got %v", deliveries[0].LastError)
+	}
+}
+
+func TestNotificationService_SavePreferences(t *testing.T) {
+	svc, _ := setupNotificationService(t, &fakeOrderReader{order: testOrder()})
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	pref, err := svc.GetPreferences(ctx, customerID)
+	if err != nil || pref.Language != "id" || pref.WhatsApp || pref.Email {
+		t.Fatalf("expected defaults with everything off, got %+v (err %v)", pref, err)
+	}
+
+	if _, err := svc.SavePreferences(ctx, PreferenceRequest{CustomerID: customerID, Push: true}); err == nil {
+		t.Fatalf("expected push without a token to be rejected")
+	}
+
+	token := " ExponentPushToken[abc] "
+	pref, err = svc.SavePreferences(ctx, PreferenceRequest{CustomerID: customerID, Language: "en", SMS: true, Push: true, PushToken: &token})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if pref.Language != "en" || !pref.SMS || !pref.Push || pref.PushToken == nil || *pref.PushToken != "ExponentPushToken[abc]" {
+		t.Fatalf("unexpected preference: %+v", pref)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 20: time.Hour}
+	for attempt, want := range cases {
+		if got := backoff.Delay(attempt); got != want {
+			t.Fatalf("backoff.Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
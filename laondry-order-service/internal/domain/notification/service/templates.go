@@ -0,0 +1,93 @@
+package service
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Events customers are notified of. Order status events are named after the
+// new status; statuses without templates are not notified.
+const (
+	EventOrderInProgress  = "order.in_progress"
+	EventOrderCompleted   = "order.completed"
+	EventOrderCanceled    = "order.canceled"
+	EventPaymentSucceeded = "payment.succeeded"
+)
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// templateData is what message templates can print; amounts are formatted
+// and empty when zero
+type templateData struct {
+	CustomerName string
+	OrderNo      string
+	OutletName   string
+	PromisedAt   string
+	Total        string
+	Paid         string
+	Outstanding  string
+	Amount       string // the payment, for payment events
+	Note         string // the reason given for the status change
+}
+
+// templates by event and language
+var templates = map[string]map[string]messageTemplate{
+	EventOrderInProgress: {
+		"id": newTemplate("Pesanan {{.OrderNo}} sedang diproses",
+			"Halo {{.CustomerName}}, cucian Anda dengan nomor pesanan {{.OrderNo}} sedang kami proses di {{.OutletName}}."+
+				"{{if .PromisedAt}} Perkiraan selesai {{.PromisedAt}}.{{end}}"),
+		"en": newTemplate("Order {{.OrderNo}} is in progress",
+			"Hi {{.CustomerName}}, we are now processing your laundry (order {{.OrderNo}}) at {{.OutletName}}."+
+				"{{if .PromisedAt}} Expected ready {{.PromisedAt}}.{{end}}"),
+	},
+	EventOrderCompleted: {
+		"id": newTemplate("Cucian {{.OrderNo}} siap diambil",
+			"Halo {{.CustomerName}}, cucian Anda dengan nomor pesanan {{.OrderNo}} sudah selesai dan siap diambil di {{.OutletName}}."+
+				"{{if .Outstanding}} Sisa tagihan {{.Outstanding}}.{{end}} Terima kasih!"),
+		"en": newTemplate("Your laundry {{.OrderNo}} is ready",
+			"Hi {{.CustomerName}}, your laundry (order {{.OrderNo}}) is done and ready for pickup at {{.OutletName}}."+
+				"{{if .Outstanding}} Amount due: {{.Outstanding}}.{{end}} Thank you!"),
+	},
+	EventOrderCanceled: {
+		"id": newTemplate("Pesanan {{.OrderNo}} dibatalkan",
+			"Halo {{.CustomerName}}, pesanan {{.OrderNo}} di {{.OutletName}} telah dibatalkan."+
+				"{{if .Note}} Alasan: {{.Note}}.{{end}}{{if .Paid}} Pembayaran Anda sebesar {{.Paid}} akan dikembalikan.{{end}}"),
+		"en": newTemplate("Order {{.OrderNo}} canceled",
+			"Hi {{.CustomerName}}, your order {{.OrderNo}} at {{.OutletName}} has been canceled."+
+				"{{if .Note}} Reason: {{.Note}}.{{end}}{{if .Paid}} Your payment of {{.Paid}} will be refunded.{{end}}"),
+	},
+	EventPaymentSucceeded: {
+		"id": newTemplate("Pembayaran {{.OrderNo}} diterima",
+			"Halo {{.CustomerName}}, pembayaran {{.Amount}} untuk pesanan {{.OrderNo}} telah kami terima."+
+				"{{if .Outstanding}} Sisa tagihan {{.Outstanding}}.{{else}} Pesanan Anda sudah lunas.{{end}}"),
+		"en": newTemplate("Payment received for {{.OrderNo}}",
+			"Hi {{.CustomerName}}, we received your payment of {{.Amount}} for order {{.OrderNo}}."+
+				"{{if .Outstanding}} Remaining balance: {{.Outstanding}}.{{else}} Your order is fully paid.{{end}}"),
+	},
+}
+
+// render fills in the event's template in lang, falling back to Indonesian
+func render(event, lang string, data templateData) (subject, body string, err error) {
+	t, ok := templates[event][lang]
+	if !ok {
+		t = templates[event]["id"]
+	}
+	var sb, bb strings.Builder
+	if err := t.subject.Execute(&sb, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&bb, data); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
@@ -2,14 +2,16 @@ package order
 
 import (
     "context"
-    "log"
+    "log/slog"
     "time"
 
     "laondry-order-service/internal/config"
     "laondry-order-service/internal/domain/order/handler/rest"
     "laondry-order-service/internal/domain/order/repository"
     "laondry-order-service/internal/domain/order/service"
+    "laondry-order-service/internal/events"
     "laondry-order-service/internal/lock"
+    "laondry-order-service/internal/surcharge"
     "laondry-order-service/pkg/validator"
 
     "gorm.io/gorm"
@@ -22,9 +24,12 @@ type OrderDomain struct {
 	QuoteService service.QuoteService
 	Handler      *rest.OrderHandler
 	QuoteHandler *rest.QuoteHandler
+
+	InvoiceService service.InvoiceService
+	InvoiceHandler *rest.InvoiceHandler
 }
 
-func NewOrderDomain(db *gorm.DB, validator *validator.Validator, cfg *config.Config) *OrderDomain {
+func NewOrderDomain(db *gorm.DB, validator *validator.Validator, cfg *config.Config, publisher events.Publisher) *OrderDomain {
     orderRepo := repository.NewOrderRepository(db)
     pricingRepo := repository.NewPricingRepository(db)
 
@@ -40,21 +45,33 @@ func NewOrderDomain(db *gorm.DB, validator *validator.Validator, cfg *config.Con
         ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
         defer cancel()
         if err := rdb.Ping(ctx).Err(); err != nil {
-            log.Printf("[Lock] Redis connection failed: %v, using in-memory locker", err)
+            slog.Warn("[Lock] Redis connection failed, using in-memory locker", "error", err)
             locker = lock.NewMemoryLocker()
         } else {
-            log.Printf("[Lock] Using Redis locker at %s", cfg.Redis.Addr)
+            slog.Info("[Lock] Using Redis locker", "addr", cfg.Redis.Addr)
             locker = lock.NewRedisLocker(rdb)
         }
     } else {
-        log.Printf("[Lock] Using in-memory locker (no Redis configured)")
+        slog.Info("[Lock] Using in-memory locker (no Redis configured)")
         locker = lock.NewMemoryLocker()
     }
+    locker = lock.Instrumented{Locker: locker}
 
-    orderService := service.NewOrderService(orderRepo, db, locker)
-    quoteService := service.NewQuoteService(pricingRepo, locker)
+    // Quotes show the surcharge of the payment method the customer picks
+    var surcharges surcharge.Table
+    if cfg != nil && cfg.Midtrans.Surcharges != "" {
+        var err error
+        if surcharges, err = surcharge.Parse(cfg.Midtrans.Surcharges); err != nil {
+            slog.Warn("[Quote] Ignoring PAYMENT_SURCHARGES", "error", err)
+        }
+    }
+
+    orderService := service.NewOrderService(orderRepo, db, locker, service.WithEventPublisher(publisher))
+    quoteService := service.NewQuoteService(pricingRepo, locker, surcharges)
     orderHandler := rest.NewOrderHandler(orderService, validator)
     quoteHandler := rest.NewQuoteHandler(quoteService, validator)
+    invoiceService := service.NewInvoiceService(orderRepo, db)
+    invoiceHandler := rest.NewInvoiceHandler(invoiceService, validator)
 
     return &OrderDomain{
         Repository:   orderRepo,
@@ -62,5 +79,8 @@ func NewOrderDomain(db *gorm.DB, validator *validator.Validator, cfg *config.Con
         QuoteService: quoteService,
         Handler:      orderHandler,
         QuoteHandler: quoteHandler,
+
+        InvoiceService: invoiceService,
+        InvoiceHandler: invoiceHandler,
     }
 }
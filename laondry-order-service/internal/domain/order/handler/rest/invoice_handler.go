@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/order/service"
+	"laondry-order-service/internal/invoice"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+	"laondry-order-service/pkg/validator"
+)
+
+type InvoiceHandler struct {
+	invoiceService service.InvoiceService
+	validator      *validator.Validator
+}
+
+func NewInvoiceHandler(invoiceService service.InvoiceService, validator *validator.Validator) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+		validator:      validator,
+	}
+}
+
+// GET /api/v1/orders/{id}/invoice.pdf
+// Downloads the order's invoice; the invoice number is issued on the first download.
+// Customers may only download their own orders' invoices and receipts.
+func (h *InvoiceHandler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	h.writeDocument(w, r, invoice.KindInvoice)
+}
+
+// GET /api/v1/orders/{id}/receipt.pdf
+// Downloads the order's receipt, sized for 58/80 mm thermal printers
+func (h *InvoiceHandler) GetReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	h.writeDocument(w, r, invoice.KindReceipt)
+}
+
+func (h *InvoiceHandler) writeDocument(w http.ResponseWriter, r *http.Request, kind invoice.Kind) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid order ID", err.Error())
+		return
+	}
+	mw.SetAccessField(r, "order_id", id.String())
+
+	doc, err := h.invoiceService.RenderOrderDocument(r.Context(), id, kind)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	mw.SetAccessField(r, "invoice_no", doc.InvoiceNo)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+doc.FileName+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc.PDF)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(doc.PDF)
+}
+
+// GET /api/v1/orders/{id}/receipt.escpos
+// GET /api/v1/orders/{id}/receipt.txt
+// Prints the order's receipt for a thermal printer: the raw ESC/POS stream,
+// or a plain-text preview of it. ?width=58|80 overrides the outlet's paper width.
+func (h *InvoiceHandler) GetReceiptESCPOS(w http.ResponseWriter, r *http.Request) {
+	h.writeThermal(w, r, invoice.KindReceipt, false)
+}
+
+func (h *InvoiceHandler) GetReceiptPreview(w http.ResponseWriter, r *http.Request) {
+	h.writeThermal(w, r, invoice.KindReceipt, true)
+}
+
+// GET /api/v1/orders/{id}/tags.escpos
+// GET /api/v1/orders/{id}/tags.txt
+// Prints one garment tag per order item, with a barcode to scan at pickup
+func (h *InvoiceHandler) GetTagsESCPOS(w http.ResponseWriter, r *http.Request) {
+	h.writeThermal(w, r, invoice.KindTags, false)
+}
+
+func (h *InvoiceHandler) GetTagsPreview(w http.ResponseWriter, r *http.Request) {
+	h.writeThermal(w, r, invoice.KindTags, true)
+}
+
+func (h *InvoiceHandler) writeThermal(w http.ResponseWriter, r *http.Request, kind invoice.Kind, preview bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid order ID", err.Error())
+		return
+	}
+	mw.SetAccessField(r, "order_id", id.String())
+
+	width := 0
+	if v := r.URL.Query().Get("width"); v != "" {
+		if width, err = strconv.Atoi(v); err != nil || (width != 58 && width != 80) {
+			response.BadRequest(w, "Invalid width", "width must be 58 or 80")
+			return
+		}
+	}
+
+	doc, err := h.invoiceService.RenderThermal(r.Context(), id, kind, width)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if doc.InvoiceNo != "" {
+		mw.SetAccessField(r, "invoice_no", doc.InvoiceNo)
+	}
+
+	body := doc.ESCPOS
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if preview {
+		body = []byte(doc.Preview)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// GET /api/v1/admin/outlets/{id}/invoice-template
+// Shows an outlet's invoice template and its last invoice number. Admin only.
+func (h *InvoiceHandler) GetInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+	outletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid outlet ID", err.Error())
+		return
+	}
+
+	setting, err := h.invoiceService.GetInvoiceTemplate(r.Context(), outletID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "Invoice template retrieved successfully", setting)
+}
+
+// PUT /api/v1/admin/outlets/{id}/invoice-template
+// Sets the layout, notes and number prefix of an outlet's invoices. Admin only.
+func (h *InvoiceHandler) SaveInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+	outletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid outlet ID", err.Error())
+		return
+	}
+
+	var req service.InvoiceTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request payload", err.Error())
+		return
+	}
+	if validationErrors := h.validator.Validate(req); len(validationErrors) > 0 {
+		response.UnprocessableEntity(w, "Validation failed", validationErrors)
+		return
+	}
+
+	req.OutletID = outletID
+	if user, ok := mw.GetUserFromContext(r.Context()); ok {
+		if userID, err := uuid.Parse(user.UserID); err == nil {
+			req.UpdatedBy = &userID
+		}
+	}
+
+	setting, err := h.invoiceService.SaveInvoiceTemplate(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "Invoice template saved successfully", setting)
+}
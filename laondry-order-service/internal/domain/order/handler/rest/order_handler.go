@@ -42,6 +42,11 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 			response.Unauthorized(w, "Authentication required")
 			return
 		}
+		// API keys act on behalf of customers and must name one
+		if user.IsAPIKey() {
+			response.BadRequest(w, "customer_id is required when using an API key", nil)
+			return
+		}
 		customerID, err := uuid.Parse(user.UserID)
 		if err != nil {
 			response.BadRequest(w, "Invalid user ID", err.Error())
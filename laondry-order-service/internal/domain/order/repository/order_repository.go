@@ -19,6 +19,20 @@ type OrderRepository interface {
     UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
     CreateStatusLog(ctx context.Context, log *entity.OrderStatusLog) error
     ListStatusLogs(ctx context.Context, orderID uuid.UUID, page, limit int, sortOrder string) ([]entity.OrderStatusLog, int64, error)
+    // SumPaidAmount totals the order's settled payments minus successful refunds
+    SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error)
+    // CreateOutboxMessage stores a side effect to be delivered after commit
+    CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error
+    // FindInvoiceByOrderID returns the order's invoice, or nil when none was issued yet
+    FindInvoiceByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.OrderInvoice, error)
+    // CreateInvoice stores an issued invoice number
+    CreateInvoice(ctx context.Context, invoice *entity.OrderInvoice) error
+    // NextInvoiceNumber advances the outlet's invoice sequence and returns the new number; call it in a transaction
+    NextInvoiceNumber(ctx context.Context, outletID uuid.UUID) (int64, error)
+    // FindInvoiceSetting returns the outlet's invoice settings, or nil when the outlet has none
+    FindInvoiceSetting(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error)
+    // SaveInvoiceSetting creates or updates the outlet's invoice template, keeping its number sequence
+    SaveInvoiceSetting(ctx context.Context, setting *entity.OutletInvoiceSetting) error
     // WithDB returns a repository bound to the provided *gorm.DB (e.g., a transaction)
     WithDB(db *gorm.DB) OrderRepository
 }
@@ -2,13 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/payments/query"
 	appErrors "laondry-order-service/pkg/errors"
 
 	"github.com/google/uuid"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type orderRepository struct {
@@ -273,6 +276,31 @@ func (r *orderRepository) CreateStatusLog(ctx context.Context, logEntry *entity.
 	return nil
 }
 
+func (r *orderRepository) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := newrelic.DatastoreSegment{Product: nrProductFor(r.db), Collection: "payment_transactions", Operation: "SELECT"}
+		seg.StartTime = newrelic.StartSegmentNow(txn)
+		defer seg.End()
+	}
+	paid, err := query.SumPaidAmount(ctx, r.db, orderID)
+	if err != nil {
+		return 0, appErrors.InternalServerError("Failed to sum order payments", err)
+	}
+	return paid, nil
+}
+
+func (r *orderRepository) CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := newrelic.DatastoreSegment{Product: nrProductFor(r.db), Collection: "outbox_messages", Operation: "INSERT"}
+		seg.StartTime = newrelic.StartSegmentNow(txn)
+		defer seg.End()
+	}
+	if err := r.db.WithContext(ctx).Create(msg).Error; err != nil {
+		return appErrors.InternalServerError("Failed to create outbox message", err)
+	}
+	return nil
+}
+
 func (r *orderRepository) ListStatusLogs(ctx context.Context, orderID uuid.UUID, page, limit int, sortOrder string) ([]entity.OrderStatusLog, int64, error) {
 	var logs []entity.OrderStatusLog
 	var total int64
@@ -309,3 +337,74 @@ func (r *orderRepository) ListStatusLogs(ctx context.Context, orderID uuid.UUID,
 	}
 	return logs, total, nil
 }
+
+func (r *orderRepository) FindInvoiceByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.OrderInvoice, error) {
+	var invoice entity.OrderInvoice
+	err := r.db.WithContext(ctx).First(&invoice, "order_id = ?", orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to find invoice", err)
+	}
+	return &invoice, nil
+}
+
+func (r *orderRepository) CreateInvoice(ctx context.Context, invoice *entity.OrderInvoice) error {
+	if err := r.db.WithContext(ctx).Create(invoice).Error; err != nil {
+		return appErrors.InternalServerError("Failed to create invoice", err)
+	}
+	return nil
+}
+
+// NextInvoiceNumber creates the outlet's settings row on its first invoice,
+// then increments last_number. The UPDATE locks the row until the transaction
+// ends, so concurrent invoices of an outlet get consecutive numbers.
+func (r *orderRepository) NextInvoiceNumber(ctx context.Context, outletID uuid.UUID) (int64, error) {
+	db := r.db.WithContext(ctx)
+	setting := entity.OutletInvoiceSetting{OutletID: outletID, PaperSize: "A4", ReceiptWidthMM: 80, ShowQRCode: true}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&setting).Error; err != nil {
+		return 0, appErrors.InternalServerError("Failed to create invoice settings", err)
+	}
+	err := db.Model(&entity.OutletInvoiceSetting{}).
+		Where("outlet_id = ?", outletID).
+		UpdateColumn("last_number", gorm.Expr("last_number + 1")).Error
+	if err != nil {
+		return 0, appErrors.InternalServerError("Failed to advance invoice number", err)
+	}
+	var number int64
+	err = db.Model(&entity.OutletInvoiceSetting{}).
+		Where("outlet_id = ?", outletID).
+		Select("last_number").
+		Scan(&number).Error
+	if err != nil {
+		return 0, appErrors.InternalServerError("Failed to read invoice number", err)
+	}
+	return number, nil
+}
+
+func (r *orderRepository) FindInvoiceSetting(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error) {
+	var setting entity.OutletInvoiceSetting
+	err := r.db.WithContext(ctx).First(&setting, "outlet_id = ?", outletID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to find invoice settings", err)
+	}
+	return &setting, nil
+}
+
+func (r *orderRepository) SaveInvoiceSetting(ctx context.Context, setting *entity.OutletInvoiceSetting) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "outlet_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"number_prefix", "title", "tax_id", "header_note", "footer_note",
+			"paper_size", "receipt_width_mm", "show_qr_code", "updated_by", "updated_at",
+		}),
+	}).Create(setting).Error
+	if err != nil {
+		return appErrors.InternalServerError("Failed to save invoice settings", err)
+	}
+	return nil
+}
@@ -597,3 +597,113 @@ func stringPtr(v string) *string {
 }
 
 func intPtr(v int) *int { return &v }
+
+func TestOrderRepository_SumPaidAmount(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&entity.PaymentTransaction{}, &entity.PaymentRefund{}); err != nil {
+		t.Fatalf("failed to migrate payment tables: %v", err)
+	}
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+	orderID := uuid.New()
+
+	mk := func(o uuid.UUID, status string, amount float64) entity.PaymentTransaction {
+		tx := entity.PaymentTransaction{OrderID: o, PaymentOrderID: "PAY-" + uuid.NewString(), GrossAmount: amount, Status: status}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("failed to create payment: %v", err)
+		}
+		return tx
+	}
+	mk(orderID, "SUCCESS", 50000)
+	mk(orderID, "PENDING", 20000)
+	mk(orderID, "EXPIRED", 20000)
+	refunded := mk(orderID, "PARTIALLY_REFUNDED", 30000)
+	mk(uuid.New(), "SUCCESS", 99999)
+
+	for i, r := range []struct {
+		status string
+		amount float64
+	}{{"SUCCESS", 10000}, {"FAILED", 5000}, {"PENDING", 2000}} {
+		refund := entity.PaymentRefund{PaymentTransactionID: refunded.ID, RefundKey: fmt.Sprintf("%s-REFUND-%d", refunded.PaymentOrderID, i), Amount: r.amount, Status: r.status, Source: "api"}
+		if err := db.Create(&refund).Error; err != nil {
+			t.Fatalf("failed to create refund: %v", err)
+		}
+	}
+
+	paid, err := repo.SumPaidAmount(ctx, orderID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paid != 70000 {
+		t.Fatalf("expected paid 70000, got %v", paid)
+	}
+}
+
+func TestOrderRepository_InvoiceNumbersAndSettings(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&entity.OutletInvoiceSetting{}, &entity.OrderInvoice{}); err != nil {
+		t.Fatalf("failed to migrate invoice tables: %v", err)
+	}
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+	outletA, outletB := uuid.New(), uuid.New()
+
+	// Each outlet has its own sequence, starting at 1
+	for i, outlet := range []uuid.UUID{outletA, outletA, outletB, outletA} {
+		n, err := repo.NextInvoiceNumber(ctx, outlet)
+		if err != nil {
+			t.Fatalf("next number %d: %v", i, err)
+		}
+		want := []int64{1, 2, 1, 3}[i]
+		if n != want {
+			t.Fatalf("call %d: expected number %d, got %d", i, want, n)
+		}
+	}
+
+	// A rolled back invoice gives its number back
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := repo.WithDB(tx).NextInvoiceNumber(ctx, outletB); err != nil {
+			return err
+		}
+		return fmt.Errorf("rollback")
+	})
+	if err == nil {
+		t.Fatalf("expected the transaction to roll back")
+	}
+	if n, _ := repo.NextInvoiceNumber(ctx, outletB); n != 2 {
+		t.Fatalf("expected number 2 after rollback, got %d", n)
+	}
+
+	// Saving the template keeps the sequence and stores show_qr_code=false
+	setting := &entity.OutletInvoiceSetting{OutletID: outletA, NumberPrefix: "KMG", PaperSize: "A5", ReceiptWidthMM: 58, ShowQRCode: false}
+	if err := repo.SaveInvoiceSetting(ctx, setting); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	found, err := repo.FindInvoiceSetting(ctx, outletA)
+	if err != nil || found == nil {
+		t.Fatalf("find settings: %+v (err %v)", found, err)
+	}
+	if found.LastNumber != 3 || found.NumberPrefix != "KMG" || found.PaperSize != "A5" || found.ShowQRCode {
+		t.Fatalf("unexpected settings %+v", found)
+	}
+	if missing, err := repo.FindInvoiceSetting(ctx, uuid.New()); err != nil || missing != nil {
+		t.Fatalf("expected no settings, got %+v (err %v)", missing, err)
+	}
+
+	orderID := uuid.New()
+	if inv, err := repo.FindInvoiceByOrderID(ctx, orderID); err != nil || inv != nil {
+		t.Fatalf("expected no invoice yet, got %+v (err %v)", inv, err)
+	}
+	inv := &entity.OrderInvoice{OrderID: orderID, OutletID: outletA, Sequence: 3, InvoiceNo: "KMG-000003", IssuedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := repo.CreateInvoice(ctx, inv); err != nil {
+		t.Fatalf("create invoice: %v", err)
+	}
+	dup := &entity.OrderInvoice{OrderID: orderID, OutletID: outletA, Sequence: 4, InvoiceNo: "KMG-000004", IssuedAt: inv.IssuedAt}
+	if err := repo.CreateInvoice(ctx, dup); err == nil {
+		t.Fatalf("expected a second invoice for the order to be rejected")
+	}
+	issued, err := repo.FindInvoiceByOrderID(ctx, orderID)
+	if err != nil || issued == nil || issued.InvoiceNo != "KMG-000003" {
+		t.Fatalf("expected KMG-000003, got %+v (err %v)", issued, err)
+	}
+}
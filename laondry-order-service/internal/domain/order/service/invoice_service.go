@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/invoice"
+)
+
+type InvoiceService interface {
+	// RenderOrderDocument renders the invoice or receipt PDF of an order,
+	// issuing its invoice number on first use
+	RenderOrderDocument(ctx context.Context, orderID uuid.UUID, kind invoice.Kind) (*OrderDocument, error)
+	// RenderThermal prints the order's receipt or garment tags for a thermal
+	// printer. widthMM (58 or 80) overrides the outlet's receipt width when set.
+	RenderThermal(ctx context.Context, orderID uuid.UUID, kind invoice.Kind, widthMM int) (*ThermalDocument, error)
+	GetInvoiceTemplate(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error)
+	SaveInvoiceTemplate(ctx context.Context, req InvoiceTemplateRequest) (*entity.OutletInvoiceSetting, error)
+}
+
+type OrderDocument struct {
+	FileName  string
+	InvoiceNo string
+	PDF       []byte
+}
+
+type ThermalDocument struct {
+	InvoiceNo string // empty for garment tags
+	ESCPOS    []byte
+	Preview   string
+}
+
+type InvoiceTemplateRequest struct {
+	OutletID       uuid.UUID  `json:"-"`
+	NumberPrefix   string     `json:"number_prefix" validate:"max=30"` // empty = INV-<outlet code>
+	Title          string     `json:"title" validate:"max=100"`
+	TaxID          string     `json:"tax_id" validate:"max=50"`
+	HeaderNote     string     `json:"header_note" validate:"max=1000"`
+	FooterNote     string     `json:"footer_note" validate:"max=1000"`
+	PaperSize      string     `json:"paper_size" validate:"omitempty,oneof=A4 A5"`       // Default A4
+	ReceiptWidthMM int        `json:"receipt_width_mm" validate:"omitempty,oneof=58 80"` // Default 80
+	ShowQRCode     *bool      `json:"show_qr_code"`                                      // Default true
+	UpdatedBy      *uuid.UUID `json:"-"`
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/order/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/invoice"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+type invoiceService struct {
+	orderRepo repository.OrderRepository
+	db        *gorm.DB
+}
+
+func NewInvoiceService(orderRepo repository.OrderRepository, db *gorm.DB) InvoiceService {
+	return &invoiceService{orderRepo: orderRepo, db: db}
+}
+
+func (s *invoiceService) withTx(ctx context.Context, fn func(r repository.OrderRepository) error) error {
+	if s.db == nil {
+		return fn(s.orderRepo)
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(s.orderRepo.WithDB(tx))
+	})
+}
+
+func (s *invoiceService) RenderOrderDocument(ctx context.Context, orderID uuid.UUID, kind invoice.Kind) (*OrderDocument, error) {
+	doc, setting, err := s.loadDocument(ctx, orderID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.issueDocument(ctx, &doc, setting); err != nil {
+		return nil, err
+	}
+
+	pdf, err := invoice.Render(doc)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to render "+string(kind), err)
+	}
+
+	fileName := doc.InvoiceNo + ".pdf"
+	if kind == invoice.KindReceipt {
+		fileName = doc.InvoiceNo + "-receipt.pdf"
+	}
+	return &OrderDocument{FileName: fileName, InvoiceNo: doc.InvoiceNo, PDF: pdf}, nil
+}
+
+func (s *invoiceService) RenderThermal(ctx context.Context, orderID uuid.UUID, kind invoice.Kind, widthMM int) (*ThermalDocument, error) {
+	doc, setting, err := s.loadDocument(ctx, orderID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if widthMM == 58 || widthMM == 80 {
+		doc.Template.ReceiptWidthMM = widthMM
+	}
+
+	var out invoice.Thermal
+	switch kind {
+	case invoice.KindReceipt:
+		if err := s.issueDocument(ctx, &doc, setting); err != nil {
+			return nil, err
+		}
+		out, err = invoice.ThermalReceipt(doc)
+	case invoice.KindTags:
+		if len(doc.Order.Items) == 0 {
+			return nil, appErrors.UnprocessableEntity("Order has no items to tag", nil)
+		}
+		out, err = invoice.GarmentTags(doc.Order, doc.Template)
+	default:
+		return nil, appErrors.BadRequest("Unsupported thermal document: "+string(kind), nil)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to print "+string(kind), err)
+	}
+	return &ThermalDocument{InvoiceNo: doc.InvoiceNo, ESCPOS: out.ESCPOS, Preview: out.Preview}, nil
+}
+
+// loadDocument loads the order, with its paid amount, and the outlet's
+// template; the invoice number is left to issueDocument. Callers that may not
+// read the order are rejected here, before a number is issued for it.
+func (s *invoiceService) loadDocument(ctx context.Context, orderID uuid.UUID, kind invoice.Kind) (invoice.Document, *entity.OutletInvoiceSetting, error) {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return invoice.Document{}, nil, err
+	}
+	if err := checkOrderAccess(ctx, order); err != nil {
+		return invoice.Document{}, nil, err
+	}
+	paid, err := s.orderRepo.SumPaidAmount(ctx, order.ID)
+	if err != nil {
+		return invoice.Document{}, nil, err
+	}
+	order.SetPaidAmount(paid)
+
+	setting, err := s.orderRepo.FindInvoiceSetting(ctx, order.OutletID)
+	if err != nil {
+		return invoice.Document{}, nil, err
+	}
+	doc := invoice.Document{Kind: kind, Order: order, Template: invoice.TemplateFromSetting(setting)}
+	return doc, setting, nil
+}
+
+// issueDocument sets the number and date of the order's invoice on doc
+func (s *invoiceService) issueDocument(ctx context.Context, doc *invoice.Document, setting *entity.OutletInvoiceSetting) error {
+	inv, err := s.issueInvoice(ctx, doc.Order, setting)
+	if err != nil {
+		return err
+	}
+	doc.InvoiceNo = inv.InvoiceNo
+	doc.IssuedAt = inv.IssuedAt
+	return nil
+}
+
+// issueInvoice returns the order's invoice, issuing the outlet's next
+// invoice number the first time. Numbers are only used once the invoice is
+// stored: a failed or concurrent attempt rolls its increment back.
+func (s *invoiceService) issueInvoice(ctx context.Context, order *entity.Order, setting *entity.OutletInvoiceSetting) (*entity.OrderInvoice, error) {
+	inv, err := s.orderRepo.FindInvoiceByOrderID(ctx, order.ID)
+	if err != nil || inv != nil {
+		return inv, err
+	}
+	if order.Status == "CANCELED" || order.Status == "CANCELLED" {
+		return nil, appErrors.UnprocessableEntity("Canceled orders are not invoiced", nil)
+	}
+
+	err = s.withTx(ctx, func(r repository.OrderRepository) error {
+		number, err := r.NextInvoiceNumber(ctx, order.OutletID)
+		if err != nil {
+			return err
+		}
+		inv = &entity.OrderInvoice{
+			OrderID:   order.ID,
+			OutletID:  order.OutletID,
+			Sequence:  number,
+			InvoiceNo: invoiceNumber(setting, order.Outlet, number),
+			IssuedAt:  time.Now().UTC().Truncate(time.Second), // stored precision, so re-renders match
+		}
+		return r.CreateInvoice(ctx, inv)
+	})
+	if err != nil {
+		// Another request may have issued the invoice first
+		if existing, findErr := s.orderRepo.FindInvoiceByOrderID(ctx, order.ID); findErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Invoice] Invoice issued", "invoice_no", inv.InvoiceNo, "order_id", order.ID, "order_no", order.OrderNo)
+	return inv, nil
+}
+
+// invoiceNumber formats the sequence with the outlet's prefix, e.g.
+// INV-HQ01-000042
+func invoiceNumber(setting *entity.OutletInvoiceSetting, outlet *entity.Outlet, number int64) string {
+	prefix := "INV"
+	if setting != nil && setting.NumberPrefix != "" {
+		prefix = setting.NumberPrefix
+	} else if outlet != nil && outlet.Code != "" {
+		prefix = "INV-" + outlet.Code
+	}
+	return fmt.Sprintf("%s-%06d", prefix, number)
+}
+
+// GetInvoiceTemplate returns the outlet's invoice template; outlets without
+// one get the defaults
+func (s *invoiceService) GetInvoiceTemplate(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error) {
+	setting, err := s.orderRepo.FindInvoiceSetting(ctx, outletID)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil {
+		def := invoice.DefaultTemplate()
+		setting = &entity.OutletInvoiceSetting{
+			OutletID:       outletID,
+			PaperSize:      def.PaperSize,
+			ReceiptWidthMM: def.ReceiptWidthMM,
+			ShowQRCode:     def.ShowQRCode,
+		}
+	}
+	return setting, nil
+}
+
+// SaveInvoiceTemplate sets the outlet's invoice template. Changing the prefix
+// does not restart the number sequence.
+func (s *invoiceService) SaveInvoiceTemplate(ctx context.Context, req InvoiceTemplateRequest) (*entity.OutletInvoiceSetting, error) {
+	setting := &entity.OutletInvoiceSetting{
+		OutletID:       req.OutletID,
+		NumberPrefix:   strings.TrimSpace(req.NumberPrefix),
+		Title:          strings.TrimSpace(req.Title),
+		TaxID:          strings.TrimSpace(req.TaxID),
+		HeaderNote:     strings.TrimSpace(req.HeaderNote),
+		FooterNote:     strings.TrimSpace(req.FooterNote),
+		PaperSize:      req.PaperSize,
+		ReceiptWidthMM: req.ReceiptWidthMM,
+		ShowQRCode:     req.ShowQRCode == nil || *req.ShowQRCode,
+		UpdatedBy:      req.UpdatedBy,
+	}
+	if setting.PaperSize == "" {
+		setting.PaperSize = "A4"
+	}
+	if setting.ReceiptWidthMM == 0 {
+		setting.ReceiptWidthMM = 80
+	}
+	if err := s.orderRepo.SaveInvoiceSetting(ctx, setting); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Invoice] Template updated", "outlet_id", req.OutletID)
+	return s.orderRepo.FindInvoiceSetting(ctx, req.OutletID)
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/invoice"
+	mw "laondry-order-service/internal/middleware"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func invoiceTestRepo(order *entity.Order) *mockOrderRepository {
+	return &mockOrderRepository{
+		findByIDFn: func(_ context.Context, id uuid.UUID) (*entity.Order, error) {
+			if id != order.ID {
+				return nil, appErrors.NotFound("Order not found", nil)
+			}
+			return order, nil
+		},
+		sumPaidAmountFn: func(context.Context, uuid.UUID) (float64, error) { return 40000, nil },
+	}
+}
+
+func TestInvoiceService_RenderOrderDocument_IssuesNumberOnce(t *testing.T) {
+	ctx := context.Background()
+	outlet := &entity.Outlet{ID: uuid.New(), Code: "HQ01", Name: "Laondry Kemang"}
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-001", OutletID: outlet.ID, Outlet: outlet, Status: "NEW", Subtotal: 60000, GrandTotal: 60000}
+	repo := invoiceTestRepo(order)
+
+	var stored *entity.OrderInvoice
+	calls := 0
+	repo.findInvoiceFn = func(context.Context, uuid.UUID) (*entity.OrderInvoice, error) { return stored, nil }
+	repo.nextInvoiceNumberFn = func(_ context.Context, outletID uuid.UUID) (int64, error) {
+		calls++
+		assert.Equal(t, outlet.ID, outletID)
+		return 42, nil
+	}
+	repo.createInvoiceFn = func(_ context.Context, inv *entity.OrderInvoice) error {
+		stored = inv
+		return nil
+	}
+	svc := NewInvoiceService(repo, nil)
+
+	doc, err := svc.RenderOrderDocument(ctx, order.ID, invoice.KindInvoice)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-HQ01-000042", doc.InvoiceNo)
+	assert.Equal(t, "INV-HQ01-000042.pdf", doc.FileName)
+	assert.True(t, bytes.HasPrefix(doc.PDF, []byte("%PDF-")))
+	assert.Equal(t, 40000.0, order.PaidAmount)
+
+	// The receipt reuses the invoice, and renders the same on every request
+	receipt, err := svc.RenderOrderDocument(ctx, order.ID, invoice.KindReceipt)
+	require.NoError(t, err)
+	again, err := svc.RenderOrderDocument(ctx, order.ID, invoice.KindReceipt)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-HQ01-000042-receipt.pdf", receipt.FileName)
+	assert.Equal(t, receipt.PDF, again.PDF)
+	assert.Equal(t, 1, calls)
+}
+
+func TestInvoiceService_RenderOrderDocument_OutletPrefix(t *testing.T) {
+	outlet := &entity.Outlet{ID: uuid.New(), Code: "HQ01"}
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-002", OutletID: outlet.ID, Outlet: outlet, Status: "NEW"}
+	repo := invoiceTestRepo(order)
+	repo.findInvoiceSettingFn = func(context.Context, uuid.UUID) (*entity.OutletInvoiceSetting, error) {
+		return &entity.OutletInvoiceSetting{OutletID: outlet.ID, NumberPrefix: "KMG/2025", PaperSize: "A5", ReceiptWidthMM: 58}, nil
+	}
+	repo.nextInvoiceNumberFn = func(context.Context, uuid.UUID) (int64, error) { return 7, nil }
+
+	doc, err := NewInvoiceService(repo, nil).RenderOrderDocument(context.Background(), order.ID, invoice.KindInvoice)
+	require.NoError(t, err)
+	assert.Equal(t, "KMG/2025-000007", doc.InvoiceNo)
+}
+
+func TestInvoiceService_RenderOrderDocument_ConcurrentIssue(t *testing.T) {
+	outlet := &entity.Outlet{ID: uuid.New(), Code: "HQ01"}
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-003", OutletID: outlet.ID, Outlet: outlet, Status: "NEW"}
+	repo := invoiceTestRepo(order)
+
+	// Another request stores the invoice between our lookup and insert
+	existing := &entity.OrderInvoice{OrderID: order.ID, OutletID: outlet.ID, Sequence: 5, InvoiceNo: "INV-HQ01-000005"}
+	lookups := 0
+	repo.findInvoiceFn = func(context.Context, uuid.UUID) (*entity.OrderInvoice, error) {
+		if lookups++; lookups == 1 {
+			return nil, nil
+		}
+		return existing, nil
+	}
+	repo.nextInvoiceNumberFn = func(context.Context, uuid.UUID) (int64, error) { return 6, nil }
+	repo.createInvoiceFn = func(context.Context, *entity.OrderInvoice) error {
+		return appErrors.InternalServerError("Failed to create invoice", errors.New("duplicate key"))
+	}
+
+	doc, err := NewInvoiceService(repo, nil).RenderOrderDocument(context.Background(), order.ID, invoice.KindInvoice)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-HQ01-000005", doc.InvoiceNo)
+}
+
+func TestInvoiceService_RenderOrderDocument_CanceledOrder(t *testing.T) {
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-004", OutletID: uuid.New(), Status: "CANCELED"}
+	repo := invoiceTestRepo(order)
+
+	_, err := NewInvoiceService(repo, nil).RenderOrderDocument(context.Background(), order.ID, invoice.KindInvoice)
+
+	var appErr *appErrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 422, appErr.StatusCode)
+}
+
+func TestInvoiceService_RenderOrderDocument_Access(t *testing.T) {
+	outlet := &entity.Outlet{ID: uuid.New(), Code: "HQ01", Name: "Laondry Kemang"}
+	owner := uuid.New()
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-005", OutletID: outlet.ID, Outlet: outlet, CustomerID: owner, Status: "NEW", GrandTotal: 60000}
+
+	tests := []struct {
+		name   string
+		user   *mw.UserClaims
+		status int
+	}{
+		{"Owner", &mw.UserClaims{UserID: owner.String(), Role: mw.RoleCustomer}, 0},
+		{"Cashier", &mw.UserClaims{UserID: uuid.NewString(), Role: mw.RoleKasir}, 0},
+		{"API key of the outlet", &mw.UserClaims{Role: mw.RoleService, APIKeyID: uuid.NewString(), Outlets: []string{outlet.ID.String()}}, 0},
+		{"Another customer", &mw.UserClaims{UserID: uuid.NewString(), Role: mw.RoleCustomer}, 403},
+		{"API key of another outlet", &mw.UserClaims{Role: mw.RoleService, APIKeyID: uuid.NewString(), Outlets: []string{uuid.NewString()}}, 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := invoiceTestRepo(order)
+			issued := false
+			repo.nextInvoiceNumberFn = func(context.Context, uuid.UUID) (int64, error) {
+				issued = true
+				return 1, nil
+			}
+			ctx := context.WithValue(context.Background(), mw.ContextUserKey, tt.user)
+
+			_, err := NewInvoiceService(repo, nil).RenderOrderDocument(ctx, order.ID, invoice.KindInvoice)
+
+			if tt.status == 0 {
+				require.NoError(t, err)
+				assert.True(t, issued)
+				return
+			}
+			var appErr *appErrors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tt.status, appErr.StatusCode)
+			assert.False(t, issued, "no invoice number may be issued for a rejected request")
+		})
+	}
+}
+
+func TestInvoiceService_SaveInvoiceTemplate_Defaults(t *testing.T) {
+	outletID := uuid.New()
+	var saved *entity.OutletInvoiceSetting
+	repo := &mockOrderRepository{
+		saveInvoiceSettingFn: func(_ context.Context, s *entity.OutletInvoiceSetting) error {
+			saved = s
+			return nil
+		},
+		findInvoiceSettingFn: func(context.Context, uuid.UUID) (*entity.OutletInvoiceSetting, error) { return saved, nil },
+	}
+
+	res, err := NewInvoiceService(repo, nil).SaveInvoiceTemplate(context.Background(), InvoiceTemplateRequest{
+		OutletID:   outletID,
+		Title:      "  NOTA  ",
+		FooterNote: "Terima kasih",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "NOTA", res.Title)
+	assert.Equal(t, "A4", res.PaperSize)
+	assert.Equal(t, 80, res.ReceiptWidthMM)
+	assert.True(t, res.ShowQRCode)
+}
+
+func TestInvoiceService_RenderThermal(t *testing.T) {
+	ctx := context.Background()
+	outlet := &entity.Outlet{ID: uuid.New(), Code: "HQ01", Name: "Laondry Kemang"}
+	weight := 2.0
+	order := &entity.Order{
+		ID: uuid.New(), OrderNo: "ORD-005", OutletID: outlet.ID, Outlet: outlet, Status: "NEW", GrandTotal: 30000,
+		Items: []entity.OrderItem{
+			{ServiceName: "Cuci Kering", WeightKg: &weight, UnitPrice: 10000, LineTotal: 20000},
+			{ServiceName: "Setrika Kilat", Qty: intPtr(1), UnitPrice: 10000, LineTotal: 10000, IsExpress: true},
+		},
+	}
+	repo := invoiceTestRepo(order)
+	repo.findInvoiceSettingFn = func(context.Context, uuid.UUID) (*entity.OutletInvoiceSetting, error) {
+		return &entity.OutletInvoiceSetting{OutletID: outlet.ID, ReceiptWidthMM: 80, FooterNote: "Terima kasih"}, nil
+	}
+	issued := 0
+	repo.nextInvoiceNumberFn = func(context.Context, uuid.UUID) (int64, error) {
+		issued++
+		return 9, nil
+	}
+	svc := NewInvoiceService(repo, nil)
+
+	// Tags are printed at drop-off, before an invoice is issued
+	tags, err := svc.RenderThermal(ctx, order.ID, invoice.KindTags, 58)
+	require.NoError(t, err)
+	assert.Empty(t, tags.InvoiceNo)
+	assert.Equal(t, 0, issued)
+	assert.Contains(t, tags.Preview, "Item 2/2")
+	assert.Contains(t, tags.Preview, "EXPRESS")
+	assert.Contains(t, tags.Preview, invoice.TagCode("ORD-005", 2))
+	assert.True(t, bytes.Contains(tags.ESCPOS, []byte("{BORD-005-1")))
+
+	receipt, err := svc.RenderThermal(ctx, order.ID, invoice.KindReceipt, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-HQ01-000009", receipt.InvoiceNo)
+	assert.Equal(t, 1, issued)
+	assert.Contains(t, receipt.Preview, "Terima kasih")
+	assert.Contains(t, receipt.Preview, "Setrika Kilat (EXPRESS)")
+	assert.True(t, bytes.HasPrefix(receipt.ESCPOS, []byte{0x1b, '@'}))
+}
+
+func TestInvoiceService_RenderThermal_TagsWithoutItems(t *testing.T) {
+	order := &entity.Order{ID: uuid.New(), OrderNo: "ORD-006", OutletID: uuid.New(), Status: "NEW"}
+
+	_, err := NewInvoiceService(invoiceTestRepo(order), nil).RenderThermal(context.Background(), order.ID, invoice.KindTags, 0)
+
+	var appErr *appErrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 422, appErr.StatusCode)
+}
@@ -7,6 +7,7 @@ import (
 
 	"laondry-order-service/internal/domain/order/repository"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
 )
 
 type OrderService interface {
@@ -22,6 +23,17 @@ type OrderService interface {
 	GetOrderStatusLogs(ctx context.Context, id uuid.UUID, page, limit int, sortOrder string) ([]entity.OrderStatusLog, int64, error)
 }
 
+// Option configures optional collaborators of the order service
+type Option func(*orderService)
+
+// WithEventPublisher publishes OrderCreated, OrderUpdated and
+// OrderStatusChanged once each change is committed
+func WithEventPublisher(p events.Publisher) Option {
+	return func(s *orderService) {
+		s.events = p
+	}
+}
+
 type CreateOrderRequest struct {
     // Customer and outlet info (customer_id will be set from auth context)
     CustomerID        uuid.UUID          `json:"customer_id"`
@@ -2,7 +2,8 @@ package service
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,8 +12,10 @@ import (
 
 	"laondry-order-service/internal/domain/order/repository"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
 	"laondry-order-service/internal/lock"
 	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/outbox"
 	appErrors "laondry-order-service/pkg/errors"
 )
 
@@ -20,10 +23,15 @@ type orderService struct {
 	orderRepo repository.OrderRepository
 	db        *gorm.DB
 	locker    lock.Locker
+	events    events.Publisher
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, db *gorm.DB, locker lock.Locker) OrderService {
-	return &orderService{orderRepo: orderRepo, db: db, locker: locker}
+func NewOrderService(orderRepo repository.OrderRepository, db *gorm.DB, locker lock.Locker, opts ...Option) OrderService {
+	s := &orderService{orderRepo: orderRepo, db: db, locker: locker}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *orderService) withTx(ctx context.Context, fn func(r repository.OrderRepository) error) error {
@@ -36,6 +44,13 @@ func (s *orderService) withTx(ctx context.Context, fn func(r repository.OrderRep
 	})
 }
 
+// publish hands a committed change to the event subscribers, if any
+func (s *orderService) publish(ctx context.Context, evt events.Event) {
+	if s.events != nil {
+		s.events.Publish(ctx, evt)
+	}
+}
+
 func (s *orderService) withLock(ctx context.Context, key string, ttl time.Duration, fn func() error) error {
 	if s.locker == nil {
 		return fn()
@@ -87,6 +102,10 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest)
 		txn.AddAttribute("outlet_id", req.OutletID.String())
 	}
 
+	if err := checkOutletScope(ctx, req.OutletID); err != nil {
+		return nil, err
+	}
+
 	// SECURITY: Verify user exists in database and get member tier
 	// Pricing should use member tier derived from authenticated user (core-api),
 	// mirroring Mobile ServiceController logic. We still accept client-provided
@@ -106,17 +125,17 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest)
 	// Try to get from JWT auth context first
 	if user, ok := mw.GetUserFromContext(ctx); ok && user != nil && user.MemberTierCode != nil && *user.MemberTierCode != "" {
 		selectedMemberTier = user.MemberTierCode
-		log.Printf("[PRICING] Using member_tier from JWT: %s", *selectedMemberTier)
+		slog.DebugContext(ctx, "[PRICING] Using member_tier from JWT", "member_tier", *selectedMemberTier)
 	} else if dbUser.MemberTier != nil && dbUser.MemberTier.Code != "" {
 		// Fallback to database user.member_tier.code
 		selectedMemberTier = &dbUser.MemberTier.Code
-		log.Printf("[PRICING] Using member_tier from DB (fallback): %s", *selectedMemberTier)
+		slog.DebugContext(ctx, "[PRICING] Using member_tier from DB (fallback)", "member_tier", *selectedMemberTier)
 	} else if req.MemberTier != nil && *req.MemberTier != "" {
 		// Last fallback: request-provided member_tier
 		selectedMemberTier = req.MemberTier
-		log.Printf("[PRICING] Using member_tier from request: %s", *selectedMemberTier)
+		slog.DebugContext(ctx, "[PRICING] Using member_tier from request", "member_tier", *selectedMemberTier)
 	} else {
-		log.Printf("[PRICING] No member_tier available, will use default pricing")
+		slog.DebugContext(ctx, "[PRICING] No member_tier available, will use default pricing")
 	}
 
 	// SECURITY: ALWAYS fetch all prices from database
@@ -154,15 +173,15 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest)
 			if selectedMemberTier != nil {
 				memberTierStr = *selectedMemberTier
 			}
-			log.Printf("[WARN] Price lookup failed, using base_price | service_id=%s service_code=%s outlet_id=%s member_tier=%s is_express=%v date=%s base_price=%.2f error=%v",
-				item.ServiceID.String(),
-				service.Code,
-				req.OutletID.String(),
-				memberTierStr,
-				item.IsExpress,
-				currentDate.Format("2006-01-02"),
-				service.BasePrice,
-				err,
+			slog.WarnContext(ctx, "[PRICING] Price lookup failed, using base_price",
+				"service_id", item.ServiceID,
+				"service_code", service.Code,
+				"outlet_id", req.OutletID,
+				"member_tier", memberTierStr,
+				"is_express", item.IsExpress,
+				"date", currentDate.Format("2006-01-02"),
+				"base_price", service.BasePrice,
+				"error", err,
 			)
 			item.UnitPrice = service.BasePrice
 		}
@@ -248,6 +267,7 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest)
 				Qty:         itemReq.Qty,
 				UnitPrice:   itemReq.UnitPrice,
 				LineTotal:   lineTotal,
+				IsExpress:   itemReq.IsExpress,
 			}
 
 			var addons []entity.OrderItemAddon
@@ -299,6 +319,9 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest)
 	if err != nil {
 		return nil, err
 	}
+	slog.InfoContext(ctx, "[Order] Order created",
+		"order_id", created.ID, "order_no", created.OrderNo, "outlet_id", created.OutletID, "grand_total", created.GrandTotal)
+	s.publish(ctx, events.NewOrderCreated(*created))
 	return created, nil
 }
 
@@ -312,9 +335,47 @@ func (s *orderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*entity.
 	if err != nil {
 		return nil, err
 	}
+	if err := checkOutletScope(ctx, order.OutletID); err != nil {
+		return nil, err
+	}
+	if err := s.fillPaidAmount(ctx, order); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
+// checkOutletScope rejects orders of outlets an outlet-scoped API key may
+// not touch
+func checkOutletScope(ctx context.Context, outletID uuid.UUID) error {
+	if user, ok := mw.GetUserFromContext(ctx); ok && !mw.CanAccessOutlet(user, outletID.String()) {
+		return appErrors.Forbidden("Order belongs to another outlet", nil)
+	}
+	return nil
+}
+
+// checkOrderAccess rejects orders of other outlets, as checkOutletScope, and
+// customers reading other customers' orders; staff and API keys may read any
+func checkOrderAccess(ctx context.Context, order *entity.Order) error {
+	if err := checkOutletScope(ctx, order.OutletID); err != nil {
+		return err
+	}
+	user, ok := mw.GetUserFromContext(ctx)
+	if ok && !user.IsAPIKey() && !mw.HasRole(user, mw.StaffRoles...) && order.CustomerID.String() != user.UserID {
+		return appErrors.Forbidden("Order belongs to another customer", nil)
+	}
+	return nil
+}
+
+// fillPaidAmount sets the paid/outstanding totals from the order's payments
+func (s *orderService) fillPaidAmount(ctx context.Context, order *entity.Order) error {
+	paid, err := s.orderRepo.SumPaidAmount(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	order.SetPaidAmount(paid)
+	return nil
+}
+
 func (s *orderService) GetOrderByOrderNo(ctx context.Context, orderNo string) (*entity.Order, error) {
 	if txn := newrelic.FromContext(ctx); txn != nil {
 		seg := txn.StartSegment("orders.GetOrderByOrderNo")
@@ -325,6 +386,12 @@ func (s *orderService) GetOrderByOrderNo(ctx context.Context, orderNo string) (*
 	if err != nil {
 		return nil, err
 	}
+	if err := checkOutletScope(ctx, order.OutletID); err != nil {
+		return nil, err
+	}
+	if err := s.fillPaidAmount(ctx, order); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
@@ -347,6 +414,18 @@ func (s *orderService) GetOrders(ctx context.Context, filters repository.OrderFi
 		txn.AddAttribute("page", filters.Page)
 		txn.AddAttribute("limit", filters.Limit)
 	}
+	// Outlet-scoped API keys only list their outlet's orders
+	if user, ok := mw.GetUserFromContext(ctx); ok && user.IsAPIKey() && len(user.Outlets) > 0 {
+		if filters.OutletID == nil {
+			outletID, err := uuid.Parse(user.Outlets[0])
+			if err != nil {
+				return nil, 0, appErrors.Forbidden("Invalid outlet scope", err)
+			}
+			filters.OutletID = &outletID
+		} else if err := checkOutletScope(ctx, *filters.OutletID); err != nil {
+			return nil, 0, err
+		}
+	}
 	orders, total, err := s.orderRepo.FindAll(ctx, filters)
 	if err != nil {
 		return nil, 0, err
@@ -368,6 +447,9 @@ func (s *orderService) UpdateOrder(ctx context.Context, id uuid.UUID, req Update
 			if err != nil {
 				return err
 			}
+			if err := checkOutletScope(ctx, order.OutletID); err != nil {
+				return err
+			}
 			if finalStatuses[order.Status] {
 				return appErrors.BadRequest("Cannot modify a finalized order", nil)
 			}
@@ -439,6 +521,7 @@ func (s *orderService) UpdateOrder(ctx context.Context, id uuid.UUID, req Update
 						Qty:         itemReq.Qty,
 						UnitPrice:   itemReq.UnitPrice,
 						LineTotal:   lineTotal,
+						IsExpress:   itemReq.IsExpress,
 					}
 
 					var addons []entity.OrderItemAddon
@@ -485,6 +568,7 @@ func (s *orderService) UpdateOrder(ctx context.Context, id uuid.UUID, req Update
 	if err != nil {
 		return nil, err
 	}
+	s.publish(ctx, events.NewOrderUpdated(*updated))
 	return updated, nil
 }
 
@@ -497,7 +581,11 @@ func (s *orderService) DeleteOrder(ctx context.Context, id uuid.UUID) error {
 	lockKey := "order:" + id.String()
 	return s.withLock(ctx, lockKey, 10*time.Second, func() error {
 		return s.withTx(ctx, func(r repository.OrderRepository) error {
-			if _, err := r.FindByID(ctx, id); err != nil {
+			order, err := r.FindByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := checkOutletScope(ctx, order.OutletID); err != nil {
 				return err
 			}
 			if err := r.Delete(ctx, id); err != nil {
@@ -516,18 +604,35 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, id uuid.UUID, req
 		txn.AddAttribute("to_status", req.Status)
 	}
 	lockKey := "order:" + id.String()
-	return s.withLock(ctx, lockKey, 10*time.Second, func() error {
+	var change events.OrderStatusChanged
+	err := s.withLock(ctx, lockKey, 10*time.Second, func() error {
 		return s.withTx(ctx, func(r repository.OrderRepository) error {
 			order, err := r.FindByID(ctx, id)
 			if err != nil {
 				return err
 			}
+			if err := checkOutletScope(ctx, order.OutletID); err != nil {
+				return err
+			}
 			if order.Status == req.Status {
 				return appErrors.BadRequest("Order already in "+req.Status+" status", nil)
 			}
 			if !isAllowedTransition(order.Status, req.Status) {
 				return appErrors.BadRequest("Invalid status transition from "+order.Status+" to "+req.Status, nil)
 			}
+			// Outlets may refuse to hand over orders that still have a balance
+			// (e.g. only the down payment was made)
+			if req.Status == "COMPLETED" && order.Outlet != nil && order.Outlet.RequireFullPaymentForCompletion {
+				paid, err := r.SumPaidAmount(ctx, id)
+				if err != nil {
+					return err
+				}
+				order.SetPaidAmount(paid)
+				if order.OutstandingAmount > 0 {
+					return appErrors.UnprocessableEntity(
+						fmt.Sprintf("Order must be fully paid before completion (outstanding %.0f)", order.OutstandingAmount), nil)
+				}
+			}
 			if err := r.UpdateStatus(ctx, id, req.Status); err != nil {
 				return err
 			}
@@ -542,12 +647,36 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, id uuid.UUID, req
 			if err := r.CreateStatusLog(ctx, logEntry); err != nil {
 				return err
 			}
+			change = events.NewOrderStatusChanged(*order, *logEntry)
+			// Open payments are canceled (or refunded) asynchronously so a
+			// gateway outage cannot block the cancellation itself
+			if req.Status == "CANCELED" {
+				msg, err := outbox.NewMessage(outbox.TopicOrderCanceled, &id, outbox.OrderCanceled{
+					OrderID:    id,
+					CanceledBy: req.ChangedBy,
+					Reason:     req.Note,
+				})
+				if err != nil {
+					return appErrors.InternalServerError("Failed to build cancellation event", err)
+				}
+				if err := r.CreateOutboxMessage(ctx, msg); err != nil {
+					return err
+				}
+			}
 			if txn := newrelic.FromContext(ctx); txn != nil {
 				txn.AddAttribute("from_status", order.Status)
 			}
 			return nil
 		})
 	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "[Order] Status changed",
+		"order_id", id, "order_no", change.OrderNo, "from_status", *change.FromStatus, "to_status", change.ToStatus)
+	s.publish(ctx, change)
+	return nil
 }
 
 func (s *orderService) CancelOrder(ctx context.Context, id uuid.UUID, canceledBy *uuid.UUID, reason *string) error {
@@ -576,7 +705,11 @@ func (s *orderService) GetOrderStatusLogs(ctx context.Context, id uuid.UUID, pag
 		txn.AddAttribute("sort_order", sortOrder)
 	}
 	// ensure order exists
-	if _, err := s.orderRepo.FindByID(ctx, id); err != nil {
+	order, err := s.orderRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkOutletScope(ctx, order.OutletID); err != nil {
 		return nil, 0, err
 	}
 	logs, total, err := s.orderRepo.ListStatusLogs(ctx, id, page, limit, sortOrder)
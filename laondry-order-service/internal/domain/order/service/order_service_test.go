@@ -11,6 +11,9 @@ import (
 
 	"laondry-order-service/internal/domain/order/repository"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/outbox"
 	appErrors "laondry-order-service/pkg/errors"
 )
 
@@ -24,6 +27,14 @@ type mockOrderRepository struct {
 	updateStatusFn    func(ctx context.Context, id uuid.UUID, status string) error
 	createStatusLogFn func(ctx context.Context, log *entity.OrderStatusLog) error
 	listStatusLogsFn  func(ctx context.Context, orderID uuid.UUID, page, limit int, sortOrder string) ([]entity.OrderStatusLog, int64, error)
+	createOutboxFn    func(ctx context.Context, msg *entity.OutboxMessage) error
+	sumPaidAmountFn   func(ctx context.Context, orderID uuid.UUID) (float64, error)
+
+	findInvoiceFn        func(ctx context.Context, orderID uuid.UUID) (*entity.OrderInvoice, error)
+	createInvoiceFn      func(ctx context.Context, invoice *entity.OrderInvoice) error
+	nextInvoiceNumberFn  func(ctx context.Context, outletID uuid.UUID) (int64, error)
+	findInvoiceSettingFn func(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error)
+	saveInvoiceSettingFn func(ctx context.Context, setting *entity.OutletInvoiceSetting) error
 }
 
 func (m *mockOrderRepository) Create(ctx context.Context, order *entity.Order) error {
@@ -89,6 +100,55 @@ func (m *mockOrderRepository) ListStatusLogs(ctx context.Context, orderID uuid.U
 	return nil, 0, nil
 }
 
+func (m *mockOrderRepository) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	if m.sumPaidAmountFn != nil {
+		return m.sumPaidAmountFn(ctx, orderID)
+	}
+	return 0, nil
+}
+
+func (m *mockOrderRepository) CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error {
+	if m.createOutboxFn != nil {
+		return m.createOutboxFn(ctx, msg)
+	}
+	return nil
+}
+
+func (m *mockOrderRepository) FindInvoiceByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.OrderInvoice, error) {
+	if m.findInvoiceFn != nil {
+		return m.findInvoiceFn(ctx, orderID)
+	}
+	return nil, nil
+}
+
+func (m *mockOrderRepository) CreateInvoice(ctx context.Context, invoice *entity.OrderInvoice) error {
+	if m.createInvoiceFn != nil {
+		return m.createInvoiceFn(ctx, invoice)
+	}
+	return nil
+}
+
+func (m *mockOrderRepository) NextInvoiceNumber(ctx context.Context, outletID uuid.UUID) (int64, error) {
+	if m.nextInvoiceNumberFn != nil {
+		return m.nextInvoiceNumberFn(ctx, outletID)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (m *mockOrderRepository) FindInvoiceSetting(ctx context.Context, outletID uuid.UUID) (*entity.OutletInvoiceSetting, error) {
+	if m.findInvoiceSettingFn != nil {
+		return m.findInvoiceSettingFn(ctx, outletID)
+	}
+	return nil, nil
+}
+
+func (m *mockOrderRepository) SaveInvoiceSetting(ctx context.Context, setting *entity.OutletInvoiceSetting) error {
+	if m.saveInvoiceSettingFn != nil {
+		return m.saveInvoiceSettingFn(ctx, setting)
+	}
+	return nil
+}
+
 // WithDB for mock just returns itself (no-op)
 func (m *mockOrderRepository) WithDB(db *gorm.DB) repository.OrderRepository { return m }
 
@@ -279,6 +339,112 @@ func TestOrderService_UpdateOrderStatusSuccess(t *testing.T) {
 	}
 }
 
+func TestOrderService_APIKeyOutletScope(t *testing.T) {
+	outletID, otherOutletID := uuid.New(), uuid.New()
+	var listed repository.OrderFilters
+	var statusUpdated bool
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: id, OutletID: otherOutletID, Status: "NEW"}, nil
+		},
+		findAllFn: func(ctx context.Context, filters repository.OrderFilters) ([]entity.Order, int64, error) {
+			listed = filters
+			return nil, 0, nil
+		},
+		updateStatusFn: func(ctx context.Context, id uuid.UUID, status string) error {
+			statusUpdated = true
+			return nil
+		},
+	}
+	service := NewOrderService(repo, nil, nil)
+	ctx := context.WithValue(context.Background(), mw.ContextUserKey, &mw.UserClaims{
+		Role:        mw.RoleService,
+		APIKeyID:    uuid.NewString(),
+		Permissions: []string{mw.PermOrdersRead, mw.PermOrdersStatus},
+		Outlets:     []string{outletID.String()},
+	})
+
+	var appErr *appErrors.AppError
+	err := service.UpdateOrderStatus(ctx, uuid.New(), UpdateStatusRequest{Status: "IN_PROGRESS"})
+	if !errors.As(err, &appErr) || appErr.StatusCode != 403 {
+		t.Fatalf("expected forbidden for another outlet's order, got %v", err)
+	}
+	if statusUpdated {
+		t.Fatalf("status must not change outside the key's outlet")
+	}
+	if _, err := service.GetOrderByID(ctx, uuid.New()); !errors.As(err, &appErr) || appErr.StatusCode != 403 {
+		t.Fatalf("expected forbidden reading another outlet's order, got %v", err)
+	}
+
+	if _, _, err := service.GetOrders(ctx, repository.OrderFilters{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if listed.OutletID == nil || *listed.OutletID != outletID {
+		t.Fatalf("expected orders filtered to the key's outlet, got %v", listed.OutletID)
+	}
+	if _, _, err := service.GetOrders(ctx, repository.OrderFilters{OutletID: &otherOutletID}); !errors.As(err, &appErr) || appErr.StatusCode != 403 {
+		t.Fatalf("expected forbidden listing another outlet, got %v", err)
+	}
+}
+
+func TestOrderService_CancelOrder_EnqueuesPaymentCancellation(t *testing.T) {
+	orderID := uuid.New()
+	canceledBy := uuid.New()
+	reason := "customer request"
+	var msgs []*entity.OutboxMessage
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: id, Status: "NEW"}, nil
+		},
+		createOutboxFn: func(ctx context.Context, msg *entity.OutboxMessage) error {
+			msgs = append(msgs, msg)
+			return nil
+		},
+	}
+
+	service := NewOrderService(repo, nil, nil)
+
+	if err := service.CancelOrder(context.Background(), orderID, &canceledBy, &reason); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Topic != outbox.TopicOrderCanceled {
+		t.Fatalf("expected one %s outbox message, got %+v", outbox.TopicOrderCanceled, msgs)
+	}
+	var evt outbox.OrderCanceled
+	if err := outbox.Decode(*msgs[0], &evt); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if evt.OrderID != orderID || evt.CanceledBy == nil || *evt.CanceledBy != canceledBy || *evt.Reason != reason {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+
+	// Other transitions do not touch payments
+	msgs = nil
+	if err := service.UpdateOrderStatus(context.Background(), orderID, UpdateStatusRequest{Status: "IN_PROGRESS"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("expected no outbox message, got %d", len(msgs))
+	}
+}
+
+func TestOrderService_CancelOrder_OutboxFailureAbortsCancel(t *testing.T) {
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: id, Status: "IN_PROGRESS"}, nil
+		},
+		createOutboxFn: func(ctx context.Context, msg *entity.OutboxMessage) error {
+			return errors.New("insert failed")
+		},
+	}
+
+	service := NewOrderService(repo, nil, nil)
+
+	if err := service.CancelOrder(context.Background(), uuid.New(), nil, nil); err == nil {
+		t.Fatalf("expected error when outbox message cannot be stored")
+	}
+}
+
 func TestOrderService_UpdateOrderStatus_InvalidTransition(t *testing.T) {
 	repo := &mockOrderRepository{
 		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
@@ -605,6 +771,35 @@ func TestOrderService_GetByID_And_GetByOrderNo(t *testing.T) {
 	}
 }
 
+func TestOrderService_GetByID_FillsPaidAmount(t *testing.T) {
+	id := uuid.New()
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, rid uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: rid, GrandTotal: 100000}, nil
+		},
+		sumPaidAmountFn: func(ctx context.Context, orderID uuid.UUID) (float64, error) {
+			if orderID != id {
+				t.Fatalf("unexpected order id %s", orderID)
+			}
+			return 60000, nil
+		},
+	}
+	svc := NewOrderService(repo, nil, nil)
+	got, err := svc.GetOrderByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.PaidAmount != 60000 || got.OutstandingAmount != 40000 {
+		t.Fatalf("expected paid 60000 / outstanding 40000, got %v / %v", got.PaidAmount, got.OutstandingAmount)
+	}
+
+	// Overpaid orders never report a negative outstanding amount
+	repo.sumPaidAmountFn = func(ctx context.Context, orderID uuid.UUID) (float64, error) { return 120000, nil }
+	if got, _ := svc.GetOrderByID(context.Background(), id); got.OutstandingAmount != 0 {
+		t.Fatalf("expected outstanding 0, got %v", got.OutstandingAmount)
+	}
+}
+
 func TestOrderService_OrderNumberUniqueness(t *testing.T) {
     var numbers []string
     repo := &mockOrderRepository{}
@@ -668,3 +863,131 @@ func TestOrderService_GetOrderStatusLogs_ForwardsToRepo(t *testing.T) {
 		t.Fatalf("unexpected results")
 	}
 }
+
+func TestOrderService_UpdateOrderStatus_RequiresFullPaymentForCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		require bool
+		paid    float64
+		wantErr bool
+	}{
+		{"Down payment only", true, 40000, true},
+		{"Fully paid", true, 100000, false},
+		{"Outlet does not require payment", false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated bool
+			repo := &mockOrderRepository{
+				findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+					return &entity.Order{
+						ID:         id,
+						Status:     "IN_PROGRESS",
+						GrandTotal: 100000,
+						Outlet:     &entity.Outlet{RequireFullPaymentForCompletion: tt.require},
+					}, nil
+				},
+				sumPaidAmountFn: func(ctx context.Context, orderID uuid.UUID) (float64, error) {
+					return tt.paid, nil
+				},
+				updateStatusFn: func(ctx context.Context, id uuid.UUID, status string) error {
+					updated = true
+					return nil
+				},
+				createStatusLogFn: func(ctx context.Context, log *entity.OrderStatusLog) error {
+					return nil
+				},
+			}
+			service := NewOrderService(repo, nil, nil)
+
+			err := service.UpdateOrderStatus(context.Background(), uuid.New(), UpdateStatusRequest{Status: "COMPLETED"})
+
+			if tt.wantErr {
+				appErr, ok := err.(*appErrors.AppError)
+				if !ok || appErr.StatusCode != 422 {
+					t.Fatalf("expected 422, got %v", err)
+				}
+				if updated {
+					t.Fatalf("expected status to stay IN_PROGRESS")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !updated {
+				t.Fatalf("expected UpdateStatus to be called on repository")
+			}
+		})
+	}
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, evt events.Event) {
+	p.events = append(p.events, evt)
+}
+
+func TestOrderService_UpdateOrderStatus_NotifiesAfterCommit(t *testing.T) {
+	failLog := false
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: id, Status: "IN_PROGRESS"}, nil
+		},
+		createStatusLogFn: func(ctx context.Context, log *entity.OrderStatusLog) error {
+			if failLog {
+				return errors.New("insert failed")
+			}
+			log.ID = uuid.New()
+			return nil
+		},
+	}
+	publisher := &recordingPublisher{}
+	service := NewOrderService(repo, nil, nil, WithEventPublisher(publisher))
+
+	orderID := uuid.New()
+	if err := service.UpdateOrderStatus(context.Background(), orderID, UpdateStatusRequest{Status: "COMPLETED"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(publisher.events))
+	}
+	c, ok := publisher.events[0].(events.OrderStatusChanged)
+	if !ok || c.OrderID != orderID || *c.FromStatus != "IN_PROGRESS" || c.ToStatus != "COMPLETED" || c.StatusLogID == uuid.Nil {
+		t.Fatalf("unexpected status change: %+v", publisher.events[0])
+	}
+
+	// Failed updates are not notified
+	failLog = true
+	if err := service.UpdateOrderStatus(context.Background(), orderID, UpdateStatusRequest{Status: "COMPLETED"}); err == nil {
+		t.Fatalf("expected error when status log cannot be stored")
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected no event for a failed update, got %d", len(publisher.events))
+	}
+}
+
+func TestOrderService_UpdateOrder_PublishesOrderUpdated(t *testing.T) {
+	orderID := uuid.New()
+	notes := "Extra starch"
+	repo := &mockOrderRepository{
+		findByIDFn: func(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+			return &entity.Order{ID: id, OrderNo: "ORD-001", Status: "NEW", Notes: &notes}, nil
+		},
+	}
+	publisher := &recordingPublisher{}
+	service := NewOrderService(repo, nil, nil, WithEventPublisher(publisher))
+
+	if _, err := service.UpdateOrder(context.Background(), orderID, UpdateOrderRequest{Notes: &notes}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(publisher.events))
+	}
+	if e, ok := publisher.events[0].(events.OrderUpdated); !ok || e.OrderID != orderID || e.OrderNo != "ORD-001" {
+		t.Fatalf("unexpected event: %+v", publisher.events[0])
+	}
+}
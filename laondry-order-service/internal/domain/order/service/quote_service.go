@@ -14,6 +14,9 @@ type QuoteRequest struct {
 	OutletID   uuid.UUID     `json:"outlet_id" validate:"required,uuid"`
 	MemberTier *string       `json:"member_tier"`
 	Date       *string       `json:"date"` // YYYY-MM-DD
+	// PaymentMethod (a Snap payment name such as credit_card) prices the
+	// surcharge passed on to the customer for that method
+	PaymentMethod *string    `json:"payment_method"`
 	Items      []QuoteItem   `json:"items" validate:"required,min=1,dive"`
 }
 
@@ -35,6 +38,8 @@ type QuoteResult struct {
 	Items    []QuoteResultItem  `json:"items"`
 	Subtotal float64            `json:"subtotal"`
 	GrandTotal float64          `json:"grand_total"`
+	PaymentSurcharge float64    `json:"payment_surcharge"` // Charged on top of GrandTotal for the requested payment method
+	AmountDue float64           `json:"amount_due"`        // GrandTotal + PaymentSurcharge
 }
 
 type QuoteMeta struct {
@@ -3,11 +3,12 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"laondry-order-service/internal/domain/order/repository"
 	"laondry-order-service/internal/lock"
+	"laondry-order-service/internal/surcharge"
 	appErrors "laondry-order-service/pkg/errors"
 
 	"github.com/google/uuid"
@@ -18,12 +19,14 @@ import (
 type quoteServiceImpl struct {
 	pricingRepo repository.PricingRepository
 	locker      lock.Locker
+	surcharges  surcharge.Table
 }
 
-func NewQuoteService(pricingRepo repository.PricingRepository, locker lock.Locker) QuoteService {
+func NewQuoteService(pricingRepo repository.PricingRepository, locker lock.Locker, surcharges surcharge.Table) QuoteService {
 	return &quoteServiceImpl{
 		pricingRepo: pricingRepo,
 		locker:      locker,
+		surcharges:  surcharges,
 	}
 }
 
@@ -50,7 +53,7 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 		txn.AddAttribute("items_count", len(req.Items))
 	}
 
-	log.Printf("[Quote] Calculating quote for outlet_id=%s, items=%d", req.OutletID.String(), len(req.Items))
+	slog.InfoContext(ctx, "[Quote] Calculating quote", "outlet_id", req.OutletID, "items", len(req.Items))
 
 	var result *QuoteResult
 	lockKey := "quote:calculate:" + req.OutletID.String()
@@ -73,7 +76,7 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 		serviceID, err := uuid.Parse(item.ServiceID)
 		if err != nil {
 			warnings = append(warnings, fmt.Sprintf("Item %d: Invalid service_id: %s", idx+1, item.ServiceID))
-			log.Printf("[Quote] Item %d: Invalid service_id: %s", idx+1, item.ServiceID)
+			slog.WarnContext(ctx, "[Quote] Invalid service_id", "item", idx+1, "service_id", item.ServiceID)
 			continue
 		}
 
@@ -82,9 +85,9 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				warnings = append(warnings, fmt.Sprintf("Item %d: Service not found: %s", idx+1, item.ServiceID))
-				log.Printf("[Quote] Item %d: Service not found: %s", idx+1, item.ServiceID)
+				slog.WarnContext(ctx, "[Quote] Service not found", "item", idx+1, "service_id", item.ServiceID)
 			} else {
-				log.Printf("[Quote] Item %d: Error fetching service: %v", idx+1, err)
+				slog.ErrorContext(ctx, "[Quote] Failed to fetch service", "item", idx+1, "service_id", item.ServiceID, "error", err)
 				return appErrors.InternalServerError("Failed to fetch service", err)
 			}
 			continue
@@ -95,9 +98,9 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 		servicePrice, err := s.pricingRepo.FindServicePrice(ctx, serviceID, req.OutletID, req.MemberTier, date, item.IsExpress)
 		if err == nil && servicePrice != nil {
 			unitPrice = servicePrice.Price
-			log.Printf("[Quote] Item %d: Found service price: %.2f", idx+1, unitPrice)
+			slog.DebugContext(ctx, "[Quote] Found service price", "item", idx+1, "unit_price", unitPrice)
 		} else {
-			log.Printf("[Quote] Item %d: Using base price: %.2f (no service_price found)", idx+1, unitPrice)
+			slog.DebugContext(ctx, "[Quote] Using base price (no service_price found)", "item", idx+1, "unit_price", unitPrice)
 		}
 
     // Calculate base total based on pricing model
@@ -135,7 +138,7 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 				if err == gorm.ErrRecordNotFound {
 					warnings = append(warnings, fmt.Sprintf("Item %d, Addon %d: Addon not found: %s", idx+1, addonIdx+1, addonReq.AddonID))
 				} else {
-					log.Printf("[Quote] Item %d, Addon %d: Error fetching addon: %v", idx+1, addonIdx+1, err)
+					slog.ErrorContext(ctx, "[Quote] Failed to fetch addon", "item", idx+1, "addon", addonIdx+1, "error", err)
 					return appErrors.InternalServerError("Failed to fetch addon", err)
 				}
 				continue
@@ -173,15 +176,23 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 		})
 
 		subtotal += lineTotal
-		log.Printf("[Quote] Item %d: %s (%.2f x %.2f = %.2f), addons: %.2f, total: %.2f",
-			idx+1, service.Code, unitPrice, baseTotal/unitPrice, baseTotal, addonsTotal, lineTotal)
+		slog.DebugContext(ctx, "[Quote] Item priced", "item", idx+1, "service_code", service.Code,
+			"unit_price", unitPrice, "quantity", baseTotal/unitPrice, "base_total", baseTotal,
+			"addons_total", addonsTotal, "line_total", lineTotal)
 	}
 
 		// TODO: Apply discount, tax, etc.
 		grandTotal := subtotal
 
-		log.Printf("[Quote] Quote calculated: subtotal=%.2f, grand_total=%.2f, items=%d, warnings=%d",
-			subtotal, grandTotal, len(items), len(warnings))
+		// The order total excludes payment fees; the surcharge only applies to
+		// the payment made with the requested method
+		var paymentSurcharge float64
+		if req.PaymentMethod != nil {
+			paymentSurcharge = s.surcharges.Surcharge(*req.PaymentMethod, grandTotal)
+		}
+
+		slog.InfoContext(ctx, "[Quote] Quote calculated", "subtotal", subtotal, "grand_total", grandTotal,
+			"items", len(items), "warnings", len(warnings))
 
 		result = &QuoteResult{
 			Meta: QuoteMeta{
@@ -193,6 +204,9 @@ func (s *quoteServiceImpl) CalculateQuote(ctx context.Context, req QuoteRequest)
 			Items:      items,
 			Subtotal:   subtotal,
 			GrandTotal: grandTotal,
+
+			PaymentSurcharge: paymentSurcharge,
+			AmountDue:        grandTotal + paymentSurcharge,
 		}
 
 		if txn := newrelic.FromContext(ctx); txn != nil {
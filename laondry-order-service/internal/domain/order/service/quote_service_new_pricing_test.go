@@ -14,7 +14,7 @@ import (
 
 func TestQuoteService_CalculateQuote_PricingModel_Weight_String(t *testing.T) {
     mockRepo := new(MockPricingRepository)
-    svc := NewQuoteService(mockRepo, nil)
+    svc := NewQuoteService(mockRepo, nil, nil)
 
     ctx := context.Background()
     serviceID := uuid.New()
@@ -36,7 +36,7 @@ func TestQuoteService_CalculateQuote_PricingModel_Weight_String(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_PricingModel_Piece_String(t *testing.T) {
     mockRepo := new(MockPricingRepository)
-    svc := NewQuoteService(mockRepo, nil)
+    svc := NewQuoteService(mockRepo, nil, nil)
 
     ctx := context.Background()
     serviceID := uuid.New()
@@ -58,7 +58,7 @@ func TestQuoteService_CalculateQuote_PricingModel_Piece_String(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_UsesMemberTier_And_Express(t *testing.T) {
     mockRepo := new(MockPricingRepository)
-    svc := NewQuoteService(mockRepo, nil)
+    svc := NewQuoteService(mockRepo, nil, nil)
 
     ctx := context.Background()
     serviceID := uuid.New()
@@ -15,7 +15,7 @@ import (
 
 func TestQuoteService_CalculateQuote_Concurrent(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	serviceID := uuid.New()
 	addonID := uuid.New()
@@ -119,7 +119,7 @@ func TestQuoteService_CalculateQuote_Concurrent(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_ConcurrentWithErrors(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	serviceID1 := uuid.New()
 	serviceID2 := uuid.New()
@@ -201,7 +201,7 @@ func TestQuoteService_CalculateQuote_RaceConditionSafety(t *testing.T) {
 	// Run with: go test -race ./internal/domain/order/service/...
 
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	serviceID := uuid.New()
 	outletID := uuid.New()
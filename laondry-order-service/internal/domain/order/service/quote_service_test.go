@@ -7,6 +7,7 @@ import (
 
 	"laondry-order-service/internal/domain/order/repository"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/surcharge"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -49,7 +50,7 @@ func (m *MockPricingRepository) WithDB(db *gorm.DB) repository.PricingRepository
 
 func TestQuoteService_CalculateQuote_Success(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	ctx := context.Background()
 	serviceID := uuid.New()
@@ -127,7 +128,7 @@ func TestQuoteService_CalculateQuote_Success(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_ServiceNotFound(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	ctx := context.Background()
 	serviceID := uuid.New()
@@ -162,7 +163,7 @@ func TestQuoteService_CalculateQuote_ServiceNotFound(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_InvalidServiceID(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	ctx := context.Background()
 	outletID := uuid.New()
@@ -191,7 +192,7 @@ func TestQuoteService_CalculateQuote_InvalidServiceID(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_MissingWeightForKgPricing(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	ctx := context.Background()
 	serviceID := uuid.New()
@@ -233,7 +234,7 @@ func TestQuoteService_CalculateQuote_MissingWeightForKgPricing(t *testing.T) {
 
 func TestQuoteService_CalculateQuote_MultipleItems(t *testing.T) {
 	mockRepo := new(MockPricingRepository)
-	svc := NewQuoteService(mockRepo, nil)
+	svc := NewQuoteService(mockRepo, nil, nil)
 
 	ctx := context.Background()
 	serviceID1 := uuid.New()
@@ -292,3 +293,49 @@ func TestQuoteService_CalculateQuote_MultipleItems(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestQuoteService_CalculateQuote_PaymentSurcharge(t *testing.T) {
+	mockRepo := new(MockPricingRepository)
+	surcharges, err := surcharge.Parse("credit_card:2.9%+2000:customer,gopay:2%")
+	assert.NoError(t, err)
+	svc := NewQuoteService(mockRepo, nil, surcharges)
+
+	ctx := context.Background()
+	serviceID := uuid.New()
+	outletID := uuid.New()
+
+	mockRepo.On("FindServiceByID", ctx, serviceID).Return(&entity.Service{
+		ID:           serviceID,
+		Code:         "CUCI_KERING",
+		Name:         "Cuci Kering",
+		PricingModel: "PER_KG",
+		BasePrice:    10000,
+	}, nil)
+	mockRepo.On("FindServicePrice", ctx, serviceID, outletID, mock.Anything, mock.Anything, false).Return(nil, gorm.ErrRecordNotFound)
+
+	weight := 10.0
+	quote := func(method *string) *QuoteResult {
+		result, err := svc.CalculateQuote(ctx, QuoteRequest{
+			OutletID:      outletID,
+			PaymentMethod: method,
+			Items:         []QuoteItem{{ServiceID: serviceID.String(), WeightKg: &weight}},
+		})
+		assert.NoError(t, err)
+		return result
+	}
+
+	// Passed on to the customer: due on top of the unchanged order total
+	card := "credit_card"
+	result := quote(&card)
+	assert.Equal(t, 100000.0, result.GrandTotal)
+	assert.Equal(t, 5047.0, result.PaymentSurcharge)
+	assert.Equal(t, 105047.0, result.AmountDue)
+
+	// Absorbed by the merchant, or no method picked yet
+	gopay := "gopay"
+	for _, method := range []*string{&gopay, nil} {
+		result := quote(method)
+		assert.Zero(t, result.PaymentSurcharge)
+		assert.Equal(t, 100000.0, result.AmountDue)
+	}
+}
@@ -2,14 +2,19 @@ package payment
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
 	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
 	phandler "laondry-order-service/internal/domain/payment/handler/rest"
 	prepo "laondry-order-service/internal/domain/payment/repository"
 	pservice "laondry-order-service/internal/domain/payment/service"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
 	"laondry-order-service/internal/lock"
+	"laondry-order-service/internal/outbox"
+	"laondry-order-service/internal/scheduler"
 	"laondry-order-service/pkg/validator"
 
 	redis "github.com/redis/go-redis/v9"
@@ -18,11 +23,12 @@ import (
 
 type PaymentDomain struct {
 	Repository prepo.PaymentRepository
-	Service    pservice.MidtransService
+	Service    pservice.PaymentService
 	Handler    *phandler.MidtransHandler
+	Locker     lock.Locker
 }
 
-func NewPaymentDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB) *PaymentDomain {
+func NewPaymentDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB, publisher events.Publisher) *PaymentDomain {
 	repo := prepo.NewPaymentRepository(db)
 
 	// Try Redis locker if REDIS_ADDR set, fallback to memory locker
@@ -37,23 +43,65 @@ func NewPaymentDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB) *
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
 		if err := rdb.Ping(ctx).Err(); err != nil {
-			log.Printf("[Payment Lock] Redis connection failed: %v, using in-memory locker", err)
+			slog.Warn("[Payment Lock] Redis connection failed, using in-memory locker", "error", err)
 			locker = lock.NewMemoryLocker()
 		} else {
-			log.Printf("[Payment Lock] Using Redis locker at %s", cfg.Redis.Addr)
+			slog.Info("[Payment Lock] Using Redis locker", "addr", cfg.Redis.Addr)
 			locker = lock.NewRedisLocker(rdb)
 		}
 	} else {
-		log.Printf("[Payment Lock] Using in-memory locker (no Redis configured)")
+		slog.Info("[Payment Lock] Using in-memory locker (no Redis configured)")
 		locker = lock.NewMemoryLocker()
 	}
+	locker = lock.Instrumented{Locker: locker}
 
-    svc := pservice.NewMidtransService(cfg, repo, db, locker)
+    gw := gateway.Instrumented{PaymentGateway: gateway.NewMidtrans(cfg.Midtrans, pservice.NewMerchantResolver(cfg, repo))}
+    svc := pservice.NewPaymentService(cfg, repo, db, locker, gw,
+        pservice.WithEventPublisher(publisher))
     h := phandler.NewMidtransHandler(svc, v, db)
 
 	return &PaymentDomain{
 		Repository: repo,
 		Service:    svc,
 		Handler:    h,
+		Locker:     locker,
 	}
 }
+
+// RegisterOutboxHandlers subscribes the payment domain to outbox topics
+func (d *PaymentDomain) RegisterOutboxHandlers(dispatcher *outbox.Dispatcher) {
+	dispatcher.Register(outbox.TopicPaymentSucceeded, func(ctx context.Context, msg entity.OutboxMessage) error {
+		var evt outbox.PaymentSucceeded
+		if err := outbox.Decode(msg, &evt); err != nil {
+			return err
+		}
+		return d.Service.ConfirmOrderPayment(ctx, evt.OrderID, evt.Note)
+	})
+	dispatcher.Register(outbox.TopicOrderCanceled, func(ctx context.Context, msg entity.OutboxMessage) error {
+		var evt outbox.OrderCanceled
+		if err := outbox.Decode(msg, &evt); err != nil {
+			return err
+		}
+		reason := ""
+		if evt.Reason != nil {
+			reason = *evt.Reason
+		}
+		return d.Service.CancelOrderPayments(ctx, evt.OrderID, evt.CanceledBy, reason)
+	})
+}
+
+// Jobs returns the payment background jobs enabled by configuration.
+func (d *PaymentDomain) Jobs(cfg *config.Config) []scheduler.Job {
+	var jobs []scheduler.Job
+	if cfg.Reconcile.Enabled && cfg.Reconcile.IntervalSeconds > 0 {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "payment-reconcile",
+			Interval: time.Duration(cfg.Reconcile.IntervalSeconds) * time.Second,
+			Run: func(ctx context.Context) error {
+				_, err := d.Service.ReconcilePendingTransactions(ctx)
+				return err
+			},
+		})
+	}
+	return jobs
+}
@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Fake is an in-memory PaymentGateway for tests. Charges start PENDING;
+// tests move them along with SetStatus. Webhooks are verified when their
+// "signature_key" equals Secret.
+type Fake struct {
+	Secret string
+	// Err, when set, is returned by every gateway call
+	Err error
+
+	mu       sync.Mutex
+	statuses map[string]*Status
+	refunds  map[string][]RefundRequest
+	calls    []string
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		Secret:   "fake-secret",
+		statuses: map[string]*Status{},
+		refunds:  map[string][]RefundRequest{},
+	}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) ClientKey(ctx context.Context) string {
+	return "fake-client-key"
+}
+
+// SetStatus sets the internal status the gateway reports for paymentOrderID
+func (f *Fake) SetStatus(paymentOrderID, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[paymentOrderID] = &Status{
+		PaymentOrderID:    paymentOrderID,
+		Status:            status,
+		TransactionStatus: status,
+		TransactionID:     "fake-trx-" + paymentOrderID,
+	}
+}
+
+// Calls lists the calls made so far as "<method> <payment order id>"
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Refunds returns the refunds requested for paymentOrderID
+func (f *Fake) Refunds(paymentOrderID string) []RefundRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]RefundRequest(nil), f.refunds[paymentOrderID]...)
+}
+
+func (f *Fake) record(method, paymentOrderID string) error {
+	f.calls = append(f.calls, method+" "+paymentOrderID)
+	return f.Err
+}
+
+func (f *Fake) CreateCharge(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateCharge", req.PaymentOrderID); err != nil {
+		return nil, err
+	}
+	f.statuses[req.PaymentOrderID] = &Status{PaymentOrderID: req.PaymentOrderID, Status: "PENDING", TransactionStatus: "PENDING"}
+	return &ChargeResult{
+		Token:           "fake-token-" + req.PaymentOrderID,
+		RedirectURL:     "https://pay.example.test/" + req.PaymentOrderID,
+		RequestPayload:  req,
+		ResponsePayload: map[string]interface{}{"token": "fake-token-" + req.PaymentOrderID},
+	}, nil
+}
+
+// CreateDirectCharge returns a pending charge with a fake VA number for bank
+// transfers and a fake QR string / deeplink otherwise
+func (f *Fake) CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateDirectCharge", req.PaymentOrderID); err != nil {
+		return nil, err
+	}
+	st := Status{
+		PaymentOrderID:    req.PaymentOrderID,
+		Status:            "PENDING",
+		TransactionStatus: "PENDING",
+		TransactionID:     "fake-trx-" + req.PaymentOrderID,
+		PaymentType:       req.Method,
+	}
+	f.statuses[req.PaymentOrderID] = &st
+	result := &DirectChargeResult{Status: st, RequestPayload: req}
+	switch req.Method {
+	case MethodBankTransfer:
+		result.VANumber, result.Bank = "8808"+req.PaymentOrderID, req.Bank
+	case MethodGoPay:
+		result.DeeplinkURL = "gojek://fake/" + req.PaymentOrderID
+		fallthrough
+	default:
+		result.QRString = "fake-qr-" + req.PaymentOrderID
+		result.QRCodeURL = "https://pay.example.test/qr/" + req.PaymentOrderID
+	}
+	return result, nil
+}
+
+func (f *Fake) CheckStatus(ctx context.Context, paymentOrderID string) (*Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CheckStatus", paymentOrderID); err != nil {
+		return nil, err
+	}
+	st, ok := f.statuses[paymentOrderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentOrderID)
+	}
+	cp := *st
+	return &cp, nil
+}
+
+func (f *Fake) Cancel(ctx context.Context, paymentOrderID string) (*Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Cancel", paymentOrderID); err != nil {
+		return nil, err
+	}
+	st, ok := f.statuses[paymentOrderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentOrderID)
+	}
+	if st.Status != "PENDING" {
+		return nil, fmt.Errorf("cannot cancel %s transaction", st.Status)
+	}
+	st.Status, st.TransactionStatus = "EXPIRED", "EXPIRED"
+	cp := *st
+	return &cp, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, paymentOrderID string, req RefundRequest) (*RefundResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Refund", paymentOrderID); err != nil {
+		return nil, err
+	}
+	if _, ok := f.statuses[paymentOrderID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentOrderID)
+	}
+	f.refunds[paymentOrderID] = append(f.refunds[paymentOrderID], req)
+	return &RefundResult{GatewayRefundID: fmt.Sprintf("fake-refund-%d", len(f.refunds[paymentOrderID]))}, nil
+}
+
+// VerifyWebhook reads "order_id" and "status" (an internal status) from payload
+func (f *Fake) VerifyWebhook(ctx context.Context, payload map[string]interface{}) *Notification {
+	orderID, _ := payload["order_id"].(string)
+	status, _ := payload["status"].(string)
+	signature, _ := payload["signature_key"].(string)
+	return &Notification{
+		Status:       Status{PaymentOrderID: orderID, Status: status, TransactionStatus: status, Raw: payload},
+		SignatureKey: signature,
+		Verified:     signature != "" && signature == f.Secret,
+	}
+}
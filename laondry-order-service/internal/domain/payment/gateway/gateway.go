@@ -0,0 +1,157 @@
+// Package gateway abstracts the payment provider behind PaymentGateway so the
+// payment service only deals with transactions, status logs and webhook logs.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned (wrapped) when the provider has no record of the
+	// transaction, e.g. a Snap page that was never opened.
+	ErrNotFound = errors.New("transaction not found at payment gateway")
+	// ErrNotConfigured is returned when the provider credentials are missing.
+	ErrNotConfigured = errors.New("payment gateway not configured")
+	// ErrMethodNotEnabled is returned when a direct charge asks for a payment
+	// method outside the configured allowlist.
+	ErrMethodNotEnabled = errors.New("payment method not enabled")
+	// ErrRejected is returned (wrapped) when the provider answered a request
+	// with a definite refusal (a 4xx other than a timeout or rate limit). Any
+	// other error leaves the outcome of the request unknown.
+	ErrRejected = errors.New("request rejected by payment gateway")
+)
+
+// PaymentGateway is implemented by every payment provider. Statuses returned
+// by a gateway are already mapped to our internal payment statuses (PENDING,
+// SUCCESS, CANCELED, EXPIRED, FAILED, REFUNDED, ...).
+type PaymentGateway interface {
+	// Name identifies the provider in webhook logs, e.g. "midtrans"
+	Name() string
+	// ClientKey is the public key handed to the frontend with a charge token;
+	// it depends on the outlet set with WithOutlet
+	ClientKey(ctx context.Context) string
+
+	CreateCharge(ctx context.Context, req ChargeRequest) (*ChargeResult, error)
+	// CreateDirectCharge charges without a hosted payment page and returns
+	// what the customer needs to pay right away: a VA number, QR or deeplink
+	CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResult, error)
+	CheckStatus(ctx context.Context, paymentOrderID string) (*Status, error)
+	// Cancel stops a pending transaction from being paid (Midtrans: expire)
+	Cancel(ctx context.Context, paymentOrderID string) (*Status, error)
+	Refund(ctx context.Context, paymentOrderID string, req RefundRequest) (*RefundResult, error)
+
+	// VerifyWebhook parses a notification payload and checks its signature.
+	// The notification is always returned so it can be logged; Verified
+	// reports whether it may be trusted.
+	VerifyWebhook(ctx context.Context, payload map[string]interface{}) *Notification
+}
+
+type Item struct {
+	ID    string
+	Name  string
+	Price int64
+	Qty   int32
+}
+
+type Customer struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+}
+
+type ChargeRequest struct {
+	PaymentOrderID  string
+	GrossAmount     int64
+	Items           []Item
+	Customer        *Customer
+	EnabledPayments []string
+	ExpiryMinutes   int
+}
+
+type ChargeResult struct {
+	Token       string
+	RedirectURL string
+	// Provider request/response bodies, stored on the transaction for auditing
+	RequestPayload  interface{}
+	ResponsePayload interface{}
+}
+
+// Direct charge methods
+const (
+	MethodQRIS         = "qris"
+	MethodBankTransfer = "bank_transfer"
+	MethodGoPay        = "gopay"
+)
+
+type DirectChargeRequest struct {
+	ChargeRequest
+	Method string
+	// Bank of a bank transfer VA: bca, bni, bri, permata, cimb or mandiri
+	Bank string
+	// CallbackURL is where the GoPay app returns to after payment
+	CallbackURL string
+}
+
+// DirectChargeResult is the pending transaction created by a direct charge
+type DirectChargeResult struct {
+	Status
+	QRString    string
+	QRCodeURL   string
+	DeeplinkURL string
+	ExpiryTime  *time.Time
+	// Provider request body, stored on the transaction for auditing; the
+	// response body is in Status.Raw
+	RequestPayload interface{}
+}
+
+// Status is the provider's view of a transaction
+type Status struct {
+	PaymentOrderID    string
+	Status            string // internal status
+	TransactionStatus string // provider status, e.g. "settlement"
+	TransactionID     string
+	PaymentType       string
+	FraudStatus       string
+	TransactionTime   *time.Time
+	SettlementTime    *time.Time
+	VANumber          string
+	Bank              string
+	BillerCode        string
+	BillKey           string
+	Raw               interface{}
+}
+
+type RefundRequest struct {
+	RefundKey string
+	Amount    int64
+	Reason    string
+}
+
+type RefundResult struct {
+	GatewayRefundID string
+	Raw             interface{}
+}
+
+// Notification is a parsed webhook payload
+type Notification struct {
+	Status
+	StatusCode   string
+	GrossAmount  string
+	SignatureKey string
+	Verified     bool
+	// Refunds carries the refund/chargeback list of refund notifications
+	Refunds []RefundEntry
+}
+
+// RefundEntry is one refund listed in a notification. Key is empty for
+// refunds made outside the API (e.g. from the provider dashboard).
+type RefundEntry struct {
+	Key             string
+	GatewayRefundID string
+	Amount          float64
+	Reason          string
+	Raw             map[string]interface{}
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"laondry-order-service/internal/metrics"
+)
+
+// Instrumented observes the API calls of a PaymentGateway in
+// metrics.GatewayRequestDuration and metrics.GatewayErrors. A transaction
+// unknown to the gateway is an answer, not a failed call.
+type Instrumented struct {
+	PaymentGateway
+}
+
+func (g Instrumented) CreateCharge(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	start := time.Now()
+	res, err := g.PaymentGateway.CreateCharge(ctx, req)
+	g.observe("create_charge", start, err)
+	return res, err
+}
+
+func (g Instrumented) CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResult, error) {
+	start := time.Now()
+	res, err := g.PaymentGateway.CreateDirectCharge(ctx, req)
+	g.observe("create_direct_charge", start, err)
+	return res, err
+}
+
+func (g Instrumented) CheckStatus(ctx context.Context, paymentOrderID string) (*Status, error) {
+	start := time.Now()
+	st, err := g.PaymentGateway.CheckStatus(ctx, paymentOrderID)
+	g.observe("check_status", start, err)
+	return st, err
+}
+
+func (g Instrumented) Cancel(ctx context.Context, paymentOrderID string) (*Status, error) {
+	start := time.Now()
+	st, err := g.PaymentGateway.Cancel(ctx, paymentOrderID)
+	g.observe("cancel", start, err)
+	return st, err
+}
+
+func (g Instrumented) Refund(ctx context.Context, paymentOrderID string, req RefundRequest) (*RefundResult, error) {
+	start := time.Now()
+	res, err := g.PaymentGateway.Refund(ctx, paymentOrderID, req)
+	g.observe("refund", start, err)
+	return res, err
+}
+
+func (g Instrumented) observe(operation string, start time.Time, err error) {
+	outcome := metrics.Outcome(err)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		outcome = "not_found"
+	case err != nil:
+		metrics.GatewayErrors.WithLabelValues(g.Name(), operation).Inc()
+	}
+	metrics.GatewayRequestDuration.WithLabelValues(g.Name(), operation, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/metrics"
+)
+
+func TestInstrumented(t *testing.T) {
+	fake := NewFake()
+	fake.SetStatus("ORDER-1", "SUCCESS")
+	gw := Instrumented{PaymentGateway: fake}
+	ctx := context.Background()
+
+	_, err := gw.CheckStatus(ctx, "ORDER-1")
+	assert.NoError(t, err)
+	_, err = gw.CheckStatus(ctx, "ORDER-UNKNOWN")
+	assert.ErrorIs(t, err, ErrNotFound)
+	fake.Err = errors.New("gateway down")
+	_, err = gw.Refund(ctx, "ORDER-1", RefundRequest{Amount: 1000})
+	assert.Error(t, err)
+
+	observations := func(operation, outcome string) uint64 {
+		var m dto.Metric
+		h := metrics.GatewayRequestDuration.WithLabelValues("fake", operation, outcome).(prometheus.Metric)
+		require.NoError(t, h.Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(1), observations("check_status", metrics.OutcomeOK))
+	assert.Equal(t, uint64(1), observations("check_status", "not_found"))
+	assert.Equal(t, uint64(1), observations("refund", metrics.OutcomeError))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.GatewayErrors.WithLabelValues("fake", "check_status")), "not found is no error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GatewayErrors.WithLabelValues("fake", "refund")))
+}
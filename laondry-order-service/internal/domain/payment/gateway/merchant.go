@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Merchant is the provider account a payment settles to
+type Merchant struct {
+	ServerKey string
+	ClientKey string
+	// EnabledPayments overrides the configured allowlist when not empty
+	EnabledPayments []string
+}
+
+// MerchantResolver looks up the merchant account of an outlet. Both methods
+// return nil, nil when the outlet settles to the default account from
+// configuration.
+type MerchantResolver interface {
+	MerchantForOutlet(ctx context.Context, outletID uuid.UUID) (*Merchant, error)
+	// MerchantForPayment resolves the outlet through the order of an existing
+	// payment transaction
+	MerchantForPayment(ctx context.Context, paymentOrderID string) (*Merchant, error)
+}
+
+type outletKey struct{}
+
+// WithOutlet tells the gateway which outlet a new charge belongs to, so it is
+// created with that outlet's merchant account
+func WithOutlet(ctx context.Context, outletID uuid.UUID) context.Context {
+	return context.WithValue(ctx, outletKey{}, outletID)
+}
+
+// outletFromContext returns the outlet set by WithOutlet
+func outletFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(outletKey{}).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
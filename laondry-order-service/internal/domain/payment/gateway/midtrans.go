@@ -0,0 +1,664 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	midtrans "github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/midtrans/midtrans-go/snap"
+
+	"laondry-order-service/internal/config"
+)
+
+type midtransGateway struct {
+	cfg       config.MidtransConfig
+	merchants MerchantResolver
+}
+
+// NewMidtrans returns the Midtrans adapter: Snap for charges and the Core API
+// for direct charges, status checks, expiry and refunds. merchants may be nil,
+// in which case every outlet uses the keys from cfg.
+func NewMidtrans(cfg config.MidtransConfig, merchants MerchantResolver) PaymentGateway {
+	return &midtransGateway{cfg: cfg, merchants: merchants}
+}
+
+func (g *midtransGateway) Name() string {
+	return "midtrans"
+}
+
+func (g *midtransGateway) ClientKey(ctx context.Context) string {
+	m, err := g.merchant(ctx, "")
+	if err != nil {
+		slog.ErrorContext(ctx, "[Payment] Failed to resolve Midtrans merchant", "error", err)
+		return ""
+	}
+	return m.ClientKey
+}
+
+// merchant picks the account for a call: the outlet set with WithOutlet, else
+// the outlet of the order paymentOrderID belongs to, else the default keys.
+// Lookup errors are returned rather than falling back, so a franchise payment
+// is never charged or verified with the wrong account.
+func (g *midtransGateway) merchant(ctx context.Context, paymentOrderID string) (*Merchant, error) {
+	def := &Merchant{ServerKey: g.cfg.ServerKey, ClientKey: g.cfg.ClientKey, EnabledPayments: g.cfg.EnabledPayments}
+	if g.merchants == nil {
+		return def, nil
+	}
+
+	var m *Merchant
+	var err error
+	if outletID, ok := outletFromContext(ctx); ok {
+		m, err = g.merchants.MerchantForOutlet(ctx, outletID)
+	} else if paymentOrderID != "" {
+		m, err = g.merchants.MerchantForPayment(ctx, paymentOrderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve midtrans merchant: %w", err)
+	}
+	if m == nil {
+		return def, nil
+	}
+	if len(m.EnabledPayments) == 0 {
+		m.EnabledPayments = g.cfg.EnabledPayments
+	}
+	return m, nil
+}
+
+func (g *midtransGateway) env() midtrans.EnvironmentType {
+	// Select environment strictly from configuration.
+	// Midtrans no longer prefixes sandbox keys with "SB-", so prefix-based
+	// auto-detection can misroute requests. Rely on MIDTRANS_IS_PRODUCTION.
+	if g.cfg.IsProduction {
+		return midtrans.Production
+	}
+	return midtrans.Sandbox
+}
+
+func (g *midtransGateway) snapClient(m *Merchant) (*snap.Client, error) {
+	if m.ServerKey == "" {
+		return nil, fmt.Errorf("midtrans server key: %w", ErrNotConfigured)
+	}
+	// Masked log to help diagnose env mismatches without leaking secrets
+	tail := m.ServerKey
+	if len(tail) > 6 {
+		tail = tail[len(tail)-6:]
+	}
+	slog.Debug("[Payment] Midtrans Snap client init",
+		"env", map[bool]string{true: "production", false: "sandbox"}[g.cfg.IsProduction], "key_tail", "***"+tail)
+
+	c := &snap.Client{}
+	c.New(m.ServerKey, g.env())
+	c.HttpClient = g.httpClient()
+	return c, nil
+}
+
+func (g *midtransGateway) coreAPIClient(m *Merchant) (*coreapi.Client, error) {
+	if m.ServerKey == "" {
+		return nil, fmt.Errorf("midtrans server key: %w", ErrNotConfigured)
+	}
+	c := &coreapi.Client{}
+	c.New(m.ServerKey, g.env())
+	c.HttpClient = g.httpClient()
+	return c, nil
+}
+
+// CreateCharge creates a Snap transaction
+func (g *midtransGateway) CreateCharge(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	m, err := g.merchant(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.snapClient(m)
+	if err != nil {
+		return nil, err
+	}
+
+	items := snapItems(req)
+
+	var expiry *snap.ExpiryDetails
+	if req.ExpiryMinutes > 0 {
+		expiry = &snap.ExpiryDetails{
+			Unit:     "minutes",
+			Duration: int64(req.ExpiryMinutes),
+		}
+	}
+
+	var enabledPayments []snap.SnapPaymentType
+	for _, p := range filterPayments(m.EnabledPayments, req.EnabledPayments) {
+		enabledPayments = append(enabledPayments, snap.SnapPaymentType(p))
+	}
+
+	snapReq := &snap.Request{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.PaymentOrderID,
+			GrossAmt: req.GrossAmount,
+		},
+		Items:           &items,
+		CustomerDetail:  customerDetails(req.Customer),
+		EnabledPayments: enabledPayments,
+		Expiry:          expiry,
+	}
+
+	slog.InfoContext(ctx, "[Payment] Calling Midtrans CreateTransaction API", "payment_order_id", req.PaymentOrderID)
+	snapResp, mErr := c.CreateTransaction(snapReq)
+	if err := midtransError(mErr); err != nil {
+		return nil, err
+	}
+	if snapResp == nil || snapResp.Token == "" {
+		return nil, errors.New("empty response from midtrans")
+	}
+
+	return &ChargeResult{
+		Token:           snapResp.Token,
+		RedirectURL:     snapResp.RedirectURL,
+		RequestPayload:  snapReq,
+		ResponsePayload: snapResp,
+	}, nil
+}
+
+// CreateDirectCharge creates a Core API charge (POST /v2/charge). Mandiri VAs
+// are Midtrans "echannel" bill payments; the other banks are bank_transfer.
+func (g *midtransGateway) CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResult, error) {
+	m, err := g.merchant(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if allow := m.EnabledPayments; len(allow) > 0 && !directMethodAllowed(allow, req.Method, req.Bank) {
+		return nil, fmt.Errorf("%w: %s", ErrMethodNotEnabled, PaymentName(req.Method, req.Bank))
+	}
+
+	c, err := g.coreAPIClient(m)
+	if err != nil {
+		return nil, err
+	}
+
+	items := snapItems(req.ChargeRequest)
+	chargeReq := &coreapi.ChargeReq{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.PaymentOrderID,
+			GrossAmt: req.GrossAmount,
+		},
+		Items:           &items,
+		CustomerDetails: customerDetails(req.Customer),
+	}
+	if req.ExpiryMinutes > 0 {
+		chargeReq.CustomExpiry = &coreapi.CustomExpiry{
+			ExpiryDuration: req.ExpiryMinutes,
+			Unit:           "minute",
+		}
+	}
+
+	switch req.Method {
+	case MethodQRIS:
+		chargeReq.PaymentType = coreapi.PaymentTypeQris
+		chargeReq.Qris = &coreapi.QrisDetails{Acquirer: "gopay"}
+	case MethodGoPay:
+		chargeReq.PaymentType = coreapi.PaymentTypeGopay
+		chargeReq.Gopay = &coreapi.GopayDetails{
+			EnableCallback: req.CallbackURL != "",
+			CallbackUrl:    req.CallbackURL,
+		}
+	case MethodBankTransfer:
+		switch req.Bank {
+		case "":
+			return nil, errors.New("bank is required for bank transfer")
+		case "mandiri":
+			chargeReq.PaymentType = coreapi.PaymentTypeEChannel
+			chargeReq.EChannel = &coreapi.EChannelDetail{
+				BillInfo1: "Payment for:",
+				BillInfo2: req.PaymentOrderID,
+			}
+		default:
+			chargeReq.PaymentType = coreapi.PaymentTypeBankTransfer
+			chargeReq.BankTransfer = &coreapi.BankTransferDetails{Bank: midtrans.Bank(req.Bank)}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payment method %q", req.Method)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Calling Midtrans Core API charge",
+		"payment_order_id", req.PaymentOrderID, "payment_method", PaymentName(req.Method, req.Bank))
+	resp, mErr := c.ChargeTransaction(chargeReq)
+	if err := midtransError(mErr); err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.TransactionID == "" {
+		return nil, errors.New("empty response from midtrans")
+	}
+
+	result := &DirectChargeResult{
+		Status: Status{
+			PaymentOrderID:    req.PaymentOrderID,
+			Status:            mapMidtransStatus(resp.TransactionStatus, resp.FraudStatus),
+			TransactionStatus: resp.TransactionStatus,
+			TransactionID:     resp.TransactionID,
+			PaymentType:       resp.PaymentType,
+			FraudStatus:       resp.FraudStatus,
+			TransactionTime:   parseTime(resp.TransactionTime),
+			BillerCode:        resp.BillerCode,
+			BillKey:           resp.BillKey,
+			Raw:               resp,
+		},
+		QRString:       resp.QRString,
+		ExpiryTime:     parseTime(resp.ExpiryTime),
+		RequestPayload: chargeReq,
+	}
+	switch {
+	case len(resp.VaNumbers) > 0:
+		result.VANumber = resp.VaNumbers[0].VANumber
+		result.Bank = resp.VaNumbers[0].Bank
+	case resp.PermataVaNumber != "":
+		result.VANumber = resp.PermataVaNumber
+		result.Bank = "permata"
+	case resp.BillKey != "":
+		result.Bank = "mandiri"
+	}
+	for _, a := range resp.Actions {
+		switch a.Name {
+		case "generate-qr-code":
+			result.QRCodeURL = a.URL
+		case "deeplink-redirect":
+			result.DeeplinkURL = a.URL
+		}
+	}
+	return result, nil
+}
+
+// PaymentName is the Snap payment name of a direct charge method or a
+// notification's payment type, the names used in MIDTRANS_ENABLED_PAYMENTS
+func PaymentName(method, bank string) string {
+	switch method {
+	case MethodQRIS:
+		return "other_qris"
+	case MethodBankTransfer:
+		if bank == "mandiri" {
+			return "echannel"
+		}
+		return bank + "_va"
+	}
+	return method
+}
+
+func directMethodAllowed(allow []string, method, bank string) bool {
+	name := PaymentName(method, bank)
+	for _, a := range allow {
+		if a == name || a == method {
+			return true
+		}
+	}
+	return false
+}
+
+func customerDetails(c *Customer) *midtrans.CustomerDetails {
+	if c == nil {
+		return nil
+	}
+	return &midtrans.CustomerDetails{
+		FName: c.FirstName,
+		LName: c.LastName,
+		Email: c.Email,
+		Phone: c.Phone,
+	}
+}
+
+// snapItems converts the request items, adjusting them so they add up to the
+// gross amount (Midtrans rejects the request with a 400 otherwise).
+func snapItems(req ChargeRequest) []midtrans.ItemDetails {
+	var items []midtrans.ItemDetails
+	var itemsTotal int64
+	for _, it := range req.Items {
+		items = append(items, midtrans.ItemDetails{
+			ID:    it.ID,
+			Name:  it.Name,
+			Price: it.Price,
+			Qty:   it.Qty,
+		})
+		itemsTotal += it.Price * int64(it.Qty)
+	}
+	if itemsTotal == req.GrossAmount {
+		return items
+	}
+
+	slog.Info("[Payment] Adjusting items to match gross_amount",
+		"payment_order_id", req.PaymentOrderID, "gross_amount", req.GrossAmount, "items_total", itemsTotal)
+	single := []midtrans.ItemDetails{{
+		ID:    req.PaymentOrderID,
+		Name:  fmt.Sprintf("Order %s", req.PaymentOrderID),
+		Price: req.GrossAmount,
+		Qty:   1,
+	}}
+	switch {
+	case len(items) == 1:
+		// Single item: set its price to gross amount
+		items[0].Price = req.GrossAmount
+		items[0].Qty = 1
+		return items
+	case len(items) == 0:
+		return single
+	case req.GrossAmount > itemsTotal:
+		// Add adjustment line to reach gross total
+		return append(items, midtrans.ItemDetails{
+			ID:    "ADJUSTMENT",
+			Name:  "Adjustment",
+			Price: req.GrossAmount - itemsTotal,
+			Qty:   1,
+		})
+	default:
+		// Items exceed gross, collapse into single gross item
+		return single
+	}
+}
+
+// filterPayments filters the requested payment methods by the merchant's
+// allowlist (MIDTRANS_ENABLED_PAYMENTS unless set per outlet).
+func filterPayments(allow, requested []string) []string {
+	if len(allow) == 0 {
+		return requested
+	}
+	if len(requested) == 0 {
+		return allow
+	}
+	allowSet := map[string]struct{}{}
+	for _, a := range allow {
+		allowSet[a] = struct{}{}
+	}
+	var final []string
+	for _, p := range requested {
+		if _, ok := allowSet[p]; ok {
+			final = append(final, p)
+		} else {
+			slog.Debug("[Payment] Filtering unsupported payment", "payment_method", p)
+		}
+	}
+	if len(final) == 0 {
+		slog.Info("[Payment] All requested payments filtered; using allowlist defaults")
+		return allow
+	}
+	return final
+}
+
+// CheckStatus queries GET /v2/{order_id}/status
+func (g *midtransGateway) CheckStatus(ctx context.Context, paymentOrderID string) (*Status, error) {
+	m, err := g.merchant(ctx, paymentOrderID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.coreAPIClient(m)
+	if err != nil {
+		return nil, err
+	}
+	resp, mErr := c.CheckTransaction(paymentOrderID)
+	if err := midtransError(mErr); err != nil {
+		return nil, err
+	}
+
+	st := &Status{
+		PaymentOrderID:    paymentOrderID,
+		Status:            mapMidtransStatus(resp.TransactionStatus, resp.FraudStatus),
+		TransactionStatus: resp.TransactionStatus,
+		TransactionID:     resp.TransactionID,
+		PaymentType:       resp.PaymentType,
+		FraudStatus:       resp.FraudStatus,
+		TransactionTime:   parseTime(resp.TransactionTime),
+		SettlementTime:    parseTime(resp.SettlementTime),
+		BillerCode:        resp.BillerCode,
+		BillKey:           resp.BillKey,
+		Raw:               resp,
+	}
+	if len(resp.VaNumbers) > 0 {
+		st.VANumber = resp.VaNumbers[0].VANumber
+		st.Bank = resp.VaNumbers[0].Bank
+	}
+	return st, nil
+}
+
+// Cancel expires a pending transaction so its Snap page / VA can no longer be paid
+func (g *midtransGateway) Cancel(ctx context.Context, paymentOrderID string) (*Status, error) {
+	m, err := g.merchant(ctx, paymentOrderID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.coreAPIClient(m)
+	if err != nil {
+		return nil, err
+	}
+	resp, mErr := c.ExpireTransaction(paymentOrderID)
+	if err := midtransError(mErr); err != nil {
+		return nil, err
+	}
+	st := &Status{PaymentOrderID: paymentOrderID, Status: "EXPIRED", TransactionStatus: "expire", Raw: resp}
+	if resp != nil && resp.TransactionStatus != "" {
+		st.TransactionStatus = resp.TransactionStatus
+		st.Status = mapMidtransStatus(resp.TransactionStatus, resp.FraudStatus)
+	}
+	return st, nil
+}
+
+// Refund calls POST /v2/{order_id}/refund
+func (g *midtransGateway) Refund(ctx context.Context, paymentOrderID string, req RefundRequest) (*RefundResult, error) {
+	m, err := g.merchant(ctx, paymentOrderID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.coreAPIClient(m)
+	if err != nil {
+		return nil, err
+	}
+	resp, mErr := c.RefundTransaction(paymentOrderID, &coreapi.RefundReq{
+		RefundKey: req.RefundKey,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	})
+	if err := midtransError(mErr); err != nil {
+		return nil, err
+	}
+	result := &RefundResult{Raw: resp}
+	if resp != nil && resp.RefundChargebackID != 0 {
+		result.GatewayRefundID = strconv.Itoa(resp.RefundChargebackID)
+	}
+	return result, nil
+}
+
+// VerifyWebhook parses an HTTP notification and checks signature_key against
+// the server key of the merchant the payment belongs to
+func (g *midtransGateway) VerifyWebhook(ctx context.Context, payload map[string]interface{}) *Notification {
+	n := &Notification{
+		Status: Status{
+			PaymentOrderID:    getString(payload, "order_id"),
+			TransactionStatus: getString(payload, "transaction_status"),
+			TransactionID:     getString(payload, "transaction_id"),
+			PaymentType:       getString(payload, "payment_type"),
+			FraudStatus:       getString(payload, "fraud_status"),
+			TransactionTime:   parseTime(getString(payload, "transaction_time")),
+			SettlementTime:    parseTime(getString(payload, "settlement_time")),
+			BillerCode:        getString(payload, "biller_code"),
+			BillKey:           getString(payload, "bill_key"),
+			Raw:               payload,
+		},
+		StatusCode:   getString(payload, "status_code"),
+		GrossAmount:  getString(payload, "gross_amount"),
+		SignatureKey: getString(payload, "signature_key"),
+	}
+	n.Status.Status = mapMidtransStatus(n.TransactionStatus, n.FraudStatus)
+	if m, err := g.merchant(ctx, n.PaymentOrderID); err != nil {
+		slog.ErrorContext(ctx, "[Payment] Cannot verify notification", "payment_order_id", n.PaymentOrderID, "error", err)
+	} else {
+		n.Verified = verifySignature(m.ServerKey, n.PaymentOrderID, n.StatusCode, n.GrossAmount, n.SignatureKey)
+	}
+
+	if vaNumbers, ok := payload["va_numbers"].([]interface{}); ok && len(vaNumbers) > 0 {
+		if vaData, ok := vaNumbers[0].(map[string]interface{}); ok {
+			n.VANumber = getString(vaData, "va_number")
+			n.Bank = getString(vaData, "bank")
+		}
+	}
+
+	// Refund and chargeback notifications carry the full refund list
+	items, _ := payload["refunds"].([]interface{})
+	for _, item := range items {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		amount, err := strconv.ParseFloat(getString(data, "refund_amount"), 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+		n.Refunds = append(n.Refunds, RefundEntry{
+			Key:             getString(data, "refund_key"),
+			GatewayRefundID: refundChargebackID(data),
+			Amount:          amount,
+			Reason:          getString(data, "reason"),
+			Raw:             data,
+		})
+	}
+	return n
+}
+
+// VerifySignature checks a signature against the default server key
+func (g *midtransGateway) VerifySignature(orderID, statusCode, grossAmount, signature string) bool {
+	return verifySignature(g.cfg.ServerKey, orderID, statusCode, grossAmount, signature)
+}
+
+// verifySignature checks sha512(order_id + status_code + gross_amount + server_key)
+func verifySignature(serverKey, orderID, statusCode, grossAmount, signature string) bool {
+	if serverKey == "" {
+		return false
+	}
+	raw := fmt.Sprintf("%s%s%s%s", orderID, statusCode, strings.TrimSpace(grossAmount), serverKey)
+	sum := sha512.Sum512([]byte(raw))
+	expected := hex.EncodeToString(sum[:])
+	return strings.EqualFold(expected, signature)
+}
+
+func mapMidtransStatus(transactionStatus, fraudStatus string) string {
+	// Map Midtrans status to our internal status
+	switch transactionStatus {
+	case "capture":
+		if fraudStatus == "accept" {
+			return "SUCCESS"
+		}
+		return "PENDING"
+	case "settlement":
+		return "SUCCESS"
+	case "pending":
+		return "PENDING"
+	case "deny", "cancel":
+		return "CANCELED"
+	case "expire":
+		return "EXPIRED"
+	case "failure":
+		return "FAILED"
+	case "refund":
+		return "REFUNDED"
+	case "partial_refund":
+		return "PARTIALLY_REFUNDED"
+	case "chargeback":
+		return "CHARGEBACK"
+	case "partial_chargeback":
+		return "PARTIAL_CHARGEBACK"
+	default:
+		return "PENDING"
+	}
+}
+
+// httpClient returns the HTTP client used by the Snap and Core API clients.
+// When MIDTRANS_API_BASE_URL is set every request is sent to that host
+// instead (used to point the service at a fake Midtrans server).
+func (g *midtransGateway) httpClient() midtrans.HttpClient {
+	client := midtrans.GetHttpClient(g.env())
+	if g.cfg.APIBaseURL == "" {
+		return client
+	}
+	base, err := url.Parse(g.cfg.APIBaseURL)
+	if err != nil || base.Host == "" {
+		return client
+	}
+	client.HttpClient = &http.Client{
+		Timeout:   midtrans.DefaultHttpTimeout,
+		Transport: &baseURLTransport{base: base, next: http.DefaultTransport},
+	}
+	return client
+}
+
+// baseURLTransport rewrites the scheme and host of outgoing requests.
+type baseURLTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.base.Scheme
+	req.URL.Host = t.base.Host
+	req.Host = t.base.Host
+	return t.next.RoundTrip(req)
+}
+
+// midtransError converts a midtrans-go error into a plain error. The SDK
+// returns *midtrans.Error, which must not be assigned to error directly or a
+// nil pointer becomes a non-nil interface. A 404 wraps ErrNotFound and other
+// definite 4xx answers wrap ErrRejected; the SDK reports a timeout as 408.
+func midtransError(err *midtrans.Error) error {
+	if err == nil {
+		return nil
+	}
+	switch code := err.StatusCode; {
+	case code == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, err.Message)
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return err
+	case code >= 400 && code < 500:
+		return fmt.Errorf("%w: %s", ErrRejected, err.Message)
+	}
+	return err
+}
+
+// refundChargebackID reads refund_chargeback_id, which Midtrans sends as a number
+func refundChargebackID(data map[string]interface{}) string {
+	switch v := data["refund_chargeback_id"].(type) {
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	case string:
+		return v
+	}
+	return ""
+}
+
+func getString(m map[string]interface{}, key string) string {
+	if v, ok := m[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func parseTime(timeStr string) *time.Time {
+	if timeStr == "" {
+		return nil
+	}
+	// Midtrans time format: "2006-01-02 15:04:05"
+	layouts := []string{
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+		"2006-01-02T15:04:05Z",
+		"2006-01-02T15:04:05-07:00",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, timeStr); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
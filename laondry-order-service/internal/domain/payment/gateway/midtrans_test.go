@@ -0,0 +1,488 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"laondry-order-service/internal/config"
+)
+
+func testConfig() config.MidtransConfig {
+	return config.MidtransConfig{
+		ServerKey: "test-server-key",
+		ClientKey: "test-client-key",
+	}
+}
+
+func signature(orderID, statusCode, grossAmount string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + "test-server-key"))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestVerifySignature tests signature verification
+func TestVerifySignature(t *testing.T) {
+	g := &midtransGateway{cfg: testConfig()}
+
+	tests := []struct {
+		name          string
+		orderID       string
+		statusCode    string
+		grossAmount   string
+		signature     string
+		expectedValid bool
+	}{
+		{
+			name:          "Valid signature",
+			orderID:       "ORDER-123",
+			statusCode:    "200",
+			grossAmount:   "150000.00",
+			signature:     signature("ORDER-123", "200", "150000.00"),
+			expectedValid: true,
+		},
+		{
+			name:          "Gross amount with surrounding spaces",
+			orderID:       "ORDER-123",
+			statusCode:    "200",
+			grossAmount:   " 150000.00 ",
+			signature:     signature("ORDER-123", "200", "150000.00"),
+			expectedValid: true,
+		},
+		{
+			name:          "Invalid signature",
+			orderID:       "ORDER-123",
+			statusCode:    "200",
+			grossAmount:   "150000.00",
+			signature:     "invalid-signature",
+			expectedValid: false,
+		},
+		{
+			name:          "Empty signature",
+			orderID:       "ORDER-123",
+			statusCode:    "200",
+			grossAmount:   "150000.00",
+			signature:     "",
+			expectedValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := g.VerifySignature(tt.orderID, tt.statusCode, tt.grossAmount, tt.signature)
+			assert.Equal(t, tt.expectedValid, result)
+		})
+	}
+}
+
+// TestMapMidtransStatus tests status mapping
+func TestMapMidtransStatus(t *testing.T) {
+	tests := []struct {
+		name              string
+		transactionStatus string
+		fraudStatus       string
+		expected          string
+	}{
+		{
+			name:              "Settlement - Success",
+			transactionStatus: "settlement",
+			fraudStatus:       "",
+			expected:          "SUCCESS",
+		},
+		{
+			name:              "Capture with accept - Success",
+			transactionStatus: "capture",
+			fraudStatus:       "accept",
+			expected:          "SUCCESS",
+		},
+		{
+			name:              "Capture without accept - Pending",
+			transactionStatus: "capture",
+			fraudStatus:       "",
+			expected:          "PENDING",
+		},
+		{
+			name:              "Pending",
+			transactionStatus: "pending",
+			fraudStatus:       "",
+			expected:          "PENDING",
+		},
+		{
+			name:              "Deny - Canceled",
+			transactionStatus: "deny",
+			fraudStatus:       "",
+			expected:          "CANCELED",
+		},
+		{
+			name:              "Cancel - Canceled",
+			transactionStatus: "cancel",
+			fraudStatus:       "",
+			expected:          "CANCELED",
+		},
+		{
+			name:              "Expire - Expired",
+			transactionStatus: "expire",
+			fraudStatus:       "",
+			expected:          "EXPIRED",
+		},
+		{
+			name:              "Failure - Failed",
+			transactionStatus: "failure",
+			fraudStatus:       "",
+			expected:          "FAILED",
+		},
+		{
+			name:              "Refund - Refunded",
+			transactionStatus: "refund",
+			fraudStatus:       "",
+			expected:          "REFUNDED",
+		},
+		{
+			name:              "Partial refund - Partially refunded",
+			transactionStatus: "partial_refund",
+			fraudStatus:       "",
+			expected:          "PARTIALLY_REFUNDED",
+		},
+		{
+			name:              "Chargeback - Chargeback",
+			transactionStatus: "chargeback",
+			fraudStatus:       "",
+			expected:          "CHARGEBACK",
+		},
+		{
+			name:              "Partial chargeback - Partial chargeback",
+			transactionStatus: "partial_chargeback",
+			fraudStatus:       "",
+			expected:          "PARTIAL_CHARGEBACK",
+		},
+		{
+			name:              "Unknown - Pending",
+			transactionStatus: "unknown",
+			fraudStatus:       "",
+			expected:          "PENDING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mapMidtransStatus(tt.transactionStatus, tt.fraudStatus)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestParseTime tests time parsing helper
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeStr   string
+		shouldErr bool
+	}{
+		{
+			name:      "Valid Midtrans format",
+			timeStr:   "2025-01-05 14:30:00",
+			shouldErr: false,
+		},
+		{
+			name:      "Valid RFC3339 format",
+			timeStr:   "2025-01-05T14:30:00Z",
+			shouldErr: false,
+		},
+		{
+			name:      "Valid RFC3339 with timezone",
+			timeStr:   "2025-01-05T14:30:00+07:00",
+			shouldErr: false,
+		},
+		{
+			name:      "Invalid format",
+			timeStr:   "invalid-time",
+			shouldErr: true,
+		},
+		{
+			name:      "Empty string",
+			timeStr:   "",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseTime(tt.timeStr)
+			if tt.shouldErr {
+				assert.Nil(t, result)
+			} else {
+				assert.NotNil(t, result)
+				assert.False(t, result.IsZero())
+			}
+		})
+	}
+}
+
+// TestVerifyWebhook tests notification parsing
+func TestVerifyWebhook(t *testing.T) {
+	g := &midtransGateway{cfg: testConfig()}
+
+	n := g.VerifyWebhook(context.Background(), map[string]interface{}{
+		"order_id":           "ORDER-123",
+		"status_code":        "200",
+		"gross_amount":       "150000.00",
+		"signature_key":      signature("ORDER-123", "200", "150000.00"),
+		"transaction_status": "partial_refund",
+		"payment_type":       "bank_transfer",
+		"settlement_time":    "2025-01-05 14:30:00",
+		"va_numbers":         []interface{}{map[string]interface{}{"va_number": "8800123", "bank": "bca"}},
+		"refunds": []interface{}{
+			map[string]interface{}{"refund_chargeback_id": float64(7), "refund_amount": "25000.00", "refund_key": "K-1"},
+			map[string]interface{}{"refund_chargeback_id": float64(8), "refund_amount": "not-a-number"},
+		},
+	})
+
+	assert.True(t, n.Verified)
+	assert.Equal(t, "PARTIALLY_REFUNDED", n.Status.Status)
+	assert.Equal(t, "8800123", n.VANumber)
+	assert.Equal(t, "bca", n.Bank)
+	assert.NotNil(t, n.SettlementTime)
+	assert.Equal(t, []RefundEntry{{
+		Key:             "K-1",
+		GatewayRefundID: "7",
+		Amount:          25000,
+		Raw:             map[string]interface{}{"refund_chargeback_id": float64(7), "refund_amount": "25000.00", "refund_key": "K-1"},
+	}}, n.Refunds)
+}
+
+// TestCheckStatusNotFound tests that a Midtrans 404 maps to ErrNotFound
+func TestCheckStatusNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"status_code":"404","status_message":"Transaction doesn't exist."}`)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.APIBaseURL = srv.URL
+	g := NewMidtrans(cfg, nil)
+
+	_, err := g.CheckStatus(context.Background(), "ORDER-404")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = NewMidtrans(config.MidtransConfig{}, nil).CheckStatus(context.Background(), "ORDER-404")
+	assert.True(t, errors.Is(err, ErrNotConfigured))
+}
+
+// TestRefundErrors tests that only a definite refusal wraps ErrRejected
+func TestRefundErrors(t *testing.T) {
+	tests := []struct {
+		body     string
+		rejected bool
+	}{
+		{`{"status_code":"412","status_message":"Merchant cannot modify the status of the transaction"}`, true},
+		{`{"status_code":"429","status_message":"Too many requests"}`, false},
+		{`{"status_code":"500","status_message":"Internal server error"}`, false},
+		{`{"status_code":"503","status_message":"Service unavailable"}`, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, tt.body)
+		}))
+		cfg := testConfig()
+		cfg.APIBaseURL = srv.URL
+
+		_, err := NewMidtrans(cfg, nil).Refund(context.Background(), "ORDER-1", RefundRequest{RefundKey: "ORDER-1-REFUND-1", Amount: 1000})
+		srv.Close()
+
+		assert.Error(t, err, tt.body)
+		assert.Equal(t, tt.rejected, errors.Is(err, ErrRejected), tt.body)
+	}
+}
+
+// TestCreateDirectCharge tests that Core API charges are built per method and
+// that the VA number, QR code and deeplink are read from the response
+func TestCreateDirectCharge(t *testing.T) {
+	var charged map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/charge", r.URL.Path)
+		charged = nil
+		json.NewDecoder(r.Body).Decode(&charged)
+		w.Header().Set("Content-Type", "application/json")
+		switch charged["payment_type"] {
+		case "qris":
+			fmt.Fprint(w, `{"status_code":"201","transaction_id":"trx-1","order_id":"ORD-1","payment_type":"qris","transaction_status":"pending","transaction_time":"2025-03-03 10:00:00","expiry_time":"2025-03-03 10:15:00","qr_string":"00020101021226","actions":[{"name":"generate-qr-code","method":"GET","url":"https://api.midtrans.test/qr"}]}`)
+		case "gopay":
+			fmt.Fprint(w, `{"status_code":"201","transaction_id":"trx-2","order_id":"ORD-1","payment_type":"gopay","transaction_status":"pending","actions":[{"name":"generate-qr-code","url":"https://api.midtrans.test/qr"},{"name":"deeplink-redirect","url":"gojek://gopay/merchanttransfer?tref=1"}]}`)
+		case "echannel":
+			fmt.Fprint(w, `{"status_code":"201","transaction_id":"trx-3","order_id":"ORD-1","payment_type":"echannel","transaction_status":"pending","bill_key":"123456","biller_code":"70012"}`)
+		default:
+			fmt.Fprint(w, `{"status_code":"201","transaction_id":"trx-4","order_id":"ORD-1","payment_type":"bank_transfer","transaction_status":"pending","va_numbers":[{"bank":"bca","va_number":"12345678901"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.APIBaseURL = srv.URL
+	g := NewMidtrans(cfg, nil)
+	ctx := context.Background()
+	base := ChargeRequest{PaymentOrderID: "ORD-1", GrossAmount: 50000, ExpiryMinutes: 15}
+
+	res, err := g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodQRIS})
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", res.Status.Status)
+	assert.Equal(t, "00020101021226", res.QRString)
+	assert.Equal(t, "https://api.midtrans.test/qr", res.QRCodeURL)
+	assert.Equal(t, "2025-03-03 10:15:00", res.ExpiryTime.Format("2006-01-02 15:04:05"))
+	assert.Equal(t, map[string]interface{}{"expiry_duration": float64(15), "unit": "minute"}, charged["custom_expiry"])
+
+	res, err = g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodGoPay, CallbackURL: "laondry://paid"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gojek://gopay/merchanttransfer?tref=1", res.DeeplinkURL)
+	assert.Equal(t, "laondry://paid", charged["gopay"].(map[string]interface{})["callback_url"])
+
+	res, err = g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodBankTransfer, Bank: "bca"})
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901", res.VANumber)
+	assert.Equal(t, "bca", res.Bank)
+
+	res, err = g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodBankTransfer, Bank: "mandiri"})
+	assert.NoError(t, err)
+	assert.Equal(t, "echannel", charged["payment_type"])
+	assert.Equal(t, "123456", res.BillKey)
+	assert.Equal(t, "70012", res.BillerCode)
+
+	cfg.EnabledPayments = []string{"other_qris", "bni_va"}
+	g = NewMidtrans(cfg, nil)
+	_, err = g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodBankTransfer, Bank: "bca"})
+	assert.True(t, errors.Is(err, ErrMethodNotEnabled))
+	_, err = g.CreateDirectCharge(ctx, DirectChargeRequest{ChargeRequest: base, Method: MethodQRIS})
+	assert.NoError(t, err)
+}
+
+// stubMerchants serves one franchise merchant for an outlet and its payments
+type stubMerchants struct {
+	outletID uuid.UUID
+	payments map[string]bool
+	merchant *Merchant
+	err      error
+}
+
+func (s *stubMerchants) MerchantForOutlet(ctx context.Context, outletID uuid.UUID) (*Merchant, error) {
+	if outletID != s.outletID {
+		return nil, s.err
+	}
+	return s.merchant, s.err
+}
+
+func (s *stubMerchants) MerchantForPayment(ctx context.Context, paymentOrderID string) (*Merchant, error) {
+	if !s.payments[paymentOrderID] {
+		return nil, s.err
+	}
+	return s.merchant, s.err
+}
+
+// TestMerchantSelection tests that calls use the outlet's merchant keys
+func TestMerchantSelection(t *testing.T) {
+	var authUser string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authUser, _, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/snap/v1/transactions" {
+			fmt.Fprint(w, `{"token":"tok","redirect_url":"https://app.midtrans.test/snap/tok"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status_code":"200","transaction_status":"pending","order_id":"ORD-FR"}`)
+	}))
+	defer srv.Close()
+
+	franchise := uuid.New()
+	merchants := &stubMerchants{
+		outletID: franchise,
+		payments: map[string]bool{"ORD-FR": true},
+		merchant: &Merchant{ServerKey: "franchise-server-key", ClientKey: "franchise-client-key"},
+	}
+	cfg := testConfig()
+	cfg.APIBaseURL = srv.URL
+	g := NewMidtrans(cfg, merchants)
+	ctx := context.Background()
+
+	_, err := g.CreateCharge(WithOutlet(ctx, franchise), ChargeRequest{PaymentOrderID: "ORD-FR", GrossAmount: 10000})
+	assert.NoError(t, err)
+	assert.Equal(t, "franchise-server-key", authUser)
+	assert.Equal(t, "franchise-client-key", g.ClientKey(WithOutlet(ctx, franchise)))
+
+	_, err = g.CreateCharge(WithOutlet(ctx, uuid.New()), ChargeRequest{PaymentOrderID: "ORD-HQ", GrossAmount: 10000})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-server-key", authUser)
+	assert.Equal(t, "test-client-key", g.ClientKey(ctx))
+
+	_, err = g.CheckStatus(ctx, "ORD-FR")
+	assert.NoError(t, err)
+	assert.Equal(t, "franchise-server-key", authUser)
+
+	// Webhooks are verified with the key of the payment's outlet
+	sum := sha512.Sum512([]byte("ORD-FR" + "200" + "10000.00" + "franchise-server-key"))
+	payload := map[string]interface{}{
+		"order_id":      "ORD-FR",
+		"status_code":   "200",
+		"gross_amount":  "10000.00",
+		"signature_key": hex.EncodeToString(sum[:]),
+	}
+	assert.True(t, g.VerifyWebhook(ctx, payload).Verified)
+	payload["signature_key"] = signature("ORD-FR", "200", "10000.00")
+	assert.False(t, g.VerifyWebhook(ctx, payload).Verified, "default key must not verify a franchise payment")
+
+	merchants.err = errors.New("db down")
+	_, err = g.CheckStatus(ctx, "ORD-FR")
+	assert.Error(t, err)
+	assert.False(t, g.VerifyWebhook(ctx, payload).Verified)
+}
+
+// TestSnapItems tests that items are adjusted to the gross amount
+func TestSnapItems(t *testing.T) {
+	req := ChargeRequest{PaymentOrderID: "ORDER-1", GrossAmount: 30000}
+
+	req.Items = []Item{{ID: "a", Price: 10000, Qty: 1}, {ID: "b", Price: 10000, Qty: 2}}
+	assert.Len(t, snapItems(req), 2)
+
+	req.Items = []Item{{ID: "a", Price: 10000, Qty: 1}, {ID: "b", Price: 5000, Qty: 1}}
+	items := snapItems(req)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "ADJUSTMENT", items[2].ID)
+	assert.Equal(t, int64(15000), items[2].Price)
+
+	req.Items = []Item{{ID: "a", Price: 20000, Qty: 1}, {ID: "b", Price: 20000, Qty: 1}}
+	items = snapItems(req)
+	assert.Len(t, items, 1)
+	assert.Equal(t, int64(30000), items[0].Price)
+
+	req.Items = []Item{{ID: "a", Price: 20000, Qty: 2}}
+	items = snapItems(req)
+	assert.Equal(t, int64(30000), items[0].Price*int64(items[0].Qty))
+}
+
+// Benchmark tests
+func BenchmarkVerifySignature(b *testing.B) {
+	g := &midtransGateway{cfg: testConfig()}
+
+	orderID := "ORDER-BENCH-123"
+	statusCode := "200"
+	grossAmount := "150000.00"
+	signature := "test-signature"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.VerifySignature(orderID, statusCode, grossAmount, signature)
+	}
+}
+
+func BenchmarkMapMidtransStatus(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mapMidtransStatus("settlement", "accept")
+	}
+}
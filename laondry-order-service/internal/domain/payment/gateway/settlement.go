@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSettlementReport is returned (wrapped) when a settlement report
+// cannot be read, e.g. a required column is missing
+var ErrInvalidSettlementReport = errors.New("invalid settlement report")
+
+// wib is the timezone of times in Midtrans reports
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// SettlementRow is one transaction of a provider settlement report
+type SettlementRow struct {
+	Line           int // 1-based line in the file, for error messages
+	PaymentOrderID string
+	TransactionID  string
+	PaymentType    string
+	GrossAmount    float64
+	FeeAmount      float64
+	NetAmount      float64
+	SettlementTime time.Time
+	Raw            map[string]string
+}
+
+// settlementColumns maps our fields to the header names Midtrans has used in
+// dashboard exports, normalized by settlementHeader
+var settlementColumns = map[string][]string{
+	"order_id":         {"order_id", "merchant_order_id"},
+	"transaction_id":   {"transaction_id", "midtrans_transaction_id"},
+	"payment_type":     {"payment_type", "payment_method", "payment_channel"},
+	"amount":           {"amount", "gross_amount", "transaction_amount"},
+	"fee":              {"fee", "fee_amount", "total_fee", "mdr"},
+	"net":              {"net_amount", "nett_amount", "settlement_amount"},
+	"settlement_time":  {"settlement_time", "settlement_date", "settlement_datetime"},
+	"transaction_time": {"transaction_time", "transaction_date"},
+}
+
+var settlementTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+}
+
+// ParseMidtransSettlement reads a Midtrans settlement report (CSV export of
+// the dashboard). Columns are found by header name; each row needs an order
+// ID or transaction ID, an amount and a settlement (or transaction) time.
+// A missing fee column reads as 0 and a missing net amount as amount - fee.
+func ParseMidtransSettlement(r io.Reader) ([]SettlementRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrInvalidSettlementReport, err)
+	}
+	names := make([]string, len(header))
+	index := map[string]int{}
+	for i, h := range header {
+		names[i] = settlementHeader(h)
+		for field, aliases := range settlementColumns {
+			if _, found := index[field]; found {
+				continue
+			}
+			for _, alias := range aliases {
+				if names[i] == alias {
+					index[field] = i
+				}
+			}
+		}
+	}
+	switch {
+	case !hasColumn(index, "order_id") && !hasColumn(index, "transaction_id"):
+		return nil, fmt.Errorf("%w: no order ID or transaction ID column", ErrInvalidSettlementReport)
+	case !hasColumn(index, "amount"):
+		return nil, fmt.Errorf("%w: no amount column", ErrInvalidSettlementReport)
+	case !hasColumn(index, "settlement_time") && !hasColumn(index, "transaction_time"):
+		return nil, fmt.Errorf("%w: no settlement time column", ErrInvalidSettlementReport)
+	}
+
+	var rows []SettlementRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettlementReport, err)
+		}
+		line, _ := cr.FieldPos(0)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := SettlementRow{
+			Line:           line,
+			PaymentOrderID: field("order_id"),
+			TransactionID:  field("transaction_id"),
+			PaymentType:    field("payment_type"),
+			Raw:            make(map[string]string, len(record)),
+		}
+		for i, v := range record {
+			if i < len(names) {
+				row.Raw[names[i]] = v
+			}
+		}
+		if row.PaymentOrderID == "" && row.TransactionID == "" {
+			return nil, fmt.Errorf("%w: line %d: no order ID or transaction ID", ErrInvalidSettlementReport, line)
+		}
+		if row.GrossAmount, err = parseSettlementAmount(field("amount")); err != nil {
+			return nil, fmt.Errorf("%w: line %d: amount: %v", ErrInvalidSettlementReport, line, err)
+		}
+		if fee := field("fee"); fee != "" {
+			if row.FeeAmount, err = parseSettlementAmount(fee); err != nil {
+				return nil, fmt.Errorf("%w: line %d: fee: %v", ErrInvalidSettlementReport, line, err)
+			}
+		}
+		row.NetAmount = row.GrossAmount - row.FeeAmount
+		if net := field("net"); net != "" {
+			if row.NetAmount, err = parseSettlementAmount(net); err != nil {
+				return nil, fmt.Errorf("%w: line %d: net amount: %v", ErrInvalidSettlementReport, line, err)
+			}
+		}
+		settled := field("settlement_time")
+		if settled == "" {
+			settled = field("transaction_time")
+		}
+		if row.SettlementTime, err = parseSettlementTime(settled); err != nil {
+			return nil, fmt.Errorf("%w: line %d: settlement time: %v", ErrInvalidSettlementReport, line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func hasColumn(index map[string]int, field string) bool {
+	_, ok := index[field]
+	return ok
+}
+
+// settlementHeader normalizes "Settlement Time" / "settlement-time" to
+// settlement_time; Excel exports start with a byte order mark
+func settlementHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, h)
+}
+
+// parseSettlementAmount reads amounts such as "100000", "100,000.00" or
+// "IDR 100000"
+func parseSettlementAmount(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "IDR"), "Rp")
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" {
+		return 0, errors.New("empty")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseSettlementTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("empty")
+	}
+	for _, layout := range settlementTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, wib); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+// SettlementDay is the Midtrans (WIB) calendar day a settlement time falls
+// on, as midnight UTC so that it stores as the same DATE in any session
+// timezone
+func SettlementDay(t time.Time) time.Time {
+	y, m, d := t.In(wib).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// SettlementDayBounds is the time range [from, to) of a settlement day
+func SettlementDayBounds(day time.Time) (from, to time.Time) {
+	y, m, d := day.Date()
+	from = time.Date(y, m, d, 0, 0, 0, 0, wib)
+	return from, from.AddDate(0, 0, 1)
+}
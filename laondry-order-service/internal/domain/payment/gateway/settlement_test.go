@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMidtransSettlement(t *testing.T) {
+	report := "\ufeffOrder ID,Transaction ID,Payment Type,Amount,Fee,Net Amount,Settlement Time\n" +
+		"ORD-001,trx-1,gopay,\"100,000.00\",2000,98000,2025-03-10 23:30:00\n" +
+		"\n" +
+		"ORD-002,,bank_transfer,IDR 50000,4000,,2025-03-11 08:00:00\n"
+
+	rows, err := ParseMidtransSettlement(strings.NewReader(report))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	first := rows[0]
+	assert.Equal(t, "ORD-001", first.PaymentOrderID)
+	assert.Equal(t, "trx-1", first.TransactionID)
+	assert.Equal(t, "gopay", first.PaymentType)
+	assert.Equal(t, 100000.0, first.GrossAmount)
+	assert.Equal(t, 2000.0, first.FeeAmount)
+	assert.Equal(t, 98000.0, first.NetAmount)
+	assert.Equal(t, "ORD-001", first.Raw["order_id"])
+
+	// Net amount defaults to amount - fee; lines are counted from the header
+	assert.Equal(t, 46000.0, rows[1].NetAmount)
+	assert.Equal(t, 4, rows[1].Line)
+
+	// 23:30 WIB is still the 10th in Jakarta (16:30 UTC)
+	day := SettlementDay(first.SettlementTime)
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), day)
+	from, to := SettlementDayBounds(day)
+	assert.True(t, !first.SettlementTime.Before(from) && first.SettlementTime.Before(to))
+	assert.Equal(t, 24*time.Hour, to.Sub(from))
+}
+
+func TestParseMidtransSettlement_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"no reference column": "Amount,Settlement Time\n1000,2025-03-10\n",
+		"no amount column":    "Order ID,Settlement Time\nORD-1,2025-03-10\n",
+		"no time column":      "Order ID,Amount\nORD-1,1000\n",
+		"bad amount":          "Order ID,Amount,Settlement Time\nORD-1,abc,2025-03-10\n",
+		"bad time":            "Order ID,Amount,Settlement Time\nORD-1,1000,yesterday\n",
+		"no reference":        "Order ID,Amount,Settlement Time\n,1000,2025-03-10\n",
+		"empty file":          "",
+	}
+	for name, report := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseMidtransSettlement(strings.NewReader(report))
+			assert.ErrorIs(t, err, ErrInvalidSettlementReport)
+		})
+	}
+}
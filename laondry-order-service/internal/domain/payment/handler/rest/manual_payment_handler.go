@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+)
+
+// POST /api/v1/orders/{id}/payments/manual
+// Records a cash, bank transfer or EDC payment taken at the outlet counter. Cashier only.
+func (h *MidtransHandler) CreateManualPayment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid order id", err.Error())
+		return
+	}
+
+	var req service.ManualPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req.OrderID = orderID
+	if user, ok := mw.GetUserFromContext(r.Context()); ok {
+		if userID, err := uuid.Parse(user.UserID); err == nil {
+			req.RecordedBy = &userID
+		}
+	}
+
+	res, err := h.svc.RecordManualPayment(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, "payment recorded", res)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/service"
+	"laondry-order-service/pkg/response"
+)
+
+// PUT /api/v1/admin/outlets/{id}/merchant-credentials
+// Sets the Midtrans merchant account a franchise outlet settles to. Admin only.
+func (h *MidtransHandler) SetMerchantCredential(w http.ResponseWriter, r *http.Request) {
+	outletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid outlet id", err.Error())
+		return
+	}
+
+	var req service.MerchantCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req.OutletID = outletID
+	if userID, ok := currentUserID(r); ok {
+		req.UpdatedBy = &userID
+	}
+
+	res, err := h.svc.SetMerchantCredential(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "merchant credentials saved", res)
+}
+
+// GET /api/v1/admin/outlets/{id}/merchant-credentials
+// Shows an outlet's merchant account with the server key masked. Admin only.
+func (h *MidtransHandler) GetMerchantCredential(w http.ResponseWriter, r *http.Request) {
+	outletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid outlet id", err.Error())
+		return
+	}
+
+	res, err := h.svc.GetMerchantCredential(r.Context(), outletID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "merchant credentials retrieved", res)
+}
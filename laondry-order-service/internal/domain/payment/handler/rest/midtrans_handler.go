@@ -5,7 +5,6 @@ import (
     "fmt"
     "net/http"
     "strconv"
-    "time"
 
     "github.com/go-chi/chi/v5"
     "github.com/google/uuid"
@@ -19,26 +18,27 @@ import (
 )
 
 type MidtransHandler struct {
-    svc       service.MidtransService
+    svc       service.PaymentService
     validator *validator.Validator
     db        *gorm.DB
 }
 
-func NewMidtransHandler(svc service.MidtransService, v *validator.Validator, db *gorm.DB) *MidtransHandler {
+func NewMidtransHandler(svc service.PaymentService, v *validator.Validator, db *gorm.DB) *MidtransHandler {
     return &MidtransHandler{svc: svc, validator: v, db: db}
 }
 
 // POST /api/v1/payments/midtrans/token
-// Creates a Snap payment token for the given order
+// Creates a Snap payment token for (part of) the outstanding balance of an order
 func (h *MidtransHandler) CreateSnapToken(w http.ResponseWriter, r *http.Request) {
     // Accept order_id as UUID or order_no (string), and auto-generate payment_order_id if missing
     type createSnapTokenInput struct {
         OrderID         string            `json:"order_id" validate:"required"`
         PaymentOrderID  string            `json:"payment_order_id"`
-        GrossAmount     float64           `json:"gross_amount" validate:"required,gt=0"`
-        Items           []service.Item    `json:"items" validate:"required,min=1"`
+        GrossAmount     float64           `json:"gross_amount" validate:"gte=0"` // 0 or omitted = pay the outstanding balance
+        Items           []service.Item    `json:"items"`
         CustomerDetail  *service.Customer `json:"customer_detail"`
         EnabledPayments []string          `json:"enabled_payments"`
+        PaymentMethod   string            `json:"payment_method"` // Optional; adds the method's surcharge upfront
         ExpiryMinutes   int               `json:"expiry_minutes"`
     }
 
@@ -55,42 +55,21 @@ func (h *MidtransHandler) CreateSnapToken(w http.ResponseWriter, r *http.Request
         }
     }
 
-    // Resolve order ID: try UUID first, otherwise treat as order_no and look up
-    var orderUUID uuid.UUID
-    var resolvedOrderNo *string
-    if id, err := uuid.Parse(in.OrderID); err == nil {
-        orderUUID = id
-    } else {
-        if h.db == nil {
-            response.BadRequest(w, "invalid order_id", "order_id must be UUID or provide order_no when backend has DB access")
-            return
-        }
-        var ord entity.Order
-        if err := h.db.Where("order_no = ?", in.OrderID).First(&ord).Error; err != nil {
-            response.BadRequest(w, "invalid order_id", fmt.Sprintf("order not found for order_no '%s'", in.OrderID))
-            return
-        }
-        orderUUID = ord.ID
-        resolvedOrderNo = &ord.OrderNo
-    }
-
-    paymentOrderID := in.PaymentOrderID
-    if paymentOrderID == "" {
-        if resolvedOrderNo != nil {
-            paymentOrderID = *resolvedOrderNo
-        } else {
-            // Fallback: generate from provided order identifier
-            paymentOrderID = fmt.Sprintf("%s-PAY-%d", in.OrderID, time.Now().Unix())
-        }
+    orderUUID, ok := h.resolveOrderID(w, in.OrderID)
+    if !ok {
+        return
     }
 
+    // An empty payment_order_id is generated from the order number; split
+    // payments of the same order get -PAY-<n> suffixes
     req := service.CreateSnapTokenRequest{
         OrderID:         orderUUID,
-        PaymentOrderID:  paymentOrderID,
+        PaymentOrderID:  in.PaymentOrderID,
         GrossAmount:     in.GrossAmount,
         Items:           in.Items,
         CustomerDetail:  in.CustomerDetail,
         EnabledPayments: in.EnabledPayments,
+        PaymentMethod:   in.PaymentMethod,
         ExpiryMinutes:   in.ExpiryMinutes,
     }
 
@@ -111,6 +90,77 @@ func (h *MidtransHandler) CreateSnapToken(w http.ResponseWriter, r *http.Request
     response.Success(w, "snap token generated", res)
 }
 
+// POST /api/v1/payments/midtrans/charge
+// Creates a Core API charge (QRIS, bank transfer VA or GoPay) and returns the VA number / QR code directly
+func (h *MidtransHandler) CreateDirectCharge(w http.ResponseWriter, r *http.Request) {
+    type createDirectChargeInput struct {
+        OrderID        string            `json:"order_id" validate:"required"`
+        PaymentOrderID string            `json:"payment_order_id"`
+        GrossAmount    float64           `json:"gross_amount" validate:"gte=0"` // 0 or omitted = pay the outstanding balance
+        PaymentMethod  string            `json:"payment_method" validate:"required,oneof=qris bank_transfer gopay"`
+        Bank           string            `json:"bank" validate:"required_if=PaymentMethod bank_transfer,omitempty,oneof=bca bni bri permata cimb mandiri"`
+        CallbackURL    string            `json:"callback_url" validate:"omitempty,url"`
+        Items          []service.Item    `json:"items"`
+        CustomerDetail *service.Customer `json:"customer_detail"`
+        ExpiryMinutes  int               `json:"expiry_minutes" validate:"gte=0"`
+    }
+
+    var in createDirectChargeInput
+    if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+        response.BadRequest(w, "invalid request body", err.Error())
+        return
+    }
+
+    if h.validator != nil {
+        if errs := h.validator.Validate(in); errs != nil && len(errs) > 0 {
+            response.BadRequest(w, "validation failed", errs)
+            return
+        }
+    }
+
+    orderUUID, ok := h.resolveOrderID(w, in.OrderID)
+    if !ok {
+        return
+    }
+
+    res, err := h.svc.CreateDirectCharge(r.Context(), service.DirectChargeRequest{
+        OrderID:        orderUUID,
+        PaymentOrderID: in.PaymentOrderID,
+        GrossAmount:    in.GrossAmount,
+        PaymentMethod:  in.PaymentMethod,
+        Bank:           in.Bank,
+        CallbackURL:    in.CallbackURL,
+        Items:          in.Items,
+        CustomerDetail: in.CustomerDetail,
+        ExpiryMinutes:  in.ExpiryMinutes,
+    })
+    if err != nil {
+        response.Error(w, err)
+        return
+    }
+
+    response.Created(w, "payment charge created", res)
+}
+
+// resolveOrderID accepts an order UUID or an order_no. It writes the error
+// response itself and reports whether the caller may continue.
+func (h *MidtransHandler) resolveOrderID(w http.ResponseWriter, orderID string) (uuid.UUID, bool) {
+    // Try UUID first, otherwise treat as order_no and look up
+    if id, err := uuid.Parse(orderID); err == nil {
+        return id, true
+    }
+    if h.db == nil {
+        response.BadRequest(w, "invalid order_id", "order_id must be UUID or provide order_no when backend has DB access")
+        return uuid.Nil, false
+    }
+    var ord entity.Order
+    if err := h.db.Where("order_no = ?", orderID).First(&ord).Error; err != nil {
+        response.BadRequest(w, "invalid order_id", fmt.Sprintf("order not found for order_no '%s'", orderID))
+        return uuid.Nil, false
+    }
+    return ord.ID, true
+}
+
 // POST /api/v1/payments/midtrans/notification
 // Webhook endpoint for Midtrans payment notifications
 // This endpoint should be publicly accessible (no auth)
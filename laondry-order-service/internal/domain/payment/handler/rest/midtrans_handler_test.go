@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,12 +20,12 @@ import (
 	"laondry-order-service/pkg/validator"
 )
 
-// MockMidtransService is a mock implementation of MidtransService
-type MockMidtransService struct {
+// MockPaymentService is a mock implementation of PaymentService
+type MockPaymentService struct {
 	mock.Mock
 }
 
-func (m *MockMidtransService) CreateSnapToken(ctx context.Context, req service.CreateSnapTokenRequest) (*service.CreateSnapTokenResponse, error) {
+func (m *MockPaymentService) CreateSnapToken(ctx context.Context, req service.CreateSnapTokenRequest) (*service.CreateSnapTokenResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -32,7 +33,15 @@ func (m *MockMidtransService) CreateSnapToken(ctx context.Context, req service.C
 	return args.Get(0).(*service.CreateSnapTokenResponse), args.Error(1)
 }
 
-func (m *MockMidtransService) CheckTransactionStatus(ctx context.Context, paymentOrderID string) (*service.TransactionStatusResponse, error) {
+func (m *MockPaymentService) CreateDirectCharge(ctx context.Context, req service.DirectChargeRequest) (*service.DirectChargeResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.DirectChargeResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) CheckTransactionStatus(ctx context.Context, paymentOrderID string) (*service.TransactionStatusResponse, error) {
 	args := m.Called(ctx, paymentOrderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -40,7 +49,7 @@ func (m *MockMidtransService) CheckTransactionStatus(ctx context.Context, paymen
 	return args.Get(0).(*service.TransactionStatusResponse), args.Error(1)
 }
 
-func (m *MockMidtransService) GetTransactionByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.PaymentTransaction, error) {
+func (m *MockPaymentService) GetTransactionByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.PaymentTransaction, error) {
 	args := m.Called(ctx, orderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -48,7 +57,7 @@ func (m *MockMidtransService) GetTransactionByOrderID(ctx context.Context, order
 	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
 }
 
-func (m *MockMidtransService) GetTransactionByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.PaymentTransaction, error) {
+func (m *MockPaymentService) GetTransactionByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.PaymentTransaction, error) {
 	args := m.Called(ctx, paymentOrderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -56,20 +65,81 @@ func (m *MockMidtransService) GetTransactionByPaymentOrderID(ctx context.Context
 	return args.Get(0).(*entity.PaymentTransaction), args.Error(1)
 }
 
-func (m *MockMidtransService) ProcessWebhookNotification(ctx context.Context, payload map[string]interface{}) (*service.WebhookResponse, error) {
-	args := m.Called(ctx, payload)
+func (m *MockPaymentService) ReconcilePendingTransactions(ctx context.Context) (*service.ReconcileResult, error) {
+	args := m.Called(ctx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*service.WebhookResponse), args.Error(1)
+	return args.Get(0).(*service.ReconcileResult), args.Error(1)
+}
+
+func (m *MockPaymentService) RecordManualPayment(ctx context.Context, req service.ManualPaymentRequest) (*service.ManualPaymentResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ManualPaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) GetWallet(ctx context.Context, userID uuid.UUID, page, limit int) (*service.WalletResponse, error) {
+	args := m.Called(ctx, userID, page, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.WalletResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) PayOrderWithWallet(ctx context.Context, req service.WalletPaymentRequest) (*service.WalletPaymentResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.WalletPaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) CreateWalletTopup(ctx context.Context, req service.WalletTopupRequest) (*service.WalletTopupResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.WalletTopupResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) RefundTransaction(ctx context.Context, req service.RefundRequest) (*service.RefundResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.RefundResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error) {
+	args := m.Called(ctx, paymentTransactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.PaymentRefund), args.Error(1)
 }
 
-func (m *MockMidtransService) VerifySignature(orderID, statusCode, grossAmount, signature string) bool {
-	args := m.Called(orderID, statusCode, grossAmount, signature)
-	return args.Bool(0)
+func (m *MockPaymentService) CancelOrderPayments(ctx context.Context, orderID uuid.UUID, canceledBy *uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, canceledBy, reason)
+	return args.Error(0)
 }
 
-func (m *MockMidtransService) GetPaymentHistory(ctx context.Context, orderID uuid.UUID) ([]entity.PaymentTransaction, error) {
+func (m *MockPaymentService) ConfirmOrderPayment(ctx context.Context, orderID uuid.UUID, note string) error {
+	args := m.Called(ctx, orderID, note)
+	return args.Error(0)
+}
+
+func (m *MockPaymentService) ProcessWebhookNotification(ctx context.Context, payload map[string]interface{}) (*service.WebhookResponse, error) {
+	args := m.Called(ctx, payload)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.WebhookResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) GetPaymentHistory(ctx context.Context, orderID uuid.UUID) ([]entity.PaymentTransaction, error) {
 	args := m.Called(ctx, orderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -77,7 +147,7 @@ func (m *MockMidtransService) GetPaymentHistory(ctx context.Context, orderID uui
 	return args.Get(0).([]entity.PaymentTransaction), args.Error(1)
 }
 
-func (m *MockMidtransService) GetTransactionHistory(ctx context.Context, filters repository.TransactionFilters) ([]entity.PaymentTransaction, int64, error) {
+func (m *MockPaymentService) GetTransactionHistory(ctx context.Context, filters repository.TransactionFilters) ([]entity.PaymentTransaction, int64, error) {
 	args := m.Called(ctx, filters)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Error(2)
@@ -85,9 +155,57 @@ func (m *MockMidtransService) GetTransactionHistory(ctx context.Context, filters
 	return args.Get(0).([]entity.PaymentTransaction), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockPaymentService) ListWebhookLogs(ctx context.Context, filters repository.WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]entity.PaymentWebhookLog), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentService) ReplayWebhookLogs(ctx context.Context, req service.ReplayWebhookRequest) (*service.ReplayWebhookResult, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ReplayWebhookResult), args.Error(1)
+}
+
+func (m *MockPaymentService) SetMerchantCredential(ctx context.Context, req service.MerchantCredentialRequest) (*service.MerchantCredentialResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantCredentialResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) GetMerchantCredential(ctx context.Context, outletID uuid.UUID) (*service.MerchantCredentialResponse, error) {
+	args := m.Called(ctx, outletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantCredentialResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) ImportSettlementReport(ctx context.Context, req service.SettlementImportRequest) (*service.SettlementImportResult, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SettlementImportResult), args.Error(1)
+}
+
+func (m *MockPaymentService) GetSettlementReconciliation(ctx context.Context, filters repository.SettlementFilters) (*service.SettlementReconciliation, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SettlementReconciliation), args.Error(1)
+}
+
 // Test fixtures
-func createTestHandler() (*MidtransHandler, *MockMidtransService) {
-    mockSvc := &MockMidtransService{}
+func createTestHandler() (*MidtransHandler, *MockPaymentService) {
+    mockSvc := &MockPaymentService{}
     v := validator.NewValidator()
     handler := NewMidtransHandler(mockSvc, v, nil)
     return handler, mockSvc
@@ -151,6 +269,46 @@ func TestCreateSnapToken(t *testing.T) {
 	})
 }
 
+// TestCreateDirectCharge tests the Core API charge handler
+func TestCreateDirectCharge(t *testing.T) {
+	handler, mockSvc := createTestHandler()
+	orderID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		va := "8808123456"
+		mockSvc.On("CreateDirectCharge", mock.Anything, mock.MatchedBy(func(req service.DirectChargeRequest) bool {
+			return req.OrderID == orderID && req.PaymentMethod == "bank_transfer" && req.Bank == "bca"
+		})).Return(&service.DirectChargeResponse{PaymentOrderID: "ORD-001", Status: "PENDING", VANumber: &va}, nil).Once()
+
+		body := fmt.Sprintf(`{"order_id":"%s","payment_method":"bank_transfer","bank":"bca"}`, orderID)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/midtrans/charge", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.CreateDirectCharge(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"va_number":"8808123456"`)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, body := range []string{
+			fmt.Sprintf(`{"order_id":"%s","payment_method":"bank_transfer"}`, orderID),
+			fmt.Sprintf(`{"order_id":"%s","payment_method":"credit_card"}`, orderID),
+			fmt.Sprintf(`{"order_id":"%s","payment_method":"bank_transfer","bank":"xyz"}`, orderID),
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/midtrans/charge", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.CreateDirectCharge(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
+
 // TestNotification tests the webhook notification handler
 func TestNotification(t *testing.T) {
 	handler, mockSvc := createTestHandler()
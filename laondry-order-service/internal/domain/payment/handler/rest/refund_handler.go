@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+)
+
+// POST /api/v1/payments/{id}/refunds
+// Refunds (part of) a settled payment transaction. Staff only.
+func (h *MidtransHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid payment transaction id", err.Error())
+		return
+	}
+
+	var req service.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req.PaymentTransactionID = id
+	if user, ok := mw.GetUserFromContext(r.Context()); ok {
+		if userID, err := uuid.Parse(user.UserID); err == nil {
+			req.RequestedBy = &userID
+		}
+	}
+
+	res, err := h.svc.RefundTransaction(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, "refund processed", res)
+}
+
+// GET /api/v1/payments/{id}/refunds
+// Lists refunds recorded for a payment transaction. Staff only.
+func (h *MidtransHandler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid payment transaction id", err.Error())
+		return
+	}
+
+	refunds, err := h.svc.ListRefunds(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Success(w, "refunds retrieved", map[string]interface{}{
+		"payment_transaction_id": id,
+		"refunds":                refunds,
+		"total":                  len(refunds),
+	})
+}
@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/domain/payment/service"
+	"laondry-order-service/pkg/response"
+)
+
+// maxSettlementReportSize caps uploaded settlement reports (a month of
+// transactions is well below this)
+const maxSettlementReportSize = 20 << 20
+
+// POST /api/v1/admin/payments/settlements/import
+// Imports a Midtrans settlement report; the CSV file content is sent as the "csv" string
+func (h *MidtransHandler) ImportSettlementReport(w http.ResponseWriter, r *http.Request) {
+	type importInput struct {
+		// OutletID is set for reports of an outlet's own merchant account
+		OutletID *uuid.UUID `json:"outlet_id"`
+		FileName string     `json:"file_name" validate:"max=255"`
+		CSV      string     `json:"csv" validate:"required"`
+	}
+
+	var in importInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSettlementReportSize)).Decode(&in); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(in); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req := service.SettlementImportRequest{
+		OutletID: in.OutletID,
+		FileName: in.FileName,
+		Report:   strings.NewReader(in.CSV),
+	}
+	if req.FileName == "" {
+		req.FileName = "settlement.csv"
+	}
+	if userID, ok := currentUserID(r); ok {
+		req.ImportedBy = &userID
+	}
+
+	res, err := h.svc.ImportSettlementReport(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, "settlement report imported", res)
+}
+
+// GET /api/v1/admin/payments/settlements
+// Shows the settlement reconciliation of a day (date=YYYY-MM-DD) and/or outlet, issues first
+func (h *MidtransHandler) GetSettlementReconciliation(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filters := repository.SettlementFilters{Page: 1, Limit: 50}
+	if date := query.Get("date"); date != "" {
+		filters.Date = &date
+	}
+	if outlet := query.Get("outlet_id"); outlet != "" {
+		outletID, err := uuid.Parse(outlet)
+		if err != nil {
+			response.BadRequest(w, "invalid outlet_id", err.Error())
+			return
+		}
+		filters.OutletID = &outletID
+	}
+	if status := query.Get("status"); status != "" {
+		filters.Status = &status
+	}
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			filters.Page = p
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filters.Limit = l
+		}
+	}
+
+	res, err := h.svc.GetSettlementReconciliation(r.Context(), filters)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	totalPages := (res.Total + int64(filters.Limit) - 1) / int64(filters.Limit)
+
+	response.Success(w, "settlement reconciliation retrieved", map[string]interface{}{
+		"summary": res.Summary,
+		"entries": res.Entries,
+		"pagination": map[string]interface{}{
+			"page":        filters.Page,
+			"limit":       filters.Limit,
+			"total":       res.Total,
+			"total_pages": totalPages,
+		},
+	})
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+)
+
+// currentUserID returns the authenticated user's ID
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// GET /api/v1/wallet
+// Returns the caller's wallet balance and ledger entries, newest first
+func (h *MidtransHandler) GetWallet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	page, limit := 1, 20
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	wallet, err := h.svc.GetWallet(r.Context(), userID, page, limit)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	totalPages := (wallet.Total + int64(limit) - 1) / int64(limit)
+
+	response.Success(w, "wallet retrieved", map[string]interface{}{
+		"balance": wallet.Balance,
+		"entries": wallet.Entries,
+		"pagination": map[string]interface{}{
+			"page":        page,
+			"limit":       limit,
+			"total":       wallet.Total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// POST /api/v1/wallet/topups
+// Starts a Snap payment that tops up the caller's wallet once it settles
+func (h *MidtransHandler) CreateWalletTopup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	var req service.WalletTopupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req.UserID = userID
+	res, err := h.svc.CreateWalletTopup(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, "wallet top-up created", res)
+}
+
+// POST /api/v1/orders/{id}/payments/wallet
+// Pays the caller's order fully or partially from their wallet balance
+func (h *MidtransHandler) CreateWalletPayment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "invalid order id", err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	// An empty body pays as much as the wallet covers
+	var req service.WalletPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	if h.validator != nil {
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			response.BadRequest(w, "validation failed", errs)
+			return
+		}
+	}
+
+	req.OrderID = orderID
+	req.UserID = userID
+	res, err := h.svc.PayOrderWithWallet(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, "wallet payment recorded", res)
+}
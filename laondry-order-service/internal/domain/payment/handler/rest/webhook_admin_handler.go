@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/domain/payment/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+)
+
+// GET /api/v1/admin/payments/webhooks
+// Lists stored webhook notifications. Defaults to failed, non-replay entries.
+func (h *MidtransHandler) ListWebhookLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filters := parseWebhookLogFilters(query.Get)
+	filters.Page = 1
+	filters.Limit = 20
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			filters.Page = p
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filters.Limit = l
+		}
+	}
+
+	logs, total, err := h.svc.ListWebhookLogs(r.Context(), filters)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	totalPages := (total + int64(filters.Limit) - 1) / int64(filters.Limit)
+
+	response.Success(w, "webhook logs retrieved", map[string]interface{}{
+		"webhook_logs": logs,
+		"pagination": map[string]interface{}{
+			"page":        filters.Page,
+			"limit":       filters.Limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// POST /api/v1/admin/payments/webhooks/replay
+// Replays one or many stored webhook notifications
+func (h *MidtransHandler) ReplayWebhookLogs(w http.ResponseWriter, r *http.Request) {
+	type replayInput struct {
+		LogIDs  []uuid.UUID       `json:"log_ids"`
+		Filters map[string]string `json:"filters"`
+		Limit   int               `json:"limit"`
+		DryRun  bool              `json:"dry_run"`
+		Force   bool              `json:"force"`
+	}
+
+	var in replayInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "invalid request body", err.Error())
+		return
+	}
+
+	req := service.ReplayWebhookRequest{
+		LogIDs: in.LogIDs,
+		DryRun: in.DryRun,
+		Force:  in.Force,
+	}
+	if len(in.LogIDs) == 0 {
+		if in.Filters == nil {
+			response.BadRequest(w, "log_ids or filters is required", nil)
+			return
+		}
+		filters := parseWebhookLogFilters(func(key string) string { return in.Filters[key] })
+		filters.Limit = in.Limit
+		req.Filters = &filters
+	}
+
+	if user, ok := mw.GetUserFromContext(r.Context()); ok {
+		if id, err := uuid.Parse(user.UserID); err == nil {
+			req.ReplayedBy = &id
+		}
+	}
+
+	res, err := h.svc.ReplayWebhookLogs(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	message := "webhook logs replayed"
+	if in.DryRun {
+		message = "webhook replay dry run completed"
+	}
+	response.Success(w, message, res)
+}
+
+// parseWebhookLogFilters reads webhook log filters from a key lookup (query
+// string or JSON body). failed defaults to true.
+func parseWebhookLogFilters(get func(string) string) repository.WebhookLogFilters {
+	filters := repository.WebhookLogFilters{OnlyFailed: true}
+
+	if v := get("payment_order_id"); v != "" {
+		filters.PaymentOrderID = &v
+	}
+	if v := get("transaction_status"); v != "" {
+		filters.TransactionStatus = &v
+	}
+	if v := get("error"); v != "" {
+		filters.ErrorContains = &v
+	}
+	if v := get("start_date"); v != "" {
+		filters.StartDate = &v
+	}
+	if v := get("end_date"); v != "" {
+		filters.EndDate = &v
+	}
+	if v := get("failed"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.OnlyFailed = b
+		}
+	}
+	if v := get("include_replays"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.IncludeReplays = b
+		}
+	}
+	return filters
+}
@@ -2,12 +2,18 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"laondry-order-service/internal/entity"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// ErrInsufficientBalance is returned by AdjustWalletBalance when a debit
+// would take the balance below zero
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
 type PaymentRepository interface {
 	// Payment Transaction operations
 	CreateTransaction(ctx context.Context, tx *entity.PaymentTransaction) error
@@ -17,6 +23,16 @@ type PaymentRepository interface {
 	UpdateTransaction(ctx context.Context, tx *entity.PaymentTransaction) error
 	ListTransactionsByOrderID(ctx context.Context, orderID uuid.UUID) ([]entity.PaymentTransaction, error)
 	ListTransactions(ctx context.Context, filters TransactionFilters) ([]entity.PaymentTransaction, int64, error)
+	ListStalePendingTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]entity.PaymentTransaction, error)
+	// MarkReconciled records that the reconciler checked the transaction,
+	// without touching updated_at
+	MarkReconciled(ctx context.Context, id uuid.UUID, at time.Time) error
+	// SumPaidAmount totals the settled payments of an order minus their
+	// successful refunds
+	SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error)
+
+	// Order lookups
+	FindOrderByID(ctx context.Context, orderID uuid.UUID) (*entity.Order, error)
 
 	// Payment Status Log operations
 	CreateStatusLog(ctx context.Context, log *entity.PaymentStatusLog) error
@@ -26,6 +42,55 @@ type PaymentRepository interface {
 	CreateWebhookLog(ctx context.Context, log *entity.PaymentWebhookLog) error
 	ListWebhookLogs(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentWebhookLog, error)
 	FindWebhookLogsByPaymentOrderID(ctx context.Context, paymentOrderID string) ([]entity.PaymentWebhookLog, error)
+	FindWebhookLogByID(ctx context.Context, id uuid.UUID) (*entity.PaymentWebhookLog, error)
+	SearchWebhookLogs(ctx context.Context, filters WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error)
+
+	// Payment Refund operations
+	CreateRefund(ctx context.Context, refund *entity.PaymentRefund) error
+	UpdateRefund(ctx context.Context, refund *entity.PaymentRefund) error
+	FindRefundByKey(ctx context.Context, refundKey string) (*entity.PaymentRefund, error)
+	ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error)
+	// SumRefundedAmount totals refunds that are not FAILED (pending ones count
+	// so concurrent requests cannot over-refund)
+	SumRefundedAmount(ctx context.Context, paymentTransactionID uuid.UUID) (float64, error)
+
+	// Outbox
+	CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error
+
+	// Wallet operations
+	GetWalletBalance(ctx context.Context, userID uuid.UUID) (float64, error)
+	// AdjustWalletBalance adds delta (negative for debits) to the user's
+	// balance with a single conditional UPDATE and returns the new balance.
+	// It fails with ErrInsufficientBalance instead of going below zero.
+	AdjustWalletBalance(ctx context.Context, userID uuid.UUID, delta float64) (float64, error)
+	CreateLedgerEntry(ctx context.Context, entry *entity.WalletLedgerEntry) error
+	ListLedgerEntries(ctx context.Context, userID uuid.UUID, page, limit int) ([]entity.WalletLedgerEntry, int64, error)
+	CreateTopup(ctx context.Context, topup *entity.WalletTopup) error
+	UpdateTopup(ctx context.Context, topup *entity.WalletTopup) error
+	FindTopupByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.WalletTopup, error)
+
+	// Per-outlet merchant credentials; gorm.ErrRecordNotFound when the
+	// outlet has none
+	FindMerchantCredential(ctx context.Context, outletID uuid.UUID) (*entity.OutletMerchantCredential, error)
+	FindMerchantCredentialByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.OutletMerchantCredential, error)
+	SaveMerchantCredential(ctx context.Context, cred *entity.OutletMerchantCredential) error
+
+	// Settlement reconciliation
+	// FindTransactionsByReferences loads transactions, with their order, by
+	// gateway transaction ID or payment order ID
+	FindTransactionsByReferences(ctx context.Context, transactionIDs, paymentOrderIDs []string) ([]entity.PaymentTransaction, error)
+	FindTopupsByPaymentOrderIDs(ctx context.Context, paymentOrderIDs []string) ([]entity.WalletTopup, error)
+	// ListSettledGatewayTransactions lists settled payments made through the
+	// gateway (not manual or wallet) with a settlement time in [from, to) that
+	// went to the merchant account of outletID, or the default account if nil
+	ListSettledGatewayTransactions(ctx context.Context, from, to time.Time, outletID *uuid.UUID) ([]entity.PaymentTransaction, error)
+	CreateSettlementReport(ctx context.Context, report *entity.SettlementReport) error
+	// ReplaceSettlementEntries deletes the entries of the settlement days
+	// from..to (inclusive) imported from reports of the same merchant account
+	// (outletID, nil for the default one) and stores entries in their place
+	ReplaceSettlementEntries(ctx context.Context, from, to time.Time, outletID *uuid.UUID, entries []entity.SettlementEntry) error
+	ListSettlementEntries(ctx context.Context, filters SettlementFilters) ([]entity.SettlementEntry, int64, error)
+	SummarizeSettlementEntries(ctx context.Context, filters SettlementFilters) ([]SettlementTotal, error)
 
 	// Utility
 	WithDB(db *gorm.DB) PaymentRepository
@@ -43,3 +108,33 @@ type TransactionFilters struct {
 	SortBy         string
 	SortOrder      string
 }
+
+type WebhookLogFilters struct {
+	PaymentOrderID    *string
+	TransactionStatus *string
+	ErrorContains     *string
+	OnlyFailed        bool // processing_error set or never processed
+	IncludeReplays    bool // include entries created by a replay
+	StartDate         *string
+	EndDate           *string
+	Page              int
+	Limit             int
+}
+
+type SettlementFilters struct {
+	Date     *string // YYYY-MM-DD settlement day
+	OutletID *uuid.UUID
+	Status   *string
+	Page     int
+	Limit    int
+}
+
+// SettlementTotal sums the settlement entries of one status
+type SettlementTotal struct {
+	Status         string  `json:"status"`
+	Count          int64   `json:"count"`
+	ReportedAmount float64 `json:"reported_amount"`
+	ReportedFee    float64 `json:"reported_fee"`
+	ReportedNet    float64 `json:"reported_net"`
+	ExpectedAmount float64 `json:"expected_amount"`
+}
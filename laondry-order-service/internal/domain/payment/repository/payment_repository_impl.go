@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/payments/query"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type paymentRepositoryImpl struct {
@@ -103,6 +105,28 @@ func (r *paymentRepositoryImpl) ListTransactionsByOrderID(ctx context.Context, o
 	return transactions, err
 }
 
+// ListStalePendingTransactions lists PENDING transactions neither updated
+// nor reconciled since staleBefore, those never reconciled first, so each run
+// moves on from the transactions the previous one checked
+func (r *paymentRepositoryImpl) ListStalePendingTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]entity.PaymentTransaction, error) {
+	var transactions []entity.PaymentTransaction
+	err := r.db.WithContext(ctx).
+		Where("status = ?", "PENDING").
+		Where("updated_at < ?", staleBefore).
+		Where("last_reconciled_at IS NULL OR last_reconciled_at < ?", staleBefore).
+		Order("last_reconciled_at ASC NULLS FIRST, updated_at ASC").
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// MarkReconciled sets last_reconciled_at of a transaction
+func (r *paymentRepositoryImpl) MarkReconciled(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.PaymentTransaction{}).
+		Where("id = ?", id).
+		UpdateColumn("last_reconciled_at", at).Error
+}
+
 // ListTransactions lists payment transactions with filters
 func (r *paymentRepositoryImpl) ListTransactions(ctx context.Context, filters TransactionFilters) ([]entity.PaymentTransaction, int64, error) {
 	var transactions []entity.PaymentTransaction
@@ -210,3 +234,380 @@ func (r *paymentRepositoryImpl) FindWebhookLogsByPaymentOrderID(ctx context.Cont
 		Find(&logs).Error
 	return logs, err
 }
+
+// FindWebhookLogByID finds a webhook log by ID
+func (r *paymentRepositoryImpl) FindWebhookLogByID(ctx context.Context, id uuid.UUID) (*entity.PaymentWebhookLog, error) {
+	var log entity.PaymentWebhookLog
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&log).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// SearchWebhookLogs lists webhook logs with filters, newest first
+func (r *paymentRepositoryImpl) SearchWebhookLogs(ctx context.Context, filters WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error) {
+	var logs []entity.PaymentWebhookLog
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.PaymentWebhookLog{})
+
+	if filters.PaymentOrderID != nil {
+		query = query.Where("payment_order_id = ?", *filters.PaymentOrderID)
+	}
+	if filters.TransactionStatus != nil {
+		query = query.Where("transaction_status = ?", *filters.TransactionStatus)
+	}
+	if filters.ErrorContains != nil {
+		query = query.Where("processing_error LIKE ?", "%"+*filters.ErrorContains+"%")
+	}
+	if filters.OnlyFailed {
+		query = query.Where("(processing_error IS NOT NULL OR processed_at IS NULL)").Where("dry_run = ?", false)
+	}
+	if !filters.IncludeReplays {
+		query = query.Where("replay_of_id IS NULL")
+	}
+	if filters.StartDate != nil {
+		startDate, err := time.Parse("2006-01-02", *filters.StartDate)
+		if err == nil {
+			query = query.Where("created_at >= ?", startDate)
+		}
+	}
+	if filters.EndDate != nil {
+		endDate, err := time.Parse("2006-01-02", *filters.EndDate)
+		if err == nil {
+			// Add one day to include the end date
+			endDate = endDate.Add(24 * time.Hour)
+			query = query.Where("created_at < ?", endDate)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filters.Page > 0 && filters.Limit > 0 {
+		offset := (filters.Page - 1) * filters.Limit
+		query = query.Offset(offset).Limit(filters.Limit)
+	}
+
+	err := query.Order("created_at DESC").Find(&logs).Error
+	return logs, total, err
+}
+
+// CreateRefund creates a payment refund record
+func (r *paymentRepositoryImpl) CreateRefund(ctx context.Context, refund *entity.PaymentRefund) error {
+	return r.db.WithContext(ctx).Create(refund).Error
+}
+
+// UpdateRefund updates a payment refund record
+func (r *paymentRepositoryImpl) UpdateRefund(ctx context.Context, refund *entity.PaymentRefund) error {
+	return r.db.WithContext(ctx).Save(refund).Error
+}
+
+// FindRefundByKey finds a refund by its Midtrans refund key
+func (r *paymentRepositoryImpl) FindRefundByKey(ctx context.Context, refundKey string) (*entity.PaymentRefund, error) {
+	var refund entity.PaymentRefund
+	err := r.db.WithContext(ctx).
+		Where("refund_key = ?", refundKey).
+		First(&refund).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ListRefunds lists all refunds for a payment transaction
+func (r *paymentRepositoryImpl) ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error) {
+	var refunds []entity.PaymentRefund
+	err := r.db.WithContext(ctx).
+		Where("payment_transaction_id = ?", paymentTransactionID).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// SumRefundedAmount sums non-failed refunds for a payment transaction
+func (r *paymentRepositoryImpl) SumRefundedAmount(ctx context.Context, paymentTransactionID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.db.WithContext(ctx).
+		Model(&entity.PaymentRefund{}).
+		Where("payment_transaction_id = ? AND status <> ?", paymentTransactionID, "FAILED").
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// SumPaidAmount totals what settled payments of an order paid towards it
+// (without payment method surcharges) minus successful refunds
+func (r *paymentRepositoryImpl) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	return query.SumPaidAmount(ctx, r.db, orderID)
+}
+
+// FindOrderByID loads the order a payment belongs to, with its outlet
+func (r *paymentRepositoryImpl) FindOrderByID(ctx context.Context, orderID uuid.UUID) (*entity.Order, error) {
+	var order entity.Order
+	if err := r.db.WithContext(ctx).Preload("Outlet").First(&order, "id = ?", orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CreateOutboxMessage stores a side effect to be delivered after commit
+func (r *paymentRepositoryImpl) CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+// GetWalletBalance returns the user's current wallet balance
+func (r *paymentRepositoryImpl) GetWalletBalance(ctx context.Context, userID uuid.UUID) (float64, error) {
+	var user entity.User
+	if err := r.db.WithContext(ctx).Select("id", "balance").First(&user, "id = ?", userID).Error; err != nil {
+		return 0, err
+	}
+	return user.Balance, nil
+}
+
+// AdjustWalletBalance applies delta in one conditional UPDATE, so concurrent
+// debits can never take the balance below zero. The updated row stays locked
+// until the surrounding transaction ends, which keeps the returned balance
+// consistent with the ledger entry written next.
+func (r *paymentRepositoryImpl) AdjustWalletBalance(ctx context.Context, userID uuid.UUID, delta float64) (float64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ? AND balance + ? >= 0", userID, delta).
+		Update("balance", gorm.Expr("balance + ?", delta))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := r.GetWalletBalance(ctx, userID); err != nil {
+			return 0, err
+		}
+		return 0, ErrInsufficientBalance
+	}
+	return r.GetWalletBalance(ctx, userID)
+}
+
+// CreateLedgerEntry appends a wallet movement
+func (r *paymentRepositoryImpl) CreateLedgerEntry(ctx context.Context, entry *entity.WalletLedgerEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// ListLedgerEntries lists a user's wallet movements, newest first
+func (r *paymentRepositoryImpl) ListLedgerEntries(ctx context.Context, userID uuid.UUID, page, limit int) ([]entity.WalletLedgerEntry, int64, error) {
+	var entries []entity.WalletLedgerEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.WalletLedgerEntry{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// CreateTopup creates a wallet top-up
+func (r *paymentRepositoryImpl) CreateTopup(ctx context.Context, topup *entity.WalletTopup) error {
+	return r.db.WithContext(ctx).Create(topup).Error
+}
+
+// UpdateTopup updates a wallet top-up
+func (r *paymentRepositoryImpl) UpdateTopup(ctx context.Context, topup *entity.WalletTopup) error {
+	return r.db.WithContext(ctx).Save(topup).Error
+}
+
+// FindTopupByPaymentOrderID finds a wallet top-up by its gateway order ID
+func (r *paymentRepositoryImpl) FindTopupByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.WalletTopup, error) {
+	var topup entity.WalletTopup
+	if err := r.db.WithContext(ctx).First(&topup, "payment_order_id = ?", paymentOrderID).Error; err != nil {
+		return nil, err
+	}
+	return &topup, nil
+}
+
+// FindMerchantCredential finds the merchant credentials of an outlet
+func (r *paymentRepositoryImpl) FindMerchantCredential(ctx context.Context, outletID uuid.UUID) (*entity.OutletMerchantCredential, error) {
+	var cred entity.OutletMerchantCredential
+	if err := r.db.WithContext(ctx).First(&cred, "outlet_id = ?", outletID).Error; err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// FindMerchantCredentialByPaymentOrderID finds the merchant credentials of the
+// outlet whose order a payment transaction belongs to
+func (r *paymentRepositoryImpl) FindMerchantCredentialByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.OutletMerchantCredential, error) {
+	var cred entity.OutletMerchantCredential
+	err := r.db.WithContext(ctx).
+		Joins("JOIN orders ON orders.outlet_id = outlet_merchant_credentials.outlet_id").
+		Joins("JOIN payment_transactions ON payment_transactions.order_id = orders.id").
+		Where("payment_transactions.payment_order_id = ?", paymentOrderID).
+		First(&cred).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// SaveMerchantCredential creates or replaces the merchant credentials of an outlet
+func (r *paymentRepositoryImpl) SaveMerchantCredential(ctx context.Context, cred *entity.OutletMerchantCredential) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "outlet_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"server_key_encrypted", "client_key", "enabled_payments", "is_active", "updated_by", "updated_at"}),
+	}).Create(cred).Error
+}
+
+// settlementLookupBatch keeps IN lists of report references well below the
+// database parameter limit
+const settlementLookupBatch = 500
+
+// FindTransactionsByReferences loads transactions, with their order, whose
+// gateway transaction ID or payment order ID is in the given lists
+func (r *paymentRepositoryImpl) FindTransactionsByReferences(ctx context.Context, transactionIDs, paymentOrderIDs []string) ([]entity.PaymentTransaction, error) {
+	seen := map[uuid.UUID]bool{}
+	var txs []entity.PaymentTransaction
+	lookup := func(column string, refs []string) error {
+		for start := 0; start < len(refs); start += settlementLookupBatch {
+			end := min(start+settlementLookupBatch, len(refs))
+			var batch []entity.PaymentTransaction
+			err := r.db.WithContext(ctx).Preload("Order").
+				Where(column+" IN ?", refs[start:end]).
+				Find(&batch).Error
+			if err != nil {
+				return err
+			}
+			for _, tx := range batch {
+				if !seen[tx.ID] {
+					seen[tx.ID] = true
+					txs = append(txs, tx)
+				}
+			}
+		}
+		return nil
+	}
+	if err := lookup("transaction_id", transactionIDs); err != nil {
+		return nil, err
+	}
+	if err := lookup("payment_order_id", paymentOrderIDs); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// FindTopupsByPaymentOrderIDs loads wallet top-ups by gateway order ID
+func (r *paymentRepositoryImpl) FindTopupsByPaymentOrderIDs(ctx context.Context, paymentOrderIDs []string) ([]entity.WalletTopup, error) {
+	var topups []entity.WalletTopup
+	for start := 0; start < len(paymentOrderIDs); start += settlementLookupBatch {
+		end := min(start+settlementLookupBatch, len(paymentOrderIDs))
+		var batch []entity.WalletTopup
+		if err := r.db.WithContext(ctx).Where("payment_order_id IN ?", paymentOrderIDs[start:end]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		topups = append(topups, batch...)
+	}
+	return topups, nil
+}
+
+// ListSettledGatewayTransactions lists settled gateway payments with a
+// settlement time in [from, to), with their order. Payments go to the
+// merchant account of their outlet while it has active credentials and to
+// the default account otherwise.
+func (r *paymentRepositoryImpl) ListSettledGatewayTransactions(ctx context.Context, from, to time.Time, outletID *uuid.UUID) ([]entity.PaymentTransaction, error) {
+	db := r.db.WithContext(ctx)
+	query := db.Preload("Order").
+		Where("status IN ? AND settlement_time >= ? AND settlement_time < ?", query.SettledStatuses, from, to).
+		Where("(payment_type IS NULL OR payment_type NOT IN ?)", []string{entity.PaymentTransactionTypeManual, entity.PaymentTransactionTypeWallet})
+	if outletID != nil {
+		query = query.Where("order_id IN (?)", db.Model(&entity.Order{}).Select("id").Where("outlet_id = ?", *outletID))
+	} else {
+		ownAccounts := db.Model(&entity.OutletMerchantCredential{}).Select("outlet_id").Where("is_active = ?", true)
+		query = query.Where("order_id NOT IN (?)", db.Model(&entity.Order{}).Select("id").Where("outlet_id IN (?)", ownAccounts))
+	}
+
+	var txs []entity.PaymentTransaction
+	err := query.Order("settlement_time").Find(&txs).Error
+	return txs, err
+}
+
+// CreateSettlementReport stores an imported settlement report
+func (r *paymentRepositoryImpl) CreateSettlementReport(ctx context.Context, report *entity.SettlementReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// ReplaceSettlementEntries deletes the entries of the settlement days
+// from..to (inclusive) imported from reports of the same merchant account
+// and stores entries in their place
+func (r *paymentRepositoryImpl) ReplaceSettlementEntries(ctx context.Context, from, to time.Time, outletID *uuid.UUID, entries []entity.SettlementEntry) error {
+	reports := r.db.WithContext(ctx).Model(&entity.SettlementReport{}).Select("id")
+	if outletID != nil {
+		reports = reports.Where("outlet_id = ?", *outletID)
+	} else {
+		reports = reports.Where("outlet_id IS NULL")
+	}
+	err := r.db.WithContext(ctx).
+		Where("settlement_date >= ? AND settlement_date <= ?", from, to).
+		Where("report_id IN (?)", reports).
+		Delete(&entity.SettlementEntry{}).Error
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	return r.db.WithContext(ctx).CreateInBatches(entries, 200).Error
+}
+
+func (r *paymentRepositoryImpl) settlementQuery(ctx context.Context, filters SettlementFilters) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&entity.SettlementEntry{})
+	if filters.Date != nil {
+		if date, err := time.Parse("2006-01-02", *filters.Date); err == nil {
+			query = query.Where("settlement_date = ?", date)
+		}
+	}
+	if filters.OutletID != nil {
+		query = query.Where("outlet_id = ?", *filters.OutletID)
+	}
+	if filters.Status != nil {
+		query = query.Where("status = ?", *filters.Status)
+	}
+	return query
+}
+
+// ListSettlementEntries lists reconciliation entries, issues first
+func (r *paymentRepositoryImpl) ListSettlementEntries(ctx context.Context, filters SettlementFilters) ([]entity.SettlementEntry, int64, error) {
+	var entries []entity.SettlementEntry
+	var total int64
+
+	query := r.settlementQuery(ctx, filters)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filters.Page > 0 && filters.Limit > 0 {
+		query = query.Offset((filters.Page - 1) * filters.Limit).Limit(filters.Limit)
+	}
+	err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN status = ? THEN 1 ELSE 0 END, settlement_date DESC, payment_order_id",
+			Vars: []interface{}{entity.SettlementMatched},
+		}}).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// SummarizeSettlementEntries totals reconciliation entries per status
+func (r *paymentRepositoryImpl) SummarizeSettlementEntries(ctx context.Context, filters SettlementFilters) ([]SettlementTotal, error) {
+	var totals []SettlementTotal
+	err := r.settlementQuery(ctx, filters).
+		Select(`status, COUNT(*) AS count,
+			COALESCE(SUM(reported_amount), 0) AS reported_amount,
+			COALESCE(SUM(reported_fee), 0) AS reported_fee,
+			COALESCE(SUM(reported_net), 0) AS reported_net,
+			COALESCE(SUM(expected_amount), 0) AS expected_amount`).
+		Group("status").
+		Order("status").
+		Scan(&totals).Error
+	return totals, err
+}
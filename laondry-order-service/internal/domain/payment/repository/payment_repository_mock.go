@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"laondry-order-service/internal/entity"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -102,6 +103,84 @@ func (m *MockPaymentRepository) FindWebhookLogsByPaymentOrderID(ctx context.Cont
 	return args.Get(0).([]entity.PaymentWebhookLog), args.Error(1)
 }
 
+func (m *MockPaymentRepository) FindWebhookLogByID(ctx context.Context, id uuid.UUID) (*entity.PaymentWebhookLog, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentWebhookLog), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SearchWebhookLogs(ctx context.Context, filters WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]entity.PaymentWebhookLog), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) ListStalePendingTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]entity.PaymentTransaction, error) {
+	args := m.Called(ctx, staleBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentRepository) MarkReconciled(ctx context.Context, id uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) CreateRefund(ctx context.Context, refund *entity.PaymentRefund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdateRefund(ctx context.Context, refund *entity.PaymentRefund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) FindRefundByKey(ctx context.Context, refundKey string) (*entity.PaymentRefund, error) {
+	args := m.Called(ctx, refundKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error) {
+	args := m.Called(ctx, paymentTransactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SumRefundedAmount(ctx context.Context, paymentTransactionID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, paymentTransactionID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindOrderByID(ctx context.Context, orderID uuid.UUID) (*entity.Order, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Order), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateOutboxMessage(ctx context.Context, msg *entity.OutboxMessage) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
 func (m *MockPaymentRepository) WithDB(db *gorm.DB) PaymentRepository {
 	args := m.Called(db)
 	if args.Get(0) == nil {
@@ -109,3 +188,115 @@ func (m *MockPaymentRepository) WithDB(db *gorm.DB) PaymentRepository {
 	}
 	return args.Get(0).(PaymentRepository)
 }
+
+func (m *MockPaymentRepository) GetWalletBalance(ctx context.Context, userID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) AdjustWalletBalance(ctx context.Context, userID uuid.UUID, delta float64) (float64, error) {
+	args := m.Called(ctx, userID, delta)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateLedgerEntry(ctx context.Context, entry *entity.WalletLedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ListLedgerEntries(ctx context.Context, userID uuid.UUID, page, limit int) ([]entity.WalletLedgerEntry, int64, error) {
+	args := m.Called(ctx, userID, page, limit)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]entity.WalletLedgerEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) CreateTopup(ctx context.Context, topup *entity.WalletTopup) error {
+	args := m.Called(ctx, topup)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdateTopup(ctx context.Context, topup *entity.WalletTopup) error {
+	args := m.Called(ctx, topup)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) FindTopupByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.WalletTopup, error) {
+	args := m.Called(ctx, paymentOrderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.WalletTopup), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindMerchantCredential(ctx context.Context, outletID uuid.UUID) (*entity.OutletMerchantCredential, error) {
+	args := m.Called(ctx, outletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OutletMerchantCredential), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindMerchantCredentialByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.OutletMerchantCredential, error) {
+	args := m.Called(ctx, paymentOrderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OutletMerchantCredential), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SaveMerchantCredential(ctx context.Context, cred *entity.OutletMerchantCredential) error {
+	args := m.Called(ctx, cred)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) FindTransactionsByReferences(ctx context.Context, transactionIDs, paymentOrderIDs []string) ([]entity.PaymentTransaction, error) {
+	args := m.Called(ctx, transactionIDs, paymentOrderIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindTopupsByPaymentOrderIDs(ctx context.Context, paymentOrderIDs []string) ([]entity.WalletTopup, error) {
+	args := m.Called(ctx, paymentOrderIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.WalletTopup), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListSettledGatewayTransactions(ctx context.Context, from, to time.Time, outletID *uuid.UUID) ([]entity.PaymentTransaction, error) {
+	args := m.Called(ctx, from, to, outletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateSettlementReport(ctx context.Context, report *entity.SettlementReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ReplaceSettlementEntries(ctx context.Context, from, to time.Time, outletID *uuid.UUID, entries []entity.SettlementEntry) error {
+	args := m.Called(ctx, from, to, outletID, entries)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ListSettlementEntries(ctx context.Context, filters SettlementFilters) ([]entity.SettlementEntry, int64, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]entity.SettlementEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) SummarizeSettlementEntries(ctx context.Context, filters SettlementFilters) ([]SettlementTotal, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]SettlementTotal), args.Error(1)
+}
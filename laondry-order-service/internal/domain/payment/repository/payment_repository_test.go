@@ -0,0 +1,362 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"laondry-order-service/internal/entity"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupWalletDB(t *testing.T) (*gorm.DB, entity.User) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&entity.User{}, &entity.WalletLedgerEntry{}, &entity.WalletTopup{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+
+	now := time.Now().UTC()
+	user := entity.User{FullName: "Jane Doe", PasswordHash: "hash", Balance: 20000, CreatedAt: now, UpdatedAt: now}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return db, user
+}
+
+func TestPaymentRepository_AdjustWalletBalance(t *testing.T) {
+	db, user := setupWalletDB(t)
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	balance, err := repo.AdjustWalletBalance(ctx, user.ID, 5000)
+	if err != nil || balance != 25000 {
+		t.Fatalf("expected credit to give 25000, got %v (err %v)", balance, err)
+	}
+
+	// Concurrent debits of 3000 against 25000: only 8 fit
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, insufficient := 0, 0
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.AdjustWalletBalance(ctx, user.ID, -3000)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrInsufficientBalance):
+				insufficient++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 8 || insufficient != 4 {
+		t.Fatalf("expected 8 debits and 4 rejections, got %d and %d", succeeded, insufficient)
+	}
+	balance, err = repo.GetWalletBalance(ctx, user.ID)
+	if err != nil || balance != 1000 {
+		t.Fatalf("expected final balance 1000, got %v (err %v)", balance, err)
+	}
+}
+
+func TestPaymentRepository_WalletLedgerIsAppendOnly(t *testing.T) {
+	db, user := setupWalletDB(t)
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	entry := &entity.WalletLedgerEntry{UserID: user.ID, EntryType: entity.WalletEntryTopup, Amount: 20000, BalanceAfter: 20000}
+	if err := repo.CreateLedgerEntry(ctx, entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry.Amount = 1
+	if err := db.Save(entry).Error; !errors.Is(err, entity.ErrWalletLedgerImmutable) {
+		t.Fatalf("expected update to be rejected, got %v", err)
+	}
+	if err := db.Delete(entry).Error; !errors.Is(err, entity.ErrWalletLedgerImmutable) {
+		t.Fatalf("expected delete to be rejected, got %v", err)
+	}
+
+	entries, total, err := repo.ListLedgerEntries(ctx, user.ID, 1, 20)
+	if err != nil || total != 1 || entries[0].Amount != 20000 {
+		t.Fatalf("expected the original entry, got %+v (total %d, err %v)", entries, total, err)
+	}
+}
+
+func TestPaymentRepository_MerchantCredentials(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.Outlet{}, &entity.Order{}, &entity.PaymentTransaction{}, &entity.OutletMerchantCredential{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+	settled := time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)
+
+	franchise := entity.Outlet{Code: "FR-01", Name: "Franchise"}
+	own := entity.Outlet{Code: "HQ-01", Name: "Head office"}
+	db.Create(&franchise)
+	db.Create(&own)
+	for _, o := range []struct {
+		outlet entity.Outlet
+		no     string
+	}{{franchise, "ORD-FR"}, {own, "ORD-HQ"}} {
+		order := entity.Order{ID: uuid.New(), OrderNo: o.no, OutletID: o.outlet.ID, CustomerID: uuid.New(), Status: "NEW"}
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+		db.Create(&entity.PaymentTransaction{OrderID: order.ID, PaymentOrderID: o.no, GrossAmount: 10000, Status: "SUCCESS", SettlementTime: &settled})
+	}
+
+	cred := &entity.OutletMerchantCredential{OutletID: franchise.ID, ServerKeyEncrypted: "enc-1", ClientKey: "client-1", IsActive: true}
+	if err := repo.SaveMerchantCredential(ctx, cred); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	cred.ServerKeyEncrypted, cred.ClientKey = "enc-2", "client-2"
+	if err := repo.SaveMerchantCredential(ctx, cred); err != nil {
+		t.Fatalf("save again: %v", err)
+	}
+
+	found, err := repo.FindMerchantCredential(ctx, franchise.ID)
+	if err != nil || found.ServerKeyEncrypted != "enc-2" || found.ClientKey != "client-2" {
+		t.Fatalf("expected updated credentials, got %+v (err %v)", found, err)
+	}
+	found, err = repo.FindMerchantCredentialByPaymentOrderID(ctx, "ORD-FR")
+	if err != nil || found.OutletID != franchise.ID {
+		t.Fatalf("expected franchise credentials for ORD-FR, got %+v (err %v)", found, err)
+	}
+	if _, err := repo.FindMerchantCredentialByPaymentOrderID(ctx, "ORD-HQ"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected no credentials for ORD-HQ, got %v", err)
+	}
+
+	// Settlement reports are per merchant account
+	from, to := settled.Add(-time.Hour), settled.Add(time.Hour)
+	for _, c := range []struct {
+		outlet *uuid.UUID
+		want   string
+	}{{&franchise.ID, "ORD-FR"}, {nil, "ORD-HQ"}} {
+		txs, err := repo.ListSettledGatewayTransactions(ctx, from, to, c.outlet)
+		if err != nil || len(txs) != 1 || txs[0].PaymentOrderID != c.want {
+			t.Fatalf("expected only %s settled on the account, got %+v (err %v)", c.want, txs, err)
+		}
+	}
+
+	cred.IsActive = false
+	if err := repo.SaveMerchantCredential(ctx, cred); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if found, err = repo.FindMerchantCredential(ctx, franchise.ID); err != nil || found.IsActive {
+		t.Fatalf("expected deactivated credentials, got %+v (err %v)", found, err)
+	}
+	inactive := &entity.OutletMerchantCredential{OutletID: own.ID, ServerKeyEncrypted: "enc-3", ClientKey: "client-3"}
+	if err := repo.SaveMerchantCredential(ctx, inactive); err != nil {
+		t.Fatalf("save inactive: %v", err)
+	}
+	if found, err = repo.FindMerchantCredential(ctx, own.ID); err != nil || found.IsActive {
+		t.Fatalf("expected credentials created inactive, got %+v (err %v)", found, err)
+	}
+}
+
+func TestPaymentRepository_SettlementEntries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.SettlementReport{}, &entity.SettlementEntry{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+	outletID := uuid.New()
+	amount := func(f float64) *float64 { return &f }
+	report := func(outlet *uuid.UUID) uuid.UUID {
+		r := &entity.SettlementReport{ID: uuid.New(), OutletID: outlet, FileName: "report.csv", PeriodStart: day, PeriodEnd: day}
+		if err := repo.CreateSettlementReport(ctx, r); err != nil {
+			t.Fatalf("create report: %v", err)
+		}
+		return r.ID
+	}
+	entry := func(reportID uuid.UUID, poid, status string, date time.Time, outlet *uuid.UUID) entity.SettlementEntry {
+		return entity.SettlementEntry{ReportID: reportID, SettlementDate: date, PaymentOrderID: poid, Status: status, OutletID: outlet, ReportedAmount: amount(10000)}
+	}
+
+	first := report(nil)
+	if err := repo.ReplaceSettlementEntries(ctx, day, nextDay, nil, []entity.SettlementEntry{
+		entry(first, "ORD-OLD", entity.SettlementMissing, day, &outletID),
+		entry(first, "ORD-NEXT", entity.SettlementMatched, nextDay, &outletID),
+	}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	// Another merchant account's report of the same day is kept
+	franchiseID := uuid.New()
+	franchise := report(&franchiseID)
+	if err := repo.ReplaceSettlementEntries(ctx, day, day, &franchiseID, []entity.SettlementEntry{
+		entry(franchise, "ORD-FR", entity.SettlementMatched, day, &franchiseID),
+	}); err != nil {
+		t.Fatalf("replace franchise: %v", err)
+	}
+	// Importing the 10th again replaces its entries but keeps the 11th
+	second := report(nil)
+	if err := repo.ReplaceSettlementEntries(ctx, day, day, nil, []entity.SettlementEntry{
+		entry(second, "ORD-001", entity.SettlementMatched, day, &outletID),
+		entry(second, "ORD-002", entity.SettlementAmountMismatch, day, &outletID),
+		entry(second, "ORD-999", entity.SettlementExtra, day, nil),
+	}); err != nil {
+		t.Fatalf("replace again: %v", err)
+	}
+
+	date := "2025-03-10"
+	entries, total, err := repo.ListSettlementEntries(ctx, SettlementFilters{Date: &date, Page: 1, Limit: 10})
+	if err != nil || total != 4 {
+		t.Fatalf("expected 4 entries on %s, got %d (err %v)", date, total, err)
+	}
+	if entries[len(entries)-1].Status != entity.SettlementMatched {
+		t.Fatalf("expected issues before matched entries, got %+v", entries)
+	}
+
+	totals, err := repo.SummarizeSettlementEntries(ctx, SettlementFilters{OutletID: &outletID})
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, tot := range totals {
+		counts[tot.Status] = tot.Count
+	}
+	if counts[entity.SettlementMatched] != 2 || counts[entity.SettlementAmountMismatch] != 1 || counts[entity.SettlementMissing] != 0 || counts[entity.SettlementExtra] != 0 {
+		t.Fatalf("unexpected outlet totals %+v", totals)
+	}
+}
+
+func TestPaymentRepository_SumPaidAmount(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.PaymentTransaction{}, &entity.PaymentRefund{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+	orderID := uuid.New()
+
+	payment := func(poid, status string, gross, surcharge float64) entity.PaymentTransaction {
+		tx := entity.PaymentTransaction{OrderID: orderID, PaymentOrderID: poid, GrossAmount: gross, SurchargeAmount: surcharge, Status: status}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("failed to create payment: %v", err)
+		}
+		return tx
+	}
+	refund := func(tx entity.PaymentTransaction, key, status string, amount float64) {
+		if err := db.Create(&entity.PaymentRefund{PaymentTransactionID: tx.ID, RefundKey: key, Amount: amount, Status: status, Source: "api"}).Error; err != nil {
+			t.Fatalf("failed to create refund: %v", err)
+		}
+	}
+
+	// Down payment of 50000 plus a 1000 surcharge, refunded in full
+	down := payment("ORD-1", "REFUNDED", 51000, 1000)
+	refund(down, "ORD-1-REFUND-1", "SUCCESS", 51000)
+	// Remaining 50000 plus a 1000 surcharge, 10000 refunded, a failed refund
+	rest := payment("ORD-1-PAY-2", "PARTIALLY_REFUNDED", 51000, 1000)
+	refund(rest, "ORD-1-PAY-2-REFUND-1", "SUCCESS", 10000)
+	refund(rest, "ORD-1-PAY-2-REFUND-2", "FAILED", 20000)
+	payment("ORD-1-PAY-3", "PENDING", 30000, 0)
+
+	paid, err := repo.SumPaidAmount(ctx, orderID)
+	if err != nil || paid != 40000 {
+		t.Fatalf("expected 40000 paid, got %v (err %v)", paid, err)
+	}
+}
+
+func TestPaymentRepository_ListStalePendingTransactions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.PaymentTransaction{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	staleBefore := now.Add(-15 * time.Minute)
+
+	// Three stale pending transactions, oldest first, and a fresh one
+	for i, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, time.Minute} {
+		tx := entity.PaymentTransaction{OrderID: uuid.New(), PaymentOrderID: fmt.Sprintf("ORD-%d", i+1), Status: "PENDING"}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("failed to create payment: %v", err)
+		}
+		if err := db.Model(&tx).UpdateColumn("updated_at", now.Add(-age)).Error; err != nil {
+			t.Fatalf("failed to age payment: %v", err)
+		}
+	}
+
+	list := func() []string {
+		txs, err := repo.ListStalePendingTransactions(ctx, staleBefore, 2)
+		if err != nil {
+			t.Fatalf("failed to list stale transactions: %v", err)
+		}
+		var ids []string
+		for _, tx := range txs {
+			ids = append(ids, tx.PaymentOrderID)
+			if err := repo.MarkReconciled(ctx, tx.ID, now); err != nil {
+				t.Fatalf("failed to mark reconciled: %v", err)
+			}
+		}
+		return ids
+	}
+
+	// Checked rows make way for the rest of the backlog, whatever their outcome
+	if got := list(); !reflect.DeepEqual(got, []string{"ORD-1", "ORD-2"}) {
+		t.Fatalf("expected the two oldest transactions, got %v", got)
+	}
+	if got := list(); !reflect.DeepEqual(got, []string{"ORD-3"}) {
+		t.Fatalf("expected the remaining stale transaction, got %v", got)
+	}
+	if got := list(); len(got) != 0 {
+		t.Fatalf("expected nothing left to reconcile, got %v", got)
+	}
+
+	// Later on, a transaction never checked comes before those checked longest ago
+	staleBefore = now.Add(time.Second)
+	if got := list(); !reflect.DeepEqual(got, []string{"ORD-4", "ORD-1"}) {
+		t.Fatalf("expected the never checked, then the oldest transaction, got %v", got)
+	}
+}
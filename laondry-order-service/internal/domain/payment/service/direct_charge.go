@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// directChargeBanks are the banks a bank transfer VA can be issued by
+var directChargeBanks = map[string]bool{
+	"bca":     true,
+	"bni":     true,
+	"bri":     true,
+	"permata": true,
+	"cimb":    true,
+	"mandiri": true,
+}
+
+type DirectChargeRequest struct {
+	OrderID        uuid.UUID `json:"order_id" validate:"required"`
+	PaymentOrderID string    `json:"payment_order_id"`              // Optional; generated like Snap payments
+	GrossAmount    float64   `json:"gross_amount" validate:"gte=0"` // 0 = the whole outstanding balance
+	PaymentMethod  string    `json:"payment_method" validate:"required,oneof=qris bank_transfer gopay"`
+	Bank           string    `json:"bank" validate:"omitempty,oneof=bca bni bri permata cimb mandiri"` // Required for bank_transfer
+	CallbackURL    string    `json:"callback_url" validate:"omitempty,url"`                            // GoPay: where the app returns after payment
+	Items          []Item    `json:"items"`
+	CustomerDetail *Customer `json:"customer_detail"`
+	ExpiryMinutes  int       `json:"expiry_minutes"`
+}
+
+type DirectChargeResponse struct {
+	PaymentTransactionID uuid.UUID  `json:"payment_transaction_id"`
+	PaymentOrderID       string     `json:"payment_order_id"`
+	GrossAmount          float64    `json:"gross_amount"`
+	SurchargeAmount      float64    `json:"surcharge_amount"` // Payment method surcharge included in GrossAmount
+	RemainingAmount      float64    `json:"remaining_amount"` // Outstanding once this payment settles
+	Status               string     `json:"status"`
+	PaymentMethod        string     `json:"payment_method"`
+	PaymentType          *string    `json:"payment_type"` // Gateway payment type, e.g. echannel for Mandiri
+	Bank                 string     `json:"bank,omitempty"`
+	VANumber             *string    `json:"va_number,omitempty"`
+	BillerCode           *string    `json:"biller_code,omitempty"`
+	BillKey              *string    `json:"bill_key,omitempty"`
+	QRString             string     `json:"qr_string,omitempty"`
+	QRCodeURL            string     `json:"qr_code_url,omitempty"`
+	DeeplinkURL          string     `json:"deeplink_url,omitempty"`
+	ExpiryTime           *time.Time `json:"expiry_time,omitempty"`
+}
+
+// CreateDirectCharge charges (part of) an order through the gateway's Core
+// API so the VA number or QR code can be shown right away, without a Snap
+// page. The transaction follows the same webhook lifecycle as Snap payments.
+func (s *paymentService) CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResponse, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.CreateDirectCharge")
+		defer seg.End()
+		txn.AddAttribute("order_id", req.OrderID.String())
+		txn.AddAttribute("payment_method", req.PaymentMethod)
+		txn.AddAttribute("gross_amount", req.GrossAmount)
+	}
+
+	switch req.PaymentMethod {
+	case gateway.MethodQRIS, gateway.MethodGoPay:
+		req.Bank = ""
+	case gateway.MethodBankTransfer:
+		if !directChargeBanks[req.Bank] {
+			return nil, appErrors.BadRequest("A supported bank is required for bank transfer", nil)
+		}
+	default:
+		return nil, appErrors.BadRequest(fmt.Sprintf("Unsupported payment method %q", req.PaymentMethod), nil)
+	}
+	if req.GrossAmount < 0 {
+		return nil, appErrors.BadRequest("Invalid gross_amount", nil)
+	}
+
+	var result *DirectChargeResponse
+	lockKey := fmt.Sprintf("payment:order:%s", req.OrderID)
+	err := s.withLock(ctx, lockKey, 30*time.Second, func() error {
+		var err error
+		result, err = s.createDirectCharge(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *paymentService) createDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResponse, error) {
+	sameCharge := func(tx *entity.PaymentTransaction) bool {
+		return tx.SnapToken == nil &&
+			tx.Metadata["charge_method"] == req.PaymentMethod &&
+			tx.Metadata["bank"] == req.Bank
+	}
+	plan, err := s.planOrderPayment(ctx, req.OrderID, req.PaymentOrderID, req.GrossAmount, sameCharge)
+	if err != nil {
+		return nil, err
+	}
+	// Charge with the merchant account of the order's outlet
+	gwCtx := gateway.WithOutlet(ctx, plan.order.OutletID)
+	if existing := plan.reuse; existing != nil {
+		slog.InfoContext(ctx, "[Payment] Returning open charge",
+			"payment_method", req.PaymentMethod, "payment_order_id", existing.PaymentOrderID)
+		return directChargeResponse(existing, plan.remaining()), nil
+	}
+	fee := s.methodFee(gateway.PaymentName(req.PaymentMethod, req.Bank), plan.amount)
+
+	chargeReq := gateway.DirectChargeRequest{
+		ChargeRequest: gateway.ChargeRequest{
+			PaymentOrderID: plan.paymentOrderID,
+			GrossAmount:    int64(plan.amount + fee.surcharge),
+			ExpiryMinutes:  req.ExpiryMinutes,
+		},
+		Method:      req.PaymentMethod,
+		Bank:        req.Bank,
+		CallbackURL: req.CallbackURL,
+	}
+	if plan.isPartial() {
+		chargeReq.Items = []gateway.Item{plan.item()}
+	} else {
+		for _, it := range req.Items {
+			chargeReq.Items = append(chargeReq.Items, gateway.Item{
+				ID:    it.ID,
+				Name:  it.Name,
+				Price: int64(it.Price),
+				Qty:   it.Qty,
+			})
+		}
+	}
+	chargeReq.Items = fee.items(chargeReq.Items, plan)
+	if req.CustomerDetail != nil {
+		chargeReq.Customer = &gateway.Customer{
+			FirstName: req.CustomerDetail.FirstName,
+			LastName:  req.CustomerDetail.LastName,
+			Email:     req.CustomerDetail.Email,
+			Phone:     req.CustomerDetail.Phone,
+		}
+	}
+
+	charge, err := s.gw.CreateDirectCharge(gwCtx, chargeReq)
+	if err != nil {
+		slog.ErrorContext(ctx, "[Payment] Direct charge failed", "gateway", s.gw.Name(),
+			"payment_order_id", plan.paymentOrderID, "error", err)
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.NoticeError(err)
+		}
+		switch {
+		case errors.Is(err, gateway.ErrNotConfigured):
+			return nil, appErrors.InternalServerError("Failed to initialize payment gateway", err)
+		case errors.Is(err, gateway.ErrMethodNotEnabled):
+			return nil, appErrors.UnprocessableEntity("Payment method is not enabled", err)
+		}
+		return nil, appErrors.InternalServerError("Failed to create payment charge", err)
+	}
+
+	expiryTime := charge.ExpiryTime
+	if expiryTime == nil && req.ExpiryMinutes > 0 {
+		exp := time.Now().Add(time.Duration(req.ExpiryMinutes) * time.Minute)
+		expiryTime = &exp
+	}
+
+	paymentTx := &entity.PaymentTransaction{
+		OrderID:         req.OrderID,
+		PaymentOrderID:  plan.paymentOrderID,
+		GrossAmount:     plan.amount,
+		Status:          "PENDING",
+		ExpiryTime:      expiryTime,
+		RequestPayload:  mapToJSONB(charge.RequestPayload),
+		ResponsePayload: mapToJSONB(charge.Raw),
+		// QR codes and deeplinks have no columns of their own
+		Metadata: entity.JSONB{
+			"charge_method": req.PaymentMethod,
+			"bank":          req.Bank,
+			"qr_string":     charge.QRString,
+			"qr_code_url":   charge.QRCodeURL,
+			"deeplink_url":  charge.DeeplinkURL,
+		},
+	}
+	fee.apply(paymentTx)
+	applyStatusDetails(paymentTx, &charge.Status)
+	if charge.Status.Status != "" {
+		paymentTx.Status = charge.Status.Status
+	}
+
+	lockKey := fmt.Sprintf("payment:create:%s", plan.paymentOrderID)
+	err = s.withLock(ctx, lockKey, 10*time.Second, func() error {
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			if err := r.CreateTransaction(ctx, paymentTx); err != nil {
+				slog.ErrorContext(ctx, "[Payment] Failed to save transaction", "payment_order_id", paymentTx.PaymentOrderID, "error", err)
+				return appErrors.InternalServerError("Failed to save payment transaction", err)
+			}
+			statusLog := &entity.PaymentStatusLog{
+				PaymentTransactionID: paymentTx.ID,
+				NewStatus:            paymentTx.Status,
+				Source:               "api_create",
+				StatusMessage:        strPtr(fmt.Sprintf("%s charge created", req.PaymentMethod)),
+				RawData:              mapToJSONB(charge.Raw),
+			}
+			if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+				slog.WarnContext(ctx, "[Payment] Failed to create status log", "payment_order_id", paymentTx.PaymentOrderID, "error", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Direct charge created", "payment_method", req.PaymentMethod,
+		"order_id", paymentTx.OrderID, "payment_order_id", paymentTx.PaymentOrderID, "amount", paymentTx.GrossAmount)
+	return directChargeResponse(paymentTx, plan.remaining()), nil
+}
+
+// directChargeResponse describes a direct charge transaction to the client
+func directChargeResponse(tx *entity.PaymentTransaction, remaining float64) *DirectChargeResponse {
+	metadata := func(key string) string {
+		v, _ := tx.Metadata[key].(string)
+		return v
+	}
+	return &DirectChargeResponse{
+		PaymentTransactionID: tx.ID,
+		PaymentOrderID:       tx.PaymentOrderID,
+		GrossAmount:          tx.GrossAmount,
+		SurchargeAmount:      tx.SurchargeAmount,
+		RemainingAmount:      remaining,
+		Status:               tx.Status,
+		PaymentMethod:        metadata("charge_method"),
+		PaymentType:          tx.PaymentType,
+		Bank:                 metadata("bank"),
+		VANumber:             tx.VANumber,
+		BillerCode:           tx.BillerCode,
+		BillKey:              tx.BillKey,
+		QRString:             metadata("qr_string"),
+		QRCodeURL:            metadata("qr_code_url"),
+		DeeplinkURL:          metadata("deeplink_url"),
+		ExpiryTime:           tx.ExpiryTime,
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func TestCreateDirectCharge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("VA number is stored on the transaction", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(150000)
+
+		var saved *entity.PaymentTransaction
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+			Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+		res, err := svc.CreateDirectCharge(ctx, DirectChargeRequest{OrderID: order.ID, PaymentMethod: "bank_transfer", Bank: "bni"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ORD-001", res.PaymentOrderID)
+		assert.Equal(t, "PENDING", res.Status)
+		assert.Equal(t, "8808ORD-001", *res.VANumber)
+		assert.Equal(t, "bni", res.Bank)
+		assert.Nil(t, saved.SnapToken)
+		assert.Equal(t, "8808ORD-001", *saved.VANumber)
+		assert.Equal(t, "fake-trx-ORD-001", *saved.TransactionID)
+		assert.Equal(t, "bank_transfer", saved.Metadata["charge_method"])
+		assert.Equal(t, []string{"CreateDirectCharge ORD-001"}, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Open charge with the same method is returned again", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(150000)
+		open := *createTestPaymentTransaction()
+		open.OrderID, open.PaymentOrderID, open.GrossAmount = order.ID, "ORD-001", 150000
+		open.SnapToken, open.SnapRedirectURL = nil, nil
+		open.Metadata = entity.JSONB{"charge_method": "qris", "bank": "", "qr_string": "0002010102"}
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{open}, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+
+		res, err := svc.CreateDirectCharge(ctx, DirectChargeRequest{OrderID: order.ID, PaymentMethod: "qris"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, open.ID, res.PaymentTransactionID)
+		assert.Equal(t, "0002010102", res.QRString)
+		assert.Empty(t, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Switching method closes the open charge", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(150000)
+		open := *createTestPaymentTransaction()
+		open.OrderID, open.PaymentOrderID, open.GrossAmount = order.ID, "ORD-001", 150000
+		open.SnapToken, open.SnapRedirectURL = nil, nil
+		open.Metadata = entity.JSONB{"charge_method": "qris", "bank": ""}
+		gw.SetStatus("ORD-001", "PENDING")
+
+		var saved *entity.PaymentTransaction
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{open}, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORD-001").Return(&open, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+			Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil)
+
+		res, err := svc.CreateDirectCharge(ctx, DirectChargeRequest{OrderID: order.ID, PaymentMethod: "gopay"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ORD-001-PAY-2", res.PaymentOrderID)
+		assert.Equal(t, "gojek://fake/ORD-001-PAY-2", res.DeeplinkURL)
+		assert.Equal(t, "gopay", saved.Metadata["charge_method"])
+		assert.Equal(t, []string{"CheckStatus ORD-001", "Cancel ORD-001", "CreateDirectCharge ORD-001-PAY-2"}, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Bank transfer without a bank", func(t *testing.T) {
+		svc := NewPaymentService(createTestConfig(), repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker(), gateway.NewFake())
+
+		_, err := svc.CreateDirectCharge(ctx, DirectChargeRequest{OrderID: testOrder(1).ID, PaymentMethod: "bank_transfer"})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// Manual payment methods accepted at the outlet counter
+const (
+	ManualMethodCash         = "cash"
+	ManualMethodBankTransfer = "bank_transfer"
+	ManualMethodEDC          = "edc"
+)
+
+type ManualPaymentRequest struct {
+	OrderID    uuid.UUID  `json:"-"`
+	Method     string     `json:"method" validate:"required,oneof=cash bank_transfer edc"`
+	Amount     float64    `json:"amount" validate:"required,gt=0"`
+	Reference  string     `json:"reference" validate:"max=100"` // Transfer reference / EDC approval code
+	ReceiptNo  string     `json:"receipt_no" validate:"max=50"` // Counter receipt number
+	Notes      string     `json:"notes" validate:"max=255"`
+	RecordedBy *uuid.UUID `json:"-"`
+}
+
+type ManualPaymentResponse struct {
+	Payment           *entity.PaymentTransaction `json:"payment"`
+	AmountTendered    float64                    `json:"amount_tendered"`
+	ChangeAmount      float64                    `json:"change_amount"`
+	PaidAmount        float64                    `json:"paid_amount"`
+	OutstandingAmount float64                    `json:"outstanding_amount"`
+}
+
+// RecordManualPayment records a cash, bank transfer or EDC payment taken at
+// the counter as a settled payment transaction. Open gateway charges of the
+// order are closed first. Cash may exceed the outstanding balance; only the
+// outstanding part is booked and the rest is returned as change.
+func (s *paymentService) RecordManualPayment(ctx context.Context, req ManualPaymentRequest) (*ManualPaymentResponse, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.RecordManualPayment")
+		defer seg.End()
+		txn.AddAttribute("order_id", req.OrderID.String())
+		txn.AddAttribute("payment_method", req.Method)
+	}
+
+	if req.Amount <= 0 {
+		return nil, appErrors.BadRequest("Amount must be greater than zero", nil)
+	}
+	if req.Method != ManualMethodCash && req.Reference == "" {
+		return nil, appErrors.BadRequest(fmt.Sprintf("Reference is required for %s payments", req.Method), nil)
+	}
+
+	var result *ManualPaymentResponse
+	lockKey := fmt.Sprintf("payment:order:%s", req.OrderID)
+	err := s.withLock(ctx, lockKey, 10*time.Second, func() error {
+		order, err := s.repo.FindOrderByID(ctx, req.OrderID)
+		if err != nil {
+			return appErrors.NotFound("Order not found", err)
+		}
+		if order.Status == "CANCELED" {
+			return appErrors.UnprocessableEntity("Cannot record a payment for a canceled order", nil)
+		}
+
+		existing, err := s.repo.ListTransactionsByOrderID(ctx, order.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to list payment transactions", err)
+		}
+		if err := s.closeOpenCharges(ctx, order, existing, nil, "manual", "Paid at the counter"); err != nil {
+			return err
+		}
+
+		paid, err := s.repo.SumPaidAmount(ctx, order.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to calculate paid amount", err)
+		}
+		outstanding := order.GrandTotal - paid
+		if outstanding <= 0 {
+			return appErrors.UnprocessableEntity("Order is already fully paid", nil)
+		}
+
+		applied, change := req.Amount, 0.0
+		if req.Amount > outstanding {
+			if req.Method != ManualMethodCash {
+				return appErrors.UnprocessableEntity(
+					fmt.Sprintf("Amount exceeds outstanding balance (%.0f)", outstanding), nil)
+			}
+			applied, change = outstanding, req.Amount-outstanding
+		}
+
+		now := time.Now()
+		tendered := req.Amount
+		paymentTx := &entity.PaymentTransaction{
+			OrderID:         order.ID,
+			PaymentOrderID:  fmt.Sprintf("%s-MANUAL-%d", order.OrderNo, len(existing)+1),
+			PaymentMethod:   strPtr(req.Method),
+			PaymentType:     strPtr(entity.PaymentTransactionTypeManual),
+			GrossAmount:     applied,
+			Status:          "SUCCESS",
+			TransactionID:   strPtrNonEmpty(req.Reference),
+			TransactionTime: &now,
+			SettlementTime:  &now,
+			ReceiptNo:       strPtrNonEmpty(req.ReceiptNo),
+			AmountTendered:  &tendered,
+			ChangeAmount:    &change,
+			RecordedBy:      req.RecordedBy,
+		}
+		if req.Notes != "" {
+			paymentTx.Metadata = entity.JSONB{"notes": req.Notes}
+		}
+
+		err = s.withTx(ctx, func(r repository.PaymentRepository) error {
+			if err := r.CreateTransaction(ctx, paymentTx); err != nil {
+				return appErrors.InternalServerError("Failed to save payment transaction", err)
+			}
+			statusLog := &entity.PaymentStatusLog{
+				PaymentTransactionID: paymentTx.ID,
+				NewStatus:            "SUCCESS",
+				Source:               "manual",
+				StatusMessage:        strPtr(fmt.Sprintf("%s payment recorded at counter: %.0f (tendered %.0f, change %.0f)", req.Method, applied, tendered, change)),
+			}
+			if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+				slog.WarnContext(ctx, "[Payment] Failed to create status log", "error", err)
+			}
+			if err := s.queueOrderConfirmation(ctx, r, paymentTx); err != nil {
+				return appErrors.InternalServerError("Failed to queue order confirmation", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		paid += applied
+		order.SetPaidAmount(paid)
+		result = &ManualPaymentResponse{
+			Payment:           paymentTx,
+			AmountTendered:    tendered,
+			ChangeAmount:      change,
+			PaidAmount:        order.PaidAmount,
+			OutstandingAmount: order.OutstandingAmount,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Manual payment recorded", "method", req.Method,
+		"order_id", req.OrderID, "payment_order_id", result.Payment.PaymentOrderID,
+		"applied", result.Payment.GrossAmount, "change", result.ChangeAmount, "outstanding", result.OutstandingAmount)
+	s.publish(ctx, events.NewPaymentSucceeded(*result.Payment))
+	return result, nil
+}
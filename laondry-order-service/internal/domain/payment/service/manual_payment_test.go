@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/lock"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func testOrder(total float64) *entity.Order {
+	return &entity.Order{ID: uuid.New(), OrderNo: "ORD-001", Status: "NEW", GrandTotal: total}
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, evt events.Event) {
+	p.events = append(p.events, evt)
+}
+
+func TestRecordManualPayment(t *testing.T) {
+	ctx := context.Background()
+	cashier := uuid.New()
+
+	t.Run("Cash overpayment books the outstanding amount and returns change", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(85000)
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(20000), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{{}}, nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.PaymentOrderID == "ORD-001-MANUAL-2" && tx.Status == "SUCCESS" && tx.IsManual() &&
+				tx.GrossAmount == 65000 && *tx.AmountTendered == 100000 && *tx.ChangeAmount == 35000 &&
+				*tx.ReceiptNo == "RCP-9" && *tx.RecordedBy == cashier
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "manual" && l.NewStatus == "SUCCESS"
+		})).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+
+		res, err := svc.RecordManualPayment(ctx, ManualPaymentRequest{
+			OrderID:    order.ID,
+			Method:     ManualMethodCash,
+			Amount:     100000,
+			ReceiptNo:  "RCP-9",
+			RecordedBy: &cashier,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(35000), res.ChangeAmount)
+		assert.Equal(t, float64(85000), res.PaidAmount)
+		assert.Equal(t, float64(0), res.OutstandingAmount)
+		assert.Empty(t, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Partial transfer leaves an outstanding balance", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+		order := testOrder(85000)
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return *tx.PaymentMethod == ManualMethodBankTransfer && *tx.TransactionID == "TRF-123" && *tx.ChangeAmount == 0
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+
+		res, err := svc.RecordManualPayment(ctx, ManualPaymentRequest{
+			OrderID:   order.ID,
+			Method:    ManualMethodBankTransfer,
+			Amount:    50000,
+			Reference: "TRF-123",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(50000), res.PaidAmount)
+		assert.Equal(t, float64(35000), res.OutstandingAmount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Publishes the recorded payment", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		publisher := &recordingPublisher{}
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake(),
+			WithEventPublisher(publisher))
+		order := testOrder(85000)
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+
+		_, err := svc.RecordManualPayment(ctx, ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodCash, Amount: 85000})
+
+		assert.NoError(t, err)
+		if assert.Len(t, publisher.events, 1) {
+			evt := publisher.events[0].(events.PaymentSucceeded)
+			assert.Equal(t, order.ID, evt.OrderID)
+			assert.Equal(t, float64(85000), evt.GrossAmount)
+		}
+	})
+
+	t.Run("Open gateway charge is closed first", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(85000)
+		open := createTestPaymentTransaction()
+		open.OrderID, open.PaymentOrderID = order.ID, "ORD-001"
+		gw.SetStatus("ORD-001", "PENDING")
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{*open}, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORD-001").Return(open, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.PaymentOrderID == "ORD-001" && tx.Status == "EXPIRED"
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "manual" && l.NewStatus == "EXPIRED"
+		})).Return(nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.PaymentOrderID == "ORD-001-MANUAL-2"
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.NewStatus == "SUCCESS"
+		})).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+
+		_, err := svc.RecordManualPayment(ctx, ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodCash, Amount: 85000})
+
+		assert.NoError(t, err)
+		assert.Contains(t, gw.Calls(), "Cancel ORD-001")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Payment is not recorded when its confirmation cannot be queued", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		publisher := &recordingPublisher{}
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake(),
+			WithEventPublisher(publisher))
+		order := testOrder(85000)
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(errors.New("connection reset")).Once()
+
+		_, err := svc.RecordManualPayment(ctx, ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodCash, Amount: 85000})
+
+		var appErr *appErrors.AppError
+		if assert.ErrorAs(t, err, &appErr) {
+			assert.Equal(t, 500, appErr.StatusCode)
+		}
+		assert.Empty(t, publisher.events)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejected requests", func(t *testing.T) {
+		order := testOrder(85000)
+		canceled := testOrder(85000)
+		canceled.Status = "CANCELED"
+
+		tests := []struct {
+			name   string
+			req    ManualPaymentRequest
+			paid   float64
+			order  *entity.Order
+			status int
+		}{
+			{"Transfer without reference", ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodBankTransfer, Amount: 1000}, 0, nil, http.StatusBadRequest},
+			{"EDC above outstanding", ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodEDC, Amount: 90000, Reference: "APP-1"}, 0, order, http.StatusUnprocessableEntity},
+			{"Already fully paid", ManualPaymentRequest{OrderID: order.ID, Method: ManualMethodCash, Amount: 1000}, 85000, order, http.StatusUnprocessableEntity},
+			{"Canceled order", ManualPaymentRequest{OrderID: canceled.ID, Method: ManualMethodCash, Amount: 1000}, 0, canceled, http.StatusUnprocessableEntity},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockRepo := repository.NewMockPaymentRepository()
+				svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+				if tt.order != nil {
+					mockRepo.On("FindOrderByID", ctx, tt.order.ID).Return(tt.order, nil).Once()
+					mockRepo.On("SumPaidAmount", ctx, tt.order.ID).Return(tt.paid, nil).Maybe()
+					mockRepo.On("ListTransactionsByOrderID", ctx, tt.order.ID).Return([]entity.PaymentTransaction{}, nil).Maybe()
+				}
+
+				res, err := svc.RecordManualPayment(ctx, tt.req)
+
+				assert.Nil(t, res)
+				var appErr *appErrors.AppError
+				assert.ErrorAs(t, err, &appErr)
+				assert.Equal(t, tt.status, appErr.StatusCode)
+				mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func TestRefundManualPaymentSkipsGateway(t *testing.T) {
+	ctx := context.Background()
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	paymentTx := settledTx()
+	paymentTx.PaymentType = strPtr(entity.PaymentTransactionTypeManual)
+	paymentTx.PaymentMethod = strPtr(ManualMethodCash)
+
+	mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+	mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(0), nil).Once()
+	mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{}, nil).Once()
+	mockRepo.On("CreateRefund", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Status == "SUCCESS"
+	})).Return(nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 150000, Reason: "Order canceled"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "REFUNDED", res.PaymentStatus)
+	assert.Empty(t, gw.Calls())
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+	"laondry-order-service/pkg/secret"
+)
+
+type MerchantCredentialRequest struct {
+	OutletID        uuid.UUID  `json:"-"`
+	ServerKey       string     `json:"server_key" validate:"required,max=200"`
+	ClientKey       string     `json:"client_key" validate:"required,max=100"`
+	EnabledPayments []string   `json:"enabled_payments"`
+	IsActive        *bool      `json:"is_active"` // Default true
+	UpdatedBy       *uuid.UUID `json:"-"`
+}
+
+// MerchantCredentialResponse never contains the server key itself
+type MerchantCredentialResponse struct {
+	OutletID        uuid.UUID `json:"outlet_id"`
+	ServerKeyHint   string    `json:"server_key_hint"` // e.g. ***x7Yz
+	ClientKey       string    `json:"client_key"`
+	EnabledPayments []string  `json:"enabled_payments"`
+	IsActive        bool      `json:"is_active"`
+}
+
+// credentialsCipher returns the cipher for merchant server keys, or nil when
+// MIDTRANS_CREDENTIALS_KEY is not set (per-outlet credentials disabled)
+func credentialsCipher(cfg *config.Config) *secret.Cipher {
+	if cfg == nil || cfg.Midtrans.CredentialsKey == "" {
+		return nil
+	}
+	c, err := secret.NewCipher(cfg.Midtrans.CredentialsKey)
+	if err != nil {
+		slog.Warn("[Payment] Invalid MIDTRANS_CREDENTIALS_KEY, per-outlet credentials disabled", "error", err)
+		return nil
+	}
+	return c
+}
+
+type merchantResolver struct {
+	repo   repository.PaymentRepository
+	cipher *secret.Cipher
+}
+
+// NewMerchantResolver resolves outlet merchant accounts from the
+// outlet_merchant_credentials table. It returns nil, so every outlet uses the
+// global keys, when MIDTRANS_CREDENTIALS_KEY is not configured.
+func NewMerchantResolver(cfg *config.Config, repo repository.PaymentRepository) gateway.MerchantResolver {
+	c := credentialsCipher(cfg)
+	if c == nil {
+		return nil
+	}
+	return &merchantResolver{repo: repo, cipher: c}
+}
+
+func (m *merchantResolver) MerchantForOutlet(ctx context.Context, outletID uuid.UUID) (*gateway.Merchant, error) {
+	return m.merchant(m.repo.FindMerchantCredential(ctx, outletID))
+}
+
+func (m *merchantResolver) MerchantForPayment(ctx context.Context, paymentOrderID string) (*gateway.Merchant, error) {
+	return m.merchant(m.repo.FindMerchantCredentialByPaymentOrderID(ctx, paymentOrderID))
+}
+
+func (m *merchantResolver) merchant(cred *entity.OutletMerchantCredential, err error) (*gateway.Merchant, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cred.IsActive {
+		return nil, nil
+	}
+	serverKey, err := m.cipher.Decrypt(cred.ServerKeyEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt server key of outlet %s: %w", cred.OutletID, err)
+	}
+	return &gateway.Merchant{
+		ServerKey:       serverKey,
+		ClientKey:       cred.ClientKey,
+		EnabledPayments: cred.EnabledPaymentList(),
+	}, nil
+}
+
+// SetMerchantCredential stores (or replaces) the merchant account of an
+// outlet. The server key is encrypted before it is written.
+func (s *paymentService) SetMerchantCredential(ctx context.Context, req MerchantCredentialRequest) (*MerchantCredentialResponse, error) {
+	c := credentialsCipher(s.cfg)
+	if c == nil {
+		return nil, appErrors.UnprocessableEntity("Per-outlet credentials are disabled (MIDTRANS_CREDENTIALS_KEY not configured)", nil)
+	}
+	if strings.TrimSpace(req.ServerKey) == "" || strings.TrimSpace(req.ClientKey) == "" {
+		return nil, appErrors.BadRequest("server_key and client_key are required", nil)
+	}
+
+	encrypted, err := c.Encrypt(strings.TrimSpace(req.ServerKey))
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to encrypt server key", err)
+	}
+	var payments []string
+	for _, p := range req.EnabledPayments {
+		if p = strings.TrimSpace(p); p != "" {
+			payments = append(payments, p)
+		}
+	}
+	cred := &entity.OutletMerchantCredential{
+		OutletID:           req.OutletID,
+		ServerKeyEncrypted: encrypted,
+		ClientKey:          strings.TrimSpace(req.ClientKey),
+		EnabledPayments:    strings.Join(payments, ","),
+		IsActive:           req.IsActive == nil || *req.IsActive,
+		UpdatedBy:          req.UpdatedBy,
+	}
+	if err := s.repo.SaveMerchantCredential(ctx, cred); err != nil {
+		return nil, appErrors.InternalServerError("Failed to save merchant credentials", err)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Merchant credentials updated", "outlet_id", req.OutletID, "active", cred.IsActive)
+	return merchantCredentialResponse(cred, req.ServerKey), nil
+}
+
+// GetMerchantCredential returns the merchant account of an outlet with the
+// server key masked
+func (s *paymentService) GetMerchantCredential(ctx context.Context, outletID uuid.UUID) (*MerchantCredentialResponse, error) {
+	cred, err := s.repo.FindMerchantCredential(ctx, outletID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appErrors.NotFound("Outlet uses the default merchant account", err)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load merchant credentials", err)
+	}
+	serverKey, err := credentialsCipher(s.cfg).Decrypt(cred.ServerKeyEncrypted)
+	if err != nil {
+		slog.WarnContext(ctx, "[Payment] Cannot decrypt server key", "outlet_id", outletID, "error", err)
+	}
+	return merchantCredentialResponse(cred, serverKey), nil
+}
+
+func merchantCredentialResponse(cred *entity.OutletMerchantCredential, serverKey string) *MerchantCredentialResponse {
+	hint := "***"
+	if len(serverKey) > 8 {
+		hint += serverKey[len(serverKey)-4:]
+	}
+	return &MerchantCredentialResponse{
+		OutletID:        cred.OutletID,
+		ServerKeyHint:   hint,
+		ClientKey:       cred.ClientKey,
+		EnabledPayments: cred.EnabledPaymentList(),
+		IsActive:        cred.IsActive,
+	}
+}
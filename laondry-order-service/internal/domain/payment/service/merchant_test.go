@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func TestMerchantCredentials(t *testing.T) {
+	ctx := context.Background()
+	cfg := createTestConfig()
+	cfg.Midtrans.CredentialsKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(cfg, mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+	outletID := uuid.New()
+
+	var saved *entity.OutletMerchantCredential
+	mockRepo.On("SaveMerchantCredential", ctx, mock.AnythingOfType("*entity.OutletMerchantCredential")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.OutletMerchantCredential) }).
+		Return(nil).Once()
+
+	res, err := svc.SetMerchantCredential(ctx, MerchantCredentialRequest{
+		OutletID:        outletID,
+		ServerKey:       "Mid-server-franchise-1234",
+		ClientKey:       "Mid-client-franchise",
+		EnabledPayments: []string{"other_qris", " bca_va "},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "***1234", res.ServerKeyHint)
+	assert.NotContains(t, saved.ServerKeyEncrypted, "franchise")
+	assert.Equal(t, "other_qris,bca_va", saved.EnabledPayments)
+	assert.True(t, saved.IsActive)
+
+	resolver := NewMerchantResolver(cfg, mockRepo)
+	mockRepo.On("FindMerchantCredential", ctx, outletID).Return(saved, nil).Once()
+	m, err := resolver.MerchantForOutlet(ctx, outletID)
+	assert.NoError(t, err)
+	assert.Equal(t, &gateway.Merchant{
+		ServerKey:       "Mid-server-franchise-1234",
+		ClientKey:       "Mid-client-franchise",
+		EnabledPayments: []string{"other_qris", "bca_va"},
+	}, m)
+
+	// Outlets without (active) credentials use the default account
+	mockRepo.On("FindMerchantCredentialByPaymentOrderID", ctx, "ORD-HQ").Return(nil, gorm.ErrRecordNotFound).Once()
+	m, err = resolver.MerchantForPayment(ctx, "ORD-HQ")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	inactive := *saved
+	inactive.IsActive = false
+	mockRepo.On("FindMerchantCredentialByPaymentOrderID", ctx, "ORD-FR").Return(&inactive, nil).Once()
+	m, err = resolver.MerchantForPayment(ctx, "ORD-FR")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	// A key encrypted with another MIDTRANS_CREDENTIALS_KEY is an error, not a fallback
+	corrupt := *saved
+	corrupt.ServerKeyEncrypted = "bm90LWVuY3J5cHRlZA=="
+	mockRepo.On("FindMerchantCredential", ctx, outletID).Return(&corrupt, nil).Once()
+	_, err = resolver.MerchantForOutlet(ctx, outletID)
+	assert.Error(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMerchantCredentialsDisabledWithoutKey(t *testing.T) {
+	cfg := createTestConfig()
+	assert.Nil(t, NewMerchantResolver(cfg, repository.NewMockPaymentRepository()))
+
+	svc := NewPaymentService(cfg, repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker(), gateway.NewFake())
+	_, err := svc.SetMerchantCredential(context.Background(), MerchantCredentialRequest{
+		OutletID:  uuid.New(),
+		ServerKey: "Mid-server-x",
+		ClientKey: "Mid-client-x",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// CancelOrderPayments closes every open payment of a canceled order: PENDING
+// transactions are canceled at the gateway so the Snap token / VA can no
+// longer be paid, and settled ones are refunded in full (to the customer's
+// wallet when WALLET_REFUND_CANCELED_ORDERS is set). It is safe to call repeatedly;
+// an error means at least one payment still needs another attempt.
+func (s *paymentService) CancelOrderPayments(ctx context.Context, orderID uuid.UUID, canceledBy *uuid.UUID, reason string) error {
+	txs, err := s.repo.ListTransactionsByOrderID(ctx, orderID)
+	if err != nil {
+		return appErrors.InternalServerError("Failed to list payment transactions", err)
+	}
+
+	var errs []error
+	for i := range txs {
+		paymentTx := &txs[i]
+		if paymentTx.Status == "PENDING" {
+			if err := s.closePendingPayment(ctx, paymentTx, "order_cancel", "Order canceled"); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", paymentTx.PaymentOrderID, err))
+				continue
+			}
+		}
+		if refundableStatuses[paymentTx.Status] {
+			if err := s.refundCanceledPayment(ctx, paymentTx, canceledBy, reason); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", paymentTx.PaymentOrderID, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	slog.InfoContext(ctx, "[Payment] Closed payments for canceled order", "order_id", orderID, "transactions", len(txs))
+	return nil
+}
+
+// closePendingPayment re-checks a PENDING transaction and cancels it at the
+// gateway. paymentTx is updated in place; a payment that turns out to be
+// settled is left as SUCCESS for the caller to handle. source and reason end
+// up in the status log.
+func (s *paymentService) closePendingPayment(ctx context.Context, paymentTx *entity.PaymentTransaction, source, reason string) error {
+	return s.withLock(ctx, transactionLockKey(paymentTx.PaymentOrderID), 30*time.Second, func() error {
+		current, err := s.repo.FindTransactionByPaymentOrderID(ctx, paymentTx.PaymentOrderID)
+		if err != nil {
+			return appErrors.NotFound("Payment transaction not found", err)
+		}
+		*paymentTx = *current
+		if current.Status != "PENDING" {
+			return nil
+		}
+
+		var newStatus, message string
+
+		st, err := s.gw.CheckStatus(ctx, current.PaymentOrderID)
+		switch {
+		case errors.Is(err, gateway.ErrNotFound):
+			// Payment page never opened: nothing exists at the gateway yet
+			st = nil
+			newStatus, message = "CANCELED", reason+" before payment was started"
+		case err != nil:
+			return err
+		default:
+			newStatus = st.Status
+			message = fmt.Sprintf("%s; gateway status %s", reason, st.TransactionStatus)
+		}
+
+		if newStatus == "PENDING" {
+			canceled, err := s.gw.Cancel(ctx, current.PaymentOrderID)
+			if err != nil {
+				return err
+			}
+			newStatus = canceled.Status
+			message = fmt.Sprintf("Canceled at %s: %s", s.gw.Name(), reason)
+		}
+
+		if err := s.withTx(ctx, func(r repository.PaymentRepository) error {
+			return s.applyGatewayStatus(ctx, r, current, newStatus, st, source, message)
+		}); err != nil {
+			return err
+		}
+		s.paymentStatusChanged(ctx, current, "PENDING")
+		*paymentTx = *current
+		return nil
+	})
+}
+
+// refundCanceledPayment refunds whatever is left of a settled payment. A
+// refund left PENDING by an inconclusive gateway error is retried first,
+// under its own refund key, since it may not have been issued.
+func (s *paymentService) refundCanceledPayment(ctx context.Context, paymentTx *entity.PaymentTransaction, canceledBy *uuid.UUID, reason string) error {
+	refunds, err := s.repo.ListRefunds(ctx, paymentTx.ID)
+	if err != nil {
+		return appErrors.InternalServerError("Failed to list refunds", err)
+	}
+	var refunded float64
+	var pending *entity.PaymentRefund
+	for i := range refunds {
+		switch refunds[i].Status {
+		case "SUCCESS":
+			refunded += refunds[i].Amount
+		case "PENDING":
+			pending = &refunds[i]
+		}
+	}
+
+	refundReason := "Order canceled"
+	if reason != "" {
+		refundReason += ": " + reason
+	}
+	refund := func(amount float64) error {
+		_, err := s.RefundTransaction(ctx, RefundRequest{
+			PaymentTransactionID: paymentTx.ID,
+			Amount:               amount,
+			Reason:               refundReason,
+			RequestedBy:          canceledBy,
+			ToWallet:             s.cfg.Wallet.RefundCanceledOrders,
+		})
+		return err
+	}
+
+	if pending != nil {
+		slog.InfoContext(ctx, "[Payment] Retrying pending refund of canceled order",
+			"order_id", paymentTx.OrderID, "payment_order_id", paymentTx.PaymentOrderID, "refund_key", pending.RefundKey, "amount", pending.Amount)
+		if err := refund(pending.Amount); err != nil {
+			return err
+		}
+		refunded += pending.Amount
+	}
+
+	remaining := math.Floor(paymentTx.GrossAmount - refunded)
+	if remaining <= 0 {
+		return nil
+	}
+	slog.InfoContext(ctx, "[Payment] Refunding settled payment of canceled order",
+		"order_id", paymentTx.OrderID, "payment_order_id", paymentTx.PaymentOrderID, "amount", remaining)
+	return refund(remaining)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func TestCancelOrderPayments(t *testing.T) {
+	ctx := context.Background()
+	orderID := uuid.New()
+	staff := uuid.New()
+
+	waiting := createTestPaymentTransaction()
+	waiting.OrderID, waiting.PaymentOrderID = orderID, "ORDER-WAITING"
+	unopened := createTestPaymentTransaction()
+	unopened.OrderID, unopened.PaymentOrderID = orderID, "ORDER-UNOPENED"
+	paid := settledTx()
+	paid.OrderID, paid.PaymentOrderID = orderID, "ORDER-PAID"
+
+	// ORDER-UNOPENED was never opened, so the gateway does not know it
+	gw := gateway.NewFake()
+	gw.SetStatus("ORDER-WAITING", "PENDING")
+	gw.SetStatus("ORDER-PAID", "SUCCESS")
+
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	mockRepo.On("ListTransactionsByOrderID", ctx, orderID).
+		Return([]entity.PaymentTransaction{*waiting, *unopened, *paid}, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-WAITING").Return(waiting, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-UNOPENED").Return(unopened, nil).Once()
+	mockRepo.On("FindTransactionByID", ctx, paid.ID).Return(paid, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-PAID").Return(paid, nil).Once()
+	mockRepo.On("SumRefundedAmount", ctx, paid.ID).Return(float64(0), nil).Once()
+	mockRepo.On("ListRefunds", ctx, paid.ID).Return([]entity.PaymentRefund{}, nil).Twice()
+	mockRepo.On("CreateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Amount == 150000 && *r.Reason == "Order canceled: customer request" && *r.RequestedBy == staff
+	})).Return(nil).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Status == "SUCCESS"
+	})).Return(nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Times(3)
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Times(3)
+
+	err := svc.CancelOrderPayments(ctx, orderID, &staff, "customer request")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "EXPIRED", waiting.Status)
+	assert.Equal(t, "CANCELED", unopened.Status)
+	assert.Equal(t, "REFUNDED", paid.Status)
+	assert.Contains(t, gw.Calls(), "Cancel ORDER-WAITING")
+	assert.NotContains(t, gw.Calls(), "Cancel ORDER-UNOPENED")
+	if refunds := gw.Refunds("ORDER-PAID"); assert.Len(t, refunds, 1) {
+		assert.Equal(t, int64(150000), refunds[0].Amount)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCancelOrderPayments_GatewayErrorIsRetried(t *testing.T) {
+	ctx := context.Background()
+	orderID := uuid.New()
+
+	gw := gateway.NewFake()
+	gw.Err = errors.New("internal server error")
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	waiting := createTestPaymentTransaction()
+	waiting.OrderID = orderID
+	mockRepo.On("ListTransactionsByOrderID", ctx, orderID).Return([]entity.PaymentTransaction{*waiting}, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, waiting.PaymentOrderID).Return(waiting, nil).Once()
+
+	err := svc.CancelOrderPayments(ctx, orderID, nil, "")
+
+	assert.Error(t, err)
+	assert.Equal(t, "PENDING", waiting.Status)
+	mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+}
+
+func TestCancelOrderPayments_PendingRefundIsRetried(t *testing.T) {
+	ctx := context.Background()
+	orderID := uuid.New()
+	paid := settledTx()
+	paid.OrderID, paid.PaymentOrderID = orderID, "ORDER-PAID"
+
+	gw := gateway.NewFake()
+	gw.SetStatus("ORDER-PAID", "SUCCESS")
+	gw.Err = context.DeadlineExceeded
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	// The gateway times out: the refund may have been issued and stays PENDING
+	var pending entity.PaymentRefund
+	mockRepo.On("ListTransactionsByOrderID", ctx, orderID).Return([]entity.PaymentTransaction{*paid}, nil).Once()
+	mockRepo.On("FindTransactionByID", ctx, paid.ID).Return(paid, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-PAID").Return(paid, nil).Once()
+	mockRepo.On("ListRefunds", ctx, paid.ID).Return([]entity.PaymentRefund{}, nil).Twice()
+	mockRepo.On("SumRefundedAmount", ctx, paid.ID).Return(float64(0), nil).Once()
+	mockRepo.On("CreateRefund", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Status == "PENDING"
+	})).Run(func(args mock.Arguments) { pending = *args.Get(1).(*entity.PaymentRefund) }).Return(nil).Once()
+
+	assert.Error(t, svc.CancelOrderPayments(ctx, orderID, nil, ""))
+	assert.Equal(t, "SUCCESS", paid.Status)
+	mockRepo.AssertExpectations(t)
+
+	// The outbox retries the cancellation: the pending refund counts towards
+	// the refunded amount, yet it is sent again under the same key
+	gw.Err = nil
+	mockRepo.On("ListTransactionsByOrderID", ctx, orderID).Return([]entity.PaymentTransaction{*paid}, nil).Once()
+	mockRepo.On("FindTransactionByID", ctx, paid.ID).Return(paid, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-PAID").Return(paid, nil).Once()
+	mockRepo.On("ListRefunds", ctx, paid.ID).Return([]entity.PaymentRefund{pending}, nil).Twice()
+	mockRepo.On("SumRefundedAmount", ctx, paid.ID).Return(pending.Amount, nil).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Status == "SUCCESS" && r.RefundKey == pending.RefundKey
+	})).Return(nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, svc.CancelOrderPayments(ctx, orderID, nil, ""))
+	assert.Equal(t, "REFUNDED", paid.Status)
+	if refunds := gw.Refunds("ORDER-PAID"); assert.Len(t, refunds, 1) {
+		assert.Equal(t, pending.RefundKey, refunds[0].RefundKey)
+		assert.Equal(t, int64(150000), refunds[0].Amount)
+	}
+	mockRepo.AssertExpectations(t)
+}
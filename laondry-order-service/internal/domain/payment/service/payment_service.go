@@ -0,0 +1,998 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/lock"
+	"laondry-order-service/internal/logger"
+	"laondry-order-service/internal/metrics"
+	"laondry-order-service/internal/outbox"
+	"laondry-order-service/internal/surcharge"
+	appErrors "laondry-order-service/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"gorm.io/gorm"
+)
+
+// PaymentService keeps the payment bookkeeping (transactions, status logs,
+// webhook logs, refunds) and talks to the provider through a PaymentGateway.
+type PaymentService interface {
+	// Snap Token
+	CreateSnapToken(ctx context.Context, req CreateSnapTokenRequest) (*CreateSnapTokenResponse, error)
+
+	// Core API charges (QRIS, VA, GoPay) without the Snap page
+	CreateDirectCharge(ctx context.Context, req DirectChargeRequest) (*DirectChargeResponse, error)
+
+	// Transaction Status
+	CheckTransactionStatus(ctx context.Context, paymentOrderID string) (*TransactionStatusResponse, error)
+	GetTransactionByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.PaymentTransaction, error)
+	GetTransactionByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.PaymentTransaction, error)
+	ReconcilePendingTransactions(ctx context.Context) (*ReconcileResult, error)
+
+	// Webhook
+	ProcessWebhookNotification(ctx context.Context, payload map[string]interface{}) (*WebhookResponse, error)
+	ListWebhookLogs(ctx context.Context, filters repository.WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error)
+	ReplayWebhookLogs(ctx context.Context, req ReplayWebhookRequest) (*ReplayWebhookResult, error)
+
+	// Manual (counter) payments
+	RecordManualPayment(ctx context.Context, req ManualPaymentRequest) (*ManualPaymentResponse, error)
+
+	// Wallet
+	GetWallet(ctx context.Context, userID uuid.UUID, page, limit int) (*WalletResponse, error)
+	PayOrderWithWallet(ctx context.Context, req WalletPaymentRequest) (*WalletPaymentResponse, error)
+	CreateWalletTopup(ctx context.Context, req WalletTopupRequest) (*WalletTopupResponse, error)
+
+	// Refunds
+	RefundTransaction(ctx context.Context, req RefundRequest) (*RefundResponse, error)
+	ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error)
+	CancelOrderPayments(ctx context.Context, orderID uuid.UUID, canceledBy *uuid.UUID, reason string) error
+
+	// Order confirmation: settling a payment queues it on the outbox, and
+	// ConfirmOrderPayment tells core-api once the order is fully paid
+	ConfirmOrderPayment(ctx context.Context, orderID uuid.UUID, note string) error
+
+	// History
+	GetPaymentHistory(ctx context.Context, orderID uuid.UUID) ([]entity.PaymentTransaction, error)
+	GetTransactionHistory(ctx context.Context, filters repository.TransactionFilters) ([]entity.PaymentTransaction, int64, error)
+
+	// Per-outlet merchant accounts
+	SetMerchantCredential(ctx context.Context, req MerchantCredentialRequest) (*MerchantCredentialResponse, error)
+	GetMerchantCredential(ctx context.Context, outletID uuid.UUID) (*MerchantCredentialResponse, error)
+
+	// Settlement reconciliation
+	ImportSettlementReport(ctx context.Context, req SettlementImportRequest) (*SettlementImportResult, error)
+	GetSettlementReconciliation(ctx context.Context, filters repository.SettlementFilters) (*SettlementReconciliation, error)
+}
+
+type paymentService struct {
+	cfg    *config.Config
+	repo   repository.PaymentRepository
+	db     *gorm.DB
+	locker lock.Locker
+	gw     gateway.PaymentGateway
+	// surcharges prices payment method fees (PAYMENT_SURCHARGES)
+	surcharges surcharge.Table
+	events     events.Publisher
+}
+
+// Option configures optional collaborators of the payment service
+type Option func(*paymentService)
+
+// WithEventPublisher publishes PaymentSucceeded, PaymentFailed and
+// RefundIssued once each change is committed
+func WithEventPublisher(p events.Publisher) Option {
+	return func(s *paymentService) {
+		s.events = p
+	}
+}
+
+func NewPaymentService(cfg *config.Config, repo repository.PaymentRepository, db *gorm.DB, locker lock.Locker, gw gateway.PaymentGateway, opts ...Option) PaymentService {
+	s := &paymentService{
+		cfg:        cfg,
+		repo:       repo,
+		db:         db,
+		locker:     locker,
+		gw:         gw,
+		surcharges: surchargeTable(cfg),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// publish hands a committed change to the event subscribers, if any
+func (s *paymentService) publish(ctx context.Context, evt events.Event) {
+	if s.events != nil {
+		s.events.Publish(ctx, evt)
+	}
+}
+
+// paymentStatusChanged publishes PaymentSucceeded or PaymentFailed for a
+// committed status change of paymentTx from oldStatus
+func (s *paymentService) paymentStatusChanged(ctx context.Context, paymentTx *entity.PaymentTransaction, oldStatus string) {
+	if paymentTx.Status == oldStatus {
+		return
+	}
+	switch paymentTx.Status {
+	case "SUCCESS":
+		s.publish(ctx, events.NewPaymentSucceeded(*paymentTx))
+	case "FAILED", "EXPIRED":
+		s.publish(ctx, events.NewPaymentFailed(*paymentTx, oldStatus))
+	}
+}
+
+// NewMidtransService returns a PaymentService backed by the Midtrans gateway
+func NewMidtransService(cfg *config.Config, repo repository.PaymentRepository, db *gorm.DB, locker lock.Locker) PaymentService {
+	return NewPaymentService(cfg, repo, db, locker, gateway.Instrumented{PaymentGateway: gateway.NewMidtrans(cfg.Midtrans, NewMerchantResolver(cfg, repo))})
+}
+
+// withTx executes the given function within a database transaction
+func (s *paymentService) withTx(ctx context.Context, fn func(r repository.PaymentRepository) error) error {
+	if s.db == nil {
+		return fn(s.repo)
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := s.repo.WithDB(tx)
+		return fn(txRepo)
+	})
+}
+
+// withLock executes the given function with a distributed lock
+func (s *paymentService) withLock(ctx context.Context, key string, ttl time.Duration, fn func() error) error {
+	if s.locker == nil {
+		slog.DebugContext(ctx, "[Payment] No locker configured, executing without lock")
+		return fn()
+	}
+	unlock, ok, err := s.locker.TryLock(ctx, key, ttl)
+	if err != nil {
+		slog.ErrorContext(ctx, "[Payment] Failed to acquire lock", "lock", key, "error", err)
+		return appErrors.InternalServerError("Failed to acquire lock", err)
+	}
+	if !ok {
+		slog.InfoContext(ctx, "[Payment] Lock is busy", "lock", key)
+		return appErrors.BadRequest("Resource busy, please try again", nil)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			slog.WarnContext(ctx, "[Payment] Failed to release lock", "lock", key, "error", err)
+		}
+	}()
+	return fn()
+}
+
+// transactionLockKey is the lock held by every writer of a payment
+// transaction's status: webhooks, status checks, the reconciler, refunds and
+// cancellations. Each saves the whole row, so under different keys one could
+// overwrite the status another just wrote.
+func transactionLockKey(paymentOrderID string) string {
+	return "payment:transaction:" + paymentOrderID
+}
+
+type Item struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	Qty   int32   `json:"qty"`
+}
+
+type Customer struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+type CreateSnapTokenRequest struct {
+	OrderID         uuid.UUID `json:"order_id" validate:"required"`
+	PaymentOrderID  string    `json:"payment_order_id"`              // Optional; generated from the order number when empty or already used
+	GrossAmount     float64   `json:"gross_amount" validate:"gte=0"` // 0 = the whole outstanding balance
+	Items           []Item    `json:"items"`
+	CustomerDetail  *Customer `json:"customer_detail"`
+	EnabledPayments []string  `json:"enabled_payments"`
+	// PaymentMethod limits the Snap page to one payment method (a Snap payment
+	// name such as credit_card) so its surcharge can be added upfront
+	PaymentMethod string `json:"payment_method"`
+	ExpiryMinutes int    `json:"expiry_minutes"`
+}
+
+type CreateSnapTokenResponse struct {
+	PaymentTransactionID uuid.UUID  `json:"payment_transaction_id"`
+	PaymentOrderID       string     `json:"payment_order_id"`
+	GrossAmount          float64    `json:"gross_amount"`
+	SurchargeAmount      float64    `json:"surcharge_amount"` // Payment method surcharge included in GrossAmount
+	RemainingAmount      float64    `json:"remaining_amount"` // Outstanding once this payment settles
+	Token                string     `json:"token"`
+	RedirectURL          string     `json:"redirect_url"`
+	ClientKey            string     `json:"client_key"`
+	ExpiryTime           *time.Time `json:"expiry_time,omitempty"`
+}
+
+type TransactionStatusResponse struct {
+	PaymentTransactionID uuid.UUID                 `json:"payment_transaction_id"`
+	OrderID              uuid.UUID                 `json:"order_id"`
+	PaymentOrderID       string                    `json:"payment_order_id"`
+	Status               string                    `json:"status"`
+	PaymentMethod        *string                   `json:"payment_method"`
+	PaymentType          *string                   `json:"payment_type"`
+	GrossAmount          float64                   `json:"gross_amount"`
+	SurchargeAmount      float64                   `json:"surcharge_amount"`
+	FeeAmount            float64                   `json:"fee_amount"`
+	TransactionID        *string                   `json:"transaction_id"`
+	TransactionTime      *time.Time                `json:"transaction_time"`
+	SettlementTime       *time.Time                `json:"settlement_time"`
+	ExpiryTime           *time.Time                `json:"expiry_time"`
+	FraudStatus          *string                   `json:"fraud_status"`
+	VANumber             *string                   `json:"va_number,omitempty"`
+	BillerCode           *string                   `json:"biller_code,omitempty"`
+	BillKey              *string                   `json:"bill_key,omitempty"`
+	StatusLogs           []entity.PaymentStatusLog `json:"status_logs,omitempty"`
+}
+
+type WebhookResponse struct {
+	PaymentTransactionID uuid.UUID `json:"payment_transaction_id"`
+	OrderID              uuid.UUID `json:"order_id"`
+	Status               string    `json:"status"`
+	Message              string    `json:"message"`
+}
+
+// CreateSnapToken creates a snap token and saves all details to database. An
+// order may be paid in several parts: each charge covers some or all of the
+// outstanding balance, and the first one must meet the outlet's deposit rule.
+func (s *paymentService) CreateSnapToken(ctx context.Context, req CreateSnapTokenRequest) (*CreateSnapTokenResponse, error) {
+	// NewRelic instrumentation
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.CreateSnapToken")
+		defer seg.End()
+		txn.AddAttribute("order_id", req.OrderID.String())
+		txn.AddAttribute("payment_order_id", req.PaymentOrderID)
+		txn.AddAttribute("gross_amount", req.GrossAmount)
+	}
+
+	lctx := logger.With(ctx, "order_id", req.OrderID, "payment_order_id", req.PaymentOrderID)
+	slog.InfoContext(lctx, "[Payment] CreateSnapToken", "amount", req.GrossAmount)
+
+	if req.GrossAmount < 0 {
+		slog.WarnContext(lctx, "[Payment] Invalid gross amount", "amount", req.GrossAmount)
+		return nil, appErrors.BadRequest("Invalid gross_amount", nil)
+	}
+
+	// One charge is planned per order at a time so split payments cannot overpay
+	var result *CreateSnapTokenResponse
+	lockKey := fmt.Sprintf("payment:order:%s", req.OrderID)
+	err := s.withLock(ctx, lockKey, 30*time.Second, func() error {
+		var err error
+		result, err = s.createSnapToken(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *paymentService) createSnapToken(ctx context.Context, req CreateSnapTokenRequest) (*CreateSnapTokenResponse, error) {
+	if req.PaymentMethod != "" {
+		if len(req.EnabledPayments) > 0 && !slices.Contains(req.EnabledPayments, req.PaymentMethod) {
+			return nil, appErrors.BadRequest("payment_method must be one of enabled_payments", nil)
+		}
+		req.EnabledPayments = []string{req.PaymentMethod}
+	}
+	sameSnapCharge := func(tx *entity.PaymentTransaction) bool {
+		return isSnapCharge(tx) && surchargeMethod(tx) == req.PaymentMethod
+	}
+	plan, err := s.planOrderPayment(ctx, req.OrderID, req.PaymentOrderID, req.GrossAmount, sameSnapCharge)
+	if err != nil {
+		return nil, err
+	}
+	// Charge with the merchant account of the order's outlet
+	gwCtx := gateway.WithOutlet(ctx, plan.order.OutletID)
+	if existing := plan.reuse; existing != nil {
+		// Return existing token if still valid and pending
+		slog.InfoContext(ctx, "[Payment] Returning existing token", "existing_payment_order_id", existing.PaymentOrderID)
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.AddAttribute("reused_token", true)
+		}
+		return &CreateSnapTokenResponse{
+			PaymentTransactionID: existing.ID,
+			PaymentOrderID:       existing.PaymentOrderID,
+			GrossAmount:          existing.GrossAmount,
+			SurchargeAmount:      existing.SurchargeAmount,
+			RemainingAmount:      plan.remaining(),
+			Token:                *existing.SnapToken,
+			RedirectURL:          *existing.SnapRedirectURL,
+			ClientKey:            s.gw.ClientKey(gwCtx),
+			ExpiryTime:           existing.ExpiryTime,
+		}, nil
+	}
+	req.PaymentOrderID = plan.paymentOrderID
+	req.GrossAmount = plan.amount
+	fee := s.methodFee(req.PaymentMethod, plan.amount)
+
+	chargeReq := gateway.ChargeRequest{
+		PaymentOrderID:  req.PaymentOrderID,
+		GrossAmount:     int64(req.GrossAmount + fee.surcharge),
+		EnabledPayments: req.EnabledPayments,
+		ExpiryMinutes:   req.ExpiryMinutes,
+	}
+	if plan.isPartial() {
+		// Order items describe the whole order, not this part of it
+		chargeReq.Items = []gateway.Item{plan.item()}
+	} else {
+		for _, it := range req.Items {
+			chargeReq.Items = append(chargeReq.Items, gateway.Item{
+				ID:    it.ID,
+				Name:  it.Name,
+				Price: int64(it.Price),
+				Qty:   it.Qty,
+			})
+		}
+	}
+	chargeReq.Items = fee.items(chargeReq.Items, plan)
+	if req.CustomerDetail != nil {
+		chargeReq.Customer = &gateway.Customer{
+			FirstName: req.CustomerDetail.FirstName,
+			LastName:  req.CustomerDetail.LastName,
+			Email:     req.CustomerDetail.Email,
+			Phone:     req.CustomerDetail.Phone,
+		}
+	}
+
+	var expiryTime *time.Time
+	if req.ExpiryMinutes > 0 {
+		exp := time.Now().Add(time.Duration(req.ExpiryMinutes) * time.Minute)
+		expiryTime = &exp
+	}
+
+	charge, err := s.gw.CreateCharge(gwCtx, chargeReq)
+	if err != nil {
+		slog.ErrorContext(ctx, "[Payment] Charge failed", "gateway", s.gw.Name(), "error", err)
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.NoticeError(err)
+		}
+		if errors.Is(err, gateway.ErrNotConfigured) {
+			return nil, appErrors.InternalServerError("Failed to initialize payment gateway", err)
+		}
+		return nil, appErrors.InternalServerError("Failed to create payment token", err)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Payment token created")
+
+	// Save to database with transaction and locking
+	var result *CreateSnapTokenResponse
+	lockKey := fmt.Sprintf("payment:create:%s", req.PaymentOrderID)
+
+	err = s.withLock(ctx, lockKey, 10*time.Second, func() error {
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			// Save payment transaction
+			paymentTx := &entity.PaymentTransaction{
+				OrderID:         req.OrderID,
+				PaymentOrderID:  req.PaymentOrderID,
+				GrossAmount:     req.GrossAmount,
+				Status:          "PENDING",
+				SnapToken:       &charge.Token,
+				SnapRedirectURL: &charge.RedirectURL,
+				ExpiryTime:      expiryTime,
+				RequestPayload:  mapToJSONB(charge.RequestPayload),
+				ResponsePayload: mapToJSONB(charge.ResponsePayload),
+			}
+			fee.apply(paymentTx)
+
+			if err := r.CreateTransaction(ctx, paymentTx); err != nil {
+				slog.ErrorContext(ctx, "[Payment] Failed to save transaction", "error", err)
+				return appErrors.InternalServerError("Failed to save payment transaction", err)
+			}
+
+			slog.InfoContext(ctx, "[Payment] Transaction saved", "payment_transaction_id", paymentTx.ID)
+
+			// Create initial status log
+			statusLog := &entity.PaymentStatusLog{
+				PaymentTransactionID: paymentTx.ID,
+				NewStatus:            "PENDING",
+				Source:               "api_create",
+				StatusMessage:        strPtr("Snap token created"),
+				RawData:              mapToJSONB(charge.ResponsePayload),
+			}
+
+			if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+				slog.WarnContext(ctx, "[Payment] Failed to create status log", "error", err)
+				// Don't fail the whole operation if status log fails
+			}
+
+			result = &CreateSnapTokenResponse{
+				PaymentTransactionID: paymentTx.ID,
+				PaymentOrderID:       req.PaymentOrderID,
+				GrossAmount:          paymentTx.GrossAmount,
+				SurchargeAmount:      paymentTx.SurchargeAmount,
+				RemainingAmount:      plan.remaining(),
+				Token:                charge.Token,
+				RedirectURL:          charge.RedirectURL,
+				ClientKey:            s.gw.ClientKey(gwCtx),
+				ExpiryTime:           expiryTime,
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		txn.AddAttribute("payment_transaction_id", result.PaymentTransactionID.String())
+		txn.AddAttribute("success", true)
+	}
+
+	slog.InfoContext(ctx, "[Payment] CreateSnapToken completed")
+	return result, nil
+}
+
+// CheckTransactionStatus checks payment status from Midtrans API and updates DB
+func (s *paymentService) CheckTransactionStatus(ctx context.Context, paymentOrderID string) (*TransactionStatusResponse, error) {
+	// NewRelic instrumentation
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.CheckTransactionStatus")
+		defer seg.End()
+		txn.AddAttribute("payment_order_id", paymentOrderID)
+	}
+
+	lctx := logger.With(ctx, "payment_order_id", paymentOrderID)
+	slog.InfoContext(lctx, "[Payment] CheckTransactionStatus")
+
+	// Get from database first
+	paymentTx, err := s.repo.FindTransactionByPaymentOrderID(ctx, paymentOrderID)
+	if err != nil {
+		slog.WarnContext(lctx, "[Payment] Transaction not found", "error", err)
+		return nil, appErrors.NotFound("Payment transaction not found", err)
+	}
+
+	lctx = logger.With(lctx, "order_id", paymentTx.OrderID)
+	slog.DebugContext(lctx, "[Payment] Found transaction", "status", paymentTx.Status)
+
+	if paymentTx.IsManual() || paymentTx.IsWallet() {
+		// Recorded at the counter or paid from the wallet; the gateway knows
+		// nothing about it
+		statusLogs, _ := s.repo.ListStatusLogs(ctx, paymentTx.ID)
+		return newTransactionStatusResponse(paymentTx, statusLogs), nil
+	}
+
+	// Query the gateway for latest status
+	slog.DebugContext(lctx, "[Payment] Querying gateway for status", "gateway", s.gw.Name())
+	st, err := s.gw.CheckStatus(ctx, paymentOrderID)
+	if err != nil {
+		slog.ErrorContext(lctx, "[Payment] Gateway status check failed", "gateway", s.gw.Name(), "error", err)
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.NoticeError(err)
+		}
+		return nil, appErrors.InternalServerError("Failed to check transaction status", err)
+	}
+
+	slog.InfoContext(lctx, "[Payment] Gateway status",
+		"transaction_status", st.TransactionStatus, "status", st.Status, "old_status", paymentTx.Status)
+
+	oldStatus := paymentTx.Status
+	err = s.withLock(ctx, transactionLockKey(paymentOrderID), 10*time.Second, func() error {
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			return s.applyGatewayStatus(ctx, r, paymentTx, st.Status, st, "api_check",
+				fmt.Sprintf("Status checked via API: %s", st.TransactionStatus))
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	s.paymentStatusChanged(ctx, paymentTx, oldStatus)
+
+	// Get status logs
+	statusLogs, _ := s.repo.ListStatusLogs(ctx, paymentTx.ID)
+
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		txn.AddAttribute("current_status", paymentTx.Status)
+		txn.AddAttribute("payment_method", strPtrToString(paymentTx.PaymentMethod))
+	}
+
+	slog.InfoContext(lctx, "[Payment] CheckTransactionStatus completed", "status", paymentTx.Status)
+
+	return newTransactionStatusResponse(paymentTx, statusLogs), nil
+}
+
+func newTransactionStatusResponse(paymentTx *entity.PaymentTransaction, statusLogs []entity.PaymentStatusLog) *TransactionStatusResponse {
+	return &TransactionStatusResponse{
+		PaymentTransactionID: paymentTx.ID,
+		OrderID:              paymentTx.OrderID,
+		PaymentOrderID:       paymentTx.PaymentOrderID,
+		Status:               paymentTx.Status,
+		PaymentMethod:        paymentTx.PaymentMethod,
+		PaymentType:          paymentTx.PaymentType,
+		GrossAmount:          paymentTx.GrossAmount,
+		SurchargeAmount:      paymentTx.SurchargeAmount,
+		FeeAmount:            paymentTx.FeeAmount,
+		TransactionID:        paymentTx.TransactionID,
+		TransactionTime:      paymentTx.TransactionTime,
+		SettlementTime:       paymentTx.SettlementTime,
+		ExpiryTime:           paymentTx.ExpiryTime,
+		FraudStatus:          paymentTx.FraudStatus,
+		VANumber:             paymentTx.VANumber,
+		BillerCode:           paymentTx.BillerCode,
+		BillKey:              paymentTx.BillKey,
+		StatusLogs:           statusLogs,
+	}
+}
+
+// applyGatewayStatus stores the given status (and, when present, the gateway
+// status details) on paymentTx and records a status log if the status
+// changed. Callers hold the payment update lock and run it inside withTx.
+func (s *paymentService) applyGatewayStatus(ctx context.Context, r repository.PaymentRepository, paymentTx *entity.PaymentTransaction, newStatus string, st *gateway.Status, source, message string) error {
+	oldStatus := paymentTx.Status
+	paymentTx.Status = newStatus
+
+	var rawData entity.JSONB
+	if st != nil {
+		rawData = mapToJSONB(st.Raw)
+		applyStatusDetails(paymentTx, st)
+		s.estimateSettlementFee(paymentTx, st)
+	}
+
+	// Save updated transaction
+	if err := r.UpdateTransaction(ctx, paymentTx); err != nil {
+		slog.ErrorContext(ctx, "[Payment] Failed to update transaction", "payment_order_id", paymentTx.PaymentOrderID, "error", err)
+		return appErrors.InternalServerError("Failed to update transaction", err)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Transaction updated", "payment_order_id", paymentTx.PaymentOrderID, "status", paymentTx.Status)
+	if paymentTx.Status == "SUCCESS" && oldStatus != "SUCCESS" {
+		if err := s.queueOrderConfirmation(ctx, r, paymentTx); err != nil {
+			return appErrors.InternalServerError("Failed to queue order confirmation", err)
+		}
+	}
+
+	// Log status change if status changed
+	if oldStatus != paymentTx.Status {
+		slog.InfoContext(ctx, "[Payment] Status changed",
+			"payment_order_id", paymentTx.PaymentOrderID, "old_status", oldStatus, "status", paymentTx.Status)
+		statusLog := &entity.PaymentStatusLog{
+			PaymentTransactionID: paymentTx.ID,
+			PreviousStatus:       &oldStatus,
+			NewStatus:            paymentTx.Status,
+			FraudStatus:          paymentTx.FraudStatus,
+			Source:               source,
+			StatusMessage:        strPtr(message),
+			RawData:              rawData,
+		}
+		if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+			slog.WarnContext(ctx, "[Payment] Failed to create status log", "payment_order_id", paymentTx.PaymentOrderID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// applyStatusDetails copies the gateway's transaction details onto paymentTx
+func applyStatusDetails(paymentTx *entity.PaymentTransaction, st *gateway.Status) {
+	paymentTx.TransactionID = strPtrNonEmpty(st.TransactionID)
+	paymentTx.PaymentMethod = strPtrNonEmpty(st.PaymentType)
+	paymentTx.PaymentType = strPtrNonEmpty(st.PaymentType)
+	paymentTx.FraudStatus = strPtrNonEmpty(st.FraudStatus)
+	if st.TransactionTime != nil {
+		paymentTx.TransactionTime = st.TransactionTime
+	}
+	if st.SettlementTime != nil {
+		paymentTx.SettlementTime = st.SettlementTime
+	}
+	// VA number, biller code and bill key depend on the payment type
+	if st.VANumber != "" {
+		paymentTx.VANumber = strPtr(st.VANumber)
+	}
+	if st.Bank != "" {
+		paymentTx.BillerCode = strPtr(st.Bank)
+	}
+	if st.BillerCode != "" {
+		paymentTx.BillerCode = strPtr(st.BillerCode)
+	}
+	if st.BillKey != "" {
+		paymentTx.BillKey = strPtr(st.BillKey)
+	}
+}
+
+// ProcessWebhookNotification processes a payment gateway webhook notification
+func (s *paymentService) ProcessWebhookNotification(ctx context.Context, payload map[string]interface{}) (*WebhookResponse, error) {
+	// NewRelic instrumentation
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.ProcessWebhookNotification")
+		defer seg.End()
+	}
+
+	return s.processNotification(ctx, payload, webhookOrigin{})
+}
+
+// webhookOrigin describes where a notification payload came from. The zero
+// value is a live notification pushed by the gateway.
+type webhookOrigin struct {
+	logID      uuid.UUID // optional pre-assigned ID for the recorded webhook log
+	replayOf   *uuid.UUID
+	replayedBy *uuid.UUID
+	dryRun     bool
+}
+
+func (o webhookOrigin) eventType() *string {
+	switch {
+	case o.replayOf == nil:
+		return nil
+	case o.dryRun:
+		return strPtr("replay_dry_run")
+	default:
+		return strPtr("replay")
+	}
+}
+
+// processNotification applies a notification payload and counts its outcome
+// in metrics.PaymentWebhooks
+func (s *paymentService) processNotification(ctx context.Context, payload map[string]interface{}, origin webhookOrigin) (*WebhookResponse, error) {
+	res, err := s.applyNotification(ctx, payload, origin)
+	eventType := "notification"
+	if t := origin.eventType(); t != nil {
+		eventType = *t
+	}
+	metrics.PaymentWebhooks.WithLabelValues(s.gw.Name(), eventType, webhookOutcome(err)).Inc()
+	return res, err
+}
+
+// webhookOutcome labels the result of a notification: processed,
+// invalid_signature, not_found or error
+func webhookOutcome(err error) string {
+	var appErr *appErrors.AppError
+	switch {
+	case err == nil:
+		return "processed"
+	case errors.As(err, &appErr) && appErr.StatusCode == http.StatusUnauthorized:
+		return "invalid_signature"
+	case errors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound:
+		return "not_found"
+	}
+	return metrics.OutcomeError
+}
+
+// applyNotification applies a notification payload. Every call records a
+// webhook log; replays are linked to the original log and dry runs stop
+// before touching the transaction.
+func (s *paymentService) applyNotification(ctx context.Context, payload map[string]interface{}, origin webhookOrigin) (*WebhookResponse, error) {
+
+	n := s.gw.VerifyWebhook(ctx, payload)
+	paymentOrderID := n.PaymentOrderID
+
+	// lctx only carries log attributes; repositories keep the caller's ctx
+	lctx := logger.With(ctx, "payment_order_id", paymentOrderID)
+	slog.InfoContext(lctx, "[Payment] Webhook received",
+		"transaction_status", n.TransactionStatus, "fraud_status", n.FraudStatus)
+
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		txn.AddAttribute("payment_order_id", paymentOrderID)
+		txn.AddAttribute("transaction_status", n.TransactionStatus)
+		txn.AddAttribute("fraud_status", n.FraudStatus)
+	}
+
+	signatureVerified := n.Verified
+	slog.DebugContext(lctx, "[Payment] Webhook signature verification", "verified", signatureVerified)
+
+	// Find payment transaction
+	paymentTx, err := s.repo.FindTransactionByPaymentOrderID(ctx, paymentOrderID)
+	var paymentTxID *uuid.UUID
+	if err == nil && paymentTx != nil {
+		paymentTxID = &paymentTx.ID
+		lctx = logger.With(lctx, "order_id", paymentTx.OrderID)
+		slog.DebugContext(lctx, "[Payment] Found transaction for webhook", "payment_transaction_id", paymentTx.ID)
+	} else {
+		slog.WarnContext(lctx, "[Payment] Transaction not found for webhook")
+	}
+
+	// Always log webhook regardless of transaction found
+	webhookLog := &entity.PaymentWebhookLog{
+		ID:                   origin.logID,
+		PaymentTransactionID: paymentTxID,
+		PaymentOrderID:       paymentOrderID,
+		Source:               s.gw.Name(),
+		TransactionStatus:    strPtrNonEmpty(n.TransactionStatus),
+		FraudStatus:          strPtrNonEmpty(n.FraudStatus),
+		StatusCode:           strPtrNonEmpty(n.StatusCode),
+		GrossAmount:          strPtrNonEmpty(n.GrossAmount),
+		SignatureKey:         strPtrNonEmpty(n.SignatureKey),
+		SignatureVerified:    signatureVerified,
+		RawPayload:           entity.JSONB(payload),
+		EventType:            origin.eventType(),
+		ReplayOfID:           origin.replayOf,
+		ReplayedBy:           origin.replayedBy,
+		DryRun:               origin.dryRun,
+	}
+
+	if !signatureVerified {
+		slog.ErrorContext(lctx, "[Payment] Invalid webhook signature")
+		webhookLog.ProcessingError = strPtr("Invalid signature")
+		_ = s.repo.CreateWebhookLog(ctx, webhookLog)
+		signatureErr := errors.New("invalid webhook signature")
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.NoticeError(signatureErr)
+		}
+		return nil, appErrors.Unauthorized("Invalid webhook signature", signatureErr)
+	}
+
+	if paymentTx == nil && strings.HasPrefix(paymentOrderID, walletTopupPrefix) {
+		return s.applyTopupNotification(ctx, n, webhookLog, origin)
+	}
+
+	if paymentTx == nil {
+		slog.ErrorContext(lctx, "[Payment] Payment transaction not found for webhook")
+		webhookLog.ProcessingError = strPtr("Payment transaction not found")
+		_ = s.repo.CreateWebhookLog(ctx, webhookLog)
+		return nil, appErrors.NotFound(fmt.Sprintf("Payment transaction not found for order_id: %s", paymentOrderID), nil)
+	}
+
+	// Update payment transaction with locking and transaction
+	oldStatus := paymentTx.Status
+	newStatus := n.Status.Status
+
+	slog.InfoContext(lctx, "[Payment] Webhook status mapping",
+		"transaction_status", n.TransactionStatus, "status", newStatus, "old_status", oldStatus)
+
+	if origin.dryRun {
+		if err := s.repo.CreateWebhookLog(ctx, webhookLog); err != nil {
+			slog.WarnContext(lctx, "[Payment] Failed to create webhook log", "error", err)
+		}
+		return &WebhookResponse{
+			PaymentTransactionID: paymentTx.ID,
+			OrderID:              paymentTx.OrderID,
+			Status:               newStatus,
+			Message:              fmt.Sprintf("Dry run: status would change %s -> %s", oldStatus, newStatus),
+		}, nil
+	}
+
+	var result *WebhookResponse
+	var refunds []entity.PaymentRefund
+	err = s.withLock(ctx, transactionLockKey(paymentOrderID), 10*time.Second, func() error {
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			// Update payment transaction
+			paymentTx.Status = newStatus
+			applyStatusDetails(paymentTx, &n.Status)
+
+			// Save updated transaction
+			if err := r.UpdateTransaction(ctx, paymentTx); err != nil {
+				slog.ErrorContext(lctx, "[Payment] Failed to update transaction from webhook", "error", err)
+				webhookLog.ProcessingError = strPtr(fmt.Sprintf("Failed to update transaction: %v", err))
+				return appErrors.InternalServerError("Failed to update transaction", err)
+			}
+
+			slog.InfoContext(lctx, "[Payment] Transaction updated from webhook", "status", paymentTx.Status)
+			if newStatus == "SUCCESS" && oldStatus != "SUCCESS" {
+				if err := s.queueOrderConfirmation(ctx, r, paymentTx); err != nil {
+					webhookLog.ProcessingError = strPtr(fmt.Sprintf("Failed to queue order confirmation: %v", err))
+					return appErrors.InternalServerError("Failed to queue order confirmation", err)
+				}
+			}
+
+			// Money arrived for an order that was already canceled: queue the
+			// cancellation again so the payment is refunded
+			if newStatus == "SUCCESS" && oldStatus != "SUCCESS" && paymentTx.Order != nil && paymentTx.Order.Status == "CANCELED" {
+				slog.WarnContext(lctx, "[Payment] Payment settled for canceled order, queueing refund")
+				msg, err := outbox.NewMessage(outbox.TopicOrderCanceled, &paymentTx.OrderID, outbox.OrderCanceled{
+					OrderID: paymentTx.OrderID,
+					Reason:  strPtr("Payment received after cancellation"),
+				})
+				if err == nil {
+					err = r.CreateOutboxMessage(ctx, msg)
+				}
+				if err != nil {
+					webhookLog.ProcessingError = strPtr(fmt.Sprintf("Failed to queue refund: %v", err))
+					return appErrors.InternalServerError("Failed to queue refund for canceled order", err)
+				}
+			}
+
+			// Refund and chargeback notifications carry the full refund list
+			issued, err := s.recordWebhookRefunds(ctx, r, paymentTx, n.Refunds)
+			if err != nil {
+				slog.ErrorContext(lctx, "[Payment] Failed to record refunds from webhook", "error", err)
+				webhookLog.ProcessingError = strPtr(fmt.Sprintf("Failed to record refunds: %v", err))
+				return appErrors.InternalServerError("Failed to record refunds", err)
+			}
+			refunds = issued
+
+			// Mark webhook as processed
+			now := time.Now()
+			webhookLog.ProcessedAt = &now
+			if err := r.CreateWebhookLog(ctx, webhookLog); err != nil {
+				slog.WarnContext(lctx, "[Payment] Failed to create webhook log", "error", err)
+			}
+
+			// Create status log if status changed
+			if oldStatus != paymentTx.Status {
+				slog.InfoContext(lctx, "[Payment] Webhook status changed", "old_status", oldStatus, "status", paymentTx.Status)
+				statusLog := &entity.PaymentStatusLog{
+					PaymentTransactionID: paymentTx.ID,
+					PreviousStatus:       &oldStatus,
+					NewStatus:            paymentTx.Status,
+					FraudStatus:          paymentTx.FraudStatus,
+					Source:               "webhook",
+					StatusMessage:        strPtr(fmt.Sprintf("Webhook notification: %s", n.TransactionStatus)),
+					RawData:              entity.JSONB(payload),
+				}
+				if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+					slog.WarnContext(lctx, "[Payment] Failed to create status log", "error", err)
+				}
+			}
+
+			result = &WebhookResponse{
+				PaymentTransactionID: paymentTx.ID,
+				OrderID:              paymentTx.OrderID,
+				Status:               paymentTx.Status,
+				Message:              "Payment notification processed successfully",
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		s.logFailedWebhook(ctx, webhookLog)
+		return nil, err
+	}
+
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		txn.AddAttribute("webhook_processed", true)
+		txn.AddAttribute("new_status", result.Status)
+	}
+
+	s.paymentStatusChanged(ctx, paymentTx, oldStatus)
+	for _, refund := range refunds {
+		s.publish(ctx, events.NewRefundIssued(*paymentTx, refund))
+	}
+
+	slog.InfoContext(lctx, "[Payment] Webhook processed", "status", result.Status)
+	return result, nil
+}
+
+// logFailedWebhook saves the webhook log of a notification whose processing
+// failed. It is written outside the rolled-back transaction, else the failure
+// would leave no trace.
+func (s *paymentService) logFailedWebhook(ctx context.Context, webhookLog *entity.PaymentWebhookLog) {
+	if webhookLog.ProcessingError == nil {
+		return
+	}
+	if err := s.repo.CreateWebhookLog(ctx, webhookLog); err != nil {
+		slog.WarnContext(ctx, "[Payment] Failed to create webhook log", "payment_order_id", webhookLog.PaymentOrderID, "error", err)
+	}
+}
+
+// GetTransactionByOrderID gets payment transaction by order ID
+func (s *paymentService) GetTransactionByOrderID(ctx context.Context, orderID uuid.UUID) (*entity.PaymentTransaction, error) {
+	return s.repo.FindTransactionByOrderID(ctx, orderID)
+}
+
+// GetTransactionByPaymentOrderID gets payment transaction by payment order ID
+func (s *paymentService) GetTransactionByPaymentOrderID(ctx context.Context, paymentOrderID string) (*entity.PaymentTransaction, error) {
+	return s.repo.FindTransactionByPaymentOrderID(ctx, paymentOrderID)
+}
+
+// GetPaymentHistory gets all payment transactions for an order
+func (s *paymentService) GetPaymentHistory(ctx context.Context, orderID uuid.UUID) ([]entity.PaymentTransaction, error) {
+	return s.repo.ListTransactionsByOrderID(ctx, orderID)
+}
+
+// GetTransactionHistory gets payment transaction history with filters
+func (s *paymentService) GetTransactionHistory(ctx context.Context, filters repository.TransactionFilters) ([]entity.PaymentTransaction, int64, error) {
+	return s.repo.ListTransactions(ctx, filters)
+}
+
+// updateOrderStatus calls core API to update order status
+func (s *paymentService) updateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus, note string) error {
+	coreAPIURL := s.cfg.External.CoreAPIURL
+	if coreAPIURL == "" {
+		return errors.New("CORE_API_URL not configured")
+	}
+
+	url := fmt.Sprintf("%s/orders/%s/status", coreAPIURL, orderID.String())
+	payload := map[string]interface{}{
+		"new_status": newStatus,
+		"note":       note,
+		"source":     "payment_webhook",
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	logger.PropagateRequestID(req)
+
+	client := &http.Client{Timeout: 10 * time.Second, Transport: metrics.InstrumentTransport("update_order_status", nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call core API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return &coreAPIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	slog.InfoContext(ctx, "[Payment] Order status updated in core-api", "order_id", orderID, "status", newStatus)
+	return nil
+}
+
+// coreAPIError is an error response from core-api
+type coreAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *coreAPIError) Error() string {
+	return fmt.Sprintf("core API returned error %d: %s", e.StatusCode, e.Body)
+}
+
+// Helper functions
+
+func mapToJSONB(data interface{}) entity.JSONB {
+	if data == nil {
+		return nil
+	}
+	// Convert to map for JSONB
+	result := make(map[string]interface{})
+
+	// Use type assertion or reflection based on actual type
+	// For simplicity, we'll just wrap it
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return entity.JSONB(v)
+	default:
+		result["data"] = data
+		return entity.JSONB(result)
+	}
+}
+
+func getStringFromMap(m map[string]interface{}, key string) string {
+	if v, ok := m[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func strPtrNonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func strPtrToString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
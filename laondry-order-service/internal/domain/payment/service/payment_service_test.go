@@ -11,8 +11,10 @@ import (
 	"gorm.io/gorm"
 
 	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
 	"laondry-order-service/internal/domain/payment/repository"
 	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
 	"laondry-order-service/internal/lock"
 )
 
@@ -44,47 +46,6 @@ func createTestPaymentTransaction() *entity.PaymentTransaction {
 	}
 }
 
-// TestVerifySignature tests signature verification
-func TestVerifySignature(t *testing.T) {
-	cfg := createTestConfig()
-	mockRepo := repository.NewMockPaymentRepository()
-	mockLocker := lock.NewMemoryLocker()
-	svc := NewMidtransService(cfg, mockRepo, nil, mockLocker)
-
-	tests := []struct {
-		name          string
-		orderID       string
-		statusCode    string
-		grossAmount   string
-		signature     string
-		expectedValid bool
-	}{
-		{
-			name:          "Invalid signature",
-			orderID:       "ORDER-123",
-			statusCode:    "200",
-			grossAmount:   "150000.00",
-			signature:     "invalid-signature",
-			expectedValid: false,
-		},
-		{
-			name:          "Empty signature",
-			orderID:       "ORDER-123",
-			statusCode:    "200",
-			grossAmount:   "150000.00",
-			signature:     "",
-			expectedValid: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := svc.VerifySignature(tt.orderID, tt.statusCode, tt.grossAmount, tt.signature)
-			assert.Equal(t, tt.expectedValid, result)
-		})
-	}
-}
-
 // TestGetTransactionByOrderID tests getting transaction by order ID
 func TestGetTransactionByOrderID(t *testing.T) {
 	cfg := createTestConfig()
@@ -256,78 +217,6 @@ func TestGetTransactionHistory(t *testing.T) {
 	})
 }
 
-// TestMapMidtransStatus tests status mapping
-func TestMapMidtransStatus(t *testing.T) {
-	tests := []struct {
-		name              string
-		transactionStatus string
-		fraudStatus       string
-		expected          string
-	}{
-		{
-			name:              "Settlement - Success",
-			transactionStatus: "settlement",
-			fraudStatus:       "",
-			expected:          "SUCCESS",
-		},
-		{
-			name:              "Capture with accept - Success",
-			transactionStatus: "capture",
-			fraudStatus:       "accept",
-			expected:          "SUCCESS",
-		},
-		{
-			name:              "Capture without accept - Pending",
-			transactionStatus: "capture",
-			fraudStatus:       "",
-			expected:          "PENDING",
-		},
-		{
-			name:              "Pending",
-			transactionStatus: "pending",
-			fraudStatus:       "",
-			expected:          "PENDING",
-		},
-		{
-			name:              "Deny - Canceled",
-			transactionStatus: "deny",
-			fraudStatus:       "",
-			expected:          "CANCELED",
-		},
-		{
-			name:              "Cancel - Canceled",
-			transactionStatus: "cancel",
-			fraudStatus:       "",
-			expected:          "CANCELED",
-		},
-		{
-			name:              "Expire - Expired",
-			transactionStatus: "expire",
-			fraudStatus:       "",
-			expected:          "EXPIRED",
-		},
-		{
-			name:              "Failure - Failed",
-			transactionStatus: "failure",
-			fraudStatus:       "",
-			expected:          "FAILED",
-		},
-		{
-			name:              "Unknown - Pending",
-			transactionStatus: "unknown",
-			fraudStatus:       "",
-			expected:          "PENDING",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := mapMidtransStatus(tt.transactionStatus, tt.fraudStatus)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 // TestProcessWebhookNotification tests webhook processing
 func TestProcessWebhookNotification(t *testing.T) {
 	cfg := createTestConfig()
@@ -380,52 +269,31 @@ func TestProcessWebhookNotification(t *testing.T) {
 	})
 }
 
-// TestParseTime tests time parsing helper
-func TestParseTime(t *testing.T) {
-	tests := []struct {
-		name      string
-		timeStr   string
-		shouldErr bool
-	}{
-		{
-			name:      "Valid Midtrans format",
-			timeStr:   "2025-01-05 14:30:00",
-			shouldErr: false,
-		},
-		{
-			name:      "Valid RFC3339 format",
-			timeStr:   "2025-01-05T14:30:00Z",
-			shouldErr: false,
-		},
-		{
-			name:      "Valid RFC3339 with timezone",
-			timeStr:   "2025-01-05T14:30:00+07:00",
-			shouldErr: false,
-		},
-		{
-			name:      "Invalid format",
-			timeStr:   "invalid-time",
-			shouldErr: true,
-		},
-		{
-			name:      "Empty string",
-			timeStr:   "",
-			shouldErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseTime(tt.timeStr)
-			if tt.shouldErr {
-				assert.Error(t, err)
-				assert.True(t, result.IsZero())
-			} else {
-				assert.NoError(t, err)
-				assert.False(t, result.IsZero())
-			}
-		})
+func TestProcessWebhookNotification_PublishesPaymentFailed(t *testing.T) {
+	ctx := context.Background()
+	cfg := createTestConfig()
+	mockRepo := repository.NewMockPaymentRepository()
+	publisher := &recordingPublisher{}
+	svc := NewPaymentService(cfg, mockRepo, nil, lock.NewMemoryLocker(), gateway.NewMidtrans(cfg.Midtrans, NewMerchantResolver(cfg, mockRepo)),
+		WithEventPublisher(publisher))
+	paymentTx := createTestPaymentTransaction()
+
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateWebhookLog", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	res, err := svc.ProcessWebhookNotification(ctx, signedPayload(cfg.Midtrans.ServerKey, paymentTx.PaymentOrderID, "expire"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "EXPIRED", res.Status)
+	if assert.Len(t, publisher.events, 1) {
+		evt := publisher.events[0].(events.PaymentFailed)
+		assert.Equal(t, paymentTx.ID, evt.PaymentID)
+		assert.Equal(t, "PENDING", evt.PreviousStatus)
+		assert.Equal(t, "EXPIRED", evt.Status)
 	}
+	mockRepo.AssertExpectations(t)
 }
 
 // TestHelperFunctions tests utility helper functions
@@ -517,27 +385,99 @@ func TestMapToJSONB(t *testing.T) {
 	})
 }
 
-// Benchmark tests
-func BenchmarkVerifySignature(b *testing.B) {
-	cfg := createTestConfig()
-	mockRepo := repository.NewMockPaymentRepository()
-	mockLocker := lock.NewMemoryLocker()
-	svc := NewMidtransService(cfg, mockRepo, nil, mockLocker)
+// TestPaymentServiceWithFakeGateway runs the payment flow against the in-memory gateway
+func TestPaymentServiceWithFakeGateway(t *testing.T) {
+	ctx := context.Background()
+	orderID := uuid.New()
 
-	orderID := "ORDER-BENCH-123"
-	statusCode := "200"
-	grossAmount := "150000.00"
-	signature := "test-signature"
+	t.Run("Create charge then settle via webhook", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		publisher := &recordingPublisher{}
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw, WithEventPublisher(publisher))
+
+		var saved *entity.PaymentTransaction
+		order := &entity.Order{ID: orderID, OrderNo: "ORD-FAKE", Status: "NEW", GrandTotal: 50000}
+		mockRepo.On("FindOrderByID", ctx, orderID).Return(order, nil)
+		mockRepo.On("ListTransactionsByOrderID", ctx, orderID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, orderID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+			Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil)
+
+		res, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{
+			OrderID:        orderID,
+			PaymentOrderID: "ORDER-FAKE-1",
+			GrossAmount:    50000,
+			Items:          []Item{{ID: "svc-1", Name: "Cuci Kering", Price: 50000, Qty: 1}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "fake-token-ORDER-FAKE-1", res.Token)
+		assert.Equal(t, "fake-client-key", res.ClientKey)
+		assert.Equal(t, "PENDING", saved.Status)
+
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-FAKE-1").Return(saved, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(orderID)).Return(nil).Once()
+		mockRepo.On("CreateWebhookLog", ctx, mock.MatchedBy(func(l *entity.PaymentWebhookLog) bool {
+			return l.Source == "fake" && l.SignatureVerified
+		})).Return(nil).Once()
+
+		hook, err := svc.ProcessWebhookNotification(ctx, map[string]interface{}{
+			"order_id":      "ORDER-FAKE-1",
+			"status":        "SUCCESS",
+			"signature_key": gw.Secret,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "SUCCESS", hook.Status)
+		assert.Equal(t, []string{"CreateCharge ORDER-FAKE-1"}, gw.Calls())
+		// The order is confirmed by the outbox, not while the webhook waits
+		if assert.Len(t, publisher.events, 1) {
+			assert.Equal(t, "ORDER-FAKE-1", publisher.events[0].(events.PaymentSucceeded).PaymentOrderID)
+		}
+		mockRepo.AssertExpectations(t)
+	})
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		svc.VerifySignature(orderID, statusCode, grossAmount, signature)
-	}
-}
+	t.Run("Unverified webhook is rejected", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := createTestPaymentTransaction()
+
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("CreateWebhookLog", ctx, mock.MatchedBy(func(l *entity.PaymentWebhookLog) bool {
+			return !l.SignatureVerified
+		})).Return(nil).Once()
+
+		res, err := svc.ProcessWebhookNotification(ctx, map[string]interface{}{
+			"order_id":      paymentTx.PaymentOrderID,
+			"status":        "SUCCESS",
+			"signature_key": "forged",
+		})
+		assert.Nil(t, res)
+		assert.Error(t, err)
+		assert.Equal(t, "PENDING", paymentTx.Status)
+		mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	})
 
-func BenchmarkMapMidtransStatus(b *testing.B) {
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		mapMidtransStatus("settlement", "accept")
-	}
+	t.Run("Status check uses the gateway status", func(t *testing.T) {
+		gw := gateway.NewFake()
+		gw.SetStatus("ORDER-TEST-PAY-1", "EXPIRED")
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := createTestPaymentTransaction()
+
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "api_check" && l.NewStatus == "EXPIRED"
+		})).Return(nil).Once()
+		mockRepo.On("ListStatusLogs", ctx, paymentTx.ID).Return([]entity.PaymentStatusLog{}, nil).Once()
+
+		res, err := svc.CheckTransactionStatus(ctx, paymentTx.PaymentOrderID)
+		assert.NoError(t, err)
+		assert.Equal(t, "EXPIRED", res.Status)
+		mockRepo.AssertExpectations(t)
+	})
 }
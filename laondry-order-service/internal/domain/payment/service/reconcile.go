@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+const (
+	defaultReconcileStaleMinutes = 15
+	defaultReconcileBatchSize    = 50
+)
+
+type ReconcileResult struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+	Expired int `json:"expired"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// ReconcilePendingTransactions re-checks PENDING transactions that have not
+// been updated or reconciled recently (e.g. because a webhook was lost)
+// against the payment gateway. Transactions past their expiry time that the gateway still reports
+// as pending, or does not know about, are marked EXPIRED.
+func (s *paymentService) ReconcilePendingTransactions(ctx context.Context) (*ReconcileResult, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.ReconcilePendingTransactions")
+		defer seg.End()
+	}
+
+	staleMinutes := s.cfg.Reconcile.StaleAfterMinutes
+	if staleMinutes <= 0 {
+		staleMinutes = defaultReconcileStaleMinutes
+	}
+	batchSize := s.cfg.Reconcile.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReconcileBatchSize
+	}
+
+	stale, err := s.repo.ListStalePendingTransactions(ctx, time.Now().Add(-time.Duration(staleMinutes)*time.Minute), batchSize)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list pending transactions", err)
+	}
+
+	result := &ReconcileResult{}
+	if len(stale) == 0 {
+		return result, nil
+	}
+
+	slog.InfoContext(ctx, "[Payment] Reconciling stale pending transactions", "count", len(stale))
+	for i := range stale {
+		if ctx.Err() != nil {
+			break
+		}
+		result.Checked++
+		newStatus, err := s.reconcileOne(ctx, &stale[i])
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "[Payment] Reconcile failed",
+				"order_id", stale[i].OrderID, "payment_order_id", stale[i].PaymentOrderID, "error", err)
+			result.Failed++
+		case newStatus == "":
+			result.Skipped++
+		case newStatus == "EXPIRED":
+			result.Expired++
+		default:
+			result.Updated++
+		}
+	}
+
+	slog.InfoContext(ctx, "[Payment] Reconcile finished", "checked", result.Checked, "updated", result.Updated,
+		"expired", result.Expired, "skipped", result.Skipped, "failed", result.Failed)
+	return result, nil
+}
+
+// reconcileOne returns the status the transaction moved to, or "" when it is
+// still pending or was changed concurrently. The check is recorded whatever
+// the outcome, so rows the gateway keeps failing on do not hold up the rest.
+func (s *paymentService) reconcileOne(ctx context.Context, paymentTx *entity.PaymentTransaction) (string, error) {
+	id := paymentTx.ID
+	defer func() {
+		if err := s.repo.MarkReconciled(ctx, id, time.Now()); err != nil {
+			slog.WarnContext(ctx, "[Payment] Failed to record reconcile check", "payment_order_id", paymentTx.PaymentOrderID, "error", err)
+		}
+	}()
+
+	expired := paymentTx.ExpiryTime != nil && time.Now().After(*paymentTx.ExpiryTime)
+
+	st, err := s.gw.CheckStatus(ctx, paymentTx.PaymentOrderID)
+
+	var newStatus, message string
+	switch {
+	case errors.Is(err, gateway.ErrNotFound) && expired:
+		st = nil
+		newStatus = "EXPIRED"
+		message = fmt.Sprintf("Expired: no %s transaction before expiry time", s.gw.Name())
+	case err != nil:
+		return "", err
+	default:
+		newStatus = st.Status
+		message = fmt.Sprintf("Reconciled via API: %s", st.TransactionStatus)
+		if newStatus == "PENDING" && expired {
+			newStatus = "EXPIRED"
+			message = fmt.Sprintf("Expired: still %s after expiry time", st.TransactionStatus)
+		}
+	}
+	if newStatus == "PENDING" {
+		return "", nil
+	}
+
+	var applied bool
+	err = s.withLock(ctx, transactionLockKey(paymentTx.PaymentOrderID), 10*time.Second, func() error {
+		// A webhook may have landed since the stale list was read
+		current, err := s.repo.FindTransactionByPaymentOrderID(ctx, paymentTx.PaymentOrderID)
+		if err != nil {
+			return appErrors.NotFound("Payment transaction not found", err)
+		}
+		if current.Status != "PENDING" {
+			return nil
+		}
+		applied = true
+		*paymentTx = *current
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			return s.applyGatewayStatus(ctx, r, paymentTx, newStatus, st, "reconciler", message)
+		})
+	})
+	if err != nil || !applied {
+		return "", err
+	}
+
+	s.paymentStatusChanged(ctx, paymentTx, "PENDING")
+	return newStatus, nil
+}
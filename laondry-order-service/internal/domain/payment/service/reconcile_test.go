@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func pendingTx(paymentOrderID string, expiry time.Time) entity.PaymentTransaction {
+	tx := *createTestPaymentTransaction()
+	tx.PaymentOrderID = paymentOrderID
+	tx.ExpiryTime = &expiry
+	return tx
+}
+
+func TestReconcilePendingTransactions(t *testing.T) {
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	settled := pendingTx("ORDER-SETTLED", future)
+	waiting := pendingTx("ORDER-WAITING", future)
+	overdue := pendingTx("ORDER-OVERDUE", past)
+	unknown := pendingTx("ORDER-UNKNOWN", past)
+	broken := pendingTx("ORDER-BROKEN", future)
+
+	// ORDER-UNKNOWN and ORDER-BROKEN are unknown to the gateway
+	gw := gateway.NewFake()
+	gw.SetStatus("ORDER-SETTLED", "SUCCESS")
+	gw.SetStatus("ORDER-WAITING", "PENDING")
+	gw.SetStatus("ORDER-OVERDUE", "PENDING")
+
+	cfg := createTestConfig()
+	cfg.Reconcile.StaleAfterMinutes = 15
+	cfg.Reconcile.BatchSize = 10
+
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(cfg, mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	mockRepo.On("ListStalePendingTransactions", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return before.Before(time.Now().Add(-14 * time.Minute))
+	}), 10).Return([]entity.PaymentTransaction{settled, waiting, overdue, unknown, broken}, nil).Once()
+
+	for _, tx := range []entity.PaymentTransaction{settled, overdue, unknown} {
+		tx := tx
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, tx.PaymentOrderID).Return(&tx, nil).Once()
+	}
+	// Every check is recorded, including those that changed nothing or failed
+	for _, tx := range []entity.PaymentTransaction{settled, waiting, overdue, unknown, broken} {
+		mockRepo.On("MarkReconciled", ctx, tx.ID, mock.Anything).Return(nil).Once()
+	}
+	mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+		return tx.PaymentOrderID == "ORDER-SETTLED" && tx.Status == "SUCCESS" &&
+			tx.TransactionID != nil && *tx.TransactionID == "fake-trx-ORDER-SETTLED"
+	})).Return(nil).Once()
+	mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(settled.OrderID)).Return(nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+		return (tx.PaymentOrderID == "ORDER-OVERDUE" || tx.PaymentOrderID == "ORDER-UNKNOWN") && tx.Status == "EXPIRED"
+	})).Return(nil).Twice()
+	mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+		return l.Source == "reconciler" && *l.PreviousStatus == "PENDING"
+	})).Return(nil).Times(3)
+
+	// "ORDER-BROKEN" is unknown to the gateway but not yet expired
+	res, err := svc.ReconcilePendingTransactions(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &ReconcileResult{Checked: 5, Updated: 1, Expired: 2, Skipped: 1, Failed: 1}, res)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReconcileSkipsTransactionChangedByWebhook(t *testing.T) {
+	ctx := context.Background()
+	tx := pendingTx("ORDER-RACE", time.Now().Add(time.Hour))
+
+	gw := gateway.NewFake()
+	gw.SetStatus("ORDER-RACE", "SUCCESS")
+
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	current := tx
+	current.Status = "SUCCESS"
+	mockRepo.On("ListStalePendingTransactions", ctx, mock.Anything, defaultReconcileBatchSize).
+		Return([]entity.PaymentTransaction{tx}, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-RACE").Return(&current, nil).Once()
+	mockRepo.On("MarkReconciled", ctx, tx.ID, mock.Anything).Return(nil).Once()
+
+	res, err := svc.ReconcilePendingTransactions(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Skipped)
+	mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReconcileWaitsForWebhookLock(t *testing.T) {
+	ctx := context.Background()
+	tx := pendingTx("ORDER-LOCKED", time.Now().Add(-time.Hour))
+
+	gw := gateway.NewFake()
+	gw.SetStatus("ORDER-LOCKED", "PENDING")
+
+	locker := lock.NewMemoryLocker()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, locker, gw)
+
+	// A webhook for the same charge is being applied
+	unlock, ok, err := locker.TryLock(ctx, transactionLockKey("ORDER-LOCKED"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	defer unlock()
+
+	mockRepo.On("ListStalePendingTransactions", ctx, mock.Anything, defaultReconcileBatchSize).
+		Return([]entity.PaymentTransaction{tx}, nil).Once()
+	mockRepo.On("MarkReconciled", ctx, tx.ID, mock.Anything).Return(nil).Once()
+
+	res, err := svc.ReconcilePendingTransactions(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Failed)
+	mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
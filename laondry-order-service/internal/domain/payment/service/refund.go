@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// refundableStatuses are payment statuses that may still be (partially) refunded
+var refundableStatuses = map[string]bool{
+	"SUCCESS":            true,
+	"PARTIALLY_REFUNDED": true,
+}
+
+type RefundRequest struct {
+	PaymentTransactionID uuid.UUID  `json:"-"`
+	Amount               float64    `json:"amount" validate:"required,gt=0"`
+	Reason               string     `json:"reason" validate:"required,max=255"`
+	RequestedBy          *uuid.UUID `json:"-"`
+	// ToWallet credits the refund to the customer's wallet instead of the
+	// gateway. Wallet payments are always refunded to the wallet.
+	ToWallet bool `json:"to_wallet"`
+}
+
+type RefundResponse struct {
+	Refund         *entity.PaymentRefund `json:"refund"`
+	PaymentStatus  string                `json:"payment_status"`
+	RefundedAmount float64               `json:"refunded_amount"`
+	Refundable     float64               `json:"refundable_amount"`
+}
+
+// RefundTransaction refunds (part of) a settled payment through the payment
+// gateway, or to the customer's wallet. The refund is recorded as PENDING
+// before calling Midtrans so the refunded total can never exceed the gross
+// amount, even with concurrent requests. It is marked FAILED only when the
+// gateway definitely rejected it; otherwise it stays PENDING until a request
+// for the same amount retries it under the same refund key.
+func (s *paymentService) RefundTransaction(ctx context.Context, req RefundRequest) (*RefundResponse, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.RefundTransaction")
+		defer seg.End()
+		txn.AddAttribute("payment_transaction_id", req.PaymentTransactionID.String())
+	}
+
+	if req.Amount <= 0 {
+		return nil, appErrors.BadRequest("Refund amount must be greater than zero", nil)
+	}
+	if req.Amount != math.Trunc(req.Amount) {
+		return nil, appErrors.BadRequest("Refund amount must be a whole number", nil)
+	}
+
+	paymentTx, err := s.repo.FindTransactionByID(ctx, req.PaymentTransactionID)
+	if err != nil {
+		return nil, appErrors.NotFound("Payment transaction not found", err)
+	}
+
+	var result *RefundResponse
+	err = s.withLock(ctx, transactionLockKey(paymentTx.PaymentOrderID), 30*time.Second, func() error {
+		// Re-read under the lock; a webhook may have changed the status
+		current, err := s.repo.FindTransactionByPaymentOrderID(ctx, paymentTx.PaymentOrderID)
+		if err != nil {
+			return appErrors.NotFound("Payment transaction not found", err)
+		}
+		if !refundableStatuses[current.Status] {
+			return appErrors.UnprocessableEntity(fmt.Sprintf("Payment with status %s cannot be refunded", current.Status), nil)
+		}
+
+		refunded, err := s.repo.SumRefundedAmount(ctx, current.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to calculate refunded amount", err)
+		}
+		existing, err := s.repo.ListRefunds(ctx, current.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to list refunds", err)
+		}
+
+		// A refund left PENDING by an inconclusive gateway error may have been
+		// issued. It is retried under its own refund key, which the gateway
+		// deduplicates, rather than sent again as a new refund.
+		var refund *entity.PaymentRefund
+		for i := range existing {
+			if existing[i].Status == "PENDING" {
+				refund = &existing[i]
+				break
+			}
+		}
+		if refund != nil {
+			if refund.Amount != req.Amount {
+				return appErrors.Conflict(fmt.Sprintf("Refund %s of %.0f is still pending; retry it with the same amount",
+					refund.RefundKey, refund.Amount), nil)
+			}
+			refunded -= refund.Amount
+		}
+		if refunded+req.Amount > current.GrossAmount {
+			return appErrors.UnprocessableEntity(
+				fmt.Sprintf("Refund exceeds refundable amount (%.0f remaining)", current.GrossAmount-refunded), nil)
+		}
+
+		toWallet := req.ToWallet || current.IsWallet()
+		if refund != nil {
+			toWallet = refund.Source == "wallet"
+		}
+		var customerID uuid.UUID
+		if toWallet {
+			order, err := s.repo.FindOrderByID(ctx, current.OrderID)
+			if err != nil {
+				return appErrors.NotFound("Order not found", err)
+			}
+			customerID = order.CustomerID
+		}
+
+		if refund == nil {
+			refund = &entity.PaymentRefund{
+				PaymentTransactionID: current.ID,
+				RefundKey:            fmt.Sprintf("%s-REFUND-%d", current.PaymentOrderID, len(existing)+1),
+				Amount:               req.Amount,
+				Reason:               strPtrNonEmpty(req.Reason),
+				Status:               "PENDING",
+				Source:               "api",
+				RequestedBy:          req.RequestedBy,
+			}
+			if toWallet {
+				refund.Source = "wallet"
+			}
+			if err := s.repo.CreateRefund(ctx, refund); err != nil {
+				return appErrors.InternalServerError("Failed to save refund", err)
+			}
+		} else {
+			slog.InfoContext(ctx, "[Payment] Retrying pending refund", "payment_order_id", current.PaymentOrderID, "refund_key", refund.RefundKey)
+		}
+
+		var refundResp *gateway.RefundResult
+		switch {
+		case toWallet:
+			// Credited below, in the same transaction as the refund
+			refundResp = &gateway.RefundResult{}
+		case current.IsManual():
+			// Counter payments are handed back at the counter
+			refundResp = &gateway.RefundResult{}
+		default:
+			slog.InfoContext(ctx, "[Payment] Requesting gateway refund", "gateway", s.gw.Name(),
+				"payment_order_id", current.PaymentOrderID, "refund_key", refund.RefundKey, "amount", req.Amount)
+			refundResp, err = s.gw.Refund(ctx, current.PaymentOrderID, gateway.RefundRequest{
+				RefundKey: refund.RefundKey,
+				Amount:    int64(req.Amount),
+				Reason:    req.Reason,
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "[Payment] Gateway refund failed", "gateway", s.gw.Name(),
+				"payment_order_id", current.PaymentOrderID, "refund_key", refund.RefundKey, "error", err)
+			refund.FailureReason = strPtr(err.Error())
+			if !refundRejected(err) {
+				// Stays PENDING and counted against the refundable amount until
+				// a retry or the refund webhook settles it
+				if uerr := s.repo.UpdateRefund(ctx, refund); uerr != nil {
+					slog.WarnContext(ctx, "[Payment] Failed to update pending refund", "refund_key", refund.RefundKey, "error", uerr)
+				}
+				return appErrors.NewAppError("Refund outcome unknown; retry the same refund to confirm it", http.StatusBadGateway, err)
+			}
+			refund.Status = "FAILED"
+			if uerr := s.repo.UpdateRefund(ctx, refund); uerr != nil {
+				slog.WarnContext(ctx, "[Payment] Failed to mark refund as failed", "refund_key", refund.RefundKey, "error", uerr)
+			}
+			return appErrors.UnprocessableEntity("Payment gateway rejected the refund", err)
+		}
+
+		refunded += req.Amount
+		newStatus := "PARTIALLY_REFUNDED"
+		if refunded >= current.GrossAmount {
+			newStatus = "REFUNDED"
+		}
+
+		err = s.withTx(ctx, func(r repository.PaymentRepository) error {
+			refund.Status = "SUCCESS"
+			refund.FailureReason = nil
+			refund.RawResponse = mapToJSONB(refundResp.Raw)
+			refund.GatewayRefundID = strPtrNonEmpty(refundResp.GatewayRefundID)
+			if err := r.UpdateRefund(ctx, refund); err != nil {
+				return appErrors.InternalServerError("Failed to update refund", err)
+			}
+			if toWallet {
+				if err := creditRefundToWallet(ctx, r, customerID, current, refund); err != nil {
+					return err
+				}
+			}
+			return s.applyGatewayStatus(ctx, r, current, newStatus, nil, "refund",
+				fmt.Sprintf("Refund %s: %.0f (%s)", refund.RefundKey, req.Amount, req.Reason))
+		})
+		if err != nil {
+			return err
+		}
+
+		s.publish(ctx, events.NewRefundIssued(*current, *refund))
+		result = &RefundResponse{
+			Refund:         refund,
+			PaymentStatus:  current.Status,
+			RefundedAmount: refunded,
+			Refundable:     current.GrossAmount - refunded,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Refund completed", "order_id", paymentTx.OrderID, "payment_order_id", paymentTx.PaymentOrderID,
+		"refunded", result.RefundedAmount, "status", result.PaymentStatus)
+	return result, nil
+}
+
+// refundRejected reports whether a gateway refund error means the refund was
+// definitely not issued. Timeouts and server errors may hide a refund that
+// went through.
+func refundRejected(err error) bool {
+	return errors.Is(err, gateway.ErrRejected) || errors.Is(err, gateway.ErrNotFound) || errors.Is(err, gateway.ErrNotConfigured)
+}
+
+// ListRefunds lists the refunds recorded for a payment transaction
+func (s *paymentService) ListRefunds(ctx context.Context, paymentTransactionID uuid.UUID) ([]entity.PaymentRefund, error) {
+	return s.repo.ListRefunds(ctx, paymentTransactionID)
+}
+
+// recordWebhookRefunds upserts the refunds listed in a refund/chargeback
+// notification and returns those that newly succeeded. Refunds we initiated are matched by refund key; refunds made
+// from the gateway dashboard are inserted. Entries that would push the total
+// over the gross amount are ignored.
+func (s *paymentService) recordWebhookRefunds(ctx context.Context, r repository.PaymentRepository, paymentTx *entity.PaymentTransaction, entries []gateway.RefundEntry) ([]entity.PaymentRefund, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	refunded, err := r.SumRefundedAmount(ctx, paymentTx.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var issued []entity.PaymentRefund
+
+	for _, e := range entries {
+		refundKey := e.Key
+		if refundKey == "" {
+			refundKey = fmt.Sprintf("%s-%s-%s", paymentTx.PaymentOrderID, strings.ToUpper(s.gw.Name()), e.GatewayRefundID)
+		}
+
+		existing, err := r.FindRefundByKey(ctx, refundKey)
+		if err == nil && existing != nil {
+			if existing.Status == "SUCCESS" {
+				continue
+			}
+			if existing.Status == "FAILED" && refunded+e.Amount > paymentTx.GrossAmount {
+				slog.WarnContext(ctx, "[Payment] Ignoring webhook refund exceeding gross amount", "refund_key", refundKey)
+				continue
+			}
+			if existing.Status == "FAILED" {
+				refunded += e.Amount
+			}
+			existing.Status = "SUCCESS"
+			existing.FailureReason = nil
+			existing.GatewayRefundID = strPtrNonEmpty(e.GatewayRefundID)
+			existing.RawResponse = entity.JSONB(e.Raw)
+			if err := r.UpdateRefund(ctx, existing); err != nil {
+				return nil, err
+			}
+			issued = append(issued, *existing)
+			continue
+		}
+
+		if refunded+e.Amount > paymentTx.GrossAmount {
+			slog.WarnContext(ctx, "[Payment] Ignoring webhook refund exceeding gross amount", "refund_key", refundKey)
+			continue
+		}
+		refund := &entity.PaymentRefund{
+			PaymentTransactionID: paymentTx.ID,
+			RefundKey:            refundKey,
+			Amount:               e.Amount,
+			Reason:               strPtrNonEmpty(e.Reason),
+			Status:               "SUCCESS",
+			Source:               "webhook",
+			GatewayRefundID:      strPtrNonEmpty(e.GatewayRefundID),
+			RawResponse:          entity.JSONB(e.Raw),
+		}
+		if err := r.CreateRefund(ctx, refund); err != nil {
+			return nil, err
+		}
+		refunded += e.Amount
+		issued = append(issued, *refund)
+	}
+	return issued, nil
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/lock"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func settledTx() *entity.PaymentTransaction {
+	tx := createTestPaymentTransaction()
+	tx.Status = "SUCCESS"
+	return tx
+}
+
+func TestRefundTransaction(t *testing.T) {
+	ctx := context.Background()
+	staff := uuid.New()
+
+	t.Run("Partial refund is sent to the gateway and recorded", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := settledTx()
+		gw.SetStatus(paymentTx.PaymentOrderID, "SUCCESS")
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(50000), nil).Once()
+		mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{{}}, nil).Once()
+		mockRepo.On("CreateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+			return r.Status == "PENDING" && r.RefundKey == "ORDER-TEST-PAY-1-REFUND-2" && *r.RequestedBy == staff
+		})).Return(nil).Once()
+		mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+			return r.Status == "SUCCESS" && r.GatewayRefundID != nil && *r.GatewayRefundID == "fake-refund-1"
+		})).Return(nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.Status == "PARTIALLY_REFUNDED"
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "refund" && l.NewStatus == "PARTIALLY_REFUNDED"
+		})).Return(nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{
+			PaymentTransactionID: paymentTx.ID,
+			Amount:               25000,
+			Reason:               "Item damaged",
+			RequestedBy:          &staff,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "PARTIALLY_REFUNDED", res.PaymentStatus)
+		assert.Equal(t, float64(75000), res.RefundedAmount)
+		assert.Equal(t, float64(75000), res.Refundable)
+		if refunds := gw.Refunds(paymentTx.PaymentOrderID); assert.Len(t, refunds, 1) {
+			assert.Equal(t, int64(25000), refunds[0].Amount)
+			assert.Equal(t, "ORDER-TEST-PAY-1-REFUND-2", refunds[0].RefundKey)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Refund exceeding the settled amount is rejected", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := settledTx()
+		gw.SetStatus(paymentTx.PaymentOrderID, "SUCCESS")
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(140000), nil).Once()
+		mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{{Status: "SUCCESS", Amount: 140000}}, nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 20000, Reason: "x"})
+
+		assert.Nil(t, res)
+		var appErr *appErrors.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusUnprocessableEntity, appErr.StatusCode)
+		assert.Empty(t, gw.Calls())
+		mockRepo.AssertNotCalled(t, "CreateRefund", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unsettled payment cannot be refunded", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewMidtransService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker())
+		paymentTx := createTestPaymentTransaction()
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 1000, Reason: "x"})
+
+		assert.Nil(t, res)
+		assert.Error(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Gateway rejection marks the refund failed", func(t *testing.T) {
+		gw := gateway.NewFake()
+		gw.Err = fmt.Errorf("%w: merchant cannot modify the status of the transaction", gateway.ErrRejected)
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := settledTx()
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{}, nil).Once()
+		mockRepo.On("CreateRefund", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+			return r.Status == "FAILED" && r.FailureReason != nil
+		})).Return(nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 150000, Reason: "x"})
+
+		assert.Nil(t, res)
+		assert.Error(t, err)
+		assert.Equal(t, "SUCCESS", paymentTx.Status)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Inconclusive gateway error keeps the refund pending", func(t *testing.T) {
+		gw := gateway.NewFake()
+		gw.Err = errors.New("context deadline exceeded")
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := settledTx()
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{}, nil).Once()
+		mockRepo.On("CreateRefund", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+			return r.Status == "PENDING" && r.FailureReason != nil
+		})).Return(nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 50000, Reason: "x"})
+
+		assert.Nil(t, res)
+		var appErr *appErrors.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusBadGateway, appErr.StatusCode)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Pending refund is retried under its refund key", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		paymentTx := settledTx()
+		gw.SetStatus(paymentTx.PaymentOrderID, "SUCCESS")
+		pending := entity.PaymentRefund{PaymentTransactionID: paymentTx.ID, RefundKey: "ORDER-TEST-PAY-1-REFUND-1",
+			Amount: 50000, Status: "PENDING", Source: "api", FailureReason: strPtr("timeout")}
+
+		mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Twice()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Twice()
+		// The pending refund is already counted as refunded
+		mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(50000), nil).Twice()
+		mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{pending}, nil).Twice()
+
+		// A different amount would be a second refund
+		_, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 20000, Reason: "x"})
+		var appErr *appErrors.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+		assert.Empty(t, gw.Refunds(paymentTx.PaymentOrderID))
+
+		mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+			return r.RefundKey == "ORDER-TEST-PAY-1-REFUND-1" && r.Status == "SUCCESS" && r.FailureReason == nil
+		})).Return(nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.Status == "PARTIALLY_REFUNDED"
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+		res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 50000, Reason: "x"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(50000), res.RefundedAmount)
+		if refunds := gw.Refunds(paymentTx.PaymentOrderID); assert.Len(t, refunds, 1) {
+			assert.Equal(t, "ORDER-TEST-PAY-1-REFUND-1", refunds[0].RefundKey)
+		}
+		mockRepo.AssertNotCalled(t, "CreateRefund", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestRefundWebhookRecordsRefunds(t *testing.T) {
+	ctx := context.Background()
+	cfg := createTestConfig()
+	mockRepo := repository.NewMockPaymentRepository()
+	publisher := &recordingPublisher{}
+	svc := NewPaymentService(cfg, mockRepo, nil, lock.NewMemoryLocker(), gateway.NewMidtrans(cfg.Midtrans, NewMerchantResolver(cfg, mockRepo)),
+		WithEventPublisher(publisher))
+	paymentTx := settledTx()
+
+	payload := map[string]interface{}(signedPayload(cfg.Midtrans.ServerKey, paymentTx.PaymentOrderID, "partial_refund"))
+	payload["refunds"] = []interface{}{
+		// Initiated through the API, already recorded as PENDING
+		map[string]interface{}{"refund_chargeback_id": float64(1), "refund_amount": "25000.00", "refund_key": "ORDER-TEST-PAY-1-REFUND-1"},
+		// Made from the Midtrans dashboard
+		map[string]interface{}{"refund_chargeback_id": float64(2), "refund_amount": "10000.00", "reason": "goodwill"},
+		// Would exceed the gross amount
+		map[string]interface{}{"refund_chargeback_id": float64(3), "refund_amount": "150000.00"},
+	}
+
+	pending := &entity.PaymentRefund{PaymentTransactionID: paymentTx.ID, RefundKey: "ORDER-TEST-PAY-1-REFUND-1", Amount: 25000, Status: "PENDING"}
+
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(25000), nil).Once()
+	mockRepo.On("FindRefundByKey", ctx, "ORDER-TEST-PAY-1-REFUND-1").Return(pending, nil).Once()
+	mockRepo.On("FindRefundByKey", ctx, "ORDER-TEST-PAY-1-MIDTRANS-2").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("FindRefundByKey", ctx, "ORDER-TEST-PAY-1-MIDTRANS-3").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r == pending && r.Status == "SUCCESS" && *r.GatewayRefundID == "1"
+	})).Return(nil).Once()
+	mockRepo.On("CreateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Source == "webhook" && r.Amount == 10000 && *r.Reason == "goodwill"
+	})).Return(nil).Once()
+	mockRepo.On("CreateWebhookLog", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	res, err := svc.ProcessWebhookNotification(ctx, payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PARTIALLY_REFUNDED", res.Status)
+	mockRepo.AssertExpectations(t)
+
+	// Both newly settled refunds are published, the ignored one is not
+	if assert.Len(t, publisher.events, 2) {
+		first := publisher.events[0].(events.RefundIssued)
+		second := publisher.events[1].(events.RefundIssued)
+		assert.Equal(t, "ORDER-TEST-PAY-1-REFUND-1", first.RefundKey)
+		assert.Equal(t, "ORDER-TEST-PAY-1-MIDTRANS-2", second.RefundKey)
+		assert.Equal(t, float64(10000), second.Amount)
+		assert.Equal(t, "PARTIALLY_REFUNDED", second.PaymentStatus)
+	}
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// settledStatuses are payment statuses whose money reached the gateway
+var settledStatuses = map[string]bool{
+	"SUCCESS":            true,
+	"PARTIALLY_REFUNDED": true,
+	"REFUNDED":           true,
+}
+
+type SettlementImportRequest struct {
+	// OutletID is the outlet whose merchant account the report is from; nil
+	// for the default account
+	OutletID   *uuid.UUID
+	FileName   string
+	Report     io.Reader // Midtrans settlement report CSV
+	ImportedBy *uuid.UUID
+}
+
+type SettlementImportResult struct {
+	Report *entity.SettlementReport `json:"report"`
+	Issues []entity.SettlementEntry `json:"issues"` // every entry that is not MATCHED
+}
+
+// SettlementReconciliation is the reconciliation of a day and/or outlet
+type SettlementReconciliation struct {
+	Summary []repository.SettlementTotal `json:"summary"`
+	Entries []entity.SettlementEntry     `json:"entries"`
+	Total   int64                        `json:"total"`
+}
+
+// ImportSettlementReport compares a gateway settlement report with our
+// payment transactions. Rows are matched by transaction ID, then payment
+// order ID; rows of wallet top-ups are matched against the top-ups. Settled
+// gateway payments of the report's days and merchant account that it does
+// not list are flagged MISSING. Importing a report replaces the entries of
+// the days it covers for its merchant account, so a corrected report can
+// simply be imported again.
+func (s *paymentService) ImportSettlementReport(ctx context.Context, req SettlementImportRequest) (*SettlementImportResult, error) {
+	rows, err := gateway.ParseMidtransSettlement(req.Report)
+	if err != nil {
+		return nil, appErrors.BadRequest(err.Error(), err)
+	}
+	if len(rows) == 0 {
+		return nil, appErrors.BadRequest("Settlement report has no transactions", nil)
+	}
+	if req.OutletID != nil {
+		if _, err := s.repo.FindMerchantCredential(ctx, *req.OutletID); err != nil {
+			return nil, appErrors.BadRequest("Outlet uses the default merchant account; import its reports without an outlet", err)
+		}
+	}
+
+	var result *SettlementImportResult
+	err = s.withLock(ctx, "payment:settlement:import", 5*time.Minute, func() error {
+		var err error
+		result, err = s.importSettlementReport(ctx, req, rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *paymentService) importSettlementReport(ctx context.Context, req SettlementImportRequest, rows []gateway.SettlementRow) (*SettlementImportResult, error) {
+	report := &entity.SettlementReport{
+		ID:         uuid.New(),
+		OutletID:   req.OutletID,
+		FileName:   req.FileName,
+		RowCount:   len(rows),
+		ImportedBy: req.ImportedBy,
+	}
+
+	var transactionIDs, paymentOrderIDs []string
+	for _, row := range rows {
+		if row.TransactionID != "" {
+			transactionIDs = append(transactionIDs, row.TransactionID)
+		}
+		if row.PaymentOrderID != "" {
+			paymentOrderIDs = append(paymentOrderIDs, row.PaymentOrderID)
+		}
+		day := gateway.SettlementDay(row.SettlementTime)
+		if report.PeriodStart.IsZero() || day.Before(report.PeriodStart) {
+			report.PeriodStart = day
+		}
+		if day.After(report.PeriodEnd) {
+			report.PeriodEnd = day
+		}
+	}
+
+	txs, err := s.repo.FindTransactionsByReferences(ctx, transactionIDs, paymentOrderIDs)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load payment transactions", err)
+	}
+	byTransactionID := make(map[string]*entity.PaymentTransaction, len(txs))
+	byPaymentOrderID := make(map[string]*entity.PaymentTransaction, len(txs))
+	for i := range txs {
+		if txs[i].TransactionID != nil {
+			byTransactionID[*txs[i].TransactionID] = &txs[i]
+		}
+		byPaymentOrderID[txs[i].PaymentOrderID] = &txs[i]
+	}
+	topups, err := s.repo.FindTopupsByPaymentOrderIDs(ctx, paymentOrderIDs)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load wallet top-ups", err)
+	}
+	topupByPaymentOrderID := make(map[string]*entity.WalletTopup, len(topups))
+	for i := range topups {
+		topupByPaymentOrderID[topups[i].PaymentOrderID] = &topups[i]
+	}
+
+	entries := make([]entity.SettlementEntry, 0, len(rows))
+	reported := map[string]bool{} // payment order IDs already listed
+	for _, row := range rows {
+		entry := settlementRowEntry(report.ID, row)
+		tx := byTransactionID[row.TransactionID]
+		if tx == nil {
+			tx = byPaymentOrderID[row.PaymentOrderID]
+		}
+		switch {
+		case tx != nil:
+			entry.PaymentOrderID = tx.PaymentOrderID
+			entry.PaymentTransactionID = &tx.ID
+			if tx.Order != nil {
+				entry.OutletID = &tx.Order.OutletID
+			}
+			entry.ExpectedAmount = floatPtr(tx.GrossAmount)
+			entry.ExpectedFee = floatPtr(tx.FeeAmount)
+			compareSettlement(&entry, row.GrossAmount, tx.GrossAmount, settledStatuses[tx.Status], tx.Status)
+		case topupByPaymentOrderID[row.PaymentOrderID] != nil:
+			topup := topupByPaymentOrderID[row.PaymentOrderID]
+			entry.ExpectedAmount = floatPtr(topup.Amount)
+			compareSettlement(&entry, row.GrossAmount, topup.Amount, topup.Status == "SUCCESS", topup.Status)
+			if entry.Note == nil {
+				entry.Note = strPtr("Wallet top-up")
+			}
+		default:
+			entry.Status = entity.SettlementExtra
+			entry.Note = strPtr("No payment with this order or transaction ID")
+		}
+		if entry.PaymentOrderID != "" {
+			if reported[entry.PaymentOrderID] {
+				entry.Status = entity.SettlementExtra
+				entry.Note = strPtr("Listed more than once in the report")
+			}
+			reported[entry.PaymentOrderID] = true
+		}
+		entries = append(entries, entry)
+	}
+
+	from, _ := gateway.SettlementDayBounds(report.PeriodStart)
+	_, to := gateway.SettlementDayBounds(report.PeriodEnd)
+	settled, err := s.repo.ListSettledGatewayTransactions(ctx, from, to, req.OutletID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list settled payments", err)
+	}
+	for i := range settled {
+		tx := &settled[i]
+		if reported[tx.PaymentOrderID] || tx.SettlementTime == nil {
+			continue
+		}
+		entry := entity.SettlementEntry{
+			ReportID:             report.ID,
+			SettlementDate:       gateway.SettlementDay(*tx.SettlementTime),
+			PaymentTransactionID: &tx.ID,
+			PaymentOrderID:       tx.PaymentOrderID,
+			TransactionID:        tx.TransactionID,
+			PaymentType:          tx.PaymentType,
+			Status:               entity.SettlementMissing,
+			ExpectedAmount:       floatPtr(tx.GrossAmount),
+			ExpectedFee:          floatPtr(tx.FeeAmount),
+			Note:                 strPtr("Settled here but not in the settlement report"),
+		}
+		if tx.Order != nil {
+			entry.OutletID = &tx.Order.OutletID
+		}
+		entries = append(entries, entry)
+	}
+
+	issues := []entity.SettlementEntry{}
+	for _, entry := range entries {
+		switch entry.Status {
+		case entity.SettlementMatched:
+			report.MatchedCount++
+		case entity.SettlementMissing:
+			report.MissingCount++
+		case entity.SettlementExtra:
+			report.ExtraCount++
+		default:
+			report.MismatchCount++
+		}
+		if entry.Status != entity.SettlementMatched {
+			issues = append(issues, entry)
+		}
+	}
+
+	err = s.withTx(ctx, func(r repository.PaymentRepository) error {
+		if err := r.CreateSettlementReport(ctx, report); err != nil {
+			return appErrors.InternalServerError("Failed to save settlement report", err)
+		}
+		if err := r.ReplaceSettlementEntries(ctx, report.PeriodStart, report.PeriodEnd, req.OutletID, entries); err != nil {
+			return appErrors.InternalServerError("Failed to save settlement entries", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Settlement report imported", "file", report.FileName, "outlet_id", report.OutletID,
+		"period_start", report.PeriodStart.Format("2006-01-02"), "period_end", report.PeriodEnd.Format("2006-01-02"),
+		"rows", report.RowCount, "matched", report.MatchedCount, "missing", report.MissingCount,
+		"extra", report.ExtraCount, "mismatched", report.MismatchCount)
+	return &SettlementImportResult{Report: report, Issues: issues}, nil
+}
+
+// GetSettlementReconciliation returns the reconciliation entries and their
+// per-status totals for a settlement day and/or outlet. EXTRA entries have no
+// outlet, so they only show without an outlet filter.
+func (s *paymentService) GetSettlementReconciliation(ctx context.Context, filters repository.SettlementFilters) (*SettlementReconciliation, error) {
+	if filters.Date != nil {
+		if _, err := time.Parse("2006-01-02", *filters.Date); err != nil {
+			return nil, appErrors.BadRequest("date must be YYYY-MM-DD", err)
+		}
+	}
+	summary, err := s.repo.SummarizeSettlementEntries(ctx, filters)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to summarize settlement entries", err)
+	}
+	entries, total, err := s.repo.ListSettlementEntries(ctx, filters)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list settlement entries", err)
+	}
+	return &SettlementReconciliation{Summary: summary, Entries: entries, Total: total}, nil
+}
+
+// settlementRowEntry starts the entry of a report row
+func settlementRowEntry(reportID uuid.UUID, row gateway.SettlementRow) entity.SettlementEntry {
+	line := row.Line
+	raw := make(entity.JSONB, len(row.Raw))
+	for k, v := range row.Raw {
+		raw[k] = v
+	}
+	return entity.SettlementEntry{
+		ReportID:       reportID,
+		SettlementDate: gateway.SettlementDay(row.SettlementTime),
+		PaymentOrderID: row.PaymentOrderID,
+		TransactionID:  strPtrNonEmpty(row.TransactionID),
+		PaymentType:    strPtrNonEmpty(row.PaymentType),
+		ReportedAmount: floatPtr(row.GrossAmount),
+		ReportedFee:    floatPtr(row.FeeAmount),
+		ReportedNet:    floatPtr(row.NetAmount),
+		LineNo:         &line,
+		RawRow:         raw,
+	}
+}
+
+// compareSettlement sets the status of an entry matched to a payment
+func compareSettlement(entry *entity.SettlementEntry, reported, expected float64, settled bool, status string) {
+	switch {
+	case !settled:
+		entry.Status = entity.SettlementStatusMismatch
+		entry.Note = strPtr(fmt.Sprintf("Reported settled, but the payment is %s here", status))
+	case math.Abs(reported-expected) >= 0.01:
+		entry.Status = entity.SettlementAmountMismatch
+		entry.Note = strPtr(fmt.Sprintf("Reported %.2f, expected %.2f", reported, expected))
+	default:
+		entry.Status = entity.SettlementMatched
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func reportedTx(paymentOrderID string, amount float64, status string, outletID uuid.UUID) entity.PaymentTransaction {
+	settled := time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)
+	return entity.PaymentTransaction{
+		ID:             uuid.New(),
+		PaymentOrderID: paymentOrderID,
+		TransactionID:  strPtr("trx-" + paymentOrderID),
+		GrossAmount:    amount,
+		Status:         status,
+		SettlementTime: &settled,
+		Order:          &entity.Order{OutletID: outletID},
+	}
+}
+
+func TestImportSettlementReport(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+	outletID := uuid.New()
+
+	matched := reportedTx("ORD-001", 100000, "SUCCESS", outletID)
+	mismatched := reportedTx("ORD-002", 50000, "SUCCESS", outletID)
+	pending := reportedTx("ORD-003", 30000, "PENDING", outletID)
+	missing := reportedTx("ORD-004", 75000, "SUCCESS", outletID)
+	topup := entity.WalletTopup{PaymentOrderID: "TOPUP-1", Amount: 20000, Status: "SUCCESS"}
+
+	report := "Order ID,Transaction ID,Amount,Fee,Settlement Time\n" +
+		",trx-ORD-001,100000,2000,2025-03-10 10:00:00\n" + // matched by transaction ID alone
+		"ORD-002,,60000,1200,2025-03-10 10:05:00\n" +
+		"ORD-003,,30000,600,2025-03-10 10:10:00\n" +
+		"ORD-999,,10000,200,2025-03-10 10:15:00\n" +
+		"TOPUP-1,,20000,400,2025-03-10 10:20:00\n" +
+		"ORD-001,,100000,2000,2025-03-10 10:25:00\n"
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	from, to := gateway.SettlementDayBounds(day)
+	var saved []entity.SettlementEntry
+	mockRepo.On("FindTransactionsByReferences", ctx, []string{"trx-ORD-001"}, []string{"ORD-002", "ORD-003", "ORD-999", "TOPUP-1", "ORD-001"}).
+		Return([]entity.PaymentTransaction{matched, mismatched, pending}, nil).Once()
+	mockRepo.On("FindTopupsByPaymentOrderIDs", ctx, mock.Anything).Return([]entity.WalletTopup{topup}, nil).Once()
+	mockRepo.On("ListSettledGatewayTransactions", ctx, from, to, (*uuid.UUID)(nil)).
+		Return([]entity.PaymentTransaction{matched, mismatched, missing}, nil).Once()
+	mockRepo.On("CreateSettlementReport", ctx, mock.AnythingOfType("*entity.SettlementReport")).Return(nil).Once()
+	mockRepo.On("ReplaceSettlementEntries", ctx, day, day, (*uuid.UUID)(nil), mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(4).([]entity.SettlementEntry) }).
+		Return(nil).Once()
+
+	res, err := svc.ImportSettlementReport(ctx, SettlementImportRequest{FileName: "march.csv", Report: strings.NewReader(report)})
+
+	require.NoError(t, err)
+	assert.Equal(t, day, res.Report.PeriodStart)
+	assert.Equal(t, 6, res.Report.RowCount)
+	assert.Equal(t, 2, res.Report.MatchedCount)
+	assert.Equal(t, 1, res.Report.MissingCount)
+	assert.Equal(t, 2, res.Report.ExtraCount)
+	assert.Equal(t, 2, res.Report.MismatchCount)
+	assert.Len(t, res.Issues, 5)
+
+	statuses := map[string]string{}
+	for _, e := range saved {
+		if e.Status != entity.SettlementExtra {
+			statuses[e.PaymentOrderID] = e.Status
+		}
+		assert.Equal(t, res.Report.ID, e.ReportID)
+	}
+	assert.Equal(t, map[string]string{
+		"ORD-001": entity.SettlementMatched,
+		"ORD-002": entity.SettlementAmountMismatch,
+		"ORD-003": entity.SettlementStatusMismatch,
+		"TOPUP-1": entity.SettlementMatched,
+		"ORD-004": entity.SettlementMissing,
+	}, statuses)
+
+	// Matched entries carry the outlet so the report can be read per outlet
+	assert.Equal(t, outletID, *saved[0].OutletID)
+	assert.Equal(t, 2000.0, *saved[0].ReportedFee)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportSettlementReport_InvalidReport(t *testing.T) {
+	svc := NewPaymentService(createTestConfig(), repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker(), gateway.NewFake())
+
+	_, err := svc.ImportSettlementReport(context.Background(), SettlementImportRequest{
+		FileName: "bad.csv",
+		Report:   strings.NewReader("Order ID,Settlement Time\nORD-1,2025-03-10\n"),
+	})
+
+	var appErr *appErrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 400, appErr.StatusCode)
+}
+
+func TestImportSettlementReport_OutletWithoutMerchantAccount(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+	outletID := uuid.New()
+
+	mockRepo.On("FindMerchantCredential", ctx, outletID).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, err := svc.ImportSettlementReport(ctx, SettlementImportRequest{
+		OutletID: &outletID,
+		FileName: "march.csv",
+		Report:   strings.NewReader("Order ID,Amount,Settlement Time\nORD-001,100000,2025-03-10 10:00:00\n"),
+	})
+
+	var appErr *appErrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 400, appErr.StatusCode)
+	mockRepo.AssertNotCalled(t, "ReplaceSettlementEntries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/outbox"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// paymentPlan is what the next gateway charge of an order covers
+type paymentPlan struct {
+	order          *entity.Order
+	paymentOrderID string
+	amount         float64
+	paid           float64 // settled before this charge
+	// reuse is an open charge for the same amount whose token can be handed out again
+	reuse *entity.PaymentTransaction
+}
+
+// isPartial reports whether the charge covers only part of the order total
+func (p *paymentPlan) isPartial() bool {
+	return p.paid > 0 || p.amount < p.order.GrandTotal
+}
+
+// remaining is the outstanding balance once the charge settles
+func (p *paymentPlan) remaining() float64 {
+	amount := p.amount
+	if p.reuse != nil {
+		amount = p.reuse.OrderAmount()
+	}
+	if rest := p.order.GrandTotal - p.paid - amount; rest > 0 {
+		return rest
+	}
+	return 0
+}
+
+// item describes a partial charge as a single Snap line item
+func (p *paymentPlan) item() gateway.Item {
+	name := "Partial payment"
+	switch {
+	case p.paid == 0:
+		name = "Down payment"
+	case p.remaining() == 0:
+		name = "Remaining payment"
+	}
+	return gateway.Item{
+		ID:    p.paymentOrderID,
+		Name:  fmt.Sprintf("%s %s", name, p.order.OrderNo),
+		Price: int64(p.amount),
+		Qty:   1,
+	}
+}
+
+// planOrderPayment decides the amount and payment order ID of the next charge
+// of an order; an amount of 0 charges the outstanding balance. An open charge
+// for the same amount that reusable accepts is handed out again, other open
+// charges of the order are closed first (see closeOpenCharges). Callers hold
+// the payment:order lock.
+func (s *paymentService) planOrderPayment(ctx context.Context, orderID uuid.UUID, paymentOrderID string, amount float64, reusable func(*entity.PaymentTransaction) bool) (*paymentPlan, error) {
+	order, err := s.repo.FindOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, appErrors.NotFound("Order not found", err)
+	}
+	if order.Status == "CANCELED" {
+		return nil, appErrors.UnprocessableEntity("Cannot pay a canceled order", nil)
+	}
+
+	txs, err := s.repo.ListTransactionsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list payment transactions", err)
+	}
+
+	used := make(map[string]bool, len(txs))
+	var reuse *entity.PaymentTransaction
+	for i := range txs {
+		tx := &txs[i]
+		used[tx.PaymentOrderID] = true
+		if reuse == nil && isOpenCharge(tx) && reusable(tx) &&
+			(paymentOrderID == "" || paymentOrderID == tx.PaymentOrderID) &&
+			(amount == 0 || amount == tx.OrderAmount()) {
+			reuse = tx
+		}
+	}
+	if err := s.closeOpenCharges(ctx, order, txs, reuse, "split_payment", "Replaced by a new payment"); err != nil {
+		return nil, err
+	}
+	if reuse != nil {
+		paid, err := s.repo.SumPaidAmount(ctx, order.ID)
+		if err != nil {
+			return nil, appErrors.InternalServerError("Failed to calculate paid amount", err)
+		}
+		return &paymentPlan{order: order, paid: paid, reuse: reuse}, nil
+	}
+
+	// Read after closing open charges: one of them may have settled meanwhile
+	paid, err := s.repo.SumPaidAmount(ctx, order.ID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to calculate paid amount", err)
+	}
+	outstanding := order.GrandTotal - paid
+	if outstanding <= 0 {
+		return nil, appErrors.UnprocessableEntity("Order is already fully paid", nil)
+	}
+
+	if amount == 0 {
+		amount = outstanding
+	}
+	if amount > outstanding {
+		return nil, appErrors.UnprocessableEntity(
+			fmt.Sprintf("Amount exceeds outstanding balance (%.0f)", outstanding), nil)
+	}
+	if paid == 0 && amount < outstanding {
+		if minDeposit := order.Outlet.MinDeposit(order.GrandTotal); amount < minDeposit {
+			return nil, appErrors.UnprocessableEntity(
+				fmt.Sprintf("Minimum down payment for this order is %.0f", minDeposit), nil)
+		}
+	}
+
+	if paymentOrderID == "" || used[paymentOrderID] {
+		paymentOrderID = order.OrderNo
+		for n := len(txs) + 1; used[paymentOrderID]; n++ {
+			paymentOrderID = fmt.Sprintf("%s-PAY-%d", order.OrderNo, n)
+		}
+	}
+
+	return &paymentPlan{
+		order:          order,
+		paymentOrderID: paymentOrderID,
+		amount:         amount,
+		paid:           paid,
+	}, nil
+}
+
+// isOpenCharge reports whether tx is a gateway charge that may still settle
+func isOpenCharge(tx *entity.PaymentTransaction) bool {
+	return tx.Status == "PENDING" && !tx.IsManual() && !tx.IsWallet()
+}
+
+// closeOpenCharges cancels the open gateway charges among txs, the payment
+// transactions of order, except keep. Any payment taken while a Snap page or
+// VA is still open could be followed by that charge settling and overpaying
+// the order. txs are updated in place, so a charge that turns out settled
+// shows as SUCCESS; read the paid amount afterwards. Callers hold the
+// payment:order lock.
+func (s *paymentService) closeOpenCharges(ctx context.Context, order *entity.Order, txs []entity.PaymentTransaction, keep *entity.PaymentTransaction, source, reason string) error {
+	for i := range txs {
+		tx := &txs[i]
+		if tx == keep || !isOpenCharge(tx) {
+			continue
+		}
+		slog.InfoContext(ctx, "[Payment] Closing open charge before taking another payment",
+			"order_id", order.ID, "order_no", order.OrderNo, "payment_order_id", tx.PaymentOrderID)
+		if err := s.closePendingPayment(ctx, tx, source, reason); err != nil {
+			return appErrors.UnprocessableEntity(
+				fmt.Sprintf("Previous payment %s is still open, please try again", tx.PaymentOrderID), err)
+		}
+	}
+	return nil
+}
+
+// isSnapCharge reports whether an open charge has a Snap page to hand out again
+func isSnapCharge(tx *entity.PaymentTransaction) bool {
+	return tx.SnapToken != nil && tx.SnapRedirectURL != nil
+}
+
+// queueOrderConfirmation queues the core-api order confirmation of a payment
+// that just settled. It is written with r, in the transaction that stores the
+// SUCCESS status, so it is neither lost nor left behind by a rollback; the
+// core-api call runs from the outbox and does not hold up the request.
+func (s *paymentService) queueOrderConfirmation(ctx context.Context, r repository.PaymentRepository, paymentTx *entity.PaymentTransaction) error {
+	note := fmt.Sprintf("Payment confirmed via %s", s.gw.Name())
+	switch {
+	case paymentTx.IsManual():
+		note = "Payment received at counter"
+	case paymentTx.IsWallet():
+		note = "Paid from wallet"
+	}
+	msg, err := outbox.NewMessage(outbox.TopicPaymentSucceeded, &paymentTx.OrderID, outbox.PaymentSucceeded{
+		OrderID:   paymentTx.OrderID,
+		PaymentID: paymentTx.ID,
+		Note:      note,
+	})
+	if err != nil {
+		return err
+	}
+	return r.CreateOutboxMessage(ctx, msg)
+}
+
+// ConfirmOrderPayment moves the order to PAYMENT_CONFIRMED once its settled
+// payments reach GrandTotal; a down payment alone does not confirm it. It
+// runs from the outbox, which retries on error; a 4xx from core-api would
+// fail the same way on every retry, so it is logged instead.
+func (s *paymentService) ConfirmOrderPayment(ctx context.Context, orderID uuid.UUID, note string) error {
+	order, err := s.repo.FindOrderByID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("load order: %w", err)
+	}
+	if order.Status == "CANCELED" || order.Status == "PAYMENT_CONFIRMED" {
+		return nil
+	}
+	paid, err := s.repo.SumPaidAmount(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("calculate paid amount: %w", err)
+	}
+	if paid < order.GrandTotal {
+		slog.InfoContext(ctx, "[Payment] Order partially paid",
+			"order_id", orderID, "order_no", order.OrderNo, "paid", paid, "grand_total", order.GrandTotal)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "[Payment] Order fully paid, updating order status to PAYMENT_CONFIRMED",
+		"order_id", orderID, "order_no", order.OrderNo)
+	err = s.updateOrderStatus(ctx, orderID, "PAYMENT_CONFIRMED", note)
+	var apiErr *coreAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+		slog.WarnContext(ctx, "[Payment] Core API rejected order confirmation",
+			"order_id", orderID, "order_no", order.OrderNo, "status_code", apiErr.StatusCode, "error", err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+	"laondry-order-service/internal/outbox"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func depositOrder() *entity.Order {
+	order := testOrder(200000)
+	order.Outlet = &entity.Outlet{MinDepositPercent: 30}
+	return order
+}
+
+// confirmationQueued matches the outbox message that confirms a payment of
+// the order in core-api
+func confirmationQueued(orderID uuid.UUID) interface{} {
+	return mock.MatchedBy(func(msg *entity.OutboxMessage) bool {
+		return msg.Topic == outbox.TopicPaymentSucceeded && *msg.AggregateID == orderID
+	})
+}
+
+func chargedItemNames(tx *entity.PaymentTransaction) []string {
+	var names []string
+	req := tx.RequestPayload["data"].(gateway.ChargeRequest)
+	for _, it := range req.Items {
+		names = append(names, it.Name)
+	}
+	return names
+}
+
+func TestCreateSnapToken_SplitPayments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Down payment above the outlet minimum", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := depositOrder()
+
+		var saved *entity.PaymentTransaction
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+			Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+		res, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{
+			OrderID:     order.ID,
+			GrossAmount: 60000,
+			Items:       []Item{{ID: "svc-1", Name: "Bedcover", Price: 200000, Qty: 1}},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ORD-001", res.PaymentOrderID)
+		assert.Equal(t, float64(60000), res.GrossAmount)
+		assert.Equal(t, float64(140000), res.RemainingAmount)
+		assert.Equal(t, float64(60000), saved.GrossAmount)
+		assert.Equal(t, []string{"Down payment ORD-001"}, chargedItemNames(saved))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Remaining balance gets its own payment order ID", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := depositOrder()
+		deposit := entity.PaymentTransaction{ID: uuid.New(), OrderID: order.ID, PaymentOrderID: "ORD-001", GrossAmount: 60000, Status: "SUCCESS"}
+
+		var saved *entity.PaymentTransaction
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{deposit}, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(60000), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+			Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+		res, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{OrderID: order.ID, PaymentOrderID: "ORD-001"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ORD-001-PAY-2", res.PaymentOrderID)
+		assert.Equal(t, float64(140000), saved.GrossAmount)
+		assert.Equal(t, float64(0), res.RemainingAmount)
+		assert.Equal(t, []string{"Remaining payment ORD-001"}, chargedItemNames(saved))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Open charge for another amount is canceled first", func(t *testing.T) {
+		gw := gateway.NewFake()
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := depositOrder()
+		token, url := "old-token", "https://pay.example.test/old"
+		open := entity.PaymentTransaction{ID: uuid.New(), OrderID: order.ID, PaymentOrderID: "ORD-001", GrossAmount: 200000,
+			Status: "PENDING", SnapToken: &token, SnapRedirectURL: &url}
+		gw.SetStatus("ORD-001", "PENDING")
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{open}, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORD-001").Return(&open, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.PaymentOrderID == "ORD-001" && tx.Status == "EXPIRED"
+		})).Return(nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.Anything).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil)
+
+		res, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{OrderID: order.ID, GrossAmount: 100000})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ORD-001-PAY-2", res.PaymentOrderID)
+		assert.Equal(t, []string{"CheckStatus ORD-001", "Cancel ORD-001", "CreateCharge ORD-001-PAY-2"}, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejected amounts", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			paid   float64
+			amount float64
+		}{
+			{"Below the minimum down payment", 0, 50000},
+			{"Above the outstanding balance", 60000, 150000},
+			{"Order already fully paid", 200000, 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				gw := gateway.NewFake()
+				mockRepo := repository.NewMockPaymentRepository()
+				svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+				order := depositOrder()
+
+				mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil)
+				mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil)
+				mockRepo.On("SumPaidAmount", ctx, order.ID).Return(tt.paid, nil)
+
+				_, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{OrderID: order.ID, GrossAmount: tt.amount})
+
+				appErr, ok := err.(*appErrors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, http.StatusUnprocessableEntity, appErr.StatusCode)
+				assert.Empty(t, gw.Calls())
+			})
+		}
+	})
+}
+
+func TestConfirmOrderOnlyWhenFullyPaid(t *testing.T) {
+	ctx := context.Background()
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	cfg := createTestConfig()
+	var confirmed int
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		confirmed++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	cfg.External.CoreAPIURL = srv.URL
+	svc := NewPaymentService(cfg, mockRepo, nil, lock.NewMemoryLocker(), gw).(*paymentService)
+	order := depositOrder()
+
+	mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil)
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(60000), nil).Once()
+	assert.NoError(t, svc.ConfirmOrderPayment(ctx, order.ID, "deposit"))
+	assert.Equal(t, 0, confirmed)
+
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(200000), nil).Once()
+	assert.NoError(t, svc.ConfirmOrderPayment(ctx, order.ID, "balance"))
+	assert.Equal(t, 1, confirmed)
+
+	// A rejected confirmation would be rejected again, so it is not retried
+	status = http.StatusUnprocessableEntity
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(200000), nil).Once()
+	assert.NoError(t, svc.ConfirmOrderPayment(ctx, order.ID, "balance"))
+
+	// Core-api failing or unreachable is returned so the outbox retries it
+	status = http.StatusBadGateway
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(200000), nil).Once()
+	assert.Error(t, svc.ConfirmOrderPayment(ctx, order.ID, "balance"))
+	assert.Equal(t, 3, confirmed)
+
+	srv.Close()
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(200000), nil).Once()
+	assert.Error(t, svc.ConfirmOrderPayment(ctx, order.ID, "balance"))
+}
+
+func TestSettledPaymentsQueueOrderConfirmation(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake()).(*paymentService)
+	paymentTx := settledTx()
+	paymentTx.PaymentType = strPtr(entity.PaymentTransactionTypeWallet)
+
+	mockRepo.On("CreateOutboxMessage", ctx, mock.MatchedBy(func(msg *entity.OutboxMessage) bool {
+		var evt outbox.PaymentSucceeded
+		return msg.Topic == outbox.TopicPaymentSucceeded && *msg.AggregateID == paymentTx.OrderID &&
+			outbox.Decode(*msg, &evt) == nil && evt.PaymentID == paymentTx.ID && evt.Note == "Paid from wallet"
+	})).Return(nil).Once()
+
+	assert.NoError(t, svc.queueOrderConfirmation(ctx, mockRepo, paymentTx))
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/surcharge"
+)
+
+// surchargeItemID is the Midtrans item detail carrying the surcharge
+const surchargeItemID = "SURCHARGE"
+
+// surchargeTable parses PAYMENT_SURCHARGES. Invalid rules are logged and
+// disable surcharges instead of charging customers a wrong amount.
+func surchargeTable(cfg *config.Config) surcharge.Table {
+	if cfg == nil || cfg.Midtrans.Surcharges == "" {
+		return nil
+	}
+	table, err := surcharge.Parse(cfg.Midtrans.Surcharges)
+	if err != nil {
+		slog.Warn("[Payment] Ignoring PAYMENT_SURCHARGES", "error", err)
+		return nil
+	}
+	return table
+}
+
+// methodFee is the surcharge and estimated gateway fee of charging the plan
+// with a known payment method (a Snap payment name such as credit_card)
+type methodFee struct {
+	method    string
+	surcharge float64
+	fee       float64
+}
+
+func (s *paymentService) methodFee(method string, amount float64) methodFee {
+	f := methodFee{method: method}
+	if method == "" {
+		return f
+	}
+	f.surcharge = s.surcharges.Surcharge(method, amount)
+	f.fee = s.surcharges.Fee(method, amount+f.surcharge)
+	return f
+}
+
+// items appends the surcharge as its own line so the customer sees it on
+// the payment page; an order share without items gets one line as well
+func (f methodFee) items(items []gateway.Item, plan *paymentPlan) []gateway.Item {
+	if f.surcharge <= 0 {
+		return items
+	}
+	if len(items) == 0 {
+		items = []gateway.Item{{
+			ID:    plan.paymentOrderID,
+			Name:  fmt.Sprintf("Order %s", plan.order.OrderNo),
+			Price: int64(plan.amount),
+			Qty:   1,
+		}}
+	}
+	return append(items, gateway.Item{
+		ID:    surchargeItemID,
+		Name:  fmt.Sprintf("Payment surcharge (%s)", f.method),
+		Price: int64(f.surcharge),
+		Qty:   1,
+	})
+}
+
+// apply records the surcharge and fee on a new transaction
+func (f methodFee) apply(paymentTx *entity.PaymentTransaction) {
+	paymentTx.GrossAmount += f.surcharge
+	paymentTx.SurchargeAmount = f.surcharge
+	paymentTx.FeeAmount = f.fee
+	if f.method != "" {
+		if paymentTx.Metadata == nil {
+			paymentTx.Metadata = entity.JSONB{}
+		}
+		paymentTx.Metadata["surcharge_method"] = f.method
+	}
+}
+
+// estimateSettlementFee records the gateway fee of a payment whose method
+// was only known once the customer picked it on the Snap page
+func (s *paymentService) estimateSettlementFee(paymentTx *entity.PaymentTransaction, st *gateway.Status) {
+	if paymentTx.FeeAmount > 0 || paymentTx.Status != "SUCCESS" || st.PaymentType == "" {
+		return
+	}
+	paymentTx.FeeAmount = s.surcharges.Fee(gateway.PaymentName(st.PaymentType, st.Bank), paymentTx.GrossAmount)
+}
+
+// surchargeMethod is the payment method an open charge was priced for
+func surchargeMethod(tx *entity.PaymentTransaction) string {
+	method, _ := tx.Metadata["surcharge_method"].(string)
+	return method
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func surchargeTestConfig() *config.Config {
+	cfg := createTestConfig()
+	cfg.Midtrans.Surcharges = "credit_card:2.9%+2000:customer,gopay:2%"
+	return cfg
+}
+
+func TestCreateSnapToken_PaymentSurcharge(t *testing.T) {
+	ctx := context.Background()
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(surchargeTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+	order := testOrder(200000)
+
+	var saved *entity.PaymentTransaction
+	mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+	mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+	mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+	mockRepo.On("CreateTransaction", ctx, mock.AnythingOfType("*entity.PaymentTransaction")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).(*entity.PaymentTransaction) }).
+		Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	res, err := svc.CreateSnapToken(ctx, CreateSnapTokenRequest{
+		OrderID:       order.ID,
+		Items:         []Item{{ID: "svc-1", Name: "Bedcover", Price: 200000, Qty: 1}},
+		PaymentMethod: "credit_card",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, float64(208033), res.GrossAmount)
+	assert.Equal(t, float64(8033), res.SurchargeAmount)
+	assert.Zero(t, res.RemainingAmount)
+
+	// The surcharge is its own item detail and the page offers only that method
+	req := saved.RequestPayload["data"].(gateway.ChargeRequest)
+	assert.Equal(t, int64(208033), req.GrossAmount)
+	assert.Equal(t, []string{"credit_card"}, req.EnabledPayments)
+	assert.Equal(t, []string{"Bedcover", "Payment surcharge (credit_card)"}, chargedItemNames(saved))
+
+	// Recorded for reconciliation: the order share stays 200000
+	assert.Equal(t, float64(208033), saved.GrossAmount)
+	assert.Equal(t, float64(8033), saved.SurchargeAmount)
+	assert.Equal(t, float64(8033), saved.FeeAmount)
+	assert.Equal(t, float64(200000), saved.OrderAmount())
+	assert.Equal(t, "credit_card", saved.Metadata["surcharge_method"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateSnapToken_PaymentMethodNotEnabled(t *testing.T) {
+	svc := NewPaymentService(surchargeTestConfig(), repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker(), gateway.NewFake())
+
+	_, err := svc.CreateSnapToken(context.Background(), CreateSnapTokenRequest{
+		OrderID:         testOrder(100000).ID,
+		EnabledPayments: []string{"gopay"},
+		PaymentMethod:   "credit_card",
+	})
+
+	assert.Error(t, err)
+}
+
+func TestEstimateSettlementFee(t *testing.T) {
+	svc := NewPaymentService(surchargeTestConfig(), nil, nil, nil, gateway.NewFake()).(*paymentService)
+
+	// Method picked on the Snap page: the merchant absorbs the fee
+	tx := &entity.PaymentTransaction{GrossAmount: 100000, Status: "SUCCESS"}
+	svc.estimateSettlementFee(tx, &gateway.Status{PaymentType: "gopay"})
+	assert.Equal(t, float64(2000), tx.FeeAmount)
+
+	// Not settled, or fee already priced at charge time
+	pending := &entity.PaymentTransaction{GrossAmount: 100000, Status: "PENDING"}
+	svc.estimateSettlementFee(pending, &gateway.Status{PaymentType: "gopay"})
+	assert.Zero(t, pending.FeeAmount)
+
+	priced := &entity.PaymentTransaction{GrossAmount: 105047, FeeAmount: 5047, Status: "SUCCESS"}
+	svc.estimateSettlementFee(priced, &gateway.Status{PaymentType: "gopay"})
+	assert.Equal(t, float64(5047), priced.FeeAmount)
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// walletTopupPrefix starts the gateway order ID of every wallet top-up; the
+// webhook uses it to tell top-ups from order payments
+const walletTopupPrefix = "TOPUP-"
+
+type WalletResponse struct {
+	UserID  uuid.UUID                  `json:"user_id"`
+	Balance float64                    `json:"balance"`
+	Entries []entity.WalletLedgerEntry `json:"entries"`
+	Total   int64                      `json:"total"`
+}
+
+type WalletPaymentRequest struct {
+	OrderID uuid.UUID `json:"-"`
+	UserID  uuid.UUID `json:"-"`
+	// Amount to debit; zero pays as much of the outstanding balance as the
+	// wallet covers
+	Amount float64 `json:"amount" validate:"gte=0"`
+}
+
+type WalletPaymentResponse struct {
+	Payment           *entity.PaymentTransaction `json:"payment"`
+	WalletBalance     float64                    `json:"wallet_balance"`
+	PaidAmount        float64                    `json:"paid_amount"`
+	OutstandingAmount float64                    `json:"outstanding_amount"`
+}
+
+type WalletTopupRequest struct {
+	UserID          uuid.UUID `json:"-"`
+	Amount          float64   `json:"amount" validate:"required,gt=0"`
+	CustomerDetail  *Customer `json:"customer_detail"`
+	EnabledPayments []string  `json:"enabled_payments"`
+	ExpiryMinutes   int       `json:"expiry_minutes"`
+}
+
+type WalletTopupResponse struct {
+	TopupID        uuid.UUID  `json:"topup_id"`
+	PaymentOrderID string     `json:"payment_order_id"`
+	Amount         float64    `json:"amount"`
+	Token          string     `json:"token"`
+	RedirectURL    string     `json:"redirect_url"`
+	ClientKey      string     `json:"client_key"`
+	ExpiryTime     *time.Time `json:"expiry_time,omitempty"`
+}
+
+// GetWallet returns the user's balance and a page of ledger entries
+func (s *paymentService) GetWallet(ctx context.Context, userID uuid.UUID, page, limit int) (*WalletResponse, error) {
+	balance, err := s.repo.GetWalletBalance(ctx, userID)
+	if err != nil {
+		return nil, appErrors.NotFound("User not found", err)
+	}
+	entries, total, err := s.repo.ListLedgerEntries(ctx, userID, page, limit)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list wallet ledger", err)
+	}
+	return &WalletResponse{
+		UserID:  userID,
+		Balance: balance,
+		Entries: entries,
+		Total:   total,
+	}, nil
+}
+
+// PayOrderWithWallet pays (part of) the customer's own order from their
+// wallet, after closing the order's open gateway charges. The debit, the
+// payment transaction and the ledger entry are written in one database
+// transaction; the debit itself is a conditional UPDATE, so the balance
+// cannot go negative even if the lock is lost.
+func (s *paymentService) PayOrderWithWallet(ctx context.Context, req WalletPaymentRequest) (*WalletPaymentResponse, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.PayOrderWithWallet")
+		defer seg.End()
+		txn.AddAttribute("order_id", req.OrderID.String())
+	}
+
+	if req.Amount < 0 {
+		return nil, appErrors.BadRequest("Amount must not be negative", nil)
+	}
+
+	var result *WalletPaymentResponse
+	orderLockKey := fmt.Sprintf("payment:order:%s", req.OrderID)
+	err := s.withLock(ctx, orderLockKey, 10*time.Second, func() error {
+		order, err := s.repo.FindOrderByID(ctx, req.OrderID)
+		if err != nil {
+			return appErrors.NotFound("Order not found", err)
+		}
+		if order.CustomerID != req.UserID {
+			return appErrors.Forbidden("Order belongs to another customer", nil)
+		}
+		if order.Status == "CANCELED" {
+			return appErrors.UnprocessableEntity("Cannot pay a canceled order", nil)
+		}
+
+		existing, err := s.repo.ListTransactionsByOrderID(ctx, order.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to list payment transactions", err)
+		}
+		if err := s.closeOpenCharges(ctx, order, existing, nil, "wallet", "Paid from wallet"); err != nil {
+			return err
+		}
+
+		paid, err := s.repo.SumPaidAmount(ctx, order.ID)
+		if err != nil {
+			return appErrors.InternalServerError("Failed to calculate paid amount", err)
+		}
+		outstanding := order.GrandTotal - paid
+		if outstanding <= 0 {
+			return appErrors.UnprocessableEntity("Order is already fully paid", nil)
+		}
+		if req.Amount > outstanding {
+			return appErrors.UnprocessableEntity(
+				fmt.Sprintf("Amount exceeds outstanding balance (%.0f)", outstanding), nil)
+		}
+
+		walletLockKey := fmt.Sprintf("wallet:%s", req.UserID)
+		return s.withLock(ctx, walletLockKey, 10*time.Second, func() error {
+			amount := req.Amount
+			if amount == 0 {
+				balance, err := s.repo.GetWalletBalance(ctx, req.UserID)
+				if err != nil {
+					return appErrors.NotFound("User not found", err)
+				}
+				amount = math.Min(balance, outstanding)
+			}
+			if amount <= 0 {
+				return appErrors.UnprocessableEntity("Insufficient wallet balance", nil)
+			}
+
+			now := time.Now()
+			paymentTx := &entity.PaymentTransaction{
+				OrderID:         order.ID,
+				PaymentOrderID:  fmt.Sprintf("%s-WALLET-%d", order.OrderNo, len(existing)+1),
+				PaymentMethod:   strPtr(entity.PaymentTransactionTypeWallet),
+				PaymentType:     strPtr(entity.PaymentTransactionTypeWallet),
+				GrossAmount:     amount,
+				Status:          "SUCCESS",
+				TransactionTime: &now,
+				SettlementTime:  &now,
+			}
+
+			var balance float64
+			err = s.withTx(ctx, func(r repository.PaymentRepository) error {
+				balance, err = r.AdjustWalletBalance(ctx, req.UserID, -amount)
+				if errors.Is(err, repository.ErrInsufficientBalance) {
+					return appErrors.UnprocessableEntity("Insufficient wallet balance", err)
+				}
+				if err != nil {
+					return appErrors.InternalServerError("Failed to debit wallet", err)
+				}
+				if err := r.CreateTransaction(ctx, paymentTx); err != nil {
+					return appErrors.InternalServerError("Failed to save payment transaction", err)
+				}
+				entry := &entity.WalletLedgerEntry{
+					UserID:               req.UserID,
+					EntryType:            entity.WalletEntryPayment,
+					Amount:               -amount,
+					BalanceAfter:         balance,
+					OrderID:              &order.ID,
+					PaymentTransactionID: &paymentTx.ID,
+					Description:          strPtr(fmt.Sprintf("Payment for order %s", order.OrderNo)),
+					CreatedBy:            &req.UserID,
+				}
+				if err := r.CreateLedgerEntry(ctx, entry); err != nil {
+					return appErrors.InternalServerError("Failed to write wallet ledger", err)
+				}
+				statusLog := &entity.PaymentStatusLog{
+					PaymentTransactionID: paymentTx.ID,
+					NewStatus:            "SUCCESS",
+					Source:               "wallet",
+					StatusMessage:        strPtr(fmt.Sprintf("Paid from wallet: %.0f (balance %.0f)", amount, balance)),
+				}
+				if err := r.CreateStatusLog(ctx, statusLog); err != nil {
+					slog.WarnContext(ctx, "[Payment] Failed to create status log", "error", err)
+				}
+				if err := s.queueOrderConfirmation(ctx, r, paymentTx); err != nil {
+					return appErrors.InternalServerError("Failed to queue order confirmation", err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			order.SetPaidAmount(paid + amount)
+			result = &WalletPaymentResponse{
+				Payment:           paymentTx,
+				WalletBalance:     balance,
+				PaidAmount:        order.PaidAmount,
+				OutstandingAmount: order.OutstandingAmount,
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Wallet payment recorded",
+		"order_id", req.OrderID, "payment_order_id", result.Payment.PaymentOrderID,
+		"amount", result.Payment.GrossAmount, "balance", result.WalletBalance, "outstanding", result.OutstandingAmount)
+	s.publish(ctx, events.NewPaymentSucceeded(*result.Payment))
+	return result, nil
+}
+
+// CreateWalletTopup starts a gateway charge that credits the wallet once it
+// settles (see applyTopupNotification)
+func (s *paymentService) CreateWalletTopup(ctx context.Context, req WalletTopupRequest) (*WalletTopupResponse, error) {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		seg := txn.StartSegment("payments.CreateWalletTopup")
+		defer seg.End()
+		txn.AddAttribute("gross_amount", req.Amount)
+	}
+
+	if req.Amount <= 0 || req.Amount != math.Trunc(req.Amount) {
+		return nil, appErrors.BadRequest("Top-up amount must be a positive whole number", nil)
+	}
+	if minAmount := s.cfg.Wallet.TopupMinAmount; minAmount > 0 && req.Amount < minAmount {
+		return nil, appErrors.BadRequest(fmt.Sprintf("Minimum top-up is %.0f", minAmount), nil)
+	}
+	if maxAmount := s.cfg.Wallet.TopupMaxAmount; maxAmount > 0 && req.Amount > maxAmount {
+		return nil, appErrors.BadRequest(fmt.Sprintf("Maximum top-up is %.0f", maxAmount), nil)
+	}
+
+	paymentOrderID := fmt.Sprintf("%s%s-%d", walletTopupPrefix,
+		strings.ToUpper(req.UserID.String()[:8]), time.Now().UnixNano()/int64(time.Millisecond))
+
+	chargeReq := gateway.ChargeRequest{
+		PaymentOrderID:  paymentOrderID,
+		GrossAmount:     int64(req.Amount),
+		Items:           []gateway.Item{{ID: "WALLET-TOPUP", Name: "Wallet top-up", Price: int64(req.Amount), Qty: 1}},
+		EnabledPayments: req.EnabledPayments,
+		ExpiryMinutes:   req.ExpiryMinutes,
+	}
+	if req.CustomerDetail != nil {
+		chargeReq.Customer = &gateway.Customer{
+			FirstName: req.CustomerDetail.FirstName,
+			LastName:  req.CustomerDetail.LastName,
+			Email:     req.CustomerDetail.Email,
+			Phone:     req.CustomerDetail.Phone,
+		}
+	}
+
+	charge, err := s.gw.CreateCharge(ctx, chargeReq)
+	if err != nil {
+		slog.ErrorContext(ctx, "[Payment] Top-up charge failed", "gateway", s.gw.Name(), "payment_order_id", paymentOrderID, "error", err)
+		return nil, appErrors.InternalServerError("Failed to create payment token", err)
+	}
+
+	var expiryTime *time.Time
+	if req.ExpiryMinutes > 0 {
+		exp := time.Now().Add(time.Duration(req.ExpiryMinutes) * time.Minute)
+		expiryTime = &exp
+	}
+
+	topup := &entity.WalletTopup{
+		UserID:          req.UserID,
+		PaymentOrderID:  paymentOrderID,
+		Amount:          req.Amount,
+		Status:          "PENDING",
+		SnapToken:       &charge.Token,
+		SnapRedirectURL: &charge.RedirectURL,
+	}
+	if err := s.repo.CreateTopup(ctx, topup); err != nil {
+		return nil, appErrors.InternalServerError("Failed to save wallet top-up", err)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Wallet top-up created", "payment_order_id", paymentOrderID, "amount", req.Amount)
+	return &WalletTopupResponse{
+		TopupID:        topup.ID,
+		PaymentOrderID: paymentOrderID,
+		Amount:         req.Amount,
+		Token:          charge.Token,
+		RedirectURL:    charge.RedirectURL,
+		ClientKey:      s.gw.ClientKey(ctx),
+		ExpiryTime:     expiryTime,
+	}, nil
+}
+
+// applyTopupNotification handles a verified notification for a wallet
+// top-up. The wallet is credited exactly once, on the first SUCCESS.
+func (s *paymentService) applyTopupNotification(ctx context.Context, n *gateway.Notification, webhookLog *entity.PaymentWebhookLog, origin webhookOrigin) (*WebhookResponse, error) {
+	paymentOrderID := n.PaymentOrderID
+
+	if origin.dryRun {
+		if err := s.repo.CreateWebhookLog(ctx, webhookLog); err != nil {
+			slog.WarnContext(ctx, "[Payment] Failed to create webhook log", "error", err)
+		}
+		return &WebhookResponse{Status: n.Status.Status, Message: "Dry run: wallet top-up notification"}, nil
+	}
+
+	var result *WebhookResponse
+	err := s.withLock(ctx, transactionLockKey(paymentOrderID), 10*time.Second, func() error {
+		return s.withTx(ctx, func(r repository.PaymentRepository) error {
+			topup, err := r.FindTopupByPaymentOrderID(ctx, paymentOrderID)
+			if err != nil {
+				webhookLog.ProcessingError = strPtr("Wallet top-up not found")
+				return appErrors.NotFound(fmt.Sprintf("Wallet top-up not found for order_id: %s", paymentOrderID), err)
+			}
+
+			newStatus := n.Status.Status
+			if topup.Status == "SUCCESS" && newStatus != "SUCCESS" {
+				// Already credited; later refunds of the top-up are handled manually
+				slog.WarnContext(ctx, "[Payment] Ignoring notification for credited top-up", "status", newStatus)
+				newStatus = topup.Status
+			}
+			credit := newStatus == "SUCCESS" && topup.Status != "SUCCESS"
+
+			topup.Status = newStatus
+			if n.PaymentType != "" {
+				topup.PaymentType = strPtr(n.PaymentType)
+			}
+			if n.TransactionID != "" {
+				topup.TransactionID = strPtr(n.TransactionID)
+			}
+
+			if credit {
+				balance, err := r.AdjustWalletBalance(ctx, topup.UserID, topup.Amount)
+				if err != nil {
+					webhookLog.ProcessingError = strPtr(fmt.Sprintf("Failed to credit wallet: %v", err))
+					return appErrors.InternalServerError("Failed to credit wallet", err)
+				}
+				entry := &entity.WalletLedgerEntry{
+					UserID:       topup.UserID,
+					EntryType:    entity.WalletEntryTopup,
+					Amount:       topup.Amount,
+					BalanceAfter: balance,
+					TopupID:      &topup.ID,
+					Description:  strPtr(fmt.Sprintf("Top-up %s via %s", paymentOrderID, strPtrToString(topup.PaymentType))),
+				}
+				if err := r.CreateLedgerEntry(ctx, entry); err != nil {
+					return appErrors.InternalServerError("Failed to write wallet ledger", err)
+				}
+				now := time.Now()
+				topup.CreditedAt = &now
+				slog.InfoContext(ctx, "[Payment] Wallet credited from top-up",
+					"user_id", topup.UserID, "amount", topup.Amount, "balance", balance)
+			}
+
+			if err := r.UpdateTopup(ctx, topup); err != nil {
+				return appErrors.InternalServerError("Failed to update wallet top-up", err)
+			}
+
+			now := time.Now()
+			webhookLog.ProcessedAt = &now
+			if err := r.CreateWebhookLog(ctx, webhookLog); err != nil {
+				slog.WarnContext(ctx, "[Payment] Failed to create webhook log", "error", err)
+			}
+
+			result = &WebhookResponse{
+				PaymentTransactionID: topup.ID,
+				Status:               topup.Status,
+				Message:              "Wallet top-up notification processed successfully",
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		s.logFailedWebhook(ctx, webhookLog)
+		return nil, err
+	}
+	return result, nil
+}
+
+// creditRefundToWallet credits a refund to the order customer's wallet
+func creditRefundToWallet(ctx context.Context, r repository.PaymentRepository, customerID uuid.UUID, paymentTx *entity.PaymentTransaction, refund *entity.PaymentRefund) error {
+	balance, err := r.AdjustWalletBalance(ctx, customerID, refund.Amount)
+	if err != nil {
+		return appErrors.InternalServerError("Failed to credit wallet", err)
+	}
+	entry := &entity.WalletLedgerEntry{
+		UserID:               customerID,
+		EntryType:            entity.WalletEntryRefund,
+		Amount:               refund.Amount,
+		BalanceAfter:         balance,
+		OrderID:              &paymentTx.OrderID,
+		PaymentTransactionID: &paymentTx.ID,
+		Description:          strPtr(fmt.Sprintf("Refund %s: %s", refund.RefundKey, strPtrToString(refund.Reason))),
+		CreatedBy:            refund.RequestedBy,
+	}
+	if err := r.CreateLedgerEntry(ctx, entry); err != nil {
+		return appErrors.InternalServerError("Failed to write wallet ledger", err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/domain/payment/gateway"
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+func TestPayOrderWithWallet(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Zero amount pays as much as the wallet covers", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		gw := gateway.NewFake()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(85000)
+		order.CustomerID = uuid.New()
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(25000), nil).Once()
+		mockRepo.On("GetWalletBalance", ctx, order.CustomerID).Return(float64(40000), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{{}}, nil).Once()
+		mockRepo.On("AdjustWalletBalance", ctx, order.CustomerID, float64(-40000)).Return(float64(0), nil).Once()
+		mockRepo.On("CreateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.PaymentOrderID == "ORD-001-WALLET-2" && tx.IsWallet() && tx.Status == "SUCCESS" && tx.GrossAmount == 40000
+		})).Return(nil).Once()
+		mockRepo.On("CreateLedgerEntry", ctx, mock.MatchedBy(func(e *entity.WalletLedgerEntry) bool {
+			return e.UserID == order.CustomerID && e.EntryType == entity.WalletEntryPayment &&
+				e.Amount == -40000 && e.BalanceAfter == 0 && *e.OrderID == order.ID
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "wallet"
+		})).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+
+		res, err := svc.PayOrderWithWallet(ctx, WalletPaymentRequest{OrderID: order.ID, UserID: order.CustomerID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(0), res.WalletBalance)
+		assert.Equal(t, float64(65000), res.PaidAmount)
+		assert.Equal(t, float64(20000), res.OutstandingAmount)
+		assert.Empty(t, gw.Calls())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Debit that would go negative is rejected", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+		order := testOrder(85000)
+		order.CustomerID = uuid.New()
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(0), nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Once()
+		mockRepo.On("AdjustWalletBalance", ctx, order.CustomerID, float64(-85000)).
+			Return(float64(0), repository.ErrInsufficientBalance).Once()
+
+		_, err := svc.PayOrderWithWallet(ctx, WalletPaymentRequest{OrderID: order.ID, UserID: order.CustomerID, Amount: 85000})
+
+		appErr, ok := err.(*appErrors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusUnprocessableEntity, appErr.StatusCode)
+		mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateLedgerEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Charge settled while being closed leaves nothing to pay", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		gw := gateway.NewFake()
+		svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+		order := testOrder(85000)
+		order.CustomerID = uuid.New()
+		open := createTestPaymentTransaction()
+		open.OrderID, open.PaymentOrderID, open.GrossAmount = order.ID, "ORD-001", 85000
+		gw.SetStatus("ORD-001", "SUCCESS")
+
+		mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+		mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{*open}, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORD-001").Return(open, nil).Once()
+		mockRepo.On("UpdateTransaction", ctx, mock.MatchedBy(func(tx *entity.PaymentTransaction) bool {
+			return tx.Status == "SUCCESS"
+		})).Return(nil).Once()
+		mockRepo.On("CreateStatusLog", ctx, mock.MatchedBy(func(l *entity.PaymentStatusLog) bool {
+			return l.Source == "wallet"
+		})).Return(nil).Once()
+		mockRepo.On("CreateOutboxMessage", ctx, confirmationQueued(order.ID)).Return(nil).Once()
+		mockRepo.On("SumPaidAmount", ctx, order.ID).Return(float64(85000), nil).Once()
+
+		_, err := svc.PayOrderWithWallet(ctx, WalletPaymentRequest{OrderID: order.ID, UserID: order.CustomerID})
+
+		appErr, ok := err.(*appErrors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusUnprocessableEntity, appErr.StatusCode)
+		assert.NotContains(t, gw.Calls(), "Cancel ORD-001")
+		mockRepo.AssertNotCalled(t, "AdjustWalletBalance", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejected requests", func(t *testing.T) {
+		order := testOrder(85000)
+		order.CustomerID = uuid.New()
+
+		tests := []struct {
+			name   string
+			req    WalletPaymentRequest
+			paid   float64
+			status int
+		}{
+			{"Another customer's order", WalletPaymentRequest{OrderID: order.ID, UserID: uuid.New()}, 0, http.StatusForbidden},
+			{"Amount above outstanding", WalletPaymentRequest{OrderID: order.ID, UserID: order.CustomerID, Amount: 90000}, 0, http.StatusUnprocessableEntity},
+			{"Fully paid order", WalletPaymentRequest{OrderID: order.ID, UserID: order.CustomerID}, 85000, http.StatusUnprocessableEntity},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockRepo := repository.NewMockPaymentRepository()
+				svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gateway.NewFake())
+				mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil)
+				mockRepo.On("SumPaidAmount", ctx, order.ID).Return(tt.paid, nil)
+				mockRepo.On("ListTransactionsByOrderID", ctx, order.ID).Return([]entity.PaymentTransaction{}, nil).Maybe()
+
+				_, err := svc.PayOrderWithWallet(ctx, tt.req)
+
+				appErr, ok := err.(*appErrors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, tt.status, appErr.StatusCode)
+				mockRepo.AssertNotCalled(t, "AdjustWalletBalance", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func TestWalletTopup(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	var topup *entity.WalletTopup
+	mockRepo.On("CreateTopup", ctx, mock.MatchedBy(func(tp *entity.WalletTopup) bool {
+		topup = tp
+		return tp.UserID == userID && tp.Amount == 50000 && tp.Status == "PENDING"
+	})).Return(nil).Once()
+
+	res, err := svc.CreateWalletTopup(ctx, WalletTopupRequest{UserID: userID, Amount: 50000})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.PaymentOrderID, "TOPUP-"))
+	assert.Equal(t, "fake-token-"+res.PaymentOrderID, res.Token)
+
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, res.PaymentOrderID).Return(nil, assert.AnError)
+	mockRepo.On("FindTopupByPaymentOrderID", ctx, res.PaymentOrderID).Return(topup, nil)
+	mockRepo.On("AdjustWalletBalance", ctx, userID, float64(50000)).Return(float64(70000), nil).Once()
+	mockRepo.On("CreateLedgerEntry", ctx, mock.MatchedBy(func(e *entity.WalletLedgerEntry) bool {
+		return e.EntryType == entity.WalletEntryTopup && e.Amount == 50000 && e.BalanceAfter == 70000 && *e.TopupID == topup.ID
+	})).Return(nil).Once()
+	mockRepo.On("UpdateTopup", ctx, mock.Anything).Return(nil)
+	mockRepo.On("CreateWebhookLog", ctx, mock.Anything).Return(nil)
+
+	payload := map[string]interface{}{"order_id": res.PaymentOrderID, "status": "SUCCESS", "signature_key": gw.Secret}
+
+	// Gateways retry notifications; the wallet is credited once
+	for i := 0; i < 2; i++ {
+		wr, err := svc.ProcessWebhookNotification(ctx, payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "SUCCESS", wr.Status)
+	}
+	assert.NotNil(t, topup.CreditedAt)
+	mockRepo.AssertExpectations(t)
+
+	t.Run("Amount outside configured limits", func(t *testing.T) {
+		cfg := createTestConfig()
+		cfg.Wallet.TopupMinAmount = 10000
+		cfg.Wallet.TopupMaxAmount = 100000
+		svc := NewPaymentService(cfg, repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker(), gateway.NewFake())
+
+		for _, amount := range []float64{5000, 200000, 15000.5} {
+			_, err := svc.CreateWalletTopup(ctx, WalletTopupRequest{UserID: userID, Amount: amount})
+			appErr, ok := err.(*appErrors.AppError)
+			assert.True(t, ok)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+		}
+	})
+}
+
+func TestWalletTopupFailureIsLogged(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	txRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, db, lock.NewMemoryLocker(), gw)
+
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, "TOPUP-MISSING").Return(nil, assert.AnError).Once()
+	mockRepo.On("WithDB", mock.Anything).Return(txRepo).Once()
+	txRepo.On("FindTopupByPaymentOrderID", ctx, "TOPUP-MISSING").Return(nil, gorm.ErrRecordNotFound).Once()
+	// Written with the outer repository, not rolled back with the transaction
+	mockRepo.On("CreateWebhookLog", ctx, mock.MatchedBy(func(l *entity.PaymentWebhookLog) bool {
+		return l.ProcessingError != nil && *l.ProcessingError == "Wallet top-up not found"
+	})).Return(nil).Once()
+
+	payload := map[string]interface{}{"order_id": "TOPUP-MISSING", "status": "SUCCESS", "signature_key": gw.Secret}
+	_, err = svc.ProcessWebhookNotification(ctx, payload)
+
+	var appErr *appErrors.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.StatusCode)
+	mockRepo.AssertExpectations(t)
+	txRepo.AssertExpectations(t)
+	txRepo.AssertNotCalled(t, "CreateWebhookLog", mock.Anything, mock.Anything)
+}
+
+func TestRefundToWallet(t *testing.T) {
+	ctx := context.Background()
+	gw := gateway.NewFake()
+	mockRepo := repository.NewMockPaymentRepository()
+	svc := NewPaymentService(createTestConfig(), mockRepo, nil, lock.NewMemoryLocker(), gw)
+
+	order := testOrder(60000)
+	order.CustomerID = uuid.New()
+	paymentTx := &entity.PaymentTransaction{
+		ID:             uuid.New(),
+		OrderID:        order.ID,
+		PaymentOrderID: "ORD-001-WALLET-1",
+		PaymentType:    strPtr(entity.PaymentTransactionTypeWallet),
+		GrossAmount:    60000,
+		Status:         "SUCCESS",
+	}
+
+	mockRepo.On("FindTransactionByID", ctx, paymentTx.ID).Return(paymentTx, nil).Once()
+	mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+	mockRepo.On("SumRefundedAmount", ctx, paymentTx.ID).Return(float64(0), nil).Once()
+	mockRepo.On("FindOrderByID", ctx, order.ID).Return(order, nil).Once()
+	mockRepo.On("ListRefunds", ctx, paymentTx.ID).Return([]entity.PaymentRefund{}, nil).Once()
+	mockRepo.On("CreateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Source == "wallet"
+	})).Return(nil).Once()
+	mockRepo.On("UpdateRefund", ctx, mock.MatchedBy(func(r *entity.PaymentRefund) bool {
+		return r.Status == "SUCCESS"
+	})).Return(nil).Once()
+	mockRepo.On("AdjustWalletBalance", ctx, order.CustomerID, float64(60000)).Return(float64(60000), nil).Once()
+	mockRepo.On("CreateLedgerEntry", ctx, mock.MatchedBy(func(e *entity.WalletLedgerEntry) bool {
+		return e.EntryType == entity.WalletEntryRefund && e.Amount == 60000 && *e.PaymentTransactionID == paymentTx.ID
+	})).Return(nil).Once()
+	mockRepo.On("UpdateTransaction", ctx, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateStatusLog", ctx, mock.Anything).Return(nil).Once()
+
+	res, err := svc.RefundTransaction(ctx, RefundRequest{PaymentTransactionID: paymentTx.ID, Amount: 60000, Reason: "Order canceled"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "REFUNDED", res.PaymentStatus)
+	assert.Empty(t, gw.Calls())
+	mockRepo.AssertExpectations(t)
+}
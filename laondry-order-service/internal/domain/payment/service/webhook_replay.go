@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	appErrors "laondry-order-service/pkg/errors"
+)
+
+// maxReplayBatch caps how many webhook logs a single replay request may touch.
+const maxReplayBatch = 100
+
+const (
+	ReplayStatusReplayed = "replayed"
+	ReplayStatusDryRun   = "dry_run"
+	ReplayStatusSkipped  = "skipped"
+	ReplayStatusFailed   = "failed"
+)
+
+type ReplayWebhookRequest struct {
+	// LogIDs selects webhook logs explicitly. When empty, Filters is used.
+	LogIDs  []uuid.UUID
+	Filters *repository.WebhookLogFilters
+	// DryRun verifies and maps the payload without updating the transaction.
+	DryRun bool
+	// Force replays logs that were already processed successfully.
+	Force      bool
+	ReplayedBy *uuid.UUID
+}
+
+type ReplayWebhookItem struct {
+	OriginalLogID  uuid.UUID  `json:"original_log_id"`
+	ReplayLogID    *uuid.UUID `json:"replay_log_id,omitempty"`
+	PaymentOrderID string     `json:"payment_order_id"`
+	Status         string     `json:"status"`
+	PaymentStatus  string     `json:"payment_status,omitempty"`
+	Message        string     `json:"message"`
+}
+
+type ReplayWebhookResult struct {
+	DryRun   bool                `json:"dry_run"`
+	Total    int                 `json:"total"`
+	Replayed int                 `json:"replayed"`
+	Skipped  int                 `json:"skipped"`
+	Failed   int                 `json:"failed"`
+	Items    []ReplayWebhookItem `json:"items"`
+}
+
+// ListWebhookLogs lists stored webhook notifications with filters
+func (s *paymentService) ListWebhookLogs(ctx context.Context, filters repository.WebhookLogFilters) ([]entity.PaymentWebhookLog, int64, error) {
+	return s.repo.SearchWebhookLogs(ctx, filters)
+}
+
+// ReplayWebhookLogs re-runs stored webhook payloads through the notification
+// pipeline. Each replay is recorded as a new webhook log linked to the original.
+func (s *paymentService) ReplayWebhookLogs(ctx context.Context, req ReplayWebhookRequest) (*ReplayWebhookResult, error) {
+	targets, err := s.replayTargets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "[Payment] Replaying webhook logs", "count", len(targets), "dry_run", req.DryRun, "force", req.Force)
+
+	result := &ReplayWebhookResult{DryRun: req.DryRun, Total: len(targets), Items: []ReplayWebhookItem{}}
+	for i := range targets {
+		item := s.replayOne(ctx, &targets[i], req)
+		switch item.Status {
+		case ReplayStatusReplayed, ReplayStatusDryRun:
+			result.Replayed++
+		case ReplayStatusSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	slog.InfoContext(ctx, "[Payment] Webhook replay finished",
+		"replayed", result.Replayed, "skipped", result.Skipped, "failed", result.Failed)
+	return result, nil
+}
+
+func (s *paymentService) replayTargets(ctx context.Context, req ReplayWebhookRequest) ([]entity.PaymentWebhookLog, error) {
+	if len(req.LogIDs) > 0 {
+		if len(req.LogIDs) > maxReplayBatch {
+			return nil, appErrors.BadRequest("Too many webhook logs in one replay request", nil)
+		}
+		logs := make([]entity.PaymentWebhookLog, 0, len(req.LogIDs))
+		for _, id := range req.LogIDs {
+			wl, err := s.repo.FindWebhookLogByID(ctx, id)
+			if err != nil {
+				return nil, appErrors.NotFound("Webhook log not found: "+id.String(), err)
+			}
+			logs = append(logs, *wl)
+		}
+		return logs, nil
+	}
+
+	if req.Filters == nil {
+		return nil, appErrors.BadRequest("Provide log_ids or filters to select webhook logs", nil)
+	}
+	filters := *req.Filters
+	filters.Page = 1
+	if filters.Limit <= 0 || filters.Limit > maxReplayBatch {
+		filters.Limit = maxReplayBatch
+	}
+	logs, _, err := s.repo.SearchWebhookLogs(ctx, filters)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list webhook logs", err)
+	}
+	return logs, nil
+}
+
+func (s *paymentService) replayOne(ctx context.Context, original *entity.PaymentWebhookLog, req ReplayWebhookRequest) ReplayWebhookItem {
+	item := ReplayWebhookItem{
+		OriginalLogID:  original.ID,
+		PaymentOrderID: original.PaymentOrderID,
+	}
+
+	// Always link to the first log in a chain so history stays flat
+	rootID := original.ID
+	if original.ReplayOfID != nil {
+		rootID = *original.ReplayOfID
+	}
+
+	if !req.Force && original.ProcessedAt != nil && original.ProcessingError == nil && !original.DryRun {
+		item.Status = ReplayStatusSkipped
+		item.Message = "Webhook already processed; use force to replay"
+		return item
+	}
+	if len(original.RawPayload) == 0 {
+		item.Status = ReplayStatusFailed
+		item.Message = "Webhook log has no stored payload"
+		return item
+	}
+
+	replayLogID := uuid.New()
+	res, err := s.processNotification(ctx, map[string]interface{}(original.RawPayload), webhookOrigin{
+		logID:      replayLogID,
+		replayOf:   &rootID,
+		replayedBy: req.ReplayedBy,
+		dryRun:     req.DryRun,
+	})
+	item.ReplayLogID = &replayLogID
+	if err != nil {
+		slog.WarnContext(ctx, "[Payment] Webhook replay failed",
+			"webhook_log_id", original.ID, "payment_order_id", original.PaymentOrderID, "error", err)
+		item.Status = ReplayStatusFailed
+		item.Message = err.Error()
+		return item
+	}
+
+	item.PaymentStatus = res.Status
+	item.Message = res.Message
+	item.Status = ReplayStatusReplayed
+	if req.DryRun {
+		item.Status = ReplayStatusDryRun
+	}
+	return item
+}
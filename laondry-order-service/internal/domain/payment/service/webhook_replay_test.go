@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/domain/payment/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+)
+
+func signedPayload(serverKey, paymentOrderID, status string) entity.JSONB {
+	sum := sha512.Sum512([]byte(paymentOrderID + "200" + "150000.00" + serverKey))
+	return entity.JSONB{
+		"order_id":           paymentOrderID,
+		"status_code":        "200",
+		"gross_amount":       "150000.00",
+		"signature_key":      hex.EncodeToString(sum[:]),
+		"transaction_status": status,
+	}
+}
+
+func TestReplayWebhookLogs(t *testing.T) {
+	cfg := createTestConfig()
+	ctx := context.Background()
+
+	t.Run("Dry run records linked log without updating transaction", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewMidtransService(cfg, mockRepo, nil, lock.NewMemoryLocker())
+
+		paymentTx := createTestPaymentTransaction()
+		original := &entity.PaymentWebhookLog{
+			ID:              uuid.New(),
+			PaymentOrderID:  paymentTx.PaymentOrderID,
+			RawPayload:      signedPayload(cfg.Midtrans.ServerKey, paymentTx.PaymentOrderID, "settlement"),
+			ProcessingError: strPtr("Payment transaction not found"),
+		}
+		replayedBy := uuid.New()
+
+		mockRepo.On("FindWebhookLogByID", ctx, original.ID).Return(original, nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, paymentTx.PaymentOrderID).Return(paymentTx, nil).Once()
+		mockRepo.On("CreateWebhookLog", ctx, mock.MatchedBy(func(l *entity.PaymentWebhookLog) bool {
+			return l.ReplayOfID != nil && *l.ReplayOfID == original.ID &&
+				l.DryRun && l.ReplayedBy != nil && *l.ReplayedBy == replayedBy &&
+				l.SignatureVerified && l.ProcessedAt == nil
+		})).Return(nil).Once()
+
+		res, err := svc.ReplayWebhookLogs(ctx, ReplayWebhookRequest{
+			LogIDs:     []uuid.UUID{original.ID},
+			DryRun:     true,
+			ReplayedBy: &replayedBy,
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, res.DryRun)
+		assert.Equal(t, 1, res.Replayed)
+		assert.Equal(t, ReplayStatusDryRun, res.Items[0].Status)
+		assert.Equal(t, "SUCCESS", res.Items[0].PaymentStatus)
+		assert.Equal(t, "PENDING", paymentTx.Status)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "UpdateTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Processed log is skipped unless forced", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewMidtransService(cfg, mockRepo, nil, lock.NewMemoryLocker())
+
+		processedAt := time.Now()
+		original := &entity.PaymentWebhookLog{
+			ID:             uuid.New(),
+			PaymentOrderID: "ORDER-TEST-PAY-1",
+			RawPayload:     signedPayload(cfg.Midtrans.ServerKey, "ORDER-TEST-PAY-1", "settlement"),
+			ProcessedAt:    &processedAt,
+		}
+		mockRepo.On("FindWebhookLogByID", ctx, original.ID).Return(original, nil).Once()
+
+		res, err := svc.ReplayWebhookLogs(ctx, ReplayWebhookRequest{LogIDs: []uuid.UUID{original.ID}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Skipped)
+		assert.Equal(t, ReplayStatusSkipped, res.Items[0].Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Replays selected by filter link to the root log", func(t *testing.T) {
+		mockRepo := repository.NewMockPaymentRepository()
+		svc := NewMidtransService(cfg, mockRepo, nil, lock.NewMemoryLocker())
+
+		rootID := uuid.New()
+		previousReplay := entity.PaymentWebhookLog{
+			ID:              uuid.New(),
+			PaymentOrderID:  "ORDER-MISSING-PAY-1",
+			RawPayload:      signedPayload(cfg.Midtrans.ServerKey, "ORDER-MISSING-PAY-1", "settlement"),
+			ProcessingError: strPtr("Payment transaction not found"),
+			ReplayOfID:      &rootID,
+		}
+		filters := repository.WebhookLogFilters{OnlyFailed: true, Limit: 500}
+
+		mockRepo.On("SearchWebhookLogs", ctx, repository.WebhookLogFilters{OnlyFailed: true, Page: 1, Limit: maxReplayBatch}).
+			Return([]entity.PaymentWebhookLog{previousReplay}, int64(1), nil).Once()
+		mockRepo.On("FindTransactionByPaymentOrderID", ctx, "ORDER-MISSING-PAY-1").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockRepo.On("CreateWebhookLog", ctx, mock.MatchedBy(func(l *entity.PaymentWebhookLog) bool {
+			return l.ReplayOfID != nil && *l.ReplayOfID == rootID && !l.DryRun && l.ProcessingError != nil
+		})).Return(nil).Once()
+
+		res, err := svc.ReplayWebhookLogs(ctx, ReplayWebhookRequest{Filters: &filters})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Failed)
+		assert.Equal(t, ReplayStatusFailed, res.Items[0].Status)
+		assert.NotNil(t, res.Items[0].ReplayLogID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requires log IDs or filters", func(t *testing.T) {
+		svc := NewMidtransService(cfg, repository.NewMockPaymentRepository(), nil, lock.NewMemoryLocker())
+
+		res, err := svc.ReplayWebhookLogs(ctx, ReplayWebhookRequest{})
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
@@ -0,0 +1,69 @@
+package realtime
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"laondry-order-service/internal/config"
+	orepo "laondry-order-service/internal/domain/order/repository"
+	rhandler "laondry-order-service/internal/domain/realtime/handler/rest"
+	rservice "laondry-order-service/internal/domain/realtime/service"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/pubsub"
+
+	redis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// redisChannel carries order events between processes
+const redisChannel = "laondry:order-events"
+
+type RealtimeDomain struct {
+	Broker  pubsub.Broker
+	Service rservice.RealtimeService
+	Handler *rhandler.EventsHandler
+}
+
+func NewRealtimeDomain(cfg *config.Config, db *gorm.DB) *RealtimeDomain {
+	broker := newBroker(cfg)
+	svc := rservice.NewRealtimeService(broker, orepo.NewOrderRepository(db))
+
+	return &RealtimeDomain{
+		Broker:  broker,
+		Service: svc,
+		Handler: rhandler.NewEventsHandler(svc),
+	}
+}
+
+// RegisterEventHandlers streams status changes and payments to clients
+func (d *RealtimeDomain) RegisterEventHandlers(bus *events.Bus) {
+	bus.Subscribe("realtime", d.Service)
+}
+
+// newBroker fans out over Redis pub/sub if REDIS_ADDR is set, so events
+// reach streams served by every worker; otherwise in memory
+func newBroker(cfg *config.Config) pubsub.Broker {
+	if cfg != nil && cfg.Redis.Addr != "" {
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		// Test Redis connection with ping
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			slog.Warn("[Realtime] Redis connection failed, using in-memory broker", "error", err)
+		} else {
+			slog.Info("[Realtime] Using Redis pub/sub", "addr", cfg.Redis.Addr)
+			return pubsub.NewRedisBroker(rdb, redisChannel)
+		}
+	} else {
+		slog.Info("[Realtime] Using in-memory broker (no Redis configured)")
+	}
+	if cfg != nil && cfg.App.ClusterEnabled && cfg.App.ClusterPrefork {
+		slog.Warn("[Realtime] Prefork workers without Redis only stream events of their own requests")
+	}
+	return pubsub.NewMemoryBroker()
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/realtime/service"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/pubsub"
+	"laondry-order-service/pkg/response"
+)
+
+// heartbeatInterval keeps idle streams from being closed by proxies
+const heartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	realtimeService service.RealtimeService
+	heartbeat       time.Duration
+}
+
+func NewEventsHandler(realtimeService service.RealtimeService) *EventsHandler {
+	return &EventsHandler{
+		realtimeService: realtimeService,
+		heartbeat:       heartbeatInterval,
+	}
+}
+
+// GET /api/v1/orders/{id}/events
+// Streams the order's status changes and payments as Server-Sent Events,
+// starting with an order.snapshot of its current state. Customers may only
+// stream their own orders, outlet-scoped API keys their outlet's.
+func (h *EventsHandler) StreamOrderEvents(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid order ID", err.Error())
+		return
+	}
+	mw.SetAccessField(r, "order_id", orderID.String())
+
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		response.Unauthorized(w, "user not found in context")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// Subscribe before taking the snapshot so no change falls in between
+	events := h.realtimeService.Subscribe(ctx, pubsub.OrderKey(orderID))
+
+	snapshot, err := h.realtimeService.Snapshot(ctx, orderID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	mw.SetAccessField(r, "order_no", snapshot.OrderNo)
+	if !mw.CanAccessOutlet(user, snapshot.OutletID.String()) {
+		response.Forbidden(w, "Order belongs to another outlet")
+		return
+	}
+	if !user.IsAPIKey() && !mw.HasRole(user, mw.StaffRoles...) && snapshot.CustomerID.String() != user.UserID {
+		response.Forbidden(w, "Order belongs to another customer")
+		return
+	}
+
+	first, err := json.Marshal(snapshot)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	h.stream(ctx, w, events, &pubsub.Event{Type: service.EventOrderSnapshot, Data: first})
+}
+
+// GET /api/v1/outlets/{id}/events
+// Streams the status changes and payments of every order of an outlet as
+// Server-Sent Events, for staff dashboards. Staff and API keys with
+// orders:read only.
+func (h *EventsHandler) StreamOutletEvents(w http.ResponseWriter, r *http.Request) {
+	outletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid outlet ID", err.Error())
+		return
+	}
+	mw.SetAccessField(r, "outlet_id", outletID.String())
+	if user, ok := mw.GetUserFromContext(r.Context()); ok && !mw.CanAccessOutlet(user, outletID.String()) {
+		response.Forbidden(w, "API key is scoped to another outlet")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	h.stream(ctx, w, h.realtimeService.Subscribe(ctx, pubsub.OutletKey(outletID)), nil)
+}
+
+// stream writes first, if any, then events until the client disconnects.
+// Clients that miss events while reconnecting should refetch the order.
+func (h *EventsHandler) stream(ctx context.Context, w http.ResponseWriter, events <-chan pubsub.Event, first *pubsub.Event) {
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(ctx, "[Realtime] Cannot lift write deadline", "error", err)
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // disable nginx buffering
+	if err := rc.Flush(); err != nil {
+		header.Del("Content-Type")
+		response.InternalServerError(w, "Streaming is not supported", err.Error())
+		return
+	}
+
+	if first != nil {
+		writeEvent(w, *first)
+	}
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, evt)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent frames evt as a Server-Sent Event. Data is compact JSON, so it
+// fits on one data line.
+func writeEvent(w http.ResponseWriter, evt pubsub.Event) {
+	if evt.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", evt.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, evt.Data)
+}
@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/realtime/service"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/pubsub"
+)
+
+type fakeOrderReader struct {
+	order *entity.Order
+}
+
+func (f *fakeOrderReader) FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+	o := *f.order
+	return &o, nil
+}
+
+func (f *fakeOrderReader) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	return 0, nil
+}
+
+func setupServer(t *testing.T, order *entity.Order, user *mw.UserClaims) (service.RealtimeService, *httptest.Server) {
+	t.Helper()
+	svc := service.NewRealtimeService(pubsub.NewMemoryBroker(), &fakeOrderReader{order: order})
+	h := NewEventsHandler(svc)
+	h.heartbeat = 50 * time.Millisecond
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), mw.ContextUserKey, user)))
+		})
+	})
+	r.Get("/orders/{id}/events", h.StreamOrderEvents)
+	r.Get("/outlets/{id}/events", h.StreamOutletEvents)
+
+	srv := httptest.NewServer(mw.Logger(r))
+	t.Cleanup(srv.Close)
+	return svc, srv
+}
+
+// readEvent reads lines up to the end of the next event or comment
+func readEvent(t *testing.T, lines <-chan string) []string {
+	t.Helper()
+	var event []string
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed, read %v", event)
+			}
+			if line == "" {
+				return event
+			}
+			event = append(event, line)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out reading event, read %v", event)
+		}
+	}
+}
+
+func openStream(t *testing.T, ctx context.Context, url string) (*http.Response, <-chan string) {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return resp, lines
+}
+
+func TestStreamOrderEvents(t *testing.T) {
+	order := &entity.Order{ID: uuid.New(), CustomerID: uuid.New(), OutletID: uuid.New(), OrderNo: "ORD-001", Status: "PROCESSING", GrandTotal: 50000}
+	svc, srv := setupServer(t, order, &mw.UserClaims{UserID: order.CustomerID.String(), Role: "customer"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, lines := openStream(t, ctx, srv.URL+"/orders/"+order.ID.String()+"/events")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	snapshot := readEvent(t, lines)
+	if len(snapshot) != 2 || snapshot[0] != "event: order.snapshot" || !strings.Contains(snapshot[1], `"status_code":"PROCESSING"`) {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+	if retry := readEvent(t, lines); retry[0] != "retry: 3000" {
+		t.Fatalf("expected retry, got %v", retry)
+	}
+
+	change := events.NewOrderStatusChanged(*order, entity.OrderStatusLog{ID: uuid.New(), OrderID: order.ID, ToStatus: "READY"})
+	svc.OrderStatusChanged(ctx, change)
+
+	evt := readEvent(t, lines)
+	for evt[0] == ": ping" {
+		evt = readEvent(t, lines)
+	}
+	if evt[0] != "id: order-status:"+change.StatusLogID.String() || evt[1] != "event: order.status_changed" || !strings.Contains(evt[2], `"to_status":"READY"`) {
+		t.Fatalf("unexpected event %v", evt)
+	}
+
+	// Idle streams get heartbeats
+	if ping := readEvent(t, lines); ping[0] != ": ping" {
+		t.Fatalf("expected heartbeat, got %v", ping)
+	}
+}
+
+func TestStreamOrderEvents_RejectsOtherCustomers(t *testing.T) {
+	order := &entity.Order{ID: uuid.New(), CustomerID: uuid.New(), OutletID: uuid.New(), OrderNo: "ORD-001"}
+	_, srv := setupServer(t, order, &mw.UserClaims{UserID: uuid.NewString(), Role: "customer"})
+
+	resp, err := http.Get(srv.URL + "/orders/" + order.ID.String() + "/events")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamOutletEvents(t *testing.T) {
+	order := &entity.Order{ID: uuid.New(), CustomerID: uuid.New(), OutletID: uuid.New(), OrderNo: "ORD-001", GrandTotal: 50000}
+	svc, srv := setupServer(t, order, &mw.UserClaims{UserID: uuid.NewString(), Role: mw.RoleKasir})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, lines := openStream(t, ctx, srv.URL+"/outlets/"+order.OutletID.String()+"/events")
+	if retry := readEvent(t, lines); retry[0] != "retry: 3000" {
+		t.Fatalf("expected retry, got %v", retry)
+	}
+
+	payment := events.NewPaymentSucceeded(entity.PaymentTransaction{ID: uuid.New(), OrderID: order.ID, PaymentOrderID: "PAY-1", GrossAmount: 50000})
+	svc.PaymentSucceeded(ctx, payment)
+
+	evt := readEvent(t, lines)
+	for evt[0] == ": ping" {
+		evt = readEvent(t, lines)
+	}
+	if evt[1] != "event: payment.succeeded" || !strings.Contains(evt[2], `"payment_order_id":"PAY-1"`) {
+		t.Fatalf("unexpected event %v", evt)
+	}
+}
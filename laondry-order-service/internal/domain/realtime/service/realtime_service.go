@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/pubsub"
+)
+
+// Event types streamed to clients
+const (
+	EventOrderSnapshot      = "order.snapshot"
+	EventOrderStatusChanged = "order.status_changed"
+	EventPaymentSucceeded   = "payment.succeeded"
+)
+
+type RealtimeService interface {
+	// OrderStatusChanged and PaymentSucceeded publish the change to the
+	// order's and outlet's streams. HandleEvent calls them for events
+	// published on the bus; they never fail the caller.
+	events.Subscriber
+	OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged)
+	PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded)
+
+	// Snapshot returns the order's current state, sent first on an order
+	// stream so clients need not poll before listening
+	Snapshot(ctx context.Context, orderID uuid.UUID) (*OrderSnapshot, error)
+	Subscribe(ctx context.Context, key string) <-chan pubsub.Event
+}
+
+// OrderReader loads orders and their settled payments; the order repository
+// satisfies it
+type OrderReader interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error)
+	SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error)
+}
+
+type OrderSnapshot struct {
+	OrderID           uuid.UUID `json:"order_id"`
+	OrderNo           string    `json:"order_no"`
+	OutletID          uuid.UUID `json:"outlet_id"`
+	CustomerID        uuid.UUID `json:"customer_id"`
+	Status            string    `json:"status_code"`
+	Total             float64   `json:"total"`
+	PaidAmount        float64   `json:"paid_amount"`
+	OutstandingAmount float64   `json:"outstanding_amount"`
+}
+
+// StatusChangedData is the data of an order.status_changed event
+type StatusChangedData struct {
+	OrderID    uuid.UUID `json:"order_id"`
+	OrderNo    string    `json:"order_no"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Note       *string   `json:"note,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// PaymentSucceededData is the data of a payment.succeeded event
+type PaymentSucceededData struct {
+	OrderID           uuid.UUID `json:"order_id"`
+	OrderNo           string    `json:"order_no"`
+	PaymentID         uuid.UUID `json:"payment_id"`
+	PaymentOrderID    string    `json:"payment_order_id"`
+	PaymentMethod     *string   `json:"payment_method"`
+	Amount            float64   `json:"amount"`
+	PaidAmount        float64   `json:"paid_amount"`
+	OutstandingAmount float64   `json:"outstanding_amount"`
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/pubsub"
+)
+
+type realtimeService struct {
+	broker pubsub.Broker
+	orders OrderReader
+	now    func() time.Time
+}
+
+func NewRealtimeService(broker pubsub.Broker, orders OrderReader) RealtimeService {
+	return &realtimeService{
+		broker: broker,
+		orders: orders,
+		now:    time.Now,
+	}
+}
+
+func (s *realtimeService) HandleEvent(ctx context.Context, evt events.Event) {
+	switch e := evt.(type) {
+	case events.OrderStatusChanged:
+		s.OrderStatusChanged(ctx, e)
+	case events.PaymentSucceeded:
+		s.PaymentSucceeded(ctx, e)
+	}
+}
+
+func (s *realtimeService) OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged) {
+	order := &entity.Order{ID: change.OrderID, OrderNo: change.OrderNo, OutletID: change.OutletID, CustomerID: change.CustomerID}
+	s.publish(ctx, "order-status:"+change.StatusLogID.String(), EventOrderStatusChanged, order, StatusChangedData{
+		OrderID:    change.OrderID,
+		OrderNo:    change.OrderNo,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		Note:       change.Note,
+		ChangedAt:  change.ChangedAt,
+	})
+}
+
+func (s *realtimeService) PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded) {
+	order, err := s.loadWithPaid(ctx, payment.OrderID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Realtime] Failed to load order for payment event",
+			"order_id", payment.OrderID, "payment_order_id", payment.PaymentOrderID, "error", err)
+		return
+	}
+	s.publish(ctx, "payment:"+payment.PaymentID.String(), EventPaymentSucceeded, order, PaymentSucceededData{
+		OrderID:           order.ID,
+		OrderNo:           order.OrderNo,
+		PaymentID:         payment.PaymentID,
+		PaymentOrderID:    payment.PaymentOrderID,
+		PaymentMethod:     payment.PaymentMethod,
+		Amount:            payment.GrossAmount,
+		PaidAmount:        order.PaidAmount,
+		OutstandingAmount: order.OutstandingAmount,
+	})
+}
+
+func (s *realtimeService) publish(ctx context.Context, id, eventType string, order *entity.Order, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.WarnContext(ctx, "[Realtime] Failed to encode event", "event", eventType, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+		return
+	}
+	evt := pubsub.Event{
+		ID:         id,
+		Type:       eventType,
+		OrderID:    order.ID,
+		OutletID:   order.OutletID,
+		CustomerID: order.CustomerID,
+		Data:       payload,
+		At:         s.now(),
+	}
+	if err := s.broker.Publish(ctx, evt); err != nil {
+		slog.WarnContext(ctx, "[Realtime] Failed to publish event", "event", eventType, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+	}
+}
+
+func (s *realtimeService) Snapshot(ctx context.Context, orderID uuid.UUID) (*OrderSnapshot, error) {
+	order, err := s.loadWithPaid(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderSnapshot{
+		OrderID:           order.ID,
+		OrderNo:           order.OrderNo,
+		OutletID:          order.OutletID,
+		CustomerID:        order.CustomerID,
+		Status:            order.Status,
+		Total:             order.GrandTotal,
+		PaidAmount:        order.PaidAmount,
+		OutstandingAmount: order.OutstandingAmount,
+	}, nil
+}
+
+func (s *realtimeService) loadWithPaid(ctx context.Context, orderID uuid.UUID) (*entity.Order, error) {
+	order, err := s.orders.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := s.orders.SumPaidAmount(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	order.SetPaidAmount(paid)
+	return order, nil
+}
+
+func (s *realtimeService) Subscribe(ctx context.Context, key string) <-chan pubsub.Event {
+	return s.broker.Subscribe(ctx, key)
+}
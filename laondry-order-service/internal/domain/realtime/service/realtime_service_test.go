@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/pubsub"
+)
+
+type fakeOrderReader struct {
+	order *entity.Order
+	paid  float64
+}
+
+func (f *fakeOrderReader) FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+	o := *f.order
+	return &o, nil
+}
+
+func (f *fakeOrderReader) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	return f.paid, nil
+}
+
+func receive(t *testing.T, ch <-chan pubsub.Event) pubsub.Event {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+		return pubsub.Event{}
+	}
+}
+
+func testOrder() *entity.Order {
+	return &entity.Order{
+		ID:         uuid.New(),
+		CustomerID: uuid.New(),
+		OutletID:   uuid.New(),
+		OrderNo:    "ORD-001",
+		Status:     "PROCESSING",
+		GrandTotal: 50000,
+	}
+}
+
+func TestRealtimeService_OrderStatusChanged(t *testing.T) {
+	order := testOrder()
+	svc := NewRealtimeService(pubsub.NewMemoryBroker(), &fakeOrderReader{order: order})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orderEvents := svc.Subscribe(ctx, pubsub.OrderKey(order.ID))
+	outletEvents := svc.Subscribe(ctx, pubsub.OutletKey(order.OutletID))
+
+	from := "PROCESSING"
+	change := events.NewOrderStatusChanged(*order, entity.OrderStatusLog{ID: uuid.New(), OrderID: order.ID, FromStatus: &from, ToStatus: "READY"})
+	svc.OrderStatusChanged(ctx, change)
+
+	evt := receive(t, orderEvents)
+	if evt.Type != EventOrderStatusChanged || evt.ID != "order-status:"+change.StatusLogID.String() || evt.CustomerID != order.CustomerID {
+		t.Fatalf("unexpected event %+v", evt)
+	}
+	var data StatusChangedData
+	if err := json.Unmarshal(evt.Data, &data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.OrderNo != "ORD-001" || *data.FromStatus != "PROCESSING" || data.ToStatus != "READY" {
+		t.Fatalf("unexpected data %+v", data)
+	}
+	if got := receive(t, outletEvents); got.ID != evt.ID {
+		t.Fatalf("expected outlet stream to get %s, got %s", evt.ID, got.ID)
+	}
+}
+
+func TestRealtimeService_PaymentSucceeded(t *testing.T) {
+	order := testOrder()
+	svc := NewRealtimeService(pubsub.NewMemoryBroker(), &fakeOrderReader{order: order, paid: 30000})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := svc.Subscribe(ctx, pubsub.OrderKey(order.ID))
+	method := "qris"
+	payment := events.NewPaymentSucceeded(entity.PaymentTransaction{ID: uuid.New(), OrderID: order.ID, PaymentOrderID: "PAY-1", PaymentMethod: &method, GrossAmount: 30000})
+	svc.PaymentSucceeded(ctx, payment)
+
+	evt := receive(t, stream)
+	var data PaymentSucceededData
+	if err := json.Unmarshal(evt.Data, &data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if evt.Type != EventPaymentSucceeded || data.PaymentID != payment.PaymentID || data.Amount != 30000 {
+		t.Fatalf("unexpected event %+v %+v", evt, data)
+	}
+	if data.PaidAmount != 30000 || data.OutstandingAmount != 20000 {
+		t.Fatalf("expected paid 30000 and outstanding 20000, got %+v", data)
+	}
+}
+
+func TestRealtimeService_Snapshot(t *testing.T) {
+	order := testOrder()
+	svc := NewRealtimeService(pubsub.NewMemoryBroker(), &fakeOrderReader{order: order, paid: 50000})
+
+	snapshot, err := svc.Snapshot(context.Background(), order.ID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snapshot.Status != "PROCESSING" || snapshot.PaidAmount != 50000 || snapshot.OutstandingAmount != 0 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"laondry-order-service/internal/config"
+	orepo "laondry-order-service/internal/domain/order/repository"
+	whandler "laondry-order-service/internal/domain/webhook/handler/rest"
+	wrepo "laondry-order-service/internal/domain/webhook/repository"
+	wservice "laondry-order-service/internal/domain/webhook/service"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/scheduler"
+	"laondry-order-service/pkg/secret"
+	"laondry-order-service/pkg/validator"
+
+	"gorm.io/gorm"
+)
+
+type WebhookDomain struct {
+	Repository wrepo.WebhookRepository
+	Service    wservice.WebhookService
+	Handler    *whandler.WebhookHandler
+}
+
+func NewWebhookDomain(cfg *config.Config, v *validator.Validator, db *gorm.DB) *WebhookDomain {
+	repo := wrepo.NewWebhookRepository(db)
+	svc := wservice.NewWebhookService(repo, orepo.NewOrderRepository(db), secretsCipher(cfg.Webhook), cfg.Webhook)
+
+	return &WebhookDomain{
+		Repository: repo,
+		Service:    svc,
+		Handler:    whandler.NewWebhookHandler(svc, v),
+	}
+}
+
+// RegisterEventHandlers queues partner webhook deliveries for status changes
+// and payments
+func (d *WebhookDomain) RegisterEventHandlers(bus *events.Bus) {
+	bus.Subscribe("webhook", d.Service)
+}
+
+// secretsCipher returns the cipher for subscription secrets, or nil when
+// WEBHOOK_SECRETS_KEY is not set (partner webhooks disabled)
+func secretsCipher(cfg config.WebhookConfig) *secret.Cipher {
+	if cfg.SecretsKey == "" {
+		slog.Info("[Webhook] WEBHOOK_SECRETS_KEY not set, partner webhooks disabled")
+		return nil
+	}
+	c, err := secret.NewCipher(cfg.SecretsKey)
+	if err != nil {
+		slog.Warn("[Webhook] Invalid WEBHOOK_SECRETS_KEY, partner webhooks disabled", "error", err)
+		return nil
+	}
+	return c
+}
+
+// Jobs returns the webhook background jobs enabled by configuration.
+func (d *WebhookDomain) Jobs(cfg *config.Config) []scheduler.Job {
+	if cfg.Webhook.PollIntervalSeconds <= 0 || cfg.Webhook.SecretsKey == "" {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "webhook-delivery",
+		Interval: time.Duration(cfg.Webhook.PollIntervalSeconds) * time.Second,
+		LockTTL:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := d.Service.DeliverDue(ctx)
+			return err
+		},
+	}}
+}
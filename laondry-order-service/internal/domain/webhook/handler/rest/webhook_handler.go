@@ -0,0 +1,243 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/webhook/repository"
+	"laondry-order-service/internal/domain/webhook/service"
+	"laondry-order-service/internal/entity"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/pkg/response"
+	"laondry-order-service/pkg/validator"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+	validator      *validator.Validator
+}
+
+func NewWebhookHandler(webhookService service.WebhookService, validator *validator.Validator) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		validator:      validator,
+	}
+}
+
+// GET /api/v1/admin/webhooks
+// Lists partner webhook subscriptions. Admin only.
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "Webhook subscriptions retrieved successfully", subs)
+}
+
+// POST /api/v1/admin/webhooks
+// Subscribes a partner URL to order and payment events, optionally scoped to
+// one outlet or customer. The signing secret is only returned here.
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeSubscription(w, r)
+	if !ok {
+		return
+	}
+	if userID, ok := currentUserID(r); ok {
+		req.CreatedBy = &userID
+	}
+
+	sub, err := h.webhookService.CreateSubscription(r.Context(), req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	mw.SetAccessField(r, "webhook_subscription_id", sub.ID.String())
+
+	response.Created(w, "Webhook subscription created successfully", sub)
+}
+
+// GET /api/v1/admin/webhooks/{id}
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := subscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "Webhook subscription retrieved successfully", sub)
+}
+
+// PUT /api/v1/admin/webhooks/{id}
+// Replaces a subscription's URL, events and scope. Set rotate_secret to get
+// a new signing secret, or is_active to re-enable an auto-disabled one.
+func (h *WebhookHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := subscriptionID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.UpdateSubscription(r.Context(), id, req)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "Webhook subscription updated successfully", sub)
+}
+
+// DELETE /api/v1/admin/webhooks/{id}
+// Deletes a subscription with its deliveries
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := subscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "Webhook subscription deleted successfully", nil)
+}
+
+// GET /api/v1/admin/webhooks/{id}/deliveries
+// Lists a subscription's deliveries, newest first. Filters: status
+// (PENDING, SUCCEEDED, FAILED), order_id, page, limit.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := subscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filters := repository.DeliveryFilters{SubscriptionID: id, Page: 1, Limit: 20}
+	if v := query.Get("status"); v != "" {
+		filters.Status = &v
+	}
+	if v := query.Get("order_id"); v != "" {
+		orderID, err := uuid.Parse(v)
+		if err != nil {
+			response.BadRequest(w, "Invalid order ID", err.Error())
+			return
+		}
+		filters.OrderID = &orderID
+	}
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		filters.Page = p
+	}
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 100 {
+		filters.Limit = l
+	}
+
+	deliveries, total, err := h.webhookService.ListDeliveries(r.Context(), filters)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	totalPages := (total + int64(filters.Limit) - 1) / int64(filters.Limit)
+	response.Success(w, "Webhook deliveries retrieved successfully", map[string]interface{}{
+		"deliveries": deliveries,
+		"pagination": map[string]interface{}{
+			"page":        filters.Page,
+			"limit":       filters.Limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// GET /api/v1/admin/webhooks/deliveries/{deliveryId}
+// Shows a delivery with its attempt log
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := deliveryID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.webhookService.GetDelivery(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, "Webhook delivery retrieved successfully", d)
+}
+
+// POST /api/v1/admin/webhooks/deliveries/{deliveryId}/redeliver
+// Sends a delivery again right away and returns it with the new attempt
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := deliveryID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.webhookService.Redeliver(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	message := "Webhook redelivered successfully"
+	if d.Status != entity.WebhookDeliverySucceeded {
+		message = "Webhook redelivery failed"
+	}
+	response.Success(w, message, d)
+}
+
+func (h *WebhookHandler) decodeSubscription(w http.ResponseWriter, r *http.Request) (service.SubscriptionRequest, bool) {
+	var req service.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request payload", err.Error())
+		return req, false
+	}
+	if validationErrors := h.validator.Validate(req); len(validationErrors) > 0 {
+		response.UnprocessableEntity(w, "Validation failed", validationErrors)
+		return req, false
+	}
+	return req, true
+}
+
+func subscriptionID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook subscription ID", err.Error())
+		return uuid.Nil, false
+	}
+	mw.SetAccessField(r, "webhook_subscription_id", id.String())
+	return id, true
+}
+
+func deliveryID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook delivery ID", err.Error())
+		return uuid.Nil, false
+	}
+	mw.SetAccessField(r, "webhook_delivery_id", id.String())
+	return id, true
+}
+
+// currentUserID returns the authenticated user's ID
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	user, ok := mw.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error
+	SaveSubscription(ctx context.Context, sub *entity.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	// FindSubscriptionByID returns gorm.ErrRecordNotFound for unknown IDs
+	FindSubscriptionByID(ctx context.Context, id uuid.UUID) (*entity.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+	ListActiveSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+
+	// RecordSuccess clears the subscription's failure streak
+	RecordSuccess(ctx context.Context, subscriptionID uuid.UUID) error
+	// RecordFailure extends the failure streak and deactivates the
+	// subscription once it reaches disableAfter (0 never disables). It
+	// reports whether this call disabled it.
+	RecordFailure(ctx context.Context, subscriptionID uuid.UUID, disableAfter int, now time.Time) (bool, error)
+
+	// CreateDeliveries queues deliveries, skipping events already queued for
+	// the same subscription. It returns how many were queued.
+	CreateDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) (int64, error)
+	// ListDueDeliveries returns PENDING deliveries of active subscriptions
+	// whose next attempt is due, oldest first
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// FindDeliveryByID returns the delivery with its attempt log, or
+	// gorm.ErrRecordNotFound
+	FindDeliveryByID(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filters DeliveryFilters) ([]entity.WebhookDelivery, int64, error)
+	SaveDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	CreateAttempt(ctx context.Context, attempt *entity.WebhookDeliveryAttempt) error
+}
+
+type DeliveryFilters struct {
+	SubscriptionID uuid.UUID
+	Status         *string
+	OrderID        *uuid.UUID
+	Page           int
+	Limit          int
+}
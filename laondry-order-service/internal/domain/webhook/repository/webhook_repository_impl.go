@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laondry-order-service/internal/entity"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(sub).Error
+}
+
+func (r *webhookRepository) SaveSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(sub).Error
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&entity.WebhookDelivery{}).Select("id").Where("subscription_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&entity.WebhookDeliveryAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&entity.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&entity.WebhookSubscription{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepository) FindSubscriptionByID(ctx context.Context, id uuid.UUID) (*entity.WebhookSubscription, error) {
+	var sub entity.WebhookSubscription
+	if err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var subs []entity.WebhookSubscription
+	err := r.db.WithContext(ctx).Order("created_at ASC").Find(&subs).Error
+	return subs, err
+}
+
+func (r *webhookRepository) ListActiveSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var subs []entity.WebhookSubscription
+	err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("created_at ASC").Find(&subs).Error
+	return subs, err
+}
+
+func (r *webhookRepository) RecordSuccess(ctx context.Context, subscriptionID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entity.WebhookSubscription{}).
+		Where("id = ? AND consecutive_failures > 0", subscriptionID).
+		Update("consecutive_failures", 0).Error
+}
+
+func (r *webhookRepository) RecordFailure(ctx context.Context, subscriptionID uuid.UUID, disableAfter int, now time.Time) (bool, error) {
+	disabled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.WebhookSubscription{}).
+			Where("id = ?", subscriptionID).
+			Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+		if err != nil || disableAfter <= 0 {
+			return err
+		}
+		res := tx.Model(&entity.WebhookSubscription{}).
+			Where("id = ? AND is_active = ? AND consecutive_failures >= ?", subscriptionID, true, disableAfter).
+			Updates(map[string]interface{}{"is_active": false, "disabled_at": now})
+		disabled = res.RowsAffected > 0
+		return res.Error
+	})
+	return disabled, err
+}
+
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) (int64, error) {
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_key"}},
+			DoNothing: true,
+		}).
+		Create(deliveries)
+	return res.RowsAffected, res.Error
+}
+
+func (r *webhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	active := r.db.Model(&entity.WebhookSubscription{}).Select("id").Where("is_active = ?", true)
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookDeliveryPending, now).
+		Where("subscription_id IN (?)", active).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) FindDeliveryByID(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("attempt_no ASC") }).
+		First(&delivery, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries lists a subscription's deliveries, newest first
+func (r *webhookRepository) ListDeliveries(ctx context.Context, filters DeliveryFilters) ([]entity.WebhookDelivery, int64, error) {
+	var deliveries []entity.WebhookDelivery
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).Where("subscription_id = ?", filters.SubscriptionID)
+	if filters.Status != nil {
+		query = query.Where("status = ?", *filters.Status)
+	}
+	if filters.OrderID != nil {
+		query = query.Where("order_id = ?", *filters.OrderID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.Limit < 1 {
+		filters.Limit = 20
+	}
+	err := query.Order("created_at DESC").Offset((filters.Page - 1) * filters.Limit).Limit(filters.Limit).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+func (r *webhookRepository) SaveDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Omit("AttemptLog").Save(delivery).Error
+}
+
+func (r *webhookRepository) CreateAttempt(ctx context.Context, attempt *entity.WebhookDeliveryAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/entity"
+)
+
+func setupWebhookDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&entity.WebhookSubscription{}, &entity.WebhookDelivery{}, &entity.WebhookDeliveryAttempt{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	return db
+}
+
+func createSubscription(t *testing.T, repo WebhookRepository, active bool) *entity.WebhookSubscription {
+	t.Helper()
+	sub := &entity.WebhookSubscription{Name: "Hotel", URL: "https://partner.example/hook", SecretEncrypted: "x", Events: "order.status_changed", IsActive: active}
+	if err := repo.CreateSubscription(context.Background(), sub); err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	return sub
+}
+
+func TestWebhookRepository_RecordFailureDisables(t *testing.T) {
+	repo := NewWebhookRepository(setupWebhookDB(t))
+	ctx := context.Background()
+	sub := createSubscription(t, repo, true)
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		disabled, err := repo.RecordFailure(ctx, sub.ID, 3, now)
+		if err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+		if disabled != (i == 3) {
+			t.Fatalf("failure %d: disabled = %t", i, disabled)
+		}
+	}
+	// Further failures do not report disabling again
+	if disabled, _ := repo.RecordFailure(ctx, sub.ID, 3, now); disabled {
+		t.Fatalf("expected an inactive subscription not to be disabled again")
+	}
+
+	got, _ := repo.FindSubscriptionByID(ctx, sub.ID)
+	if got.IsActive || got.DisabledAt == nil || got.ConsecutiveFailures != 4 {
+		t.Fatalf("unexpected subscription: %+v", got)
+	}
+	if err := repo.RecordSuccess(ctx, sub.ID); err != nil {
+		t.Fatalf("record success: %v", err)
+	}
+	if got, _ = repo.FindSubscriptionByID(ctx, sub.ID); got.ConsecutiveFailures != 0 {
+		t.Fatalf("expected failures to reset, got %d", got.ConsecutiveFailures)
+	}
+}
+
+func TestWebhookRepository_Deliveries(t *testing.T) {
+	repo := NewWebhookRepository(setupWebhookDB(t))
+	ctx := context.Background()
+	active := createSubscription(t, repo, true)
+	inactive := createSubscription(t, repo, false)
+	now := time.Now()
+
+	newDelivery := func(sub uuid.UUID, key string, next time.Time) *entity.WebhookDelivery {
+		return &entity.WebhookDelivery{SubscriptionID: sub, EventID: uuid.New(), EventKey: key, Event: "order.status_changed", OrderID: uuid.New(), Payload: "{}", NextAttemptAt: next}
+	}
+	queued, err := repo.CreateDeliveries(ctx, []*entity.WebhookDelivery{
+		newDelivery(active.ID, "order-status:1", now.Add(-time.Minute)),
+		newDelivery(active.ID, "order-status:2", now.Add(time.Minute)),
+		newDelivery(inactive.ID, "order-status:1", now.Add(-time.Minute)),
+	})
+	if err != nil || queued != 3 {
+		t.Fatalf("expected 3 deliveries queued, got %d (err %v)", queued, err)
+	}
+	// The same event is not queued twice for a subscription
+	if queued, err = repo.CreateDeliveries(ctx, []*entity.WebhookDelivery{newDelivery(active.ID, "order-status:1", now)}); err != nil || queued != 0 {
+		t.Fatalf("expected duplicate to be skipped, got %d (err %v)", queued, err)
+	}
+
+	due, err := repo.ListDueDeliveries(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	if len(due) != 1 || due[0].SubscriptionID != active.ID || due[0].EventKey != "order-status:1" {
+		t.Fatalf("expected only the due delivery of the active subscription, got %+v", due)
+	}
+
+	if err := repo.CreateAttempt(ctx, &entity.WebhookDeliveryAttempt{DeliveryID: due[0].ID, AttemptNo: 1, DurationMs: 5}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+	got, err := repo.FindDeliveryByID(ctx, due[0].ID)
+	if err != nil || len(got.AttemptLog) != 1 {
+		t.Fatalf("expected delivery with one attempt, got %+v (err %v)", got, err)
+	}
+
+	list, total, err := repo.ListDeliveries(ctx, DeliveryFilters{SubscriptionID: active.ID, Limit: 1})
+	if err != nil || total != 2 || len(list) != 1 {
+		t.Fatalf("expected 1 of 2 deliveries, got %d of %d (err %v)", len(list), total, err)
+	}
+
+	if err := repo.DeleteSubscription(ctx, active.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.FindDeliveryByID(ctx, due[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected deliveries to be deleted with the subscription, got %v", err)
+	}
+	if err := repo.DeleteSubscription(ctx, active.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Laondry-Event"
+	HeaderDelivery  = "X-Laondry-Delivery" // delivery ID, stable across retries
+	HeaderTimestamp = "X-Laondry-Timestamp"
+	HeaderSignature = "X-Laondry-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the X-Laondry-Signature value of a body sent at timestamp
+// (Unix seconds): "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret. Partners recompute
+// it to authenticate the payload and reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was made by Sign with the same inputs
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/domain/webhook/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+)
+
+// Events partners can subscribe to
+const (
+	EventOrderStatusChanged = "order.status_changed"
+	EventPaymentSucceeded   = "payment.succeeded"
+)
+
+type WebhookService interface {
+	// OrderStatusChanged and PaymentSucceeded queue a delivery to every
+	// active subscription in scope. HandleEvent calls them for events
+	// published on the bus; they never fail the caller.
+	events.Subscriber
+	OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged)
+	PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded)
+
+	// DeliverDue sends queued deliveries, rescheduling failed ones with
+	// backoff and disabling subscriptions that keep failing
+	DeliverDue(ctx context.Context) (*DeliveryResult, error)
+	// Redeliver sends a delivery again right away, whatever its status
+	Redeliver(ctx context.Context, deliveryID uuid.UUID) (*entity.WebhookDelivery, error)
+
+	CreateSubscription(ctx context.Context, req SubscriptionRequest) (*SubscriptionResponse, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, req SubscriptionRequest) (*SubscriptionResponse, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*SubscriptionResponse, error)
+	ListSubscriptions(ctx context.Context) ([]SubscriptionResponse, error)
+	ListDeliveries(ctx context.Context, filters repository.DeliveryFilters) ([]entity.WebhookDelivery, int64, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error)
+}
+
+// OrderReader loads orders with their customer, outlet and items; the order
+// repository satisfies it
+type OrderReader interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error)
+	SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error)
+}
+
+type SubscriptionRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	URL  string `json:"url" validate:"required,url,max=500"`
+	// Secret signs the payloads; one is generated when empty. On update an
+	// empty secret keeps the current one unless RotateSecret is set.
+	Secret       string     `json:"secret" validate:"omitempty,min=16,max=200"`
+	RotateSecret bool       `json:"rotate_secret"`
+	Events       []string   `json:"events" validate:"required,min=1,dive,oneof=order.status_changed payment.succeeded"`
+	OutletID     *uuid.UUID `json:"outlet_id"`   // only orders of this outlet
+	CustomerID   *uuid.UUID `json:"customer_id"` // only orders of this customer
+	IsActive     *bool      `json:"is_active"`   // Default true; reactivating clears the failure streak
+	CreatedBy    *uuid.UUID `json:"-"`
+}
+
+type SubscriptionResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	OutletID            *uuid.UUID `json:"outlet_id"`
+	CustomerID          *uuid.UUID `json:"customer_id"`
+	IsActive            bool       `json:"is_active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// Secret is only returned when it is created or rotated
+	Secret string `json:"secret,omitempty"`
+}
+
+type DeliveryResult struct {
+	Succeeded int `json:"succeeded"`
+	Retried   int `json:"retried"`
+	Failed    int `json:"failed"`
+	Disabled  int `json:"disabled"` // subscriptions disabled in this run
+}
+
+// Event is the JSON body posted to partners
+type Event struct {
+	ID        uuid.UUID `json:"id"` // same for every subscription told about the event
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+type EventData struct {
+	Order        *entity.Order     `json:"order"` // snapshot when the event happened, without status history
+	Customer     *CustomerSnapshot `json:"customer"`
+	StatusChange *StatusChange     `json:"status_change,omitempty"`
+	Payment      *PaymentSnapshot  `json:"payment,omitempty"`
+}
+
+// CustomerSnapshot is the customer's contact details; the order's customer
+// record is left out so balances and account state are not shared
+type CustomerSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	FullName    string    `json:"full_name"`
+	Email       *string   `json:"email"`
+	PhoneNumber *string   `json:"phone_number"`
+}
+
+type StatusChange struct {
+	FromStatus *string    `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	Note       *string    `json:"note"`
+	ChangedBy  *uuid.UUID `json:"changed_by"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+type PaymentSnapshot struct {
+	ID              uuid.UUID  `json:"id"`
+	PaymentOrderID  string     `json:"payment_order_id"`
+	PaymentMethod   *string    `json:"payment_method"`
+	PaymentType     *string    `json:"payment_type"`
+	GrossAmount     float64    `json:"gross_amount"`
+	SurchargeAmount float64    `json:"surcharge_amount"`
+	Status          string     `json:"status"`
+	SettlementTime  *time.Time `json:"settlement_time"`
+}
@@ -0,0 +1,572 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/webhook/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	"laondry-order-service/internal/metrics"
+	"laondry-order-service/internal/retry"
+	appErrors "laondry-order-service/pkg/errors"
+	"laondry-order-service/pkg/secret"
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultTimeout     = 10 * time.Second
+	deliveryBatchSize  = 50
+	maxResponseBody    = 2048 // bytes of the partner's response kept in the attempt log
+)
+
+// backoff schedules retries of failed deliveries
+var backoff = retry.Backoff{Base: time.Minute, Max: 6 * time.Hour}
+
+var errDisabled = appErrors.UnprocessableEntity("Partner webhooks are disabled (WEBHOOK_SECRETS_KEY not configured)", nil)
+
+type webhookService struct {
+	repo         repository.WebhookRepository
+	orders       OrderReader
+	cipher       *secret.Cipher
+	client       *http.Client
+	maxAttempts  int
+	disableAfter int
+	now          func() time.Time
+}
+
+// NewWebhookService sends partner webhooks signed with secrets encrypted by
+// cipher. A nil cipher disables webhooks: no events are queued and
+// subscriptions cannot be created.
+func NewWebhookService(repo repository.WebhookRepository, orders OrderReader, cipher *secret.Cipher, cfg config.WebhookConfig) WebhookService {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	return &webhookService{
+		repo:   repo,
+		orders: orders,
+		cipher: cipher,
+		client: &http.Client{
+			Timeout: timeout,
+			// A redirect is a misconfigured endpoint; report it instead of
+			// posting the payload somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		maxAttempts:  maxAttempts,
+		disableAfter: cfg.DisableAfterFailures,
+		now:          time.Now,
+	}
+}
+
+func (s *webhookService) HandleEvent(ctx context.Context, evt events.Event) {
+	switch e := evt.(type) {
+	case events.OrderStatusChanged:
+		s.OrderStatusChanged(ctx, e)
+	case events.PaymentSucceeded:
+		s.PaymentSucceeded(ctx, e)
+	}
+}
+
+func (s *webhookService) OrderStatusChanged(ctx context.Context, change events.OrderStatusChanged) {
+	s.queue(ctx, change.OrderID, "order-status:"+change.StatusLogID.String(), EventOrderStatusChanged, func(d *EventData) {
+		changedAt := change.ChangedAt
+		if changedAt.IsZero() {
+			changedAt = s.now()
+		}
+		d.StatusChange = &StatusChange{
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			Note:       change.Note,
+			ChangedBy:  change.ChangedBy,
+			ChangedAt:  changedAt,
+		}
+	})
+}
+
+func (s *webhookService) PaymentSucceeded(ctx context.Context, payment events.PaymentSucceeded) {
+	s.queue(ctx, payment.OrderID, "payment:"+payment.PaymentID.String(), EventPaymentSucceeded, func(d *EventData) {
+		d.Payment = &PaymentSnapshot{
+			ID:              payment.PaymentID,
+			PaymentOrderID:  payment.PaymentOrderID,
+			PaymentMethod:   payment.PaymentMethod,
+			PaymentType:     payment.PaymentType,
+			GrossAmount:     payment.GrossAmount,
+			SurchargeAmount: payment.SurchargeAmount,
+			Status:          payment.Status,
+			SettlementTime:  payment.SettlementTime,
+		}
+	})
+}
+
+// queue renders the event once and queues a delivery to every subscription
+// that wants it for the order's outlet and customer
+func (s *webhookService) queue(ctx context.Context, orderID uuid.UUID, eventKey, event string, fill func(*EventData)) {
+	if s.cipher == nil {
+		return
+	}
+	subs, err := s.repo.ListActiveSubscriptions(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to list subscriptions", "event", event, "error", err)
+		return
+	}
+	subs = slices.DeleteFunc(subs, func(sub entity.WebhookSubscription) bool {
+		return !slices.Contains(sub.EventList(), event)
+	})
+	if len(subs) == 0 {
+		return
+	}
+
+	order, err := s.orders.FindByID(ctx, orderID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to load order", "order_id", orderID, "event", event, "error", err)
+		return
+	}
+	paid, err := s.orders.SumPaidAmount(ctx, orderID)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to load payments of order", "order_id", orderID, "error", err)
+		return
+	}
+	order.SetPaidAmount(paid)
+
+	var targets []entity.WebhookSubscription
+	for _, sub := range subs {
+		if sub.Matches(event, order.OutletID, order.CustomerID) {
+			targets = append(targets, sub)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	now := s.now()
+	evt := Event{ID: uuid.New(), Event: event, CreatedAt: now, Data: EventData{Order: order}}
+	if order.Customer != nil {
+		evt.Data.Customer = &CustomerSnapshot{
+			ID:          order.Customer.ID,
+			FullName:    order.Customer.FullName,
+			Email:       order.Customer.Email,
+			PhoneNumber: order.Customer.PhoneNumber,
+		}
+	}
+	order.Customer = nil
+	order.StatusLogs = nil
+	fill(&evt.Data)
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to encode event", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+		return
+	}
+
+	deliveries := make([]*entity.WebhookDelivery, 0, len(targets))
+	for _, sub := range targets {
+		deliveries = append(deliveries, &entity.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        evt.ID,
+			EventKey:       eventKey,
+			Event:          event,
+			OrderID:        order.ID,
+			Payload:        string(payload),
+			NextAttemptAt:  now,
+		})
+	}
+	queued, err := s.repo.CreateDeliveries(ctx, deliveries)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to queue deliveries", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "error", err)
+		return
+	}
+	if queued > 0 {
+		slog.InfoContext(ctx, "[Webhook] Deliveries queued", "event", event, "order_id", order.ID, "order_no", order.OrderNo, "subscriptions", queued)
+	}
+}
+
+func (s *webhookService) DeliverDue(ctx context.Context) (*DeliveryResult, error) {
+	due, err := s.repo.ListDueDeliveries(ctx, s.now(), deliveryBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("list due webhooks: %w", err)
+	}
+
+	subs := make(map[uuid.UUID]*entity.WebhookSubscription)
+	disabled := 0
+	policy := retry.Policy{Backoff: backoff, MaxAttempts: s.maxAttempts}
+	counts := retry.Due(ctx, due, policy, s.now,
+		func(ctx context.Context, d *entity.WebhookDelivery) (int, error) {
+			sub, ok := subs[d.SubscriptionID]
+			if !ok {
+				var err error
+				if sub, err = s.repo.FindSubscriptionByID(ctx, d.SubscriptionID); err != nil {
+					slog.WarnContext(ctx, "[Webhook] Failed to load subscription", "subscription_id", d.SubscriptionID, "error", err)
+					return d.Attempts, retry.ErrSkip
+				}
+				subs[d.SubscriptionID] = sub
+			}
+			if !sub.IsActive { // disabled earlier in this run
+				return d.Attempts, retry.ErrSkip
+			}
+			err := s.attempt(ctx, sub, d, false)
+			return d.Attempts, err
+		},
+		func(ctx context.Context, d *entity.WebhookDelivery, o retry.Outcome, sendErr error, now, next time.Time) {
+			if s.settle(ctx, subs[d.SubscriptionID], d, o, sendErr, now, next) {
+				disabled++
+			}
+		})
+	return &DeliveryResult{Succeeded: counts.Done, Retried: counts.Retried, Failed: counts.GaveUp, Disabled: disabled}, nil
+}
+
+// settle saves the outcome of a scheduled delivery and counts the
+// subscription's failures in a row; it reports whether the subscription got
+// disabled for reaching disableAfter
+func (s *webhookService) settle(ctx context.Context, sub *entity.WebhookSubscription, d *entity.WebhookDelivery, o retry.Outcome, sendErr error, now, next time.Time) bool {
+	switch o {
+	case retry.Done:
+		d.Status = entity.WebhookDeliverySucceeded
+		d.DeliveredAt = &now
+	case retry.GiveUp:
+		slog.WarnContext(ctx, "[Webhook] Delivery failed permanently", "delivery_id", d.ID, "event", d.Event,
+			"order_id", d.OrderID, "url", sub.URL, "attempts", d.Attempts, "error", sendErr)
+		d.Status = entity.WebhookDeliveryFailed
+	case retry.Retry:
+		slog.InfoContext(ctx, "[Webhook] Delivery attempt failed", "delivery_id", d.ID, "event", d.Event,
+			"order_id", d.OrderID, "url", sub.URL, "attempts", d.Attempts, "error", sendErr)
+		d.NextAttemptAt = next
+	}
+	if err := s.repo.SaveDelivery(ctx, d); err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to save delivery", "delivery_id", d.ID, "error", err)
+	}
+
+	if o == retry.Done {
+		if sub.ConsecutiveFailures > 0 {
+			sub.ConsecutiveFailures = 0
+			if err := s.repo.RecordSuccess(ctx, sub.ID); err != nil {
+				slog.WarnContext(ctx, "[Webhook] Failed to reset failures of subscription", "subscription_id", sub.ID, "error", err)
+			}
+		}
+		return false
+	}
+	sub.ConsecutiveFailures++
+	disabled, err := s.repo.RecordFailure(ctx, sub.ID, s.disableAfter, now)
+	if err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to record failure of subscription", "subscription_id", sub.ID, "error", err)
+	}
+	if disabled {
+		slog.WarnContext(ctx, "[Webhook] Subscription disabled after failed attempts in a row",
+			"subscription_id", sub.ID, "url", sub.URL, "failures", s.disableAfter)
+		sub.IsActive = false
+		sub.DisabledAt = &now
+	}
+	return disabled
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, deliveryID uuid.UUID) (*entity.WebhookDelivery, error) {
+	if s.cipher == nil {
+		return nil, errDisabled
+	}
+	d, err := s.repo.FindDeliveryByID(ctx, deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appErrors.NotFound("Webhook delivery not found", err)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load webhook delivery", err)
+	}
+	sub, err := s.repo.FindSubscriptionByID(ctx, d.SubscriptionID)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load webhook subscription", err)
+	}
+
+	// Manual attempts neither count towards disabling the subscription nor
+	// change the retry schedule of a delivery that is still pending
+	if err := s.attempt(ctx, sub, d, true); err == nil {
+		now := s.now()
+		d.Status = entity.WebhookDeliverySucceeded
+		d.DeliveredAt = &now
+		if err := s.repo.RecordSuccess(ctx, sub.ID); err != nil {
+			slog.WarnContext(ctx, "[Webhook] Failed to reset failures of subscription", "subscription_id", sub.ID, "error", err)
+		}
+	}
+	if err := s.repo.SaveDelivery(ctx, d); err != nil {
+		return nil, appErrors.InternalServerError("Failed to save webhook delivery", err)
+	}
+
+	slog.InfoContext(ctx, "[Webhook] Delivery redelivered", "delivery_id", d.ID, "order_id", d.OrderID, "url", sub.URL, "status", d.Status)
+	return s.GetDelivery(ctx, d.ID)
+}
+
+// attempt posts the delivery once and logs the attempt. It updates the
+// delivery's attempt counts and last result but not its status. Manual
+// attempts are counted apart so they do not use up automatic retries or
+// lengthen the backoff.
+func (s *webhookService) attempt(ctx context.Context, sub *entity.WebhookSubscription, d *entity.WebhookDelivery, manual bool) error {
+	if manual {
+		d.ManualAttempts++
+	} else {
+		d.Attempts++
+	}
+	rec := &entity.WebhookDeliveryAttempt{DeliveryID: d.ID, AttemptNo: d.Attempts + d.ManualAttempts, Manual: manual}
+
+	start := time.Now()
+	code, body, err := s.post(ctx, sub, d)
+	rec.DurationMs = time.Since(start).Milliseconds()
+
+	d.LastStatusCode = nil
+	d.LastError = nil
+	if code != 0 {
+		rec.StatusCode = &code
+		d.LastStatusCode = &code
+	}
+	if body != "" {
+		rec.ResponseBody = &body
+	}
+	outcome := "delivered"
+	if err != nil {
+		msg := err.Error()
+		rec.Error = &msg
+		d.LastError = &msg
+		outcome = "failed"
+	}
+	metrics.WebhookDeliveries.WithLabelValues(d.Event, outcome).Inc()
+	if err := s.repo.CreateAttempt(ctx, rec); err != nil {
+		slog.WarnContext(ctx, "[Webhook] Failed to record delivery attempt", "delivery_id", d.ID, "attempt", rec.AttemptNo, "error", err)
+	}
+	return err
+}
+
+// post sends the signed payload; anything but a 2xx response is an error
+func (s *webhookService) post(ctx context.Context, sub *entity.WebhookSubscription, d *entity.WebhookDelivery) (int, string, error) {
+	key, err := s.cipher.Decrypt(sub.SecretEncrypted)
+	if err != nil {
+		return 0, "", fmt.Errorf("decrypt signing secret: %w", err)
+	}
+
+	body := []byte(d.Payload)
+	timestamp := s.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, strings.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Laondry-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(key, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*SubscriptionResponse, error) {
+	if s.cipher == nil {
+		return nil, errDisabled
+	}
+	if err := checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	secretValue, encrypted, err := s.newSecret(req.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &entity.WebhookSubscription{
+		Name:            strings.TrimSpace(req.Name),
+		URL:             strings.TrimSpace(req.URL),
+		SecretEncrypted: encrypted,
+		Events:          joinEvents(req.Events),
+		OutletID:        req.OutletID,
+		CustomerID:      req.CustomerID,
+		IsActive:        req.IsActive == nil || *req.IsActive,
+		CreatedBy:       req.CreatedBy,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, appErrors.InternalServerError("Failed to create webhook subscription", err)
+	}
+
+	slog.InfoContext(ctx, "[Webhook] Subscription created", "subscription_id", sub.ID, "url", sub.URL, "events", sub.Events)
+	resp := subscriptionResponse(sub)
+	resp.Secret = secretValue
+	return resp, nil
+}
+
+func (s *webhookService) UpdateSubscription(ctx context.Context, id uuid.UUID, req SubscriptionRequest) (*SubscriptionResponse, error) {
+	sub, err := s.findSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	var secretValue string
+	if req.Secret != "" || req.RotateSecret {
+		if s.cipher == nil {
+			return nil, errDisabled
+		}
+		if secretValue, sub.SecretEncrypted, err = s.newSecret(req.Secret); err != nil {
+			return nil, err
+		}
+	}
+	sub.Name = strings.TrimSpace(req.Name)
+	sub.URL = strings.TrimSpace(req.URL)
+	sub.Events = joinEvents(req.Events)
+	sub.OutletID = req.OutletID
+	sub.CustomerID = req.CustomerID
+	if req.IsActive != nil {
+		if *req.IsActive && !sub.IsActive {
+			sub.ConsecutiveFailures = 0
+			sub.DisabledAt = nil
+		}
+		sub.IsActive = *req.IsActive
+	}
+	if err := s.repo.SaveSubscription(ctx, sub); err != nil {
+		return nil, appErrors.InternalServerError("Failed to update webhook subscription", err)
+	}
+
+	slog.InfoContext(ctx, "[Webhook] Subscription updated", "subscription_id", sub.ID, "active", sub.IsActive, "secret_rotated", secretValue != "")
+	resp := subscriptionResponse(sub)
+	resp.Secret = secretValue
+	return resp, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	err := s.repo.DeleteSubscription(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return appErrors.NotFound("Webhook subscription not found", err)
+	}
+	if err != nil {
+		return appErrors.InternalServerError("Failed to delete webhook subscription", err)
+	}
+	slog.InfoContext(ctx, "[Webhook] Subscription deleted", "subscription_id", id)
+	return nil
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id uuid.UUID) (*SubscriptionResponse, error) {
+	sub, err := s.findSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionResponse(sub), nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]SubscriptionResponse, error) {
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to list webhook subscriptions", err)
+	}
+	list := make([]SubscriptionResponse, 0, len(subs))
+	for i := range subs {
+		list = append(list, *subscriptionResponse(&subs[i]))
+	}
+	return list, nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, filters repository.DeliveryFilters) ([]entity.WebhookDelivery, int64, error) {
+	if _, err := s.findSubscription(ctx, filters.SubscriptionID); err != nil {
+		return nil, 0, err
+	}
+	deliveries, total, err := s.repo.ListDeliveries(ctx, filters)
+	if err != nil {
+		return nil, 0, appErrors.InternalServerError("Failed to list webhook deliveries", err)
+	}
+	return deliveries, total, nil
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error) {
+	d, err := s.repo.FindDeliveryByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appErrors.NotFound("Webhook delivery not found", err)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load webhook delivery", err)
+	}
+	return d, nil
+}
+
+func (s *webhookService) findSubscription(ctx context.Context, id uuid.UUID) (*entity.WebhookSubscription, error) {
+	sub, err := s.repo.FindSubscriptionByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appErrors.NotFound("Webhook subscription not found", err)
+	}
+	if err != nil {
+		return nil, appErrors.InternalServerError("Failed to load webhook subscription", err)
+	}
+	return sub, nil
+}
+
+// newSecret encrypts the given signing secret, or a generated one when empty
+func (s *webhookService) newSecret(value string) (plain, encrypted string, err error) {
+	if value = strings.TrimSpace(value); value == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return "", "", appErrors.InternalServerError("Failed to generate signing secret", err)
+		}
+		value = "whsec_" + hex.EncodeToString(buf)
+	}
+	encrypted, err = s.cipher.Encrypt(value)
+	if err != nil {
+		return "", "", appErrors.InternalServerError("Failed to encrypt signing secret", err)
+	}
+	return value, encrypted, nil
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return appErrors.BadRequest("url must be an absolute http or https URL", err)
+	}
+	return nil
+}
+
+func joinEvents(events []string) string {
+	var list []string
+	for _, e := range events {
+		if e = strings.TrimSpace(e); e != "" && !slices.Contains(list, e) {
+			list = append(list, e)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+func subscriptionResponse(sub *entity.WebhookSubscription) *SubscriptionResponse {
+	return &SubscriptionResponse{
+		ID:                  sub.ID,
+		Name:                sub.Name,
+		URL:                 sub.URL,
+		Events:              sub.EventList(),
+		OutletID:            sub.OutletID,
+		CustomerID:          sub.CustomerID,
+		IsActive:            sub.IsActive,
+		ConsecutiveFailures: sub.ConsecutiveFailures,
+		DisabledAt:          sub.DisabledAt,
+		CreatedAt:           sub.CreatedAt,
+		UpdatedAt:           sub.UpdatedAt,
+	}
+}
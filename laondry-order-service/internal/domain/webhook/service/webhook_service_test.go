@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/webhook/repository"
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/events"
+	appErrors "laondry-order-service/pkg/errors"
+	"laondry-order-service/pkg/secret"
+)
+
+type fakeOrderReader struct {
+	order *entity.Order
+	paid  float64
+}
+
+func (f *fakeOrderReader) FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+	o := *f.order
+	return &o, nil
+}
+
+func (f *fakeOrderReader) SumPaidAmount(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	return f.paid, nil
+}
+
+// partner records the webhooks it receives and answers with status
+type partner struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (p *partner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, r)
+	p.bodies = append(p.bodies, body)
+	w.WriteHeader(p.status)
+	w.Write([]byte("ack"))
+}
+
+func setupWebhookService(t *testing.T, orders OrderReader, cfg config.WebhookConfig) *webhookService {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&entity.WebhookSubscription{}, &entity.WebhookDelivery{}, &entity.WebhookDeliveryAttempt{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+
+	cipher, err := secret.NewCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+	return NewWebhookService(repository.NewWebhookRepository(db), orders, cipher, cfg).(*webhookService)
+}
+
+func testOrder() *entity.Order {
+	customerID := uuid.New()
+	phone := "081234567890"
+	return &entity.Order{
+		ID:         uuid.New(),
+		OrderNo:    "ORD-001",
+		Status:     "COMPLETED",
+		CustomerID: customerID,
+		OutletID:   uuid.New(),
+		Customer:   &entity.User{ID: customerID, FullName: "Hotel Melati", PhoneNumber: &phone, Balance: 900000},
+		StatusLogs: []entity.OrderStatusLog{{ToStatus: "NEW"}},
+		GrandTotal: 50000,
+	}
+}
+
+func subscribe(t *testing.T, svc *webhookService, req SubscriptionRequest) *SubscriptionResponse {
+	t.Helper()
+	if req.Name == "" {
+		req.Name = "Partner"
+	}
+	sub, err := svc.CreateSubscription(context.Background(), req)
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	return sub
+}
+
+func TestWebhookService_DeliversSignedEventsInScope(t *testing.T) {
+	order := testOrder()
+	p := &partner{status: http.StatusOK}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	svc := setupWebhookService(t, &fakeOrderReader{order: order, paid: 20000}, config.WebhookConfig{})
+	ctx := context.Background()
+
+	inScope := subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}, CustomerID: &order.CustomerID, Secret: "0123456789abcdef0123"})
+	otherOutlet := uuid.New()
+	subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}, OutletID: &otherOutlet})
+	subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventPaymentSucceeded}})
+	inactive := false
+	subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}, IsActive: &inactive})
+
+	from := "IN_PROGRESS"
+	change := events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, FromStatus: &from, ToStatus: "COMPLETED", ChangedAt: time.Now()}
+	svc.OrderStatusChanged(ctx, change)
+	// Redelivered events are not queued twice
+	svc.OrderStatusChanged(ctx, change)
+
+	result, err := svc.DeliverDue(ctx)
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if result.Succeeded != 1 || result.Retried+result.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(p.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(p.requests))
+	}
+
+	req, body := p.requests[0], p.bodies[0]
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify(inScope.Secret, timestamp, body, req.Header.Get(HeaderSignature)) {
+		t.Fatalf("signature %q does not verify", req.Header.Get(HeaderSignature))
+	}
+	if req.Header.Get(HeaderEvent) != EventOrderStatusChanged || req.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+
+	var evt Event
+	if err := json.Unmarshal(body, &evt); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if evt.Event != EventOrderStatusChanged || evt.Data.StatusChange == nil || *evt.Data.StatusChange.FromStatus != "IN_PROGRESS" || evt.Data.StatusChange.ToStatus != "COMPLETED" {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+	if evt.Data.Order == nil || evt.Data.Order.OrderNo != "ORD-001" || evt.Data.Order.OutstandingAmount != 30000 {
+		t.Fatalf("unexpected order snapshot: %+v", evt.Data.Order)
+	}
+	if evt.Data.Order.Customer != nil || evt.Data.Order.StatusLogs != nil {
+		t.Fatalf("expected customer record and status history to be left out, got %+v", evt.Data.Order)
+	}
+	if evt.Data.Customer == nil || evt.Data.Customer.FullName != "Hotel Melati" || *evt.Data.Customer.PhoneNumber != "081234567890" {
+		t.Fatalf("unexpected customer snapshot: %+v", evt.Data.Customer)
+	}
+
+	deliveries, total, err := svc.ListDeliveries(ctx, repository.DeliveryFilters{SubscriptionID: inScope.ID})
+	if err != nil || total != 1 {
+		t.Fatalf("expected one delivery, got %d (err %v)", total, err)
+	}
+	d := deliveries[0]
+	if d.Status != entity.WebhookDeliverySucceeded || d.Attempts != 1 || d.DeliveredAt == nil || *d.LastStatusCode != http.StatusOK {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+	if req.Header.Get(HeaderDelivery) != d.ID.String() {
+		t.Fatalf("expected delivery header %s, got %s", d.ID, req.Header.Get(HeaderDelivery))
+	}
+}
+
+func TestWebhookService_PaymentSucceeded(t *testing.T) {
+	order := testOrder()
+	p := &partner{status: http.StatusNoContent}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	svc := setupWebhookService(t, &fakeOrderReader{order: order, paid: 50000}, config.WebhookConfig{})
+	ctx := context.Background()
+	subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventPaymentSucceeded}, OutletID: &order.OutletID})
+
+	method := "qris"
+	svc.PaymentSucceeded(ctx, events.PaymentSucceeded{PaymentID: uuid.New(), OrderID: order.ID, PaymentOrderID: "ORD-001-1", PaymentMethod: &method, GrossAmount: 50000, Status: "SUCCESS"})
+	if result, err := svc.DeliverDue(ctx); err != nil || result.Succeeded != 1 {
+		t.Fatalf("expected one delivery, got %+v (err %v)", result, err)
+	}
+
+	var evt Event
+	if err := json.Unmarshal(p.bodies[0], &evt); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if evt.Event != EventPaymentSucceeded || evt.Data.Payment == nil || evt.Data.Payment.PaymentOrderID != "ORD-001-1" || evt.Data.Payment.GrossAmount != 50000 {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+	if evt.Data.StatusChange != nil || evt.Data.Order.OutstandingAmount != 0 {
+		t.Fatalf("unexpected event data: %+v", evt.Data)
+	}
+}
+
+func TestWebhookService_RetriesThenDisablesSubscription(t *testing.T) {
+	order := testOrder()
+	p := &partner{status: http.StatusInternalServerError}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	svc := setupWebhookService(t, &fakeOrderReader{order: order}, config.WebhookConfig{MaxAttempts: 5, DisableAfterFailures: 3})
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	sub := subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}})
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "IN_PROGRESS"})
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "COMPLETED"})
+
+	result, _ := svc.DeliverDue(ctx)
+	if result.Retried != 2 || result.Disabled != 0 {
+		t.Fatalf("expected two retries, got %+v", result)
+	}
+	// Not due again until the backoff passes
+	if result, _ := svc.DeliverDue(ctx); result.Retried != 0 {
+		t.Fatalf("expected nothing due during backoff, got %+v", result)
+	}
+
+	now = now.Add(time.Minute)
+	result, _ = svc.DeliverDue(ctx)
+	if result.Retried != 1 || result.Disabled != 1 {
+		t.Fatalf("expected the third failure to disable the subscription, got %+v", result)
+	}
+	got, _ := svc.GetSubscription(ctx, sub.ID)
+	if got.IsActive || got.DisabledAt == nil || got.ConsecutiveFailures != 3 {
+		t.Fatalf("unexpected subscription: %+v", got)
+	}
+
+	// Disabled subscriptions receive nothing, even once retries are due
+	now = now.Add(time.Hour)
+	if result, _ := svc.DeliverDue(ctx); result.Retried+result.Succeeded != 0 {
+		t.Fatalf("expected no deliveries while disabled, got %+v", result)
+	}
+
+	// Re-enabling clears the streak and resumes the pending deliveries
+	p.status = http.StatusOK
+	active := true
+	got, err := svc.UpdateSubscription(ctx, sub.ID, SubscriptionRequest{Name: "Partner", URL: server.URL, Events: []string{EventOrderStatusChanged}, IsActive: &active})
+	if err != nil || !got.IsActive || got.ConsecutiveFailures != 0 || got.DisabledAt != nil || got.Secret != "" {
+		t.Fatalf("unexpected subscription after re-enabling: %+v (err %v)", got, err)
+	}
+	if result, _ := svc.DeliverDue(ctx); result.Succeeded != 2 {
+		t.Fatalf("expected both deliveries to succeed, got %+v", result)
+	}
+	if len(p.requests) != 5 {
+		t.Fatalf("expected 5 requests, got %d", len(p.requests))
+	}
+}
+
+func TestWebhookService_FailsAfterMaxAttemptsAndRedelivers(t *testing.T) {
+	order := testOrder()
+	p := &partner{status: http.StatusBadGateway}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	svc := setupWebhookService(t, &fakeOrderReader{order: order}, config.WebhookConfig{MaxAttempts: 2})
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	sub := subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}})
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "CANCELLED"})
+
+	svc.DeliverDue(ctx)
+	now = now.Add(time.Hour)
+	if result, _ := svc.DeliverDue(ctx); result.Failed != 1 {
+		t.Fatalf("expected delivery to fail after max attempts, got %+v", result)
+	}
+
+	deliveries, _, _ := svc.ListDeliveries(ctx, repository.DeliveryFilters{SubscriptionID: sub.ID})
+	d, err := svc.GetDelivery(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("get delivery: %v", err)
+	}
+	if d.Status != entity.WebhookDeliveryFailed || len(d.AttemptLog) != 2 || *d.LastError != "endpoint returned HTTP 502" {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+	if a := d.AttemptLog[1]; a.AttemptNo != 2 || *a.StatusCode != http.StatusBadGateway || *a.ResponseBody != "ack" || a.Manual {
+		t.Fatalf("unexpected attempt: %+v", a)
+	}
+
+	p.status = http.StatusOK
+	d, err = svc.Redeliver(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if d.Status != entity.WebhookDeliverySucceeded || d.Attempts != 2 || d.ManualAttempts != 1 || d.LastError != nil || len(d.AttemptLog) != 3 || !d.AttemptLog[2].Manual {
+		t.Fatalf("unexpected delivery after redelivery: %+v", d)
+	}
+	// Retries and redeliveries send the same delivery ID and payload
+	if p.requests[0].Header.Get(HeaderDelivery) != p.requests[2].Header.Get(HeaderDelivery) || string(p.bodies[0]) != string(p.bodies[2]) {
+		t.Fatalf("expected redelivery of the same payload")
+	}
+
+	var appErr *appErrors.AppError
+	if _, err := svc.Redeliver(ctx, uuid.New()); !errors.As(err, &appErr) || appErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestWebhookService_RedeliveryKeepsRetrySchedule(t *testing.T) {
+	order := testOrder()
+	p := &partner{status: http.StatusBadGateway}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	svc := setupWebhookService(t, &fakeOrderReader{order: order}, config.WebhookConfig{MaxAttempts: 3})
+	ctx := context.Background()
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	sub := subscribe(t, svc, SubscriptionRequest{URL: server.URL, Events: []string{EventOrderStatusChanged}})
+	svc.OrderStatusChanged(ctx, events.OrderStatusChanged{StatusLogID: uuid.New(), OrderID: order.ID, ToStatus: "IN_PROGRESS"})
+	svc.DeliverDue(ctx)
+
+	deliveries, _, _ := svc.ListDeliveries(ctx, repository.DeliveryFilters{SubscriptionID: sub.ID})
+	next := deliveries[0].NextAttemptAt
+	d, err := svc.Redeliver(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if d.Status != entity.WebhookDeliveryPending || d.Attempts != 1 || d.ManualAttempts != 1 || !d.NextAttemptAt.Equal(next) {
+		t.Fatalf("expected the failed redelivery to leave the retry schedule alone, got %+v", d)
+	}
+
+	// The second automatic attempt is retried, not the last of MaxAttempts
+	now = now.Add(time.Hour)
+	if result, _ := svc.DeliverDue(ctx); result.Retried != 1 || result.Failed != 0 {
+		t.Fatalf("expected the delivery to be retried, got %+v", result)
+	}
+	d, _ = svc.GetDelivery(ctx, d.ID)
+	if d.Attempts != 2 || len(d.AttemptLog) != 3 || d.AttemptLog[2].AttemptNo != 3 || d.AttemptLog[2].Manual {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+}
+
+func TestWebhookService_Subscriptions(t *testing.T) {
+	svc := setupWebhookService(t, &fakeOrderReader{order: testOrder()}, config.WebhookConfig{})
+	ctx := context.Background()
+	var appErr *appErrors.AppError
+
+	if _, err := svc.CreateSubscription(ctx, SubscriptionRequest{Name: "FTP", URL: "ftp://partner.example", Events: []string{EventPaymentSucceeded}}); !errors.As(err, &appErr) || appErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected non-http URL to be rejected, got %v", err)
+	}
+
+	sub := subscribe(t, svc, SubscriptionRequest{URL: "https://partner.example/hook", Events: []string{EventPaymentSucceeded, EventPaymentSucceeded}})
+	if len(sub.Secret) < 32 || sub.Secret[:6] != "whsec_" || len(sub.Events) != 1 || !sub.IsActive {
+		t.Fatalf("unexpected subscription: %+v", sub)
+	}
+
+	got, err := svc.GetSubscription(ctx, sub.ID)
+	if err != nil || got.Secret != "" {
+		t.Fatalf("expected the secret not to be shown again, got %+v (err %v)", got, err)
+	}
+
+	rotated, err := svc.UpdateSubscription(ctx, sub.ID, SubscriptionRequest{Name: "Partner", URL: "https://partner.example/v2", Events: []string{EventOrderStatusChanged}, RotateSecret: true})
+	if err != nil || rotated.Secret == "" || rotated.Secret == sub.Secret || rotated.URL != "https://partner.example/v2" {
+		t.Fatalf("unexpected rotation: %+v (err %v)", rotated, err)
+	}
+
+	if err := svc.DeleteSubscription(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetSubscription(ctx, sub.ID); !errors.As(err, &appErr) || appErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+
+	disabled := NewWebhookService(svc.repo, svc.orders, nil, config.WebhookConfig{})
+	if _, err := disabled.CreateSubscription(ctx, SubscriptionRequest{Name: "x", URL: "https://partner.example", Events: []string{EventPaymentSucceeded}}); !errors.As(err, &appErr) || appErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected webhooks to be disabled without a secrets key, got %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"payment.succeeded"}`)
+	sig := Sign("secret", 1700000000, body)
+	if sig != Sign("secret", 1700000000, body) || sig[:7] != "sha256=" || len(sig) != 7+64 {
+		t.Fatalf("unexpected signature %q", sig)
+	}
+	if !Verify("secret", 1700000000, body, sig) {
+		t.Fatalf("expected signature to verify")
+	}
+	if Verify("other", 1700000000, body, sig) || Verify("secret", 1700000001, body, sig) || Verify("secret", 1700000000, []byte(`{}`), sig) {
+		t.Fatalf("expected changed secret, timestamp or body not to verify")
+	}
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey is the stored response to a request sent with an
+// Idempotency-Key header, replayed when the caller retries it
+type IdempotencyKey struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Principal    string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_idempotency_keys_principal_key" json:"principal"` // user:<id> or key:<API key id>
+	Key          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_principal_key" json:"key"`
+	Method       string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string    `gorm:"type:varchar(255);not null" json:"path"`
+	RequestHash  string    `gorm:"type:varchar(64);not null" json:"request_hash"` // SHA-256 of method, path and body
+	StatusCode   int       `gorm:"not null" json:"status_code"`
+	ContentType  string    `gorm:"type:varchar(100)" json:"content_type"`
+	ResponseBody []byte    `gorm:"not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
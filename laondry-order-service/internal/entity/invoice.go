@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutletInvoiceSetting is an outlet's invoice template and its invoice number
+// sequence. Outlets without a row use the default template and get a row with
+// their first invoice.
+type OutletInvoiceSetting struct {
+	OutletID       uuid.UUID  `gorm:"type:uuid;primary_key" json:"outlet_id"`
+	NumberPrefix   string     `gorm:"type:varchar(30)" json:"number_prefix"` // empty = INV-<outlet code>
+	LastNumber     int64      `gorm:"not null;default:0" json:"last_number"`
+	Title          string     `gorm:"type:varchar(100)" json:"title"` // empty = INVOICE / RECEIPT
+	TaxID          string     `gorm:"type:varchar(50)" json:"tax_id"` // NPWP printed under the outlet address
+	HeaderNote     string     `gorm:"type:text" json:"header_note"`
+	FooterNote     string     `gorm:"type:text" json:"footer_note"`
+	PaperSize      string     `gorm:"type:varchar(10);not null;default:'A4'" json:"paper_size"` // A4 or A5, invoices only
+	ReceiptWidthMM int        `gorm:"not null;default:80" json:"receipt_width_mm"`              // 58 or 80
+	ShowQRCode     bool       `gorm:"not null" json:"show_qr_code"`                             // no gorm default, or false would not be inserted
+	UpdatedBy      *uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (OutletInvoiceSetting) TableName() string {
+	return "outlet_invoice_settings"
+}
+
+// OrderInvoice is the invoice number issued to an order. It is issued once,
+// the first time the invoice or receipt is requested, and never changes.
+type OrderInvoice struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	OrderID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"order_id"`
+	OutletID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_order_invoices_outlet_sequence" json:"outlet_id"`
+	Sequence  int64     `gorm:"not null;uniqueIndex:idx_order_invoices_outlet_sequence" json:"sequence"`
+	InvoiceNo string    `gorm:"type:varchar(50);not null;unique" json:"invoice_no"`
+	IssuedAt  time.Time `gorm:"not null" json:"issued_at"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (OrderInvoice) TableName() string {
+	return "order_invoices"
+}
+
+func (i *OrderInvoice) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
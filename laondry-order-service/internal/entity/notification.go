@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification delivery statuses
+const (
+	NotificationPending = "PENDING" // queued or waiting for a retry
+	NotificationSent    = "SENT"
+	NotificationFailed  = "FAILED" // gave up after NOTIFY_MAX_ATTEMPTS
+)
+
+// NotificationPreference holds the channels a customer opted into. Customers
+// without a row receive no notifications.
+type NotificationPreference struct {
+	CustomerID uuid.UUID `gorm:"type:uuid;primary_key" json:"customer_id"`
+	Language   string    `gorm:"type:varchar(5);not null" json:"language"` // id or en
+	WhatsApp   bool      `gorm:"column:whatsapp;not null" json:"whatsapp"`
+	SMS        bool      `gorm:"column:sms;not null" json:"sms"`
+	Email      bool      `gorm:"not null" json:"email"`
+	Push       bool      `gorm:"not null" json:"push"`
+	PushToken  *string   `gorm:"type:varchar(255)" json:"push_token"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// NotificationDelivery is one message to a customer on one channel. Rows are
+// queued when the event happens and sent by the notification-delivery job;
+// (EventKey, Channel) is unique so an event is never sent twice.
+type NotificationDelivery struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	EventKey      string     `gorm:"type:varchar(150);not null;uniqueIndex:idx_notification_deliveries_event_channel" json:"event_key"`
+	Event         string     `gorm:"type:varchar(50);not null" json:"event"` // e.g. order.completed, payment.succeeded
+	OrderID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	CustomerID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Channel       string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_deliveries_event_channel" json:"channel"`
+	Recipient     string     `gorm:"type:varchar(255);not null" json:"recipient"`
+	Language      string     `gorm:"type:varchar(5);not null" json:"language"`
+	Subject       string     `gorm:"type:varchar(255);not null" json:"subject"`
+	Body          string     `gorm:"type:text;not null" json:"body"`
+	Status        string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
+
+func (d *NotificationDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.Status == "" {
+		d.Status = NotificationPending
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+	return nil
+}
@@ -35,6 +35,8 @@ type Order struct {
 	UpdatedAt         time.Time      `gorm:"not null" json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 	IsExpress         bool           `gorm:"-" json:"is_express"` // Virtual field, computed from items
+	PaidAmount        float64        `gorm:"-" json:"paid_amount"`        // Virtual field, settled payments minus refunds
+	OutstandingAmount float64        `gorm:"-" json:"outstanding_amount"` // Virtual field, total minus paid_amount
 
 	Customer   *User            `gorm:"foreignKey:CustomerID;references:ID" json:"customer,omitempty"`
 	Outlet     *Outlet          `gorm:"foreignKey:OutletID;references:ID" json:"outlet,omitempty"`
@@ -53,6 +55,15 @@ func (o *Order) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// SetPaidAmount fills the paid/outstanding virtual fields
+func (o *Order) SetPaidAmount(paid float64) {
+	o.PaidAmount = paid
+	o.OutstandingAmount = o.GrandTotal - paid
+	if o.OutstandingAmount < 0 {
+		o.OutstandingAmount = 0
+	}
+}
+
 // MarshalJSON adds computed fields for mobile compatibility
 func (o *Order) MarshalJSON() ([]byte, error) {
 	type Alias Order
@@ -66,12 +77,13 @@ func (o *Order) MarshalJSON() ([]byte, error) {
 	// Add status_name (map status code to display name)
 	statusName := getStatusName(o.Status)
 
-	// Check if any item is express
+	// The order is express when any item is
 	isExpress := false
-	for range o.Items {
-		// For now, we assume all items have same express status
-		// TODO: Store is_express in order_items table
-		break
+	for _, item := range o.Items {
+		if item.IsExpress {
+			isExpress = true
+			break
+		}
 	}
 	o.IsExpress = isExpress
 
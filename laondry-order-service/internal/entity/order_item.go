@@ -17,6 +17,7 @@ type OrderItem struct {
 	Qty         *int      `gorm:"type:int" json:"qty"`
 	UnitPrice   float64   `gorm:"type:decimal(12,2);not null" json:"unit_price"`
 	LineTotal   float64   `gorm:"type:decimal(12,2);not null" json:"subtotal"` // Mobile expects subtotal
+	IsExpress   bool      `gorm:"not null;default:false" json:"is_express"`
     CreatedAt   time.Time `gorm:"not null" json:"created_at"`
     UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
 
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxMessage is a side effect recorded in the same database transaction
+// as the change that caused it, delivered later by the outbox dispatcher
+type OutboxMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Topic         string     `gorm:"type:varchar(100);not null;index" json:"topic"` // e.g., order.canceled
+	AggregateID   *uuid.UUID `gorm:"type:uuid;index" json:"aggregate_id"`
+	Payload       JSONB      `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string     `gorm:"type:varchar(20);not null;default:'PENDING'" json:"status"` // PENDING, DONE, DEAD
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
+
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.Status == "" {
+		m.Status = "PENDING"
+	}
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = time.Now()
+	}
+	return nil
+}
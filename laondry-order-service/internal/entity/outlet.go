@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,6 +25,10 @@ type Outlet struct {
     UpdatedAt   time.Time      `gorm:"not null" json:"updated_at"`
     DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
+	// Payment rules
+	MinDepositPercent               float64 `gorm:"type:decimal(5,2);default:0" json:"min_deposit_percent"` // Minimum first payment as % of the order total, 0 = none
+	RequireFullPaymentForCompletion bool    `gorm:"default:false" json:"require_full_payment_for_completion"`  // Block COMPLETED (handover) until fully paid
+
 	ServicePrices []ServicePrice `gorm:"foreignKey:OutletID;constraint:OnDelete:CASCADE" json:"service_prices,omitempty"`
 	Orders        []Order        `gorm:"foreignKey:OutletID;constraint:OnDelete:RESTRICT" json:"orders,omitempty"`
 }
@@ -38,3 +43,12 @@ func (o *Outlet) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// MinDeposit is the smallest first payment accepted for an order of the
+// given total, rounded up to whole rupiah
+func (o *Outlet) MinDeposit(total float64) float64 {
+	if o == nil || o.MinDepositPercent <= 0 {
+		return 0
+	}
+	return math.Ceil(total * o.MinDepositPercent / 100)
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutletMerchantCredential holds the Midtrans merchant account a franchise
+// outlet settles to. Outlets without a row use the global MIDTRANS_* keys.
+type OutletMerchantCredential struct {
+	OutletID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"outlet_id"`
+	ServerKeyEncrypted string     `gorm:"type:text;not null" json:"-"` // AES-GCM, see MIDTRANS_CREDENTIALS_KEY
+	ClientKey          string     `gorm:"type:varchar(100);not null" json:"client_key"`
+	EnabledPayments    string     `gorm:"type:varchar(500)" json:"enabled_payments"` // CSV; empty = MIDTRANS_ENABLED_PAYMENTS
+	IsActive           bool       `gorm:"not null" json:"is_active"`                 // no gorm default, or false would not be inserted
+	UpdatedBy          *uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt          time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (OutletMerchantCredential) TableName() string {
+	return "outlet_merchant_credentials"
+}
+
+// EnabledPaymentList splits EnabledPayments
+func (c *OutletMerchantCredential) EnabledPaymentList() []string {
+	var list []string
+	for _, p := range strings.Split(c.EnabledPayments, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
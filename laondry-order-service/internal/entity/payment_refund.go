@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PaymentRefund records a refund (or chargeback) against a payment transaction
+type PaymentRefund struct {
+	ID                   uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	PaymentTransactionID uuid.UUID      `gorm:"type:uuid;not null;index" json:"payment_transaction_id"`
+	RefundKey            string         `gorm:"type:varchar(100);not null;uniqueIndex" json:"refund_key"` // Midtrans refund_key (idempotency key)
+	Amount               float64        `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Reason               *string        `gorm:"type:text" json:"reason"`
+	Status               string         `gorm:"type:varchar(30);not null;default:'PENDING'" json:"status"` // PENDING, SUCCESS, FAILED
+	Source               string         `gorm:"type:varchar(50);not null" json:"source"`                   // api, webhook, wallet
+	RequestedBy          *uuid.UUID     `gorm:"type:uuid" json:"requested_by"`
+	GatewayRefundID      *string        `gorm:"type:varchar(100)" json:"gateway_refund_id"` // Midtrans refund_chargeback_id
+	FailureReason        *string        `gorm:"type:text" json:"failure_reason"`
+	RawResponse          JSONB          `gorm:"type:jsonb" json:"raw_response"`
+	CreatedAt            time.Time      `gorm:"not null" json:"created_at"`
+	UpdatedAt            time.Time      `gorm:"not null" json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	PaymentTransaction *PaymentTransaction `gorm:"foreignKey:PaymentTransactionID;references:ID" json:"payment_transaction,omitempty"`
+}
+
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
+
+func (p *PaymentRefund) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -9,6 +9,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// PaymentTransactionTypeManual marks payments recorded at the outlet counter
+// (cash, bank transfer to the outlet account, EDC) rather than via a gateway.
+const PaymentTransactionTypeManual = "manual"
+
+// PaymentTransactionTypeWallet marks payments debited from the customer's
+// wallet balance
+const PaymentTransactionTypeWallet = "wallet"
+
 // PaymentTransaction stores all payment transaction data
 type PaymentTransaction struct {
 	ID              uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
@@ -17,7 +25,9 @@ type PaymentTransaction struct {
 	PaymentMethod   *string        `gorm:"type:varchar(50)" json:"payment_method"`                          // e.g., gopay, bank_transfer
 	PaymentType     *string        `gorm:"type:varchar(50)" json:"payment_type"`                            // e.g., e-wallet, bank_transfer
 	GrossAmount     float64        `gorm:"type:decimal(12,2);not null" json:"gross_amount"`
-	Status          string         `gorm:"type:varchar(30);not null;default:'PENDING'" json:"status"` // PENDING, SUCCESS, FAILED, EXPIRED, CANCELED
+	SurchargeAmount float64        `gorm:"type:decimal(12,2);not null;default:0" json:"surcharge_amount"` // Payment method fee paid by the customer, included in GrossAmount
+	FeeAmount       float64        `gorm:"type:decimal(12,2);not null;default:0" json:"fee_amount"`       // Estimated gateway fee kept from GrossAmount
+	Status          string         `gorm:"type:varchar(30);not null;default:'PENDING'" json:"status"` // PENDING, SUCCESS, FAILED, EXPIRED, CANCELED, REFUNDED, PARTIALLY_REFUNDED, CHARGEBACK, PARTIAL_CHARGEBACK
 	TransactionID   *string        `gorm:"type:varchar(100);index" json:"transaction_id"`             // Midtrans transaction_id
 	FraudStatus     *string        `gorm:"type:varchar(30)" json:"fraud_status"`
 	SnapToken       *string        `gorm:"type:text" json:"snap_token"`
@@ -31,6 +41,11 @@ type PaymentTransaction struct {
 	RequestPayload  JSONB          `gorm:"type:jsonb" json:"request_payload"`          // Original snap token request
 	ResponsePayload JSONB          `gorm:"type:jsonb" json:"response_payload"`         // Snap token response
 	Metadata        JSONB          `gorm:"type:jsonb" json:"metadata"`                 // Additional data
+	ReceiptNo       *string        `gorm:"type:varchar(50);index" json:"receipt_no"`   // Manual payments: counter receipt number
+	AmountTendered  *float64       `gorm:"type:decimal(12,2)" json:"amount_tendered"`  // Manual payments: amount handed over
+	ChangeAmount    *float64       `gorm:"type:decimal(12,2)" json:"change_amount"`    // Manual payments: change returned
+	RecordedBy      *uuid.UUID     `gorm:"type:uuid" json:"recorded_by"`               // Manual payments: cashier
+	LastReconciledAt *time.Time    `json:"last_reconciled_at"`                         // When the reconciler last checked it
 	CreatedAt       time.Time      `gorm:"not null" json:"created_at"`
 	UpdatedAt       time.Time      `gorm:"not null" json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
@@ -38,12 +53,34 @@ type PaymentTransaction struct {
 	Order            *Order                     `gorm:"foreignKey:OrderID;references:ID" json:"order,omitempty"`
 	StatusLogs       []PaymentStatusLog         `gorm:"foreignKey:PaymentTransactionID;constraint:OnDelete:CASCADE" json:"status_logs,omitempty"`
 	WebhookLogs      []PaymentWebhookLog        `gorm:"foreignKey:PaymentTransactionID;constraint:OnDelete:CASCADE" json:"webhook_logs,omitempty"`
+	Refunds          []PaymentRefund            `gorm:"foreignKey:PaymentTransactionID;constraint:OnDelete:CASCADE" json:"refunds,omitempty"`
 }
 
 func (PaymentTransaction) TableName() string {
 	return "payment_transactions"
 }
 
+// IsManual reports whether the payment was recorded at the counter
+func (p *PaymentTransaction) IsManual() bool {
+	return p.PaymentType != nil && *p.PaymentType == PaymentTransactionTypeManual
+}
+
+// IsWallet reports whether the payment was debited from the wallet balance
+func (p *PaymentTransaction) IsWallet() bool {
+	return p.PaymentType != nil && *p.PaymentType == PaymentTransactionTypeWallet
+}
+
+// OrderAmount is the part of GrossAmount that pays for the order, i.e.
+// without the payment method surcharge
+func (p *PaymentTransaction) OrderAmount() float64 {
+	return p.GrossAmount - p.SurchargeAmount
+}
+
+// NetAmount is what the gateway settles to us once its fee is deducted
+func (p *PaymentTransaction) NetAmount() float64 {
+	return p.GrossAmount - p.FeeAmount
+}
+
 func (p *PaymentTransaction) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
@@ -23,10 +23,14 @@ type PaymentWebhookLog struct {
 	RawPayload           JSONB          `gorm:"type:jsonb;not null" json:"raw_payload"` // Full webhook payload
 	ProcessedAt          *time.Time     `json:"processed_at"`
 	ProcessingError      *string        `gorm:"type:text" json:"processing_error"`
+	ReplayOfID           *uuid.UUID     `gorm:"type:uuid;index" json:"replay_of_id"` // Original log when this entry is a replay
+	ReplayedBy           *uuid.UUID     `gorm:"type:uuid" json:"replayed_by"`
+	DryRun               bool           `gorm:"default:false" json:"dry_run"`
 	CreatedAt            time.Time      `gorm:"not null" json:"created_at"`
 	DeletedAt            gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	PaymentTransaction *PaymentTransaction `gorm:"foreignKey:PaymentTransactionID;references:ID" json:"payment_transaction,omitempty"`
+	ReplayOf           *PaymentWebhookLog  `gorm:"foreignKey:ReplayOfID;references:ID" json:"replay_of,omitempty"`
 }
 
 func (PaymentWebhookLog) TableName() string {
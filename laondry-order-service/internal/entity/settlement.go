@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Settlement entry statuses: how a payment compares between the gateway's
+// settlement report and payment_transactions
+const (
+	SettlementMatched        = "MATCHED"
+	SettlementMissing        = "MISSING"         // settled here, not in the report
+	SettlementExtra          = "EXTRA"           // in the report, unknown here (or listed twice)
+	SettlementAmountMismatch = "AMOUNT_MISMATCH" // reported gross differs from ours
+	SettlementStatusMismatch = "STATUS_MISMATCH" // reported settled, not settled here
+)
+
+// SettlementReport is one imported gateway settlement report. Each merchant
+// account has its own reports: OutletID is the outlet whose account the
+// report is from, nil for the default account.
+type SettlementReport struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	OutletID      *uuid.UUID `gorm:"type:uuid;index" json:"outlet_id"`
+	FileName      string     `gorm:"type:varchar(255);not null" json:"file_name"`
+	PeriodStart   time.Time  `gorm:"type:date;not null" json:"period_start"`
+	PeriodEnd     time.Time  `gorm:"type:date;not null" json:"period_end"`
+	RowCount      int        `gorm:"not null" json:"row_count"`
+	MatchedCount  int        `gorm:"not null" json:"matched_count"`
+	MissingCount  int        `gorm:"not null" json:"missing_count"`
+	ExtraCount    int        `gorm:"not null" json:"extra_count"`
+	MismatchCount int        `gorm:"not null" json:"mismatch_count"` // amount and status mismatches
+	ImportedBy    *uuid.UUID `gorm:"type:uuid" json:"imported_by"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (SettlementReport) TableName() string {
+	return "settlement_reports"
+}
+
+func (r *SettlementReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// SettlementEntry is the reconciliation result of one payment on one
+// settlement day. Reported* come from the report, Expected* from the payment
+// transaction; either side is empty for EXTRA and MISSING entries.
+type SettlementEntry struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ReportID             uuid.UUID  `gorm:"type:uuid;not null;index" json:"report_id"`
+	SettlementDate       time.Time  `gorm:"type:date;not null;index" json:"settlement_date"`
+	OutletID             *uuid.UUID `gorm:"type:uuid;index" json:"outlet_id"`
+	PaymentTransactionID *uuid.UUID `gorm:"type:uuid;index" json:"payment_transaction_id"`
+	PaymentOrderID       string     `gorm:"type:varchar(100);not null;index" json:"payment_order_id"`
+	TransactionID        *string    `gorm:"type:varchar(100)" json:"transaction_id"`
+	PaymentType          *string    `gorm:"type:varchar(50)" json:"payment_type"`
+	Status               string     `gorm:"type:varchar(30);not null;index" json:"status"`
+	ReportedAmount       *float64   `gorm:"type:decimal(12,2)" json:"reported_amount"`
+	ReportedFee          *float64   `gorm:"type:decimal(12,2)" json:"reported_fee"`
+	ReportedNet          *float64   `gorm:"type:decimal(12,2)" json:"reported_net"`
+	ExpectedAmount       *float64   `gorm:"type:decimal(12,2)" json:"expected_amount"`
+	ExpectedFee          *float64   `gorm:"type:decimal(12,2)" json:"expected_fee"` // our fee estimate, see PaymentTransaction.FeeAmount
+	Note                 *string    `gorm:"type:text" json:"note"`
+	LineNo               *int       `json:"line_no"` // line in the imported file
+	RawRow               JSONB      `gorm:"type:jsonb" json:"raw_row"`
+	CreatedAt            time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (SettlementEntry) TableName() string {
+	return "settlement_entries"
+}
+
+func (e *SettlementEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
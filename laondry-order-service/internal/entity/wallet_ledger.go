@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Wallet ledger entry types
+const (
+	WalletEntryTopup   = "TOPUP"
+	WalletEntryPayment = "PAYMENT"
+	WalletEntryRefund  = "REFUND"
+)
+
+// ErrWalletLedgerImmutable is returned when code tries to change a ledger row
+var ErrWalletLedgerImmutable = errors.New("wallet ledger entries are append-only")
+
+// WalletLedgerEntry is one movement of a user's wallet balance. Amount is
+// signed (credits positive, debits negative) and BalanceAfter is the running
+// balance right after the movement. Rows are never updated or deleted.
+type WalletLedgerEntry struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID               uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	EntryType            string     `gorm:"type:varchar(20);not null" json:"entry_type"` // TOPUP, PAYMENT, REFUND
+	Amount               float64    `gorm:"type:decimal(12,2);not null" json:"amount"`
+	BalanceAfter         float64    `gorm:"type:decimal(12,2);not null" json:"balance_after"`
+	OrderID              *uuid.UUID `gorm:"type:uuid;index" json:"order_id"`
+	PaymentTransactionID *uuid.UUID `gorm:"type:uuid" json:"payment_transaction_id"`
+	TopupID              *uuid.UUID `gorm:"type:uuid" json:"topup_id"`
+	Description          *string    `gorm:"type:text" json:"description"`
+	CreatedBy            *uuid.UUID `gorm:"type:uuid" json:"created_by"`
+	CreatedAt            time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (WalletLedgerEntry) TableName() string {
+	return "wallet_ledger"
+}
+
+func (w *WalletLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+func (w *WalletLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrWalletLedgerImmutable
+}
+
+func (w *WalletLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrWalletLedgerImmutable
+}
+
+// WalletTopup is a wallet top-up paid through the payment gateway. The
+// wallet is credited once, when the top-up reaches SUCCESS.
+type WalletTopup struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	PaymentOrderID  string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"payment_order_id"` // Gateway order_id, TOPUP-...
+	Amount          float64    `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Status          string     `gorm:"type:varchar(30);not null;default:'PENDING'" json:"status"` // PENDING, SUCCESS, FAILED, EXPIRED, CANCELED
+	SnapToken       *string    `gorm:"type:varchar(255)" json:"snap_token"`
+	SnapRedirectURL *string    `gorm:"type:text" json:"snap_redirect_url"`
+	PaymentType     *string    `gorm:"type:varchar(50)" json:"payment_type"`
+	TransactionID   *string    `gorm:"type:varchar(100)" json:"transaction_id"`
+	CreditedAt      *time.Time `json:"credited_at"`
+	CreatedAt       time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (WalletTopup) TableName() string {
+	return "wallet_topups"
+}
+
+func (w *WalletTopup) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	if w.Status == "" {
+		w.Status = "PENDING"
+	}
+	return nil
+}
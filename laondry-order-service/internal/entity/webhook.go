@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "PENDING" // queued or waiting for a retry
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED" // gave up after WEBHOOK_MAX_ATTEMPTS
+)
+
+// WebhookSubscription is a partner endpoint told about order and payment
+// events. OutletID and CustomerID narrow it to one outlet or one corporate
+// customer; nil matches any.
+type WebhookSubscription struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name                string     `gorm:"type:varchar(100);not null" json:"name"`
+	URL                 string     `gorm:"type:varchar(500);not null" json:"url"`
+	SecretEncrypted     string     `gorm:"type:text;not null" json:"-"`         // AES-GCM, see WEBHOOK_SECRETS_KEY
+	Events              string     `gorm:"type:varchar(500);not null" json:"-"` // CSV, see EventList
+	OutletID            *uuid.UUID `gorm:"type:uuid;index" json:"outlet_id"`
+	CustomerID          *uuid.UUID `gorm:"type:uuid;index" json:"customer_id"`
+	IsActive            bool       `gorm:"not null" json:"is_active"`                      // no gorm default, or false would not be inserted
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"` // failed attempts since the last success
+	DisabledAt          *time.Time `json:"disabled_at"`                                    // set when auto-disabled after repeated failures
+	CreatedBy           *uuid.UUID `gorm:"type:uuid" json:"created_by"`
+	CreatedAt           time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// EventList splits Events
+func (s *WebhookSubscription) EventList() []string {
+	var list []string
+	for _, e := range strings.Split(s.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// Matches reports whether the subscription wants event for an order of the
+// given outlet and customer
+func (s *WebhookSubscription) Matches(event string, outletID, customerID uuid.UUID) bool {
+	if !s.IsActive || !slices.Contains(s.EventList(), event) {
+		return false
+	}
+	if s.OutletID != nil && *s.OutletID != outletID {
+		return false
+	}
+	return s.CustomerID == nil || *s.CustomerID == customerID
+}
+
+// WebhookDelivery is one event sent to one subscription. The payload is
+// rendered when the event happens so retries send the same snapshot;
+// (SubscriptionID, EventKey) is unique so an event is never queued twice.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_subscription_event" json:"subscription_id"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null" json:"event_id"` // same for every subscription told about the event
+	EventKey       string     `gorm:"type:varchar(150);not null;uniqueIndex:idx_webhook_deliveries_subscription_event" json:"event_key"`
+	Event          string     `gorm:"type:varchar(50);not null" json:"event"`
+	OrderID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`        // automatic attempts, see WEBHOOK_MAX_ATTEMPTS
+	ManualAttempts int        `gorm:"not null;default:0" json:"manual_attempts"` // redeliveries, which leave the retry schedule alone
+	NextAttemptAt  time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      *string    `gorm:"type:text" json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"not null" json:"updated_at"`
+
+	AttemptLog []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE" json:"attempt_log,omitempty"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.Status == "" {
+		d.Status = WebhookDeliveryPending
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+// WebhookDeliveryAttempt records one HTTP request of a delivery
+type WebhookDeliveryAttempt struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	AttemptNo    int       `gorm:"not null" json:"attempt_no"`
+	Manual       bool      `gorm:"not null" json:"manual"` // triggered by the redeliver endpoint
+	StatusCode   *int      `json:"status_code"`
+	ResponseBody *string   `gorm:"type:text" json:"response_body"` // truncated
+	Error        *string   `gorm:"type:text" json:"error"`
+	DurationMs   int64     `gorm:"not null" json:"duration_ms"`
+	CreatedAt    time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
+
+func (a *WebhookDeliveryAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Package escpos builds ESC/POS byte streams for 58 and 80 mm thermal
+// printers, together with a plain-text preview of what the printer prints.
+package escpos
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Characters per line in the printer's default font, 12 dots wide
+const (
+	Columns58 = 32
+	Columns80 = 48
+)
+
+// ColumnsFor returns the line width of a paper width in mm
+func ColumnsFor(widthMM int) int {
+	if widthMM == 58 {
+		return Columns58
+	}
+	return Columns80
+}
+
+type Align byte
+
+const (
+	AlignLeft   Align = 0
+	AlignCenter Align = 1
+	AlignRight  Align = 2
+)
+
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Writer appends commands to the byte stream and mirrors the printed text in
+// the preview. Text is sent in code page 437, the printers' default; other
+// characters print as "?".
+type Writer struct {
+	cols    int
+	buf     bytes.Buffer
+	preview strings.Builder
+	align   Align
+}
+
+// NewWriter starts a stream for a printer with the given line width
+func NewWriter(columns int) *Writer {
+	w := &Writer{cols: columns}
+	w.buf.Write([]byte{esc, '@'})    // initialize
+	w.buf.Write([]byte{esc, 't', 0}) // code page 437
+	return w
+}
+
+// Columns is the line width
+func (w *Writer) Columns() int {
+	return w.cols
+}
+
+func (w *Writer) Align(a Align) *Writer {
+	w.align = a
+	w.buf.Write([]byte{esc, 'a', byte(a)})
+	return w
+}
+
+func (w *Writer) Bold(on bool) *Writer {
+	w.buf.Write([]byte{esc, 'E', flag(on)})
+	return w
+}
+
+// Reverse prints white on black, used to make flags stand out
+func (w *Writer) Reverse(on bool) *Writer {
+	w.buf.Write([]byte{gs, 'B', flag(on)})
+	return w
+}
+
+// Tall switches double-height text, which keeps the line width
+func (w *Writer) Tall(on bool) *Writer {
+	w.buf.Write([]byte{gs, '!', flag(on)})
+	return w
+}
+
+// Line prints text, wrapped at the line width
+func (w *Writer) Line(text string) *Writer {
+	for _, line := range wrap(text, w.Columns()) {
+		w.write(line)
+	}
+	return w
+}
+
+// Pair prints label on the left and value on the right of one line; the
+// label is shortened when both do not fit
+func (w *Writer) Pair(label, value string) *Writer {
+	cols := w.Columns()
+	space := cols - utf8.RuneCountInString(value) - 1
+	label = truncate(label, max(space, 0))
+	pad := cols - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+	w.write(label + strings.Repeat(" ", max(pad, 1)) + value)
+	return w
+}
+
+// Separator prints a full line of ch
+func (w *Writer) Separator(ch string) *Writer {
+	w.write(strings.Repeat(ch, w.Columns()))
+	return w
+}
+
+// Feed prints n empty lines
+func (w *Writer) Feed(n int) *Writer {
+	w.buf.Write([]byte{esc, 'd', byte(n)})
+	for i := 0; i < n; i++ {
+		w.preview.WriteString("\n")
+	}
+	return w
+}
+
+// Barcode prints data as a CODE128 barcode with the text below it, with bars
+// as wide as the paper allows
+func (w *Writer) Barcode(data string) *Writer {
+	payload := append([]byte("{B"), encode(data)...)
+	modules := (len(data)+3)*11 + 2 // start, check and stop symbols, 11 modules each
+	width := min(max(w.cols*12/modules, 1), 3)
+	w.buf.Write([]byte{gs, 'h', 80})          // height in dots
+	w.buf.Write([]byte{gs, 'w', byte(width)}) // module width in dots
+	w.buf.Write([]byte{gs, 'H', 2})           // text below
+	w.buf.Write([]byte{gs, 'k', 73, byte(len(payload))})
+	w.buf.Write(payload)
+	w.buf.WriteByte(lf)
+
+	w.previewLine(strings.Repeat("|", min(w.Columns(), utf8.RuneCountInString(data)+10)))
+	w.previewLine(data)
+	return w
+}
+
+// QRCode prints data as a QR code (model 2, error correction M)
+func (w *Writer) QRCode(data string) *Writer {
+	payload := encode(data)
+	store := len(payload) + 3
+	w.buf.Write([]byte{gs, '(', 'k', 4, 0, 49, 65, 50, 0})                              // model 2
+	w.buf.Write([]byte{gs, '(', 'k', 3, 0, 49, 67, 6})                                  // module size
+	w.buf.Write([]byte{gs, '(', 'k', 3, 0, 49, 69, 49})                                 // error correction M
+	w.buf.Write([]byte{gs, '(', 'k', byte(store % 256), byte(store / 256), 49, 80, 48}) // store data
+	w.buf.Write(payload)
+	w.buf.Write([]byte{gs, '(', 'k', 3, 0, 49, 81, 48}) // print
+	w.buf.WriteByte(lf)
+
+	w.previewLine("[QR " + data + "]")
+	return w
+}
+
+// Cut feeds the paper past the cutter and cuts it, leaving a small hinge
+func (w *Writer) Cut() *Writer {
+	w.buf.Write([]byte{gs, 'V', 66, 0})
+	w.preview.WriteString(strings.TrimSpace(strings.Repeat("- ", w.cols/2)) + "\n")
+	return w
+}
+
+// Bytes is the ESC/POS stream
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// Preview is the printed text, aligned as on paper
+func (w *Writer) Preview() string {
+	return w.preview.String()
+}
+
+func (w *Writer) write(line string) {
+	w.buf.Write(encode(line))
+	w.buf.WriteByte(lf)
+	w.previewLine(line)
+}
+
+// previewLine aligns a line like the printer does
+func (w *Writer) previewLine(line string) {
+	pad := w.cols - utf8.RuneCountInString(line)
+	switch {
+	case pad <= 0:
+	case w.align == AlignCenter:
+		line = strings.Repeat(" ", pad/2) + line + strings.Repeat(" ", pad-pad/2)
+	case w.align == AlignRight:
+		line = strings.Repeat(" ", pad) + line
+	default:
+		line += strings.Repeat(" ", pad)
+	}
+	w.preview.WriteString(strings.TrimRight(line, " ") + "\n")
+}
+
+func flag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// encode converts text to code page 437
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := charmap.CodePage437.EncodeRune(r); ok && r >= ' ' {
+			out = append(out, b)
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// wrap breaks text into lines of at most width runes, at spaces when it can
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				r := []rune(word)
+				lines = append(lines, string(r[:width]))
+				word = string(r[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(r[:width])
+	}
+	return string(r[:width-1]) + "~"
+}
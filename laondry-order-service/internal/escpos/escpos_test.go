@@ -0,0 +1,60 @@
+package escpos
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter_Commands(t *testing.T) {
+	w := NewWriter(Columns58)
+	w.Align(AlignCenter).Bold(true).Line("Café").Bold(false).Cut()
+
+	want := []byte{
+		esc, '@', esc, 't', 0,
+		esc, 'a', 1,
+		esc, 'E', 1,
+		'C', 'a', 'f', 0x82, lf, // é in code page 437
+		esc, 'E', 0,
+		gs, 'V', 66, 0,
+	}
+	assert.Equal(t, want, w.Bytes())
+	assert.Equal(t, "              Café\n"+"- - - - - - - - - - - - - - - -\n", w.Preview())
+}
+
+func TestWriter_PairAndWrap(t *testing.T) {
+	w := NewWriter(20)
+	w.Pair("Subtotal", "Rp 75.000")
+	w.Pair("A very long service name", "Rp 1.000")
+	w.Line("Barang yang tidak diambil dalam 30 hari")
+	w.Tall(true).Line("ORD-1").Tall(false)
+
+	assert.Equal(t, ""+
+		"Subtotal   Rp 75.000\n"+
+		"A very lon~ Rp 1.000\n"+
+		"Barang yang tidak\n"+
+		"diambil dalam 30\n"+
+		"hari\n"+
+		"ORD-1\n", w.Preview())
+	assert.True(t, bytes.Contains(w.Bytes(), []byte{gs, '!', 1}))
+}
+
+func TestWriter_Barcode(t *testing.T) {
+	w := NewWriter(Columns80)
+	w.Barcode("ORD-1-2")
+
+	assert.True(t, bytes.Contains(w.Bytes(), append([]byte{gs, 'k', 73, 9}, []byte("{BORD-1-2")...)))
+	assert.True(t, bytes.Contains(w.Bytes(), []byte{gs, 'w', 3}))
+	assert.Contains(t, w.Preview(), "ORD-1-2\n")
+
+	// A long code narrows the bars to fit 58 mm paper (384 dots)
+	w = NewWriter(Columns58)
+	w.Barcode("ORD-20250324-0001-1")
+	assert.True(t, bytes.Contains(w.Bytes(), []byte{gs, 'w', 1}))
+}
+
+func TestWrap_LongWord(t *testing.T) {
+	assert.Equal(t, []string{"abcdef", "gh ij"}, wrap("abcdefgh ij", 6))
+	assert.Equal(t, []string{""}, wrap("", 6))
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Publisher publishes committed events; services depend on it rather than
+// on the Bus
+type Publisher interface {
+	Publish(ctx context.Context, evt Event)
+}
+
+// Subscriber reacts to events. It is handed every event and ignores the
+// types it does not handle. Subscribers must not fail the publisher: they
+// log their own errors and should return quickly, queueing slow work.
+type Subscriber interface {
+	HandleEvent(ctx context.Context, evt Event)
+}
+
+// SubscriberFunc adapts a function to Subscriber
+type SubscriberFunc func(ctx context.Context, evt Event)
+
+func (f SubscriberFunc) HandleEvent(ctx context.Context, evt Event) {
+	f(ctx, evt)
+}
+
+type subscription struct {
+	name string
+	sub  Subscriber
+}
+
+// Bus delivers each event synchronously to its subscribers, in the order
+// they subscribed. A subscriber that panics is logged and skipped.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers sub under name, used in logs
+func (b *Bus) Subscribe(name string, sub Subscriber) {
+	if sub == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, subscription{name: name, sub: sub})
+}
+
+func (b *Bus) Publish(ctx context.Context, evt Event) {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, s := range subs {
+		deliver(ctx, s, evt)
+	}
+}
+
+func deliver(ctx context.Context, s subscription, evt Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "[Events] Subscriber panicked", "subscriber", s.name, "event", evt.EventType(), "panic", r)
+		}
+	}()
+	s.sub.HandleEvent(ctx, evt)
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is the version of the event payloads. Fields may be added
+// without bumping it; renaming, removing or retyping a field bumps it.
+const SchemaVersion = 1
+
+// Envelope is the JSON form of an event for consumers outside the process:
+//
+//	{"id": "...", "type": "order.status_changed", "version": 1,
+//	 "occurred_at": "2025-04-21T10:00:00Z", "data": {...}}
+type Envelope struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// decoders decode the data of each event type
+var decoders = map[string]func(json.RawMessage) (Event, error){
+	TypeOrderCreated:       decode[OrderCreated],
+	TypeOrderStatusChanged: decode[OrderStatusChanged],
+	TypeOrderUpdated:       decode[OrderUpdated],
+	TypePaymentSucceeded:   decode[PaymentSucceeded],
+	TypePaymentFailed:      decode[PaymentFailed],
+	TypeRefundIssued:       decode[RefundIssued],
+}
+
+func decode[T Event](data json.RawMessage) (Event, error) {
+	var evt T
+	err := json.Unmarshal(data, &evt)
+	return evt, err
+}
+
+// NewEnvelope wraps evt in an envelope with a new ID
+func NewEnvelope(evt Event, occurredAt time.Time) (*Envelope, error) {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s event: %w", evt.EventType(), err)
+	}
+	return &Envelope{
+		ID:         uuid.New(),
+		Type:       evt.EventType(),
+		Version:    SchemaVersion,
+		OccurredAt: occurredAt.UTC(),
+		Data:       data,
+	}, nil
+}
+
+// Decode returns the typed event in the envelope
+func (e *Envelope) Decode() (Event, error) {
+	decode, ok := decoders[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", e.Type)
+	}
+	if e.Version > SchemaVersion {
+		return nil, fmt.Errorf("%s event version %d is newer than supported version %d", e.Type, e.Version, SchemaVersion)
+	}
+	evt, err := decode(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s event: %w", e.Type, err)
+	}
+	return evt, nil
+}
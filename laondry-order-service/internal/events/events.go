@@ -0,0 +1,207 @@
+// Package events defines the domain events of the order and payment
+// lifecycle and a Bus that hands them to subscribers once the change is
+// committed. Side effects (notifications, partner webhooks, live streams,
+// analytics) subscribe to the bus instead of being wired into the services.
+//
+// Events are delivered after commit and are not persisted: a crash between
+// the commit and delivery loses them. Side effects that must not be lost,
+// like refunding a canceled order or confirming a paid one, stay in the
+// transactional outbox.
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+)
+
+// Event types, used as the "type" of the JSON envelope
+const (
+	TypeOrderCreated       = "order.created"
+	TypeOrderStatusChanged = "order.status_changed"
+	TypeOrderUpdated       = "order.updated"
+	TypePaymentSucceeded   = "payment.succeeded"
+	TypePaymentFailed      = "payment.failed"
+	TypeRefundIssued       = "refund.issued"
+)
+
+// Event is one of the typed events below
+type Event interface {
+	EventType() string
+}
+
+// OrderCreated is published when an order is placed
+type OrderCreated struct {
+	OrderID    uuid.UUID  `json:"order_id"`
+	OrderNo    string     `json:"order_no"`
+	CustomerID uuid.UUID  `json:"customer_id"`
+	OutletID   uuid.UUID  `json:"outlet_id"`
+	OrderType  string     `json:"order_type"`
+	Status     string     `json:"status"`
+	GrandTotal float64    `json:"grand_total"`
+	PromisedAt *time.Time `json:"promised_at"`
+	CreatedBy  *uuid.UUID `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// OrderStatusChanged is published for every status log entry after the
+// initial NEW one, including cancellations
+type OrderStatusChanged struct {
+	StatusLogID uuid.UUID  `json:"status_log_id"`
+	OrderID     uuid.UUID  `json:"order_id"`
+	OrderNo     string     `json:"order_no"`
+	CustomerID  uuid.UUID  `json:"customer_id"`
+	OutletID    uuid.UUID  `json:"outlet_id"`
+	FromStatus  *string    `json:"from_status"`
+	ToStatus    string     `json:"to_status"`
+	ChangedBy   *uuid.UUID `json:"changed_by"`
+	Note        *string    `json:"note"`
+	ChangedAt   time.Time  `json:"changed_at"`
+}
+
+// OrderUpdated is published when an order's details or items are edited
+type OrderUpdated struct {
+	OrderID    uuid.UUID  `json:"order_id"`
+	OrderNo    string     `json:"order_no"`
+	CustomerID uuid.UUID  `json:"customer_id"`
+	OutletID   uuid.UUID  `json:"outlet_id"`
+	Status     string     `json:"status"`
+	GrandTotal float64    `json:"grand_total"`
+	PromisedAt *time.Time `json:"promised_at"`
+	UpdatedBy  *uuid.UUID `json:"updated_by"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// PaymentSucceeded is published when a payment settles, whether through the
+// gateway, at the counter or from the wallet
+type PaymentSucceeded struct {
+	PaymentID       uuid.UUID  `json:"payment_id"`
+	OrderID         uuid.UUID  `json:"order_id"`
+	PaymentOrderID  string     `json:"payment_order_id"`
+	PaymentMethod   *string    `json:"payment_method"`
+	PaymentType     *string    `json:"payment_type"`
+	GrossAmount     float64    `json:"gross_amount"`
+	SurchargeAmount float64    `json:"surcharge_amount"`
+	Status          string     `json:"status"`
+	SettlementTime  *time.Time `json:"settlement_time"`
+}
+
+// PaymentFailed is published when a pending payment fails or expires
+type PaymentFailed struct {
+	PaymentID      uuid.UUID `json:"payment_id"`
+	OrderID        uuid.UUID `json:"order_id"`
+	PaymentOrderID string    `json:"payment_order_id"`
+	PaymentMethod  *string   `json:"payment_method"`
+	PaymentType    *string   `json:"payment_type"`
+	GrossAmount    float64   `json:"gross_amount"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"` // FAILED or EXPIRED
+}
+
+// RefundIssued is published when a refund succeeds, whether we requested it
+// or the gateway reported it
+type RefundIssued struct {
+	RefundID       uuid.UUID `json:"refund_id"`
+	PaymentID      uuid.UUID `json:"payment_id"`
+	OrderID        uuid.UUID `json:"order_id"`
+	PaymentOrderID string    `json:"payment_order_id"`
+	RefundKey      string    `json:"refund_key"`
+	Amount         float64   `json:"amount"`
+	Reason         *string   `json:"reason"`
+	Source         string    `json:"source"` // api, wallet or webhook
+	PaymentStatus  string    `json:"payment_status"`
+}
+
+func (OrderCreated) EventType() string       { return TypeOrderCreated }
+func (OrderStatusChanged) EventType() string { return TypeOrderStatusChanged }
+func (OrderUpdated) EventType() string       { return TypeOrderUpdated }
+func (PaymentSucceeded) EventType() string   { return TypePaymentSucceeded }
+func (PaymentFailed) EventType() string      { return TypePaymentFailed }
+func (RefundIssued) EventType() string       { return TypeRefundIssued }
+
+func NewOrderCreated(order entity.Order) OrderCreated {
+	return OrderCreated{
+		OrderID:    order.ID,
+		OrderNo:    order.OrderNo,
+		CustomerID: order.CustomerID,
+		OutletID:   order.OutletID,
+		OrderType:  order.OrderType,
+		Status:     order.Status,
+		GrandTotal: order.GrandTotal,
+		PromisedAt: order.PromisedAt,
+		CreatedBy:  order.CreatedBy,
+		CreatedAt:  order.CreatedAt,
+	}
+}
+
+func NewOrderStatusChanged(order entity.Order, change entity.OrderStatusLog) OrderStatusChanged {
+	return OrderStatusChanged{
+		StatusLogID: change.ID,
+		OrderID:     order.ID,
+		OrderNo:     order.OrderNo,
+		CustomerID:  order.CustomerID,
+		OutletID:    order.OutletID,
+		FromStatus:  change.FromStatus,
+		ToStatus:    change.ToStatus,
+		ChangedBy:   change.ChangedBy,
+		Note:        change.Note,
+		ChangedAt:   change.ChangedAt,
+	}
+}
+
+func NewOrderUpdated(order entity.Order) OrderUpdated {
+	return OrderUpdated{
+		OrderID:    order.ID,
+		OrderNo:    order.OrderNo,
+		CustomerID: order.CustomerID,
+		OutletID:   order.OutletID,
+		Status:     order.Status,
+		GrandTotal: order.GrandTotal,
+		PromisedAt: order.PromisedAt,
+		UpdatedBy:  order.UpdatedBy,
+		UpdatedAt:  order.UpdatedAt,
+	}
+}
+
+func NewPaymentSucceeded(payment entity.PaymentTransaction) PaymentSucceeded {
+	return PaymentSucceeded{
+		PaymentID:       payment.ID,
+		OrderID:         payment.OrderID,
+		PaymentOrderID:  payment.PaymentOrderID,
+		PaymentMethod:   payment.PaymentMethod,
+		PaymentType:     payment.PaymentType,
+		GrossAmount:     payment.GrossAmount,
+		SurchargeAmount: payment.SurchargeAmount,
+		Status:          payment.Status,
+		SettlementTime:  payment.SettlementTime,
+	}
+}
+
+func NewPaymentFailed(payment entity.PaymentTransaction, previousStatus string) PaymentFailed {
+	return PaymentFailed{
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		PaymentOrderID: payment.PaymentOrderID,
+		PaymentMethod:  payment.PaymentMethod,
+		PaymentType:    payment.PaymentType,
+		GrossAmount:    payment.GrossAmount,
+		PreviousStatus: previousStatus,
+		Status:         payment.Status,
+	}
+}
+
+func NewRefundIssued(payment entity.PaymentTransaction, refund entity.PaymentRefund) RefundIssued {
+	return RefundIssued{
+		RefundID:       refund.ID,
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		PaymentOrderID: payment.PaymentOrderID,
+		RefundKey:      refund.RefundKey,
+		Amount:         refund.Amount,
+		Reason:         refund.Reason,
+		Source:         refund.Source,
+		PaymentStatus:  payment.Status,
+	}
+}
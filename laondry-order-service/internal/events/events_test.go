@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBus_DeliversInSubscriptionOrder(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe("first", SubscriberFunc(func(ctx context.Context, evt Event) {
+		got = append(got, "first:"+evt.EventType())
+	}))
+	bus.Subscribe("panics", SubscriberFunc(func(ctx context.Context, evt Event) {
+		panic("boom")
+	}))
+	bus.Subscribe("last", SubscriberFunc(func(ctx context.Context, evt Event) {
+		if e, ok := evt.(OrderStatusChanged); ok {
+			got = append(got, "last:"+e.ToStatus)
+		}
+	}))
+
+	bus.Publish(context.Background(), OrderStatusChanged{ToStatus: "READY"})
+
+	want := []string{"first:order.status_changed", "last:READY"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	from := "IN_PROGRESS"
+	evt := OrderStatusChanged{
+		StatusLogID: uuid.New(),
+		OrderID:     uuid.New(),
+		OrderNo:     "ORD-001",
+		FromStatus:  &from,
+		ToStatus:    "READY",
+		ChangedAt:   time.Date(2025, 4, 21, 10, 0, 0, 0, time.UTC),
+	}
+	env, err := NewEnvelope(evt, time.Now())
+	if err != nil {
+		t.Fatalf("new envelope: %v", err)
+	}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var decoded Envelope
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Type != TypeOrderStatusChanged || decoded.Version != SchemaVersion {
+		t.Fatalf("unexpected envelope %+v", decoded)
+	}
+	got, err := decoded.Decode()
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	change, ok := got.(OrderStatusChanged)
+	if !ok || change.StatusLogID != evt.StatusLogID || *change.FromStatus != from || !change.ChangedAt.Equal(evt.ChangedAt) {
+		t.Fatalf("unexpected event %#v", got)
+	}
+}
+
+// The JSON field names are a contract with consumers; renaming one needs a
+// new SchemaVersion
+func TestEnvelope_StableFieldNames(t *testing.T) {
+	env, err := NewEnvelope(PaymentSucceeded{PaymentID: uuid.New(), PaymentOrderID: "PAY-1", GrossAmount: 50000}, time.Now())
+	if err != nil {
+		t.Fatalf("new envelope: %v", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, field := range []string{"payment_id", "order_id", "payment_order_id", "payment_method", "payment_type", "gross_amount", "surcharge_amount", "status", "settlement_time"} {
+		if _, ok := data[field]; !ok {
+			t.Errorf("missing field %s in %v", field, data)
+		}
+	}
+}
+
+func TestEnvelope_RejectsUnknownAndNewerEvents(t *testing.T) {
+	if _, err := (&Envelope{Type: "order.teleported", Version: 1, Data: []byte(`{}`)}).Decode(); err == nil {
+		t.Fatalf("expected error for unknown type")
+	}
+	if _, err := (&Envelope{Type: TypeOrderCreated, Version: SchemaVersion + 1, Data: []byte(`{}`)}).Decode(); err == nil {
+		t.Fatalf("expected error for newer version")
+	}
+}
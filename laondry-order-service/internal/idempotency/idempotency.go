@@ -0,0 +1,205 @@
+// Package idempotency lets clients retry unsafe requests without repeating
+// them. A request sent with an Idempotency-Key header is processed once per
+// caller and key; retries within the replay window get the stored response.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+	mw "laondry-order-service/internal/middleware"
+	"laondry-order-service/internal/scheduler"
+	"laondry-order-service/pkg/response"
+)
+
+const (
+	// Header is the request header carrying the client's key
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from the store
+	ReplayedHeader = "Idempotent-Replayed"
+
+	defaultTTL   = 24 * time.Hour
+	maxKeyLength = 255
+	maxBodySize  = 1 << 20 // requests and responses larger than this are not stored
+	// lockTTL outlasts the server's write timeout, so the lock is only lost
+	// if the instance dies mid-request
+	lockTTL = 30 * time.Second
+)
+
+type Middleware struct {
+	store  Store
+	locker lock.Locker
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// New keeps responses for ttl. The locker makes concurrent requests with the
+// same key wait their turn: only one is processed, the others get 409.
+func New(store Store, locker lock.Locker, ttl time.Duration) *Middleware {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &Middleware{store: store, locker: locker, ttl: ttl, now: time.Now}
+}
+
+// Handler processes requests with an Idempotency-Key once. Must be mounted
+// after Auth: keys are scoped to the caller. Requests without the header, or
+// without a caller, pass through.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		user, _ := mw.GetUserFromContext(r.Context())
+		principal := user.Principal()
+		if key == "" || principal == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			response.BadRequest(w, "Idempotency-Key must be at most 255 characters", nil)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			response.BadRequest(w, "Failed to read request body", err.Error())
+			return
+		}
+		if len(body) > maxBodySize {
+			response.BadRequest(w, "Request body too large for an idempotent request", nil)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		unlock, ok, err := m.locker.TryLock(r.Context(), "idempotency:"+principal+":"+key, lockTTL)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "[Idempotency] Failed to lock key", "idempotency_key", key, "principal", principal, "error", err)
+			response.InternalServerError(w, "Failed to check Idempotency-Key", nil)
+			return
+		}
+		if !ok {
+			response.Conflict(w, "A request with this Idempotency-Key is still being processed")
+			return
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				slog.WarnContext(r.Context(), "[Idempotency] Failed to unlock key", "idempotency_key", key, "principal", principal, "error", err)
+			}
+		}()
+
+		stored, err := m.store.Find(r.Context(), principal, key, m.now())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "[Idempotency] Failed to load key", "idempotency_key", key, "principal", principal, "error", err)
+			response.InternalServerError(w, "Failed to check Idempotency-Key", nil)
+			return
+		}
+		if stored != nil {
+			if stored.RequestHash != hash {
+				response.Conflict(w, "Idempotency-Key was already used for a different request")
+				return
+			}
+			replay(w, stored)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if !storable(rec.status) || rec.overflow {
+			return
+		}
+		respBody := rec.body.Bytes()
+		if respBody == nil {
+			respBody = []byte{}
+		}
+		now := m.now()
+		record := &entity.IdempotencyKey{
+			Principal:    principal,
+			Key:          key,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			RequestHash:  hash,
+			StatusCode:   rec.status,
+			ContentType:  rec.Header().Get("Content-Type"),
+			ResponseBody: respBody,
+			ExpiresAt:    now.Add(m.ttl),
+			CreatedAt:    now,
+		}
+		// The response is sent; store it even if the client hung up
+		if err := m.store.Save(context.WithoutCancel(r.Context()), record); err != nil {
+			slog.WarnContext(r.Context(), "[Idempotency] Failed to store response", "idempotency_key", key, "principal", principal, "error", err)
+		}
+	})
+}
+
+// Job returns a scheduler job that deletes expired responses every interval
+func (m *Middleware) Job(interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "idempotency-cleanup",
+		Interval: interval,
+		LockTTL:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			n, err := m.store.DeleteExpired(ctx, m.now())
+			if n > 0 {
+				slog.InfoContext(ctx, "[Idempotency] Deleted expired keys", "count", n)
+			}
+			return err
+		},
+	}
+}
+
+// storable reports whether a response is final. Server errors are not
+// stored so the client can retry them; neither are conflicts and rate
+// limits, which say nothing about the request itself.
+func storable(status int) bool {
+	switch {
+	case status >= 500, status == http.StatusConflict, status == http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, stored *entity.IdempotencyKey) {
+	if stored.ContentType != "" {
+		w.Header().Set("Content-Type", stored.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.ResponseBody)
+}
+
+// recorder passes the response through and keeps a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.body.Len()+len(b) > maxBodySize {
+		r.overflow = true
+	} else if !r.overflow {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,202 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/lock"
+	mw "laondry-order-service/internal/middleware"
+)
+
+func setupMiddleware(t *testing.T) (*Middleware, Store, *time.Time) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&entity.IdempotencyKey{}))
+
+	store := NewStore(db)
+	m := New(store, lock.NewMemoryLocker(), time.Hour)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	return m, store, &now
+}
+
+// createOrder answers like the order handler, counting the orders it creates
+func createOrder(created *atomic.Int32, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if release != nil {
+			<-release
+		}
+		n := created.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true,"data":{"order_no":"ORD-` + strconv.Itoa(int(n)) + `"}}`))
+	})
+}
+
+func request(user *mw.UserClaims, key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	if user != nil {
+		req = req.WithContext(context.WithValue(req.Context(), mw.ContextUserKey, user))
+	}
+	return req
+}
+
+var customer = &mw.UserClaims{UserID: "user-1"}
+
+func TestMiddleware_ReplaysRetries(t *testing.T) {
+	m, _, _ := setupMiddleware(t)
+	var created atomic.Int32
+	handler := m.Handler(createOrder(&created, nil))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, request(customer, "key-1", `{"outlet_id":"a"}`))
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, request(customer, "key-1", `{"outlet_id":"a"}`))
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), created.Load())
+
+	// Keys are per caller
+	other := httptest.NewRecorder()
+	handler.ServeHTTP(other, request(&mw.UserClaims{UserID: "user-2"}, "key-1", `{"outlet_id":"a"}`))
+	assert.Empty(t, other.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(2), created.Load())
+}
+
+func TestMiddleware_RejectsKeyReuseWithAnotherBody(t *testing.T) {
+	m, _, _ := setupMiddleware(t)
+	var created atomic.Int32
+	handler := m.Handler(createOrder(&created, nil))
+
+	handler.ServeHTTP(httptest.NewRecorder(), request(customer, "key-1", `{"outlet_id":"a"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request(customer, "key-1", `{"outlet_id":"b"}`))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, int32(1), created.Load())
+}
+
+func TestMiddleware_ConcurrentDuplicates(t *testing.T) {
+	m, _, _ := setupMiddleware(t)
+	var created atomic.Int32
+	release := make(chan struct{})
+	handler := m.Handler(createOrder(&created, release))
+
+	first := httptest.NewRecorder()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(first, request(customer, "key-1", `{}`))
+	}()
+	// Wait until the first request holds the key
+	require.Eventually(t, func() bool {
+		unlock, ok, _ := m.locker.TryLock(context.Background(), "idempotency:user:user-1:key-1", time.Second)
+		if ok {
+			unlock()
+		}
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	dup := httptest.NewRecorder()
+	handler.ServeHTTP(dup, request(customer, "key-1", `{}`))
+	assert.Equal(t, http.StatusConflict, dup.Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, int32(1), created.Load())
+
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, request(customer, "key-1", `{}`))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_DoesNotStoreServerErrors(t *testing.T) {
+	m, _, _ := setupMiddleware(t)
+	var calls atomic.Int32
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request(customer, "key-1", `{}`))
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, request(customer, "key-1", `{}`))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_PassesThroughWithoutKeyOrCaller(t *testing.T) {
+	m, _, _ := setupMiddleware(t)
+	var created atomic.Int32
+	handler := m.Handler(createOrder(&created, nil))
+
+	for _, req := range []*http.Request{
+		request(customer, "", `{}`),
+		request(customer, "", `{}`),
+		request(nil, "key-1", `{}`),
+		request(nil, "key-1", `{}`),
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Empty(t, rr.Header().Get(ReplayedHeader))
+	}
+	assert.Equal(t, int32(4), created.Load())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request(customer, strings.Repeat("k", 256), `{}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestMiddleware_ExpiresAfterTTL(t *testing.T) {
+	m, store, now := setupMiddleware(t)
+	var created atomic.Int32
+	handler := m.Handler(createOrder(&created, nil))
+
+	handler.ServeHTTP(httptest.NewRecorder(), request(customer, "key-1", `{}`))
+	*now = now.Add(time.Hour)
+
+	// A new request after the window reuses the key
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request(customer, "key-1", `{"changed":true}`))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, int32(2), created.Load())
+
+	*now = now.Add(time.Hour)
+	require.NoError(t, m.Job(time.Hour).Run(context.Background()))
+	record, err := store.Find(context.Background(), "user:user-1", "key-1", now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, record, "expired keys are deleted")
+}
@@ -0,0 +1,55 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laondry-order-service/internal/entity"
+)
+
+type Store interface {
+	// Find returns the unexpired response stored for the caller's key, or nil
+	Find(ctx context.Context, principal, key string, now time.Time) (*entity.IdempotencyKey, error)
+	// Save stores a response, replacing an expired one for the same key
+	Save(ctx context.Context, record *entity.IdempotencyKey) error
+	// DeleteExpired removes responses past their replay window
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Find(ctx context.Context, principal, key string, now time.Time) (*entity.IdempotencyKey, error) {
+	var record entity.IdempotencyKey
+	err := s.db.WithContext(ctx).
+		Where("principal = ? AND key = ? AND expires_at > ?", principal, key, now).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *gormStore) Save(ctx context.Context, record *entity.IdempotencyKey) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "principal"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"method", "path", "request_hash", "status_code", "content_type", "response_body", "expires_at", "created_at"}),
+	}).Create(record).Error
+}
+
+func (s *gormStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&entity.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,177 @@
+// Package invoice renders order invoices and receipts as PDF. The output only
+// depends on the Document, so rendering the same order twice gives the same
+// bytes and documents can be golden-tested.
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"laondry-order-service/internal/entity"
+)
+
+// Kind selects the layout: an A4/A5 invoice or a thermal-paper receipt
+type Kind string
+
+const (
+	KindInvoice Kind = "invoice"
+	KindReceipt Kind = "receipt"
+)
+
+// Payment statuses printed on documents
+const (
+	PaymentPaid          = "PAID"
+	PaymentPartiallyPaid = "PARTIALLY_PAID"
+	PaymentUnpaid        = "UNPAID"
+)
+
+// wib is the timezone dates are printed in
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// Template is an outlet's document layout, see entity.OutletInvoiceSetting
+type Template struct {
+	Title          string // empty = INVOICE / RECEIPT
+	TaxID          string
+	HeaderNote     string
+	FooterNote     string
+	PaperSize      string // A4 or A5
+	ReceiptWidthMM int    // 58 or 80
+	ShowQRCode     bool
+}
+
+// DefaultTemplate is used by outlets without invoice settings
+func DefaultTemplate() Template {
+	return Template{PaperSize: "A4", ReceiptWidthMM: 80, ShowQRCode: true}
+}
+
+// TemplateFromSetting reads an outlet's template; nil gives the default
+func TemplateFromSetting(s *entity.OutletInvoiceSetting) Template {
+	t := DefaultTemplate()
+	if s == nil {
+		return t
+	}
+	t.Title = s.Title
+	t.TaxID = s.TaxID
+	t.HeaderNote = s.HeaderNote
+	t.FooterNote = s.FooterNote
+	t.ShowQRCode = s.ShowQRCode
+	if s.PaperSize == "A5" {
+		t.PaperSize = "A5"
+	}
+	if s.ReceiptWidthMM == 58 {
+		t.ReceiptWidthMM = 58
+	}
+	return t
+}
+
+// Document is everything printed on an invoice or receipt. Order must have
+// its Outlet, Customer, Items and Items.Addons loaded and its paid amount set.
+type Document struct {
+	Kind      Kind
+	InvoiceNo string
+	IssuedAt  time.Time
+	Order     *entity.Order
+	Template  Template
+}
+
+// Validate checks a document can be rendered
+func (d Document) Validate() error {
+	switch {
+	case d.Kind != KindInvoice && d.Kind != KindReceipt:
+		return fmt.Errorf("unknown document kind %q", d.Kind)
+	case d.Order == nil:
+		return fmt.Errorf("document has no order")
+	case d.InvoiceNo == "":
+		return fmt.Errorf("document has no invoice number")
+	}
+	return nil
+}
+
+// PaymentStatus describes how much of the order is paid
+func PaymentStatus(o *entity.Order) string {
+	switch {
+	case o.GrandTotal > 0 && o.OutstandingAmount < 0.01:
+		return PaymentPaid
+	case o.PaidAmount >= 0.01:
+		return PaymentPartiallyPaid
+	default:
+		return PaymentUnpaid
+	}
+}
+
+// FormatRupiah formats an amount the Indonesian way: Rp 1.250.000 (or
+// Rp 1.250.000,50 with cents)
+func FormatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole, frac := cents/100, cents%100
+
+	digits := fmt.Sprintf("%d", whole)
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if frac != 0 {
+		return fmt.Sprintf("%sRp %s,%02d", sign, b.String(), frac)
+	}
+	return sign + "Rp " + b.String()
+}
+
+// FormatDate prints a time in WIB, e.g. 25 Mar 2025 17:00 WIB
+func FormatDate(t time.Time) string {
+	return t.In(wib).Format("02 Jan 2006 15:04") + " WIB"
+}
+
+// itemQuantity prints an item's weight or piece count
+func itemQuantity(item entity.OrderItem) string {
+	switch {
+	case item.WeightKg != nil:
+		return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", *item.WeightKg), "0"), ".") + " kg"
+	case item.Qty != nil:
+		return fmt.Sprintf("%d pcs", *item.Qty)
+	default:
+		return "1"
+	}
+}
+
+// outletLines is the outlet's address block
+func outletLines(o *entity.Outlet, taxID string) []string {
+	if o == nil {
+		return nil
+	}
+	var lines []string
+	if o.AddressLine != nil && *o.AddressLine != "" {
+		lines = append(lines, *o.AddressLine)
+	}
+	var city []string
+	for _, p := range []*string{o.City, o.Province, o.PostalCode} {
+		if p != nil && *p != "" {
+			city = append(city, *p)
+		}
+	}
+	if len(city) > 0 {
+		lines = append(lines, strings.Join(city, ", "))
+	}
+	var contact []string
+	if o.Phone != nil && *o.Phone != "" {
+		contact = append(contact, "Tel. "+*o.Phone)
+	}
+	if o.Email != nil && *o.Email != "" {
+		contact = append(contact, *o.Email)
+	}
+	if len(contact) > 0 {
+		lines = append(lines, strings.Join(contact, " | "))
+	}
+	if taxID != "" {
+		lines = append(lines, "NPWP "+taxID)
+	}
+	return lines
+}
@@ -0,0 +1,168 @@
+package invoice
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/entity"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden PDFs in testdata")
+
+func strPtr(s string) *string { return &s }
+
+func testDocument(kind Kind) Document {
+	weight, qty := 3.5, 2
+	order := &entity.Order{
+		ID:            uuid.MustParse("8b1f2a3c-0000-4000-8000-000000000001"),
+		OrderNo:       "ORD-20250324-0001",
+		OrderType:     "PICKUP",
+		Subtotal:      75000,
+		Discount:      5000,
+		Tax:           7700,
+		DeliveryFee:   10000,
+		GrandTotal:    87700,
+		Notes:         strPtr("Pisahkan pakaian putih"),
+		CreatedAt:     time.Date(2025, 3, 24, 2, 15, 0, 0, time.UTC),
+		PickupAddress: strPtr("Jl. Kemang Raya No. 10, Jakarta Selatan"),
+		Outlet: &entity.Outlet{
+			Code:        "HQ01",
+			Name:        "Laondry Kemang",
+			Phone:       strPtr("021-555-0101"),
+			AddressLine: strPtr("Jl. Kemang Raya No. 1"),
+			City:        strPtr("Jakarta Selatan"),
+			Province:    strPtr("DKI Jakarta"),
+			PostalCode:  strPtr("12730"),
+		},
+		Customer: &entity.User{FullName: "Siti Rahayu", PhoneNumber: strPtr("081234567890")},
+		Items: []entity.OrderItem{
+			{ServiceName: "Cuci Kering Setrika", WeightKg: &weight, UnitPrice: 10000, LineTotal: 35000,
+				Addons: []entity.OrderItemAddon{{AddonName: "Pewangi Premium", Qty: 1, UnitPrice: 5000, LineTotal: 5000}}},
+			{ServiceName: "Bed Cover Besar", Qty: &qty, UnitPrice: 17500, LineTotal: 35000},
+		},
+	}
+	order.SetPaidAmount(50000)
+
+	tmpl := DefaultTemplate()
+	tmpl.TaxID = "01.234.567.8-901.000"
+	tmpl.HeaderNote = "Terima kasih telah mencuci di Laondry"
+	tmpl.FooterNote = "Barang yang tidak diambil dalam 30 hari bukan tanggung jawab kami."
+	return Document{
+		Kind:      kind,
+		InvoiceNo: "INV-HQ01-000042",
+		IssuedAt:  time.Date(2025, 3, 24, 3, 0, 0, 0, time.UTC),
+		Order:     order,
+		Template:  tmpl,
+	}
+}
+
+// assertGolden compares a rendered PDF with testdata/<name>, or rewrites it
+// with go test -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run go test ./internal/invoice -update")
+	assert.True(t, bytes.Equal(want, got), "%s differs from the golden file, inspect it and run with -update if the change is intended", name)
+}
+
+func TestRender_Golden(t *testing.T) {
+	receipt58 := testDocument(KindReceipt)
+	receipt58.Template.ReceiptWidthMM = 58
+	a5 := testDocument(KindInvoice)
+	a5.Template.PaperSize = "A5"
+	a5.Template.ShowQRCode = false
+
+	for name, doc := range map[string]Document{
+		"invoice_a4.pdf": testDocument(KindInvoice),
+		"invoice_a5.pdf": a5,
+		"receipt_80.pdf": testDocument(KindReceipt),
+		"receipt_58.pdf": receipt58,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Render(doc)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(got, []byte("%PDF-")))
+
+			again, err := Render(doc)
+			require.NoError(t, err)
+			assert.Equal(t, got, again, "rendering must be deterministic")
+
+			assertGolden(t, name, got)
+		})
+	}
+}
+
+func TestRender_Invalid(t *testing.T) {
+	doc := testDocument(KindInvoice)
+	doc.InvoiceNo = ""
+	_, err := Render(doc)
+	assert.Error(t, err)
+
+	doc = testDocument("statement")
+	_, err = Render(doc)
+	assert.Error(t, err)
+}
+
+func TestPaymentStatus(t *testing.T) {
+	order := &entity.Order{GrandTotal: 100000}
+	order.SetPaidAmount(0)
+	assert.Equal(t, PaymentUnpaid, PaymentStatus(order))
+	order.SetPaidAmount(40000)
+	assert.Equal(t, PaymentPartiallyPaid, PaymentStatus(order))
+	order.SetPaidAmount(100000)
+	assert.Equal(t, PaymentPaid, PaymentStatus(order))
+}
+
+func TestFormatRupiah(t *testing.T) {
+	assert.Equal(t, "Rp 0", FormatRupiah(0))
+	assert.Equal(t, "Rp 950", FormatRupiah(950))
+	assert.Equal(t, "Rp 1.250.000", FormatRupiah(1250000))
+	assert.Equal(t, "Rp 10.000,50", FormatRupiah(10000.5))
+	assert.Equal(t, "-Rp 5.000", FormatRupiah(-5000))
+}
+
+func TestTemplateFromSetting(t *testing.T) {
+	assert.Equal(t, DefaultTemplate(), TemplateFromSetting(nil))
+
+	tmpl := TemplateFromSetting(&entity.OutletInvoiceSetting{Title: "NOTA", PaperSize: "Letter", ReceiptWidthMM: 58})
+	assert.Equal(t, "NOTA", tmpl.Title)
+	assert.Equal(t, "A4", tmpl.PaperSize) // unsupported sizes fall back to A4
+	assert.Equal(t, 58, tmpl.ReceiptWidthMM)
+	assert.False(t, tmpl.ShowQRCode)
+}
+
+func TestThermal_Golden(t *testing.T) {
+	promised := time.Date(2025, 3, 25, 10, 0, 0, 0, time.UTC)
+	doc := testDocument(KindReceipt)
+	doc.Order.PromisedAt = &promised
+	doc.Order.Items[0].IsExpress = true
+
+	receipt, err := ThermalReceipt(doc)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(receipt.ESCPOS, []byte{0x1b, '@'}))
+	assertGolden(t, "receipt_80.txt", []byte(receipt.Preview))
+
+	tmpl := doc.Template
+	tmpl.ReceiptWidthMM = 58
+	tags, err := GarmentTags(doc.Order, tmpl)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(tags.ESCPOS, []byte{0x1d, 'V', 66, 0}), "one cut per item")
+	assert.True(t, bytes.Contains(tags.ESCPOS, []byte("{B"+TagCode(doc.Order.OrderNo, 2))))
+	assertGolden(t, "tags_58.txt", []byte(tags.Preview))
+
+	_, err = GarmentTags(&entity.Order{OrderNo: "ORD-EMPTY"}, tmpl)
+	assert.Error(t, err)
+}
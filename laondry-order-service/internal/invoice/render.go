@@ -0,0 +1,394 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+
+	"laondry-order-service/internal/entity"
+)
+
+const font = "Helvetica"
+
+// Render draws the document as a PDF
+func Render(doc Document) ([]byte, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	var pdf *gofpdf.Fpdf
+	if doc.Kind == KindReceipt {
+		// Thermal paper is one long page: draw once to measure the content,
+		// then again on a page of that height
+		width := float64(doc.Template.ReceiptWidthMM)
+		measured := newPDF(doc, "", gofpdf.SizeType{Wd: width, Ht: 5000})
+		height := drawReceipt(measured, doc)
+		pdf = newPDF(doc, "", gofpdf.SizeType{Wd: width, Ht: height})
+		drawReceipt(pdf, doc)
+	} else {
+		pdf = newPDF(doc, doc.Template.PaperSize, gofpdf.SizeType{})
+		drawInvoice(pdf, doc)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render %s %s: %w", doc.Kind, doc.InvoiceNo, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// newPDF starts a document whose metadata only depends on doc
+func newPDF(doc Document, sizeStr string, size gofpdf.SizeType) *gofpdf.Fpdf {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{OrientationStr: "P", UnitStr: "mm", SizeStr: sizeStr, Size: size})
+	pdf.SetCreationDate(doc.IssuedAt)
+	pdf.SetModificationDate(doc.IssuedAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle(doc.InvoiceNo, true)
+	if doc.Order.Outlet != nil {
+		pdf.SetAuthor(doc.Order.Outlet.Name, true)
+	}
+	return pdf
+}
+
+// writer wraps the PDF with the text encoding of the core fonts
+type writer struct {
+	pdf *gofpdf.Fpdf
+	tr  func(string) string
+}
+
+func newWriter(pdf *gofpdf.Fpdf) *writer {
+	return &writer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+func (w *writer) font(style string, size float64) {
+	w.pdf.SetFont(font, style, size)
+}
+
+// cell writes one line of text, shortened to fit w
+func (w *writer) cell(width, height float64, text, align string, ln int) {
+	w.pdf.CellFormat(width, height, w.fit(text, width), "", ln, align, false, 0, "")
+}
+
+// fit shortens text with "..." until it fits width (minus cell padding)
+func (w *writer) fit(text string, width float64) string {
+	s := w.tr(text)
+	limit := width - 2*w.pdf.GetCellMargin()
+	if width <= 0 || w.pdf.GetStringWidth(s) <= limit {
+		return s
+	}
+	for len(s) > 0 && w.pdf.GetStringWidth(s+"...") > limit {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
+
+// paragraph writes wrapped text
+func (w *writer) paragraph(width, height float64, text, align string) {
+	w.pdf.MultiCell(width, height, w.tr(text), "", align, false)
+}
+
+// row writes a label on the left and a value on the right of width
+func (w *writer) row(x, width, height float64, label, value string) {
+	w.pdf.SetX(x)
+	w.cell(width*0.55, height, label, "L", 0)
+	w.cell(width*0.45, height, value, "R", 1)
+}
+
+// qr draws the QR code of content as filled squares, merging each run of
+// dark modules into one rectangle
+func (w *writer) qr(content string, x, y, size float64) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+	module := size / float64(len(bitmap))
+	w.pdf.SetFillColor(0, 0, 0)
+	for r, line := range bitmap {
+		for c := 0; c < len(line); {
+			if !line[c] {
+				c++
+				continue
+			}
+			start := c
+			for c < len(line) && line[c] {
+				c++
+			}
+			w.pdf.Rect(x+float64(start)*module, y+float64(r)*module, float64(c-start)*module, module, "F")
+		}
+	}
+}
+
+// totals lists the amount lines shown under the items
+func totals(o *entity.Order) [][2]string {
+	lines := [][2]string{{"Subtotal", FormatRupiah(o.Subtotal)}}
+	if o.Discount > 0 {
+		lines = append(lines, [2]string{"Discount", FormatRupiah(-o.Discount)})
+	}
+	if o.Tax > 0 {
+		lines = append(lines, [2]string{"Tax", FormatRupiah(o.Tax)})
+	}
+	if o.DeliveryFee > 0 {
+		lines = append(lines, [2]string{"Delivery fee", FormatRupiah(o.DeliveryFee)})
+	}
+	return lines
+}
+
+func documentTitle(doc Document) string {
+	if doc.Template.Title != "" {
+		return doc.Template.Title
+	}
+	return strings.ToUpper(string(doc.Kind))
+}
+
+func customerName(o *entity.Order) string {
+	if o.Customer == nil {
+		return "-"
+	}
+	return o.Customer.FullName
+}
+
+// drawInvoice lays out an A4 or A5 invoice
+func drawInvoice(pdf *gofpdf.Fpdf, doc Document) {
+	o := doc.Order
+	w := newWriter(pdf)
+
+	margin, base, qrSize := 15.0, 10.0, 28.0
+	if doc.Template.PaperSize == "A5" {
+		margin, base, qrSize = 10.0, 8.0, 22.0
+	}
+	lh := base * 0.5
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	contentW := pageW - 2*margin
+
+	// Outlet on the left, document title on the right
+	top := pdf.GetY()
+	outletName := ""
+	if o.Outlet != nil {
+		outletName = o.Outlet.Name
+	}
+	w.font("B", base+6)
+	w.cell(contentW*0.6, lh*2, outletName, "L", 1)
+	w.font("", base-1)
+	for _, line := range outletLines(o.Outlet, doc.Template.TaxID) {
+		w.cell(contentW*0.6, lh, line, "L", 1)
+	}
+	bottom := pdf.GetY()
+	pdf.SetXY(margin+contentW*0.6, top)
+	w.font("B", base+8)
+	w.cell(contentW*0.4, lh*2, documentTitle(doc), "R", 1)
+	pdf.SetY(max(bottom, pdf.GetY()) + lh/2)
+
+	if doc.Template.HeaderNote != "" {
+		w.font("I", base-1)
+		w.paragraph(contentW, lh, doc.Template.HeaderNote, "L")
+	}
+	pdf.Line(margin, pdf.GetY()+lh/2, margin+contentW, pdf.GetY()+lh/2)
+	pdf.SetY(pdf.GetY() + lh)
+
+	// Invoice details and customer, with the order's QR code on the right
+	top = pdf.GetY()
+	detailW := contentW * 0.5
+	if !doc.Template.ShowQRCode {
+		qrSize = 0
+	}
+	details := [][2]string{
+		{"Invoice No.", doc.InvoiceNo},
+		{"Invoice date", FormatDate(doc.IssuedAt)},
+		{"Order No.", o.OrderNo},
+		{"Order date", FormatDate(o.CreatedAt)},
+		{"Order type", o.OrderType},
+	}
+	for _, d := range details {
+		pdf.SetX(margin)
+		w.font("", base-1)
+		w.cell(detailW*0.38, lh, d[0], "L", 0)
+		w.font("B", base-1)
+		w.cell(detailW*0.62, lh, d[1], "L", 1)
+	}
+	detailsBottom := pdf.GetY()
+
+	billX := margin + detailW
+	billW := contentW - detailW - qrSize
+	pdf.SetXY(billX, top)
+	w.font("", base-1)
+	w.cell(billW, lh, "Bill to", "L", 1)
+	pdf.SetX(billX)
+	w.font("B", base)
+	w.cell(billW, lh, customerName(o), "L", 1)
+	w.font("", base-1)
+	if o.Customer != nil && o.Customer.PhoneNumber != nil {
+		pdf.SetX(billX)
+		w.cell(billW, lh, *o.Customer.PhoneNumber, "L", 1)
+	}
+	address := o.DeliveryAddress
+	if address == nil {
+		address = o.PickupAddress
+	}
+	if address != nil && *address != "" {
+		pdf.SetX(billX)
+		w.paragraph(billW, lh, *address, "L")
+	}
+	bottom = max(detailsBottom, pdf.GetY())
+	if qrSize > 0 {
+		w.qr(o.OrderNo, margin+contentW-qrSize, top, qrSize)
+		bottom = max(bottom, top+qrSize)
+	}
+	pdf.SetY(bottom + lh)
+
+	// Items, with each item's add-ons below it
+	cols := []float64{contentW * 0.5, contentW * 0.14, contentW * 0.18, contentW * 0.18}
+	pdf.SetFillColor(235, 235, 235)
+	w.font("B", base-1)
+	for i, head := range []string{"Description", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		ln := 0
+		if i == len(cols)-1 {
+			ln = 1
+		}
+		pdf.CellFormat(cols[i], lh*1.6, w.tr(head), "B", ln, align, true, 0, "")
+	}
+	w.font("", base-1)
+	for _, item := range o.Items {
+		w.cell(cols[0], lh*1.4, item.ServiceName, "L", 0)
+		w.cell(cols[1], lh*1.4, itemQuantity(item), "R", 0)
+		w.cell(cols[2], lh*1.4, FormatRupiah(item.UnitPrice), "R", 0)
+		w.cell(cols[3], lh*1.4, FormatRupiah(item.LineTotal), "R", 1)
+		for _, addon := range item.Addons {
+			w.cell(cols[0], lh*1.2, "    + "+addon.AddonName, "L", 0)
+			w.cell(cols[1], lh*1.2, fmt.Sprintf("%d", addon.Qty), "R", 0)
+			w.cell(cols[2], lh*1.2, FormatRupiah(addon.UnitPrice), "R", 0)
+			w.cell(cols[3], lh*1.2, FormatRupiah(addon.LineTotal), "R", 1)
+		}
+	}
+	pdf.Line(margin, pdf.GetY(), margin+contentW, pdf.GetY())
+	pdf.SetY(pdf.GetY() + lh/2)
+
+	// Totals and payment status
+	totalsX, totalsW := margin+contentW*0.55, contentW*0.45
+	for _, t := range totals(o) {
+		w.row(totalsX, totalsW, lh*1.2, t[0], t[1])
+	}
+	w.font("B", base+1)
+	w.row(totalsX, totalsW, lh*1.6, "Total", FormatRupiah(o.GrandTotal))
+	w.font("", base-1)
+	w.row(totalsX, totalsW, lh*1.2, "Paid", FormatRupiah(o.PaidAmount))
+	w.font("B", base-1)
+	w.row(totalsX, totalsW, lh*1.2, "Outstanding", FormatRupiah(o.OutstandingAmount))
+	w.row(totalsX, totalsW, lh*1.2, "Payment status", strings.ReplaceAll(PaymentStatus(o), "_", " "))
+	pdf.SetY(pdf.GetY() + lh)
+
+	if o.Notes != nil && *o.Notes != "" {
+		w.font("B", base-1)
+		w.cell(contentW, lh, "Notes", "L", 1)
+		w.font("", base-1)
+		w.paragraph(contentW, lh, *o.Notes, "L")
+		pdf.SetY(pdf.GetY() + lh)
+	}
+	if doc.Template.FooterNote != "" {
+		w.font("I", base-2)
+		w.paragraph(contentW, lh, doc.Template.FooterNote, "C")
+	}
+}
+
+// drawReceipt lays out a thermal-paper receipt and returns the page height
+// it needs
+func drawReceipt(pdf *gofpdf.Fpdf, doc Document) float64 {
+	o := doc.Order
+	w := newWriter(pdf)
+
+	margin, base := 3.0, 8.0
+	if doc.Template.ReceiptWidthMM == 58 {
+		margin, base = 2.0, 7.0
+	}
+	lh := base * 0.45
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetCellMargin(0)
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	contentW := pageW - 2*margin
+	separator := func() {
+		y := pdf.GetY() + lh/2
+		pdf.SetDashPattern([]float64{0.8, 0.8}, 0)
+		pdf.Line(margin, y, margin+contentW, y)
+		pdf.SetDashPattern([]float64{}, 0)
+		pdf.SetY(y + lh/2)
+	}
+
+	if o.Outlet != nil {
+		w.font("B", base+2)
+		w.paragraph(contentW, lh*1.3, o.Outlet.Name, "C")
+	}
+	w.font("", base-1)
+	for _, line := range outletLines(o.Outlet, doc.Template.TaxID) {
+		w.paragraph(contentW, lh, line, "C")
+	}
+	if doc.Template.HeaderNote != "" {
+		w.paragraph(contentW, lh, doc.Template.HeaderNote, "C")
+	}
+	separator()
+
+	w.font("B", base+1)
+	w.cell(contentW, lh*1.3, documentTitle(doc), "C", 1)
+	w.font("", base-1)
+	for _, d := range [][2]string{
+		{"No.", doc.InvoiceNo},
+		{"Date", FormatDate(doc.IssuedAt)},
+		{"Order", o.OrderNo},
+		{"Customer", customerName(o)},
+	} {
+		w.cell(contentW*0.3, lh, d[0], "L", 0)
+		w.cell(contentW*0.7, lh, d[1], "R", 1)
+	}
+	separator()
+
+	for _, item := range o.Items {
+		w.font("B", base-1)
+		w.paragraph(contentW, lh, item.ServiceName, "L")
+		w.font("", base-1)
+		w.row(margin, contentW, lh, "  "+itemQuantity(item)+" x "+FormatRupiah(item.UnitPrice), FormatRupiah(item.LineTotal))
+		for _, addon := range item.Addons {
+			w.row(margin, contentW, lh, fmt.Sprintf("  + %s x%d", addon.AddonName, addon.Qty), FormatRupiah(addon.LineTotal))
+		}
+	}
+	separator()
+
+	for _, t := range totals(o) {
+		w.row(margin, contentW, lh, t[0], t[1])
+	}
+	w.font("B", base)
+	w.row(margin, contentW, lh*1.3, "TOTAL", FormatRupiah(o.GrandTotal))
+	w.font("", base-1)
+	w.row(margin, contentW, lh, "Paid", FormatRupiah(o.PaidAmount))
+	w.row(margin, contentW, lh, "Outstanding", FormatRupiah(o.OutstandingAmount))
+	separator()
+
+	w.font("B", base)
+	w.cell(contentW, lh*1.3, strings.ReplaceAll(PaymentStatus(o), "_", " "), "C", 1)
+
+	if doc.Template.ShowQRCode {
+		size := min(30, contentW*0.6)
+		pdf.SetY(pdf.GetY() + lh/2)
+		w.qr(o.OrderNo, margin+(contentW-size)/2, pdf.GetY(), size)
+		pdf.SetY(pdf.GetY() + size + lh/2)
+		w.font("", base-1)
+		w.cell(contentW, lh, o.OrderNo, "C", 1)
+	}
+	if doc.Template.FooterNote != "" {
+		w.font("I", base-1)
+		pdf.SetY(pdf.GetY() + lh/2)
+		w.paragraph(contentW, lh, doc.Template.FooterNote, "C")
+	}
+	return pdf.GetY() + margin
+}
@@ -0,0 +1,128 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/escpos"
+)
+
+// KindTags is the set of garment tags of an order, one per item
+const KindTags Kind = "tags"
+
+// Thermal is a document for a thermal printer: the raw ESC/POS stream and a
+// plain-text preview of the printout
+type Thermal struct {
+	ESCPOS  []byte
+	Preview string
+}
+
+func thermal(w *escpos.Writer) Thermal {
+	return Thermal{ESCPOS: w.Bytes(), Preview: w.Preview()}
+}
+
+// ThermalReceipt prints the receipt of doc on the template's paper width
+func ThermalReceipt(doc Document) (Thermal, error) {
+	if err := doc.Validate(); err != nil {
+		return Thermal{}, err
+	}
+	o := doc.Order
+	w := escpos.NewWriter(escpos.ColumnsFor(doc.Template.ReceiptWidthMM))
+
+	w.Align(escpos.AlignCenter)
+	if o.Outlet != nil {
+		w.Bold(true).Line(o.Outlet.Name).Bold(false)
+	}
+	for _, line := range outletLines(o.Outlet, doc.Template.TaxID) {
+		w.Line(line)
+	}
+	if doc.Template.HeaderNote != "" {
+		w.Line(doc.Template.HeaderNote)
+	}
+	w.Separator("-")
+
+	w.Bold(true).Line(documentTitle(doc)).Bold(false)
+	w.Align(escpos.AlignLeft)
+	w.Pair("No.", doc.InvoiceNo).
+		Pair("Date", FormatDate(doc.IssuedAt)).
+		Pair("Order", o.OrderNo).
+		Pair("Customer", customerName(o))
+	w.Separator("-")
+
+	for _, item := range o.Items {
+		name := item.ServiceName
+		if item.IsExpress {
+			name += " (EXPRESS)"
+		}
+		w.Line(name)
+		w.Pair("  "+itemQuantity(item)+" x "+FormatRupiah(item.UnitPrice), FormatRupiah(item.LineTotal))
+		for _, addon := range item.Addons {
+			w.Pair(fmt.Sprintf("  + %s x%d", addon.AddonName, addon.Qty), FormatRupiah(addon.LineTotal))
+		}
+	}
+	w.Separator("-")
+
+	for _, t := range totals(o) {
+		w.Pair(t[0], t[1])
+	}
+	w.Bold(true).Pair("TOTAL", FormatRupiah(o.GrandTotal)).Bold(false)
+	w.Pair("Paid", FormatRupiah(o.PaidAmount))
+	w.Pair("Outstanding", FormatRupiah(o.OutstandingAmount))
+	w.Separator("-")
+
+	w.Align(escpos.AlignCenter)
+	w.Bold(true).Line(strings.ReplaceAll(PaymentStatus(o), "_", " ")).Bold(false)
+	if o.PromisedAt != nil {
+		w.Line("Ready " + FormatDate(*o.PromisedAt))
+	}
+	if doc.Template.ShowQRCode {
+		w.Feed(1).QRCode(o.OrderNo)
+	}
+	if doc.Template.FooterNote != "" {
+		w.Feed(1).Line(doc.Template.FooterNote)
+	}
+	w.Feed(3).Cut()
+	return thermal(w), nil
+}
+
+// TagCode is the barcode printed on the tag of an order's index-th item
+// (1-based), e.g. ORD-20250324-0001-2
+func TagCode(orderNo string, index int) string {
+	return fmt.Sprintf("%s-%d", orderNo, index)
+}
+
+// GarmentTags prints one tag per item of the order, to be attached to the
+// customer's garments, cutting between tags. Tags only need the order, so
+// they can be printed at drop-off before an invoice exists.
+func GarmentTags(o *entity.Order, tmpl Template) (Thermal, error) {
+	if o == nil || len(o.Items) == 0 {
+		return Thermal{}, fmt.Errorf("order has no items to tag")
+	}
+	w := escpos.NewWriter(escpos.ColumnsFor(tmpl.ReceiptWidthMM))
+
+	for i, item := range o.Items {
+		w.Align(escpos.AlignCenter)
+		if o.Outlet != nil {
+			w.Line(o.Outlet.Name)
+		}
+		w.Tall(true).Bold(true).Line(o.OrderNo).Bold(false).Tall(false)
+		w.Line(fmt.Sprintf("Item %d/%d", i+1, len(o.Items)))
+		if item.IsExpress {
+			w.Reverse(true).Bold(true).Line(" EXPRESS ").Bold(false).Reverse(false)
+		}
+		w.Align(escpos.AlignLeft)
+		w.Bold(true).Line(item.ServiceName).Bold(false)
+		w.Pair("Qty", itemQuantity(item))
+		for _, addon := range item.Addons {
+			w.Line(fmt.Sprintf("+ %s x%d", addon.AddonName, addon.Qty))
+		}
+		w.Pair("Customer", customerName(o))
+		if o.PromisedAt != nil {
+			w.Pair("Ready", FormatDate(*o.PromisedAt))
+		}
+		w.Align(escpos.AlignCenter).Barcode(TagCode(o.OrderNo, i+1))
+		w.Feed(3).Cut()
+	}
+	return thermal(w), nil
+}
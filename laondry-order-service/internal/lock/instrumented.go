@@ -0,0 +1,48 @@
+package lock
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"laondry-order-service/internal/metrics"
+)
+
+// Instrumented counts the TryLock outcomes of a Locker in
+// metrics.LockAcquisitions.
+type Instrumented struct {
+	Locker Locker
+}
+
+func (l Instrumented) TryLock(ctx context.Context, key string, ttl time.Duration) (func() error, bool, error) {
+	unlock, ok, err := l.Locker.TryLock(ctx, key, ttl)
+	outcome := "acquired"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case !ok:
+		outcome = "busy"
+	}
+	metrics.LockAcquisitions.WithLabelValues(lockName(key), outcome).Inc()
+	return unlock, ok, err
+}
+
+var lockNameSegment = regexp.MustCompile(`^[a-z][a-z_-]*$`)
+
+// lockName drops the IDs from a key, e.g. payment:webhook:ORDER-123 becomes
+// payment:webhook and order:<uuid> becomes order, keeping at most two
+// segments so every lock name is a small, fixed set
+func lockName(key string) string {
+	var name []string
+	for _, segment := range strings.SplitN(key, ":", 3) {
+		if len(name) == 2 || !lockNameSegment.MatchString(segment) {
+			break
+		}
+		name = append(name, segment)
+	}
+	if len(name) == 0 {
+		return "other"
+	}
+	return strings.Join(name, ":")
+}
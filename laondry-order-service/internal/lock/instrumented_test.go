@@ -0,0 +1,41 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"laondry-order-service/internal/metrics"
+)
+
+func TestInstrumented(t *testing.T) {
+	l := Instrumented{Locker: NewMemoryLocker()}
+	ctx := context.Background()
+
+	unlock, ok, err := l.TryLock(ctx, "metrics-test:a", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, ok, _ = l.TryLock(ctx, "metrics-test:a", time.Second)
+	assert.False(t, ok)
+	assert.NoError(t, unlock())
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.LockAcquisitions.WithLabelValues("metrics-test:a", "acquired")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.LockAcquisitions.WithLabelValues("metrics-test:a", "busy")))
+}
+
+func TestLockName(t *testing.T) {
+	for key, want := range map[string]string{
+		"payment:webhook:ORDER-20240101-0001":           "payment:webhook",
+		"order:3f1c2b9e-7a57-4e0c-9d0b-2a6f1c1e9a10":    "order",
+		"quote:calculate:3f1c2b9e-7a57-4e0c-9d0b-2a6f1": "quote:calculate",
+		"orders:create":                   "orders:create",
+		"scheduler:payment-reconcile":     "scheduler:payment-reconcile",
+		"idempotency:user:42:retry-token": "idempotency:user",
+		"42":                              "other",
+	} {
+		assert.Equal(t, want, lockName(key), key)
+	}
+}
@@ -0,0 +1,151 @@
+// Package logger sets up the service's log/slog logger. Records logged with
+// a context (slog.InfoContext and friends) carry the request ID and the
+// attributes attached to that context with With, so the lines of one request
+// can be found together. Values of sensitive keys are redacted.
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the values of sensitive keys, see Redact
+const Redacted = "[REDACTED]"
+
+// New returns a logger writing JSON records, or logfmt-like text when format
+// is "text", at level and above. Unknown levels mean info.
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level), ReplaceAttr: Redact}
+	var h slog.Handler
+	if strings.EqualFold(format, "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// ParseLevel parses debug, info, warn or error, case-insensitively
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// RequestIDHeader carries the request ID from callers and on to the
+// services called while handling the request
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+type attrsKey struct{}
+
+// WithRequestID returns a copy of ctx whose log records carry request_id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, empty outside of a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// PropagateRequestID sets the X-Request-ID of an outgoing request to the
+// request ID of its context
+func PropagateRequestID(req *http.Request) {
+	if id := RequestID(req.Context()); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
+
+// With returns a copy of ctx whose log records carry args, alternating keys
+// and values as for slog.Logger.With, e.g.
+//
+//	ctx = logger.With(ctx, "order_id", order.ID)
+func With(ctx context.Context, args ...any) context.Context {
+	parent, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, len(parent)+r.NumAttrs())
+	attrs = append(attrs, parent...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// contextHandler adds the request ID and attributes of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+			r.AddAttrs(attrs...)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// sensitiveKeys are redacted, as are keys ending in "_" and one of them, e.g.
+// snap_token or whatsapp_access_token
+var sensitiveKeys = []string{
+	"password", "secret", "token", "authorization", "api_key", "key_hash",
+	"server_key", "signature_key", "credentials_key", "pin", "card_number", "cvv",
+}
+
+// Redact is a slog.HandlerOptions.ReplaceAttr that hides the values of
+// secrets and masks e-mail addresses, phone numbers and notification
+// recipients
+func Redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if key == s || strings.HasSuffix(key, "_"+s) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+	switch key {
+	case "email":
+		return slog.String(a.Key, maskEmail(a.Value.String()))
+	case "phone", "phone_number", "recipient":
+		return slog.String(a.Key, maskTail(a.Value.String(), 4))
+	}
+	return a
+}
+
+// maskEmail keeps the first letter of the local part and the domain
+func maskEmail(s string) string {
+	at := strings.LastIndexByte(s, '@')
+	if at < 1 {
+		return maskTail(s, 0)
+	}
+	return s[:1] + "***" + s[at:]
+}
+
+// maskTail replaces all but the last keep characters with *
+func maskTail(s string, keep int) string {
+	if len(s) <= keep {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-keep) + s[len(s)-keep:]
+}
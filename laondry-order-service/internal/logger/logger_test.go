@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	return rec
+}
+
+func TestLogger_ContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "info", "json")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = With(ctx, "user_id", "u-1")
+	child := With(ctx, "order_id", "o-1")
+
+	log.InfoContext(child, "order created", "payment_order_id", "PAY-1")
+	rec := decode(t, &buf)
+	assert.Equal(t, "order created", rec["msg"])
+	assert.Equal(t, "req-1", rec["request_id"])
+	assert.Equal(t, "u-1", rec["user_id"])
+	assert.Equal(t, "o-1", rec["order_id"])
+	assert.Equal(t, "PAY-1", rec["payment_order_id"])
+
+	// The parent context is not changed by With
+	buf.Reset()
+	log.InfoContext(ctx, "done")
+	rec = decode(t, &buf)
+	assert.Equal(t, "u-1", rec["user_id"])
+	assert.NotContains(t, rec, "order_id")
+
+	buf.Reset()
+	log.Info("no context")
+	assert.NotContains(t, decode(t, &buf), "request_id")
+}
+
+func TestLogger_Level(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "WARN", "json")
+	log.Info("hidden")
+	assert.Zero(t, buf.Len())
+	log.Warn("shown")
+	assert.Equal(t, "WARN", decode(t, &buf)["level"])
+
+	assert.Equal(t, slog.LevelDebug, ParseLevel("debug"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("verbose"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel(""))
+}
+
+func TestLogger_Redact(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "info", "json")
+	log.Info("sensitive",
+		"snap_token", "abc",
+		"Authorization", "Bearer xyz",
+		"server_key", "SB-Mid-server-1",
+		"password", "hunter2",
+		"email", "budi@example.com",
+		"phone_number", "081234567890",
+		"token_version", 3,
+		"order_id", "o-1",
+	)
+	rec := decode(t, &buf)
+	assert.Equal(t, Redacted, rec["snap_token"])
+	assert.Equal(t, Redacted, rec["Authorization"])
+	assert.Equal(t, Redacted, rec["server_key"])
+	assert.Equal(t, Redacted, rec["password"])
+	assert.Equal(t, "b***@example.com", rec["email"])
+	assert.Equal(t, "********7890", rec["phone_number"])
+	assert.EqualValues(t, 3, rec["token_version"])
+	assert.Equal(t, "o-1", rec["order_id"])
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"context"
+
+	"laondry-order-service/internal/events"
+)
+
+// CountEvents counts the events of the bus that have a metric, e.g.
+//
+//	bus.Subscribe("metrics", metrics.CountEvents)
+var CountEvents = events.SubscriberFunc(func(_ context.Context, evt events.Event) {
+	switch e := evt.(type) {
+	case events.OrderStatusChanged:
+		from := ""
+		if e.FromStatus != nil {
+			from = *e.FromStatus
+		}
+		OrderStatusTransitions.WithLabelValues(from, e.ToStatus).Inc()
+	}
+})
@@ -0,0 +1,154 @@
+// Package metrics defines the service's Prometheus metrics. They are
+// registered on Registry and served by Handler, on their own port so they
+// stay off the public API; see Serve and, for prefork mode, Workers.
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric of this process, including Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequestDuration is labelled with the chi route pattern, not the
+	// path, so order IDs do not create series
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of GORM queries by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "outcome"})
+
+	LockAcquisitions = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "lock_acquisitions_total",
+		Help: "Locker.TryLock calls by lock name and outcome: acquired, busy or error.",
+	}, []string{"lock", "outcome"})
+
+	CoreAPIRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "core_api_request_duration_seconds",
+		Help:    "Duration of calls to core-api by operation and status code, \"error\" when no response arrived.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "code"})
+
+	GatewayRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "payment_gateway_request_duration_seconds",
+		Help:    "Duration of payment gateway API calls by gateway, operation and outcome.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"gateway", "operation", "outcome"})
+
+	GatewayErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_gateway_errors_total",
+		Help: "Failed payment gateway API calls by gateway and operation.",
+	}, []string{"gateway", "operation"})
+
+	PaymentWebhooks = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_webhooks_total",
+		Help: "Payment gateway notifications by gateway, event type and outcome.",
+	}, []string{"gateway", "event_type", "outcome"})
+
+	WebhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "Attempts to deliver partner webhooks by event and outcome.",
+	}, []string{"event", "outcome"})
+
+	AuthTokenCacheLookups = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_cache_lookups_total",
+		Help: "Token validations by how the cache answered: hit, negative_hit, miss, or shared with a concurrent validation.",
+	}, []string{"result"})
+
+	OrderStatusTransitions = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_status_transitions_total",
+		Help: "Order status changes by previous and new status.",
+	}, []string{"from", "to"})
+)
+
+// Outcome labels shared by several metrics
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Outcome is OutcomeOK for a nil err, else OutcomeError
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeOK
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return handlerFor(Registry)
+}
+
+func handlerFor(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Serve serves h under /metrics on addr in the background. Shut the
+// returned server down with the service.
+func Serve(addr string, h http.Handler) *http.Server {
+	srv := newServer(h)
+	srv.Addr = addr
+	go func() {
+		slog.Info("[Metrics] Serving /metrics", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("[Metrics] Server error", "addr", addr, "error", err)
+		}
+	}()
+	return srv
+}
+
+func newServer(h http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h)
+	return &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
+
+// InstrumentTransport observes the requests sent through next, or
+// http.DefaultTransport when nil, in CoreAPIRequestDuration
+func InstrumentTransport(operation string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		CoreAPIRequestDuration.WithLabelValues(operation, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
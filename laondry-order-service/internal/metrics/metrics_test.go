@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/events"
+)
+
+// find returns the gathered series of family name with exactly labels
+func find(t *testing.T, families []*dto.MetricFamily, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range mf.Metric {
+			if len(m.Label) != len(labels) {
+				continue
+			}
+			for _, l := range m.Label {
+				if labels[l.GetName()] != l.GetValue() {
+					continue metrics
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
+
+func sampleCount(t *testing.T, name string, labels map[string]string) uint64 {
+	t.Helper()
+	families, err := Registry.Gather()
+	require.NoError(t, err)
+	if m := find(t, families, name, labels); m != nil {
+		return m.GetHistogram().GetSampleCount()
+	}
+	return 0
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+	require.NoError(t, db.AutoMigrate(&widget{}))
+
+	require.NoError(t, db.Create(&widget{Name: "a"}).Error)
+	var w widget
+	require.NoError(t, db.First(&w).Error)
+	assert.ErrorIs(t, db.First(&w, 42).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Table("missing").Find(&[]widget{}).Error)
+
+	assert.Equal(t, uint64(1), sampleCount(t, "db_query_duration_seconds",
+		map[string]string{"operation": "create", "table": "widgets", "outcome": OutcomeOK}))
+	assert.Equal(t, uint64(2), sampleCount(t, "db_query_duration_seconds",
+		map[string]string{"operation": "query", "table": "widgets", "outcome": OutcomeOK}))
+	assert.Equal(t, uint64(1), sampleCount(t, "db_query_duration_seconds",
+		map[string]string{"operation": "query", "table": "missing", "outcome": OutcomeError}))
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: InstrumentTransport("test_call", nil)}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = client.Get("http://127.0.0.1:1")
+	require.Error(t, err)
+
+	assert.Equal(t, uint64(1), sampleCount(t, "core_api_request_duration_seconds", map[string]string{"operation": "test_call", "code": "401"}))
+	assert.Equal(t, uint64(1), sampleCount(t, "core_api_request_duration_seconds", map[string]string{"operation": "test_call", "code": "error"}))
+}
+
+func TestWorkers(t *testing.T) {
+	dir := t.TempDir()
+	srv, err := ServeSocket(WorkerSocket(dir, 0))
+	require.NoError(t, err)
+	defer srv.Close()
+	OrderStatusTransitions.WithLabelValues("WORKER_TEST", "DONE").Inc()
+
+	// Worker 1 never started
+	workers := NewWorkers([]string{WorkerSocket(dir, 0), WorkerSocket(dir, 1)})
+	families, err := workers.Gather()
+	require.NoError(t, err)
+
+	up := find(t, families, "prefork_worker_up", map[string]string{"worker": "0"})
+	require.NotNil(t, up)
+	assert.Equal(t, 1.0, up.GetGauge().GetValue())
+	down := find(t, families, "prefork_worker_up", map[string]string{"worker": "1"})
+	require.NotNil(t, down)
+	assert.Equal(t, 0.0, down.GetGauge().GetValue())
+
+	transitions := find(t, families, "order_status_transitions_total", map[string]string{"from": "WORKER_TEST", "to": "DONE", "worker": "0"})
+	require.NotNil(t, transitions)
+	assert.Equal(t, 1.0, transitions.GetCounter().GetValue())
+
+	rr := httptest.NewRecorder()
+	workers.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `order_status_transitions_total{from="WORKER_TEST",to="DONE",worker="0"} 1`)
+	assert.Contains(t, rr.Body.String(), `go_goroutines{worker="0"}`)
+}
+
+func TestCountEvents(t *testing.T) {
+	from := "READY"
+	CountEvents.HandleEvent(context.Background(), events.OrderStatusChanged{FromStatus: &from, ToStatus: "EVENT_TEST"})
+	CountEvents.HandleEvent(context.Background(), events.OrderUpdated{Status: "EVENT_TEST"})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(OrderStatusTransitions.WithLabelValues("READY", "EVENT_TEST")))
+}
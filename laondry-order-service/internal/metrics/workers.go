@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// Prefork workers are separate processes behind one SO_REUSEPORT port, so a
+// scrape of any one of them would see a random worker's counters. Instead
+// each worker serves its metrics on a unix socket and the master serves the
+// metrics of all of them, each series labelled with its worker index.
+
+const workerScrapeTimeout = 5 * time.Second
+
+// WorkerSocket is the socket in dir that prefork worker i serves metrics on
+func WorkerSocket(dir string, worker int) string {
+	return filepath.Join(dir, fmt.Sprintf("worker-%d.sock", worker))
+}
+
+// ServeSocket serves Handler on the unix socket path in the background,
+// replacing a socket left behind by a previous run
+func ServeSocket(path string) (*http.Server, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	srv := newServer(Handler())
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			slog.Error("[Metrics] Server error", "socket", path, "error", err)
+		}
+	}()
+	return srv, nil
+}
+
+// Workers gathers the metrics of prefork workers from their sockets. A worker
+// that does not answer, e.g. while it restarts, is left out of the scrape and
+// reported by prefork_worker_up.
+type Workers struct {
+	sockets []string
+	client  *http.Client
+}
+
+func NewWorkers(sockets []string) *Workers {
+	transport := &http.Transport{
+		// The URL host is the worker index; every request dials its socket
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			i, err := strconv.Atoi(host)
+			if err != nil || i < 0 || i >= len(sockets) {
+				return nil, fmt.Errorf("unknown worker %q", host)
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sockets[i])
+		},
+	}
+	return &Workers{sockets: sockets, client: &http.Client{Transport: transport, Timeout: workerScrapeTimeout}}
+}
+
+// Handler serves the merged metrics of all workers
+func (w *Workers) Handler() http.Handler {
+	return handlerFor(w)
+}
+
+// Gather implements prometheus.Gatherer
+func (w *Workers) Gather() ([]*dto.MetricFamily, error) {
+	perWorker := make([][]*dto.MetricFamily, len(w.sockets))
+	scraped := make([]bool, len(w.sockets))
+	var wg sync.WaitGroup
+	for i := range w.sockets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			families, err := w.scrape(i)
+			if err != nil {
+				slog.Warn("[Metrics] Failed to scrape worker", "worker", i, "error", err)
+				return
+			}
+			perWorker[i], scraped[i] = families, true
+		}(i)
+	}
+	wg.Wait()
+
+	up := &dto.MetricFamily{
+		Name: proto.String("prefork_worker_up"),
+		Help: proto.String("Whether the last scrape of a prefork worker succeeded."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	gatherers := make(prometheus.Gatherers, 0, len(w.sockets)+1)
+	for i, families := range perWorker {
+		value := 0.0
+		if scraped[i] {
+			value = 1
+		}
+		worker := workerLabel(i)
+		up.Metric = append(up.Metric, &dto.Metric{Label: []*dto.LabelPair{worker}, Gauge: &dto.Gauge{Value: proto.Float64(value)}})
+		for _, mf := range families {
+			for _, m := range mf.Metric {
+				m.Label = append(m.Label, worker)
+				sort.Slice(m.Label, func(a, b int) bool { return m.Label[a].GetName() < m.Label[b].GetName() })
+			}
+		}
+		gatherers = append(gatherers, staticGatherer(families))
+	}
+	gatherers = append(gatherers, staticGatherer{up})
+	return gatherers.Gather()
+}
+
+// scrape fetches the metric families of worker i in the protobuf format
+func (w *Workers) scrape(i int) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://"+strconv.Itoa(i)+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeProtoDelim)))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("worker returned HTTP %d", resp.StatusCode)
+	}
+
+	var families []*dto.MetricFamily
+	dec := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		families = append(families, mf)
+	}
+}
+
+func workerLabel(i int) *dto.LabelPair {
+	return &dto.LabelPair{Name: proto.String("worker"), Value: proto.String(strconv.Itoa(i))}
+}
+
+type staticGatherer []*dto.MetricFamily
+
+func (g staticGatherer) Gather() ([]*dto.MetricFamily, error) {
+	return g, nil
+}
@@ -10,6 +10,7 @@ import (
 
 type accessLogEntry struct {
     Timestamp   string  `json:"ts"`
+    RequestID   string  `json:"request_id,omitempty"`
     Method      string  `json:"method"`
     Path        string  `json:"path"`
     Query       string  `json:"query"`
@@ -21,6 +22,10 @@ type accessLogEntry struct {
     OutletID    string  `json:"outlet_id,omitempty"`
     OrderID     string  `json:"order_id,omitempty"`
     OrderNo     string  `json:"order_no,omitempty"`
+    // Who made the request: a user, or an internal service's API key
+    UserID       string `json:"user_id,omitempty"`
+    APIKeyID     string `json:"api_key_id,omitempty"`
+    APIKeyPrefix string `json:"api_key_prefix,omitempty"`
 }
 
 type accessCtxKey struct{}
@@ -53,6 +58,7 @@ func AccessLog(w io.Writer) func(http.Handler) http.Handler {
             next.ServeHTTP(wrapped, r.WithContext(ctx))
             entry := accessLogEntry{
                 Timestamp:  time.Now().Format(time.RFC3339Nano),
+                RequestID:  fields["request_id"],
                 Method:     r.Method,
                 Path:       r.URL.Path,
                 Query:      r.URL.RawQuery,
@@ -64,6 +70,9 @@ func AccessLog(w io.Writer) func(http.Handler) http.Handler {
                 OutletID:   fields["outlet_id"],
                 OrderID:    fields["order_id"],
                 OrderNo:    fields["order_no"],
+                UserID:       fields["user_id"],
+                APIKeyID:     fields["api_key_id"],
+                APIKeyPrefix: fields["api_key_prefix"],
             }
             _ = json.NewEncoder(w).Encode(entry)
         })
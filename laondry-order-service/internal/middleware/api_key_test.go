@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPIKeys accepts "good-key" with the given permissions and outlets
+type stubAPIKeys struct {
+	permissions []string
+	outlets     []string
+	remoteIP    string
+}
+
+func (s *stubAPIKeys) AuthenticateAPIKey(ctx context.Context, key, remoteIP string) (*UserClaims, error) {
+	s.remoteIP = remoteIP
+	if key != "good-key" {
+		return nil, ErrInvalidAPIKey
+	}
+	return &UserClaims{Role: RoleService, APIKeyID: "key-1", APIKeyPrefix: "lok_abc", Permissions: s.permissions, Outlets: s.outlets}, nil
+}
+
+func TestAPIKeyPermission(t *testing.T) {
+	tests := []struct {
+		method, path string
+		permission   string
+		allowed      bool
+	}{
+		{"GET", "/api/v1/orders", PermOrdersRead, true},
+		{"GET", "/api/v1/orders/", PermOrdersRead, true},
+		{"GET", "/api/v1/orders/3f1c", PermOrdersRead, true},
+		{"GET", "/api/v1/orders/order-no/ORD-1", PermOrdersRead, true},
+		{"GET", "/api/v1/outlets/3f1c/events", PermOrdersRead, true},
+		{"POST", "/api/v1/orders", PermOrdersCreate, true},
+		{"PUT", "/api/v1/orders/3f1c", PermOrdersUpdate, true},
+		{"PATCH", "/api/v1/orders/3f1c/status", PermOrdersStatus, true},
+		{"POST", "/api/v1/orders/3f1c/cancel", PermOrdersStatus, true},
+		{"POST", "/api/v1/orders/3f1c/payments/manual", "", false},
+		{"GET", "/api/v1/orders/3f1c/invoice.pdf", "", false},
+		{"GET", "/api/v1/wallet", "", false},
+		{"POST", "/api/v1/payments/midtrans/token", "", false},
+		{"GET", "/api/v1/admin/api-keys", "", false},
+		{"PATCH", "/api/v1/orders//status", "", false},
+	}
+	for _, tt := range tests {
+		permission, ok := apiKeyPermission(tt.method, tt.path)
+		assert.Equal(t, tt.allowed, ok, "%s %s", tt.method, tt.path)
+		assert.Equal(t, tt.permission, permission, "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestAuth_APIKey(t *testing.T) {
+	keys := &stubAPIKeys{permissions: []string{PermOrdersRead}}
+	SetAPIKeyAuthenticator(keys)
+	defer SetAPIKeyAuthenticator(nil)
+
+	do := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.7:51234"
+		req.Header.Set(APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		Auth(mockHandler()).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("GET", "/api/v1/orders", "good-key")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response map[string]any
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	user := response["user"].(map[string]any)
+	assert.Equal(t, "key-1", user["api_key_id"])
+	assert.Equal(t, "10.0.0.7", keys.remoteIP)
+
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/v1/orders", "good-key").Code, "missing permission")
+	assert.Equal(t, http.StatusForbidden, do("GET", "/api/v1/wallet", "good-key").Code, "endpoint closed to API keys")
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/orders", "bad-key").Code)
+
+	SetAPIKeyAuthenticator(nil)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/orders", "good-key").Code, "API keys disabled")
+}
+
+func TestAuth_APIKeyPassesRoleChecksAndIsLogged(t *testing.T) {
+	SetAPIKeyAuthenticator(&stubAPIKeys{permissions: []string{PermOrdersRead}})
+	defer SetAPIKeyAuthenticator(nil)
+
+	var logs bytes.Buffer
+	handler := AccessLog(&logs)(Auth(RequireRole(StaffRoles...)(mockHandler())))
+	req := httptest.NewRequest("GET", "/api/v1/outlets/3f1c/events", nil)
+	req.Header.Set(APIKeyHeader, "good-key")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "key-1", entry["api_key_id"])
+	assert.Equal(t, "lok_abc", entry["api_key_prefix"])
+	assert.Nil(t, entry["user_id"])
+}
+
+func TestCanAccessOutlet(t *testing.T) {
+	scoped := &UserClaims{APIKeyID: "key-1", Outlets: []string{"outlet-1"}}
+	assert.True(t, CanAccessOutlet(scoped, "outlet-1"))
+	assert.False(t, CanAccessOutlet(scoped, "outlet-2"))
+	assert.True(t, CanAccessOutlet(&UserClaims{APIKeyID: "key-2"}, "outlet-2"), "unscoped key")
+	assert.True(t, CanAccessOutlet(&UserClaims{UserID: "u", Outlets: []string{"outlet-1"}}, "outlet-2"), "users are not outlet-scoped")
+}
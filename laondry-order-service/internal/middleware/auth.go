@@ -3,13 +3,16 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"laondry-order-service/internal/logger"
+	"laondry-order-service/internal/metrics"
 	"laondry-order-service/pkg/response"
 )
 
@@ -26,7 +29,7 @@ var coreAPIURL string
 // SetCoreAPIURL sets the core API URL from config
 func SetCoreAPIURL(url string) {
 	coreAPIURL = url
-	log.Printf("Core API URL set to: %s", coreAPIURL)
+	slog.Info("[Auth] Core API URL set", "url", coreAPIURL)
 }
 
 type UserClaims struct {
@@ -36,11 +39,86 @@ type UserClaims struct {
     Role   string `json:"role"`
     // Optional: member tier code derived from core-api user profile
     MemberTierCode *string `json:"member_tier_code,omitempty"`
+    // Optional: outlets the user works at, from the token's outlets claim
+    Outlets []string `json:"outlets,omitempty"`
+    // Set when an internal service authenticated with an API key instead of
+    // a user token, see IsAPIKey
+    APIKeyID     string   `json:"api_key_id,omitempty"`
+    APIKeyPrefix string   `json:"api_key_prefix,omitempty"`
+    Permissions  []string `json:"permissions,omitempty"`
 }
 
-// Auth middleware validates token dengan core-api
+// TokenAuthenticator turns a bearer token into the user's claims
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*UserClaims, error)
+}
+
+// authenticator verifies bearer tokens for Auth and OptionalAuth
+var authenticator TokenAuthenticator = CoreAPIAuthenticator{}
+
+// SetAuthenticator sets how bearer tokens are verified; core-api by default
+func SetAuthenticator(a TokenAuthenticator) {
+	authenticator = a
+}
+
+// CoreAPIAuthenticator validates tokens by fetching the user's profile from
+// core-api, one round trip per request
+type CoreAPIAuthenticator struct{}
+
+func (CoreAPIAuthenticator) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	return validateTokenWithCoreAPI(ctx, token)
+}
+
+// FallbackAuthenticator verifies tokens with Local and asks Remote only when
+// Local cannot check them (ErrVerificationUnavailable). Tokens Local rejects
+// are not retried.
+type FallbackAuthenticator struct {
+	Local  TokenAuthenticator
+	Remote TokenAuthenticator
+}
+
+func (a FallbackAuthenticator) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	user, err := a.Local.Authenticate(ctx, token)
+	if errors.Is(err, ErrVerificationUnavailable) {
+		slog.InfoContext(ctx, "[Auth] Falling back to core-api", "error", err)
+		return a.Remote.Authenticate(ctx, token)
+	}
+	return user, err
+}
+
+func (a FallbackAuthenticator) Invalidate(ctx context.Context, token string) error {
+	for _, next := range []TokenAuthenticator{a.Local, a.Remote} {
+		if cache, ok := next.(tokenInvalidator); ok {
+			if err := cache.Invalidate(ctx, token); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Auth middleware validates the bearer token, see SetAuthenticator. Internal
+// callers may send an X-Api-Key instead, see SetAPIKeyAuthenticator.
 func Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			user, err := authenticateAPIKey(r, key)
+			if err != nil {
+				if user != nil {
+					slog.WarnContext(r.Context(), "[APIKey] Request denied",
+						"api_key_prefix", user.APIKeyPrefix, "api_key_id", user.APIKeyID,
+						"method", r.Method, "path", r.URL.Path, "error", err)
+					response.Forbidden(w, err.Error())
+					return
+				}
+				slog.WarnContext(r.Context(), "[APIKey] Key validation failed", "error", err)
+				response.Unauthorized(w, "Invalid or expired API key")
+				return
+			}
+			next.ServeHTTP(w, withUser(r, user))
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			response.Unauthorized(w, "Authorization header required")
@@ -55,17 +133,15 @@ func Auth(next http.Handler) http.Handler {
 
 		token := parts[1]
 
-		// Validate token dengan core-api
-		user, err := validateTokenWithCoreAPI(token)
+		user, err := authenticator.Authenticate(r.Context(), token)
 		if err != nil {
-			log.Printf("[Auth] Token validation failed: %v", err)
+			slog.WarnContext(r.Context(), "[Auth] Token validation failed", "error", err)
 			response.Unauthorized(w, "Invalid or expired token")
 			return
 		}
 
 		// Store user info di context
-		ctx := context.WithValue(r.Context(), ContextUserKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withUser(r, user))
 	})
 }
 
@@ -85,33 +161,35 @@ func OptionalAuth(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
-		user, err := validateTokenWithCoreAPI(token)
+		user, err := authenticator.Authenticate(r.Context(), token)
 		if err == nil && user != nil {
-			ctx := context.WithValue(r.Context(), ContextUserKey, user)
-			r = r.WithContext(ctx)
+			r = withUser(r, user)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-func validateTokenWithCoreAPI(token string) (*UserClaims, error) {
+// validateTokenWithCoreAPI passes the request ID of ctx on to core-api
+func validateTokenWithCoreAPI(ctx context.Context, token string) (*UserClaims, error) {
 	if coreAPIURL == "" {
 		return nil, fmt.Errorf("core API URL not configured")
 	}
 
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Timeout:   5 * time.Second,
+		Transport: metrics.InstrumentTransport("validate_token", nil),
 	}
 
 	// Call core-api /user-profile/me endpoint to validate token
-	req, err := http.NewRequest("GET", coreAPIURL+"/user-profile/me", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", coreAPIURL+"/user-profile/me", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/json")
+	logger.PropagateRequestID(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -121,6 +199,9 @@ func validateTokenWithCoreAPI(token string) (*UserClaims, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w: core-api returned %d: %s", ErrInvalidToken, resp.StatusCode, string(body))
+		}
 		return nil, fmt.Errorf("core-api returned %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -142,7 +223,7 @@ func validateTokenWithCoreAPI(token string) (*UserClaims, error) {
 	}
 
 	if !result.Success {
-		return nil, fmt.Errorf("core-api validation failed")
+		return nil, fmt.Errorf("%w: core-api validation failed", ErrInvalidToken)
 	}
 
     claims := &UserClaims{
@@ -159,6 +240,21 @@ func validateTokenWithCoreAPI(token string) (*UserClaims, error) {
     return claims, nil
 }
 
+// withUser stores the authenticated principal in the request context and
+// records it in the access log and the request's log lines
+func withUser(r *http.Request, user *UserClaims) *http.Request {
+	ctx := context.WithValue(r.Context(), ContextUserKey, user)
+	if user.IsAPIKey() {
+		SetAccessField(r, "api_key_id", user.APIKeyID)
+		SetAccessField(r, "api_key_prefix", user.APIKeyPrefix)
+		ctx = logger.With(ctx, "api_key_id", user.APIKeyID)
+	} else {
+		SetAccessField(r, "user_id", user.UserID)
+		ctx = logger.With(ctx, "user_id", user.UserID)
+	}
+	return r.WithContext(ctx)
+}
+
 // GetUserFromContext mengambil user claims dari context
 func GetUserFromContext(ctx context.Context) (*UserClaims, bool) {
 	user, ok := ctx.Value(ContextUserKey).(*UserClaims)
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -311,7 +312,7 @@ func TestValidateTokenWithCoreAPI_Success(t *testing.T) {
 	defer mockServer.Close()
 	SetCoreAPIURL(mockServer.URL)
 
-	user, err := validateTokenWithCoreAPI("valid-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "valid-token")
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, "user-123", user.UserID)
@@ -325,7 +326,7 @@ func TestValidateTokenWithCoreAPI_InvalidToken(t *testing.T) {
 	defer mockServer.Close()
 	SetCoreAPIURL(mockServer.URL)
 
-	user, err := validateTokenWithCoreAPI("invalid-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "invalid-token")
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	assert.Contains(t, err.Error(), "401")
@@ -337,7 +338,7 @@ func TestValidateTokenWithCoreAPI_CoreAPINotConfigured(t *testing.T) {
 	coreAPIURL = ""
 	defer func() { coreAPIURL = originalURL }()
 
-	user, err := validateTokenWithCoreAPI("some-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "some-token")
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	assert.Contains(t, err.Error(), "not configured")
@@ -346,7 +347,7 @@ func TestValidateTokenWithCoreAPI_CoreAPINotConfigured(t *testing.T) {
 func TestValidateTokenWithCoreAPI_CoreAPIDown(t *testing.T) {
 	SetCoreAPIURL("http://localhost:99999")
 
-	user, err := validateTokenWithCoreAPI("some-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "some-token")
 	assert.Error(t, err)
 	assert.Nil(t, user)
 }
@@ -361,7 +362,7 @@ func TestValidateTokenWithCoreAPI_InvalidResponse(t *testing.T) {
 	defer mockServer.Close()
 	SetCoreAPIURL(mockServer.URL)
 
-	user, err := validateTokenWithCoreAPI("some-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "some-token")
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	assert.Contains(t, err.Error(), "decode")
@@ -380,7 +381,7 @@ func TestValidateTokenWithCoreAPI_UnsuccessfulResponse(t *testing.T) {
 	defer mockServer.Close()
 	SetCoreAPIURL(mockServer.URL)
 
-	user, err := validateTokenWithCoreAPI("expired-token")
+	user, err := validateTokenWithCoreAPI(context.Background(), "expired-token")
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	assert.Contains(t, err.Error(), "validation failed")
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"laondry-order-service/internal/logger"
 	"laondry-order-service/pkg/response"
 )
 
@@ -16,7 +17,8 @@ func CORS(next http.Handler) http.Handler {
 
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, "+logger.RequestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", logger.RequestIDHeader)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	// jwksMinRefresh limits refetches for unknown key IDs and after failures
+	jwksMinRefresh = time.Minute
+	jwksTimeout    = 5 * time.Second
+)
+
+// jwksKeySet caches the RSA keys of a JWKS endpoint. Keys are refetched
+// periodically and when a token names an unknown key ID, so rotated keys are
+// picked up without a restart.
+type jwksKeySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func newJWKSKeySet(url string, refresh time.Duration) *jwksKeySet {
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+	return &jwksKeySet{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: jwksTimeout},
+		now:     time.Now,
+	}
+}
+
+// Keys returns the key with the given ID, or every key when kid is empty.
+// The error is only meaningful when no key is returned.
+func (s *jwksKeySet) Keys(ctx context.Context, kid string) ([]*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	_, known := s.keys[kid]
+	stale := now.Sub(s.fetchedAt) >= s.refresh
+	missing := kid != "" && !known
+	var err error
+	if (stale || missing) && now.Sub(s.attemptedAt) >= jwksMinRefresh {
+		s.attemptedAt = now
+		// A client hanging up should not fail the fetch for everyone else
+		if err = s.fetch(context.WithoutCancel(ctx)); err != nil {
+			slog.WarnContext(ctx, "[Auth] Failed to fetch JWKS", "url", s.url, "error", err)
+		}
+	} else if s.keys == nil {
+		err = fmt.Errorf("JWKS from %s unavailable", s.url)
+	}
+
+	if kid != "" {
+		if key, ok := s.keys[kid]; ok {
+			return []*rsa.PublicKey{key}, nil
+		}
+		return nil, err
+	}
+	keys := make([]*rsa.PublicKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys, err
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (s *jwksKeySet) fetch(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, jwksTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			slog.WarnContext(ctx, "[Auth] Skipping JWKS key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	s.keys = keys
+	s.fetchedAt = s.now()
+	return nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
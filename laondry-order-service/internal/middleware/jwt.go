@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Auth modes, see config.AuthConfig
+const (
+	AuthModeLocal             = "local"
+	AuthModeCoreAPI           = "core_api"
+	AuthModeLocalWithFallback = "local_with_fallback"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrVerificationUnavailable means the token could not be checked
+	// locally, e.g. the JWKS endpoint or the database is unreachable or no
+	// key matches. The fallback mode asks core-api instead.
+	ErrVerificationUnavailable = errors.New("token cannot be verified locally")
+)
+
+// TokenVersions returns the current token version of a user. Tokens carrying
+// an older version were revoked by core-api. Deleted or blocked users return
+// ErrTokenRevoked.
+type TokenVersions interface {
+	CurrentTokenVersion(ctx context.Context, userID string) (int, error)
+}
+
+// JWTConfig configures a JWTVerifier. At least one of HS256Secrets,
+// RS256Keys and JWKSURL must be set.
+type JWTConfig struct {
+	HS256Secrets [][]byte
+	RS256Keys    []*rsa.PublicKey
+	JWKSURL      string
+	JWKSRefresh  time.Duration
+	Issuer       string // checked when set
+	Audience     string // checked when set
+	Leeway       time.Duration
+	// TokenVersions enables revocation checks; nil skips them
+	TokenVersions TokenVersions
+}
+
+// JWTVerifier verifies core-api access tokens without calling core-api
+type JWTVerifier struct {
+	cfg  JWTConfig
+	jwks *jwksKeySet
+	now  func() time.Time
+}
+
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	if len(cfg.HS256Secrets) == 0 && len(cfg.RS256Keys) == 0 && cfg.JWKSURL == "" {
+		return nil, errors.New("no JWT secrets or keys configured")
+	}
+	v := &JWTVerifier{cfg: cfg, now: time.Now}
+	if cfg.JWKSURL != "" {
+		v.jwks = newJWKSKeySet(cfg.JWKSURL, cfg.JWKSRefresh)
+	}
+	return v, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the claims of a core-api access token (see
+// AuthTokenHelper::issue)
+type jwtClaims struct {
+	Subject        string   `json:"sub"`
+	Email          string   `json:"email"`
+	Phone          string   `json:"phone"`
+	Role           string   `json:"role"`
+	MemberTierCode *string  `json:"member_tier_code"`
+	Outlets        []string `json:"outlets"`
+	TokenVersion   *int     `json:"token_version"`
+	Issuer         string   `json:"iss"`
+	Audience       audience `json:"aud"`
+	ExpiresAt      *float64 `json:"exp"`
+	NotBefore      *float64 `json:"nbf"`
+}
+
+// audience is the "aud" claim, either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Authenticate verifies the token's signature, lifetime, issuer, audience and
+// token version and returns its claims
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	if err := v.verifySignature(ctx, header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validate(&claims); err != nil {
+		return nil, err
+	}
+	if err := v.checkTokenVersion(ctx, &claims); err != nil {
+		return nil, err
+	}
+
+	user := &UserClaims{
+		UserID:  claims.Subject,
+		Email:   claims.Email,
+		Phone:   claims.Phone,
+		Role:    claims.Role,
+		Outlets: claims.Outlets,
+	}
+	if claims.MemberTierCode != nil && *claims.MemberTierCode != "" {
+		user.MemberTierCode = claims.MemberTierCode
+	}
+	return user, nil
+}
+
+func (v *JWTVerifier) verifySignature(ctx context.Context, header jwtHeader, signed string, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if len(v.cfg.HS256Secrets) == 0 {
+			return fmt.Errorf("%w: no HS256 secret configured", ErrVerificationUnavailable)
+		}
+		for _, secret := range v.cfg.HS256Secrets {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(signed))
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	case "RS256":
+		keys, err := v.rsaKeys(ctx, header.Kid)
+		if len(keys) == 0 {
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrVerificationUnavailable, err)
+			}
+			return fmt.Errorf("%w: no RS256 key for kid %q", ErrVerificationUnavailable, header.Kid)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		for _, key := range keys {
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+}
+
+// rsaKeys returns the configured keys and the JWKS keys matching kid. The
+// error is only meaningful when no key is returned.
+func (v *JWTVerifier) rsaKeys(ctx context.Context, kid string) ([]*rsa.PublicKey, error) {
+	keys := v.cfg.RS256Keys
+	if v.jwks == nil {
+		return keys, nil
+	}
+	fetched, err := v.jwks.Keys(ctx, kid)
+	return append(keys[:len(keys):len(keys)], fetched...), err
+}
+
+func (v *JWTVerifier) validate(claims *jwtClaims) error {
+	now := v.now()
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(v.cfg.Leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(v.cfg.Leeway).Before(unixTime(*claims.NotBefore)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if v.cfg.Issuer != "" && claims.Issuer != v.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if v.cfg.Audience != "" && !slices.Contains(claims.Audience, v.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, []string(claims.Audience))
+	}
+	return nil
+}
+
+func (v *JWTVerifier) checkTokenVersion(ctx context.Context, claims *jwtClaims) error {
+	if v.cfg.TokenVersions == nil {
+		return nil
+	}
+	if claims.TokenVersion == nil {
+		return fmt.Errorf("%w: missing token_version", ErrInvalidToken)
+	}
+	current, err := v.cfg.TokenVersions.CurrentTokenVersion(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrInvalidToken) {
+			return err
+		}
+		return fmt.Errorf("%w: token version lookup: %v", ErrVerificationUnavailable, err)
+	}
+	if *claims.TokenVersion < current {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// ParseRSAPublicKeys parses the RSA public keys and certificates in PEM data
+func ParseRSAPublicKeys(data []byte) ([]*rsa.PublicKey, error) {
+	var keys []*rsa.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var key any
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", strings.ToLower(block.Type), err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an RSA key", strings.ToLower(block.Type))
+		}
+		keys = append(keys, rsaKey)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA public keys found")
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,386 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laondry-order-service/internal/entity"
+)
+
+var testSecret = []byte("core-api-jwt-secret")
+
+func tokenClaims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"sub":              "7d3c6a9e-1f0b-4a53-9d1e-2b8f6c4e5a10",
+		"name":             "Budi",
+		"phone":            "081234567890",
+		"role":             "kasir",
+		"token_version":    2,
+		"member_tier_code": "GOLD",
+		"outlets":          []string{"outlet-1", "outlet-2"},
+		"iat":              time.Now().Unix(),
+		"exp":              time.Now().Add(15 * time.Minute).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"typ": "JWT", "alg": "HS256"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"typ": "JWT", "alg": "RS256"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newVerifier(t *testing.T, cfg JWTConfig) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(cfg)
+	require.NoError(t, err)
+	return v
+}
+
+type fakeTokenVersions map[string]int
+
+func (f fakeTokenVersions) CurrentTokenVersion(ctx context.Context, userID string) (int, error) {
+	version, ok := f[userID]
+	if !ok {
+		return 0, ErrTokenRevoked
+	}
+	return version, nil
+}
+
+func TestNewJWTVerifier_RequiresAKey(t *testing.T) {
+	_, err := NewJWTVerifier(JWTConfig{})
+	assert.Error(t, err)
+}
+
+func TestJWTVerifier_HS256(t *testing.T) {
+	v := newVerifier(t, JWTConfig{HS256Secrets: [][]byte{testSecret}})
+
+	user, err := v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "7d3c6a9e-1f0b-4a53-9d1e-2b8f6c4e5a10", user.UserID)
+	assert.Equal(t, "081234567890", user.Phone)
+	assert.Equal(t, "kasir", user.Role)
+	require.NotNil(t, user.MemberTierCode)
+	assert.Equal(t, "GOLD", *user.MemberTierCode)
+	assert.Equal(t, []string{"outlet-1", "outlet-2"}, user.Outlets)
+
+	// Customers without a tier get a null claim
+	user, err = v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(map[string]any{"member_tier_code": nil})))
+	require.NoError(t, err)
+	assert.Nil(t, user.MemberTierCode)
+}
+
+func TestJWTVerifier_HS256RotatedSecrets(t *testing.T) {
+	old := []byte("previous-secret")
+	v := newVerifier(t, JWTConfig{HS256Secrets: [][]byte{testSecret, old}})
+
+	_, err := v.Authenticate(context.Background(), signHS256(t, old, tokenClaims(nil)))
+	assert.NoError(t, err)
+
+	_, err = v.Authenticate(context.Background(), signHS256(t, []byte("forged"), tokenClaims(nil)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTVerifier_RejectsInvalidTokens(t *testing.T) {
+	v := newVerifier(t, JWTConfig{
+		HS256Secrets: [][]byte{testSecret},
+		Issuer:       "laondry-core",
+		Audience:     "laondry-order",
+		Leeway:       30 * time.Second,
+	})
+	valid := map[string]any{"iss": "laondry-core", "aud": []string{"laondry-mobile", "laondry-order"}}
+	with := func(overrides map[string]any) map[string]any {
+		claims := tokenClaims(valid)
+		for k, val := range overrides {
+			if val == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = val
+			}
+		}
+		return claims
+	}
+
+	_, err := v.Authenticate(context.Background(), signHS256(t, testSecret, with(nil)))
+	require.NoError(t, err)
+	// Within the leeway
+	_, err = v.Authenticate(context.Background(), signHS256(t, testSecret, with(map[string]any{"exp": time.Now().Add(-10 * time.Second).Unix()})))
+	require.NoError(t, err)
+
+	unsigned := encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, with(nil)) + "."
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"malformed", "not-a-jwt", ErrInvalidToken},
+		{"alg none", unsigned, ErrInvalidToken},
+		{"expired", signHS256(t, testSecret, with(map[string]any{"exp": time.Now().Add(-time.Minute).Unix()})), ErrTokenExpired},
+		{"no expiry", signHS256(t, testSecret, with(map[string]any{"exp": nil})), ErrInvalidToken},
+		{"not valid yet", signHS256(t, testSecret, with(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})), ErrInvalidToken},
+		{"no subject", signHS256(t, testSecret, with(map[string]any{"sub": nil})), ErrInvalidToken},
+		{"wrong issuer", signHS256(t, testSecret, with(map[string]any{"iss": "someone-else"})), ErrInvalidToken},
+		{"wrong audience", signHS256(t, testSecret, with(map[string]any{"aud": "laondry-mobile"})), ErrInvalidToken},
+		{"RS256 without keys", signRS256(t, newRSAKey(t), "", with(nil)), ErrVerificationUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := v.Authenticate(context.Background(), tc.token)
+			assert.Nil(t, user)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestJWTVerifier_RS256PEMKeys(t *testing.T) {
+	key := newRSAKey(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	other := newRSAKey(t)
+	data := append(
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&other.PublicKey)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...,
+	)
+	keys, err := ParseRSAPublicKeys(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	v := newVerifier(t, JWTConfig{RS256Keys: keys})
+	user, err := v.Authenticate(context.Background(), signRS256(t, key, "", tokenClaims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "kasir", user.Role)
+
+	_, err = v.Authenticate(context.Background(), signRS256(t, newRSAKey(t), "", tokenClaims(nil)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = ParseRSAPublicKeys([]byte("not pem"))
+	assert.Error(t, err)
+}
+
+func jwksDocument(keys map[string]*rsa.PrivateKey) []byte {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func TestJWTVerifier_JWKS(t *testing.T) {
+	first, second := newRSAKey(t), newRSAKey(t)
+	var document atomic.Value
+	document.Store(jwksDocument(map[string]*rsa.PrivateKey{"key-1": first}))
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	v := newVerifier(t, JWTConfig{JWKSURL: server.URL})
+	now := time.Now()
+	v.jwks.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := v.Authenticate(context.Background(), signRS256(t, first, "key-1", tokenClaims(nil)))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "keys are cached")
+
+	// A rotated key is fetched on first sight, at most once a minute
+	document.Store(jwksDocument(map[string]*rsa.PrivateKey{"key-1": first, "key-2": second}))
+	_, err := v.Authenticate(context.Background(), signRS256(t, second, "key-2", tokenClaims(nil)))
+	assert.ErrorIs(t, err, ErrVerificationUnavailable)
+	now = now.Add(jwksMinRefresh)
+	_, err = v.Authenticate(context.Background(), signRS256(t, second, "key-2", tokenClaims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// Known keys keep working while the endpoint is down
+	server.Close()
+	now = now.Add(2 * time.Hour)
+	_, err = v.Authenticate(context.Background(), signRS256(t, first, "key-1", tokenClaims(nil)))
+	assert.NoError(t, err)
+}
+
+func TestJWTVerifier_JWKSUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	v := newVerifier(t, JWTConfig{JWKSURL: server.URL})
+	_, err := v.Authenticate(context.Background(), signRS256(t, newRSAKey(t), "key-1", tokenClaims(nil)))
+	assert.ErrorIs(t, err, ErrVerificationUnavailable)
+}
+
+func TestJWTVerifier_TokenVersion(t *testing.T) {
+	userID := "7d3c6a9e-1f0b-4a53-9d1e-2b8f6c4e5a10"
+	versions := fakeTokenVersions{userID: 2}
+	v := newVerifier(t, JWTConfig{HS256Secrets: [][]byte{testSecret}, TokenVersions: versions})
+
+	_, err := v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(nil)))
+	require.NoError(t, err)
+
+	// Logging out everywhere bumps the version
+	versions[userID] = 3
+	_, err = v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(nil)))
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(map[string]any{"token_version": nil})))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	delete(versions, userID)
+	_, err = v.Authenticate(context.Background(), signHS256(t, testSecret, tokenClaims(map[string]any{"token_version": 3})))
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestUserTokenVersions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.User{}))
+
+	now := time.Now()
+	active := entity.User{FullName: "Active", PasswordHash: "hash", IsActive: true, TokenVersion: 4, CreatedAt: now, UpdatedAt: now}
+	blocked := entity.User{FullName: "Blocked", PasswordHash: "hash", IsActive: true, TokenVersion: 1, CreatedAt: now, UpdatedAt: now}
+	deleted := entity.User{FullName: "Deleted", PasswordHash: "hash", IsActive: true, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, db.Create(&[]*entity.User{&active, &blocked, &deleted}).Error)
+	require.NoError(t, db.Model(&blocked).Update("is_active", false).Error)
+	require.NoError(t, db.Delete(&deleted).Error)
+
+	versions := NewUserTokenVersions(db)
+	version, err := versions.CurrentTokenVersion(context.Background(), active.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 4, version)
+
+	for _, id := range []uuid.UUID{blocked.ID, deleted.ID, uuid.New()} {
+		_, err = versions.CurrentTokenVersion(context.Background(), id.String())
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	}
+	_, err = versions.CurrentTokenVersion(context.Background(), "42")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+type stubAuthenticator struct {
+	user  *UserClaims
+	err   error
+	calls int
+}
+
+func (s *stubAuthenticator) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	s.calls++
+	return s.user, s.err
+}
+
+func TestFallbackAuthenticator(t *testing.T) {
+	remoteUser := &UserClaims{UserID: "user-123", Role: "customer"}
+
+	t.Run("falls back when local verification is unavailable", func(t *testing.T) {
+		remote := &stubAuthenticator{user: remoteUser}
+		a := FallbackAuthenticator{Local: &stubAuthenticator{err: ErrVerificationUnavailable}, Remote: remote}
+		user, err := a.Authenticate(context.Background(), "token")
+		require.NoError(t, err)
+		assert.Equal(t, remoteUser, user)
+		assert.Equal(t, 1, remote.calls)
+	})
+
+	t.Run("does not retry rejected tokens", func(t *testing.T) {
+		remote := &stubAuthenticator{user: remoteUser}
+		a := FallbackAuthenticator{Local: &stubAuthenticator{err: ErrTokenRevoked}, Remote: remote}
+		_, err := a.Authenticate(context.Background(), "token")
+		assert.True(t, errors.Is(err, ErrTokenRevoked))
+		assert.Equal(t, 0, remote.calls)
+	})
+}
+
+func TestAuth_LocalVerification(t *testing.T) {
+	SetAuthenticator(newVerifier(t, JWTConfig{HS256Secrets: [][]byte{testSecret}}))
+	defer SetAuthenticator(CoreAPIAuthenticator{})
+	// core-api must not be called
+	SetCoreAPIURL("http://localhost:99999")
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, testSecret, tokenClaims(nil)))
+	rr := httptest.NewRecorder()
+	Auth(mockHandler()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response map[string]any
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	user := response["user"].(map[string]any)
+	assert.Equal(t, "7d3c6a9e-1f0b-4a53-9d1e-2b8f6c4e5a10", user["user_id"])
+	assert.Equal(t, "GOLD", user["member_tier_code"])
+
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, []byte("forged"), tokenClaims(nil)))
+	rr = httptest.NewRecorder()
+	Auth(mockHandler()).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
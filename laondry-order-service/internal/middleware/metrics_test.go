@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Use(Recovery)
+	r.Get("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/panic", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	observations := func(route, status string) uint64 {
+		var m dto.Metric
+		h := metrics.HTTPRequestDuration.WithLabelValues(http.MethodGet, route, status).(prometheus.Metric)
+		require.NoError(t, h.Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(2), observations("/metrics-test/{id}", "204"), "one series for both IDs")
+	assert.Equal(t, uint64(1), observations("/metrics-test/{id}", "500"))
+	assert.Equal(t, uint64(1), observations("unmatched", "404"))
+}
@@ -22,7 +22,7 @@ func NewRelic(app *newrelic.Application) func(http.Handler) http.Handler {
 			txn.SetWebRequestHTTP(r)
 
 			// Wrap response writer
-			w = txn.SetWebResponse(w)
+			w = &nrResponseWriter{ResponseWriter: txn.SetWebResponse(w), inner: w}
 
 			// Pass transaction via context
 			ctx := newrelic.NewContext(r.Context(), txn)
@@ -30,3 +30,21 @@ func NewRelic(app *newrelic.Application) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// nrResponseWriter lets http.ResponseController reach the writer New Relic
+// wraps, e.g. to lift the write deadline of streamed responses; New Relic's
+// own wrapper does not expose it.
+type nrResponseWriter struct {
+	http.ResponseWriter
+	inner http.ResponseWriter
+}
+
+func (w *nrResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *nrResponseWriter) Unwrap() http.ResponseWriter {
+	return w.inner
+}
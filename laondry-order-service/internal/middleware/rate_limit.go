@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"laondry-order-service/pkg/response"
+)
+
+// Rate-limited route groups
+const (
+	RateLimitQuote        = "quote"
+	RateLimitOrders       = "orders"
+	RateLimitNotification = "midtrans-notification"
+)
+
+// RateLimitRule allows bursts of Burst requests, refilled at PerMinute
+type RateLimitRule struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimitStore keeps token buckets
+type RateLimitStore interface {
+	// Take removes a token from the bucket key, which holds up to burst
+	// tokens refilled at rate per second. When the bucket is empty it
+	// reports how long until the next token.
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+// RateLimiter limits requests per caller and route group. A caller is the
+// API key or user authenticated by Auth, else the client IP, so Limit
+// should be mounted after Auth where there is one.
+type RateLimiter struct {
+	store      RateLimitStore
+	rules      map[string]RateLimitRule
+	trustProxy bool
+}
+
+// NewRateLimiter limits the groups in rules; groups without a rule, or with
+// PerMinute 0, are not limited. A nil limiter or store limits nothing.
+func NewRateLimiter(store RateLimitStore, rules map[string]RateLimitRule, trustProxy bool) *RateLimiter {
+	return &RateLimiter{store: store, rules: rules, trustProxy: trustProxy}
+}
+
+// Limit returns the middleware for a route group. Requests over the limit
+// get 429 with Retry-After. If the store fails, requests are let through.
+func (l *RateLimiter) Limit(group string) func(http.Handler) http.Handler {
+	var rule RateLimitRule
+	if l != nil && l.store != nil {
+		rule = l.rules[group]
+	}
+	if rule.PerMinute <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	burst := rule.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	rate := float64(rule.PerMinute) / 60
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := group + ":" + l.caller(r)
+			allowed, retryAfter, err := l.store.Take(r.Context(), key, rate, burst)
+			if err != nil {
+				slog.WarnContext(r.Context(), "[RateLimit] Cannot check limit, letting request through", "key", key, "error", err)
+			} else if !allowed {
+				response.TooManyRequests(w, "Too many requests, please retry later", retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// caller identifies who a bucket belongs to
+func (l *RateLimiter) caller(r *http.Request) string {
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		if principal := user.Principal(); principal != "" {
+			return principal
+		}
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP is the address of the client. Behind a trusted proxy it is the
+// X-Real-IP the proxy set, or the last X-Forwarded-For hop, which the proxy
+// appended; earlier hops are client-supplied.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	return remoteIP(r)
+}
+
+// memoryRateLimitLimit bounds the buckets of a MemoryRateLimitStore
+const memoryRateLimitLimit = 100000
+
+// MemoryRateLimitStore keeps buckets in this process only; with several
+// instances each one allows the full rate
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket is full again and can be dropped
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= memoryRateLimitLimit {
+			s.sweep(now)
+		}
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	var wait time.Duration
+	if allowed {
+		b.tokens--
+	} else {
+		wait = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return allowed, wait, nil
+}
+
+// sweep drops buckets that refilled; a full bucket is the same as none
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
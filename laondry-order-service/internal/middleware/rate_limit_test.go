@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	return false, 0, errors.New("redis: connection refused")
+}
+
+func TestMemoryRateLimitStore_RefillsTokens(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// 2 tokens, one per second
+	for i := 0; i < 2; i++ {
+		ok, _, err := store.Take(ctx, "k", 1, 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, _ := store.Take(ctx, "k", 1, 2)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, wait, _ = store.Take(ctx, "k", 1, 2)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _, _ = store.Take(ctx, "k", 1, 2)
+	assert.True(t, ok)
+
+	ok, _, _ = store.Take(ctx, "other", 1, 2)
+	assert.True(t, ok, "buckets are per key")
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limiter := NewRateLimiter(store, map[string]RateLimitRule{
+		RateLimitQuote: {PerMinute: 60, Burst: 2},
+	}, false)
+	handler := limiter.Limit(RateLimitQuote)(mockHandler())
+
+	do := func(remoteAddr string, user *UserClaims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/quote", nil)
+		req.RemoteAddr = remoteAddr
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, user))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", nil).Code)
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:2000", nil).Code)
+	rr := do("10.0.0.1:3000", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	// Another IP, user or API key has its own bucket
+	assert.Equal(t, http.StatusOK, do("10.0.0.2:1000", nil).Code)
+	user := &UserClaims{UserID: "user-1"}
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", user).Code)
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", user).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.2:1000", user).Code, "users are limited wherever they come from")
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", &UserClaims{APIKeyID: "key-1"}).Code)
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	for name, limiter := range map[string]*RateLimiter{
+		"nil limiter":  nil,
+		"nil store":    NewRateLimiter(nil, map[string]RateLimitRule{RateLimitQuote: {PerMinute: 1, Burst: 1}}, false),
+		"no rule":      NewRateLimiter(NewMemoryRateLimitStore(), nil, false),
+		"disabled":     NewRateLimiter(NewMemoryRateLimitStore(), map[string]RateLimitRule{RateLimitQuote: {Burst: 1}}, false),
+		"store outage": NewRateLimiter(failingRateLimitStore{}, map[string]RateLimitRule{RateLimitQuote: {PerMinute: 1, Burst: 1}}, false),
+	} {
+		handler := limiter.Limit(RateLimitQuote)(mockHandler())
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/quote", nil))
+			assert.Equal(t, http.StatusOK, rr.Code, name)
+		}
+	}
+}
+
+func TestRateLimiter_ClientIP(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/payments/midtrans/notification", nil)
+	req.RemoteAddr = "172.16.0.5:40000"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.9")
+
+	assert.Equal(t, "172.16.0.5", NewRateLimiter(nil, nil, false).clientIP(req), "proxy headers ignored unless trusted")
+	trusted := NewRateLimiter(nil, nil, true)
+	assert.Equal(t, "203.0.113.9", trusted.clientIP(req), "last hop, added by our proxy")
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	assert.Equal(t, "203.0.113.7", trusted.clientIP(req))
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("skipping redis tests, cannot connect to %s: %v", addr, err)
+	}
+
+	ctx := context.Background()
+	store := NewRedisRateLimitStore(client)
+	key := "test:" + time.Now().String()
+	defer client.Del(ctx, RateLimitKeyPrefix+key)
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := store.Take(ctx, key, 0.5, 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, err := store.Take(ctx, key, 0.5, 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.InDelta(t, 2*time.Second, wait, float64(100*time.Millisecond))
+	ttl, err := client.PTTL(ctx, RateLimitKeyPrefix+key).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0), "idle buckets expire")
+}
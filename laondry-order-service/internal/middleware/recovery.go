@@ -1,8 +1,9 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 
 	"laondry-order-service/pkg/response"
 )
@@ -11,7 +12,8 @@ func Recovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				slog.ErrorContext(r.Context(), "[HTTP] Panic recovered",
+					"panic", err, "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
 				response.InternalServerError(w, "Internal server error", "Something went wrong")
 			}
 		}()
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// TokenCacheKeyPrefix prefixes the Redis keys of cached tokens. Core-api can
+// drop a token on logout by deleting TokenCacheKeyPrefix + sha256 hex of it.
+const TokenCacheKeyPrefix = "laondry:auth-token:"
+
+// RedisTokenCache is a TokenCache shared by every instance
+type RedisTokenCache struct {
+	client *redis.Client
+}
+
+func NewRedisTokenCache(client *redis.Client) *RedisTokenCache {
+	return &RedisTokenCache{client: client}
+}
+
+func (c *RedisTokenCache) Get(ctx context.Context, key string) (*CachedToken, bool, error) {
+	data, err := c.client.Get(ctx, TokenCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var token CachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, false, err
+	}
+	return &token, true, nil
+}
+
+func (c *RedisTokenCache) Set(ctx context.Context, key string, token CachedToken, ttl time.Duration) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, TokenCacheKeyPrefix+key, data, ttl).Err()
+}
+
+func (c *RedisTokenCache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, TokenCacheKeyPrefix+key).Err()
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logger.RequestID(r.Context())
+	}))
+
+	t.Run("generated when missing", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		_, err := uuid.Parse(seen)
+		assert.NoError(t, err)
+		assert.Equal(t, seen, rr.Header().Get(logger.RequestIDHeader))
+	})
+
+	t.Run("kept from the caller", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(logger.RequestIDHeader, "core-api:4f2a-91")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, "core-api:4f2a-91", seen)
+		assert.Equal(t, "core-api:4f2a-91", rr.Header().Get(logger.RequestIDHeader))
+	})
+
+	t.Run("replaced when unsafe", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(logger.RequestIDHeader, "abc\" level=ERROR")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.NotEqual(t, "abc\" level=ERROR", seen)
+		_, err := uuid.Parse(seen)
+		assert.NoError(t, err)
+	})
+}
+
+func TestValidateTokenWithCoreAPI_ForwardsRequestID(t *testing.T) {
+	var forwarded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(logger.RequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":true,"data":{"id":"user-123","role":"customer"}}`))
+	}))
+	defer server.Close()
+	SetCoreAPIURL(server.URL)
+
+	ctx := logger.WithRequestID(context.Background(), "req-42")
+	user, err := validateTokenWithCoreAPI(ctx, "valid-token")
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", user.UserID)
+	assert.Equal(t, "req-42", forwarded)
+}
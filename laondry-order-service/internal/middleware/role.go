@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"laondry-order-service/pkg/response"
+)
+
+// Role slugs issued by core-api (see RoleSeeder)
+const (
+	RoleSuperAdmin = "superadmin"
+	RoleAdmin      = "admin"
+	RoleCustomer   = "customer"
+	RoleKaryawan   = "karyawan"
+	RoleKasir      = "kasir"
+	RoleKurir      = "kurir"
+	RoleCS         = "cs"
+	// RoleService is the role of API key principals, see IsAPIKey
+	RoleService = "service"
+)
+
+// AdminRoles may access back-office endpoints.
+var AdminRoles = []string{RoleSuperAdmin, RoleAdmin}
+
+// StaffRoles are outlet staff who may act on other users' payments.
+var StaffRoles = []string{RoleSuperAdmin, RoleAdmin, RoleKaryawan, RoleKasir, RoleCS}
+
+// CashierRoles may take payments at the outlet counter.
+var CashierRoles = []string{RoleSuperAdmin, RoleAdmin, RoleKasir}
+
+// RequireRole only lets requests through when the authenticated user has one
+// of the given roles. Must be mounted after Auth. API keys pass: Auth already
+// checked their permissions for the endpoint.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok || user == nil {
+				response.Unauthorized(w, "Authentication required")
+				return
+			}
+			if !user.IsAPIKey() && !HasRole(user, roles...) {
+				response.Forbidden(w, "You do not have permission to access this resource")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasRole reports whether the user has any of the given roles.
+func HasRole(user *UserClaims, roles ...string) bool {
+	if user == nil {
+		return false
+	}
+	role := strings.ToLower(user.Role)
+	for _, r := range roles {
+		if strings.ToLower(r) == role {
+			return true
+		}
+	}
+	return false
+}
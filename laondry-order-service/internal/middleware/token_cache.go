@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"golang.org/x/sync/singleflight"
+
+	"laondry-order-service/internal/metrics"
+	"laondry-order-service/pkg/response"
+)
+
+// CachedToken is the cached validation result of a token. User is nil for
+// rejected tokens.
+type CachedToken struct {
+	User *UserClaims `json:"user,omitempty"`
+}
+
+// TokenCache stores validation results by TokenCacheKey
+type TokenCache interface {
+	Get(ctx context.Context, key string) (*CachedToken, bool, error)
+	Set(ctx context.Context, key string, entry CachedToken, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// TokenCacheKey is the cache key of a token; tokens are never stored
+func TokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenCacheStats counts how validations were answered
+type TokenCacheStats struct {
+	Hits         uint64 // valid token found in the cache
+	NegativeHits uint64 // rejected token found in the cache
+	Misses       uint64 // validated by the next authenticator
+	Shared       uint64 // waited for a concurrent validation of the same token
+}
+
+// CachingAuthenticator caches the results of a slow authenticator, usually
+// core-api. Rejected tokens are cached for negativeTTL so a client retrying a
+// bad token does not reach core-api every time; outages are not cached.
+// Concurrent validations of the same token share one call.
+type CachingAuthenticator struct {
+	next        TokenAuthenticator
+	cache       TokenCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	group       singleflight.Group
+	now         func() time.Time
+
+	hits, negativeHits, misses, shared atomic.Uint64
+}
+
+func NewCachingAuthenticator(next TokenAuthenticator, cache TokenCache, ttl, negativeTTL time.Duration) *CachingAuthenticator {
+	return &CachingAuthenticator{
+		next:        next,
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+	}
+}
+
+func (a *CachingAuthenticator) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	key := TokenCacheKey(token)
+	entry, ok, err := a.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "[Auth] Token cache read failed", "error", err)
+	}
+	if ok {
+		if entry.User == nil {
+			a.record(ctx, &a.negativeHits, "NegativeHit", "negative_hit")
+			return nil, errCachedRejection
+		}
+		a.record(ctx, &a.hits, "Hit", "hit")
+		return copyClaims(entry.User), nil
+	}
+
+	// The shared call must not fail because the caller that started it hung up
+	callCtx := context.WithoutCancel(ctx)
+	leader := false
+	user, err, _ := a.group.Do(key, func() (any, error) {
+		leader = true
+		user, err := a.next.Authenticate(callCtx, token)
+		a.store(callCtx, key, token, user, err)
+		return user, err
+	})
+	if leader {
+		a.record(ctx, &a.misses, "Miss", "miss")
+	} else {
+		a.record(ctx, &a.shared, "Shared", "shared")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return copyClaims(user.(*UserClaims)), nil
+}
+
+var errCachedRejection = fmt.Errorf("%w: rejected recently", ErrInvalidToken)
+
+func (a *CachingAuthenticator) store(ctx context.Context, key, token string, user *UserClaims, err error) {
+	var entry CachedToken
+	ttl := a.negativeTTL
+	switch {
+	case err == nil:
+		entry.User = user
+		ttl = a.ttl
+		// Never serve a token past its expiry
+		if exp, ok := tokenExpiry(token); ok && exp.Sub(a.now()) < ttl {
+			ttl = exp.Sub(a.now())
+		}
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokenExpired), errors.Is(err, ErrTokenRevoked):
+	default:
+		return
+	}
+	if ttl <= 0 {
+		return
+	}
+	if err := a.cache.Set(ctx, key, entry, ttl); err != nil {
+		slog.WarnContext(ctx, "[Auth] Token cache write failed", "error", err)
+	}
+}
+
+// Invalidate drops the cached result of token, e.g. after a logout
+func (a *CachingAuthenticator) Invalidate(ctx context.Context, token string) error {
+	return a.cache.Delete(ctx, TokenCacheKey(token))
+}
+
+func (a *CachingAuthenticator) Stats() TokenCacheStats {
+	return TokenCacheStats{
+		Hits:         a.hits.Load(),
+		NegativeHits: a.negativeHits.Load(),
+		Misses:       a.misses.Load(),
+		Shared:       a.shared.Load(),
+	}
+}
+
+// record counts the result, exports it as auth_token_cache_lookups_total
+// {result=label} and reports it to New Relic as
+// Custom/Auth/TokenCache/<result>, from which the hit rate is charted
+func (a *CachingAuthenticator) record(ctx context.Context, counter *atomic.Uint64, result, label string) {
+	counter.Add(1)
+	metrics.AuthTokenCacheLookups.WithLabelValues(label).Inc()
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		txn.AddAttribute("authTokenCache", result)
+		if app := txn.Application(); app != nil {
+			app.RecordCustomMetric("Auth/TokenCache/"+result, 1)
+		}
+	}
+}
+
+func copyClaims(user *UserClaims) *UserClaims {
+	if user == nil {
+		return nil
+	}
+	c := *user
+	return &c
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var claims struct {
+		ExpiresAt *float64 `json:"exp"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}, false
+	}
+	return unixTime(*claims.ExpiresAt), true
+}
+
+// tokenInvalidator is implemented by authenticators that cache tokens
+type tokenInvalidator interface {
+	Invalidate(ctx context.Context, token string) error
+}
+
+// InvalidateTokenCache drops the cached validation result of the bearer
+// token. Core-api calls it with the user's token on logout; holding the
+// token is all it takes to drop it. Prefork workers share the Redis cache or
+// run without one, so the request may reach any worker.
+func InvalidateTokenCache(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		response.Unauthorized(w, "Invalid authorization header format")
+		return
+	}
+	if cache, ok := authenticator.(tokenInvalidator); ok {
+		if err := cache.Invalidate(r.Context(), parts[1]); err != nil {
+			slog.ErrorContext(r.Context(), "[Auth] Failed to clear token cache", "error", err)
+			response.InternalServerError(w, "Failed to clear token cache", nil)
+			return
+		}
+	}
+	response.Success(w, "Token cache cleared", nil)
+}
+
+// memoryTokenCacheLimit bounds the entries of a MemoryTokenCache
+const memoryTokenCacheLimit = 10000
+
+// MemoryTokenCache is a TokenCache for a single process
+type MemoryTokenCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryTokenEntry
+	now     func() time.Time
+}
+
+type memoryTokenEntry struct {
+	token     CachedToken
+	expiresAt time.Time
+}
+
+func NewMemoryTokenCache() *MemoryTokenCache {
+	return &MemoryTokenCache{entries: make(map[string]memoryTokenEntry), now: time.Now}
+}
+
+func (c *MemoryTokenCache) Get(ctx context.Context, key string) (*CachedToken, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	token := entry.token
+	return &token, true, nil
+}
+
+func (c *MemoryTokenCache) Set(ctx context.Context, key string, token CachedToken, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= memoryTokenCacheLimit {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: evict arbitrary entries, they are cheap to revalidate
+		for k := range c.entries {
+			if len(c.entries) < memoryTokenCacheLimit {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = memoryTokenEntry{token: token, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (c *MemoryTokenCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
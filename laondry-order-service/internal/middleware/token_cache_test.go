@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	redis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laondry-order-service/internal/metrics"
+)
+
+// coreAPIStub answers like core-api: valid-token is valid, anything else is
+// rejected. Calls block until release is closed, when set.
+type coreAPIStub struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (s *coreAPIStub) Authenticate(ctx context.Context, token string) (*UserClaims, error) {
+	s.calls.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if token != "valid-token" {
+		return nil, ErrInvalidToken
+	}
+	return &UserClaims{UserID: "user-123", Role: "customer"}, nil
+}
+
+func newTestCache(next TokenAuthenticator) (*CachingAuthenticator, *MemoryTokenCache, *time.Time) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	cache := NewMemoryTokenCache()
+	cache.now = clock
+	a := NewCachingAuthenticator(next, cache, time.Minute, 10*time.Second)
+	a.now = clock
+	return a, cache, &now
+}
+
+func TestCachingAuthenticator_CachesValidTokens(t *testing.T) {
+	core := &coreAPIStub{}
+	a, _, now := newTestCache(core)
+	hits := testutil.ToFloat64(metrics.AuthTokenCacheLookups.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(metrics.AuthTokenCacheLookups.WithLabelValues("miss"))
+
+	for i := 0; i < 3; i++ {
+		user, err := a.Authenticate(context.Background(), "valid-token")
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", user.UserID)
+		// Callers get their own copy
+		user.Role = "admin"
+	}
+	assert.Equal(t, int32(1), core.calls.Load())
+	assert.Equal(t, TokenCacheStats{Hits: 2, Misses: 1}, a.Stats())
+	assert.Equal(t, hits+2, testutil.ToFloat64(metrics.AuthTokenCacheLookups.WithLabelValues("hit")))
+	assert.Equal(t, misses+1, testutil.ToFloat64(metrics.AuthTokenCacheLookups.WithLabelValues("miss")))
+
+	*now = now.Add(time.Minute)
+	user, err := a.Authenticate(context.Background(), "valid-token")
+	require.NoError(t, err)
+	assert.Equal(t, "customer", user.Role)
+	assert.Equal(t, int32(2), core.calls.Load(), "revalidated after the TTL")
+}
+
+func TestCachingAuthenticator_CachesRejections(t *testing.T) {
+	core := &coreAPIStub{}
+	a, _, now := newTestCache(core)
+
+	for i := 0; i < 3; i++ {
+		_, err := a.Authenticate(context.Background(), "bad-token")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+	assert.Equal(t, int32(1), core.calls.Load())
+	assert.Equal(t, TokenCacheStats{NegativeHits: 2, Misses: 1}, a.Stats())
+
+	*now = now.Add(10 * time.Second)
+	_, err := a.Authenticate(context.Background(), "bad-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(2), core.calls.Load())
+}
+
+func TestCachingAuthenticator_DoesNotCacheOutages(t *testing.T) {
+	core := &coreAPIStub{err: errors.New("failed to call core-api: connection refused")}
+	a, _, _ := newTestCache(core)
+
+	for i := 0; i < 2; i++ {
+		_, err := a.Authenticate(context.Background(), "valid-token")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(2), core.calls.Load())
+
+	core.err = nil
+	_, err := a.Authenticate(context.Background(), "valid-token")
+	assert.NoError(t, err)
+}
+
+func TestCachingAuthenticator_CapsTTLAtTokenExpiry(t *testing.T) {
+	token := signHS256(t, testSecret, tokenClaims(map[string]any{"exp": time.Now().Add(5 * time.Second).Unix()}))
+	next := newVerifier(t, JWTConfig{HS256Secrets: [][]byte{testSecret}})
+	a, cache, now := newTestCache(next)
+
+	_, err := a.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	_, ok, _ := cache.Get(context.Background(), TokenCacheKey(token))
+	assert.True(t, ok)
+
+	*now = now.Add(6 * time.Second)
+	_, ok, _ = cache.Get(context.Background(), TokenCacheKey(token))
+	assert.False(t, ok, "cached past the token's expiry")
+}
+
+func TestCachingAuthenticator_CollapsesConcurrentValidations(t *testing.T) {
+	core := &coreAPIStub{release: make(chan struct{})}
+	a := NewCachingAuthenticator(core, NewMemoryTokenCache(), time.Minute, 10*time.Second)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	var started sync.WaitGroup
+	wg.Add(callers)
+	started.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			started.Done()
+			user, err := a.Authenticate(context.Background(), "valid-token")
+			assert.NoError(t, err)
+			assert.Equal(t, "user-123", user.UserID)
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(core.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), core.calls.Load())
+	stats := a.Stats()
+	assert.Equal(t, uint64(callers), stats.Misses+stats.Shared+stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestInvalidateTokenCache(t *testing.T) {
+	core := &coreAPIStub{}
+	a, _, _ := newTestCache(core)
+	SetAuthenticator(FallbackAuthenticator{Local: &stubAuthenticator{err: ErrVerificationUnavailable}, Remote: a})
+	defer SetAuthenticator(CoreAPIAuthenticator{})
+
+	_, err := a.Authenticate(context.Background(), "valid-token")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/auth/token-cache", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	rr := httptest.NewRecorder()
+	InvalidateTokenCache(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	_, err = a.Authenticate(context.Background(), "valid-token")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), core.calls.Load(), "revalidated after logout")
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/auth/token-cache", nil)
+	rr = httptest.NewRecorder()
+	InvalidateTokenCache(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// A cache outage is logged, its details are not sent to the caller
+	SetAuthenticator(NewCachingAuthenticator(core, brokenTokenCache{}, time.Minute, 10*time.Second))
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/auth/token-cache", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	rr = httptest.NewRecorder()
+	InvalidateTokenCache(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "dial tcp")
+}
+
+// brokenTokenCache fails like an unreachable Redis
+type brokenTokenCache struct{}
+
+func (brokenTokenCache) Get(ctx context.Context, key string) (*CachedToken, bool, error) {
+	return nil, false, errors.New("dial tcp 10.0.0.5:6379: connection refused")
+}
+
+func (brokenTokenCache) Set(ctx context.Context, key string, entry CachedToken, ttl time.Duration) error {
+	return errors.New("dial tcp 10.0.0.5:6379: connection refused")
+}
+
+func (brokenTokenCache) Delete(ctx context.Context, key string) error {
+	return errors.New("dial tcp 10.0.0.5:6379: connection refused")
+}
+
+func TestRedisTokenCache(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("skipping redis tests, cannot connect to %s: %v", addr, err)
+	}
+
+	ctx := context.Background()
+	cache := NewRedisTokenCache(client)
+	key := TokenCacheKey("redis-test-" + time.Now().String())
+	defer cache.Delete(ctx, key)
+
+	tier := "GOLD"
+	require.NoError(t, cache.Set(ctx, key, CachedToken{User: &UserClaims{UserID: "user-123", MemberTierCode: &tier}}, time.Minute))
+	got, ok, err := cache.Get(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "user-123", got.User.UserID)
+	assert.Equal(t, "GOLD", *got.User.MemberTierCode)
+
+	require.NoError(t, cache.Delete(ctx, key))
+	_, ok, err = cache.Get(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/entity"
+)
+
+// UserTokenVersions reads token versions from the users table shared with
+// core-api
+type UserTokenVersions struct {
+	db *gorm.DB
+}
+
+func NewUserTokenVersions(db *gorm.DB) *UserTokenVersions {
+	return &UserTokenVersions{db: db}
+}
+
+func (u *UserTokenVersions) CurrentTokenVersion(ctx context.Context, userID string) (int, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: sub is not a user ID", ErrInvalidToken)
+	}
+	var user entity.User
+	err = u.db.WithContext(ctx).Select("id", "token_version", "is_active").Where("id = ?", id).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%w: user not found", ErrTokenRevoked)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !user.IsActive {
+		return 0, fmt.Errorf("%w: user is blocked", ErrTokenRevoked)
+	}
+	return user.TokenVersion, nil
+}
@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"laondry-order-service/internal/entity"
+	"laondry-order-service/internal/retry"
+	"laondry-order-service/internal/scheduler"
+)
+
+const (
+	defaultMaxAttempts = 10
+	defaultBatchSize   = 50
+)
+
+// backoff schedules retries of failed messages
+var backoff = retry.Backoff{Base: 5 * time.Second, Max: time.Hour}
+
+// Handler delivers one message. Returning an error schedules a retry, so
+// handlers must be idempotent.
+type Handler func(ctx context.Context, msg entity.OutboxMessage) error
+
+type DispatchResult struct {
+	Delivered int `json:"delivered"`
+	Retried   int `json:"retried"`
+	Dead      int `json:"dead"`
+}
+
+type Dispatcher struct {
+	store       Store
+	handlers    map[string]Handler
+	maxAttempts int
+	batchSize   int
+	now         func() time.Time
+}
+
+func NewDispatcher(store Store, maxAttempts int) *Dispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	return &Dispatcher{
+		store:       store,
+		handlers:    make(map[string]Handler),
+		maxAttempts: maxAttempts,
+		batchSize:   defaultBatchSize,
+		now:         time.Now,
+	}
+}
+
+// Register sets the handler for a topic. Must be called before dispatching.
+func (d *Dispatcher) Register(topic string, h Handler) {
+	d.handlers[topic] = h
+}
+
+// Dispatch delivers due messages once
+func (d *Dispatcher) Dispatch(ctx context.Context) (*DispatchResult, error) {
+	msgs, err := d.store.ListDue(ctx, d.now(), d.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("list outbox messages: %w", err)
+	}
+
+	policy := retry.Policy{Backoff: backoff, MaxAttempts: d.maxAttempts}
+	counts := retry.Due(ctx, msgs, policy, d.now, d.attempt, d.settle)
+	return &DispatchResult{Delivered: counts.Done, Retried: counts.Retried, Dead: counts.GaveUp}, nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, msg *entity.OutboxMessage) (int, error) {
+	err := d.deliver(ctx, msg)
+	msg.Attempts++
+	return msg.Attempts, err
+}
+
+func (d *Dispatcher) settle(ctx context.Context, msg *entity.OutboxMessage, o retry.Outcome, err error, now, next time.Time) {
+	switch o {
+	case retry.Done:
+		msg.Status = StatusDone
+		msg.ProcessedAt = &now
+		msg.LastError = nil
+	case retry.GiveUp:
+		slog.ErrorContext(ctx, "[Outbox] Message failed permanently", "message_id", msg.ID, "topic", msg.Topic,
+			"aggregate_id", msg.AggregateID, "attempts", msg.Attempts, "error", err)
+		msg.Status = StatusDead
+		msg.LastError = strPtr(err.Error())
+	case retry.Retry:
+		slog.WarnContext(ctx, "[Outbox] Message attempt failed", "message_id", msg.ID, "topic", msg.Topic,
+			"aggregate_id", msg.AggregateID, "attempts", msg.Attempts, "error", err)
+		msg.NextAttemptAt = next
+		msg.LastError = strPtr(err.Error())
+	}
+	if err := d.store.Save(ctx, msg); err != nil {
+		slog.WarnContext(ctx, "[Outbox] Failed to save message", "message_id", msg.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, msg *entity.OutboxMessage) (err error) {
+	h, ok := d.handlers[msg.Topic]
+	if !ok {
+		return fmt.Errorf("no handler registered for topic %s", msg.Topic)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, *msg)
+}
+
+// Job returns a scheduler job that dispatches due messages every interval
+func (d *Dispatcher) Job(interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "outbox-dispatch",
+		Interval: interval,
+		LockTTL:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := d.Dispatch(ctx)
+			return err
+		},
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"laondry-order-service/internal/entity"
+)
+
+type memoryStore struct {
+	msgs []*entity.OutboxMessage
+}
+
+func (s *memoryStore) ListDue(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error) {
+	var due []entity.OutboxMessage
+	for _, m := range s.msgs {
+		if m.Status == StatusPending && !m.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, *m)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, msg *entity.OutboxMessage) error {
+	for i, m := range s.msgs {
+		if m.ID == msg.ID {
+			copied := *msg
+			s.msgs[i] = &copied
+		}
+	}
+	return nil
+}
+
+func enqueue(t *testing.T, s *memoryStore, topic string, payload interface{}) *entity.OutboxMessage {
+	msg, err := NewMessage(topic, nil, payload)
+	assert.NoError(t, err)
+	msg.ID = uuid.New()
+	s.msgs = append(s.msgs, msg)
+	return msg
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Delivers and decodes payload", func(t *testing.T) {
+		store := &memoryStore{}
+		orderID := uuid.New()
+		enqueue(t, store, TopicOrderCanceled, OrderCanceled{OrderID: orderID})
+
+		d := NewDispatcher(store, 3)
+		d.now = func() time.Time { return now }
+		var got OrderCanceled
+		d.Register(TopicOrderCanceled, func(ctx context.Context, msg entity.OutboxMessage) error {
+			return Decode(msg, &got)
+		})
+
+		res, err := d.Dispatch(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Delivered)
+		assert.Equal(t, orderID, got.OrderID)
+		assert.Equal(t, StatusDone, store.msgs[0].Status)
+		assert.NotNil(t, store.msgs[0].ProcessedAt)
+	})
+
+	t.Run("Failed delivery is retried with backoff then marked dead", func(t *testing.T) {
+		store := &memoryStore{}
+		enqueue(t, store, TopicOrderCanceled, map[string]string{})
+
+		d := NewDispatcher(store, 2)
+		d.now = func() time.Time { return now }
+		d.Register(TopicOrderCanceled, func(ctx context.Context, msg entity.OutboxMessage) error {
+			return errors.New("gateway down")
+		})
+
+		res, _ := d.Dispatch(ctx)
+		assert.Equal(t, 1, res.Retried)
+		assert.Equal(t, StatusPending, store.msgs[0].Status)
+		assert.Equal(t, now.Add(backoff.Base), store.msgs[0].NextAttemptAt)
+		assert.Equal(t, "gateway down", *store.msgs[0].LastError)
+
+		// Not due yet
+		res, _ = d.Dispatch(ctx)
+		assert.Equal(t, DispatchResult{}, *res)
+
+		d.now = func() time.Time { return now.Add(time.Minute) }
+		res, _ = d.Dispatch(ctx)
+		assert.Equal(t, 1, res.Dead)
+		assert.Equal(t, StatusDead, store.msgs[0].Status)
+		assert.Equal(t, 2, store.msgs[0].Attempts)
+	})
+
+	t.Run("Unknown topic is retried", func(t *testing.T) {
+		store := &memoryStore{}
+		enqueue(t, store, "unknown.topic", map[string]string{})
+
+		res, _ := NewDispatcher(store, 3).Dispatch(ctx)
+
+		assert.Equal(t, 1, res.Retried)
+		assert.Contains(t, *store.msgs[0].LastError, "no handler")
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, backoff.Delay(1))
+	assert.Equal(t, 10*time.Second, backoff.Delay(2))
+	assert.Equal(t, 40*time.Second, backoff.Delay(4))
+	assert.Equal(t, time.Hour, backoff.Delay(20))
+}
@@ -0,0 +1,67 @@
+// Package outbox implements a transactional outbox: side effects are stored
+// as rows in the same database transaction as the business change, then
+// delivered by a Dispatcher that retries with backoff until the handler succeeds.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"laondry-order-service/internal/entity"
+)
+
+const (
+	StatusPending = "PENDING"
+	StatusDone    = "DONE"
+	StatusDead    = "DEAD"
+)
+
+// Topics
+const (
+	TopicOrderCanceled    = "order.canceled"
+	TopicPaymentSucceeded = "payment.succeeded"
+)
+
+// OrderCanceled is the payload of TopicOrderCanceled
+type OrderCanceled struct {
+	OrderID    uuid.UUID  `json:"order_id"`
+	CanceledBy *uuid.UUID `json:"canceled_by,omitempty"`
+	Reason     *string    `json:"reason,omitempty"`
+}
+
+// PaymentSucceeded is the payload of TopicPaymentSucceeded: the order is
+// confirmed in core-api once its payments cover the total
+type PaymentSucceeded struct {
+	OrderID   uuid.UUID `json:"order_id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Note      string    `json:"note"`
+}
+
+// NewMessage builds an outbox message with payload encoded as JSON
+func NewMessage(topic string, aggregateID *uuid.UUID, payload interface{}) (*entity.OutboxMessage, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal outbox payload: %w", err)
+	}
+	var data entity.JSONB
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("outbox payload must be a JSON object: %w", err)
+	}
+	return &entity.OutboxMessage{
+		Topic:       topic,
+		AggregateID: aggregateID,
+		Payload:     data,
+		Status:      StatusPending,
+	}, nil
+}
+
+// Decode unmarshals a message payload into v
+func Decode(msg entity.OutboxMessage, v interface{}) error {
+	raw, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/entity"
+)
+
+type Store interface {
+	// ListDue returns PENDING messages whose next attempt is due, oldest first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error)
+	Save(ctx context.Context, msg *entity.OutboxMessage) error
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) ListDue(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error) {
+	var msgs []entity.OutboxMessage
+	err := s.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&msgs).Error
+	return msgs, err
+}
+
+func (s *gormStore) Save(ctx context.Context, msg *entity.OutboxMessage) error {
+	return s.db.WithContext(ctx).Save(msg).Error
+}
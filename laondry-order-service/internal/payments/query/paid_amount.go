@@ -0,0 +1,36 @@
+// Package query holds payment queries shared by the repositories of the
+// order and payment domains, so neither has to import the other.
+package query
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"laondry-order-service/internal/entity"
+)
+
+// SettledStatuses are payment statuses whose gross amount reached us
+var SettledStatuses = []string{"SUCCESS", "PARTIALLY_REFUNDED", "REFUNDED"}
+
+// SumPaidAmount totals what settled payments of an order paid towards it
+// (without payment method surcharges) minus successful refunds. A refund may
+// include the surcharge, which never counted towards the order, so the
+// refunds of each transaction are capped at what it paid towards the order.
+// db may be a transaction.
+func SumPaidAmount(ctx context.Context, db *gorm.DB, orderID uuid.UUID) (float64, error) {
+	db = db.WithContext(ctx)
+	refunded := db.Model(&entity.PaymentRefund{}).
+		Select("COALESCE(SUM(payment_refunds.amount), 0)").
+		Where("payment_refunds.payment_transaction_id = payment_transactions.id AND payment_refunds.status = ?", "SUCCESS")
+	settled := db.Model(&entity.PaymentTransaction{}).
+		Select("payment_transactions.gross_amount - payment_transactions.surcharge_amount AS paid, (?) AS refunded", refunded).
+		Where("payment_transactions.order_id = ? AND payment_transactions.status IN ?", orderID, SettledStatuses)
+
+	var paid float64
+	err := db.Table("(?) AS settled", settled).
+		Select("COALESCE(SUM(CASE WHEN refunded < paid THEN paid - refunded ELSE 0 END), 0)").
+		Scan(&paid).Error
+	return paid, err
+}
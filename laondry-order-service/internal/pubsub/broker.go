@@ -0,0 +1,42 @@
+// Package pubsub fans out real-time order events to the clients streaming
+// them, within one process or, over Redis, across processes.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is delivered to subscribers of its order and of its outlet
+type Event struct {
+	ID         string          `json:"id"`   // unique per event, sent as the SSE id
+	Type       string          `json:"type"` // e.g. order.status_changed, payment.succeeded
+	OrderID    uuid.UUID       `json:"order_id"`
+	OutletID   uuid.UUID       `json:"outlet_id"`
+	CustomerID uuid.UUID       `json:"customer_id"`
+	Data       json.RawMessage `json:"data"`
+	At         time.Time       `json:"at"`
+}
+
+// Broker publishes events to every subscriber, in this process and others
+// sharing the broker
+type Broker interface {
+	Publish(ctx context.Context, evt Event) error
+	// Subscribe returns the events for key (see OrderKey and OutletKey)
+	// until ctx is done, when the channel is closed. Events are dropped for
+	// subscribers that fall behind.
+	Subscribe(ctx context.Context, key string) <-chan Event
+}
+
+// OrderKey subscribes to the events of one order
+func OrderKey(orderID uuid.UUID) string {
+	return "order:" + orderID.String()
+}
+
+// OutletKey subscribes to the events of every order of an outlet
+func OutletKey(outletID uuid.UUID) string {
+	return "outlet:" + outletID.String()
+}
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	redis "github.com/redis/go-redis/v9"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func expectNone(t *testing.T, ch <-chan Event) {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		t.Fatalf("unexpected event %+v", evt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testBroker(t *testing.T, publisher, subscriber Broker) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orderID, outletID := uuid.New(), uuid.New()
+	orderEvents := subscriber.Subscribe(ctx, OrderKey(orderID))
+	outletEvents := subscriber.Subscribe(ctx, OutletKey(outletID))
+	otherEvents := subscriber.Subscribe(ctx, OrderKey(uuid.New()))
+	time.Sleep(50 * time.Millisecond)
+
+	evt := Event{ID: "1", Type: "order.status_changed", OrderID: orderID, OutletID: outletID, Data: []byte(`{"to_status":"COMPLETED"}`)}
+	if err := publisher.Publish(ctx, evt); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	for _, ch := range []<-chan Event{orderEvents, outletEvents} {
+		got := receive(t, ch)
+		if got.ID != "1" || got.OrderID != orderID || string(got.Data) != `{"to_status":"COMPLETED"}` {
+			t.Fatalf("unexpected event %+v", got)
+		}
+	}
+	expectNone(t, otherEvents)
+
+	// Unsubscribing closes the channel
+	cancel()
+	select {
+	case _, ok := <-orderEvents:
+		if ok {
+			t.Fatalf("expected channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("channel not closed after unsubscribing")
+	}
+}
+
+func TestMemoryBroker(t *testing.T) {
+	b := NewMemoryBroker()
+	testBroker(t, b, b)
+}
+
+func TestMemoryBroker_DropsEventsForSlowSubscribers(t *testing.T) {
+	b := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orderID := uuid.New()
+	ch := b.Subscribe(ctx, OrderKey(orderID))
+	for i := 0; i < subscriberBuffer+5; i++ {
+		b.Publish(ctx, Event{OrderID: orderID})
+	}
+	if len(ch) != subscriberBuffer {
+		t.Fatalf("expected %d buffered events, got %d", subscriberBuffer, len(ch))
+	}
+}
+
+func TestRedisBroker_FansOutAcrossBrokers(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("skipping redis tests, cannot connect to %s: %v", addr, err)
+	}
+
+	channel := "test:order-events:" + uuid.NewString()
+	publisher := NewRedisBroker(client, channel)
+	defer publisher.Close()
+	subscriber := NewRedisBroker(client, channel) // another process
+	defer subscriber.Close()
+
+	testBroker(t, publisher, subscriber)
+}
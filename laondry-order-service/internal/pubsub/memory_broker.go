@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before
+// events are dropped for it
+const subscriberBuffer = 32
+
+// MemoryBroker delivers events within this process only. It suits a single
+// process; prefork workers each have their own and need RedisBroker.
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[string]map[chan Event]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, evt Event) error {
+	b.deliver(evt)
+	return nil
+}
+
+// deliver hands evt to the local subscribers of its order and outlet
+func (b *MemoryBroker) deliver(evt Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, key := range []string{OrderKey(evt.OrderID), OutletKey(evt.OutletID)} {
+		for ch := range b.subs[key] {
+			select {
+			case ch <- evt:
+			default:
+				slog.Warn("[PubSub] Subscriber is too slow, dropped event", "key", key, "event_id", evt.ID, "order_id", evt.OrderID)
+			}
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, key string) <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	if b.subs[key] == nil {
+		b.subs[key] = make(map[chan Event]struct{})
+	}
+	b.subs[key][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs[key], ch)
+		if len(b.subs[key]) == 0 {
+			delete(b.subs, key)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RedisBroker publishes events on a Redis channel every process subscribes
+// to; each process then delivers them to its own subscribers. It is needed
+// when prefork workers serve the same streams.
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+	local   *MemoryBroker
+	pubsub  *redis.PubSub
+}
+
+// NewRedisBroker subscribes to channel and starts delivering its events.
+// go-redis reconnects the subscription if the connection drops.
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	b := &RedisBroker{
+		client:  client,
+		channel: channel,
+		local:   NewMemoryBroker(),
+		pubsub:  client.Subscribe(context.Background(), channel),
+	}
+	go b.run()
+	return b
+}
+
+func (b *RedisBroker) run() {
+	for msg := range b.pubsub.Channel() {
+		var evt Event
+		if err := json.Unmarshal([]byte(msg.Payload), &evt); err != nil {
+			slog.Warn("[PubSub] Ignoring malformed event", "channel", b.channel, "error", err)
+			continue
+		}
+		b.local.deliver(evt)
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, evt Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	if err := b.client.Publish(ctx, b.channel, payload).Err(); err != nil {
+		// Keep this process's subscribers up to date while Redis is down
+		b.local.deliver(evt)
+		return fmt.Errorf("publish to redis: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, key string) <-chan Event {
+	return b.local.Subscribe(ctx, key)
+}
+
+// Close stops receiving events from Redis
+func (b *RedisBroker) Close() error {
+	return b.pubsub.Close()
+}
@@ -0,0 +1,21 @@
+// Package retry holds the retry schedule and send loop of background
+// senders such as the outbox dispatcher.
+package retry
+
+import "time"
+
+// Backoff is an exponential retry schedule: the delay starts at Base and
+// doubles per attempt, capped at Max
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay is how long to wait after the given failed attempt (1 for the first)
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
@@ -0,0 +1,23 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: 5 * time.Second, Max: time.Hour}
+	for attempt, want := range map[int]time.Duration{
+		0:  5 * time.Second,
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		4:  40 * time.Second,
+		10: 42*time.Minute + 40*time.Second,
+		11: time.Hour,
+		80: time.Hour,
+	} {
+		if got := b.Delay(attempt); got != want {
+			t.Fatalf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Outcome is what became of one attempt at a due item
+type Outcome int
+
+const (
+	// Done means the attempt succeeded
+	Done Outcome = iota
+	// Retry means the attempt failed and the item is due again later
+	Retry
+	// GiveUp means the last allowed attempt failed
+	GiveUp
+)
+
+// ErrSkip, returned by an attempt, leaves the item as it is for a later run
+var ErrSkip = errors.New("retry: item skipped")
+
+// Policy is how often and how soon failed items are tried again
+type Policy struct {
+	Backoff     Backoff
+	MaxAttempts int
+}
+
+// Counts tallies the outcomes of one run
+type Counts struct {
+	Done    int
+	Retried int
+	GaveUp  int
+}
+
+// Attempt tries one item and returns how many attempts it has had, this one
+// included
+type Attempt[T any] func(ctx context.Context, item *T) (attempts int, err error)
+
+// Settle records the outcome of an attempt; err is nil when it succeeded and
+// next, the time of the following attempt, is only set for retries
+type Settle[T any] func(ctx context.Context, item *T, o Outcome, err error, now, next time.Time)
+
+// Due attempts each item once, in order, and settles it by the policy. It
+// stops early when ctx is done; the remaining items stay due.
+func Due[T any](ctx context.Context, items []T, p Policy, now func() time.Time, attempt Attempt[T], settle Settle[T]) Counts {
+	var counts Counts
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		item := &items[i]
+		attempts, err := attempt(ctx, item)
+		if errors.Is(err, ErrSkip) {
+			continue
+		}
+		t := now()
+		switch {
+		case err == nil:
+			settle(ctx, item, Done, nil, t, time.Time{})
+			counts.Done++
+		case attempts >= p.MaxAttempts:
+			settle(ctx, item, GiveUp, err, t, time.Time{})
+			counts.GaveUp++
+		default:
+			settle(ctx, item, Retry, err, t, t.Add(p.Backoff.Delay(attempts)))
+			counts.Retried++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type item struct {
+	name     string
+	attempts int
+	fail     bool
+	skip     bool
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := Policy{Backoff: Backoff{Base: time.Minute, Max: time.Hour}, MaxAttempts: 3}
+	items := []item{
+		{name: "ok"},
+		{name: "first failure", fail: true},
+		{name: "last failure", attempts: 2, fail: true},
+		{name: "skipped", skip: true},
+	}
+
+	type settled struct {
+		outcome Outcome
+		next    time.Time
+	}
+	got := map[string]settled{}
+	counts := Due(context.Background(), items, policy, func() time.Time { return now },
+		func(ctx context.Context, it *item) (int, error) {
+			if it.skip {
+				return 0, ErrSkip
+			}
+			it.attempts++
+			if it.fail {
+				return it.attempts, errors.New("down")
+			}
+			return it.attempts, nil
+		},
+		func(ctx context.Context, it *item, o Outcome, err error, now, next time.Time) {
+			if (o == Done) != (err == nil) {
+				t.Fatalf("%s: outcome %d with error %v", it.name, o, err)
+			}
+			got[it.name] = settled{o, next}
+		})
+
+	if want := (Counts{Done: 1, Retried: 1, GaveUp: 1}); counts != want {
+		t.Fatalf("counts = %+v, want %+v", counts, want)
+	}
+	want := map[string]settled{
+		"ok":            {Done, time.Time{}},
+		"first failure": {Retry, now.Add(time.Minute)},
+		"last failure":  {GiveUp, time.Time{}},
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Fatalf("%s settled as %+v, want %+v", name, got[name], w)
+		}
+	}
+	if _, ok := got["skipped"]; ok {
+		t.Fatal("skipped item was settled")
+	}
+}
+
+func TestDue_StopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	counts := Due(ctx, []item{{name: "a"}}, Policy{MaxAttempts: 1}, time.Now,
+		func(ctx context.Context, it *item) (int, error) { calls++; return 1, nil },
+		func(ctx context.Context, it *item, o Outcome, err error, now, next time.Time) {})
+
+	if calls != 0 || counts != (Counts{}) {
+		t.Fatalf("attempted %d items after cancel, counts %+v", calls, counts)
+	}
+}
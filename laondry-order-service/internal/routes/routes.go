@@ -4,32 +4,47 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"gorm.io/gorm"
 
-	"laondry-order-service/internal/config"
+	"laondry-order-service/internal/domain/apikey"
+	"laondry-order-service/internal/domain/notification"
 	"laondry-order-service/internal/domain/order"
 	"laondry-order-service/internal/domain/payment"
+	"laondry-order-service/internal/domain/realtime"
+	"laondry-order-service/internal/domain/webhook"
+	"laondry-order-service/internal/idempotency"
 	"laondry-order-service/internal/middleware"
 	"laondry-order-service/pkg/response"
-	"laondry-order-service/pkg/validator"
 )
 
 type Router struct {
-	orderDomain   *order.OrderDomain
-	paymentDomain *payment.PaymentDomain
+	orderDomain        *order.OrderDomain
+	paymentDomain      *payment.PaymentDomain
+	notificationDomain *notification.NotificationDomain
+	webhookDomain      *webhook.WebhookDomain
+	realtimeDomain     *realtime.RealtimeDomain
+	apikeyDomain       *apikey.APIKeyDomain
+	rateLimiter        *middleware.RateLimiter
+	idempotency        *idempotency.Middleware
 }
 
-func NewRouter(cfg *config.Config, db *gorm.DB, validator *validator.Validator, orderDomain *order.OrderDomain) *Router {
-	paymentDomain := payment.NewPaymentDomain(cfg, validator, db)
+func NewRouter(orderDomain *order.OrderDomain, paymentDomain *payment.PaymentDomain, notificationDomain *notification.NotificationDomain, webhookDomain *webhook.WebhookDomain, realtimeDomain *realtime.RealtimeDomain, apikeyDomain *apikey.APIKeyDomain, rateLimiter *middleware.RateLimiter, idempotency *idempotency.Middleware) *Router {
 	return &Router{
-		orderDomain:   orderDomain,
-		paymentDomain: paymentDomain,
+		orderDomain:        orderDomain,
+		paymentDomain:      paymentDomain,
+		notificationDomain: notificationDomain,
+		webhookDomain:      webhookDomain,
+		realtimeDomain:     realtimeDomain,
+		apikeyDomain:       apikeyDomain,
+		rateLimiter:        rateLimiter,
+		idempotency:        idempotency,
 	}
 }
 
 func (rt *Router) Setup() http.Handler {
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Metrics)
 	r.Use(middleware.Recovery)
 	r.Use(middleware.Logger)
 	r.Use(middleware.CORS)
@@ -42,31 +57,77 @@ func (rt *Router) Setup() http.Handler {
 	})
 
 	r.Route("/api/v1", func(r chi.Router) {
-		// Protected routes - require authentication
+		// Core-api drops a logged out token from the validation cache
+		r.Delete("/auth/token-cache", middleware.InvalidateTokenCache)
+
+		// Protected routes - require authentication. API keys of internal
+		// callers may only reach the order endpoints their permissions allow,
+		// see middleware.apiKeyRoutes.
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.Auth)
 
 			// Quote endpoint (calculate pricing)
-			r.Post("/quote", rt.orderDomain.QuoteHandler.CalculateQuote)
+			r.With(rt.rateLimiter.Limit(middleware.RateLimitQuote)).
+				Post("/quote", rt.orderDomain.QuoteHandler.CalculateQuote)
 
 			// Orders endpoints
 			r.Route("/orders", func(r chi.Router) {
-				r.Post("/", rt.orderDomain.Handler.CreateOrder)
+				r.Use(rt.rateLimiter.Limit(middleware.RateLimitOrders))
+
+				// Retries with the same Idempotency-Key get the first response
+				r.With(rt.idempotency.Handler).Post("/", rt.orderDomain.Handler.CreateOrder)
 				r.Get("/", rt.orderDomain.Handler.GetOrders)
 				r.Get("/{id}", rt.orderDomain.Handler.GetOrderByID)
 				r.Get("/order-no/{orderNo}", rt.orderDomain.Handler.GetOrderByOrderNo)
 				r.Get("/{id}/status-logs", rt.orderDomain.Handler.GetOrderStatusLogs)
 				r.Get("/{id}/timeline", rt.orderDomain.Handler.GetOrderStatusLogs) // Alias for mobile
+				r.Get("/{id}/invoice.pdf", rt.orderDomain.InvoiceHandler.GetInvoicePDF)
+				r.Get("/{id}/receipt.pdf", rt.orderDomain.InvoiceHandler.GetReceiptPDF)
+				r.Get("/{id}/receipt.escpos", rt.orderDomain.InvoiceHandler.GetReceiptESCPOS)
+				r.Get("/{id}/receipt.txt", rt.orderDomain.InvoiceHandler.GetReceiptPreview)
+				r.Get("/{id}/tags.escpos", rt.orderDomain.InvoiceHandler.GetTagsESCPOS)
+				r.Get("/{id}/tags.txt", rt.orderDomain.InvoiceHandler.GetTagsPreview)
 				r.Put("/{id}", rt.orderDomain.Handler.UpdateOrder)
 				r.Delete("/{id}", rt.orderDomain.Handler.DeleteOrder)
 				r.Patch("/{id}/status", rt.orderDomain.Handler.UpdateOrderStatus)
 				r.Post("/{id}/cancel", rt.orderDomain.Handler.CancelOrder)
+
+				// Cash / transfer / EDC payments taken at the counter
+				r.With(middleware.RequireRole(middleware.CashierRoles...)).
+					Post("/{id}/payments/manual", rt.paymentDomain.Handler.CreateManualPayment)
+
+				// Pay from the customer's wallet balance
+				r.Post("/{id}/payments/wallet", rt.paymentDomain.Handler.CreateWalletPayment)
+
+				// Customer notifications sent for the order
+				r.With(middleware.RequireRole(middleware.StaffRoles...)).
+					Get("/{id}/notifications", rt.notificationDomain.Handler.ListOrderNotifications)
+
+				// Live status and payment events (Server-Sent Events)
+				r.Get("/{id}/events", rt.realtimeDomain.Handler.StreamOrderEvents)
+			})
+
+			// Live events of all orders of an outlet, for staff dashboards
+			r.With(middleware.RequireRole(middleware.StaffRoles...)).
+				Get("/outlets/{id}/events", rt.realtimeDomain.Handler.StreamOutletEvents)
+
+			// Customer notification opt-in
+			r.Get("/notifications/preferences", rt.notificationDomain.Handler.GetPreferences)
+			r.Put("/notifications/preferences", rt.notificationDomain.Handler.SavePreferences)
+
+			// Customer wallet: balance, ledger and top-ups
+			r.Route("/wallet", func(r chi.Router) {
+				r.Get("/", rt.paymentDomain.Handler.GetWallet)
+				r.Post("/topups", rt.paymentDomain.Handler.CreateWalletTopup)
 			})
 
 			// Payment endpoints - Midtrans (Protected)
 			r.Route("/payments/midtrans", func(r chi.Router) {
 				// Create snap token
-				r.Post("/token", rt.paymentDomain.Handler.CreateSnapToken)
+				r.With(rt.idempotency.Handler).Post("/token", rt.paymentDomain.Handler.CreateSnapToken)
+
+				// Create a Core API charge (QRIS, VA, GoPay) without the Snap page
+				r.Post("/charge", rt.paymentDomain.Handler.CreateDirectCharge)
 
 				// Check transaction status (queries Midtrans API)
 				r.Get("/status/{paymentOrderId}", rt.paymentDomain.Handler.CheckStatus)
@@ -83,10 +144,60 @@ func (rt *Router) Setup() http.Handler {
 				// Get transaction history with filters
 				r.Get("/history", rt.paymentDomain.Handler.GetTransactionHistory)
 			})
+
+			// Payment refunds (staff only)
+			r.Route("/payments/{id}/refunds", func(r chi.Router) {
+				r.Use(middleware.RequireRole(middleware.StaffRoles...))
+				r.Post("/", rt.paymentDomain.Handler.CreateRefund)
+				r.Get("/", rt.paymentDomain.Handler.ListRefunds)
+			})
+
+			// Admin endpoints
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(middleware.RequireRole(middleware.AdminRoles...))
+
+				// Webhook logs: list failed notifications and replay them
+				r.Get("/payments/webhooks", rt.paymentDomain.Handler.ListWebhookLogs)
+				r.Post("/payments/webhooks/replay", rt.paymentDomain.Handler.ReplayWebhookLogs)
+
+				// Settlement reconciliation against imported Midtrans reports
+				r.Post("/payments/settlements/import", rt.paymentDomain.Handler.ImportSettlementReport)
+				r.Get("/payments/settlements", rt.paymentDomain.Handler.GetSettlementReconciliation)
+
+				// Per-outlet Midtrans merchant accounts
+				r.Get("/outlets/{id}/merchant-credentials", rt.paymentDomain.Handler.GetMerchantCredential)
+				r.Put("/outlets/{id}/merchant-credentials", rt.paymentDomain.Handler.SetMerchantCredential)
+
+				// Per-outlet invoice and receipt templates
+				r.Get("/outlets/{id}/invoice-template", rt.orderDomain.InvoiceHandler.GetInvoiceTemplate)
+				r.Put("/outlets/{id}/invoice-template", rt.orderDomain.InvoiceHandler.SaveInvoiceTemplate)
+
+				// Partner webhook subscriptions, their deliveries and manual redelivery
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Get("/", rt.webhookDomain.Handler.ListSubscriptions)
+					r.Post("/", rt.webhookDomain.Handler.CreateSubscription)
+					r.Get("/deliveries/{deliveryId}", rt.webhookDomain.Handler.GetDelivery)
+					r.Post("/deliveries/{deliveryId}/redeliver", rt.webhookDomain.Handler.Redeliver)
+					r.Get("/{id}", rt.webhookDomain.Handler.GetSubscription)
+					r.Put("/{id}", rt.webhookDomain.Handler.UpdateSubscription)
+					r.Delete("/{id}", rt.webhookDomain.Handler.DeleteSubscription)
+					r.Get("/{id}/deliveries", rt.webhookDomain.Handler.ListDeliveries)
+				})
+
+				// API keys of internal callers (core-api, back-office jobs)
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Get("/", rt.apikeyDomain.Handler.ListAPIKeys)
+					r.Post("/", rt.apikeyDomain.Handler.IssueAPIKey)
+					r.Get("/{id}", rt.apikeyDomain.Handler.GetAPIKey)
+					r.Delete("/{id}", rt.apikeyDomain.Handler.RevokeAPIKey)
+				})
+			})
 		})
 
-		// Public webhook endpoint (no auth) - must be accessible by Midtrans
-		r.Post("/payments/midtrans/notification", rt.paymentDomain.Handler.Notification)
+		// Public webhook endpoint (no auth) - must be accessible by Midtrans.
+		// Limited per client IP so it cannot be flooded.
+		r.With(rt.rateLimiter.Limit(middleware.RateLimitNotification)).
+			Post("/payments/midtrans/notification", rt.paymentDomain.Handler.Notification)
 	})
 
 	return r
@@ -0,0 +1,103 @@
+// Package scheduler runs periodic background jobs. Each run takes a lock so
+// that only one instance executes a job at a time when the locker is shared
+// (Redis); with the in-memory locker this only holds within one process.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"laondry-order-service/internal/lock"
+	"laondry-order-service/internal/logger"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// LockTTL bounds how long a crashed run can hold the lock. Defaults to Interval.
+	LockTTL time.Duration
+	Run     func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	locker lock.Locker
+	jobs   []Job
+	wg     sync.WaitGroup
+}
+
+func New(locker lock.Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Add registers a job. Must be called before Start.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every job on its interval until ctx is canceled. It does not block.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		job := job
+		if job.Interval <= 0 {
+			slog.WarnContext(ctx, "[Scheduler] Job has no interval, not starting", "job", job.Name)
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			slog.InfoContext(ctx, "[Scheduler] Job started", "job", job.Name, "interval", job.Interval.String())
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					slog.InfoContext(ctx, "[Scheduler] Job stopped", "job", job.Name)
+					return
+				case <-ticker.C:
+					if _, err := s.RunOnce(ctx, job); err != nil {
+						slog.ErrorContext(ctx, "[Scheduler] Job failed", "job", job.Name, "error", err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until all started jobs have returned.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunOnce runs job if its lock is free. ran is false when another instance
+// holds the lock. Records the job logs carry its name.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) (ran bool, err error) {
+	ctx = logger.With(ctx, "job", job.Name)
+	if s.locker != nil {
+		ttl := job.LockTTL
+		if ttl <= 0 {
+			ttl = job.Interval
+		}
+		unlock, ok, err := s.locker.TryLock(ctx, "scheduler:"+job.Name, ttl)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				slog.WarnContext(ctx, "[Scheduler] Failed to release lock", "error", err)
+			}
+		}()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %s panicked: %v", job.Name, r)
+		}
+	}()
+	return true, job.Run(ctx)
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"laondry-order-service/internal/lock"
+)
+
+func TestRunOnceSkipsWhenLockHeld(t *testing.T) {
+	ctx := context.Background()
+	locker := lock.NewMemoryLocker()
+	s := New(locker)
+
+	var runs int32
+	job := Job{Name: "test", Interval: time.Minute, Run: func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}}
+
+	unlock, ok, _ := locker.TryLock(ctx, "scheduler:test", time.Minute)
+	assert.True(t, ok)
+
+	ran, err := s.RunOnce(ctx, job)
+	assert.NoError(t, err)
+	assert.False(t, ran)
+
+	_ = unlock()
+	ran, err = s.RunOnce(ctx, job)
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+}
+
+func TestRunOnceReportsErrorsAndPanics(t *testing.T) {
+	s := New(lock.NewMemoryLocker())
+
+	_, err := s.RunOnce(context.Background(), Job{Name: "err", Run: func(ctx context.Context) error {
+		return errors.New("boom")
+	}})
+	assert.EqualError(t, err, "boom")
+
+	_, err = s.RunOnce(context.Background(), Job{Name: "panic", Run: func(ctx context.Context) error {
+		panic("boom")
+	}})
+	assert.Error(t, err)
+}
+
+func TestStartStopsOnCancel(t *testing.T) {
+	s := New(lock.NewMemoryLocker())
+	var runs int32
+	s.Add(Job{Name: "tick", Interval: 5 * time.Millisecond, Run: func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	s.Wait()
+}
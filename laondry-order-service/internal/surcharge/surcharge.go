@@ -0,0 +1,97 @@
+// Package surcharge prices the fee a payment method costs us and the part of
+// it passed on to the customer.
+package surcharge
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rule is the fee of one payment method: Percent of the charged amount plus
+// a Fixed amount. With PassThrough the customer pays it as a surcharge.
+type Rule struct {
+	Method      string
+	Percent     float64 // e.g. 2.9 for 2.9%
+	Fixed       float64
+	PassThrough bool
+}
+
+// Table holds the rules by payment method, keyed by the Snap payment names
+// (credit_card, gopay, other_qris, bca_va, echannel, ...). A nil Table has no
+// fees.
+type Table map[string]Rule
+
+// Parse reads a comma-separated rule list such as
+//
+//	credit_card:2.9%+2000:customer,gopay:2%,bca_va:4000
+//
+// Each rule is method:fee[:customer|merchant] where fee is a percentage, a
+// fixed amount or both joined with "+". Fees are absorbed by the merchant
+// unless marked customer.
+func Parse(spec string) (Table, error) {
+	t := Table{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("surcharge rule %q: want method:fee[:customer|merchant]", entry)
+		}
+		rule := Rule{Method: strings.TrimSpace(parts[0])}
+		for _, term := range strings.Split(parts[1], "+") {
+			term = strings.TrimSpace(term)
+			isPercent := strings.HasSuffix(term, "%")
+			v, err := strconv.ParseFloat(strings.TrimSuffix(term, "%"), 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("surcharge rule %q: invalid fee %q", entry, term)
+			}
+			if isPercent {
+				rule.Percent += v
+			} else {
+				rule.Fixed += v
+			}
+		}
+		if rule.Percent >= 100 {
+			return nil, fmt.Errorf("surcharge rule %q: percentage must be below 100", entry)
+		}
+		if len(parts) == 3 {
+			switch strings.TrimSpace(parts[2]) {
+			case "customer":
+				rule.PassThrough = true
+			case "merchant":
+			default:
+				return nil, fmt.Errorf("surcharge rule %q: fee is paid by customer or merchant", entry)
+			}
+		}
+		if _, dup := t[rule.Method]; dup {
+			return nil, fmt.Errorf("surcharge rule for %s given twice", rule.Method)
+		}
+		t[rule.Method] = rule
+	}
+	return t, nil
+}
+
+// Fee estimates what the gateway keeps of a charge of gross paid with method
+func (t Table) Fee(method string, gross float64) float64 {
+	rule, ok := t[method]
+	if !ok || gross <= 0 {
+		return 0
+	}
+	return math.Round(gross*rule.Percent/100 + rule.Fixed)
+}
+
+// Surcharge is what the customer pays on top of amount when method passes
+// its fee through. It is grossed up so that the fee on amount+surcharge is
+// covered, and rounded up to a whole rupiah.
+func (t Table) Surcharge(method string, amount float64) float64 {
+	rule, ok := t[method]
+	if !ok || !rule.PassThrough || amount <= 0 {
+		return 0
+	}
+	p := rule.Percent / 100
+	return math.Ceil((amount*p + rule.Fixed) / (1 - p))
+}
@@ -0,0 +1,57 @@
+package surcharge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	table, err := Parse("credit_card:2.9%+2000:customer, gopay:2%,bca_va:4000:merchant,")
+	require.NoError(t, err)
+	assert.Equal(t, Table{
+		"credit_card": {Method: "credit_card", Percent: 2.9, Fixed: 2000, PassThrough: true},
+		"gopay":       {Method: "gopay", Percent: 2},
+		"bca_va":      {Method: "bca_va", Fixed: 4000},
+	}, table)
+
+	empty, err := Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, spec := range []string{
+		"credit_card",
+		"credit_card:abc",
+		"credit_card:-1%",
+		"credit_card:100%",
+		"credit_card:2%:someone",
+		":2%",
+		"gopay:2%,gopay:3%",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestFeeAndSurcharge(t *testing.T) {
+	table, err := Parse("credit_card:2.9%+2000:customer,gopay:2%,bca_va:4000")
+	require.NoError(t, err)
+
+	// Absorbed by the merchant: no surcharge, fee on the gross
+	assert.Zero(t, table.Surcharge("gopay", 100000))
+	assert.Equal(t, float64(2000), table.Fee("gopay", 100000))
+	assert.Equal(t, float64(4000), table.Fee("bca_va", 100000))
+
+	// Passed through: the fee on amount+surcharge is covered
+	s := table.Surcharge("credit_card", 100000)
+	assert.Equal(t, float64(5047), s)
+	assert.LessOrEqual(t, table.Fee("credit_card", 100000+s), s)
+
+	// Unknown methods and a nil table cost nothing
+	assert.Zero(t, table.Fee("shopeepay", 100000))
+	assert.Zero(t, table.Surcharge("shopeepay", 100000))
+	var none Table
+	assert.Zero(t, none.Fee("gopay", 100000))
+	assert.Zero(t, none.Surcharge("credit_card", 100000))
+}
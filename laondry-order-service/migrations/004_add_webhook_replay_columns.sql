@@ -0,0 +1,17 @@
+-- Migration: Track webhook replays
+-- Created: 2025-01-20
+-- Description: Links replayed webhook notifications to the original log entry
+
+ALTER TABLE payment_webhook_logs
+    ADD COLUMN IF NOT EXISTS replay_of_id UUID REFERENCES payment_webhook_logs(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS replayed_by UUID,
+    ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_logs_replay_of_id ON payment_webhook_logs(replay_of_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_logs_processing_error ON payment_webhook_logs(created_at)
+    WHERE processing_error IS NOT NULL;
+
+COMMENT ON COLUMN payment_webhook_logs.event_type IS 'NULL for live notifications, replay or replay_dry_run for admin replays';
+COMMENT ON COLUMN payment_webhook_logs.replay_of_id IS 'Original webhook log when this entry was created by a replay';
+COMMENT ON COLUMN payment_webhook_logs.replayed_by IS 'User who triggered the replay';
+COMMENT ON COLUMN payment_webhook_logs.dry_run IS 'Replay that verified the payload without applying it';
@@ -0,0 +1,30 @@
+-- Migration: Create payment refunds table
+-- Created: 2025-01-27
+-- Description: Refund ledger for Midtrans refunds and chargebacks
+
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_transaction_id UUID NOT NULL REFERENCES payment_transactions(id) ON DELETE CASCADE,
+    refund_key VARCHAR(100) NOT NULL UNIQUE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    status VARCHAR(30) NOT NULL DEFAULT 'PENDING',
+    source VARCHAR(50) NOT NULL,
+    requested_by UUID,
+    gateway_refund_id VARCHAR(100),
+    failure_reason TEXT,
+    raw_response JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment_transaction_id ON payment_refunds(payment_transaction_id);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_deleted_at ON payment_refunds(deleted_at);
+
+COMMENT ON TABLE payment_refunds IS 'Refunds and chargebacks recorded against payment transactions';
+COMMENT ON COLUMN payment_refunds.refund_key IS 'Idempotency key sent to Midtrans (refund_key)';
+COMMENT ON COLUMN payment_refunds.status IS 'PENDING, SUCCESS, FAILED; non-failed refunds count toward the refunded total';
+COMMENT ON COLUMN payment_refunds.source IS 'api (staff request) or webhook (refund made outside this service)';
+COMMENT ON COLUMN payment_refunds.requested_by IS 'Staff user who requested the refund';
+COMMENT ON COLUMN payment_refunds.gateway_refund_id IS 'Midtrans refund_chargeback_id';
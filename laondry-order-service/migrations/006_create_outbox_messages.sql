@@ -0,0 +1,25 @@
+-- Migration: Create transactional outbox
+-- Created: 2025-02-03
+-- Description: Side effects written with the business change and delivered asynchronously with retries
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    topic VARCHAR(100) NOT NULL,
+    aggregate_id UUID,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_topic ON outbox_messages(topic);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_aggregate_id ON outbox_messages(aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages(next_attempt_at) WHERE status = 'PENDING';
+
+COMMENT ON TABLE outbox_messages IS 'Transactional outbox; rows are delivered by the outbox-dispatch background job';
+COMMENT ON COLUMN outbox_messages.topic IS 'Event name, e.g. order.canceled';
+COMMENT ON COLUMN outbox_messages.status IS 'PENDING, DONE, or DEAD after exhausting OUTBOX_MAX_ATTEMPTS';
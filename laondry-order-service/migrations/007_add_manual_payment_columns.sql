@@ -0,0 +1,17 @@
+-- Migration: Manual payments at the outlet counter
+-- Created: 2025-02-10
+-- Description: Cash / bank transfer / EDC payments recorded by the cashier share payment_transactions
+
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS receipt_no VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS amount_tendered DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS change_amount DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS recorded_by UUID;
+
+CREATE INDEX IF NOT EXISTS idx_payment_transactions_receipt_no ON payment_transactions(receipt_no);
+
+COMMENT ON COLUMN payment_transactions.payment_type IS 'Gateway payment_type, or manual for payments recorded at the counter';
+COMMENT ON COLUMN payment_transactions.receipt_no IS 'Counter receipt number (manual payments)';
+COMMENT ON COLUMN payment_transactions.amount_tendered IS 'Amount handed over by the customer; gross_amount is the part applied to the order';
+COMMENT ON COLUMN payment_transactions.change_amount IS 'Change returned for cash overpayment';
+COMMENT ON COLUMN payment_transactions.recorded_by IS 'Cashier who recorded the manual payment';
@@ -0,0 +1,58 @@
+-- Migration: Customer wallet ledger and top-ups
+-- Created: 2025-02-17
+-- Description: Append-only ledger of users.balance movements and gateway top-ups
+
+CREATE TABLE IF NOT EXISTS wallet_ledger (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    entry_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    balance_after DECIMAL(12,2) NOT NULL CHECK (balance_after >= 0),
+    order_id UUID,
+    payment_transaction_id UUID REFERENCES payment_transactions(id),
+    topup_id UUID,
+    description TEXT,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_ledger_user_id ON wallet_ledger(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_wallet_ledger_order_id ON wallet_ledger(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_ledger_topup_id ON wallet_ledger(topup_id) WHERE topup_id IS NOT NULL;
+
+-- The ledger is append-only
+CREATE OR REPLACE FUNCTION wallet_ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_wallet_ledger_append_only ON wallet_ledger;
+CREATE TRIGGER trg_wallet_ledger_append_only
+    BEFORE UPDATE OR DELETE ON wallet_ledger
+    FOR EACH ROW EXECUTE FUNCTION wallet_ledger_append_only();
+
+CREATE TABLE IF NOT EXISTS wallet_topups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    payment_order_id VARCHAR(100) NOT NULL UNIQUE,
+    amount DECIMAL(12,2) NOT NULL,
+    status VARCHAR(30) NOT NULL DEFAULT 'PENDING',
+    snap_token VARCHAR(255),
+    snap_redirect_url TEXT,
+    payment_type VARCHAR(50),
+    transaction_id VARCHAR(100),
+    credited_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_topups_user_id ON wallet_topups(user_id);
+
+-- Debits use a conditional UPDATE; this is the last line of defence
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_balance_non_negative;
+ALTER TABLE users ADD CONSTRAINT chk_users_balance_non_negative CHECK (balance >= 0);
+
+COMMENT ON TABLE wallet_ledger IS 'Append-only wallet movements; amount is signed, balance_after is the running balance';
+COMMENT ON COLUMN wallet_ledger.entry_type IS 'TOPUP, PAYMENT or REFUND';
+COMMENT ON TABLE wallet_topups IS 'Wallet top-ups paid through the payment gateway (order_id prefix TOPUP-)';
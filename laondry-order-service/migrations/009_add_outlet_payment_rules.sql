@@ -0,0 +1,10 @@
+-- Migration: Split / down payments per order
+-- Created: 2025-02-24
+-- Description: Per-outlet deposit rule and full-payment requirement before completion
+
+ALTER TABLE outlets
+    ADD COLUMN IF NOT EXISTS min_deposit_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (min_deposit_percent >= 0 AND min_deposit_percent <= 100),
+    ADD COLUMN IF NOT EXISTS require_full_payment_for_completion BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN outlets.min_deposit_percent IS 'Minimum first payment as a percentage of the order total; 0 disables the deposit rule';
+COMMENT ON COLUMN outlets.require_full_payment_for_completion IS 'Orders cannot move to COMPLETED (handover) while an outstanding balance remains';
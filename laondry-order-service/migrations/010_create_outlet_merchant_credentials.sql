@@ -0,0 +1,18 @@
+-- Migration: Per-outlet Midtrans merchant credentials
+-- Created: 2025-03-03
+-- Description: Franchise outlets settle to their own Midtrans merchant account; server keys are stored AES-GCM encrypted
+
+CREATE TABLE IF NOT EXISTS outlet_merchant_credentials (
+    outlet_id UUID PRIMARY KEY REFERENCES outlets(id) ON DELETE CASCADE,
+    server_key_encrypted TEXT NOT NULL,
+    client_key VARCHAR(100) NOT NULL,
+    enabled_payments VARCHAR(500),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE outlet_merchant_credentials IS 'Midtrans merchant account per outlet; outlets without a row use the global MIDTRANS_* keys';
+COMMENT ON COLUMN outlet_merchant_credentials.server_key_encrypted IS 'base64(nonce || AES-256-GCM ciphertext), key from MIDTRANS_CREDENTIALS_KEY';
+COMMENT ON COLUMN outlet_merchant_credentials.enabled_payments IS 'Comma separated Snap payment names; empty = MIDTRANS_ENABLED_PAYMENTS';
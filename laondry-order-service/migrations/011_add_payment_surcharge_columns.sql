@@ -0,0 +1,10 @@
+-- Migration: Payment method surcharges
+-- Created: 2025-03-10
+-- Description: Record the customer surcharge and the estimated gateway fee of each payment for settlement reconciliation
+
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS surcharge_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (surcharge_amount >= 0),
+    ADD COLUMN IF NOT EXISTS fee_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (fee_amount >= 0);
+
+COMMENT ON COLUMN payment_transactions.surcharge_amount IS 'Payment method fee passed on to the customer; included in gross_amount but not applied to the order';
+COMMENT ON COLUMN payment_transactions.fee_amount IS 'Estimated gateway fee; net settlement is gross_amount - fee_amount';
@@ -0,0 +1,47 @@
+-- Migration: Settlement reconciliation
+-- Created: 2025-03-17
+-- Description: Imported Midtrans settlement reports and their per-payment comparison with payment_transactions
+
+CREATE TABLE IF NOT EXISTS settlement_reports (
+    id UUID PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    row_count INT NOT NULL DEFAULT 0,
+    matched_count INT NOT NULL DEFAULT 0,
+    missing_count INT NOT NULL DEFAULT 0,
+    extra_count INT NOT NULL DEFAULT 0,
+    mismatch_count INT NOT NULL DEFAULT 0,
+    imported_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS settlement_entries (
+    id UUID PRIMARY KEY,
+    report_id UUID NOT NULL REFERENCES settlement_reports(id) ON DELETE CASCADE,
+    settlement_date DATE NOT NULL,
+    outlet_id UUID,
+    payment_transaction_id UUID REFERENCES payment_transactions(id) ON DELETE SET NULL,
+    payment_order_id VARCHAR(100) NOT NULL,
+    transaction_id VARCHAR(100),
+    payment_type VARCHAR(50),
+    status VARCHAR(30) NOT NULL CHECK (status IN ('MATCHED', 'MISSING', 'EXTRA', 'AMOUNT_MISMATCH', 'STATUS_MISMATCH')),
+    reported_amount DECIMAL(12,2),
+    reported_fee DECIMAL(12,2),
+    reported_net DECIMAL(12,2),
+    expected_amount DECIMAL(12,2),
+    expected_fee DECIMAL(12,2),
+    note TEXT,
+    line_no INT,
+    raw_row JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlement_entries_report_id ON settlement_entries(report_id);
+CREATE INDEX IF NOT EXISTS idx_settlement_entries_date_outlet ON settlement_entries(settlement_date, outlet_id);
+CREATE INDEX IF NOT EXISTS idx_settlement_entries_status ON settlement_entries(status);
+CREATE INDEX IF NOT EXISTS idx_settlement_entries_payment_order_id ON settlement_entries(payment_order_id);
+
+COMMENT ON TABLE settlement_entries IS 'Reconciliation per payment and settlement day; importing a report replaces the entries of the days it covers';
+COMMENT ON COLUMN settlement_entries.settlement_date IS 'Settlement day in WIB (Asia/Jakarta), as in the Midtrans report';
+COMMENT ON COLUMN settlement_entries.outlet_id IS 'Outlet of the matched order; NULL for EXTRA entries';
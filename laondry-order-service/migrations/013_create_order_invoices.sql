@@ -0,0 +1,34 @@
+-- Migration: Order invoices and per-outlet invoice templates
+-- Created: 2025-03-24
+-- Description: Sequential invoice numbers per outlet, issued once per order, and the template used to render invoice and receipt PDFs
+
+CREATE TABLE IF NOT EXISTS outlet_invoice_settings (
+    outlet_id UUID PRIMARY KEY REFERENCES outlets(id) ON DELETE CASCADE,
+    number_prefix VARCHAR(30),
+    last_number BIGINT NOT NULL DEFAULT 0 CHECK (last_number >= 0),
+    title VARCHAR(100),
+    tax_id VARCHAR(50),
+    header_note TEXT,
+    footer_note TEXT,
+    paper_size VARCHAR(10) NOT NULL DEFAULT 'A4' CHECK (paper_size IN ('A4', 'A5')),
+    receipt_width_mm INT NOT NULL DEFAULT 80 CHECK (receipt_width_mm IN (58, 80)),
+    show_qr_code BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_invoices (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE RESTRICT,
+    outlet_id UUID NOT NULL REFERENCES outlets(id) ON DELETE RESTRICT,
+    sequence BIGINT NOT NULL,
+    invoice_no VARCHAR(50) NOT NULL UNIQUE,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_order_invoices_outlet_sequence UNIQUE (outlet_id, sequence)
+);
+
+COMMENT ON TABLE outlet_invoice_settings IS 'Invoice template per outlet; last_number is the outlet''s invoice sequence';
+COMMENT ON COLUMN outlet_invoice_settings.number_prefix IS 'Invoice number prefix; empty = INV-<outlet code>';
+COMMENT ON TABLE order_invoices IS 'Invoice number of an order, issued on first request and never reused';
@@ -0,0 +1,5 @@
+-- Migration: Express flag on order items
+-- Created: 2025-03-31
+-- Description: Marks items ordered with express service, printed on thermal receipts and garment tags
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS is_express BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,47 @@
+-- Migration: Customer notifications
+-- Created: 2025-04-07
+-- Description: Per-customer channel opt-in and the delivery log of order and payment notifications
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    customer_id UUID PRIMARY KEY,
+    language VARCHAR(5) NOT NULL DEFAULT 'id',
+    whatsapp BOOLEAN NOT NULL DEFAULT FALSE,
+    sms BOOLEAN NOT NULL DEFAULT FALSE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    push BOOLEAN NOT NULL DEFAULT FALSE,
+    push_token VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE notification_preferences IS 'Channels each customer opted into; customers without a row are not notified';
+COMMENT ON COLUMN notification_preferences.language IS 'Message language: id or en';
+
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_key VARCHAR(150) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    order_id UUID NOT NULL,
+    customer_id UUID NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    language VARCHAR(5) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_deliveries_event_channel ON notification_deliveries(event_key, channel);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_order_id ON notification_deliveries(order_id);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_customer_id ON notification_deliveries(customer_id);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due ON notification_deliveries(next_attempt_at) WHERE status = 'PENDING';
+
+COMMENT ON TABLE notification_deliveries IS 'Customer notifications per channel; PENDING rows are sent by the notification-delivery background job';
+COMMENT ON COLUMN notification_deliveries.event_key IS 'Identifies the event, e.g. order-status:<status log id>; unique per channel so events are sent once';
+COMMENT ON COLUMN notification_deliveries.status IS 'PENDING, SENT, or FAILED after exhausting NOTIFY_MAX_ATTEMPTS';
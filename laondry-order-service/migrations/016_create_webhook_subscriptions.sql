@@ -0,0 +1,71 @@
+-- Migration: Partner webhooks
+-- Created: 2025-04-14
+-- Description: Webhook subscriptions of partner systems, their deliveries and the log of delivery attempts
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    secret_encrypted TEXT NOT NULL,
+    events VARCHAR(500) NOT NULL,
+    outlet_id UUID,
+    customer_id UUID,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_outlet_id ON webhook_subscriptions(outlet_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_customer_id ON webhook_subscriptions(customer_id);
+
+COMMENT ON TABLE webhook_subscriptions IS 'Partner endpoints told about order and payment events';
+COMMENT ON COLUMN webhook_subscriptions.secret_encrypted IS 'HMAC-SHA256 signing secret, AES-GCM encrypted with WEBHOOK_SECRETS_KEY';
+COMMENT ON COLUMN webhook_subscriptions.events IS 'Comma-separated events: order.status_changed, payment.succeeded';
+COMMENT ON COLUMN webhook_subscriptions.outlet_id IS 'Only orders of this outlet; NULL for all outlets';
+COMMENT ON COLUMN webhook_subscriptions.customer_id IS 'Only orders of this customer; NULL for all customers';
+COMMENT ON COLUMN webhook_subscriptions.consecutive_failures IS 'Failed attempts since the last success; the subscription is disabled at WEBHOOK_DISABLE_AFTER_FAILURES';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_key VARCHAR(150) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    order_id UUID NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_event ON webhook_deliveries(subscription_id, event_key);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_order_id ON webhook_deliveries(order_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+
+COMMENT ON TABLE webhook_deliveries IS 'Events per subscription; PENDING rows are sent by the webhook-delivery background job';
+COMMENT ON COLUMN webhook_deliveries.payload IS 'Signed JSON body, rendered when the event happened so retries send the same snapshot';
+COMMENT ON COLUMN webhook_deliveries.status IS 'PENDING, SUCCEEDED, or FAILED after exhausting WEBHOOK_MAX_ATTEMPTS';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt_no INTEGER NOT NULL,
+    manual BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INTEGER,
+    response_body TEXT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts(delivery_id);
+
+COMMENT ON TABLE webhook_delivery_attempts IS 'Every HTTP request made for a webhook delivery, including manual redeliveries';
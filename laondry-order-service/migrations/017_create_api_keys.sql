@@ -0,0 +1,29 @@
+-- Migration: Service API keys
+-- Created: 2025-04-21
+-- Description: Hashed, scoped API keys for internal callers (core-api, back-office jobs)
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    permissions VARCHAR(500) NOT NULL,
+    outlet_id UUID,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(45),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_outlet_id ON api_keys(outlet_id);
+
+COMMENT ON TABLE api_keys IS 'API keys of internal callers, sent in the X-Api-Key header';
+COMMENT ON COLUMN api_keys.prefix IS 'Public part of the key (lok_<8 hex>), used to look it up and shown in audit logs';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 hex of the full key; the key itself is only shown when issued';
+COMMENT ON COLUMN api_keys.permissions IS 'Comma-separated permissions: orders:read, orders:create, orders:update, orders:status';
+COMMENT ON COLUMN api_keys.outlet_id IS 'Only orders of this outlet; NULL for all outlets';
+COMMENT ON COLUMN api_keys.last_used_at IS 'Updated at most once a minute';
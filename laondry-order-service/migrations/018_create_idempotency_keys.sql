@@ -0,0 +1,25 @@
+-- Migration: Idempotency keys
+-- Created: 2025-04-28
+-- Description: Responses to requests sent with an Idempotency-Key header, replayed on retries
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    principal VARCHAR(100) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL,
+    content_type VARCHAR(100),
+    response_body BYTEA NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_principal_key ON idempotency_keys(principal, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Stored responses of POST /orders and POST /payments/midtrans/token per caller and Idempotency-Key; expired rows are deleted by the idempotency-cleanup job';
+COMMENT ON COLUMN idempotency_keys.principal IS 'Caller the key belongs to: user:<user id> or key:<API key id>';
+COMMENT ON COLUMN idempotency_keys.request_hash IS 'SHA-256 of method, path and body; a retry with another body is rejected with 409';
+COMMENT ON COLUMN idempotency_keys.expires_at IS 'End of the replay window, IDEMPOTENCY_TTL_HOURS after the first request';
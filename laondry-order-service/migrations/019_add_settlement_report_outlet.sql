@@ -0,0 +1,10 @@
+-- Migration: Settlement reports per merchant account
+-- Created: 2025-05-05
+-- Description: Scopes settlement imports to the merchant account (outlet or default) the report is from
+
+ALTER TABLE settlement_reports
+    ADD COLUMN IF NOT EXISTS outlet_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_settlement_reports_outlet_id ON settlement_reports(outlet_id);
+
+COMMENT ON COLUMN settlement_reports.outlet_id IS 'Outlet whose own merchant account the report is from, NULL for the default account';
@@ -0,0 +1,9 @@
+-- Migration: Count webhook redeliveries apart
+-- Created: 2025-05-06
+-- Description: Manual redeliveries no longer use up a delivery's automatic retries
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS manual_attempts INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN webhook_deliveries.attempts IS 'Automatic attempts; the delivery fails after WEBHOOK_MAX_ATTEMPTS';
+COMMENT ON COLUMN webhook_deliveries.manual_attempts IS 'Attempts made through the redeliver endpoint';
@@ -0,0 +1,10 @@
+-- Migration: Track reconciliation checks
+-- Created: 2025-05-07
+-- Description: The reconciler moves on to other pending transactions instead of re-checking the same oldest batch
+
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS last_reconciled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_payment_transactions_pending_reconciled ON payment_transactions(last_reconciled_at) WHERE status = 'PENDING';
+
+COMMENT ON COLUMN payment_transactions.last_reconciled_at IS 'When the reconciler last checked the transaction against the gateway, whatever the outcome';
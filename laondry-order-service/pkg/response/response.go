@@ -2,7 +2,10 @@ package response
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	appErrors "laondry-order-service/pkg/errors"
 )
@@ -106,6 +109,20 @@ func Conflict(w http.ResponseWriter, message string) {
 	})
 }
 
+// TooManyRequests answers a rate-limited request; clients may retry after
+// retryAfter, sent as the Retry-After header in whole seconds
+func TooManyRequests(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	JSON(w, http.StatusTooManyRequests, Response{
+		Success: false,
+		Message: message,
+	})
+}
+
 func InternalServerError(w http.ResponseWriter, message string, err interface{}) {
 	JSON(w, http.StatusInternalServerError, Response{
 		Success: false,
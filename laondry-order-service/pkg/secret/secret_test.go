@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(testKey('a'))
+	assert.NoError(t, err)
+
+	enc, err := c.Encrypt("SB-Mid-server-abc")
+	assert.NoError(t, err)
+	assert.NotContains(t, enc, "SB-Mid-server-abc")
+
+	again, _ := c.Encrypt("SB-Mid-server-abc")
+	assert.NotEqual(t, enc, again, "nonce must be random")
+
+	dec, err := c.Decrypt(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, "SB-Mid-server-abc", dec)
+
+	other, _ := NewCipher(testKey('b'))
+	_, err = other.Decrypt(enc)
+	assert.True(t, errors.Is(err, ErrInvalidCiphertext))
+
+	_, err = c.Decrypt("not base64!")
+	assert.True(t, errors.Is(err, ErrInvalidCiphertext))
+}
+
+func TestNilCipher(t *testing.T) {
+	var c *Cipher
+	_, err := c.Encrypt("x")
+	assert.True(t, errors.Is(err, ErrNoKey))
+	_, err = c.Decrypt("x")
+	assert.True(t, errors.Is(err, ErrNoKey))
+}
+
+func TestNewCipherRejectsShortKey(t *testing.T) {
+	_, err := NewCipher(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+	_, err = NewCipher("%%%")
+	assert.Error(t, err)
+}